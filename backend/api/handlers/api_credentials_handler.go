@@ -5,17 +5,32 @@ import (
 	"net/http"
 	"strings"
 
-	"sitesen/backend/services/settings"
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/services/settings"
 )
 
 // APICredentialsHandler API credentials handler
 type APICredentialsHandler struct {
 	service *settings.Service
+	stepUp  func(http.HandlerFunc) http.HandlerFunc
 }
 
-// NewAPICredentialsHandler yeni handler oluşturur
+// NewAPICredentialsHandler yeni handler oluşturur. Access token'lar AUTH_JWKS_URL
+// anahtarlarıyla doğrulanır.
 func NewAPICredentialsHandler(service *settings.Service) *APICredentialsHandler {
-	return &APICredentialsHandler{service: service}
+	return &APICredentialsHandler{service: service, stepUp: middleware.RequireStepUpHTTP}
+}
+
+// NewAPICredentialsHandlerWithKeys access token'ları verilen anahtar kaynağıyla
+// doğrulayan handler oluşturur
+func NewAPICredentialsHandlerWithKeys(service *settings.Service, keys claims.KeySource) *APICredentialsHandler {
+	return &APICredentialsHandler{
+		service: service,
+		stepUp: func(next http.HandlerFunc) http.HandlerFunc {
+			return middleware.RequireStepUpHTTPWithKeys(keys, next)
+		},
+	}
 }
 
 // ===============================================
@@ -285,9 +300,10 @@ func (h *APICredentialsHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/v1/settings/credentials", h.RequireSuperAdmin(h.ListCredentials))
 	mux.HandleFunc("GET /api/v1/settings/credentials/services", h.RequireSuperAdmin(h.GetAvailableServices))
 	mux.HandleFunc("GET /api/v1/settings/credentials/{id}", h.RequireSuperAdmin(h.GetCredential))
-	mux.HandleFunc("POST /api/v1/settings/credentials", h.RequireSuperAdmin(h.CreateCredential))
-	mux.HandleFunc("PUT /api/v1/settings/credentials/{id}", h.RequireSuperAdmin(h.UpdateCredential))
-	mux.HandleFunc("DELETE /api/v1/settings/credentials/{id}", h.RequireSuperAdmin(h.DeleteCredential))
+	// Anahtar ekleme/değiştirme/silme son 10 dakikada iki adımlı doğrulama ister
+	mux.HandleFunc("POST /api/v1/settings/credentials", h.RequireSuperAdmin(h.stepUp(h.CreateCredential)))
+	mux.HandleFunc("PUT /api/v1/settings/credentials/{id}", h.RequireSuperAdmin(h.stepUp(h.UpdateCredential)))
+	mux.HandleFunc("DELETE /api/v1/settings/credentials/{id}", h.RequireSuperAdmin(h.stepUp(h.DeleteCredential)))
 	mux.HandleFunc("POST /api/v1/settings/credentials/{id}/test", h.RequireSuperAdmin(h.TestCredential))
 	mux.HandleFunc("GET /api/v1/settings/credentials/{id}/audit", h.RequireSuperAdmin(h.GetAuditLog))
 }
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/services/settings"
+)
+
+type credentialsFixture struct {
+	mux    *http.ServeMux
+	signer *claims.Signer
+}
+
+func newCredentialsFixture(t *testing.T) *credentialsFixture {
+	t.Helper()
+	key, err := claims.GenerateKey("k1")
+	require.NoError(t, err)
+	signer, err := claims.NewSigner("k1", key)
+	require.NoError(t, err)
+	t.Setenv("API_CREDENTIALS_ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef")
+	service, err := settings.NewService()
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewAPICredentialsHandlerWithKeys(service, signer).RegisterRoutes(mux)
+	return &credentialsFixture{mux: mux, signer: signer}
+}
+
+func (f *credentialsFixture) token(t *testing.T, mfaAt *time.Time) string {
+	t.Helper()
+	c := claims.NewAccessClaims("u1", "p1", []string{"super_admin"}, []string{"*"}, "s1", "j1", time.Now())
+	if mfaAt != nil {
+		c.MFAAt = jwt.NewNumericDate(*mfaAt)
+	}
+	token, err := f.signer.Sign(c)
+	require.NoError(t, err)
+	return token
+}
+
+func (f *credentialsFixture) do(method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User-Role", "super_admin")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	f.mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPICredentials_StepUpRequired(t *testing.T) {
+	f := newCredentialsFixture(t)
+	stale := time.Now().Add(-claims.StepUpMaxAge - time.Minute)
+	body := `{"service_name":"iyzico","api_key":"sk_test"}`
+
+	routes := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/v1/settings/credentials", body},
+		{http.MethodPut, "/api/v1/settings/credentials/c1", `{"is_active":false}`},
+		{http.MethodDelete, "/api/v1/settings/credentials/c1", ""},
+	}
+	for _, r := range routes {
+		w := f.do(r.method, r.path, r.body, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, r.method)
+
+		w = f.do(r.method, r.path, r.body, "bozuk")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, r.method)
+
+		w = f.do(r.method, r.path, r.body, f.token(t, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, r.method)
+		assert.Contains(t, w.Body.String(), "step_up_required", r.method)
+
+		w = f.do(r.method, r.path, r.body, f.token(t, &stale))
+		assert.Equal(t, http.StatusForbidden, w.Code, r.method)
+	}
+}
+
+func TestAPICredentials_StepUpFresh(t *testing.T) {
+	f := newCredentialsFixture(t)
+	fresh := time.Now().Add(-time.Minute)
+	token := f.token(t, &fresh)
+
+	w := f.do(http.MethodPost, "/api/v1/settings/credentials", `{"service_name":"iyzico","api_key":"sk_test"}`, token)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = f.do(http.MethodPut, "/api/v1/settings/credentials/c1", `{"is_active":false}`, token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = f.do(http.MethodDelete, "/api/v1/settings/credentials/c1", "", token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestAPICredentials_ReadsDoNotNeedStepUp(t *testing.T) {
+	f := newCredentialsFixture(t)
+
+	w := f.do(http.MethodGet, "/api/v1/settings/credentials/services", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPICredentials_SuperAdminBeforeStepUp(t *testing.T) {
+	f := newCredentialsFixture(t)
+	fresh := time.Now().Add(-time.Minute)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/settings/credentials/c1", nil)
+	req.Header.Set("Authorization", "Bearer "+f.token(t, &fresh))
+	w := httptest.NewRecorder()
+	f.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "step_up_required")
+}
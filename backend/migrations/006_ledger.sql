@@ -0,0 +1,62 @@
+-- Çift Taraflı Muhasebe (Yevmiye) Migration
+-- ======================================
+
+-- Kaynak bağlantısı ve ters kayıt referansı
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS source_type VARCHAR(30); -- assessment, payment, expense, consumption_invoice
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS source_id UUID;
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS reversal_of UUID REFERENCES ledger_entries(id);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_source ON ledger_entries(source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_reversal ON ledger_entries(reversal_of);
+CREATE INDEX IF NOT EXISTS idx_ledger_lines_entry ON ledger_lines(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_lines_unit ON ledger_lines(unit_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_lines_account ON ledger_lines(account_id);
+
+-- Bir kayıt yalnızca bir kez ters çevrilebilir
+CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_reversal ON ledger_entries(reversal_of) WHERE reversal_of IS NOT NULL;
+
+-- Trigger: yevmiye kaydı dengesi (transaction sonunda kontrol edilir)
+CREATE OR REPLACE FUNCTION check_ledger_entry_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_entry_id UUID;
+    v_debit DECIMAL(14,2);
+    v_credit DECIMAL(14,2);
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_entry_id := OLD.entry_id;
+    ELSE
+        v_entry_id := NEW.entry_id;
+    END IF;
+
+    SELECT COALESCE(SUM(debit_amount), 0), COALESCE(SUM(credit_amount), 0)
+    INTO v_debit, v_credit
+    FROM ledger_lines
+    WHERE entry_id = v_entry_id;
+
+    IF v_debit <> v_credit THEN
+        RAISE EXCEPTION 'Yevmiye kaydı dengesiz: % (borç %, alacak %)', v_entry_id, v_debit, v_credit;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_lines_balanced ON ledger_lines;
+CREATE CONSTRAINT TRIGGER ledger_lines_balanced
+    AFTER INSERT OR UPDATE OR DELETE ON ledger_lines
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_ledger_entry_balanced();
+
+-- Varsayılan hesap planı (demo site)
+INSERT INTO chart_of_accounts (property_id, account_code, account_name, account_type) VALUES
+    ('11111111-1111-1111-1111-111111111111', '100', 'Kasa', 'ASSET'),
+    ('11111111-1111-1111-1111-111111111111', '102', 'Bankalar', 'ASSET'),
+    ('11111111-1111-1111-1111-111111111111', '120', 'Sakin Alacakları', 'ASSET'),
+    ('11111111-1111-1111-1111-111111111111', '320', 'Satıcılar', 'LIABILITY'),
+    ('11111111-1111-1111-1111-111111111111', '340', 'Alınan Avanslar', 'LIABILITY'),
+    ('11111111-1111-1111-1111-111111111111', '600', 'Aidat Gelirleri', 'REVENUE'),
+    ('11111111-1111-1111-1111-111111111111', '601', 'Tüketim Gelirleri', 'REVENUE'),
+    ('11111111-1111-1111-1111-111111111111', '642', 'Gecikme Tazminatı Gelirleri', 'REVENUE'),
+    ('11111111-1111-1111-1111-111111111111', '770', 'Genel Yönetim Giderleri', 'EXPENSE')
+ON CONFLICT (property_id, account_code) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Gecikme Tazminatı (KMK md. 20) Migration
+-- ======================================
+
+-- Tazminatın en son işletildiği tarih; tekrar çalıştırmada mükerrer tahsili önler
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS late_fee_accrued_through DATE;
+
+CREATE INDEX IF NOT EXISTS idx_assessments_overdue ON monthly_assessments(status, due_date)
+    WHERE status IN ('PENDING', 'PARTIAL', 'OVERDUE');
+
+-- Varsayılan ayarlar: aylık %5, başlayan her ay için
+UPDATE tenants
+SET settings = '{"late_fee_percentage": 5, "late_fee_method": "MONTHLY"}'::jsonb || COALESCE(settings, '{}'::jsonb)
+WHERE NOT (COALESCE(settings, '{}'::jsonb) ? 'late_fee_method');
@@ -0,0 +1,34 @@
+-- Ödeme Dağıtımı ve Daire Avansları Migration
+-- ======================================
+
+-- Ödenen tutarın gecikme tazminatına düşen kısmı; kalan ödeme ana paraya sayılır
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS late_fee_paid DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Önceki davranış: ödemeler önce tazminata sayılıyordu
+UPDATE monthly_assessments
+SET late_fee_paid = LEAST(COALESCE(late_fee, 0), COALESCE(paid_amount, 0))
+WHERE COALESCE(late_fee, 0) > 0 AND COALESCE(paid_amount, 0) > 0;
+
+-- Ödemenin tahakkuka düşen payının tazminat kısmı (iade edilirken geri alınır)
+ALTER TABLE payment_assessments ADD COLUMN IF NOT EXISTS late_fee_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Borçları aşan ve daire avansına (340) aktarılan tutar
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS credit_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMP;
+
+-- Avansın yeni tahakkuklara mahsubu
+CREATE TABLE IF NOT EXISTS unit_credit_applications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    unit_id UUID NOT NULL REFERENCES units(id),
+    assessment_id UUID NOT NULL REFERENCES monthly_assessments(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    reversed_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- İade nedeniyle geri alınan kısım
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_unit_credit_applications_unit ON unit_credit_applications(unit_id, created_at);
+
+-- Varsayılan dağıtım politikası: önce gecikme tazminatı (TBK md. 100)
+UPDATE tenants
+SET settings = '{"payment_allocation_policy": "LATE_FEE_FIRST"}'::jsonb || COALESCE(settings, '{}'::jsonb)
+WHERE NOT (COALESCE(settings, '{}'::jsonb) ? 'payment_allocation_policy');
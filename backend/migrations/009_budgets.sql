@@ -0,0 +1,39 @@
+-- İşletme Projesi (Bütçe) Migration
+-- ======================================
+
+-- KMK md. 37: yönetici yıllık işletme projesini genel kurula sunar; onaylanan
+-- projedeki gider kalemleri aylık aidatın dayanağıdır
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    fiscal_year INT NOT NULL,            -- Dönemin başladığı yıl
+    start_month INT NOT NULL DEFAULT 1 CHECK (start_month BETWEEN 1 AND 12),
+    name VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'DRAFT'
+        CHECK (status IN ('DRAFT', 'PENDING_APPROVAL', 'APPROVED', 'SUPERSEDED')),
+    notes TEXT,
+    rejection_reason TEXT,
+    approval_reference VARCHAR(200),     -- Genel kurul karar no / tarihi
+    approved_by UUID REFERENCES users(id),
+    approved_at TIMESTAMP,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_property ON budgets(property_id, fiscal_year);
+
+-- Bir dönem için yalnızca bir onaylı proje olabilir (revizyonda eskisi SUPERSEDED olur)
+CREATE UNIQUE INDEX IF NOT EXISTS uq_budgets_approved ON budgets(property_id, fiscal_year) WHERE status = 'APPROVED';
+
+-- Gider kalemi bazında yıllık planlanan tutarlar
+CREATE TABLE IF NOT EXISTS budget_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES expense_categories(id),
+    planned_amount DECIMAL(12,2) NOT NULL CHECK (planned_amount >= 0),
+    notes TEXT,
+    UNIQUE (budget_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_items_budget ON budget_items(budget_id);
@@ -0,0 +1,61 @@
+-- Demirbaş / Yedek Akçe Fonları Migration
+-- ======================================
+
+-- Aidattan ayrı toplanan ve ayrı hesapta izlenen fonlar. Her fon hesap planında
+-- 549 Özel Fonlar altında kendi alt hesabına (549.01, 549.02, ...) sahiptir.
+CREATE TABLE IF NOT EXISTS reserve_funds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    account_code VARCHAR(20) NOT NULL,
+
+    -- Katkı planı: monthly_amount site toplamıdır, dairelere distribution_type ile dağıtılır
+    monthly_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (monthly_amount >= 0),
+    distribution_type VARCHAR(20) NOT NULL DEFAULT 'SHARE_RATIO'
+        CHECK (distribution_type IN ('SHARE_RATIO', 'EQUAL', 'AREA_M2')),
+    applies_to_commercial BOOLEAN NOT NULL DEFAULT true,
+    applies_to_ground_floor BOOLEAN NOT NULL DEFAULT true,
+    starts_on DATE NOT NULL,             -- İlk katkı dönemi (ayın ilk günü)
+    ends_on DATE,                        -- Son katkı dönemi; boşsa süresiz
+    target_amount DECIMAL(14,2),         -- Hedef fon büyüklüğü (bilgi amaçlı)
+
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (property_id, account_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reserve_funds_property ON reserve_funds(property_id);
+
+-- Tahakkuklarda fon katkı payı aidattan ayrı izlenir (total_amount = base + reserve + late_fee)
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS reserve_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS reserve_paid DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Fon katkı satırları (expense_category_id boş, reserve_fund_id dolu)
+ALTER TABLE assessment_details ADD COLUMN IF NOT EXISTS reserve_fund_id UUID REFERENCES reserve_funds(id);
+
+-- Ödemenin / avans mahsubunun fon katkısına düşen kısmı
+ALTER TABLE payment_assessments ADD COLUMN IF NOT EXISTS reserve_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE unit_credit_applications ADD COLUMN IF NOT EXISTS reserve_amount DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE unit_credit_applications ADD COLUMN IF NOT EXISTS reserve_reversed DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Fondan harcama: yönetim kurulu / genel kurul kararına dayanır, onaylanınca defterlenir
+CREATE TABLE IF NOT EXISTS reserve_fund_withdrawals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    fund_id UUID NOT NULL REFERENCES reserve_funds(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    description TEXT NOT NULL,
+    decision_reference VARCHAR(200) NOT NULL, -- Karar no
+    decision_date DATE NOT NULL,
+    cash_account VARCHAR(20) NOT NULL DEFAULT '102', -- Ödemenin çıktığı hesap (100 Kasa / 102 Bankalar)
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by UUID REFERENCES users(id),
+    approved_by UUID REFERENCES users(id),
+    approved_at TIMESTAMP,
+    rejection_reason TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reserve_withdrawals_fund ON reserve_fund_withdrawals(fund_id, status);
@@ -0,0 +1,52 @@
+-- Ödeme Planları (Taksitlendirme) Migration
+-- =========================================
+
+-- Vadesi geçmiş tahakkukların taksitlere bölünmesi. Plan ACTIVE iken kapsadığı
+-- tahakkuklara gecikme tazminatı işletilmez; taksit kaçırılırsa plan BROKEN olur,
+-- affedilen tazminat geri yüklenir ve tazminat işletmesi kaldığı yerden devam eder.
+CREATE TABLE IF NOT EXISTS payment_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE', 'COMPLETED', 'BROKEN', 'CANCELLED')),
+    total_amount DECIMAL(12,2) NOT NULL CHECK (total_amount > 0), -- Taksitlere bölünen borç (af sonrası)
+    waived_late_fee DECIMAL(12,2) NOT NULL DEFAULT 0,              -- Plan sürdükçe affedilen tazminat
+    paid_amount DECIMAL(12,2) NOT NULL DEFAULT 0,                  -- Plan tahakkuklarına yapılan ödemeler
+    installment_count INTEGER NOT NULL CHECK (installment_count > 0),
+    grace_days INTEGER NOT NULL DEFAULT 0,                         -- Taksit vadesinden sonra tanınan süre
+    notes TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    closed_at TIMESTAMP,                                           -- COMPLETED / BROKEN / CANCELLED anı
+    close_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_plans_unit ON payment_plans(unit_id, status);
+CREATE INDEX IF NOT EXISTS idx_payment_plans_active ON payment_plans(property_id) WHERE status = 'ACTIVE';
+
+-- Plana dahil tahakkuklar: plan anındaki ödenen tutar, sonraki ödemelerin plana sayılması için saklanır
+CREATE TABLE IF NOT EXISTS payment_plan_assessments (
+    plan_id UUID NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    assessment_id UUID NOT NULL REFERENCES monthly_assessments(id),
+    outstanding_amount DECIMAL(12,2) NOT NULL, -- Plan anındaki açık tutar (af öncesi)
+    paid_at_start DECIMAL(12,2) NOT NULL,
+    waived_late_fee DECIMAL(12,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (plan_id, assessment_id)
+);
+
+CREATE TABLE IF NOT EXISTS payment_plan_installments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    plan_id UUID NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    installment_no INTEGER NOT NULL,
+    due_date DATE NOT NULL,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    paid_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'PARTIAL', 'PAID', 'MISSED')),
+    paid_at TIMESTAMP,
+    UNIQUE (plan_id, installment_no)
+);
+
+-- Tahakkuk aynı anda tek bir aktif plana dahil olabilir
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS payment_plan_id UUID REFERENCES payment_plans(id);
+CREATE INDEX IF NOT EXISTS idx_monthly_assessments_plan ON monthly_assessments(payment_plan_id) WHERE payment_plan_id IS NOT NULL;
@@ -0,0 +1,31 @@
+-- Tahakkuk Düzeltmeleri ve Alacak Dekontları Migration
+-- ===================================================
+
+-- Düzeltme kayıtları orijinal tahakkuk kaydına bağlanır. reversal_of'tan farklı olarak
+-- orijinal kayıt iptal edilmiş sayılmaz; bir kayda birden fazla düzeltme bağlanabilir.
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS correction_of UUID REFERENCES ledger_entries(id);
+CREATE INDEX IF NOT EXISTS idx_ledger_correction ON ledger_entries(correction_of);
+
+-- Tahakkuk düzeltme geçmişi. amount işaretlidir: pozitif borç artışı, negatif indirimdir.
+-- İndirimin tahakkukun ödenmemiş aidat kısmını aşan bölümü daire avansına (340) aktarılır;
+-- tahakkuk ödenen tutarın altına düşürülmez. Alacak dekontu (CREDIT_NOTE) doğrudan avansa yazılır.
+CREATE TABLE IF NOT EXISTS assessment_corrections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    assessment_id UUID REFERENCES monthly_assessments(id),           -- Alacak dekontunda isteğe bağlı
+    correction_type VARCHAR(20) NOT NULL
+        CHECK (correction_type IN ('ADJUSTMENT', 'CREDIT_NOTE', 'REDISTRIBUTION')),
+    expense_category_id UUID REFERENCES expense_categories(id),
+    amount DECIMAL(12,2) NOT NULL CHECK (amount <> 0),
+    assessment_change DECIMAL(12,2) NOT NULL DEFAULT 0,              -- Tahakkuk tutarına yansıyan kısım
+    credit_amount DECIMAL(12,2) NOT NULL DEFAULT 0,                  -- Daire avansına aktarılan kısım
+    reason TEXT NOT NULL,
+    original_entry_id UUID REFERENCES ledger_entries(id),            -- Düzeltilen AIDAT kaydı
+    ledger_entry_id UUID REFERENCES ledger_entries(id),              -- DUZELTME kaydı
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_corrections_assessment ON assessment_corrections(assessment_id);
+CREATE INDEX IF NOT EXISTS idx_assessment_corrections_unit ON assessment_corrections(unit_id, created_at);
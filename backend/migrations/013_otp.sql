@@ -0,0 +1,32 @@
+-- SMS Doğrulama Kodları (OTP) Migration
+-- ====================================
+
+-- Telefonun en son ne zaman OTP ile doğrulandığı
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP;
+
+-- Gönderilen kodlar. Kod düz metin saklanmaz; telefon ve amaçla birlikte HMAC-SHA256
+-- özeti tutulur. Gönderim hızı sınırları bu tablodan sayılır (telefon ve IP bazında).
+CREATE TABLE IF NOT EXISTS otp_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    phone VARCHAR(20) NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('LOGIN', 'PASSWORD_RESET')),
+    code_hash VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(45),
+    attempts INTEGER NOT NULL DEFAULT 0,  -- Bu koda yapılan hatalı deneme sayısı
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,                -- Başarılı kullanım veya deneme hakkı bitince dolar
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_codes_phone ON otp_codes(phone, purpose, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_otp_codes_ip ON otp_codes(ip_address, created_at);
+
+-- Hatalı doğrulama denemeleri; pencere içindeki sayı sınırı aşınca telefon kilitlenir
+CREATE TABLE IF NOT EXISTS otp_failures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    phone VARCHAR(20) NOT NULL,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_failures_phone ON otp_failures(phone, created_at);
@@ -0,0 +1,24 @@
+-- Refresh Token Rotasyonu ve Oturumlar Migration
+-- ====================================
+
+-- Verilen her refresh token. Token düz metin saklanmaz; SHA-256 özeti tutulur.
+-- Aynı girişten türeyen tokenlar bir aileyi (family_id) paylaşır ve bir oturumu temsil eder.
+-- Her yenilemede eski token rotated_at ile kapanır; kapanmış bir token tekrar
+-- kullanılırsa (çalınma belirtisi) tüm aile iptal edilir.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY,                                  -- JWT jti
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    parent_id UUID REFERENCES refresh_tokens(id),         -- Yenilenen önceki token
+    token_hash VARCHAR(64) NOT NULL,
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoke_reason VARCHAR(20) CHECK (revoke_reason IN ('LOGOUT', 'LOGOUT_ALL', 'REUSE', 'PASSWORD_RESET')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
@@ -0,0 +1,112 @@
+-- Site Bazlı Yetkilendirme (RBAC) Migration
+-- ======================================
+
+-- Yetki kataloğu. Kodlar servislerdeki RequirePermission çağrılarıyla aynıdır.
+-- "*" yalnızca platform yöneticisine (ADMIN) verilir.
+CREATE TABLE IF NOT EXISTS permissions (
+    code VARCHAR(100) PRIMARY KEY,
+    module VARCHAR(30) NOT NULL,
+    description VARCHAR(200) NOT NULL
+);
+
+-- Roller. STAFF rolleri siteye atanır (property_role_assignments); RESIDENT rolleri
+-- resident_units.role'den gelir; PLATFORM rolü users.roles'tan gelir ve tüm sitelerde geçerlidir.
+CREATE TABLE IF NOT EXISTS roles (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('PLATFORM', 'STAFF', 'RESIDENT'))
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_code VARCHAR(30) NOT NULL REFERENCES roles(code) ON DELETE CASCADE,
+    permission_code VARCHAR(100) NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+    PRIMARY KEY (role_code, permission_code)
+);
+
+-- Personel rollerinin site bazında atanması
+CREATE TABLE IF NOT EXISTS property_role_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_code VARCHAR(30) NOT NULL REFERENCES roles(code),
+    granted_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_by UUID REFERENCES users(id),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_assignments_user ON property_role_assignments(user_id, property_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_role_assignments_active
+    ON property_role_assignments(property_id, user_id, role_code) WHERE revoked_at IS NULL;
+
+INSERT INTO permissions (code, module, description) VALUES
+('*', 'platform', 'Tüm yetkiler'),
+('identity.role.manage', 'identity', 'Sitede personel rolü atama ve kaldırma'),
+('identity.role.admin', 'identity', 'Rol yetki tanımlarını düzenleme'),
+('finance.assessment.read', 'finance', 'Aidat ve tahakkukları görüntüleme'),
+('finance.assessment.create', 'finance', 'Tahakkuk oluşturma'),
+('finance.assessment.correct', 'finance', 'Tahakkuk düzeltme ve alacak dekontu'),
+('finance.ledger.read', 'finance', 'Mizan ve daire ekstrelerini görüntüleme'),
+('finance.late_fee.accrue', 'finance', 'Gecikme tazminatı işletme'),
+('finance.payment.create', 'finance', 'Ödeme yapma'),
+('finance.payment.refund', 'finance', 'Ödeme iadesi'),
+('finance.budget.manage', 'finance', 'İşletme projesi hazırlama'),
+('finance.budget.approve', 'finance', 'İşletme projesi onaylama'),
+('finance.reserve.manage', 'finance', 'Demirbaş fonu yönetimi ve çekim talebi'),
+('finance.reserve.approve', 'finance', 'Demirbaş fonu çekim onayı'),
+('finance.payment_plan.manage', 'finance', 'Ödeme planı oluşturma ve iptal'),
+('expense.read', 'expense', 'Giderleri görüntüleme'),
+('expense.manage', 'expense', 'Gider kaydı oluşturma ve düzenleme'),
+('visitor.read', 'visitor', 'Ziyaretçi kayıtlarını görüntüleme'),
+('visitor.checkin', 'visitor', 'Ziyaretçi giriş-çıkış işlemleri'),
+('package.manage', 'package', 'Kargo teslim alma ve teslim etme'),
+('patrol.manage', 'patrol', 'Devriye turları'),
+('reservation.create', 'reservation', 'Ortak alan rezervasyonu yapma'),
+('reservation.manage', 'reservation', 'Rezervasyonları onaylama ve iptal'),
+('meeting.vote', 'meeting', 'Genel kurulda oy kullanma'),
+('meeting.manage', 'meeting', 'Genel kurul hazırlama'),
+('bulletin.manage', 'bulletin', 'Duyuru yayınlama')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO roles (code, name, kind) VALUES
+('ADMIN', 'Platform Yöneticisi', 'PLATFORM'),
+('MANAGER', 'Site Yöneticisi', 'STAFF'),
+('ACCOUNTANT', 'Muhasebe', 'STAFF'),
+('SECURITY', 'Güvenlik', 'STAFF'),
+('CONCIERGE', 'Konsiyerj', 'STAFF'),
+('OWNER', 'Kat Maliki', 'RESIDENT'),
+('TENANT', 'Kiracı', 'RESIDENT'),
+('PROXY', 'Vekil', 'RESIDENT')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code)
+SELECT 'MANAGER', code FROM permissions WHERE code NOT IN ('*', 'identity.role.admin')
+UNION ALL
+SELECT 'ADMIN', '*'
+UNION ALL
+SELECT 'ACCOUNTANT', unnest(ARRAY[
+    'finance.assessment.read', 'finance.assessment.create', 'finance.assessment.correct', 'finance.ledger.read',
+    'finance.late_fee.accrue', 'finance.payment.create', 'finance.payment.refund', 'finance.budget.manage',
+    'finance.reserve.manage', 'finance.payment_plan.manage', 'expense.read', 'expense.manage'])
+UNION ALL
+SELECT 'SECURITY', unnest(ARRAY['visitor.read', 'visitor.checkin', 'patrol.manage'])
+UNION ALL
+SELECT 'CONCIERGE', unnest(ARRAY['visitor.read', 'visitor.checkin', 'package.manage', 'reservation.manage'])
+UNION ALL
+SELECT 'OWNER', unnest(ARRAY['finance.assessment.read', 'finance.payment.create', 'reservation.create', 'meeting.vote'])
+UNION ALL
+SELECT 'TENANT', unnest(ARRAY['finance.assessment.read', 'finance.payment.create', 'reservation.create'])
+UNION ALL
+SELECT 'PROXY', unnest(ARRAY['finance.assessment.read', 'finance.payment.create', 'meeting.vote'])
+ON CONFLICT DO NOTHING;
+
+-- Mevcut yöneticiler: global MANAGER rolü olanlar aktif sitelerinde, yönetim kadrosundakiler kendi sitelerinde
+INSERT INTO property_role_assignments (property_id, user_id, role_code)
+SELECT DISTINCT property_id, user_id, 'MANAGER' FROM (
+    SELECT active_property_id AS property_id, id AS user_id
+    FROM users WHERE 'MANAGER' = ANY(roles) AND active_property_id IS NOT NULL
+    UNION
+    SELECT property_id, user_id
+    FROM management_staff WHERE is_active = true AND user_id IS NOT NULL AND end_date IS NULL
+) m
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,49 @@
+-- Sakin Davetleri ve Daire Kayıt Süreci Migration
+-- ======================================
+
+-- Yöneticinin bir daireye kat maliki, kiracı veya vekil olarak davet ettiği kişiler.
+-- Davet bağlantısındaki token düz metin saklanmaz; SHA-256 özeti tutulur. Davet bir kez
+-- kabul edilebilir; kabul edildiğinde oluşan resident_units kaydı bağlanır.
+CREATE TABLE IF NOT EXISTS resident_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('OWNER', 'TENANT', 'PROXY')),
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20),
+    email VARCHAR(200),
+    move_in_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    delivery VARCHAR(10) NOT NULL CHECK (delivery IN ('SMS', 'MANUAL')), -- MANUAL: bağlantıyı yönetici iletir
+    invited_by UUID REFERENCES users(id),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_by UUID REFERENCES users(id),
+    resident_unit_id UUID REFERENCES resident_units(id),
+    revoked_at TIMESTAMP,
+    revoked_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (phone IS NOT NULL OR email IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_resident_invitations_property ON resident_invitations(property_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_resident_invitations_unit ON resident_invitations(unit_id);
+
+-- Taşınma kaydı: çıkışta ilişki silinmez, is_active=false ve end_date ile geçmişte kalır.
+ALTER TABLE resident_units ADD COLUMN IF NOT EXISTS ended_by UUID REFERENCES users(id);
+ALTER TABLE resident_units ADD COLUMN IF NOT EXISTS ended_at TIMESTAMP;
+
+-- Taşınıp geri dönen sakin aynı daireye aynı rolle yeniden kaydedilebilmeli: tekillik
+-- yalnızca aktif ilişkiler için aranır.
+ALTER TABLE resident_units DROP CONSTRAINT IF EXISTS resident_units_resident_id_unit_id_role_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_resident_units_active
+    ON resident_units(resident_id, unit_id, role) WHERE is_active = true;
+
+INSERT INTO permissions (code, module, description) VALUES
+('identity.resident.manage', 'identity', 'Sakin davet etme ve taşınma işlemleri')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+('MANAGER', 'identity.resident.manage')
+ON CONFLICT DO NOTHING;
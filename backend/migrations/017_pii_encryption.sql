@@ -0,0 +1,38 @@
+-- Kişisel Verilerin Şifrelenmesi (TCKN, telefon) Migration
+-- ======================================
+
+-- Telefon ve TCKN sürümlü AES-256-GCM anahtarıyla şifreli (*_encrypted, "v<sürüm>:..."),
+-- aramalar anahtarlı HMAC özetiyle (*_hash) yapılır. pii_key_version kaydın şifrelendiği
+-- anahtar sürümüdür; NULL ise kayıt henüz şifrelenmemiştir (düz metin phone). Yeniden
+-- şifreleme işi aktif sürümde olmayan kayıtları dönüştürür ve düz metni siler.
+ALTER TABLE users ALTER COLUMN phone DROP NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_hash VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pii_key_version INTEGER;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_hash ON users(phone_hash);
+CREATE INDEX IF NOT EXISTS idx_users_pii_key_version ON users(pii_key_version);
+
+-- Bir kişi tek hesap: TCKN özeti tekil
+DROP INDEX IF EXISTS idx_users_tc_hash;
+ALTER TABLE users ALTER COLUMN tc_hash TYPE VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tc_hash ON users(tc_hash);
+
+-- Davet telefonları da şifreli saklanır
+ALTER TABLE resident_invitations ADD COLUMN IF NOT EXISTS phone_encrypted VARCHAR(500);
+ALTER TABLE resident_invitations ADD COLUMN IF NOT EXISTS phone_hash VARCHAR(64);
+ALTER TABLE resident_invitations ADD COLUMN IF NOT EXISTS pii_key_version INTEGER;
+ALTER TABLE resident_invitations DROP CONSTRAINT IF EXISTS resident_invitations_check;
+ALTER TABLE resident_invitations ADD CONSTRAINT resident_invitations_contact_check
+    CHECK (phone IS NOT NULL OR phone_hash IS NOT NULL OR email IS NOT NULL);
+
+-- Doğrulama kodlarında telefon yerine özeti tutulur. Kodlar dakikalarla sınırlı
+-- yaşadığı için mevcut kayıtlar taşınmaz.
+DELETE FROM otp_codes;
+DELETE FROM otp_failures;
+ALTER TABLE otp_codes RENAME COLUMN phone TO phone_hash;
+ALTER TABLE otp_codes ALTER COLUMN phone_hash TYPE VARCHAR(64);
+ALTER TABLE otp_failures RENAME COLUMN phone TO phone_hash;
+ALTER TABLE otp_failures ALTER COLUMN phone_hash TYPE VARCHAR(64);
+
+INSERT INTO permissions (code, module, description) VALUES
+('identity.pii.rotate', 'identity', 'Kişisel verileri yeni anahtarla yeniden şifreleme')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,49 @@
+-- İki Adımlı Doğrulama (TOTP, RFC 6238) Migration
+-- ======================================
+
+-- Kullanıcının TOTP anahtarı. Anahtar kişisel veri anahtarlarıyla şifreli saklanır
+-- ("v<sürüm>:..."); enabled_at NULL ise kayıt onay kodunu bekler. last_used_step aynı
+-- kodun tekrar kullanılmasını engeller. Art arda hatalı kodlar hesabı kısa süre kilitler.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted VARCHAR(500) NOT NULL,
+    pii_key_version INTEGER,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Tek kullanımlık kurtarma kodları (SHA-256 özeti)
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user ON mfa_recovery_codes(user_id) WHERE used_at IS NULL;
+
+-- Hatırlanan cihazlar: girişte ikinci adım istenmez (hassas işlemler için yine istenir)
+CREATE TABLE IF NOT EXISTS mfa_trusted_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_trusted_devices_user ON mfa_trusted_devices(user_id);
+
+-- İki adımlı doğrulamanın zorunlu olduğu roller site yönetimi (tenant) ayarındadır:
+--   tenants.settings -> 'mfa_required_roles'  ör. ["MANAGER", "ACCOUNTANT"]
+-- Kullanıcının herhangi bir sitede bu rollerden birine sahip olması yeterlidir. Platform
+-- rolleri (ADMIN) kimlik servisinin MFA_REQUIRED_PLATFORM_ROLES ayarıyla zorunlu tutulur.
+UPDATE tenants SET settings = jsonb_set(COALESCE(settings, '{}'::jsonb), '{mfa_required_roles}', '["MANAGER", "ACCOUNTANT"]'::jsonb)
+WHERE NOT (COALESCE(settings, '{}'::jsonb) ? 'mfa_required_roles');
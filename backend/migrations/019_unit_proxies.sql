@@ -0,0 +1,98 @@
+-- Vekalet (Daire Bazlı Yetki Devri) Migration
+-- ======================================
+
+-- Kat malikinin bir dairesi için başka bir kişiye belirli süreyle verdiği vekalet.
+-- Kapsamlar: PAY_DUES (aidat ödeme), VOTE (anket ve genel kurulda oy), NOTIFICATIONS
+-- (daireye giden bildirimleri alma). Vekaletname belgesi yüklenmeden vekalet verilemez;
+-- dosya kimlik servisinin belge dizininde, özeti (SHA-256) burada tutulur. Süresi dolan
+-- veya iptal edilen vekalet silinmez, geçmişte kalır.
+CREATE TABLE IF NOT EXISTS unit_proxies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    grantor_id UUID NOT NULL REFERENCES users(id),
+    proxy_id UUID NOT NULL REFERENCES users(id),
+    scopes TEXT[] NOT NULL CHECK (cardinality(scopes) > 0 AND scopes <@ ARRAY['PAY_DUES', 'VOTE', 'NOTIFICATIONS']),
+    starts_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    document_key VARCHAR(200) NOT NULL,
+    document_name VARCHAR(255) NOT NULL,
+    document_type VARCHAR(100) NOT NULL,
+    document_size BIGINT NOT NULL,
+    document_sha256 VARCHAR(64) NOT NULL,
+    note TEXT,
+    revoked_at TIMESTAMP,
+    revoked_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (expires_at > starts_at),
+    CHECK (grantor_id <> proxy_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_unit_proxies_proxy ON unit_proxies(proxy_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_unit_proxies_unit ON unit_proxies(unit_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_unit_proxies_property ON unit_proxies(property_id, created_at DESC);
+
+-- Şu an geçerli vekaletler: iptal edilmemiş, süresi içinde ve veren kişi hâlâ dairenin
+-- aktif kat maliki. Malik taşındığında (move-out) verdiği vekaletler kendiliğinden düşer.
+-- Finans, anket ve toplantı servisleri vekaleti yalnızca bu görünüm üzerinden okur.
+CREATE OR REPLACE VIEW active_unit_proxies AS
+SELECT p.*
+FROM unit_proxies p
+WHERE p.revoked_at IS NULL
+  AND p.starts_at <= NOW()
+  AND p.expires_at > NOW()
+  AND EXISTS (
+      SELECT 1 FROM resident_units ru
+      WHERE ru.resident_id = p.grantor_id AND ru.unit_id = p.unit_id AND ru.role = 'OWNER'
+        AND ru.is_active = true AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+  );
+
+-- Anket oyları daire başınadır: vekil birden çok daire adına oy kullanabildiği için kişi
+-- bazlı tekillik kaldırılır. Vekaletle kullanılan oyda vekalet ve adına oy verilen malik
+-- saklanır.
+ALTER TABLE survey_votes DROP CONSTRAINT IF EXISTS survey_votes_survey_id_voter_id_key;
+ALTER TABLE survey_votes ADD COLUMN IF NOT EXISTS proxy_grant_id UUID REFERENCES unit_proxies(id);
+ALTER TABLE survey_votes ADD COLUMN IF NOT EXISTS on_behalf_of UUID REFERENCES users(id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_survey_votes_unit_option ON survey_votes(survey_id, unit_id, option_id);
+
+-- Genel kurul hazirun listesi: her daire bir kez, kendisi veya vekili tarafından temsil edilir
+CREATE TABLE IF NOT EXISTS meeting_attendance (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id),
+    attendee_id UUID NOT NULL REFERENCES users(id),
+    proxy_grant_id UUID REFERENCES unit_proxies(id),
+    on_behalf_of UUID REFERENCES users(id),
+    share_ratio DECIMAL(10,4) NOT NULL,
+    checked_in_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (meeting_id, unit_id)
+);
+
+-- Gündem maddesi oyları (meetings.agenda[].order); daire başına bir oy, arsa payıyla
+CREATE TABLE IF NOT EXISTS meeting_votes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    meeting_id UUID NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    agenda_order INT NOT NULL,
+    unit_id UUID NOT NULL REFERENCES units(id),
+    voter_id UUID NOT NULL REFERENCES users(id),
+    proxy_grant_id UUID REFERENCES unit_proxies(id),
+    on_behalf_of UUID REFERENCES users(id),
+    choice VARCHAR(10) NOT NULL CHECK (choice IN ('FOR', 'AGAINST', 'ABSTAIN')),
+    share_ratio DECIMAL(10,4) NOT NULL,
+    voted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (meeting_id, agenda_order, unit_id)
+);
+
+INSERT INTO permissions (code, module, description) VALUES
+('identity.proxy.grant', 'identity', 'Daire için vekalet verme ve iptal'),
+('identity.proxy.manage', 'identity', 'Sitedeki vekaletleri görüntüleme ve iptal'),
+('survey.vote', 'survey', 'Anket ve oylamalarda oy kullanma')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+('OWNER', 'identity.proxy.grant'),
+('OWNER', 'survey.vote'),
+('TENANT', 'survey.vote'),
+('PROXY', 'survey.vote'),
+('MANAGER', 'identity.proxy.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,35 @@
+-- Kiracı kaydı: askıya alma, büyük/küçük harf duyarsız slug/alan adı araması ve
+-- değişikliklerin servislerdeki önbelleğe LISTEN/NOTIFY ile bildirilmesi
+-- Migration 020
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+-- Slug ve alan adı küçük harfle saklanır ve aranır
+UPDATE tenants SET slug = lower(slug), custom_domain = lower(custom_domain)
+WHERE slug <> lower(slug) OR custom_domain <> lower(custom_domain);
+
+-- UNIQUE kısıtları zaten indeksli; ifade indeksleri lower() aramalarını karşılar
+DROP INDEX IF EXISTS idx_tenants_slug;
+DROP INDEX IF EXISTS idx_tenants_custom_domain;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tenants_slug_lower ON tenants (lower(slug));
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tenants_custom_domain_lower ON tenants (lower(custom_domain))
+    WHERE custom_domain IS NOT NULL;
+
+-- Kiracı eklendiğinde, değiştiğinde veya silindiğinde tenant_changed kanalına kiracı kimliği
+-- gönderilir; TenantManager önbelleği bu bildirimle temizlenir
+CREATE OR REPLACE FUNCTION notify_tenant_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('tenant_changed', COALESCE(NEW.id, OLD.id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_tenants_notify ON tenants;
+CREATE TRIGGER trg_tenants_notify
+    AFTER INSERT OR UPDATE OR DELETE ON tenants
+    FOR EACH ROW EXECUTE FUNCTION notify_tenant_changed();
+
+INSERT INTO permissions (code, module, description) VALUES
+('platform.tenant.manage', 'platform', 'Kiracı (site yönetimi) oluşturma, askıya alma ve yeniden etkinleştirme')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,24 @@
+-- Kiracı kullanım ölçümü: gönderilen SMS sayaçları ve plan limiti uyarıları
+-- Günlük anlık görüntüler migration 003'teki usage_metrics tablosuna yazılır
+-- Migration 021
+
+-- Site adına gönderilen SMS'ler (davet, vekalet bildirimi vb.); giriş kodları platforma aittir
+CREATE TABLE IF NOT EXISTS sms_usage (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    usage_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    sent_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, usage_date)
+);
+
+-- %80 eşiği uyarıları; aynı limit için kiracıya bir kez gönderilir (plan değişince yeniden)
+CREATE TABLE IF NOT EXISTS tenant_limit_warnings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    resource_type VARCHAR(50) NOT NULL, -- units, users
+    limit_value INT NOT NULL,
+    usage_value INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, resource_type, limit_value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_properties_tenant ON properties(tenant_id);
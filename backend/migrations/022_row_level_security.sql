@@ -0,0 +1,129 @@
+-- Kiracı izolasyonu: satır düzeyinde güvenlik (RLS)
+-- Servisler istek kapsamındaki sorguları database.InTenantTx ile siteeksen_tenant rolüne
+-- geçip app.tenant_id ve app.user_id ayarlanmış transaction'da çalıştırır. Bu rol yalnızca
+-- o kiracının sitelerine ait satırları görür ve yazabilir; kiracı ayarlanmamışsa hiçbir satır
+-- görünmez. Tabloların sahibi olan uygulama kullanıcısı politikalara tabi değildir (giriş,
+-- arka plan işleri, kiracı kaydı, ödeme callback'i).
+-- Migration 022
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'siteeksen_tenant') THEN
+        CREATE ROLE siteeksen_tenant NOLOGIN;
+    END IF;
+END
+$$;
+
+-- Migration'ı çalıştıran kullanıcı uygulama kullanıcısı değilse rol ayrıca verilmelidir:
+-- GRANT siteeksen_tenant TO <uygulama kullanıcısı>;
+GRANT siteeksen_tenant TO CURRENT_USER;
+
+GRANT USAGE ON SCHEMA public TO siteeksen_tenant;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO siteeksen_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO siteeksen_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO siteeksen_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO siteeksen_tenant;
+
+-- Transaction'ın kiracısı; ayarlanmamışsa NULL (hiçbir satır eşleşmez)
+CREATE OR REPLACE FUNCTION app_current_tenant() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::uuid
+$$ LANGUAGE sql STABLE;
+
+-- Politikalar USING ile tanımlanır; WITH CHECK verilmediğinden aynı koşul yazılan satıra da
+-- uygulanır (başka kiracının sitesine kayıt eklenemez, kayıt başka kiracıya taşınamaz)
+
+-- Kiracının kendisi ve doğrudan kiracıya bağlı tablolar
+ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON tenants;
+CREATE POLICY tenant_isolation ON tenants USING (id = app_current_tenant());
+
+ALTER TABLE properties ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON properties;
+CREATE POLICY tenant_isolation ON properties USING (tenant_id = app_current_tenant());
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['invoices', 'usage_metrics', 'sms_usage', 'tenant_limit_warnings'] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %I USING (tenant_id = app_current_tenant())', t);
+    END LOOP;
+END
+$$;
+
+-- Siteye (property_id) bağlı tablolar
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'blocks', 'units', 'management_staff', 'chart_of_accounts', 'expense_categories',
+        'monthly_assessments', 'ledger_entries', 'consumption_tariffs', 'announcements',
+        'request_categories', 'requests', 'expenses', 'visitors', 'parking_zones', 'parking_logs',
+        'facilities', 'reservations', 'bank_accounts', 'bank_transactions', 'bulletin_posts',
+        'surveys', 'packages', 'asset_categories', 'assets', 'contracts', 'patrol_checkpoints',
+        'patrol_routes', 'patrol_logs', 'employees', 'payroll', 'inventory_categories',
+        'inventory_items', 'energy_analytics', 'meetings', 'payment_risk_scores', 'budgets',
+        'reserve_funds', 'payment_plans', 'assessment_corrections', 'property_role_assignments',
+        'resident_invitations', 'unit_proxies'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %I USING (
+            EXISTS (SELECT 1 FROM properties p WHERE p.id = %I.property_id AND p.tenant_id = app_current_tenant()))', t, t);
+    END LOOP;
+END
+$$;
+
+-- Üst kayıt üzerinden bağlı tablolar: üst tablo sorgusu da politikaya tabi olduğundan satır,
+-- üst kaydı kiracıya görünüyorsa görünür
+DO $$
+DECLARE
+    r TEXT[];
+BEGIN
+    FOREACH r SLICE 1 IN ARRAY ARRAY[
+        -- tablo, yabancı anahtar, üst tablo
+        ['resident_units', 'unit_id', 'units'],
+        ['vehicles', 'unit_id', 'units'],
+        ['meters', 'unit_id', 'units'],
+        ['consumption_invoices', 'unit_id', 'units'],
+        ['payments', 'unit_id', 'units'],
+        ['survey_votes', 'unit_id', 'units'],
+        ['unit_credit_applications', 'unit_id', 'units'],
+        ['meeting_attendance', 'unit_id', 'units'],
+        ['meeting_votes', 'unit_id', 'units'],
+        ['meter_readings', 'meter_id', 'meters'],
+        ['assessment_details', 'assessment_id', 'monthly_assessments'],
+        ['payment_assessments', 'assessment_id', 'monthly_assessments'],
+        ['payment_plan_assessments', 'assessment_id', 'monthly_assessments'],
+        ['payment_plan_installments', 'plan_id', 'payment_plans'],
+        ['ledger_lines', 'entry_id', 'ledger_entries'],
+        ['expense_invoices', 'expense_id', 'expenses'],
+        ['expense_distributions', 'expense_id', 'expenses'],
+        ['budget_items', 'budget_id', 'budgets'],
+        ['reserve_fund_withdrawals', 'fund_id', 'reserve_funds'],
+        ['survey_options', 'survey_id', 'surveys'],
+        ['announcement_reads', 'announcement_id', 'announcements'],
+        ['request_comments', 'request_id', 'requests'],
+        ['bulletin_comments', 'post_id', 'bulletin_posts'],
+        ['bulletin_messages', 'post_id', 'bulletin_posts'],
+        ['asset_maintenance', 'asset_id', 'assets'],
+        ['employee_leaves', 'employee_id', 'employees'],
+        ['inventory_movements', 'item_id', 'inventory_items']
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', r[1]);
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', r[1]);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %1$I USING (
+            EXISTS (SELECT 1 FROM %3$I parent WHERE parent.id = %1$I.%2$I))', r[1], r[2], r[3]);
+    END LOOP;
+END
+$$;
+
+-- Görünümler varsayılan olarak sahibinin yetkisiyle çalışır ve RLS'i atlar; kapsamlı
+-- transaction'da sorgulayanın politikaları uygulansın
+ALTER VIEW unit_balances SET (security_invoker = true);
+ALTER VIEW monthly_collection_summary SET (security_invoker = true);
+ALTER VIEW monthly_expense_summary SET (security_invoker = true);
+ALTER VIEW active_unit_proxies SET (security_invoker = true);
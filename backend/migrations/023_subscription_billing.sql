@@ -0,0 +1,47 @@
+-- Kiracı abonelik faturalandırması: aylık/yıllık dönemler, kayıtlı kartla tahsilat,
+-- başarısız tahsilatta yeniden deneme (dunning) ve plan değişikliğinde kıst hesap
+-- Yeni durum: past_due (tahsilat başarısız, yeniden deneme süresince hizmet devam eder)
+-- Migration 023
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS billing_cycle VARCHAR(10) NOT NULL DEFAULT 'monthly'; -- monthly, yearly
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS current_period_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS billing_email VARCHAR(255);
+
+-- iyzico kart saklama token'ları; kart numarası tutulmaz
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS card_token VARCHAR(255);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS card_user_key VARCHAR(255);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS card_last_four VARCHAR(4);
+
+-- Plan düşürmeden kalan kuruş alacak; sonraki faturalardan düşülür
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS credit_balance INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS dunning_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS next_payment_retry_at TIMESTAMP WITH TIME ZONE;
+
+-- Faturalandırma işinin taradığı kiracılar
+CREATE INDEX IF NOT EXISTS idx_tenants_billing_due ON tenants (subscription_status, current_period_end);
+
+-- Fatura ayrıntıları; tutarlar kuruş cinsinden (amount_total = subtotal - credit_applied)
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'subscription'; -- subscription, proration
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS plan VARCHAR(50);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS billing_cycle VARCHAR(10);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS subtotal INTEGER;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS credit_applied INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS attempt_count INT NOT NULL DEFAULT 0;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS payment_id VARCHAR(100); -- iyzico paymentId
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS transaction_id VARCHAR(100);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Bir dönem için tek abonelik faturası; iş yarıda kesilip yeniden çalışırsa çift fatura kesilmez
+CREATE UNIQUE INDEX IF NOT EXISTS uq_invoices_subscription_period ON invoices (tenant_id, period_start)
+    WHERE kind = 'subscription';
+
+INSERT INTO permissions (code, module, description) VALUES
+('tenant.billing.manage', 'tenant', 'Abonelik planı, ödeme yöntemi ve faturaları yönetme')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+('MANAGER', 'tenant.billing.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+-- Toplu içe aktarma: daireler, sakinler, devir bakiyeleri ve sayaçlar Excel/CSV ile yüklenir
+-- Devir bakiyesi: eski yazılımdan aktarılan açık borç, dairenin OPENING türündeki tahakkukudur.
+-- Dönemi devir tarihinin ayıdır; o aya düzenli tahakkuk da kesilebildiğinden dönem tekilliği
+-- yalnızca düzenli tahakkuklara uygulanır. Her dairenin en fazla bir devir tahakkuku olur.
+-- Migration 024
+
+ALTER TABLE monthly_assessments ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'REGULAR'; -- REGULAR, OPENING
+
+ALTER TABLE monthly_assessments DROP CONSTRAINT IF EXISTS monthly_assessments_unit_id_period_year_period_month_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assessments_unit_period
+    ON monthly_assessments(unit_id, period_year, period_month) WHERE kind = 'REGULAR';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assessments_opening
+    ON monthly_assessments(unit_id) WHERE kind = 'OPENING';
+
+INSERT INTO permissions (code, module, description) VALUES
+('identity.import.manage', 'identity', 'Daire, sakin, devir bakiyesi ve sayaç listelerini toplu içe aktarma')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+('MANAGER', 'identity.import.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,44 @@
+-- Toplu sayaç okuma: yönetici okuma şablonunu indirir, görevli sahada doldurur ve liste
+-- yüklenir. Önceki okumadan küçük (geri giden) veya olağan tüketimin çok üstündeki okumalar
+-- kaydedilmez, yönetici incelemesine alınır. Onaylanan okuma meter_readings'e yazılır.
+-- Migration 025
+
+CREATE TABLE IF NOT EXISTS meter_reading_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    meter_id UUID NOT NULL REFERENCES meters(id) ON DELETE CASCADE,
+    reading_date DATE NOT NULL,
+    previous_value DECIMAL(12,3) NOT NULL,                           -- Yükleme anındaki son okuma
+    reported_value DECIMAL(12,3) NOT NULL,                           -- Listedeki okuma
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('ROLLBACK', 'JUMP')),
+    message TEXT NOT NULL,
+    source_row INT,                                                  -- Listedeki satır numarası
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    meter_reading_id UUID REFERENCES meter_readings(id) ON DELETE SET NULL,
+    resolution_note TEXT,
+    uploaded_by UUID REFERENCES users(id),
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Sayacın aynı tarihli tek bekleyen incelemesi olur; liste tekrar yüklenirse güncellenir
+CREATE UNIQUE INDEX IF NOT EXISTS uq_meter_reading_reviews_pending
+    ON meter_reading_reviews(meter_id, reading_date) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_meter_reading_reviews_property ON meter_reading_reviews(property_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_readings_meter_date ON meter_readings(meter_id, reading_date);
+
+-- Kiracı izolasyonu (022 ile aynı politika)
+ALTER TABLE meter_reading_reviews ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON meter_reading_reviews;
+CREATE POLICY tenant_isolation ON meter_reading_reviews USING (
+    EXISTS (SELECT 1 FROM properties p WHERE p.id = meter_reading_reviews.property_id AND p.tenant_id = app_current_tenant()));
+
+INSERT INTO permissions (code, module, description) VALUES
+('finance.meter_reading.manage', 'finance', 'Toplu sayaç okuma yükleme ve şüpheli okumaları inceleme')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_code, permission_code) VALUES
+('MANAGER', 'finance.meter_reading.manage'),
+('ACCOUNTANT', 'finance.meter_reading.manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- Tahsil edilen tutarı beklenenden farklı kart ödemeleri: ödeme AMOUNT_MISMATCH durumuna
+-- alınır, sağlayıcı işlem numarası ve tahsil edilen tutar saklanır. Tutar iyzico'dan
+-- otomatik iade edilir; iade başarısız olursa ödeme yönetici incelemesinde kalır.
+-- Migration 026
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS paid_amount DECIMAL(12,2);
+
+CREATE INDEX IF NOT EXISTS idx_payments_amount_mismatch ON payments(unit_id) WHERE status = 'AMOUNT_MISMATCH';
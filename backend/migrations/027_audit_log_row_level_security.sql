@@ -0,0 +1,10 @@
+-- Denetim logları için kiracı izolasyonu (022'deki tenant_isolation politikası).
+-- Politika tablo sahibine de uygulanır (FORCE): uygulama kullanıcısı denetim loglarını
+-- yalnızca app.tenant_id ayarlanmış transaction'da o kiracı için okuyup yazabilir,
+-- kapsamsız sorgularda hiçbir satır görünmez.
+-- Migration 027
+
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+CREATE POLICY tenant_isolation ON audit_logs USING (tenant_id = app_current_tenant());
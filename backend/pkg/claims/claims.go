@@ -0,0 +1,124 @@
+// Package claims kimlik servisi ile diğer servisler arasındaki token sözleşmesi.
+//
+// Tokenlar kimlik servisinde asimetrik anahtarla (RS256 veya EdDSA) imzalanır ve
+// başlıkta anahtar kimliği (kid) taşır. Diğer servisler yalnızca kimlik servisinin
+// yayınladığı JWKS'teki açık anahtarlarla doğrular; ortak sır paylaşılmaz.
+package claims
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer tokenları veren servis
+const Issuer = "siteeksen-identity"
+
+// Token türleri. Refresh token access token yerine kabul edilmez. MFA tokenı şifresi
+// doğrulanmış ama ikinci adımı bekleyen girişi taşır; yalnızca kimlik servisinde geçerlidir.
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+	TokenMFA     = "mfa"
+)
+
+// Süreler
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	MFATokenTTL     = 5 * time.Minute
+	// StepUpMaxAge hassas işlemler için iki adımlı doğrulamanın en fazla bu kadar eski olabileceği süre
+	StepUpMaxAge = 10 * time.Minute
+)
+
+var (
+	// ErrInvalidToken imza, süre, veren veya tür doğrulaması başarısız
+	ErrInvalidToken = errors.New("geçersiz veya süresi dolmuş token")
+	// ErrUnknownKey token başlığındaki kid bilinen anahtarlarda yok
+	ErrUnknownKey = errors.New("bilinmeyen imza anahtarı")
+)
+
+// Claims token içeriği. Kullanıcı kimliği standart "sub" alanındadır.
+type Claims struct {
+	PropertyID  string   `json:"property_id,omitempty"`
+	Roles       []string `json:"roles,omitempty"` // Aktif sitedeki roller
+	Permissions []string `json:"perms,omitempty"` // Aktif sitedeki rollerden çözülen yetkiler
+	SessionID   string   `json:"sid,omitempty"`   // Refresh token ailesi (oturum)
+	TokenUse    string   `json:"token_use"`       // access, refresh, mfa
+	// MFAAt oturumda TOTP veya kurtarma koduyla son doğrulama zamanı (hassas işlemler için)
+	MFAAt *jwt.NumericDate `json:"mfa_at,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID tokenın sahibi olan kullanıcı
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// StepUpFresh iki adımlı doğrulama son StepUpMaxAge içinde yapılmış mı
+func (c *Claims) StepUpFresh(now time.Time) bool {
+	return c.MFAAt != nil && now.Sub(c.MFAAt.Time) <= StepUpMaxAge
+}
+
+// HasPermission yetki listesinde istenen yetki var mı. "*" tüm yetkileri, "finance.*"
+// gibi bir önek o modüldeki tüm yetkileri kapsar.
+func HasPermission(granted []string, required string) bool {
+	for _, p := range granted {
+		if p == required || p == "*" {
+			return true
+		}
+		if strings.HasSuffix(p, ".*") && strings.HasPrefix(required, p[:len(p)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAccessClaims access token içeriği oluşturur
+func NewAccessClaims(userID, propertyID string, roles, permissions []string, sessionID, tokenID string, now time.Time) *Claims {
+	return &Claims{
+		PropertyID:  propertyID,
+		Roles:       roles,
+		Permissions: permissions,
+		SessionID:   sessionID,
+		TokenUse:    TokenAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   userID,
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
+	}
+}
+
+// NewRefreshClaims refresh token içeriği oluşturur; tokenID kimlik servisindeki kayıt kimliğidir
+func NewRefreshClaims(userID, sessionID, tokenID string, now time.Time) *Claims {
+	return &Claims{
+		SessionID: sessionID,
+		TokenUse:  TokenRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   userID,
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenTTL)),
+		},
+	}
+}
+
+// NewMFAClaims ikinci adımı bekleyen giriş için kısa ömürlü token içeriği oluşturur
+func NewMFAClaims(userID, tokenID string, now time.Time) *Claims {
+	return &Claims{
+		TokenUse: TokenMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   userID,
+			ID:        tokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFATokenTTL)),
+		},
+	}
+}
@@ -0,0 +1,246 @@
+package claims
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaKey(t *testing.T, id string) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := NewKey(id, private)
+	require.NoError(t, err)
+	return key
+}
+
+func edKey(t *testing.T, id string) *Key {
+	t.Helper()
+	key, err := GenerateKey(id)
+	require.NoError(t, err)
+	return key
+}
+
+func TestSignAndParse(t *testing.T) {
+	now := time.Now()
+	for _, key := range []*Key{rsaKey(t, "rsa-1"), edKey(t, "ed-1")} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			signer, err := NewSigner("", key)
+			require.NoError(t, err)
+
+			token, err := signer.Sign(NewAccessClaims("u1", "p1", []string{"MANAGER"}, []string{"finance.budget.approve"}, "s1", "j1", now))
+			require.NoError(t, err)
+
+			c, err := ParseAccess(token, signer)
+			require.NoError(t, err)
+			assert.Equal(t, "u1", c.UserID())
+			assert.Equal(t, "p1", c.PropertyID)
+			assert.Equal(t, []string{"MANAGER"}, c.Roles)
+			assert.Equal(t, "s1", c.SessionID)
+			assert.Equal(t, []string{"finance.budget.approve"}, c.Permissions)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+			assert.Equal(t, key.Algorithm, parsed.Header["alg"])
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	now := time.Now()
+	signer, err := NewSigner("", edKey(t, "k1"))
+	require.NoError(t, err)
+	other, err := NewSigner("", edKey(t, "k1"))
+	require.NoError(t, err)
+
+	// Refresh token access yerine kullanılamaz
+	refresh, err := signer.Sign(NewRefreshClaims("u1", "s1", "r1", now))
+	require.NoError(t, err)
+	_, err = ParseAccess(refresh, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = Parse(refresh, signer, TokenRefresh)
+	assert.NoError(t, err)
+
+	// İkinci adımı bekleyen giriş tokenı da access yerine geçmez
+	mfa, err := signer.Sign(NewMFAClaims("u1", "m1", now))
+	require.NoError(t, err)
+	_, err = ParseAccess(mfa, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = Parse(mfa, signer, TokenMFA)
+	assert.NoError(t, err)
+
+	// Süresi dolmuş
+	expired, err := signer.Sign(NewAccessClaims("u1", "", nil, nil, "", "j1", now.Add(-time.Hour)))
+	require.NoError(t, err)
+	_, err = ParseAccess(expired, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = ParseAt(expired, signer, TokenAccess, now.Add(-time.Hour))
+	assert.NoError(t, err, "süre verilen zamana göre kontrol edilir")
+
+	// Aynı kid, farklı anahtar
+	forged, err := other.Sign(NewAccessClaims("u1", "", nil, nil, "", "j1", now))
+	require.NoError(t, err)
+	_, err = ParseAccess(forged, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// HMAC ile imzalanmış eski tip token
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, NewAccessClaims("u1", "", nil, nil, "", "j1", now))
+	hmacToken.Header["kid"] = "k1"
+	legacy, err := hmacToken.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ParseAccess(legacy, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	for _, key := range []*Key{rsaKey(t, "rsa-1"), edKey(t, "ed-1")} {
+		jwk, err := NewJWK(key.ID, key.Public())
+		require.NoError(t, err)
+		assert.Equal(t, key.Algorithm, jwk.Alg)
+
+		public, err := jwk.PublicKey()
+		require.NoError(t, err)
+		assert.Equal(t, key.Public(), public)
+	}
+}
+
+func TestSignerRotation(t *testing.T) {
+	now := time.Now()
+	old, next := edKey(t, "2026-01"), rsaKey(t, "2026-07")
+
+	signer, err := NewSigner("2026-01", old, next)
+	require.NoError(t, err)
+	before, err := signer.Sign(NewAccessClaims("u1", "", nil, nil, "", "j1", now))
+	require.NoError(t, err)
+	assert.Len(t, signer.JWKS().Keys, 2)
+
+	// Yeni anahtar aktifleşir; eski anahtarla verilmiş token hâlâ geçerli
+	require.NoError(t, signer.Replace("2026-07", old, next))
+	after, err := signer.Sign(NewAccessClaims("u1", "", nil, nil, "", "j2", now))
+	require.NoError(t, err)
+	_, err = ParseAccess(before, signer)
+	assert.NoError(t, err)
+	_, err = ParseAccess(after, signer)
+	assert.NoError(t, err)
+
+	// Eski anahtar kaldırılınca eski tokenlar reddedilir
+	require.NoError(t, signer.Replace("2026-07", next))
+	_, err = ParseAccess(before, signer)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	assert.Error(t, signer.Replace("yok", next))
+	assert.Equal(t, "2026-07", signer.ActiveKeyID())
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-01.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	ed := edKey(t, "2026-07")
+	der, err = x509.MarshalPKCS8PrivateKey(ed.private)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-07.pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2026-01\n"), 0o600))
+
+	signer := &Signer{}
+	require.NoError(t, signer.LoadDir(dir))
+	assert.Equal(t, "2026-01", signer.ActiveKeyID())
+	assert.Len(t, signer.JWKS().Keys, 2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2026-07"), 0o600))
+	require.NoError(t, signer.LoadDir(dir))
+	assert.Equal(t, "2026-07", signer.ActiveKeyID())
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	now := time.Now()
+	signer, err := NewSigner("", edKey(t, "k1"))
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(signer.JWKS())
+	}))
+	defer srv.Close()
+
+	clock := now
+	remote := NewRemoteKeySet(srv.URL)
+	remote.now = func() time.Time { return clock }
+
+	token, err := signer.Sign(NewAccessClaims("u1", "", nil, nil, "", "j1", now))
+	require.NoError(t, err)
+	_, err = ParseAccess(token, remote)
+	require.NoError(t, err)
+	_, err = ParseAccess(token, remote)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Yeni anahtar yayınlandı ve aktifleşti: bilinmeyen kid önbelleği yeniler
+	k2 := edKey(t, "k2")
+	require.NoError(t, signer.Replace("k2", signer.keys["k1"], k2))
+	rotated, err := signer.Sign(NewAccessClaims("u1", "", nil, nil, "", "j2", now))
+	require.NoError(t, err)
+
+	_, err = ParseAccess(rotated, remote)
+	assert.Error(t, err, "yenileme aralığı dolmadan tekrar çekilmez")
+
+	clock = clock.Add(time.Minute)
+	_, err = ParseAccess(rotated, remote)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// Kimlik servisi erişilemezse bilinen anahtarlarla devam edilir
+	srv.Close()
+	clock = clock.Add(time.Hour)
+	_, err = ParseAccess(rotated, remote)
+	assert.NoError(t, err)
+}
+
+func TestHasPermission(t *testing.T) {
+	granted := []string{"visitor.checkin", "finance.*"}
+	assert.True(t, HasPermission(granted, "visitor.checkin"))
+	assert.True(t, HasPermission(granted, "finance.budget.approve"))
+	assert.False(t, HasPermission(granted, "visitor.read"))
+	assert.False(t, HasPermission(granted, "financeX.read"))
+	assert.False(t, HasPermission(nil, "visitor.read"))
+	assert.True(t, HasPermission([]string{"*"}, "identity.role.admin"))
+}
+
+func TestStepUpFresh(t *testing.T) {
+	now := time.Now()
+	signer, err := NewSigner("", edKey(t, "k1"))
+	require.NoError(t, err)
+
+	c := NewAccessClaims("u1", "p1", nil, nil, "s1", "j1", now)
+	assert.False(t, c.StepUpFresh(now))
+
+	c.MFAAt = jwt.NewNumericDate(now.Add(-time.Minute))
+	token, err := signer.Sign(c)
+	require.NoError(t, err)
+	parsed, err := ParseAccess(token, signer)
+	require.NoError(t, err)
+	assert.True(t, parsed.StepUpFresh(now))
+	assert.False(t, parsed.StepUpFresh(now.Add(StepUpMaxAge)))
+}
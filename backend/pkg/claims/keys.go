@@ -0,0 +1,253 @@
+package claims
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key kid ile tanımlanan imza anahtarı (RSA en az 2048 bit veya Ed25519)
+type Key struct {
+	ID        string
+	Algorithm string // RS256, EdDSA
+	private   crypto.Signer
+}
+
+// NewKey özel anahtardan imza anahtarı oluşturur; algoritma anahtar türünden belirlenir
+func NewKey(id string, private crypto.Signer) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("anahtar kimliği (kid) boş olamaz")
+	}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA anahtarı en az 2048 bit olmalı", id)
+		}
+		return &Key{ID: id, Algorithm: jwt.SigningMethodRS256.Alg(), private: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: jwt.SigningMethodEdDSA.Alg(), private: k}, nil
+	default:
+		return nil, fmt.Errorf("%s: desteklenmeyen anahtar türü %T", id, private)
+	}
+}
+
+// GenerateKey yeni Ed25519 imza anahtarı üretir
+func GenerateKey(id string) (*Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(id, private)
+}
+
+// ParsePrivateKeyPEM PEM biçimindeki RSA (PKCS#1/PKCS#8) veya Ed25519 (PKCS#8) özel anahtarını okur
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: PEM bloğu bulunamadı", id)
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		return NewKey(id, k)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: desteklenmeyen anahtar türü %T", id, k)
+	}
+	return NewKey(id, signer)
+}
+
+// Public anahtarın açık kısmı
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// Signer kimlik servisinin anahtar halkası. Yalnızca aktif anahtar imzalar; halkadaki
+// tüm anahtarlar JWKS'te yayınlanır ve doğrulamada kabul edilir.
+//
+// Kesintisiz anahtar değişimi:
+//  1. Yeni anahtar dizine eklenir, aktif anahtar değişmez. Yeni anahtar JWKS'te
+//     yayınlanır; servisler önbelleklerini yeniler (ya da bilinmeyen kid görünce çeker).
+//  2. JWKS önbellek süresi dolduktan sonra active_kid dosyası yeni anahtara çevrilir.
+//  3. Eski anahtarla imzalanmış refresh tokenların süresi (RefreshTokenTTL) dolunca
+//     eski anahtar dizinden silinir.
+//
+// Her adımda LoadDir (kimlik servisinde SIGHUP) veya sıralı yeniden başlatma yeterlidir.
+type Signer struct {
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewSigner anahtar halkası oluşturur; activeID boşsa kimliği alfabetik olarak en büyük anahtar imzalar
+func NewSigner(activeID string, keys ...*Key) (*Signer, error) {
+	s := &Signer{}
+	if err := s.Replace(activeID, keys...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replace halkayı yeni anahtarlarla değiştirir; hata durumunda eski halka korunur
+func (s *Signer) Replace(activeID string, keys ...*Key) error {
+	if len(keys) == 0 {
+		return errors.New("en az bir imza anahtarı gerekli")
+	}
+	ring := make(map[string]*Key, len(keys))
+	for _, k := range keys {
+		if _, dup := ring[k.ID]; dup {
+			return fmt.Errorf("anahtar kimliği tekrar ediyor: %s", k.ID)
+		}
+		ring[k.ID] = k
+	}
+	if activeID == "" {
+		for id := range ring {
+			if id > activeID {
+				activeID = id
+			}
+		}
+	}
+	active, ok := ring[activeID]
+	if !ok {
+		return fmt.Errorf("aktif anahtar bulunamadı: %s", activeID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = active
+	s.keys = ring
+	return nil
+}
+
+// ActiveKeyID imzalamada kullanılan anahtarın kimliği
+func (s *Signer) ActiveKeyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active.ID
+}
+
+// Sign içeriği aktif anahtarla imzalar ve başlığa kid ekler
+func (s *Signer) Sign(c *Claims) (string, error) {
+	s.mu.RLock()
+	key := s.active
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method(), c)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// PublicKey halkadaki anahtarın açık kısmı (KeySource)
+func (s *Signer) PublicKey(kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return k.Public(), nil
+}
+
+// JWKS halkadaki tüm anahtarların açık kısımları, kid sırasıyla
+func (s *Signer) JWKS() JWKSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+	for _, k := range s.keys {
+		jwk, err := NewJWK(k.ID, k.Public())
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// LoadKeyDir dizindeki *.pem dosyalarını okur; dosya adı (uzantısız) anahtar kimliği olur
+func LoadKeyDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePrivateKeyPEM(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s dizininde imza anahtarı yok", dir)
+	}
+	return keys, nil
+}
+
+// ActiveKeyFile anahtar dizininde imzalayan anahtarın kimliğini tutan dosya
+const ActiveKeyFile = "active_kid"
+
+// NewSignerFromEnv JWT_KEYS_DIR dizinindeki anahtarlarla halka kurar. İmzalayan anahtar
+// dizindeki active_kid dosyasından, yoksa JWT_ACTIVE_KID'den okunur. Dizin tanımlı değilse
+// (yerel geliştirme) geçici bir Ed25519 anahtarı üretilir; bu anahtarla verilen tokenlar
+// servis yeniden başlayınca geçersizleşir.
+func NewSignerFromEnv() (*Signer, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Println("UYARI: JWT_KEYS_DIR tanımlı değil, geçici imza anahtarı üretiliyor")
+		key, err := GenerateKey("dev")
+		if err != nil {
+			return nil, err
+		}
+		return NewSigner("", key)
+	}
+	s := &Signer{}
+	if err := s.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadDir halkayı dizindeki anahtarlarla değiştirir. Çalışan serviste anahtar eklemek,
+// aktif anahtarı değiştirmek veya eski anahtarı kaldırmak için dizin güncellenip
+// LoadDir yeniden çağrılır.
+func (s *Signer) LoadDir(dir string) error {
+	keys, err := LoadKeyDir(dir)
+	if err != nil {
+		return err
+	}
+	activeID := os.Getenv("JWT_ACTIVE_KID")
+	if data, err := os.ReadFile(filepath.Join(dir, ActiveKeyFile)); err == nil {
+		activeID = strings.TrimSpace(string(data))
+	}
+	return s.Replace(activeID, keys...)
+}
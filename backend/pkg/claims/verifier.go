@@ -0,0 +1,163 @@
+package claims
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySource kid'e göre doğrulama anahtarı sağlar (Signer veya RemoteKeySet)
+type KeySource interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// Parse tokenın imzasını, süresini, vereni ve türünü doğrular. Yalnızca RS256 ve
+// EdDSA kabul edilir; HMAC ve "none" reddedilir.
+func Parse(tokenString string, keys KeySource, use string) (*Claims, error) {
+	return ParseAt(tokenString, keys, use, time.Now())
+}
+
+// ParseAt Parse gibi doğrular; süre kontrolü verilen zamana göre yapılır (tokenı kendi
+// saatiyle üreten servisler için)
+func ParseAt(tokenString string, keys KeySource, use string, now time.Time) (*Claims, error) {
+	c := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, c, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrUnknownKey
+		}
+		return keys.PublicKey(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if c.TokenUse != use || c.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return c, nil
+}
+
+// ParseAccess access tokenı doğrular
+func ParseAccess(tokenString string, keys KeySource) (*Claims, error) {
+	return Parse(tokenString, keys, TokenAccess)
+}
+
+// RemoteKeySet kimlik servisinin JWKS'ini çeker ve önbellekte tutar. Önbellek TTL
+// dolunca veya bilinmeyen bir kid geldiğinde yenilenir; yenileme denemeleri
+// minRefresh aralığıyla sınırlıdır. Kimlik servisine ulaşılamazsa bilinen anahtarlarla
+// doğrulamaya devam edilir.
+type RemoteKeySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	now         func() time.Time
+}
+
+// NewRemoteKeySet yeni JWKS önbelleği oluşturur (TTL 10 dk, en sık 30 sn'de bir yenileme)
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        10 * time.Minute,
+		minRefresh: 30 * time.Second,
+		keys:       map[string]crypto.PublicKey{},
+		now:        time.Now,
+	}
+}
+
+// DefaultJWKSURL AUTH_JWKS_URL tanımlı değilse kullanılan adres
+const DefaultJWKSURL = "http://identity-service:8081/.well-known/jwks.json"
+
+// NewRemoteKeySetFromEnv AUTH_JWKS_URL adresinden JWKS önbelleği oluşturur
+func NewRemoteKeySetFromEnv() *RemoteKeySet {
+	url := os.Getenv("AUTH_JWKS_URL")
+	if url == "" {
+		url = DefaultJWKSURL
+	}
+	return NewRemoteKeySet(url)
+}
+
+// PublicKey kid'e ait açık anahtar
+func (r *RemoteKeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	fresh := r.now().Sub(r.fetchedAt) < r.ttl
+	r.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := r.refresh(); err != nil && !ok {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if key, ok := r.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (r *RemoteKeySet) refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastAttempt) < r.minRefresh {
+		return nil
+	}
+	r.lastAttempt = now
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("JWKS alınamadı: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS alınamadı: HTTP %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("JWKS çözümlenemedi: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	r.keys = keys
+	r.fetchedAt = now
+	return nil
+}
@@ -6,11 +6,21 @@ import (
 	"os"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var pool *pgxpool.Pool
 
+// Querier pgxpool.Pool ve pgx.Tx için ortak sorgu arayüzü.
+// Paylaşılan paketler bu arayüzü alarak çağıranın transaction'ına katılabilir.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // Config veritabanı bağlantı ayarları
 type Config struct {
 	Host     string
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TenantRole satır düzeyi güvenlik (RLS) politikalarına tabi veritabanı rolü (migration 022).
+// Tabloların sahibi olan uygulama kullanıcısı politikalara tabi değildir; istek kapsamındaki
+// sorgular InTenantTx veya Conn ile bu role geçerek çalışır.
+const TenantRole = "siteeksen_tenant"
+
+// ErrNoTenantScope istek context'inde kiracı kapsamı yok. Kapsamsız sorgu çalıştırılmaz.
+var ErrNoTenantScope = errors.New("kiracı kapsamı yok")
+
+// Scope isteğin kiracısı ve kullanıcısı; InTenantTx bunları app.tenant_id ve app.user_id
+// oturum ayarlarına yazar
+type Scope struct {
+	TenantID string
+	UserID   string
+}
+
+type scopeKey struct{}
+
+// WithScope kapsamı context'e ekler (tenant.DatabaseScope middleware'i)
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext context'teki kapsam
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok && scope.TenantID != ""
+}
+
+// DB sorgu çalıştırıp transaction başlatabilen bağlantı (pgxpool.Pool, pgx.Tx)
+type DB interface {
+	Querier
+	TxBeginner
+}
+
+// Conn istek kapsamındaki repository sorgularının bağlantısı. Context'te kiracı kapsamı varsa
+// her sorgu (ve Begin ile açılan transaction) InTenantTx gibi TenantRole'de, kiracının
+// ayarlarıyla ayrı bir transaction'da çalışır; RLS politikaları uygulanır. İstek
+// tenant.DatabaseScope'tan geçmiş ama kiracısı çözülememişse sorgular ErrNoTenantScope ile
+// reddedilir. Kapsam hiç yoksa (arka plan işleri, ödeme callback'i) db olduğu gibi döner.
+func Conn(ctx context.Context, db DB) DB {
+	scope, requested := ctx.Value(scopeKey{}).(Scope)
+	switch {
+	case !requested:
+		return db
+	case scope.TenantID == "":
+		return unscopedConn{}
+	default:
+		return tenantConn{db: db, scope: scope}
+	}
+}
+
+// TxBeginner transaction başlatabilen bağlantı (pgxpool.Pool, pgx.Conn)
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTenantTx fn'i context'teki kiracı kapsamıyla bir transaction içinde çalıştırır. Transaction
+// TenantRole'e geçer ve app.tenant_id / app.user_id ayarlanır; böylece fn'deki sorgular yalnızca
+// kiracının sitelerine ait satırları görür ve başka kiracının satırına yazamaz. fn hata
+// dönerse transaction geri alınır. Kapsam yoksa ErrNoTenantScope döner.
+func InTenantTx(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error) error {
+	scope, ok := ScopeFromContext(ctx)
+	if !ok {
+		return ErrNoTenantScope
+	}
+
+	tx, err := beginTenantTx(ctx, db, scope)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// beginTenantTx TenantRole'e geçmiş, kiracı ve kullanıcı ayarları yapılmış transaction açar.
+// Ayarlar transaction'a yereldir; bağlantı havuza sahibin yetkileriyle döner.
+func beginTenantTx(ctx context.Context, db TxBeginner, scope Scope) (pgx.Tx, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `SELECT set_config('role', $1, true), set_config('app.tenant_id', $2, true), set_config('app.user_id', $3, true)`,
+		TenantRole, scope.TenantID, scope.UserID); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// tenantConn her sorguyu kiracı kapsamlı kendi transaction'ında çalıştırır. Transaction
+// Exec'te hemen, Query'de satırlar kapandığında, QueryRow'da Scan sonrasında tamamlanır.
+type tenantConn struct {
+	db    TxBeginner
+	scope Scope
+}
+
+func (c tenantConn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return beginTenantTx(ctx, c.db, c.scope)
+}
+
+func (c tenantConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return tag, err
+	}
+	return tag, tx.Commit(ctx)
+}
+
+func (c tenantConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return &tenantRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+func (c tenantConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return errRow{err}
+	}
+	return tenantRow{Row: tx.QueryRow(ctx, sql, args...), ctx: ctx, tx: tx}
+}
+
+// tenantRows satırlar bittiğinde veya kapatıldığında transaction'ı tamamlar; commit hatası Err'de döner
+type tenantRows struct {
+	pgx.Rows
+	ctx  context.Context
+	tx   pgx.Tx
+	err  error
+	done bool
+}
+
+func (r *tenantRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *tenantRows) Close() {
+	r.finish()
+}
+
+func (r *tenantRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+	return r.err
+}
+
+func (r *tenantRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.Rows.Close()
+	if r.Rows.Err() != nil {
+		r.tx.Rollback(r.ctx)
+		return
+	}
+	r.err = r.tx.Commit(r.ctx)
+}
+
+type tenantRow struct {
+	pgx.Row
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (r tenantRow) Scan(dest ...any) error {
+	if err := r.Row.Scan(dest...); err != nil {
+		r.tx.Rollback(r.ctx)
+		return err
+	}
+	return r.tx.Commit(r.ctx)
+}
+
+// unscopedConn kiracısı çözülemeyen isteğin bağlantısı; hiçbir sorgu çalıştırılmaz
+type unscopedConn struct{}
+
+func (unscopedConn) Begin(context.Context) (pgx.Tx, error) {
+	return nil, ErrNoTenantScope
+}
+
+func (unscopedConn) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrNoTenantScope
+}
+
+func (unscopedConn) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, ErrNoTenantScope
+}
+
+func (unscopedConn) QueryRow(context.Context, string, ...any) pgx.Row {
+	return errRow{ErrNoTenantScope}
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
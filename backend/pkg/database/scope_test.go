@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeFromContext(t *testing.T) {
+	_, ok := ScopeFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = ScopeFromContext(WithScope(context.Background(), Scope{UserID: "u1"}))
+	assert.False(t, ok, "kiracısız kapsam geçersiz")
+
+	scope, ok := ScopeFromContext(WithScope(context.Background(), Scope{TenantID: "t1", UserID: "u1"}))
+	assert.True(t, ok)
+	assert.Equal(t, Scope{TenantID: "t1", UserID: "u1"}, scope)
+}
+
+// Kapsam yoksa transaction hiç açılmaz
+func TestInTenantTx_RequiresScope(t *testing.T) {
+	called := false
+	err := InTenantTx(context.Background(), nil, func(tx pgx.Tx) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrNoTenantScope)
+	assert.False(t, called)
+}
+
+// fakeDB açılan transaction'ları ve çalıştırılan sorguları kaydeder
+type fakeDB struct {
+	txs []*fakeTx
+}
+
+func (db *fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx := &fakeTx{}
+	db.txs = append(db.txs, tx)
+	return tx, nil
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("kapsamlı sorgu havuzda çalıştı")
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, errors.New("kapsamlı sorgu havuzda çalıştı")
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return errRow{errors.New("kapsamlı sorgu havuzda çalıştı")}
+}
+
+type fakeTx struct {
+	pgx.Tx
+	sql        []string
+	args       [][]any
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) record(sql string, args []any) {
+	tx.sql = append(tx.sql, sql)
+	tx.args = append(tx.args, args)
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.record(sql, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.record(sql, args)
+	return &fakeRows{left: 2}, nil
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	tx.record(sql, args)
+	if strings.Contains(sql, "yok") {
+		return errRow{pgx.ErrNoRows}
+	}
+	return errRow{}
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	if !tx.rolledBack {
+		tx.committed = true
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+type fakeRows struct {
+	pgx.Rows
+	left   int
+	closed bool
+}
+
+func (r *fakeRows) Next() bool {
+	if r.left == 0 {
+		r.closed = true
+		return false
+	}
+	r.left--
+	return true
+}
+
+func (r *fakeRows) Close()     { r.closed = true }
+func (r *fakeRows) Err() error { return nil }
+
+// Kapsam yoksa (arka plan işi) havuz olduğu gibi kullanılır
+func TestConn_WithoutScope(t *testing.T) {
+	db := &fakeDB{}
+	assert.Same(t, db, Conn(context.Background(), db))
+}
+
+// Kiracısı çözülemeyen istekte hiçbir sorgu çalışmaz
+func TestConn_UnresolvedTenant(t *testing.T) {
+	db := &fakeDB{}
+	conn := Conn(WithScope(context.Background(), Scope{UserID: "u1"}), db)
+	ctx := context.Background()
+
+	_, err := conn.Exec(ctx, "DELETE FROM units")
+	assert.ErrorIs(t, err, ErrNoTenantScope)
+	_, err = conn.Query(ctx, "SELECT id FROM units")
+	assert.ErrorIs(t, err, ErrNoTenantScope)
+	assert.ErrorIs(t, conn.QueryRow(ctx, "SELECT 1").Scan(), ErrNoTenantScope)
+	_, err = conn.Begin(ctx)
+	assert.ErrorIs(t, err, ErrNoTenantScope)
+	assert.Empty(t, db.txs)
+}
+
+// Her sorgu kiracı rolüne geçmiş kendi transaction'ında çalışır ve sonunda tamamlanır
+func TestConn_TenantScope(t *testing.T) {
+	ctx := WithScope(context.Background(), Scope{TenantID: "t1", UserID: "u1"})
+	db := &fakeDB{}
+	conn := Conn(ctx, db)
+
+	_, err := conn.Exec(ctx, "UPDATE units SET share_ratio = 1")
+	require.NoError(t, err)
+	require.Len(t, db.txs, 1)
+	tx := db.txs[0]
+	require.Len(t, tx.sql, 2)
+	assert.Contains(t, tx.sql[0], "set_config('role'")
+	assert.Equal(t, []any{TenantRole, "t1", "u1"}, tx.args[0])
+	assert.Equal(t, "UPDATE units SET share_ratio = 1", tx.sql[1])
+	assert.True(t, tx.committed)
+
+	// Query: transaction satırlar bitince tamamlanır
+	rows, err := conn.Query(ctx, "SELECT id FROM units")
+	require.NoError(t, err)
+	tx = db.txs[1]
+	assert.True(t, rows.Next())
+	assert.False(t, tx.committed, "satırlar okunurken transaction açık kalır")
+	assert.True(t, rows.Next())
+	assert.False(t, rows.Next())
+	assert.True(t, tx.committed)
+	rows.Close()
+	require.NoError(t, rows.Err())
+
+	// Erken kapatılan satırlar da transaction'ı tamamlar
+	rows, err = conn.Query(ctx, "SELECT id FROM units")
+	require.NoError(t, err)
+	rows.Close()
+	assert.True(t, db.txs[2].committed)
+
+	// QueryRow: hata olduğu gibi döner, transaction geri alınır
+	require.NoError(t, conn.QueryRow(ctx, "SELECT 1").Scan())
+	assert.True(t, db.txs[3].committed)
+	assert.ErrorIs(t, conn.QueryRow(ctx, "SELECT yok").Scan(), pgx.ErrNoRows)
+	assert.True(t, db.txs[4].rolledBack)
+
+	// Begin çağıranın yönettiği kapsamlı transaction döner
+	scoped, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	assert.Same(t, db.txs[5], scoped)
+	assert.Contains(t, db.txs[5].sql[0], "set_config('role'")
+	assert.False(t, db.txs[5].committed)
+}
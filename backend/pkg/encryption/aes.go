@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
+	"strings"
 )
 
 // Service AES-256 şifreleme servisi
@@ -85,10 +86,22 @@ func Hash(data string) string {
 	return base64.StdEncoding.EncodeToString(hash[:])
 }
 
-// MaskTCKN TCKN'yi maskeler (****12345678)
+// MaskTCKN TCKN'nin yalnızca son dört hanesini bırakır (*******5678)
 func MaskTCKN(tckn string) string {
 	if len(tckn) < 4 {
 		return "****"
 	}
-	return "****" + tckn[len(tckn)-8:]
+	return strings.Repeat("*", len(tckn)-4) + tckn[len(tckn)-4:]
+}
+
+// MaskPhone telefonun ülke kodunu ve son dört hanesini bırakır (+90******4567)
+func MaskPhone(phone string) string {
+	if len(phone) <= 4 {
+		return "****"
+	}
+	prefix := ""
+	if strings.HasPrefix(phone, "+90") && len(phone) > 7 {
+		prefix, phone = "+90", phone[3:]
+	}
+	return prefix + strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
 }
@@ -0,0 +1,171 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownKeyVersion şifreli veri halkada olmayan bir anahtar sürümüyle şifrelenmiş
+var ErrUnknownKeyVersion = errors.New("bilinmeyen şifreleme anahtarı sürümü")
+
+// Keyring sürümlü AES-256-GCM anahtarları. Yeni veri aktif sürümle şifrelenir; eski
+// sürümler yalnızca çözmek için tutulur. Şifreli metin "v<sürüm>:<base64>" biçimindedir,
+// böylece anahtar değiştirildiğinde hangi kayıtların yeniden şifreleneceği bilinir.
+type Keyring struct {
+	active int
+	keys   map[int]*Service
+}
+
+// NewKeyring base64 anahtarlardan halka oluşturur; aktif sürüm halkada olmalı
+func NewKeyring(active int, keys map[int]string) (*Keyring, error) {
+	k := &Keyring{active: active, keys: make(map[int]*Service, len(keys))}
+	for version, key := range keys {
+		if version < 1 {
+			return nil, fmt.Errorf("geçersiz anahtar sürümü: %d", version)
+		}
+		svc, err := NewService(key)
+		if err != nil {
+			return nil, fmt.Errorf("anahtar v%d: %w", version, err)
+		}
+		k.keys[version] = svc
+	}
+	if _, ok := k.keys[active]; !ok {
+		return nil, fmt.Errorf("aktif anahtar sürümü (v%d) halkada yok", active)
+	}
+	return k, nil
+}
+
+// ActiveVersion yeni verinin şifrelendiği anahtar sürümü
+func (k *Keyring) ActiveVersion() int {
+	return k.active
+}
+
+// Encrypt metni aktif anahtarla şifreler
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	ciphertext, err := k.keys[k.active].Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return "v" + strconv.Itoa(k.active) + ":" + ciphertext, nil
+}
+
+// Decrypt şifreli metni sürümündeki anahtarla çözer. Sürüm öneki olmayan metin
+// Service.Encrypt çıktısı kabul edilir ve v1 anahtarıyla çözülür.
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	version, data := splitVersion(ciphertext)
+	svc, ok := k.keys[version]
+	if !ok {
+		return "", ErrUnknownKeyVersion
+	}
+	return svc.Decrypt(data)
+}
+
+// KeyVersion şifreli metnin anahtar sürümü
+func KeyVersion(ciphertext string) int {
+	version, _ := splitVersion(ciphertext)
+	return version
+}
+
+func splitVersion(ciphertext string) (int, string) {
+	if strings.HasPrefix(ciphertext, "v") {
+		if i := strings.IndexByte(ciphertext, ':'); i > 1 {
+			if version, err := strconv.Atoi(ciphertext[1:i]); err == nil {
+				return version, ciphertext[i+1:]
+			}
+		}
+	}
+	return 1, ciphertext
+}
+
+// BlindIndex şifreli alanlarda eşitlik araması için anahtarlı deterministik özet
+// (HMAC-SHA256). Düz SHA-256'dan farkı: 10^10 olasılıklı telefon veya TCKN uzayı
+// anahtar bilinmeden taranamaz. Anahtar şifreleme anahtarından ayrıdır ve
+// değiştirilirse tüm özetlerin yeniden hesaplanması gerekir.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex base64 anahtardan özetleyici oluşturur (en az 32 byte)
+func NewBlindIndex(keyBase64 string) (*BlindIndex, error) {
+	key, err := base64.StdEncoding.DecodeString(keyBase64)
+	if err != nil {
+		return nil, errors.New("geçersiz base64 anahtar")
+	}
+	if len(key) < 32 {
+		return nil, errors.New("özet anahtarı en az 32 byte olmalı")
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// Hash değerin alan türüne (ör. "phone", "tckn") bağlı hex özeti. Aynı değer farklı
+// alanlarda farklı özet üretir.
+func (b *BlindIndex) Hash(field, value string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(field + "|" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PIICipher kişisel veri alanlarının şifrelenmesi ve aranabilir özetleri
+type PIICipher struct {
+	*Keyring
+	*BlindIndex
+}
+
+// NewPIICipherFromEnv anahtarları ortamdan okur:
+//
+//	PII_ENCRYPTION_KEYS=1:<base64>,2:<base64>   sürümlü AES-256 anahtarları
+//	PII_ACTIVE_KEY_VERSION=2                    yeni verinin şifrelendiği sürüm (varsayılan en büyük)
+//	PII_HASH_KEY=<base64>                       arama özetleri için HMAC anahtarı
+//
+// Şifreli veri anahtarsız okunamayacağı için geçici anahtar üretilmez.
+func NewPIICipherFromEnv() (*PIICipher, error) {
+	raw := os.Getenv("PII_ENCRYPTION_KEYS")
+	if raw == "" {
+		return nil, errors.New("PII_ENCRYPTION_KEYS tanımlı değil")
+	}
+	keys := map[int]string{}
+	var versions []int
+	for _, entry := range strings.Split(raw, ",") {
+		version, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("PII_ENCRYPTION_KEYS girdisi <sürüm>:<anahtar> biçiminde olmalı: %q", entry)
+		}
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz anahtar sürümü: %q", version)
+		}
+		keys[v] = key
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	active := versions[len(versions)-1]
+	if v := os.Getenv("PII_ACTIVE_KEY_VERSION"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz PII_ACTIVE_KEY_VERSION: %q", v)
+		}
+		active = parsed
+	}
+	keyring, err := NewKeyring(active, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	hashKey := os.Getenv("PII_HASH_KEY")
+	if hashKey == "" {
+		return nil, errors.New("PII_HASH_KEY tanımlı değil")
+	}
+	index, err := NewBlindIndex(hashKey)
+	if err != nil {
+		return nil, fmt.Errorf("PII_HASH_KEY: %w", err)
+	}
+	return &PIICipher{Keyring: keyring, BlindIndex: index}, nil
+}
@@ -0,0 +1,110 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomKey(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	k1, k2 := randomKey(t), randomKey(t)
+
+	old, err := NewKeyring(1, map[int]string{1: k1})
+	require.NoError(t, err)
+	v1, err := old.Encrypt("+905551234567")
+	require.NoError(t, err)
+	assert.Equal(t, 1, KeyVersion(v1))
+
+	// Eski sürüm yalnızca çözmek için halkada kalır
+	rotated, err := NewKeyring(2, map[int]string{1: k1, 2: k2})
+	require.NoError(t, err)
+	plain, err := rotated.Decrypt(v1)
+	require.NoError(t, err)
+	assert.Equal(t, "+905551234567", plain)
+	v2, err := rotated.Encrypt(plain)
+	require.NoError(t, err)
+	assert.Equal(t, 2, KeyVersion(v2))
+
+	_, err = old.Decrypt(v2)
+	assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+
+	// Sürüm öneki olmayan eski Service çıktısı v1 kabul edilir
+	svc, err := NewService(k1)
+	require.NoError(t, err)
+	legacy, err := svc.Encrypt("10000000146")
+	require.NoError(t, err)
+	plain, err = rotated.Decrypt(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "10000000146", plain)
+
+	_, err = NewKeyring(3, map[int]string{1: k1})
+	assert.Error(t, err, "aktif sürüm halkada olmalı")
+}
+
+func TestBlindIndex(t *testing.T) {
+	key := randomKey(t)
+	index, err := NewBlindIndex(key)
+	require.NoError(t, err)
+
+	assert.Equal(t, index.Hash("phone", "+905551234567"), index.Hash("phone", "+905551234567"))
+	assert.NotEqual(t, index.Hash("phone", "10000000146"), index.Hash("tckn", "10000000146"))
+	assert.Len(t, index.Hash("tckn", "10000000146"), 64)
+
+	other, err := NewBlindIndex(randomKey(t))
+	require.NoError(t, err)
+	assert.NotEqual(t, index.Hash("phone", "+905551234567"), other.Hash("phone", "+905551234567"))
+
+	_, err = NewBlindIndex(base64.StdEncoding.EncodeToString([]byte("kisa")))
+	assert.Error(t, err)
+}
+
+func TestNewPIICipherFromEnv(t *testing.T) {
+	k1, k2 := randomKey(t), randomKey(t)
+	t.Setenv("PII_ENCRYPTION_KEYS", "1:"+k1+", 2:"+k2)
+	t.Setenv("PII_HASH_KEY", randomKey(t))
+
+	pii, err := NewPIICipherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2, pii.ActiveVersion(), "varsayılan en büyük sürüm")
+
+	t.Setenv("PII_ACTIVE_KEY_VERSION", "1")
+	pii, err = NewPIICipherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 1, pii.ActiveVersion())
+
+	t.Setenv("PII_HASH_KEY", "")
+	_, err = NewPIICipherFromEnv()
+	assert.Error(t, err)
+}
+
+func TestValidateTCKN(t *testing.T) {
+	for tckn, valid := range map[string]bool{
+		"10000000146": true,
+		"10000000147": false, // 11. hane
+		"10000000156": false, // 10. hane
+		"00000000000": false, // ilk hane sıfır
+		"1000000014":  false,
+		"1000000014a": false,
+		"":            false,
+	} {
+		assert.Equal(t, valid, ValidateTCKN(tckn), tckn)
+	}
+}
+
+func TestMasks(t *testing.T) {
+	assert.Equal(t, "*******0146", MaskTCKN("10000000146"))
+	assert.Equal(t, "****", MaskTCKN("123"))
+	assert.Equal(t, "+90******4567", MaskPhone("+905551234567"))
+	assert.Equal(t, "******4567", MaskPhone("5551234567"))
+}
@@ -0,0 +1,27 @@
+package encryption
+
+// ValidateTCKN T.C. kimlik numarasının biçimini ve kontrol hanelerini doğrular: 11 hane,
+// ilk hane sıfır değil; 10. hane (tek sıradakilerin toplamı×7 − çift sıradakilerin
+// toplamı) mod 10, 11. hane ilk on hanenin toplamı mod 10.
+func ValidateTCKN(tckn string) bool {
+	if len(tckn) != 11 || tckn[0] == '0' {
+		return false
+	}
+	var d [11]int
+	for i, c := range tckn {
+		if c < '0' || c > '9' {
+			return false
+		}
+		d[i] = int(c - '0')
+	}
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+	sum := 0
+	for _, v := range d[:10] {
+		sum += v
+	}
+	return sum%10 == d[10]
+}
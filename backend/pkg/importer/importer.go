@@ -0,0 +1,117 @@
+// Package importer yeni sitenin eski yazılımdan taşınması için Excel/CSV listelerini okur:
+// daireler, sakinler, devir bakiyeleri ve sayaçlar. Dosya satırları hücre düzeyinde
+// doğrulanıp kayıtlara çevrilir; kayıtlar Session ile doğal anahtarlarına göre (daire: blok +
+// kapı no, sakin: daire + telefon + rol, devir: daire, sayaç: seri no) eklenir veya güncellenir,
+// böylece aynı dosya tekrar yüklendiğinde değişiklik olmaz.
+package importer
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kind içe aktarılan liste türü
+type Kind string
+
+const (
+	KindUnits     Kind = "units"
+	KindResidents Kind = "residents"
+	KindBalances  Kind = "balances"
+	KindMeters    Kind = "meters"
+)
+
+// Kinds desteklenen liste türleri (yükleme sırasıyla: sakin, devir ve sayaç daireye bağlanır)
+var Kinds = []Kind{KindUnits, KindResidents, KindBalances, KindMeters}
+
+// ParseKind URL'deki türü doğrular
+func ParseKind(s string) (Kind, bool) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Action satırın veritabanına etkisi
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+	ActionFailed    Action = "failed"
+)
+
+// RowError dosyadaki hatalı satır
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// InvalidError veritabanına bağlı kontrolde reddedilen satır (daire yok, seri no başka sitede
+// vb.). Session bu hatayı döndüğünde transaction kullanılabilir kalır; diğer hatalar yüklemeyi
+// durdurur.
+type InvalidError struct {
+	Message string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Message
+}
+
+// Invalid satırı reddeden InvalidError oluşturur
+func Invalid(format string, args ...any) error {
+	return &InvalidError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Session tek transaction'da çalışan yükleme. Yöntemler kaydı doğal anahtarıyla arar; yoksa
+// ekler, alanları farklıysa günceller. Commit çağrılmazsa hiçbir değişiklik kalmaz.
+type Session interface {
+	Unit(ctx context.Context, u *UnitRecord) (Action, error)
+	Resident(ctx context.Context, r *ResidentRecord) (Action, error)
+	Balance(ctx context.Context, b *BalanceRecord) (Action, error)
+	Meter(ctx context.Context, m *MeterRecord) (Action, error)
+	// Added yüklemede eklenen plan kaynağı sayısı (tenant.ResourceUnits, tenant.ResourceUsers)
+	Added(resource string) int
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// RowResult satırın önizleme / yükleme sonucu
+type RowResult struct {
+	Row    int      `json:"row"`
+	Key    string   `json:"key,omitempty"` // Doğal anahtarın okunur hali (ör. "A-12")
+	Action Action   `json:"action"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Result yüklemenin özeti. Applied false ise (önizleme veya hatalı satır) hiçbir değişiklik
+// yazılmamıştır.
+type Result struct {
+	Kind      Kind        `json:"kind"`
+	DryRun    bool        `json:"dry_run"`
+	Applied   bool        `json:"applied"`
+	Total     int         `json:"total"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Failed    int         `json:"failed"`
+	Rows      []RowResult `json:"rows"`
+}
+
+// Add satır sonucunu özete ekler
+func (r *Result) Add(row RowResult) {
+	r.Total++
+	switch row.Action {
+	case ActionCreated:
+		r.Created++
+	case ActionUpdated:
+		r.Updated++
+	case ActionUnchanged:
+		r.Unchanged++
+	case ActionFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTable_CSV(t *testing.T) {
+	// Türkçe Excel'in yazdığı biçim: BOM, noktalı virgül, başlıkta farklı yazım
+	data := "\xef\xbb\xbfKAPI NO.;Blok;Kat;Arsa Payı\n\n12;A;3;12,5\n"
+	table, err := ReadTable(strings.NewReader(data), UnitColumns)
+	require.NoError(t, err)
+	require.Len(t, table.Rows, 1)
+	row := table.Rows[0]
+	assert.Equal(t, 3, row.Num)
+	assert.Equal(t, "12", table.Value(row, "door"))
+	assert.Equal(t, "12,5", table.Value(row, "share"))
+	assert.Empty(t, table.Value(row, "gross"), "olmayan sütun boş döner")
+
+	_, err = ReadTable(strings.NewReader("Blok,Kat\nA,1\n"), UnitColumns)
+	require.ErrorIs(t, err, ErrInvalidFile)
+	assert.Contains(t, err.Error(), "Kapı No, Arsa Payı")
+
+	_, err = ReadTable(strings.NewReader("\n ; \n"), UnitColumns)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	_, err = ReadTable(bytes.NewReader([]byte{'K', 'a', 'p', 0xfd, '\n'}), UnitColumns)
+	assert.ErrorIs(t, err, ErrInvalidFile, "UTF-8 olmayan CSV")
+}
+
+func TestTemplateRoundTrip(t *testing.T) {
+	for _, kind := range Kinds {
+		data, err := Template(kind)
+		require.NoError(t, err, kind)
+		table, err := ReadTable(bytes.NewReader(data), Columns(kind))
+		require.NoError(t, err, kind)
+		assert.Len(t, table.Rows, 1, kind)
+	}
+
+	data, _ := Template(KindMeters)
+	meters, errs, err := ReadMeters(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Empty(t, errs)
+	require.Len(t, meters, 1)
+	m := meters[0]
+	assert.Equal(t, "HEAT", m.MeterType)
+	assert.Equal(t, "HM-100234", m.SerialNumber)
+	assert.Equal(t, time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC), m.InstallationDate)
+	require.NotNil(t, m.InitialReading)
+	assert.Equal(t, 1520.5, *m.InitialReading)
+
+	_, err = Template("unknown")
+	assert.Error(t, err)
+}
+
+func TestReadUnits(t *testing.T) {
+	data := "Blok;Kat;Kapı No;Arsa Payı;Brüt m²;Bölüm Tipi;Ticari\n" +
+		"A;Zemin;1;10;95,5;Dükkan;\n" +
+		";2;5;12,25;;;evet\n" +
+		"A;ikinci;;0;-3;Villa;belki\n"
+	units, errs, err := ReadUnits(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, units, 2)
+	assert.Equal(t, UnitRecord{Row: 2, Block: "A", Floor: 0, DoorNumber: "1", ShareRatio: 10, GrossAreaM2: 95.5,
+		UnitType: "SHOP", IsCommercial: true}, units[0])
+	assert.Equal(t, UnitRecord{Row: 3, Floor: 2, DoorNumber: "5", ShareRatio: 12.25, UnitType: "APARTMENT", IsCommercial: true}, units[1])
+
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		assert.Equal(t, 4, e.Row)
+		messages = append(messages, e.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"kapı numarası zorunlu",
+		`kat sayı olmalı: "ikinci"`,
+		"arsa payı sıfırdan büyük olmalı",
+		"alan negatif olamaz",
+		`bilinmeyen bölüm tipi "Villa"`,
+		`ticari evet veya hayır olmalı: "belki"`,
+	}, messages)
+}
+
+func TestReadResidentsBalances(t *testing.T) {
+	residents, errs, err := ReadResidents(strings.NewReader(
+		"Blok,Kapı No,Ad,Soyad,Telefon,Rol,Giriş Tarihi\n" +
+			"B,4,Ali,Demir,0532 111 22 33,Kat Maliki,15.03.2021\n" +
+			"B,5,Ayşe,,0532 111 22 34,Komşu,2021-13-01\n"))
+	require.NoError(t, err)
+	require.Len(t, residents, 1)
+	assert.Equal(t, "OWNER", residents[0].Role)
+	assert.Equal(t, "0532 111 22 33", residents[0].Phone, "telefon servis tarafından normalleştirilir")
+	assert.Equal(t, time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC), residents[0].StartDate)
+	assert.Len(t, errs, 3)
+
+	balances, errs, err := ReadBalances(strings.NewReader(
+		"Kapı No;Devir Tutarı;Devir Tarihi\n" +
+			"1;1.250,75 TL;31.12.2025\n" +
+			"2;0;31.12.2025\n" +
+			"3;-100;\n"))
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, 1250.75, balances[0].Amount)
+	assert.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), balances[0].AsOf)
+	assert.Equal(t, []RowError{
+		{Row: 3, Message: "devir tutarı sıfırdan büyük olmalı"},
+		{Row: 4, Message: "devir tutarı sıfırdan büyük olmalı"},
+		{Row: 4, Message: "devir tarihi zorunlu"},
+	}, errs)
+}
+
+func TestParseCells(t *testing.T) {
+	for value, want := range map[string]float64{"1.234,5": 1234.5, "1234.5": 1234.5, "₺ 99,90": 99.9, "12": 12} {
+		got, ok := ParseDecimal(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, want, got, value)
+	}
+	_, ok := ParseDecimal("on iki")
+	assert.False(t, ok)
+
+	date, ok := ParseDate("46022") // Excel seri numarası
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), date)
+	_, ok = ParseDate("31/12")
+	assert.False(t, ok)
+
+	kind, ok := ParseKind("meters")
+	assert.True(t, ok)
+	assert.Equal(t, KindMeters, kind)
+	_, ok = ParseKind("payments")
+	assert.False(t, ok)
+}
+
+func TestResultAdd(t *testing.T) {
+	var r Result
+	r.Add(RowResult{Row: 2, Action: ActionCreated})
+	r.Add(RowResult{Row: 3, Action: ActionUnchanged})
+	r.Add(RowResult{Row: 4, Action: ActionFailed, Errors: []string{"daire bulunamadı"}})
+	assert.Equal(t, 3, r.Total)
+	assert.Equal(t, 1, r.Created)
+	assert.Equal(t, 1, r.Unchanged)
+	assert.Equal(t, 1, r.Failed)
+	assert.Len(t, r.Rows, 3)
+}
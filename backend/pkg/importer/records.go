@@ -0,0 +1,366 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Alan uzunlukları (units, users ve meters tablolarının sütunları)
+const (
+	maxBlockName   = 50
+	maxDoorNumber  = 20
+	maxPersonName  = 100
+	maxEmail       = 200
+	maxSerial      = 50
+	maxMeterDetail = 50
+	maxDescription = 500
+)
+
+// Ortak daire sütunları: sakin, devir ve sayaç satırları daireye blok ve kapı no ile bağlanır
+var unitKeyColumns = []Column{
+	{Field: "block", Header: "Blok"},
+	{Field: "door", Header: "Kapı No", Aliases: []string{"Kapı", "Daire No"}, Required: true},
+}
+
+// UnitColumns daire listesinin sütunları
+var UnitColumns = append(append([]Column{}, unitKeyColumns...),
+	Column{Field: "floor", Header: "Kat", Required: true},
+	Column{Field: "share", Header: "Arsa Payı", Aliases: []string{"Pay"}, Required: true},
+	Column{Field: "gross", Header: "Brüt m²", Aliases: []string{"Brüt Alan"}},
+	Column{Field: "net", Header: "Net m²", Aliases: []string{"Net Alan"}},
+	Column{Field: "type", Header: "Bölüm Tipi", Aliases: []string{"Tip"}},
+	Column{Field: "commercial", Header: "Ticari"},
+)
+
+// ResidentColumns sakin listesinin sütunları
+var ResidentColumns = append(append([]Column{}, unitKeyColumns...),
+	Column{Field: "first_name", Header: "Ad", Aliases: []string{"Adı"}, Required: true},
+	Column{Field: "last_name", Header: "Soyad", Aliases: []string{"Soyadı"}, Required: true},
+	Column{Field: "phone", Header: "Telefon", Aliases: []string{"Cep Telefonu", "GSM"}, Required: true},
+	Column{Field: "email", Header: "E-posta", Aliases: []string{"Email", "Mail"}},
+	Column{Field: "role", Header: "Rol", Aliases: []string{"Sıfat", "Oturum Şekli"}, Required: true},
+	Column{Field: "start_date", Header: "Giriş Tarihi", Aliases: []string{"Taşınma Tarihi"}},
+)
+
+// BalanceColumns devir bakiyesi listesinin sütunları
+var BalanceColumns = append(append([]Column{}, unitKeyColumns...),
+	Column{Field: "amount", Header: "Devir Tutarı", Aliases: []string{"Borç", "Bakiye", "Tutar"}, Required: true},
+	Column{Field: "as_of", Header: "Devir Tarihi", Aliases: []string{"Tarih"}, Required: true},
+	Column{Field: "description", Header: "Açıklama"},
+)
+
+// MeterColumns sayaç listesinin sütunları
+var MeterColumns = append(append([]Column{}, unitKeyColumns...),
+	Column{Field: "type", Header: "Sayaç Tipi", Aliases: []string{"Tip", "Tür"}, Required: true},
+	Column{Field: "serial", Header: "Sayaç No", Aliases: []string{"Seri No", "Seri Numarası"}, Required: true},
+	Column{Field: "brand", Header: "Marka"},
+	Column{Field: "model", Header: "Model"},
+	Column{Field: "installed", Header: "Montaj Tarihi"},
+	Column{Field: "calibrated", Header: "Kalibrasyon Tarihi"},
+	Column{Field: "reading", Header: "İlk Okuma", Aliases: []string{"Endeks", "Son Okuma"}},
+	Column{Field: "reading_date", Header: "Okuma Tarihi"},
+)
+
+// Columns türün sütunları
+func Columns(kind Kind) []Column {
+	switch kind {
+	case KindUnits:
+		return UnitColumns
+	case KindResidents:
+		return ResidentColumns
+	case KindBalances:
+		return BalanceColumns
+	case KindMeters:
+		return MeterColumns
+	}
+	return nil
+}
+
+// UnitRecord daire satırı; anahtar blok + kapı no (büyük/küçük harf gözetilmez)
+type UnitRecord struct {
+	Row          int
+	Block        string
+	Floor        int
+	DoorNumber   string
+	ShareRatio   float64
+	GrossAreaM2  float64
+	NetAreaM2    float64
+	UnitType     string // APARTMENT, SHOP, OFFICE, PARKING
+	IsCommercial bool
+}
+
+// ResidentRecord sakin satırı; anahtar daire + telefon + rol
+type ResidentRecord struct {
+	Row        int
+	Block      string
+	DoorNumber string
+	FirstName  string
+	LastName   string
+	Phone      string // Dosyadaki hali; servis normalleştirir
+	Email      string
+	Role       string    // OWNER, TENANT, PROXY
+	StartDate  time.Time // Boşsa yükleme günü
+}
+
+// BalanceRecord dairenin devir borcu; anahtar daire (her dairenin tek devir bakiyesi olur)
+type BalanceRecord struct {
+	Row         int
+	Block       string
+	DoorNumber  string
+	Amount      float64
+	AsOf        time.Time
+	Description string
+}
+
+// MeterRecord sayaç satırı; anahtar seri numarası
+type MeterRecord struct {
+	Row                 int
+	Block               string
+	DoorNumber          string
+	MeterType           string // HEAT, WATER_COLD, WATER_HOT, GAS, ELECTRIC
+	SerialNumber        string
+	Brand               string
+	Model               string
+	InstallationDate    time.Time
+	LastCalibrationDate time.Time
+	InitialReading      *float64  // Sayacın devraldığı endeks; ilk okumanın önceki değeri olur
+	ReadingDate         time.Time // Boşsa montaj tarihi, o da yoksa yükleme günü
+}
+
+// UnitLabel dairenin okunur adı: "A-12" veya bloksuz "12"
+func UnitLabel(block, door string) string {
+	if block == "" {
+		return door
+	}
+	return block + "-" + door
+}
+
+// Bölüm tipi hücresinin Türkçe karşılıkları
+var unitTypeNames = map[string]string{
+	"daire":     "APARTMENT",
+	"konut":     "APARTMENT",
+	"dükkan":    "SHOP",
+	"dükkân":    "SHOP",
+	"mağaza":    "SHOP",
+	"ofis":      "OFFICE",
+	"büro":      "OFFICE",
+	"otopark":   "PARKING",
+	"apartment": "APARTMENT",
+	"shop":      "SHOP",
+	"office":    "OFFICE",
+	"parking":   "PARKING",
+}
+
+// Rol hücresinin Türkçe karşılıkları
+var roleNames = map[string]string{
+	"malik":     "OWNER",
+	"katmaliki": "OWNER",
+	"evsahibi":  "OWNER",
+	"sahibi":    "OWNER",
+	"kiracı":    "TENANT",
+	"vekil":     "PROXY",
+	"owner":     "OWNER",
+	"tenant":    "TENANT",
+	"proxy":     "PROXY",
+}
+
+// Sayaç tipi hücresinin Türkçe karşılıkları
+var meterTypeNames = map[string]string{
+	"ısı":         "HEAT",
+	"ısınma":      "HEAT",
+	"kalorimetre": "HEAT",
+	"payölçer":    "HEAT",
+	"su":          "WATER_COLD",
+	"soğuksu":     "WATER_COLD",
+	"sıcaksu":     "WATER_HOT",
+	"gaz":         "GAS",
+	"gas":         "GAS",
+	"doğalgaz":    "GAS",
+	"elektrik":    "ELECTRIC",
+	"heat":        "HEAT",
+	"watercold":   "WATER_COLD",
+	"waterhot":    "WATER_HOT",
+	"electric":    "ELECTRIC",
+}
+
+// rowChecker tek satırın hücre hatalarını toplar
+type rowChecker struct {
+	table  *Table
+	row    Row
+	errors []RowError
+}
+
+func (c *rowChecker) value(field string) string {
+	return c.table.Value(c.row, field)
+}
+
+func (c *rowChecker) fail(format string, args ...any) {
+	c.errors = append(c.errors, RowError{Row: c.row.Num, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *rowChecker) check(ok bool, format string, args ...any) {
+	if !ok {
+		c.fail(format, args...)
+	}
+}
+
+func (c *rowChecker) text(field, name string, max int, required bool) string {
+	v := c.value(field)
+	if required && v == "" {
+		c.fail("%s zorunlu", name)
+	}
+	c.check(utf8.RuneCountInString(v) <= max, "%s en fazla %d karakter olabilir", name, max)
+	return v
+}
+
+func (c *rowChecker) decimal(field, name string) (float64, bool) {
+	v := c.value(field)
+	if v == "" {
+		return 0, false
+	}
+	n, ok := ParseDecimal(v)
+	if !ok {
+		c.fail("%s sayı olmalı: %q", name, v)
+	}
+	return n, ok
+}
+
+func (c *rowChecker) date(field, name string) time.Time {
+	v := c.value(field)
+	if v == "" {
+		return time.Time{}
+	}
+	t, ok := ParseDate(v)
+	if !ok {
+		c.fail("%s GG.AA.YYYY biçiminde olmalı: %q", name, v)
+	}
+	return t
+}
+
+func (c *rowChecker) code(field, name string, names map[string]string) string {
+	v := c.value(field)
+	if v == "" {
+		return ""
+	}
+	code, ok := names[HeaderKey(v)]
+	if !ok {
+		c.fail("bilinmeyen %s %q", name, v)
+	}
+	return code
+}
+
+// unitKey satırın daire sütunları
+func (c *rowChecker) unitKey() (block, door string) {
+	return c.text("block", "blok adı", maxBlockName, false), c.text("door", "kapı numarası", maxDoorNumber, true)
+}
+
+// readRows dosyayı okur ve her satırı parse ile kayda çevirir. Hatalı satırlar kayıtlara
+// eklenmez; hataları satır numaralarıyla döner.
+func readRows[T any](r io.Reader, columns []Column, parse func(c *rowChecker) T) ([]T, []RowError, error) {
+	table, err := ReadTable(r, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	var records []T
+	var errs []RowError
+	for _, row := range table.Rows {
+		c := &rowChecker{table: table, row: row}
+		record := parse(c)
+		if len(c.errors) > 0 {
+			errs = append(errs, c.errors...)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, errs, nil
+}
+
+// ReadUnits daire listesini okur. Dükkan ve ofisler ticari sayılır.
+func ReadUnits(r io.Reader) ([]UnitRecord, []RowError, error) {
+	return readRows(r, UnitColumns, func(c *rowChecker) UnitRecord {
+		u := UnitRecord{Row: c.row.Num}
+		u.Block, u.DoorNumber = c.unitKey()
+		floor, ok := ParseFloor(c.value("floor"))
+		c.check(ok, "kat sayı olmalı: %q", c.value("floor"))
+		u.Floor = floor
+		share, ok := c.decimal("share", "arsa payı")
+		c.check(!ok || share > 0, "arsa payı sıfırdan büyük olmalı")
+		c.check(c.value("share") != "", "arsa payı zorunlu")
+		u.ShareRatio = share
+		u.GrossAreaM2, _ = c.decimal("gross", "brüt alan")
+		u.NetAreaM2, _ = c.decimal("net", "net alan")
+		c.check(u.GrossAreaM2 >= 0 && u.NetAreaM2 >= 0, "alan negatif olamaz")
+		u.UnitType = c.code("type", "bölüm tipi", unitTypeNames)
+		if u.UnitType == "" {
+			u.UnitType = "APARTMENT"
+		}
+		commercial, ok := ParseBool(c.value("commercial"))
+		c.check(ok, "ticari evet veya hayır olmalı: %q", c.value("commercial"))
+		u.IsCommercial = commercial || u.UnitType == "SHOP" || u.UnitType == "OFFICE"
+		return u
+	})
+}
+
+// ReadResidents sakin listesini okur. Telefon biçimi servis tarafından doğrulanır.
+func ReadResidents(r io.Reader) ([]ResidentRecord, []RowError, error) {
+	return readRows(r, ResidentColumns, func(c *rowChecker) ResidentRecord {
+		res := ResidentRecord{Row: c.row.Num}
+		res.Block, res.DoorNumber = c.unitKey()
+		res.FirstName = c.text("first_name", "ad", maxPersonName, true)
+		res.LastName = c.text("last_name", "soyad", maxPersonName, true)
+		res.Phone = c.text("phone", "telefon", 20, true)
+		res.Email = c.text("email", "e-posta", maxEmail, false)
+		c.check(res.Email == "" || strings.Contains(res.Email, "@"), "e-posta geçersiz: %q", res.Email)
+		res.Role = c.code("role", "rol", roleNames)
+		c.check(c.value("role") != "", "rol zorunlu (Malik, Kiracı veya Vekil)")
+		res.StartDate = c.date("start_date", "giriş tarihi")
+		return res
+	})
+}
+
+// ReadBalances devir bakiyesi listesini okur. Tutar dairenin devir tarihindeki açık borcudur;
+// alacak (avans) bakiyeleri desteklenmez.
+func ReadBalances(r io.Reader) ([]BalanceRecord, []RowError, error) {
+	return readRows(r, BalanceColumns, func(c *rowChecker) BalanceRecord {
+		b := BalanceRecord{Row: c.row.Num}
+		b.Block, b.DoorNumber = c.unitKey()
+		amount, ok := c.decimal("amount", "devir tutarı")
+		c.check(c.value("amount") != "", "devir tutarı zorunlu")
+		c.check(!ok || amount > 0, "devir tutarı sıfırdan büyük olmalı")
+		b.Amount = amount
+		b.AsOf = c.date("as_of", "devir tarihi")
+		c.check(c.value("as_of") != "", "devir tarihi zorunlu")
+		b.Description = c.text("description", "açıklama", maxDescription, false)
+		return b
+	})
+}
+
+// ReadMeters sayaç listesini okur
+func ReadMeters(r io.Reader) ([]MeterRecord, []RowError, error) {
+	return readRows(r, MeterColumns, func(c *rowChecker) MeterRecord {
+		m := MeterRecord{Row: c.row.Num}
+		m.Block, m.DoorNumber = c.unitKey()
+		m.MeterType = c.code("type", "sayaç tipi", meterTypeNames)
+		c.check(c.value("type") != "", "sayaç tipi zorunlu")
+		m.SerialNumber = c.text("serial", "sayaç no", maxSerial, true)
+		m.Brand = c.text("brand", "marka", maxMeterDetail, false)
+		m.Model = c.text("model", "model", maxMeterDetail, false)
+		m.InstallationDate = c.date("installed", "montaj tarihi")
+		m.LastCalibrationDate = c.date("calibrated", "kalibrasyon tarihi")
+		if reading, ok := c.decimal("reading", "ilk okuma"); ok {
+			c.check(reading >= 0, "ilk okuma negatif olamaz")
+			m.InitialReading = &reading
+		}
+		m.ReadingDate = c.date("reading_date", "okuma tarihi")
+		return m
+	})
+}
+
+// UnitKey dairenin karşılaştırma anahtarı; veritabanındaki eşleştirme gibi büyük/küçük harf
+// gözetmez
+func UnitKey(block, door string) string {
+	return strings.ToLower(block) + "/" + strings.ToLower(door)
+}
@@ -0,0 +1,255 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows bir dosyada okunan en fazla veri satırı
+const MaxRows = 5000
+
+// ErrInvalidFile dosya okunamadı, boş veya zorunlu sütunlar eksik
+var ErrInvalidFile = errors.New("dosya okunamadı")
+
+// Column dosya sütunu. Başlıklar HeaderKey ile büyük/küçük harf, boşluk ve noktalama farkı
+// gözetmeden Header veya Aliases ile eşleştirilir.
+type Column struct {
+	Field    string
+	Header   string // Şablondaki başlık
+	Aliases  []string
+	Required bool
+}
+
+// Table dosyanın ilk sayfası: başlık satırından sonraki dolu satırlar
+type Table struct {
+	columns map[string]int // alan → sütun
+	Rows    []Row
+}
+
+// Row veri satırı; Num dosyadaki satır numarasıdır (hata mesajlarında kullanılır)
+type Row struct {
+	Num   int
+	cells []string
+}
+
+// Has sütun dosyada var mı
+func (t *Table) Has(field string) bool {
+	_, ok := t.columns[field]
+	return ok
+}
+
+// Value satırın alandaki hücresi (boşluklar kırpılır); sütun yoksa boş
+func (t *Table) Value(row Row, field string) string {
+	col, ok := t.columns[field]
+	if !ok || col >= len(row.cells) {
+		return ""
+	}
+	return strings.TrimSpace(row.cells[col])
+}
+
+// ReadTable Excel (xlsx) veya CSV dosyasını okur. Biçim içerikten anlaşılır; CSV UTF-8
+// olmalıdır, ayırıcı noktalı virgül veya virgüldür. Excel'de ilk sayfa okunur ve hücrelerin
+// ham değeri alınır (tarihler seri numarası olarak gelir, ParseDate okur). İlk dolu satır
+// başlıktır; boş satırlar atlanır.
+func ReadTable(r io.Reader, columns []Column) (*Table, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	var rows [][]string
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		rows, err = readXLSX(data)
+	} else {
+		rows, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := -1
+	for i, row := range rows {
+		if !blank(row) {
+			header = i
+			break
+		}
+	}
+	if header == -1 {
+		return nil, fmt.Errorf("%w: dosya boş", ErrInvalidFile)
+	}
+
+	aliases := map[string]string{}
+	for _, c := range columns {
+		aliases[HeaderKey(c.Header)] = c.Field
+		for _, a := range c.Aliases {
+			aliases[HeaderKey(a)] = c.Field
+		}
+	}
+	t := &Table{columns: map[string]int{}}
+	for i, cell := range rows[header] {
+		if field, ok := aliases[HeaderKey(cell)]; ok {
+			if _, dup := t.columns[field]; !dup {
+				t.columns[field] = i
+			}
+		}
+	}
+	var missing []string
+	for _, c := range columns {
+		if c.Required && !t.Has(c.Field) {
+			missing = append(missing, c.Header)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s sütunu yok", ErrInvalidFile, strings.Join(missing, ", "))
+	}
+
+	for i := header + 1; i < len(rows); i++ {
+		if blank(rows[i]) {
+			continue
+		}
+		if len(t.Rows) == MaxRows {
+			return nil, fmt.Errorf("%w: dosya en fazla %d satır olabilir", ErrInvalidFile, MaxRows)
+		}
+		t.Rows = append(t.Rows, Row{Num: i + 1, cells: rows[i]})
+	}
+	return t, nil
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Excel dosyası açılamadı", ErrInvalidFile)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: Excel dosyasında sayfa yok", ErrInvalidFile)
+	}
+	rows, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("%w: Excel sayfası okunamadı", ErrInvalidFile)
+	}
+	return rows, nil
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: CSV dosyası UTF-8 kaydedilmeli", ErrInvalidFile)
+	}
+	// Türkçe Excel CSV'yi noktalı virgülle yazar; ayırıcı ilk satırdan anlaşılır
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	// Boş satırlar csv.Reader'da atlanır; satır numaraları dosyadakiyle aynı kalsın diye her
+	// kayıt başladığı satıra yerleştirilir
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: CSV dosyası okunamadı: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, record)
+	}
+}
+
+func blank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HeaderKey başlığı eşleştirme anahtarına çevirir: "Kapı No." → "kapıno", "Brüt m²" → "brütm2"
+func HeaderKey(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLowerSpecial(unicode.TurkishCase, header) {
+		switch {
+		case r == '²':
+			b.WriteRune('2')
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseFloor kat hücresini okur; boş hücre ve "Zemin" 0, "Bodrum" -1 sayılır
+func ParseFloor(value string) (int, bool) {
+	switch strings.ToLowerSpecial(unicode.TurkishCase, value) {
+	case "", "z", "zemin", "giriş":
+		return 0, true
+	case "b", "bodrum":
+		return -1, true
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil || n != float64(int(n)) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// ParseDecimal Türkçe ("1.234,5") veya Excel'in yazdığı ("1234.5") biçimde sayıyı okur
+func ParseDecimal(value string) (float64, bool) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "₺"), "TL"))
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	return n, err == nil
+}
+
+// dateLayouts tarih hücresinde kabul edilen biçimler
+var dateLayouts = []string{"02.01.2006", "2.1.2006", "02/01/2006", "2/1/2006", "2006-01-02"}
+
+// ParseDate "31.12.2025", "2025-12-31" veya Excel seri numarası biçimindeki tarihi okur
+func ParseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 && serial < 2958466 {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err == nil {
+			y, m, d := t.Date()
+			return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseBool evet/hayır hücresini okur; boş hücre hayır sayılır
+func ParseBool(value string) (bool, bool) {
+	switch strings.ToLowerSpecial(unicode.TurkishCase, value) {
+	case "evet", "e", "x", "1", "true", "var":
+		return true, true
+	case "", "hayır", "h", "0", "false", "yok", "-":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Şablonların sayfa adı ve örnek satırı (sütun sırasıyla)
+var templates = map[Kind]struct {
+	sheet   string
+	example []any
+}{
+	KindUnits:     {"Daireler", []any{"A", 0, "1", 12.5, 110, 95, "Daire", "Hayır"}},
+	KindResidents: {"Sakinler", []any{"A", "1", "Ayşe", "Yılmaz", "0532 123 45 67", "ayse@example.com", "Malik", "01.01.2020"}},
+	KindBalances:  {"Devir Bakiyeleri", []any{"A", "1", "1.250,00", "31.12.2025", "Eski yazılımdan devir"}},
+	KindMeters:    {"Sayaçlar", []any{"A", "1", "Isı", "HM-100234", "Techem", "C5", "01.10.2020", "01.10.2024", "1520,5", "31.12.2025"}},
+}
+
+// Template türün boş Excel şablonu: başlık satırı ve bir örnek satır
+func Template(kind Kind) ([]byte, error) {
+	tpl, ok := templates[kind]
+	if !ok {
+		return nil, fmt.Errorf("bilinmeyen liste türü %q", kind)
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", tpl.sheet); err != nil {
+		return nil, err
+	}
+	columns := Columns(kind)
+	for i, c := range columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(tpl.sheet, cell, c.Header)
+	}
+	for i, v := range tpl.example {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 2)
+		f.SetCellValue(tpl.sheet, cell, v)
+	}
+	style, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err == nil {
+		f.SetRowStyle(tpl.sheet, 1, 1, style)
+	}
+	last, _ := excelize.ColumnNumberToName(len(columns))
+	f.SetColWidth(tpl.sheet, "A", last, 16)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ===============================================
+// SAHTE Sağlayıcı (geliştirme ve testler)
+// ===============================================
+
+// FakeProvider mesajları göndermeden bellekte tutan sağlayıcı. SMS_PROVIDER=fake ile
+// yerel ortamda, doğrudan kullanılarak testlerde OTP akışını çevrimdışı denemeye yarar.
+type FakeProvider struct {
+	mu       sync.Mutex
+	messages []SendRequest
+	fail     bool
+}
+
+// NewFakeProvider yeni sahte sağlayıcı oluşturur
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Send mesajı kaydeder; SetFailing(true) sonrası hata döner
+func (f *FakeProvider) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail {
+		return nil, errors.New("sahte sağlayıcı: gönderim başarısız")
+	}
+	f.messages = append(f.messages, *req)
+	return &SendResponse{
+		Success:   true,
+		MessageID: fmt.Sprintf("fake-%d", len(f.messages)),
+	}, nil
+}
+
+// GetBalance sabit bakiye döner
+func (f *FakeProvider) GetBalance(ctx context.Context) (*BalanceResponse, error) {
+	return &BalanceResponse{Balance: 0, Currency: "TRY"}, nil
+}
+
+// GetDeliveryReport her mesajı teslim edilmiş sayar
+func (f *FakeProvider) GetDeliveryReport(ctx context.Context, messageID string) (*DeliveryReport, error) {
+	return &DeliveryReport{MessageID: messageID, Status: "delivered", DeliveredAt: time.Now()}, nil
+}
+
+// SetFailing sonraki gönderimlerin hata dönmesini sağlar
+func (f *FakeProvider) SetFailing(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+// Messages kaydedilen mesajların kopyası
+func (f *FakeProvider) Messages() []SendRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SendRequest(nil), f.messages...)
+}
+
+// LastMessage numaraya gönderilen son mesaj
+func (f *FakeProvider) LastMessage(to string) (SendRequest, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.messages) - 1; i >= 0; i-- {
+		if f.messages[i].To == to {
+			return f.messages[i], true
+		}
+	}
+	return SendRequest{}, false
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 )
@@ -24,6 +25,9 @@ type SendRequest struct {
 	To      string `json:"to"`
 	Message string `json:"message"`
 	From    string `json:"from,omitempty"`
+
+	// PropertyID gönderimin yapıldığı site; kullanım ölçümünde kiracıya yazılır (boşsa platform)
+	PropertyID string `json:"-"`
 }
 
 // SendResponse SMS gönderim yanıtı
@@ -154,8 +158,8 @@ func (n *NetgsmProvider) GetBalance(ctx context.Context) (*BalanceResponse, erro
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	// Parse balance from response
+	// Yanıt gövdesi henüz ayrıştırılmıyor; bağlantının yeniden kullanılabilmesi için tüketilir
+	io.Copy(io.Discard, resp.Body)
 	return &BalanceResponse{
 		Balance:  0, // Parse from body
 		Currency: "TRY",
@@ -284,6 +288,7 @@ func (i *IletiMerkeziProvider) GetDeliveryReport(ctx context.Context, messageID
 type Service struct {
 	providers map[string]Provider
 	primary   string
+	onSent    func(ctx context.Context, req *SendRequest)
 }
 
 // NewService yeni SMS servisi oluşturur
@@ -299,8 +304,21 @@ func (s *Service) RegisterProvider(name string, provider Provider) {
 	s.providers[name] = provider
 }
 
+// OnSent başarılı her gönderimden sonra çağrılacak fonksiyonu kaydeder (kullanım ölçümü)
+func (s *Service) OnSent(fn func(ctx context.Context, req *SendRequest)) {
+	s.onSent = fn
+}
+
 // Send SMS gönderir
 func (s *Service) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	resp, err := s.send(ctx, req)
+	if err == nil && resp != nil && resp.Success && s.onSent != nil {
+		s.onSent(ctx, req)
+	}
+	return resp, err
+}
+
+func (s *Service) send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
 	provider, ok := s.providers[s.primary]
 	if !ok {
 		return nil, fmt.Errorf("birincil sağlayıcı bulunamadı: %s", s.primary)
@@ -332,3 +350,32 @@ func (s *Service) SendBulk(ctx context.Context, requests []*SendRequest) []*Send
 	}
 	return responses
 }
+
+// NewServiceFromEnv ortam değişkenlerinden SMS servisi oluşturur. SMS_PROVIDER birincil
+// sağlayıcıyı seçer (netgsm, iletimerkezi, fake; varsayılan netgsm). Kimlik bilgisi
+// tanımlı sağlayıcılar yedek olarak kaydedilir; fake yalnızca açıkça seçilirse kullanılır.
+func NewServiceFromEnv() *Service {
+	primary := os.Getenv("SMS_PROVIDER")
+	if primary == "" {
+		primary = "netgsm"
+	}
+	s := NewService(primary)
+	if code := os.Getenv("NETGSM_USER_CODE"); code != "" {
+		s.RegisterProvider("netgsm", NewNetgsmProvider(NetgsmConfig{
+			UserCode:  code,
+			Password:  os.Getenv("NETGSM_PASSWORD"),
+			MsgHeader: os.Getenv("NETGSM_MSG_HEADER"),
+		}))
+	}
+	if key := os.Getenv("ILETIMERKEZI_API_KEY"); key != "" {
+		s.RegisterProvider("iletimerkezi", NewIletiMerkeziProvider(IletiMerkeziConfig{
+			APIKey:  key,
+			APIHash: os.Getenv("ILETIMERKEZI_API_HASH"),
+			Sender:  os.Getenv("ILETIMERKEZI_SENDER"),
+		}))
+	}
+	if primary == "fake" {
+		s.RegisterProvider("fake", NewFakeProvider())
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/siteeksen/backend/pkg/database"
+)
+
+// Hesap kodları (Tekdüzen Hesap Planı'ndan sadeleştirilmiş)
+const (
+	AccountCash               = "100" // Kasa
+	AccountBank               = "102" // Bankalar
+	AccountResidentReceivable = "120" // Sakin alacakları (daire bazlı)
+	AccountVendorPayable      = "320" // Satıcılar
+	AccountAdvances           = "340" // Alınan avanslar
+	AccountReserveFunds       = "549" // Özel fonlar (demirbaş / yedek akçe); her fonun alt hesabı vardır
+	AccountOpeningBalance     = "570" // Geçmiş dönem devirleri (eski yazılımdan aktarılan bakiyeler)
+	AccountAssessmentRevenue  = "600" // Aidat gelirleri
+	AccountMeterRevenue       = "601" // Sayaç / tüketim gelirleri
+	AccountLateFeeRevenue     = "642" // Gecikme tazminatı gelirleri
+	AccountOperatingExpense   = "770" // Genel yönetim giderleri
+)
+
+// Account - hesap planı kalemi
+type Account struct {
+	Code string
+	Name string
+	Type string // ASSET, LIABILITY, EQUITY, REVENUE, EXPENSE
+}
+
+// DefaultAccounts - her site için açılan varsayılan hesap planı
+func DefaultAccounts() []Account {
+	return []Account{
+		{Code: AccountCash, Name: "Kasa", Type: "ASSET"},
+		{Code: AccountBank, Name: "Bankalar", Type: "ASSET"},
+		{Code: AccountResidentReceivable, Name: "Sakin Alacakları", Type: "ASSET"},
+		{Code: AccountVendorPayable, Name: "Satıcılar", Type: "LIABILITY"},
+		{Code: AccountAdvances, Name: "Alınan Avanslar", Type: "LIABILITY"},
+		{Code: AccountReserveFunds, Name: "Özel Fonlar", Type: "EQUITY"},
+		{Code: AccountOpeningBalance, Name: "Geçmiş Dönem Devirleri", Type: "EQUITY"},
+		{Code: AccountAssessmentRevenue, Name: "Aidat Gelirleri", Type: "REVENUE"},
+		{Code: AccountMeterRevenue, Name: "Tüketim Gelirleri", Type: "REVENUE"},
+		{Code: AccountLateFeeRevenue, Name: "Gecikme Tazminatı Gelirleri", Type: "REVENUE"},
+		{Code: AccountOperatingExpense, Name: "Genel Yönetim Giderleri", Type: "EXPENSE"},
+	}
+}
+
+// DefaultAccount - varsayılan hesap planında kod arar
+func DefaultAccount(code string) (Account, bool) {
+	for _, a := range DefaultAccounts() {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return Account{}, false
+}
+
+// EnsureChartOfAccounts - site için varsayılan hesap planını açar (mevcut hesaplara dokunmaz)
+func EnsureChartOfAccounts(ctx context.Context, q database.Querier, propertyID string) error {
+	for _, a := range DefaultAccounts() {
+		_, err := q.Exec(ctx, `
+			INSERT INTO chart_of_accounts (property_id, account_code, account_name, account_type)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (property_id, account_code) DO NOTHING
+		`, propertyID, a.Code, a.Name, a.Type)
+		if err != nil {
+			return fmt.Errorf("hesap planı oluşturulamadı (%s): %w", a.Code, err)
+		}
+	}
+	return nil
+}
+
+// OpenSubAccount - üst hesabın altında sıradaki alt hesabı açar ("549" → "549.01", "549.02")
+// ve kodunu döner. Üst hesap site için açılmamışsa önce o açılır.
+func OpenSubAccount(ctx context.Context, q database.Querier, propertyID, parentCode, name string) (string, error) {
+	parentID, err := resolveAccount(ctx, q, propertyID, parentCode)
+	if err != nil {
+		return "", err
+	}
+	var accountType string
+	if err := q.QueryRow(ctx, `SELECT account_type FROM chart_of_accounts WHERE id = $1 FOR UPDATE`, parentID).Scan(&accountType); err != nil {
+		return "", err
+	}
+
+	var next int
+	err = q.QueryRow(ctx, `
+		SELECT COALESCE(MAX(SUBSTRING(account_code FROM '\.(\d+)$')::int), 0) + 1
+		FROM chart_of_accounts
+		WHERE property_id = $1 AND parent_account_id = $2
+	`, propertyID, parentID).Scan(&next)
+	if err != nil {
+		return "", err
+	}
+
+	code := fmt.Sprintf("%s.%02d", parentCode, next)
+	_, err = q.Exec(ctx, `
+		INSERT INTO chart_of_accounts (property_id, account_code, account_name, account_type, parent_account_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, propertyID, code, name, accountType, parentID)
+	if err != nil {
+		return "", fmt.Errorf("alt hesap açılamadı (%s): %w", code, err)
+	}
+	return code, nil
+}
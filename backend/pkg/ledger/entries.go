@@ -0,0 +1,232 @@
+package ledger
+
+import "time"
+
+// AssessmentAccrual - aidat tahakkuku: 120 Sakin Alacakları / 600 Aidat Gelirleri
+func AssessmentAccrual(propertyID, unitID, assessmentID string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocAssessment,
+		Description:     description,
+		SourceType:      "assessment",
+		SourceID:        assessmentID,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: amount},
+			{AccountCode: AccountAssessmentRevenue, Credit: amount},
+		},
+	}
+}
+
+// Collection - tahsilat: 102 Bankalar (veya 100 Kasa) / 120 Sakin Alacakları
+func Collection(propertyID, unitID, paymentID string, amount float64, cashAccount string, date time.Time, description string) *Entry {
+	return CollectionWithAdvance(propertyID, unitID, paymentID, amount, 0, cashAccount, date, description)
+}
+
+// CollectionWithAdvance - borçları aşan tahsilat: 102 Bankalar / 120 Sakin Alacakları
+// (tahakkuklara dağıtılan kısım) ve 340 Alınan Avanslar (daire avansı)
+func CollectionWithAdvance(propertyID, unitID, paymentID string, applied, advance float64, cashAccount string, date time.Time, description string) *Entry {
+	if cashAccount == "" {
+		cashAccount = AccountBank
+	}
+	lines := []Line{{AccountCode: cashAccount, Debit: roundKurus(applied + advance)}}
+	if applied > 0 {
+		lines = append(lines, Line{AccountCode: AccountResidentReceivable, UnitID: unitID, Credit: applied})
+	}
+	if advance > 0 {
+		lines = append(lines, Line{AccountCode: AccountAdvances, UnitID: unitID, Credit: advance})
+	}
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocCollection,
+		Description:     description,
+		SourceType:      "payment",
+		SourceID:        paymentID,
+		Lines:           lines,
+	}
+}
+
+// AdvanceApplication - daire avansının tahakkuka mahsubu: 340 Alınan Avanslar / 120 Sakin Alacakları
+func AdvanceApplication(propertyID, unitID, assessmentID string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocCollection,
+		Description:     description,
+		SourceType:      "advance_application",
+		SourceID:        assessmentID,
+		Lines: []Line{
+			{AccountCode: AccountAdvances, UnitID: unitID, Debit: amount},
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Credit: amount},
+		},
+	}
+}
+
+// ReserveContribution - fon katkı payı tahakkuku: 120 Sakin Alacakları / 549.xx fon alt hesabı.
+// Aidat tahakkukundan ayrı kayıt olduğu için ekstrede ayrı satır olarak görünür.
+func ReserveContribution(propertyID, unitID, assessmentID, fundAccount string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocAssessment,
+		Description:     description,
+		SourceType:      "assessment_reserve",
+		SourceID:        assessmentID,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: amount},
+			{AccountCode: fundAccount, Credit: amount},
+		},
+	}
+}
+
+// ReserveWithdrawal - fondan harcama: 549.xx fon alt hesabı / 102 Bankalar (veya 100 Kasa)
+func ReserveWithdrawal(propertyID, withdrawalID, fundAccount, cashAccount string, amount float64, date time.Time, description, documentNumber, createdBy string) *Entry {
+	if cashAccount == "" {
+		cashAccount = AccountBank
+	}
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentNumber:  documentNumber,
+		DocumentType:    DocReserveFund,
+		Description:     description,
+		SourceType:      "reserve_withdrawal",
+		SourceID:        withdrawalID,
+		CreatedBy:       createdBy,
+		Lines: []Line{
+			{AccountCode: fundAccount, Debit: amount},
+			{AccountCode: cashAccount, Credit: amount},
+		},
+	}
+}
+
+// VendorInvoice - tedarikçi faturası: 770 Genel Yönetim Giderleri / 320 Satıcılar.
+// Faturasız (elden ödenen) giderlerde alacak tarafı 100 Kasa olur.
+func VendorInvoice(propertyID, expenseID string, amount float64, invoiced bool, date time.Time, description, documentNumber string) *Entry {
+	creditAccount := AccountVendorPayable
+	if !invoiced {
+		creditAccount = AccountCash
+	}
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentNumber:  documentNumber,
+		DocumentType:    DocInvoice,
+		Description:     description,
+		SourceType:      "expense",
+		SourceID:        expenseID,
+		Lines: []Line{
+			{AccountCode: AccountOperatingExpense, Debit: amount},
+			{AccountCode: creditAccount, Credit: amount},
+		},
+	}
+}
+
+// MeterBilling - sayaç tüketim faturası: 120 Sakin Alacakları / 601 Tüketim Gelirleri
+func MeterBilling(propertyID, unitID, invoiceID string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocMeterBilling,
+		Description:     description,
+		SourceType:      "consumption_invoice",
+		SourceID:        invoiceID,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: amount},
+			{AccountCode: AccountMeterRevenue, Credit: amount},
+		},
+	}
+}
+
+// Correction - serbest düzeltme kaydı (kalemler çağıran tarafından verilir)
+func Correction(propertyID, sourceType, sourceID string, lines []Line, date time.Time, description, createdBy string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocCorrection,
+		Description:     description,
+		SourceType:      sourceType,
+		SourceID:        sourceID,
+		CreatedBy:       createdBy,
+		Lines:           lines,
+	}
+}
+
+// Reversal - orijinal kaydın borç/alacak tarafları yer değiştirilmiş DUZELTME kaydı
+func Reversal(original *Entry, description, createdBy string) *Entry {
+	lines := make([]Line, len(original.Lines))
+	for i, l := range original.Lines {
+		lines[i] = Line{AccountCode: l.AccountCode, UnitID: l.UnitID, Debit: l.Credit, Credit: l.Debit}
+	}
+	entry := Correction(original.PropertyID, original.SourceType, original.SourceID, lines, time.Now(), description, createdBy)
+	entry.ReversalOf = original.ID
+	return entry
+}
+
+// LateFeeWaiver - ödeme planı kapsamında gecikme tazminatı affı: 642 Gecikme Tazminatı Gelirleri /
+// 120 Sakin Alacakları. Plan bozulursa kayıt ters kayıtla geri alınır.
+func LateFeeWaiver(propertyID, unitID, planID string, amount float64, date time.Time, description, createdBy string) *Entry {
+	return Correction(propertyID, "payment_plan_waiver", planID, []Line{
+		{AccountCode: AccountLateFeeRevenue, Debit: amount},
+		{AccountCode: AccountResidentReceivable, UnitID: unitID, Credit: amount},
+	}, date, description, createdBy)
+}
+
+// AssessmentCorrection - tahakkuk düzeltmesi. change pozitifse 120 Sakin Alacakları /
+// 600 Aidat Gelirleri; indirimde 600 / 120 (açık kısım) ve 340 Alınan Avanslar (ödenmiş
+// kısım, credit). Alacak dekontu change = 0 ile yalnızca avansa yazar. Kayıt correctionOf
+// ile orijinal AIDAT kaydına bağlanır; orijinal kayıt iptal edilmiş sayılmaz.
+func AssessmentCorrection(propertyID, unitID, correctionID, correctionOf string, change, credit float64, date time.Time, description, createdBy string) *Entry {
+	var lines []Line
+	if change > 0 {
+		lines = []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: change},
+			{AccountCode: AccountAssessmentRevenue, Credit: change},
+		}
+	} else {
+		lines = []Line{{AccountCode: AccountAssessmentRevenue, Debit: roundKurus(credit - change)}}
+		if change < 0 {
+			lines = append(lines, Line{AccountCode: AccountResidentReceivable, UnitID: unitID, Credit: -change})
+		}
+		if credit > 0 {
+			lines = append(lines, Line{AccountCode: AccountAdvances, UnitID: unitID, Credit: credit})
+		}
+	}
+	entry := Correction(propertyID, "assessment_correction", correctionID, lines, date, description, createdBy)
+	entry.CorrectionOf = correctionOf
+	return entry
+}
+
+// LateFeeAccrual - gecikme tazminatı tahakkuku: 120 Sakin Alacakları / 642 Gecikme Tazminatı Gelirleri
+func LateFeeAccrual(propertyID, unitID, assessmentID string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocAssessment,
+		Description:     description,
+		SourceType:      "assessment_late_fee",
+		SourceID:        assessmentID,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: amount},
+			{AccountCode: AccountLateFeeRevenue, Credit: amount},
+		},
+	}
+}
+
+// OpeningBalance - devir bakiyesi: 120 Sakin Alacakları / 570 Geçmiş Dönem Devirleri. Kayıt
+// dairenin devir tahakkukuna bağlanır; bakiye değişirse ters kaydı yapılıp yeniden defterlenir.
+func OpeningBalance(propertyID, unitID, assessmentID string, amount float64, date time.Time, description string) *Entry {
+	return &Entry{
+		PropertyID:      propertyID,
+		TransactionDate: date,
+		DocumentType:    DocOpening,
+		Description:     description,
+		SourceType:      "opening_balance",
+		SourceID:        assessmentID,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: unitID, Debit: amount},
+			{AccountCode: AccountOpeningBalance, Credit: amount},
+		},
+	}
+}
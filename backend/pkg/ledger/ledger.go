@@ -0,0 +1,389 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+)
+
+// DocumentType - yevmiye belge tipleri
+type DocumentType string
+
+const (
+	DocAssessment   DocumentType = "AIDAT"    // Aidat tahakkuku
+	DocCollection   DocumentType = "TAHSILAT" // Tahsilat
+	DocInvoice      DocumentType = "FATURA"   // Tedarikçi faturası
+	DocMeterBilling DocumentType = "SAYAC"    // Sayaç tüketim faturası
+	DocCorrection   DocumentType = "DUZELTME" // Düzeltme / ters kayıt
+	DocReserveFund  DocumentType = "FON"      // Demirbaş / yedek akçe fonundan harcama
+	DocOpening      DocumentType = "DEVIR"    // Eski yazılımdan devreden bakiye
+)
+
+// ErrUnbalanced - borç ve alacak toplamı eşit olmayan kayıt
+var ErrUnbalanced = errors.New("yevmiye kaydı dengesiz: borç ve alacak toplamları eşit değil")
+
+// Entry - yevmiye kaydı
+type Entry struct {
+	ID              string       `json:"id,omitempty"`
+	PropertyID      string       `json:"property_id"`
+	TransactionDate time.Time    `json:"transaction_date"`
+	DocumentNumber  string       `json:"document_number,omitempty"`
+	DocumentType    DocumentType `json:"document_type"`
+	Description     string       `json:"description"`
+	SourceType      string       `json:"source_type,omitempty"` // assessment, assessment_late_fee, assessment_reserve, assessment_correction, payment, expense, consumption_invoice, reserve_withdrawal, payment_plan_waiver, opening_balance
+	SourceID        string       `json:"source_id,omitempty"`
+	ReversalOf      string       `json:"reversal_of,omitempty"`   // Ters çevrilen orijinal kayıt
+	CorrectionOf    string       `json:"correction_of,omitempty"` // Kısmen düzeltilen orijinal kayıt
+	CreatedBy       string       `json:"created_by,omitempty"`
+	Lines           []Line       `json:"lines"`
+}
+
+// Line - yevmiye kalemi (tek satırda yalnızca borç veya alacak dolu olur)
+type Line struct {
+	AccountCode string  `json:"account_code"`
+	UnitID      string  `json:"unit_id,omitempty"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+}
+
+// Validate - kaydın defterlenebilir olup olmadığını kontrol eder
+func (e *Entry) Validate() error {
+	if e.PropertyID == "" {
+		return errors.New("yevmiye kaydı için site gerekli")
+	}
+	if e.DocumentType == "" {
+		return errors.New("yevmiye kaydı için belge tipi gerekli")
+	}
+	if len(e.Lines) < 2 {
+		return errors.New("yevmiye kaydı en az iki kalem içermeli")
+	}
+
+	var debit, credit int64
+	for i, l := range e.Lines {
+		if l.AccountCode == "" {
+			return fmt.Errorf("%d. kalemde hesap kodu eksik", i+1)
+		}
+		d, c := toKurus(l.Debit), toKurus(l.Credit)
+		if d < 0 || c < 0 {
+			return fmt.Errorf("%d. kalemde negatif tutar", i+1)
+		}
+		if (d > 0) == (c > 0) {
+			return fmt.Errorf("%d. kalemde yalnızca borç veya alacak tutarı olmalı", i+1)
+		}
+		debit += d
+		credit += c
+	}
+	if debit != credit {
+		return fmt.Errorf("%w (borç %.2f, alacak %.2f)", ErrUnbalanced, float64(debit)/100, float64(credit)/100)
+	}
+	return nil
+}
+
+// Total - kaydın borç toplamı
+func (e *Entry) Total() float64 {
+	var total int64
+	for _, l := range e.Lines {
+		total += toKurus(l.Debit)
+	}
+	return float64(total) / 100
+}
+
+// Ledger - çift taraflı muhasebe defteri. Sorgular isteğin kiracı kapsamında (RLS) çalışır.
+type Ledger struct {
+	pool *pgxpool.Pool
+}
+
+// New - yeni defter
+func New(pool *pgxpool.Pool) *Ledger {
+	return &Ledger{pool: pool}
+}
+
+// Post - kaydı kendi transaction'ı içinde defterler
+func (l *Ledger) Post(ctx context.Context, entry *Entry) (string, error) {
+	tx, err := database.Conn(ctx, l.pool).Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := l.PostTx(ctx, tx, entry)
+	if err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// PostTx - kaydı çağıranın transaction'ı içinde defterler.
+// Dengesiz kayıtlar veritabanına hiç gönderilmeden reddedilir.
+func (l *Ledger) PostTx(ctx context.Context, q database.Querier, entry *Entry) (string, error) {
+	if err := entry.Validate(); err != nil {
+		return "", err
+	}
+	if entry.TransactionDate.IsZero() {
+		entry.TransactionDate = time.Now()
+	}
+
+	accounts := make(map[string]string)
+	for _, line := range entry.Lines {
+		if _, ok := accounts[line.AccountCode]; ok {
+			continue
+		}
+		accountID, err := resolveAccount(ctx, q, entry.PropertyID, line.AccountCode)
+		if err != nil {
+			return "", err
+		}
+		accounts[line.AccountCode] = accountID
+	}
+
+	var entryID string
+	err := q.QueryRow(ctx, `
+		INSERT INTO ledger_entries
+			(property_id, transaction_date, document_number, document_type, description,
+			 source_type, source_id, reversal_of, correction_of, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), NULLIF($7, '')::uuid, NULLIF($8, '')::uuid,
+				NULLIF($9, '')::uuid, NULLIF($10, '')::uuid)
+		RETURNING id
+	`, entry.PropertyID, entry.TransactionDate, entry.DocumentNumber, string(entry.DocumentType), entry.Description,
+		entry.SourceType, entry.SourceID, entry.ReversalOf, entry.CorrectionOf, entry.CreatedBy).Scan(&entryID)
+	if err != nil {
+		return "", fmt.Errorf("yevmiye kaydı yazılamadı: %w", err)
+	}
+
+	for _, line := range entry.Lines {
+		_, err := q.Exec(ctx, `
+			INSERT INTO ledger_lines (entry_id, account_id, unit_id, debit_amount, credit_amount)
+			VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+		`, entryID, accounts[line.AccountCode], line.UnitID, roundKurus(line.Debit), roundKurus(line.Credit))
+		if err != nil {
+			return "", fmt.Errorf("yevmiye kalemi yazılamadı: %w", err)
+		}
+	}
+
+	entry.ID = entryID
+	return entryID, nil
+}
+
+// FindBySource - kaynağa bağlı, ters kaydı yapılmamış kayıtları getirir
+func (l *Ledger) FindBySource(ctx context.Context, q database.Querier, docType DocumentType, sourceType, sourceID string) ([]Entry, error) {
+	rows, err := q.Query(ctx, `
+		SELECT le.id, le.property_id, le.transaction_date, COALESCE(le.document_number, ''),
+			   le.document_type, COALESCE(le.description, '')
+		FROM ledger_entries le
+		WHERE le.document_type = $1 AND le.source_type = $2 AND le.source_id = $3
+		  AND NOT EXISTS (SELECT 1 FROM ledger_entries r WHERE r.reversal_of = le.id)
+		ORDER BY le.created_at
+	`, string(docType), sourceType, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var dt string
+		if err := rows.Scan(&e.ID, &e.PropertyID, &e.TransactionDate, &e.DocumentNumber, &dt, &e.Description); err != nil {
+			return nil, err
+		}
+		e.DocumentType = DocumentType(dt)
+		e.SourceType = sourceType
+		e.SourceID = sourceID
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		lines, err := loadLines(ctx, q, entries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Lines = lines
+	}
+	return entries, nil
+}
+
+// ReverseTx - orijinal kaydı borç/alacak yer değiştirerek DUZELTME kaydıyla iptal eder
+func (l *Ledger) ReverseTx(ctx context.Context, q database.Querier, original *Entry, description, createdBy string) (string, error) {
+	if original.ID == "" {
+		return "", errors.New("ters kayıt için orijinal kayıt gerekli")
+	}
+	if len(original.Lines) == 0 {
+		lines, err := loadLines(ctx, q, original.ID)
+		if err != nil {
+			return "", err
+		}
+		original.Lines = lines
+	}
+	return l.PostTx(ctx, q, Reversal(original, description, createdBy))
+}
+
+// UnitBalance - dairenin belirli tarihe kadarki cari bakiyesi (borç - alacak)
+func (l *Ledger) UnitBalance(ctx context.Context, unitID string, asOf time.Time) (float64, error) {
+	var balance float64
+	err := database.Conn(ctx, l.pool).QueryRow(ctx, `
+		SELECT COALESCE(SUM(ll.debit_amount) - SUM(ll.credit_amount), 0)
+		FROM ledger_lines ll
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		WHERE ll.unit_id = $1 AND le.transaction_date <= $2
+	`, unitID, asOf).Scan(&balance)
+	return balance, err
+}
+
+// UnitAccountBalanceTx - dairenin tek hesaptaki bakiyesi (borç - alacak); avans
+// bakiyesi için 340 hesabı negatif (alacak) bakiye verir
+func (l *Ledger) UnitAccountBalanceTx(ctx context.Context, q database.Querier, unitID, accountCode string) (float64, error) {
+	var balance float64
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(ll.debit_amount) - SUM(ll.credit_amount), 0)
+		FROM ledger_lines ll
+		JOIN chart_of_accounts coa ON coa.id = ll.account_id
+		WHERE ll.unit_id = $1 AND coa.account_code = $2
+	`, unitID, accountCode).Scan(&balance)
+	return roundKurus(balance), err
+}
+
+// AccountBalance - sitenin tek hesaptaki belirli tarihe kadarki bakiyesi (borç - alacak).
+// Kod bir üst hesapsa ("549") alt hesaplar ("549.01") da dahil edilir.
+func (l *Ledger) AccountBalance(ctx context.Context, propertyID, accountCode string, asOf time.Time) (float64, error) {
+	var balance float64
+	err := database.Conn(ctx, l.pool).QueryRow(ctx, `
+		SELECT COALESCE(SUM(ll.debit_amount) - SUM(ll.credit_amount), 0)
+		FROM ledger_lines ll
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		JOIN chart_of_accounts coa ON coa.id = ll.account_id
+		WHERE coa.property_id = $1 AND (coa.account_code = $2 OR coa.account_code LIKE $2 || '.%')
+		  AND le.transaction_date <= $3
+	`, propertyID, accountCode, asOf).Scan(&balance)
+	return roundKurus(balance), err
+}
+
+// TrialBalanceRow - mizan satırı
+type TrialBalanceRow struct {
+	AccountCode string  `json:"account_code"`
+	AccountName string  `json:"account_name"`
+	AccountType string  `json:"account_type"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	Balance     float64 `json:"balance"`
+}
+
+// TrialBalance - mizan
+type TrialBalance struct {
+	PropertyID  string            `json:"property_id"`
+	AsOf        time.Time         `json:"as_of"`
+	Rows        []TrialBalanceRow `json:"rows"`
+	TotalDebit  float64           `json:"total_debit"`
+	TotalCredit float64           `json:"total_credit"`
+	Balanced    bool              `json:"balanced"`
+}
+
+// TrialBalance - belirli tarih itibarıyla mizanı defterden hesaplar
+func (l *Ledger) TrialBalance(ctx context.Context, propertyID string, asOf time.Time) (*TrialBalance, error) {
+	rows, err := database.Conn(ctx, l.pool).Query(ctx, `
+		SELECT coa.account_code, coa.account_name, coa.account_type,
+			   COALESCE(SUM(ll.debit_amount), 0), COALESCE(SUM(ll.credit_amount), 0)
+		FROM chart_of_accounts coa
+		LEFT JOIN (
+			ledger_lines ll
+			JOIN ledger_entries le ON le.id = ll.entry_id AND le.transaction_date <= $2
+		) ON ll.account_id = coa.id
+		WHERE coa.property_id = $1
+		GROUP BY coa.account_code, coa.account_name, coa.account_type
+		ORDER BY coa.account_code
+	`, propertyID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tb := &TrialBalance{PropertyID: propertyID, AsOf: asOf}
+	var totalDebit, totalCredit int64
+	for rows.Next() {
+		var r TrialBalanceRow
+		if err := rows.Scan(&r.AccountCode, &r.AccountName, &r.AccountType, &r.Debit, &r.Credit); err != nil {
+			return nil, err
+		}
+		r.Balance = roundKurus(r.Debit - r.Credit)
+		totalDebit += toKurus(r.Debit)
+		totalCredit += toKurus(r.Credit)
+		tb.Rows = append(tb.Rows, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tb.TotalDebit = float64(totalDebit) / 100
+	tb.TotalCredit = float64(totalCredit) / 100
+	tb.Balanced = totalDebit == totalCredit
+	return tb, nil
+}
+
+func loadLines(ctx context.Context, q database.Querier, entryID string) ([]Line, error) {
+	rows, err := q.Query(ctx, `
+		SELECT coa.account_code, COALESCE(ll.unit_id::text, ''), ll.debit_amount, ll.credit_amount
+		FROM ledger_lines ll
+		JOIN chart_of_accounts coa ON coa.id = ll.account_id
+		WHERE ll.entry_id = $1
+	`, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []Line
+	for rows.Next() {
+		var line Line
+		if err := rows.Scan(&line.AccountCode, &line.UnitID, &line.Debit, &line.Credit); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// resolveAccount - hesap kodunu sitenin hesap planındaki ID'ye çevirir.
+// Varsayılan hesap planındaki kodlar site için henüz açılmamışsa otomatik açılır.
+func resolveAccount(ctx context.Context, q database.Querier, propertyID, code string) (string, error) {
+	var id string
+	err := q.QueryRow(ctx, `
+		SELECT id FROM chart_of_accounts
+		WHERE property_id = $1 AND account_code = $2 AND is_active = true
+	`, propertyID, code).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+
+	account, ok := DefaultAccount(code)
+	if !ok {
+		return "", fmt.Errorf("hesap planında %s kodlu hesap yok", code)
+	}
+	err = q.QueryRow(ctx, `
+		INSERT INTO chart_of_accounts (property_id, account_code, account_name, account_type)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (property_id, account_code) DO NOTHING
+		RETURNING id
+	`, propertyID, account.Code, account.Name, account.Type).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%s kodlu hesap pasif durumda", code)
+	}
+	return id, err
+}
+
+func toKurus(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func roundKurus(amount float64) float64 {
+	return float64(toKurus(amount)) / 100
+}
@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_Balanced(t *testing.T) {
+	entry := AssessmentAccrual("p1", "u1", "a1", 1250.50, time.Now(), "Şubat 2026 aidatı")
+	require.NoError(t, entry.Validate())
+	assert.Equal(t, 1250.50, entry.Total())
+}
+
+func TestValidate_RejectsUnbalanced(t *testing.T) {
+	entry := &Entry{
+		PropertyID:   "p1",
+		DocumentType: DocCorrection,
+		Lines: []Line{
+			{AccountCode: AccountResidentReceivable, UnitID: "u1", Debit: 100},
+			{AccountCode: AccountAssessmentRevenue, Credit: 99.99},
+		},
+	}
+	err := entry.Validate()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnbalanced))
+}
+
+func TestValidate_FloatSumsCompareInKurus(t *testing.T) {
+	entry := &Entry{
+		PropertyID:   "p1",
+		DocumentType: DocInvoice,
+		Lines: []Line{
+			{AccountCode: AccountOperatingExpense, Debit: 0.1},
+			{AccountCode: AccountOperatingExpense, Debit: 0.2},
+			{AccountCode: AccountVendorPayable, Credit: 0.3},
+		},
+	}
+	assert.NoError(t, entry.Validate())
+}
+
+func TestValidate_LineRules(t *testing.T) {
+	base := func(lines ...Line) *Entry {
+		return &Entry{PropertyID: "p1", DocumentType: DocCorrection, Lines: lines}
+	}
+
+	assert.Error(t, base(Line{AccountCode: AccountCash, Debit: 10}).Validate(), "tek kalem")
+	assert.Error(t, base(
+		Line{AccountCode: AccountCash, Debit: 10, Credit: 10},
+		Line{AccountCode: AccountBank, Debit: 10, Credit: 10},
+	).Validate(), "iki taraflı kalem")
+	assert.Error(t, base(
+		Line{AccountCode: AccountCash},
+		Line{AccountCode: AccountBank},
+	).Validate(), "boş kalem")
+	assert.Error(t, base(
+		Line{AccountCode: AccountCash, Debit: -10},
+		Line{AccountCode: AccountBank, Credit: -10},
+	).Validate(), "negatif tutar")
+	assert.Error(t, base(
+		Line{Debit: 10},
+		Line{AccountCode: AccountBank, Credit: 10},
+	).Validate(), "hesap kodu eksik")
+}
+
+func TestVendorInvoice_CreditAccount(t *testing.T) {
+	invoiced := VendorInvoice("p1", "e1", 500, true, time.Now(), "Asansör bakımı", "FTR-1")
+	assert.Equal(t, AccountVendorPayable, invoiced.Lines[1].AccountCode)
+
+	cash := VendorInvoice("p1", "e2", 500, false, time.Now(), "Bahçe bakımı", "")
+	assert.Equal(t, AccountCash, cash.Lines[1].AccountCode)
+	assert.NoError(t, cash.Validate())
+}
+
+func TestReversal_SwapsSides(t *testing.T) {
+	original := Collection("p1", "u1", "pay1", 750, "", time.Now(), "Kredi kartı tahsilatı")
+	original.ID = "entry-1"
+
+	rev := Reversal(original, "İade", "admin")
+	require.NoError(t, rev.Validate())
+
+	assert.Equal(t, DocCorrection, rev.DocumentType)
+	assert.Equal(t, "entry-1", rev.ReversalOf)
+	assert.Equal(t, "payment", rev.SourceType)
+	assert.Equal(t, AccountBank, rev.Lines[0].AccountCode)
+	assert.Equal(t, 750.0, rev.Lines[0].Credit)
+	assert.Equal(t, 0.0, rev.Lines[0].Debit)
+	assert.Equal(t, "u1", rev.Lines[1].UnitID)
+	assert.Equal(t, 750.0, rev.Lines[1].Debit)
+}
+
+func TestCollectionWithAdvance_SplitsReceivableAndAdvance(t *testing.T) {
+	entry := CollectionWithAdvance("p1", "u1", "pay1", 1200, 300.5, "", time.Now(), "Tahsilat")
+	require.NoError(t, entry.Validate())
+	require.Len(t, entry.Lines, 3)
+	assert.Equal(t, 1500.5, entry.Lines[0].Debit)
+	assert.Equal(t, AccountResidentReceivable, entry.Lines[1].AccountCode)
+	assert.Equal(t, AccountAdvances, entry.Lines[2].AccountCode)
+	assert.Equal(t, "u1", entry.Lines[2].UnitID)
+
+	advanceOnly := CollectionWithAdvance("p1", "u1", "pay2", 0, 500, "", time.Now(), "Avans")
+	require.NoError(t, advanceOnly.Validate())
+	assert.Len(t, advanceOnly.Lines, 2)
+}
+
+func TestAdvanceApplication(t *testing.T) {
+	entry := AdvanceApplication("p1", "u1", "a1", 250, time.Now(), "Avans mahsubu")
+	require.NoError(t, entry.Validate())
+	assert.Equal(t, AccountAdvances, entry.Lines[0].AccountCode)
+	assert.Equal(t, 250.0, entry.Lines[0].Debit)
+	assert.Equal(t, "advance_application", entry.SourceType)
+}
+
+func TestReserveEntries(t *testing.T) {
+	contribution := ReserveContribution("p1", "u1", "a1", "549.01", 75, time.Now(), "Demirbaş katkı payı")
+	require.NoError(t, contribution.Validate())
+	assert.Equal(t, DocAssessment, contribution.DocumentType)
+	assert.Equal(t, "u1", contribution.Lines[0].UnitID)
+	assert.Equal(t, "549.01", contribution.Lines[1].AccountCode)
+	assert.Equal(t, 75.0, contribution.Lines[1].Credit)
+
+	withdrawal := ReserveWithdrawal("p1", "w1", "549.01", "", 1200, time.Now(), "Çatı onarımı", "YK-2026/4", "m1")
+	require.NoError(t, withdrawal.Validate())
+	assert.Equal(t, DocReserveFund, withdrawal.DocumentType)
+	assert.Equal(t, 1200.0, withdrawal.Lines[0].Debit)
+	assert.Equal(t, AccountBank, withdrawal.Lines[1].AccountCode)
+}
+
+func TestMeterBilling(t *testing.T) {
+	entry := MeterBilling("p1", "u1", "ci1", 252.22, time.Now(), "HEAT tüketim faturası")
+	require.NoError(t, entry.Validate())
+	assert.Equal(t, DocMeterBilling, entry.DocumentType)
+	assert.Equal(t, "consumption_invoice", entry.SourceType)
+	assert.Equal(t, AccountResidentReceivable, entry.Lines[0].AccountCode)
+	assert.Equal(t, "u1", entry.Lines[0].UnitID)
+	assert.Equal(t, 252.22, entry.Lines[0].Debit)
+	assert.Equal(t, AccountMeterRevenue, entry.Lines[1].AccountCode)
+}
+
+func TestLateFeeWaiver(t *testing.T) {
+	waiver := LateFeeWaiver("p1", "u1", "plan1", 140.5, time.Now(), "Ödeme planı tazminat affı", "m1")
+	require.NoError(t, waiver.Validate())
+	assert.Equal(t, DocCorrection, waiver.DocumentType)
+	assert.Equal(t, "payment_plan_waiver", waiver.SourceType)
+	assert.Equal(t, AccountLateFeeRevenue, waiver.Lines[0].AccountCode)
+	assert.Equal(t, "u1", waiver.Lines[1].UnitID)
+	assert.Equal(t, 140.5, waiver.Lines[1].Credit)
+}
+
+func TestOpeningBalance(t *testing.T) {
+	entry := OpeningBalance("p1", "u1", "a1", 2450.75, time.Now(), "Devir bakiyesi")
+	require.NoError(t, entry.Validate())
+	assert.Equal(t, DocOpening, entry.DocumentType)
+	assert.Equal(t, "opening_balance", entry.SourceType)
+	assert.Equal(t, "u1", entry.Lines[0].UnitID)
+	assert.Equal(t, AccountOpeningBalance, entry.Lines[1].AccountCode)
+	assert.Equal(t, 2450.75, entry.Lines[1].Credit)
+
+	account, ok := DefaultAccount(AccountOpeningBalance)
+	require.True(t, ok)
+	assert.Equal(t, "EQUITY", account.Type)
+}
+
+func TestAssessmentCorrection(t *testing.T) {
+	increase := AssessmentCorrection("p1", "u1", "c1", "e1", 75, 0, time.Now(), "Asansör bakım farkı", "m1")
+	require.NoError(t, increase.Validate())
+	assert.Equal(t, DocCorrection, increase.DocumentType)
+	assert.Equal(t, "e1", increase.CorrectionOf)
+	assert.Empty(t, increase.ReversalOf)
+	assert.Equal(t, AccountResidentReceivable, increase.Lines[0].AccountCode)
+	assert.Equal(t, 75.0, increase.Lines[0].Debit)
+
+	// 100 TL indirim: 60 TL açık borçtan düşer, ödenmiş 40 TL avansa aktarılır
+	decrease := AssessmentCorrection("p1", "u1", "c2", "e1", -60, 40, time.Now(), "Fatura düzeltmesi", "m1")
+	require.NoError(t, decrease.Validate())
+	require.Len(t, decrease.Lines, 3)
+	assert.Equal(t, AccountAssessmentRevenue, decrease.Lines[0].AccountCode)
+	assert.Equal(t, 100.0, decrease.Lines[0].Debit)
+	assert.Equal(t, 60.0, decrease.Lines[1].Credit)
+	assert.Equal(t, AccountAdvances, decrease.Lines[2].AccountCode)
+	assert.Equal(t, "u1", decrease.Lines[2].UnitID)
+
+	creditNote := AssessmentCorrection("p1", "u1", "c3", "", 0, 250, time.Now(), "Alacak dekontu", "m1")
+	require.NoError(t, creditNote.Validate())
+	require.Len(t, creditNote.Lines, 2)
+	assert.Equal(t, AccountAdvances, creditNote.Lines[1].AccountCode)
+}
+
+func TestDefaultAccount(t *testing.T) {
+	acc, ok := DefaultAccount(AccountLateFeeRevenue)
+	require.True(t, ok)
+	assert.Equal(t, "REVENUE", acc.Type)
+
+	_, ok = DefaultAccount("999")
+	assert.False(t, ok)
+}
+
+func TestNewStatement_RunningBalance(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	st := NewStatement("u1", day(1), day(31), 150, []StatementLine{
+		{EntryID: "e1", Date: day(1), DocumentType: DocAssessment, Debit: 1200},
+		{EntryID: "e2", Date: day(12), DocumentType: DocCollection, Credit: 1000.5},
+		{EntryID: "e3", Date: day(15), DocumentType: DocCollection, Debit: 250, Credit: 250}, // avans mahsubu
+		{EntryID: "e4", Date: day(20), DocumentType: DocAssessment, Debit: 60},
+	})
+
+	require.Len(t, st.Lines, 3)
+	assert.Equal(t, 1350.0, st.Lines[0].Balance)
+	assert.Equal(t, 1000.5, st.Lines[1].Credit)
+	assert.Equal(t, 349.5, st.Lines[1].Balance)
+	assert.Equal(t, "e4", st.Lines[2].EntryID)
+	assert.Equal(t, 409.5, st.ClosingBalance)
+	assert.Equal(t, 1260.0, st.TotalDebit)
+	assert.Equal(t, 1000.5, st.TotalCredit)
+}
+
+func TestNewStatement_Empty(t *testing.T) {
+	st := NewStatement("u1", time.Now(), time.Now(), -300, nil)
+	assert.Empty(t, st.Lines)
+	assert.Equal(t, -300.0, st.ClosingBalance)
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"time"
+)
+
+// StatementLine - hesap ekstresi satırı; aynı kayıttaki daire kalemleri netleştirilir
+type StatementLine struct {
+	EntryID        string       `json:"entry_id"`
+	Date           time.Time    `json:"date"`
+	DocumentType   DocumentType `json:"document_type"`
+	DocumentNumber string       `json:"document_number,omitempty"`
+	Description    string       `json:"description"`
+	Debit          float64      `json:"debit"`
+	Credit         float64      `json:"credit"`
+	Balance        float64      `json:"balance"` // Satır sonrası yürüyen bakiye (borç +)
+}
+
+// Statement - dairenin tarih aralığındaki hesap ekstresi
+type Statement struct {
+	UnitID         string          `json:"unit_id"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance float64         `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	TotalDebit     float64         `json:"total_debit"`
+	TotalCredit    float64         `json:"total_credit"`
+	ClosingBalance float64         `json:"closing_balance"`
+}
+
+// NewStatement - devir bakiyesi ve kronolojik satırlardan yürüyen bakiyeli ekstre oluşturur.
+// Borç ve alacağı eşit olan satırlar (ör. avans mahsubu) bakiyeyi değiştirmediği için atlanır.
+func NewStatement(unitID string, from, to time.Time, opening float64, lines []StatementLine) *Statement {
+	st := &Statement{UnitID: unitID, From: from, To: to, OpeningBalance: roundKurus(opening), Lines: []StatementLine{}}
+
+	balance := toKurus(opening)
+	var totalDebit, totalCredit int64
+	for _, l := range lines {
+		net := toKurus(l.Debit) - toKurus(l.Credit)
+		if net == 0 {
+			continue
+		}
+		l.Debit, l.Credit = 0, 0
+		if net > 0 {
+			l.Debit = float64(net) / 100
+			totalDebit += net
+		} else {
+			l.Credit = float64(-net) / 100
+			totalCredit -= net
+		}
+		balance += net
+		l.Balance = float64(balance) / 100
+		st.Lines = append(st.Lines, l)
+	}
+
+	st.TotalDebit = float64(totalDebit) / 100
+	st.TotalCredit = float64(totalCredit) / 100
+	st.ClosingBalance = float64(balance) / 100
+	return st
+}
+
+// UnitStatement - dairenin [from, to] aralığındaki hesap ekstresini defterden oluşturur
+func (l *Ledger) UnitStatement(ctx context.Context, unitID string, from, to time.Time) (*Statement, error) {
+	var opening float64
+	err := l.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(ll.debit_amount) - SUM(ll.credit_amount), 0)
+		FROM ledger_lines ll
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		WHERE ll.unit_id = $1 AND le.transaction_date < $2
+	`, unitID, from).Scan(&opening)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := l.pool.Query(ctx, `
+		SELECT le.id, le.transaction_date, COALESCE(le.document_number, ''), le.document_type,
+			   COALESCE(le.description, ''), SUM(ll.debit_amount), SUM(ll.credit_amount)
+		FROM ledger_lines ll
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		WHERE ll.unit_id = $1 AND le.transaction_date BETWEEN $2 AND $3
+		GROUP BY le.id, le.transaction_date, le.document_number, le.document_type, le.description, le.created_at
+		ORDER BY le.transaction_date, le.created_at, le.id
+	`, unitID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []StatementLine
+	for rows.Next() {
+		var sl StatementLine
+		var dt string
+		if err := rows.Scan(&sl.EntryID, &sl.Date, &sl.DocumentNumber, &dt, &sl.Description, &sl.Debit, &sl.Credit); err != nil {
+			return nil, err
+		}
+		sl.DocumentType = DocumentType(dt)
+		lines = append(lines, sl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewStatement(unitID, from, to, opening, lines), nil
+}
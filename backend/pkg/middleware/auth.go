@@ -1,24 +1,33 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/siteeksen/backend/pkg/claims"
 )
 
-// Claims JWT token payload
-type Claims struct {
-	UserID     string   `json:"user_id"`
-	PropertyID string   `json:"property_id"`
-	Roles      []string `json:"roles"`
-	jwt.RegisteredClaims
-}
+var (
+	defaultKeysOnce sync.Once
+	defaultKeys     claims.KeySource
+)
 
-// AuthMiddleware JWT doğrulama middleware'i
+// AuthMiddleware JWT doğrulama middleware'i. Tokenlar kimlik servisinin JWKS'indeki
+// açık anahtarlarla doğrulanır (AUTH_JWKS_URL).
 func AuthMiddleware() gin.HandlerFunc {
+	defaultKeysOnce.Do(func() {
+		defaultKeys = claims.NewRemoteKeySetFromEnv()
+	})
+	return AuthMiddlewareWithKeys(defaultKeys)
+}
+
+// AuthMiddlewareWithKeys verilen anahtar kaynağıyla doğrulayan middleware (kimlik
+// servisi kendi anahtar halkasını, testler sabit anahtarları kullanır)
+func AuthMiddlewareWithKeys(keys claims.KeySource) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -36,14 +45,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		tokenClaims, err := claims.ParseAccess(parts[1], keys)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Geçersiz veya süresi dolmuş token",
 			})
@@ -51,9 +54,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Context'e kullanıcı bilgilerini ekle
-		c.Set("user_id", claims.UserID)
-		c.Set("property_id", claims.PropertyID)
-		c.Set("roles", claims.Roles)
+		c.Set("user_id", tokenClaims.UserID())
+		c.Set("property_id", tokenClaims.PropertyID)
+		c.Set("roles", tokenClaims.Roles)
+		c.Set("permissions", tokenClaims.Permissions)
+		c.Set("session_id", tokenClaims.SessionID)
+		if tokenClaims.MFAAt != nil {
+			c.Set("mfa_at", tokenClaims.MFAAt.Time)
+		}
 
 		c.Next()
 	}
@@ -86,6 +94,87 @@ func RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission aktif sitedeki rollerden çözülen yetkiyi gerektirir
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("property_id") == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Aktif site seçilmemiş",
+			})
+			return
+		}
+
+		if !claims.HasPermission(c.GetStringSlice("permissions"), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "Bu işlem için yetkiniz yok",
+				"permission": permission,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireStepUp hassas işlemler (iade, banka hesabı, entegrasyon anahtarı) için son
+// claims.StepUpMaxAge içinde iki adımlı doğrulama ister. İstemci 403 ve
+// step_up_required yanıtında kimlik servisinden /users/me/mfa/step-up ile yeni
+// access token alıp isteği tekrarlar.
+func RequireStepUp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mfaAt := c.GetTime("mfa_at")
+		if mfaAt.IsZero() || time.Since(mfaAt) > claims.StepUpMaxAge {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":            "Bu işlem için iki adımlı doğrulama gerekli",
+				"step_up_required": true,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireStepUpHTTP net/http handler'ları için RequireStepUp. Access token
+// AUTH_JWKS_URL anahtarlarıyla doğrulanır.
+func RequireStepUpHTTP(next http.HandlerFunc) http.HandlerFunc {
+	defaultKeysOnce.Do(func() {
+		defaultKeys = claims.NewRemoteKeySetFromEnv()
+	})
+	return RequireStepUpHTTPWithKeys(defaultKeys, next)
+}
+
+// RequireStepUpHTTPWithKeys access token'ı verilen anahtar kaynağıyla doğrulayan
+// RequireStepUpHTTP
+func RequireStepUpHTTPWithKeys(keys claims.KeySource, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, gin.H{"error": "Authorization header gerekli"})
+			return
+		}
+		tokenClaims, err := claims.ParseAccess(token, keys)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, gin.H{"error": "Geçersiz veya süresi dolmuş token"})
+			return
+		}
+		if !tokenClaims.StepUpFresh(time.Now()) {
+			writeJSON(w, http.StatusForbidden, gin.H{
+				"error":            "Bu işlem için iki adımlı doğrulama gerekli",
+				"step_up_required": true,
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
 // AuditLog erişim loglarını kaydeder
 func AuditLog() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -26,15 +26,21 @@ func NewIyzicoClient() *IyzicoClient {
 		baseURL = "https://sandbox-api.iyzipay.com" // Sandbox varsayılan
 	}
 
+	return NewIyzicoClientWithConfig(os.Getenv("IYZICO_API_KEY"), os.Getenv("IYZICO_SECRET_KEY"), baseURL)
+}
+
+// NewIyzicoClientWithConfig - verilen anahtar ve adresle client oluştur (test sunucusu için)
+func NewIyzicoClientWithConfig(apiKey, secretKey, baseURL string) *IyzicoClient {
 	return &IyzicoClient{
-		apiKey:    os.Getenv("IYZICO_API_KEY"),
-		secretKey: os.Getenv("IYZICO_SECRET_KEY"),
+		apiKey:    apiKey,
+		secretKey: secretKey,
 		baseURL:   baseURL,
 	}
 }
 
 // PaymentRequest - ödeme isteği
 type PaymentRequest struct {
+	ConversationID  string         `json:"conversationId,omitempty"` // Callback'te geri döner
 	Price           string         `json:"price"`
 	PaidPrice       string         `json:"paidPrice"`
 	Currency        string         `json:"currency"`
@@ -98,6 +104,7 @@ type PaymentResponse struct {
 	Currency            string  `json:"currency,omitempty"`
 	Installment         int     `json:"installment,omitempty"`
 	PaymentTransactionID string `json:"paymentTransactionId,omitempty"`
+	ConversationID      string  `json:"conversationId,omitempty"`
 	CardToken           string  `json:"cardToken,omitempty"`
 	CardUserKey         string  `json:"cardUserKey,omitempty"`
 }
@@ -151,8 +158,8 @@ func (c *IyzicoClient) Create3DSPayment(req *ThreeDSInitRequest) (*ThreeDSInitRe
 }
 
 // Complete3DSPayment - 3D Secure tamamlama
-func (c *IyzicoClient) Complete3DSPayment(paymentID string) (*PaymentResponse, error) {
-	req := map[string]string{"paymentId": paymentID}
+func (c *IyzicoClient) Complete3DSPayment(paymentID, conversationID string) (*PaymentResponse, error) {
+	req := map[string]string{"paymentId": paymentID, "conversationId": conversationID}
 	return c.makeRequest("/payment/3dsecure/auth", req)
 }
 
@@ -175,12 +182,16 @@ func (c *IyzicoClient) CreateCardToken(card *PaymentCard, email string) (*CardTo
 	defer resp.Body.Close()
 
 	var result CardTokenResponse
-	json.NewDecoder(resp.Body).Decode(&result)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
 	return &result, nil
 }
 
 type CardTokenResponse struct {
 	Status       string `json:"status"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
 	CardToken    string `json:"cardToken"`
 	CardUserKey  string `json:"cardUserKey"`
 	CardAlias    string `json:"cardAlias"`
@@ -0,0 +1,336 @@
+// Package iyzicotest testler için yerel sahte iyzico sunucusu sağlar.
+// 3DS başlatma, 3DS tamamlama, direkt ödeme, kart saklama ve iade uçlarını taklit eder;
+// istek imza başlıklarını ve sepet toplamını gerçek API gibi kontrol eder.
+package iyzicotest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/siteeksen/backend/pkg/payment"
+)
+
+// Sandbox test kartları
+const (
+	CardSuccess          = "5528790000000008"
+	CardInsufficientFund = "4543590000000006"
+	CardNo3DSAuth        = "4059030000000009" // Banka 3DS doğrulamasını reddeder (mdStatus=0)
+)
+
+const (
+	APIKey    = "sandbox-test-api-key"
+	SecretKey = "sandbox-test-secret-key"
+)
+
+type pendingPayment struct {
+	ConversationID string
+	PaymentID      string
+	Price          float64
+	CallbackURL    string
+	MDStatus       string
+	Completed      bool
+}
+
+// Server sahte iyzico sunucusu
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*pendingPayment // iyzico paymentId -> ödeme
+	cards    map[string]string          // kart token'ı -> kart numarası
+	requests []string
+}
+
+// NewServer yeni sahte sunucu başlatır; test sonunda Close çağrılmalıdır
+func NewServer() *Server {
+	s := &Server{payments: make(map[string]*pendingPayment), cards: make(map[string]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/payment/3dsecure/initialize", s.handleInitialize)
+	mux.HandleFunc("/payment/3dsecure/auth", s.handleAuth)
+	mux.HandleFunc("/payment/auth", s.handleDirect)
+	mux.HandleFunc("/payment/refund", s.handleRefund)
+	mux.HandleFunc("/cardstorage/card", s.handleStoreCard)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// Client sunucuya bağlı iyzico client
+func (s *Server) Client() *payment.IyzicoClient {
+	return payment.NewIyzicoClientWithConfig(APIKey, SecretKey, s.URL)
+}
+
+// PaymentService sunucuya bağlı ödeme servisi
+func (s *Server) PaymentService() *payment.PaymentService {
+	return payment.NewPaymentServiceWithClient(s.Client())
+}
+
+// Requests sunucuya gelen isteklerin yolları (sırasıyla)
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// CallbackForm banka 3DS sayfasından callback adresine gönderilecek formu üretir.
+// conversationID, 3DS başlatılırken verilen referanstır.
+func (s *Server) CallbackForm(conversationID string) (url.Values, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.payments {
+		if p.ConversationID == conversationID {
+			status := "success"
+			if p.MDStatus != "1" {
+				status = "failure"
+			}
+			return url.Values{
+				"status":         {status},
+				"paymentId":      {p.PaymentID},
+				"conversationId": {p.ConversationID},
+				"mdStatus":       {p.MDStatus},
+			}, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path)
+		s.mu.Unlock()
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "IYZWS "+APIKey+":") || r.Header.Get("x-iyzi-rnd") == "" {
+			writeJSON(w, failure("1001", "Geçersiz imza"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request) {
+	var req payment.ThreeDSInitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, failure("11", "Geçersiz istek"))
+		return
+	}
+	if resp, ok := validate(&req.PaymentRequest); !ok {
+		writeJSON(w, resp)
+		return
+	}
+	if req.CallbackURL == "" {
+		writeJSON(w, failure("5004", "callbackUrl gönderilmesi zorunludur"))
+		return
+	}
+	if req.PaymentCard.CardNumber == CardInsufficientFund {
+		writeJSON(w, failure("10051", "Kart limiti yetersiz, yetersiz bakiye"))
+		return
+	}
+
+	price, _ := strconv.ParseFloat(req.Price, 64)
+	mdStatus := "1"
+	if req.PaymentCard.CardNumber == CardNo3DSAuth {
+		mdStatus = "0"
+	}
+
+	s.mu.Lock()
+	s.seq++
+	paymentID := fmt.Sprintf("%d", 10000000+s.seq)
+	s.payments[paymentID] = &pendingPayment{
+		ConversationID: req.ConversationID,
+		PaymentID:      paymentID,
+		Price:          price,
+		CallbackURL:    req.CallbackURL,
+		MDStatus:       mdStatus,
+	}
+	s.mu.Unlock()
+
+	html := fmt.Sprintf(`<html><body><form id="iyzico-3ds-form" action="%s" method="post">`+
+		`<input type="hidden" name="paymentId" value="%s"><input type="hidden" name="conversationId" value="%s">`+
+		`</form></body></html>`, req.CallbackURL, paymentID, req.ConversationID)
+
+	writeJSON(w, map[string]interface{}{
+		"status":             "success",
+		"conversationId":     req.ConversationID,
+		"threeDSHtmlContent": base64.StdEncoding.EncodeToString([]byte(html)),
+	})
+}
+
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PaymentID      string `json:"paymentId"`
+		ConversationID string `json:"conversationId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, failure("11", "Geçersiz istek"))
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.payments[req.PaymentID]
+	if !ok || (req.ConversationID != "" && p.ConversationID != req.ConversationID) {
+		s.mu.Unlock()
+		writeJSON(w, failure("5115", "Ödeme bulunamadı"))
+		return
+	}
+	if p.MDStatus != "1" {
+		s.mu.Unlock()
+		writeJSON(w, failure("10219", "3DS doğrulaması başarısız"))
+		return
+	}
+	if p.Completed {
+		s.mu.Unlock()
+		writeJSON(w, failure("5117", "Ödeme daha önce tamamlanmış"))
+		return
+	}
+	p.Completed = true
+	s.mu.Unlock()
+
+	writeJSON(w, payment.PaymentResponse{
+		Status:               "success",
+		PaymentID:            p.PaymentID,
+		Price:                p.Price,
+		PaidPrice:            p.Price,
+		Currency:             "TRY",
+		Installment:          1,
+		PaymentTransactionID: "tx-" + p.PaymentID,
+		ConversationID:       p.ConversationID,
+	})
+}
+
+func (s *Server) handleDirect(w http.ResponseWriter, r *http.Request) {
+	var req payment.PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, failure("11", "Geçersiz istek"))
+		return
+	}
+	if resp, ok := validate(&req); !ok {
+		writeJSON(w, resp)
+		return
+	}
+	cardNumber := req.PaymentCard.CardNumber
+	if token := req.PaymentCard.CardToken; token != "" {
+		s.mu.Lock()
+		number, ok := s.cards[token]
+		s.mu.Unlock()
+		if !ok {
+			writeJSON(w, failure("5191", "Kayıtlı kart bulunamadı"))
+			return
+		}
+		cardNumber = number
+	}
+	if cardNumber == CardInsufficientFund {
+		writeJSON(w, failure("10051", "Kart limiti yetersiz, yetersiz bakiye"))
+		return
+	}
+
+	price, _ := strconv.ParseFloat(req.Price, 64)
+	s.mu.Lock()
+	s.seq++
+	paymentID := fmt.Sprintf("%d", 10000000+s.seq)
+	s.mu.Unlock()
+
+	writeJSON(w, payment.PaymentResponse{
+		Status:               "success",
+		PaymentID:            paymentID,
+		Price:                price,
+		PaidPrice:            price,
+		Currency:             "TRY",
+		Installment:          1,
+		PaymentTransactionID: "tx-" + paymentID,
+		ConversationID:       req.ConversationID,
+	})
+}
+
+func (s *Server) handleStoreCard(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string              `json:"email"`
+		Card  payment.PaymentCard `json:"card"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, failure("11", "Geçersiz istek"))
+		return
+	}
+	number := req.Card.CardNumber
+	if len(number) < 15 || req.Card.ExpireMonth == "" || req.Card.ExpireYear == "" {
+		writeJSON(w, failure("12", "Kart numarası geçersizdir"))
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	token := fmt.Sprintf("card-%d", s.seq)
+	s.cards[token] = number
+	s.mu.Unlock()
+
+	writeJSON(w, payment.CardTokenResponse{
+		Status:         "success",
+		CardToken:      token,
+		CardUserKey:    "user-" + req.Email,
+		CardAlias:      req.Card.CardHolderName,
+		BinNumber:      number[:6],
+		LastFourDigits: number[len(number)-4:],
+		CardType:       "CREDIT_CARD",
+	})
+}
+
+func (s *Server) handleRefund(w http.ResponseWriter, r *http.Request) {
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, failure("11", "Geçersiz istek"))
+		return
+	}
+	price, _ := strconv.ParseFloat(req["price"], 64)
+	writeJSON(w, payment.RefundResponse{
+		Status:    "success",
+		PaymentID: strings.TrimPrefix(req["paymentTransactionId"], "tx-"),
+		Price:     price,
+	})
+}
+
+// validate gerçek API'nin temel kontrollerini uygular: kart bilgisi ve sepet toplamı
+func validate(req *payment.PaymentRequest) (map[string]interface{}, bool) {
+	if req.PaymentCard == nil || (req.PaymentCard.CardNumber == "" && req.PaymentCard.CardToken == "") {
+		return failure("12", "Kart numarası geçersizdir"), false
+	}
+	price, err := strconv.ParseFloat(req.Price, 64)
+	if err != nil || price <= 0 {
+		return failure("5", "price gönderilmesi zorunludur"), false
+	}
+
+	var basket int64
+	for _, item := range req.BasketItems {
+		p, err := strconv.ParseFloat(item.Price, 64)
+		if err != nil || p <= 0 {
+			return failure("5", "Sepet kalemi tutarı geçersiz"), false
+		}
+		basket += int64(math.Round(p * 100))
+	}
+	if basket != int64(math.Round(price*100)) {
+		return failure("5152", "Sepet tutarları toplamı price ile eşit olmalıdır"), false
+	}
+	return nil, true
+}
+
+func failure(code, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":       "failure",
+		"errorCode":    code,
+		"errorMessage": message,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -1,7 +1,9 @@
 package payment
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 )
 
 // PaymentService - ödeme servisi
@@ -11,8 +13,13 @@ type PaymentService struct {
 
 // NewPaymentService - yeni ödeme servisi
 func NewPaymentService() *PaymentService {
+	return NewPaymentServiceWithClient(NewIyzicoClient())
+}
+
+// NewPaymentServiceWithClient - verilen iyzico client ile ödeme servisi
+func NewPaymentServiceWithClient(client *IyzicoClient) *PaymentService {
 	return &PaymentService{
-		iyzico: NewIyzicoClient(),
+		iyzico: client,
 	}
 }
 
@@ -24,7 +31,9 @@ type AssessmentPaymentInput struct {
 	UserEmail      string
 	UserPhone      string
 	UserIP         string
+	ConversationID string // Callback'te ödemeyi eşleştirmek için (payments.id)
 	AssessmentIDs  []string
+	Items          []PaymentItem // Doluysa sepet tutarları buradan alınır
 	TotalAmount    float64
 	Card           *CardInput
 	SaveCard       bool
@@ -32,6 +41,13 @@ type AssessmentPaymentInput struct {
 	CallbackURL    string
 }
 
+// PaymentItem - sepetteki tek aidat ve tutarı
+type PaymentItem struct {
+	ID     string
+	Name   string
+	Amount float64
+}
+
 type CardInput struct {
 	HolderName  string
 	Number      string
@@ -51,24 +67,43 @@ type PaymentResult struct {
 	ThreeDSContent   string // 3DS için HTML
 	CardToken        string // Kart kaydedildiyse
 	CardUserKey      string
+	ConversationID   string
+	PaidPrice        float64
+	ErrorCode        string
+	RawResponse      []byte // gateway_response olarak saklanır
 }
 
 // ProcessAssessmentPayment - aidat ödemesi işle
 func (s *PaymentService) ProcessAssessmentPayment(input *AssessmentPaymentInput) (*PaymentResult, error) {
-	// Basket items oluştur
-	basketItems := make([]BasketItem, len(input.AssessmentIDs))
-	pricePerItem := input.TotalAmount / float64(len(input.AssessmentIDs))
+	// Basket items oluştur (kalem toplamı iyzico tarafından price ile karşılaştırılır)
+	items := input.Items
+	if len(items) == 0 {
+		items = splitEvenly(input.AssessmentIDs, input.TotalAmount)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("ödeme sepeti boş")
+	}
 
-	for i, assessmentID := range input.AssessmentIDs {
+	basketItems := make([]BasketItem, len(items))
+	for i, item := range items {
+		name := item.Name
+		if name == "" {
+			name = fmt.Sprintf("Aidat Ödemesi - %s", shortID(item.ID))
+		}
 		basketItems[i] = BasketItem{
-			ID:        assessmentID,
-			Name:      fmt.Sprintf("Aidat Ödemesi - %s", assessmentID[:8]),
+			ID:        item.ID,
+			Name:      name,
 			Category1: "Aidat",
 			ItemType:  "VIRTUAL",
-			Price:     fmt.Sprintf("%.2f", pricePerItem),
+			Price:     fmt.Sprintf("%.2f", item.Amount),
 		}
 	}
 
+	basketID := input.ConversationID
+	if basketID == "" {
+		basketID = fmt.Sprintf("B-%s", shortID(items[0].ID))
+	}
+
 	// Alıcı bilgileri
 	buyer := &Buyer{
 		ID:                  input.UserID,
@@ -121,11 +156,12 @@ func (s *PaymentService) ProcessAssessmentPayment(input *AssessmentPaymentInput)
 	if input.Use3DSecure {
 		req := &ThreeDSInitRequest{
 			PaymentRequest: PaymentRequest{
+				ConversationID:  input.ConversationID,
 				Price:           priceStr,
 				PaidPrice:       priceStr,
 				Currency:        "TRY",
 				Installment:     1,
-				BasketID:        fmt.Sprintf("B-%s", input.AssessmentIDs[0][:8]),
+				BasketID:        basketID,
 				PaymentCard:     paymentCard,
 				Buyer:           buyer,
 				ShippingAddress: address,
@@ -140,26 +176,33 @@ func (s *PaymentService) ProcessAssessmentPayment(input *AssessmentPaymentInput)
 			return nil, err
 		}
 
+		raw, _ := json.Marshal(resp)
 		if resp.Status != "success" {
 			return &PaymentResult{
-				Success:      false,
-				ErrorMessage: resp.ErrorMessage,
+				Success:        false,
+				ErrorMessage:   resp.ErrorMessage,
+				ErrorCode:      resp.ErrorCode,
+				ConversationID: input.ConversationID,
+				RawResponse:    raw,
 			}, nil
 		}
 
 		return &PaymentResult{
 			Success:        true,
 			ThreeDSContent: resp.ThreeDSHtmlContent,
+			ConversationID: input.ConversationID,
+			RawResponse:    raw,
 		}, nil
 	}
 
 	// Direkt ödeme (3D'siz)
 	req := &PaymentRequest{
+		ConversationID:  input.ConversationID,
 		Price:           priceStr,
 		PaidPrice:       priceStr,
 		Currency:        "TRY",
 		Installment:     1,
-		BasketID:        fmt.Sprintf("B-%s", input.AssessmentIDs[0][:8]),
+		BasketID:        basketID,
 		PaymentCard:     paymentCard,
 		Buyer:           buyer,
 		ShippingAddress: address,
@@ -172,41 +215,70 @@ func (s *PaymentService) ProcessAssessmentPayment(input *AssessmentPaymentInput)
 		return nil, err
 	}
 
-	if resp.Status != "success" {
-		return &PaymentResult{
-			Success:      false,
-			ErrorMessage: resp.ErrorMessage,
-		}, nil
-	}
-
-	return &PaymentResult{
-		Success:       true,
-		PaymentID:     resp.PaymentID,
-		TransactionID: resp.PaymentTransactionID,
-		CardToken:     resp.CardToken,
-		CardUserKey:   resp.CardUserKey,
-	}, nil
+	return paymentResult(resp), nil
 }
 
-// Complete3DSPayment - 3DS tamamlama
-func (s *PaymentService) Complete3DSPayment(paymentID string) (*PaymentResult, error) {
-	resp, err := s.iyzico.Complete3DSPayment(paymentID)
+// Complete3DSPayment - 3DS tamamlama. paymentID iyzico'nun callback'te gönderdiği
+// ödeme numarası, conversationID ise başlatmada verilen referanstır.
+func (s *PaymentService) Complete3DSPayment(paymentID, conversationID string) (*PaymentResult, error) {
+	resp, err := s.iyzico.Complete3DSPayment(paymentID, conversationID)
 	if err != nil {
 		return nil, err
 	}
 
+	return paymentResult(resp), nil
+}
+
+func paymentResult(resp *PaymentResponse) *PaymentResult {
+	raw, _ := json.Marshal(resp)
 	if resp.Status != "success" {
 		return &PaymentResult{
-			Success:      false,
-			ErrorMessage: resp.ErrorMessage,
-		}, nil
+			Success:        false,
+			ErrorMessage:   resp.ErrorMessage,
+			ErrorCode:      resp.ErrorCode,
+			ConversationID: resp.ConversationID,
+			RawResponse:    raw,
+		}
 	}
 
 	return &PaymentResult{
-		Success:       true,
-		PaymentID:     resp.PaymentID,
-		TransactionID: resp.PaymentTransactionID,
-	}, nil
+		Success:        true,
+		PaymentID:      resp.PaymentID,
+		TransactionID:  resp.PaymentTransactionID,
+		CardToken:      resp.CardToken,
+		CardUserKey:    resp.CardUserKey,
+		ConversationID: resp.ConversationID,
+		PaidPrice:      resp.PaidPrice,
+		RawResponse:    raw,
+	}
+}
+
+// splitEvenly - toplam tutarı kalemlere kuruş hassasiyetinde böler; kalan kuruşlar
+// ilk kalemlere dağıtılır, böylece sepet toplamı her zaman tutara eşit olur.
+func splitEvenly(ids []string, total float64) []PaymentItem {
+	if len(ids) == 0 {
+		return nil
+	}
+	totalKurus := int64(math.Round(total * 100))
+	per := totalKurus / int64(len(ids))
+	remainder := totalKurus % int64(len(ids))
+
+	items := make([]PaymentItem, len(ids))
+	for i, id := range ids {
+		amount := per
+		if int64(i) < remainder {
+			amount++
+		}
+		items[i] = PaymentItem{ID: id, Amount: float64(amount) / 100}
+	}
+	return items
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
 }
 
 // RefundPayment - ödeme iadesi
@@ -216,15 +288,118 @@ func (s *PaymentService) RefundPayment(transactionID string, amount float64) (*P
 		return nil, err
 	}
 
+	raw, _ := json.Marshal(resp)
 	if resp.Status != "success" {
 		return &PaymentResult{
 			Success:      false,
 			ErrorMessage: resp.ErrorMessage,
+			ErrorCode:    resp.ErrorCode,
+			RawResponse:  raw,
 		}, nil
 	}
 
 	return &PaymentResult{
-		Success:   true,
-		PaymentID: resp.PaymentID,
+		Success:     true,
+		PaymentID:   resp.PaymentID,
+		PaidPrice:   resp.Price,
+		RawResponse: raw,
+	}, nil
+}
+
+// SubscriptionChargeInput - kiracı abonelik faturası tahsilatı (kayıtlı kart, 3D'siz)
+type SubscriptionChargeInput struct {
+	TenantID    string
+	TenantName  string
+	Email       string
+	InvoiceID   string // conversationId ve sepet numarası
+	Description string
+	AmountKurus int64
+	Card        *CardInput // Token ve UserKey dolu olmalı
+}
+
+// ChargeSubscription - abonelik faturasını kiracının kayıtlı kartından çeker. Tutar kuruş
+// olarak verilir; kart sahibi işlem sırasında bulunmadığından 3D Secure kullanılmaz.
+func (s *PaymentService) ChargeSubscription(input *SubscriptionChargeInput) (*PaymentResult, error) {
+	if input.Card == nil || input.Card.Token == "" {
+		return nil, fmt.Errorf("kayıtlı kart gerekli")
+	}
+	if input.AmountKurus <= 0 {
+		return nil, fmt.Errorf("tahsilat tutarı geçersiz")
+	}
+
+	price := fmt.Sprintf("%d.%02d", input.AmountKurus/100, input.AmountKurus%100)
+	address := &Address{
+		ContactName: input.TenantName,
+		City:        "İstanbul",
+		Country:     "Turkey",
+		Address:     "Türkiye",
+	}
+	req := &PaymentRequest{
+		ConversationID: input.InvoiceID,
+		Price:          price,
+		PaidPrice:      price,
+		Currency:       "TRY",
+		Installment:    1,
+		BasketID:       input.InvoiceID,
+		PaymentCard: &PaymentCard{
+			CardToken:   input.Card.Token,
+			CardUserKey: input.Card.UserKey,
+		},
+		Buyer: &Buyer{
+			ID:                  input.TenantID,
+			Name:                input.TenantName,
+			Surname:             input.TenantName,
+			Email:               input.Email,
+			IdentityNumber:      "11111111111", // Gerçek uygulamada vergi numarası kullanılmalı
+			RegistrationAddress: "Türkiye",
+			City:                "İstanbul",
+			Country:             "Turkey",
+		},
+		ShippingAddress: address,
+		BillingAddress:  address,
+		BasketItems: []BasketItem{{
+			ID:        input.InvoiceID,
+			Name:      input.Description,
+			Category1: "Abonelik",
+			ItemType:  "VIRTUAL",
+			Price:     price,
+		}},
+	}
+
+	resp, err := s.iyzico.CreatePayment(req)
+	if err != nil {
+		return nil, err
+	}
+	return paymentResult(resp), nil
+}
+
+// StoredCard - iyzico kart saklama servisine kaydedilen kart
+type StoredCard struct {
+	Token    string
+	UserKey  string
+	LastFour string
+	Alias    string
+}
+
+// StoreCard - kartı iyzico'da saklar; sonraki tahsilatlar dönen token ile yapılır.
+// Kart numarası sistemde tutulmaz.
+func (s *PaymentService) StoreCard(card *CardInput, email string) (*StoredCard, error) {
+	resp, err := s.iyzico.CreateCardToken(&PaymentCard{
+		CardHolderName: card.HolderName,
+		CardNumber:     card.Number,
+		ExpireMonth:    card.ExpireMonth,
+		ExpireYear:     card.ExpireYear,
+	}, email)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != "success" || resp.CardToken == "" {
+		return nil, fmt.Errorf("kart kaydedilemedi: %s", resp.ErrorMessage)
+	}
+	return &StoredCard{
+		Token:    resp.CardToken,
+		UserKey:  resp.CardUserKey,
+		LastFour: resp.LastFourDigits,
+		Alias:    resp.CardAlias,
 	}, nil
 }
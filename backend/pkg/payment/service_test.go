@@ -0,0 +1,202 @@
+package payment_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/payment/iyzicotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paymentInput(cardNumber string) *payment.AssessmentPaymentInput {
+	return &payment.AssessmentPaymentInput{
+		UserID:         "user-1",
+		UserName:       "Ahmet",
+		UserSurname:    "Yılmaz",
+		UserEmail:      "ahmet@example.com",
+		UserPhone:      "+905551234567",
+		UserIP:         "127.0.0.1",
+		ConversationID: "payment-1",
+		Items: []payment.PaymentItem{
+			{ID: "assessment-1", Amount: 1200},
+			{ID: "assessment-2", Amount: 1150.50},
+		},
+		TotalAmount: 2350.50,
+		Card: &payment.CardInput{
+			HolderName:  "Ahmet Yılmaz",
+			Number:      cardNumber,
+			ExpireMonth: "12",
+			ExpireYear:  "2030",
+			Cvc:         "123",
+		},
+		Use3DSecure: true,
+		CallbackURL: "http://localhost:8082/api/v1/finance/payments/callback",
+	}
+}
+
+func TestProcessAssessmentPayment_3DSInitAndComplete(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	svc := srv.PaymentService()
+
+	init, err := svc.ProcessAssessmentPayment(paymentInput(iyzicotest.CardSuccess))
+	require.NoError(t, err)
+	require.True(t, init.Success, init.ErrorMessage)
+
+	html, err := base64.StdEncoding.DecodeString(init.ThreeDSContent)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "/api/v1/finance/payments/callback")
+	assert.NotEmpty(t, init.RawResponse)
+
+	form, ok := srv.CallbackForm("payment-1")
+	require.True(t, ok)
+	assert.Equal(t, "success", form.Get("status"))
+
+	done, err := svc.Complete3DSPayment(form.Get("paymentId"), form.Get("conversationId"))
+	require.NoError(t, err)
+	require.True(t, done.Success, done.ErrorMessage)
+	assert.Equal(t, 2350.50, done.PaidPrice)
+	assert.Equal(t, "payment-1", done.ConversationID)
+	assert.NotEmpty(t, done.TransactionID)
+
+	// Aynı ödeme ikinci kez tamamlanamaz
+	again, err := svc.Complete3DSPayment(form.Get("paymentId"), form.Get("conversationId"))
+	require.NoError(t, err)
+	assert.False(t, again.Success)
+
+	assert.Equal(t, []string{"/payment/3dsecure/initialize", "/payment/3dsecure/auth", "/payment/3dsecure/auth"}, srv.Requests())
+}
+
+func TestProcessAssessmentPayment_Declined(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+
+	result, err := srv.PaymentService().ProcessAssessmentPayment(paymentInput(iyzicotest.CardInsufficientFund))
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "10051", result.ErrorCode)
+	assert.NotEmpty(t, result.RawResponse)
+}
+
+func TestProcessAssessmentPayment_3DSRejectedByBank(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	svc := srv.PaymentService()
+
+	init, err := svc.ProcessAssessmentPayment(paymentInput(iyzicotest.CardNo3DSAuth))
+	require.NoError(t, err)
+	require.True(t, init.Success)
+
+	form, _ := srv.CallbackForm("payment-1")
+	assert.Equal(t, "failure", form.Get("status"))
+	assert.Equal(t, "0", form.Get("mdStatus"))
+
+	done, err := svc.Complete3DSPayment(form.Get("paymentId"), "payment-1")
+	require.NoError(t, err)
+	assert.False(t, done.Success)
+}
+
+func TestProcessAssessmentPayment_EvenSplitMatchesBasketTotal(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+
+	input := paymentInput(iyzicotest.CardSuccess)
+	input.Items = nil
+	input.AssessmentIDs = []string{"a1", "a2", "a3"}
+	input.TotalAmount = 100
+
+	result, err := srv.PaymentService().ProcessAssessmentPayment(input)
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.ErrorMessage)
+}
+
+func TestIyzicoClient_RejectsBadSignature(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+
+	client := payment.NewIyzicoClientWithConfig("wrong-key", "secret", srv.URL)
+	svc := payment.NewPaymentServiceWithClient(client)
+
+	result, err := svc.ProcessAssessmentPayment(paymentInput(iyzicotest.CardSuccess))
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
+func storeCard(t *testing.T, svc *payment.PaymentService, number string) *payment.StoredCard {
+	t.Helper()
+	card, err := svc.StoreCard(&payment.CardInput{
+		HolderName:  "Mavikent Yönetim",
+		Number:      number,
+		ExpireMonth: "12",
+		ExpireYear:  "2030",
+	}, "yonetim@mavikent.com")
+	require.NoError(t, err)
+	return card
+}
+
+func TestChargeSubscription_SavedCard(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	svc := srv.PaymentService()
+
+	card := storeCard(t, svc, iyzicotest.CardSuccess)
+	assert.NotEmpty(t, card.Token)
+	assert.NotEmpty(t, card.UserKey)
+	assert.Equal(t, "0008", card.LastFour)
+
+	result, err := svc.ChargeSubscription(&payment.SubscriptionChargeInput{
+		TenantID:    "tenant-1",
+		TenantName:  "Mavikent",
+		Email:       "yonetim@mavikent.com",
+		InvoiceID:   "invoice-1",
+		Description: "Profesyonel plan aylık abonelik",
+		AmountKurus: 59905,
+		Card:        &payment.CardInput{Token: card.Token, UserKey: card.UserKey},
+	})
+	require.NoError(t, err)
+	require.True(t, result.Success, result.ErrorMessage)
+	assert.Equal(t, 599.05, result.PaidPrice)
+	assert.Equal(t, "invoice-1", result.ConversationID)
+	assert.NotEmpty(t, result.TransactionID)
+
+	assert.Equal(t, []string{"/cardstorage/card", "/payment/auth"}, srv.Requests())
+}
+
+func TestChargeSubscription_Declined(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	svc := srv.PaymentService()
+
+	card := storeCard(t, svc, iyzicotest.CardInsufficientFund)
+	result, err := svc.ChargeSubscription(&payment.SubscriptionChargeInput{
+		TenantID:    "tenant-1",
+		InvoiceID:   "invoice-1",
+		AmountKurus: 29900,
+		Card:        &payment.CardInput{Token: card.Token, UserKey: card.UserKey},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "10051", result.ErrorCode)
+
+	// Saklanmamış token ile tahsilat yapılamaz
+	result, err = svc.ChargeSubscription(&payment.SubscriptionChargeInput{
+		InvoiceID:   "invoice-2",
+		AmountKurus: 29900,
+		Card:        &payment.CardInput{Token: "unknown"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+
+	_, err = svc.ChargeSubscription(&payment.SubscriptionChargeInput{InvoiceID: "invoice-3", AmountKurus: 100})
+	assert.Error(t, err)
+}
+
+func TestStoreCard_InvalidNumber(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.PaymentService().StoreCard(&payment.CardInput{Number: "1234", ExpireMonth: "12", ExpireYear: "2030"}, "a@b.com")
+	assert.Error(t, err)
+}
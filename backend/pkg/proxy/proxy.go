@@ -0,0 +1,130 @@
+// Package proxy kat maliklerinin daireleri için verdiği vekaletlerin servisler arası
+// ortak kuralları. Vekalet kimlik servisinde verilir (unit_proxies); finans, anket,
+// toplantı ve bildirim servisleri geçerli vekaletleri active_unit_proxies görünümünden okur.
+package proxy
+
+import (
+	"context"
+
+	"github.com/siteeksen/backend/pkg/database"
+)
+
+// Vekalet kapsamları
+const (
+	ScopePayDues       = "PAY_DUES"      // Aidat ve tahakkuk ödeme
+	ScopeVote          = "VOTE"          // Anket, oylama ve genel kurulda oy
+	ScopeNotifications = "NOTIFICATIONS" // Daireye giden bildirimleri alma
+)
+
+// Scopes verilebilecek kapsamlar
+var Scopes = []string{ScopePayDues, ScopeVote, ScopeNotifications}
+
+// ValidScope kapsam tanımlı mı
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxGrantorsPerProxy bir kişinin aynı sitede vekili olabileceği en fazla kat maliki
+// sayısı (KMK md. 31): kat maliki sayısı yirmiye kadar olan sitelerde bir kişi yalnızca
+// bir malike, daha kalabalık sitelerde malik sayısının yüzde beşine kadar vekil olabilir.
+func MaxGrantorsPerProxy(ownerCount int) int {
+	if ownerCount <= 20 {
+		return 1
+	}
+	return ownerCount * 5 / 100
+}
+
+// Unit kullanıcının oy kullanabileceği daire. GrantID boşsa daire kullanıcının kendi
+// dairesidir; doluysa vekaletle temsil edilir ve OnBehalfOf vekaleti veren malikdir.
+type Unit struct {
+	UnitID     string  `json:"unit_id"`
+	UnitName   string  `json:"unit_name"`
+	ShareRatio float64 `json:"share_ratio"` // Arsa payı
+	Area       float64 `json:"area_m2"`     // Net alan, yoksa brüt alan
+	GrantID    string  `json:"proxy_grant_id,omitempty"`
+	OnBehalfOf string  `json:"on_behalf_of,omitempty"`
+}
+
+// ViaProxy daire vekaletle mi temsil ediliyor
+func (u *Unit) ViaProxy() bool {
+	return u.GrantID != ""
+}
+
+// VotingUnits kullanıcının sitede oy kullanabileceği daireler: aktif kat maliki olduğu
+// daireler, includeTenants verilirse kiracısı olduğu daireler ve VOTE kapsamlı geçerli
+// vekaletler. Aynı daire birden çok yoldan geliyorsa kullanıcının kendi kaydı önceliklidir.
+func VotingUnits(ctx context.Context, q database.Querier, userID, propertyID string, includeTenants bool) ([]Unit, error) {
+	rows, err := q.Query(ctx, `
+		SELECT u.id::text, COALESCE(u.block || '-', '') || u.door_number, u.share_ratio,
+			   COALESCE(u.net_area_m2, u.gross_area_m2, 0), '', '', 0 AS priority
+		FROM units u
+		WHERE u.property_id::text = $2 AND EXISTS (
+			SELECT 1 FROM resident_units ru
+			WHERE ru.unit_id = u.id AND ru.resident_id = $1 AND ru.is_active = true
+			  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+			  AND (ru.role = 'OWNER' OR ($3 AND ru.role = 'TENANT'))
+		)
+		UNION ALL
+		SELECT u.id::text, COALESCE(u.block || '-', '') || u.door_number, u.share_ratio,
+			   COALESCE(u.net_area_m2, u.gross_area_m2, 0), p.id::text, p.grantor_id::text, 1
+		FROM active_unit_proxies p
+		JOIN units u ON u.id = p.unit_id
+		WHERE p.proxy_id = $1 AND p.property_id::text = $2 AND $4 = ANY(p.scopes)
+		ORDER BY priority, 2, 5
+	`, userID, propertyID, includeTenants, ScopeVote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []Unit{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var u Unit
+		var priority int
+		if err := rows.Scan(&u.UnitID, &u.UnitName, &u.ShareRatio, &u.Area, &u.GrantID, &u.OnBehalfOf, &priority); err != nil {
+			return nil, err
+		}
+		if seen[u.UnitID] {
+			continue
+		}
+		seen[u.UnitID] = true
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// UnitRecipients dairelere gidecek bildirimlerin alıcıları (kullanıcı kimlikleri): aktif
+// sakinler ve NOTIFICATIONS kapsamlı geçerli vekaletlerin vekilleri
+func UnitRecipients(ctx context.Context, q database.Querier, unitIDs []string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT ru.resident_id::text
+		FROM resident_units ru
+		WHERE ru.unit_id::text = ANY($1) AND ru.is_active = true
+		  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+		UNION
+		SELECT p.proxy_id::text
+		FROM active_unit_proxies p
+		WHERE p.unit_id::text = ANY($1) AND $2 = ANY(p.scopes)
+		ORDER BY 1
+	`, unitIDs, ScopeNotifications)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients, rows.Err()
+}
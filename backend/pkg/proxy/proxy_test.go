@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxGrantorsPerProxy(t *testing.T) {
+	assert.Equal(t, 1, MaxGrantorsPerProxy(0))
+	assert.Equal(t, 1, MaxGrantorsPerProxy(20))
+	assert.Equal(t, 1, MaxGrantorsPerProxy(21)) // %5'i 1,05
+	assert.Equal(t, 2, MaxGrantorsPerProxy(40))
+	assert.Equal(t, 6, MaxGrantorsPerProxy(124))
+}
+
+func TestValidScope(t *testing.T) {
+	for _, s := range Scopes {
+		assert.True(t, ValidScope(s))
+	}
+	assert.False(t, ValidScope("vote"))
+	assert.False(t, ValidScope(""))
+}
+
+func TestCount_ProxyVotesCountOncePerUnit(t *testing.T) {
+	tally := Count([]Ballot{
+		{UnitID: "u1", Choice: "FOR", Weight: 120},
+		{UnitID: "u2", Choice: "FOR", Weight: 80, ViaProxy: true},
+		// Malik ve vekilinin aynı daire için iki kaydı tek oy sayılır
+		{UnitID: "u2", Choice: "FOR", Weight: 80},
+		{UnitID: "u3", Choice: "AGAINST", Weight: 100, ViaProxy: true},
+		{UnitID: "u4", Choice: "UNKNOWN", Weight: 50},
+	}, []string{"FOR", "AGAINST", "ABSTAIN"})
+
+	require.Len(t, tally.Results, 3)
+	assert.Equal(t, 3, tally.Units)
+	assert.Equal(t, 2, tally.ProxyUnits)
+	assert.Equal(t, 300.0, tally.TotalWeight)
+
+	forResult := tally.Results[0]
+	assert.Equal(t, "FOR", forResult.Choice)
+	assert.Equal(t, 2, forResult.Units)
+	assert.Equal(t, 1, forResult.ProxyUnits)
+	assert.Equal(t, 200.0, forResult.Weight)
+	assert.Equal(t, 66.67, forResult.Percentage)
+	assert.Equal(t, 66.67, forResult.WeightPercentage)
+
+	assert.Equal(t, 1, tally.Results[1].Units)
+	assert.Equal(t, 0, tally.Results[2].Units)
+	assert.Equal(t, 0.0, tally.Results[2].Percentage)
+}
+
+func TestCount_MultipleChoiceCountsUnitOnceInTotals(t *testing.T) {
+	tally := Count([]Ballot{
+		{UnitID: "u1", Choice: "a", Weight: 1},
+		{UnitID: "u1", Choice: "b", Weight: 1},
+		{UnitID: "u2", Choice: "b", Weight: 1},
+	}, []string{"a", "b"})
+
+	assert.Equal(t, 2, tally.Units)
+	assert.Equal(t, 2.0, tally.TotalWeight)
+	assert.Equal(t, 50.0, tally.Results[0].Percentage)
+	assert.Equal(t, 100.0, tally.Results[1].Percentage)
+}
+
+func TestCount_Empty(t *testing.T) {
+	tally := Count(nil, []string{"FOR"})
+	assert.Equal(t, 0, tally.Units)
+	assert.Equal(t, 0.0, tally.Results[0].Percentage)
+}
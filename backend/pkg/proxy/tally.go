@@ -0,0 +1,87 @@
+package proxy
+
+import "math"
+
+// Ballot bir dairenin tek seçeneğe verdiği oy. Weight ağırlıklı oylamalarda dairenin
+// alanı veya arsa payıdır.
+type Ballot struct {
+	UnitID   string
+	Choice   string
+	Weight   float64
+	ViaProxy bool
+}
+
+// ChoiceResult seçeneğin sonucu. Oylar daire başına sayılır; vekaletle kullanılan oylar
+// ayrıca ProxyUnits'te gösterilir ama toplamlara bir kez girer.
+type ChoiceResult struct {
+	Choice           string  `json:"choice"`
+	Units            int     `json:"units"`
+	ProxyUnits       int     `json:"proxy_units"`
+	Weight           float64 `json:"weight"`
+	Percentage       float64 `json:"percentage"`
+	WeightPercentage float64 `json:"weight_percentage"`
+}
+
+// Tally oyların seçenek bazlı sonucu
+type Tally struct {
+	Results     []ChoiceResult `json:"results"`
+	Units       int            `json:"units"`       // Oy kullanan daire sayısı
+	ProxyUnits  int            `json:"proxy_units"` // Vekaletle oy kullanan daire sayısı
+	TotalWeight float64        `json:"total_weight"`
+}
+
+// Count oyları sayar. choices sonuçların sırasını belirler; listede olmayan seçeneğe
+// verilen oylar sayılmaz. Aynı dairenin aynı seçeneğe ikinci oyu (ör. malik ve vekilinin
+// ikisinin de kaydı) yok sayılır; çok seçimli oylamalarda bir daire birden çok seçeneğe
+// oy verebilir ama katılımda ve ağırlık toplamında bir kez sayılır. Yüzdeler oy kullanan
+// daire sayısına ve bu dairelerin ağırlık toplamına göredir.
+func Count(ballots []Ballot, choices []string) *Tally {
+	index := make(map[string]int, len(choices))
+	results := make([]ChoiceResult, len(choices))
+	for i, choice := range choices {
+		index[choice] = i
+		results[i].Choice = choice
+	}
+
+	tally := &Tally{}
+	counted := map[[2]string]bool{}
+	units := map[string]bool{}
+	for _, b := range ballots {
+		i, ok := index[b.Choice]
+		if !ok || counted[[2]string{b.UnitID, b.Choice}] {
+			continue
+		}
+		counted[[2]string{b.UnitID, b.Choice}] = true
+		results[i].Units++
+		results[i].Weight += b.Weight
+		if b.ViaProxy {
+			results[i].ProxyUnits++
+		}
+		if !units[b.UnitID] {
+			units[b.UnitID] = true
+			tally.Units++
+			tally.TotalWeight += b.Weight
+			if b.ViaProxy {
+				tally.ProxyUnits++
+			}
+		}
+	}
+
+	for i := range results {
+		results[i].Weight = round(results[i].Weight, 4)
+		if tally.Units > 0 {
+			results[i].Percentage = round(float64(results[i].Units)*100/float64(tally.Units), 2)
+		}
+		if tally.TotalWeight > 0 {
+			results[i].WeightPercentage = round(results[i].Weight*100/tally.TotalWeight, 2)
+		}
+	}
+	tally.TotalWeight = round(tally.TotalWeight, 4)
+	tally.Results = results
+	return tally
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,246 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Logo formatları
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/siteeksen/backend/pkg/tenant"
+)
+
+// Logo dosyası için üst sınır (2 MB)
+const maxLogoSize = 2 << 20
+
+type rgb struct{ r, g, b int }
+
+var (
+	defaultPrimary   = rgb{37, 99, 235}
+	defaultSecondary = rgb{240, 240, 245}
+)
+
+// SetBranding - rapor renklerini ve logosunu sitenin markasına göre ayarlar.
+// Ana renk tablo başlıklarında, ikincil rengin açık tonu özet kartlarında kullanılır.
+// logo PNG veya JPEG olmalıdır; boşsa başlığa logo basılmaz.
+func (g *PDFGenerator) SetBranding(branding *tenant.TenantBranding, logo []byte) error {
+	if branding != nil {
+		if c, ok := parseHexColor(branding.PrimaryColor); ok {
+			g.primary = c
+		}
+		if c, ok := parseHexColor(branding.SecondaryColor); ok {
+			g.secondary = tint(c, 0.85)
+		}
+	}
+	if len(logo) == 0 {
+		return nil
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(logo))
+	if err != nil {
+		return fmt.Errorf("logo okunamadı: %w", err)
+	}
+	imageType := map[string]string{"png": "PNG", "jpeg": "JPG"}[format]
+	g.pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(logo))
+	if err := g.pdf.Error(); err != nil {
+		return fmt.Errorf("logo eklenemedi: %w", err)
+	}
+	g.logo = "logo"
+	return nil
+}
+
+// Logo indirme ve önbellek ayarları
+const (
+	logoFetchTimeout = 5 * time.Second
+	logoCacheTTL     = time.Hour
+	maxCachedLogos   = 256
+)
+
+var (
+	errLogoScheme  = errors.New("logo adresi https olmalıdır")
+	errLogoAddress = errors.New("logo adresi iç ağa veya yerel makineye işaret ediyor")
+)
+
+// logoClient yalnızca genel internetteki adreslere bağlanır. Adres DNS çözümlemesinden
+// sonra bağlantı anında denetlendiği için yönlendirme veya DNS yeniden bağlama ile iç
+// ağa ulaşılamaz.
+var logoClient = &http.Client{
+	Timeout: logoFetchTimeout,
+	Transport: &http.Transport{
+		Proxy: nil, // Ortam proxy'si adres denetimini atlatmasın
+		DialContext: (&net.Dialer{
+			Timeout: logoFetchTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip, err := netip.ParseAddr(host)
+				if err != nil || !publicAddr(ip) {
+					return errLogoAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   logoFetchTimeout,
+		ResponseHeaderTimeout: logoFetchTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errLogoScheme
+		}
+		if len(via) >= 3 {
+			return errors.New("logo adresi çok fazla yönlendirme yaptı")
+		}
+		return nil
+	},
+}
+
+// publicAddr - adres genel internette mi (yerel, özel, link-local ve çoklu yayın değil)
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// CGNAT adres aralığı (RFC 6598); IsPrivate kapsamaz
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// FetchLogo - TenantBranding.LogoURL adresindeki logoyu indirir. Yalnızca genel internetteki
+// https adresleri kabul edilir; yanıt maxLogoSize ile sınırlıdır.
+func FetchLogo(ctx context.Context, logoURL string) ([]byte, error) {
+	u, err := url.Parse(logoURL)
+	if err != nil {
+		return nil, fmt.Errorf("logo adresi geçersiz: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, errLogoScheme
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := logoClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("logo indirilemedi: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogoSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxLogoSize {
+		return nil, fmt.Errorf("logo çok büyük (en fazla %d bayt)", maxLogoSize)
+	}
+	return data, nil
+}
+
+// LogoCache - kiracı logolarını süreli olarak bellekte tutar; her PDF için logo yeniden
+// indirilmez. Kiracının logo adresi değişirse yeni adres hemen indirilir. İndirilemeyen
+// logolar önbelleğe alınmaz.
+type LogoCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]cachedLogo
+	now   func() time.Time
+	fetch func(ctx context.Context, logoURL string) ([]byte, error)
+}
+
+type cachedLogo struct {
+	url     string
+	data    []byte
+	expires time.Time
+}
+
+// NewLogoCache - logoları ttl süresince tutan önbellek
+func NewLogoCache(ttl time.Duration) *LogoCache {
+	return &LogoCache{ttl: ttl, items: make(map[string]cachedLogo), now: time.Now, fetch: FetchLogo}
+}
+
+// Get - kiracının logosunu önbellekten veya logoURL'den getirir
+func (c *LogoCache) Get(ctx context.Context, tenantID, logoURL string) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.items[tenantID]
+	c.mu.Unlock()
+	if ok && entry.url == logoURL && c.now().Before(entry.expires) {
+		return entry.data, nil
+	}
+
+	data, err := c.fetch(ctx, logoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, ok := c.items[tenantID]; !ok && len(c.items) >= maxCachedLogos {
+		c.evict(now)
+	}
+	c.items[tenantID] = cachedLogo{url: logoURL, data: data, expires: now.Add(c.ttl)}
+	return data, nil
+}
+
+// evict - süresi dolan logoları, yoksa süresi en yakın olanı siler
+func (c *LogoCache) evict(now time.Time) {
+	var oldest string
+	for id, entry := range c.items {
+		if !now.Before(entry.expires) {
+			delete(c.items, id)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.items[oldest].expires) {
+			oldest = id
+		}
+	}
+	if len(c.items) >= maxCachedLogos {
+		delete(c.items, oldest)
+	}
+}
+
+var tenantLogos = NewLogoCache(logoCacheTTL)
+
+// FetchTenantLogo - kiracının logosunu paylaşılan önbellek üzerinden getirir. Kiracısı
+// bilinmeyen istekte logo önbelleğe alınmadan indirilir.
+func FetchTenantLogo(ctx context.Context, tenantID, logoURL string) ([]byte, error) {
+	if tenantID == "" {
+		return FetchLogo(ctx, logoURL)
+	}
+	return tenantLogos.Get(ctx, tenantID, logoURL)
+}
+
+// parseHexColor - "#2563EB" veya "2563eb" biçimindeki rengi çözer
+func parseHexColor(s string) (rgb, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return rgb{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return rgb{}, false
+	}
+	return rgb{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}, true
+}
+
+// tint - rengi ratio oranında beyazla karıştırır (arka planlarda okunabilirlik için)
+func tint(c rgb, ratio float64) rgb {
+	mix := func(v int) int { return v + int(float64(255-v)*ratio+0.5) }
+	return rgb{mix(c.r), mix(c.g), mix(c.b)}
+}
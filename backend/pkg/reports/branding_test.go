@@ -0,0 +1,110 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchLogo_RequiresHTTPS(t *testing.T) {
+	for _, u := range []string{"http://example.com/logo.png", "ftp://example.com/logo.png", "/logo.png", "https://"} {
+		_, err := FetchLogo(t.Context(), u)
+		assert.ErrorIs(t, err, errLogoScheme, u)
+	}
+}
+
+func TestFetchLogo_BlocksInternalAddresses(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("iç ağdaki sunucuya istek yapılmamalı")
+	}))
+	defer srv.Close()
+
+	_, err := FetchLogo(t.Context(), srv.URL+"/logo.png")
+	assert.ErrorIs(t, err, errLogoAddress)
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestLogoCache(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	fetches := map[string]int{}
+	fail := false
+	c := NewLogoCache(time.Hour)
+	c.now = func() time.Time { return now }
+	c.fetch = func(_ context.Context, logoURL string) ([]byte, error) {
+		if fail {
+			return nil, errors.New("indirilemedi")
+		}
+		fetches[logoURL]++
+		return []byte(logoURL), nil
+	}
+
+	get := func(tenantID, logoURL string) []byte {
+		data, err := c.Get(t.Context(), tenantID, logoURL)
+		require.NoError(t, err)
+		return data
+	}
+
+	assert.Equal(t, []byte("https://a/logo.png"), get("t1", "https://a/logo.png"))
+	assert.Equal(t, []byte("https://a/logo.png"), get("t1", "https://a/logo.png"))
+	assert.Equal(t, 1, fetches["https://a/logo.png"])
+
+	// Her kiracının logosu ayrı tutulur
+	get("t2", "https://a/logo.png")
+	assert.Equal(t, 2, fetches["https://a/logo.png"])
+
+	// Logo adresi değişince yeniden indirilir
+	assert.Equal(t, []byte("https://b/logo.png"), get("t1", "https://b/logo.png"))
+	assert.Equal(t, 1, fetches["https://b/logo.png"])
+
+	// Süresi dolan logo yeniden indirilir
+	now = now.Add(time.Hour)
+	get("t1", "https://b/logo.png")
+	assert.Equal(t, 2, fetches["https://b/logo.png"])
+
+	// İndirilemeyen logo önbelleğe alınmaz
+	fail = true
+	_, err := c.Get(t.Context(), "t3", "https://c/logo.png")
+	assert.Error(t, err)
+	fail = false
+	get("t3", "https://c/logo.png")
+	assert.Equal(t, 1, fetches["https://c/logo.png"])
+}
+
+func TestLogoCache_Bounded(t *testing.T) {
+	c := NewLogoCache(time.Hour)
+	c.fetch = func(_ context.Context, logoURL string) ([]byte, error) { return []byte(logoURL), nil }
+
+	for i := range maxCachedLogos + 10 {
+		_, err := c.Get(t.Context(), fmt.Sprintf("t%d", i), "https://a/logo.png")
+		require.NoError(t, err)
+	}
+	assert.Len(t, c.items, maxCachedLogos)
+}
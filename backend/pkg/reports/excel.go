@@ -147,6 +147,72 @@ func (g *ExcelGenerator) GenerateConsumptionExcel(data *ConsumptionReportData) (
 	return buf.Bytes(), err
 }
 
+// GenerateBudgetExcel - Bütçe / gerçekleşen ve işletme hesabı Excel raporu
+func (g *ExcelGenerator) GenerateBudgetExcel(data *BudgetReportData) ([]byte, error) {
+	sheet := "Bütçe - Gerçekleşen"
+	g.file.SetSheetName("Sheet1", sheet)
+
+	g.file.SetCellValue(sheet, "A1", data.PropertyName)
+	g.file.SetCellValue(sheet, "A2", fmt.Sprintf("%s: %s - %s (Rapor Tarihi %s)", data.BudgetName, data.StartDate, data.EndDate, data.AsOf))
+	if data.ApprovalReference != "" {
+		g.file.SetCellValue(sheet, "A3", fmt.Sprintf("Genel Kurul Kararı: %s", data.ApprovalReference))
+	}
+
+	headers := []string{"Gider Kalemi", "Yıllık Plan", "Dönem Planı", "Gerçekleşen", "Bekleyen", "Fark", "Kullanım (%)"}
+	for i, h := range headers {
+		col := string(rune('A' + i))
+		g.file.SetCellValue(sheet, col+"5", h)
+	}
+
+	lines := append(append([]BudgetLine{}, data.Lines...), data.Total)
+	for i, line := range lines {
+		row := 6 + i
+		if i == len(lines)-1 {
+			row++ // Toplam satırından önce bir satır boşluk
+		}
+		g.file.SetCellValue(sheet, fmt.Sprintf("A%d", row), line.Category)
+		g.file.SetCellValue(sheet, fmt.Sprintf("B%d", row), line.Planned)
+		g.file.SetCellValue(sheet, fmt.Sprintf("C%d", row), line.PlannedToDate)
+		g.file.SetCellValue(sheet, fmt.Sprintf("D%d", row), line.Actual)
+		g.file.SetCellValue(sheet, fmt.Sprintf("E%d", row), line.Pending)
+		g.file.SetCellValue(sheet, fmt.Sprintf("F%d", row), line.Variance)
+		g.file.SetCellValue(sheet, fmt.Sprintf("G%d", row), line.Utilization)
+	}
+	g.file.SetColWidth(sheet, "A", "A", 30)
+	g.file.SetColWidth(sheet, "B", "G", 15)
+
+	// İşletme hesabı - yeni sheet
+	accountSheet := "İşletme Hesabı"
+	g.file.NewSheet(accountSheet)
+
+	g.file.SetCellValue(accountSheet, "A1", data.PropertyName)
+	g.file.SetCellValue(accountSheet, "A2", fmt.Sprintf("İşletme Hesabı: %s - %s", data.StartDate, data.AsOf))
+	result := "Dönem Fazlası"
+	if data.OperatingResult < 0 {
+		result = "Dönem Açığı"
+	}
+	rows := [][]interface{}{
+		{"Aidat Gelirleri", data.Assessed},
+		{"Gecikme Tazminatı Gelirleri", data.LateFees},
+		{"Giderler", -data.Total.Actual},
+		{result, data.OperatingResult},
+		{"Tahsil Edilen", data.Collected},
+	}
+	for i, r := range rows {
+		g.file.SetCellValue(accountSheet, fmt.Sprintf("A%d", 4+i), r[0])
+		g.file.SetCellValue(accountSheet, fmt.Sprintf("B%d", 4+i), r[1])
+	}
+	g.file.SetColWidth(accountSheet, "A", "A", 30)
+	g.file.SetColWidth(accountSheet, "B", "B", 18)
+
+	g.applyStyles(sheet)
+	g.applyStyles(accountSheet)
+
+	var buf bytes.Buffer
+	err := g.file.Write(&buf)
+	return buf.Bytes(), err
+}
+
 // GenerateMeterReadingTemplate - Sayaç okuma şablonu (boş)
 func (g *ExcelGenerator) GenerateMeterReadingTemplate(units []MeterTemplateUnit) ([]byte, error) {
 	sheet := "Sayaç Okuma"
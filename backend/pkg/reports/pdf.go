@@ -12,6 +12,9 @@ import (
 type PDFGenerator struct {
 	pdf       *gofpdf.Fpdf
 	pageWidth float64
+	primary   rgb    // Tablo başlıkları
+	secondary rgb    // Özet kartları
+	logo      string // Kayıtlı logo görseli (boşsa logo basılmaz)
 }
 
 // NewPDFGenerator - Yeni PDF generator
@@ -22,6 +25,8 @@ func NewPDFGenerator() *PDFGenerator {
 	return &PDFGenerator{
 		pdf:       pdf,
 		pageWidth: 210,
+		primary:   defaultPrimary,
+		secondary: defaultSecondary,
 	}
 }
 
@@ -32,7 +37,7 @@ func (g *PDFGenerator) GenerateAssessmentReport(data *AssessmentReportData) ([]b
 	
 	// Dönem bilgisi
 	g.pdf.SetFont("Arial", "B", 12)
-	g.pdf.Cell(0, 10, fmt.Sprintf("Dönem: %s", data.Period), "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 10, fmt.Sprintf("Dönem: %s", data.Period), "", 1, "L", false, 0, "")
 	g.pdf.Ln(5)
 
 	// Özet kartları
@@ -46,7 +51,7 @@ func (g *PDFGenerator) GenerateAssessmentReport(data *AssessmentReportData) ([]b
 	// Gider kalemleri tablosu
 	g.pdf.Ln(10)
 	g.pdf.SetFont("Arial", "B", 11)
-	g.pdf.Cell(0, 8, "Gider Kalemleri", "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 8, "Gider Kalemleri", "", 1, "L", false, 0, "")
 
 	headers := []string{"Kalem", "Dağıtım", "Tutar"}
 	widths := []float64{80, 50, 50}
@@ -63,7 +68,7 @@ func (g *PDFGenerator) GenerateAssessmentReport(data *AssessmentReportData) ([]b
 	// Daire bazlı tahakkuklar
 	g.pdf.AddPage()
 	g.pdf.SetFont("Arial", "B", 11)
-	g.pdf.Cell(0, 8, "Daire Bazlı Tahakkuklar", "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 8, "Daire Bazlı Tahakkuklar", "", 1, "L", false, 0, "")
 
 	unitHeaders := []string{"Daire", "Sakin", "Tahakkuk", "Ödenen", "Kalan"}
 	unitWidths := []float64{30, 50, 35, 35, 35}
@@ -92,7 +97,7 @@ func (g *PDFGenerator) GenerateCollectionReport(data *CollectionReportData) ([]b
 	g.addHeader(data.PropertyName, "Tahsilat Raporu")
 
 	g.pdf.SetFont("Arial", "", 10)
-	g.pdf.Cell(0, 8, fmt.Sprintf("Rapor Dönemi: %s - %s", data.StartDate, data.EndDate), "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 8, fmt.Sprintf("Rapor Dönemi: %s - %s", data.StartDate, data.EndDate), "", 1, "L", false, 0, "")
 	g.pdf.Ln(5)
 
 	// Ödeme listesi
@@ -112,8 +117,8 @@ func (g *PDFGenerator) GenerateCollectionReport(data *CollectionReportData) ([]b
 
 	// Toplam
 	g.pdf.SetFont("Arial", "B", 10)
-	g.pdf.Cell(105, 8, "", "", 0, "", false, 0, "")
-	g.pdf.Cell(35, 8, formatCurrency(data.TotalAmount), "T", 1, "R", false, 0, "")
+	g.pdf.CellFormat(105, 8, "", "", 0, "", false, 0, "")
+	g.pdf.CellFormat(35, 8, formatCurrency(data.TotalAmount), "T", 1, "R", false, 0, "")
 
 	g.addFooter()
 
@@ -128,7 +133,7 @@ func (g *PDFGenerator) GenerateConsumptionReport(data *ConsumptionReportData) ([
 	g.addHeader(data.PropertyName, fmt.Sprintf("%s Tüketim Raporu", data.MeterType))
 
 	g.pdf.SetFont("Arial", "", 10)
-	g.pdf.Cell(0, 8, fmt.Sprintf("Dönem: %s", data.Period), "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 8, fmt.Sprintf("Dönem: %s", data.Period), "", 1, "L", false, 0, "")
 	g.pdf.Ln(5)
 
 	headers := []string{"Daire", "Sayaç No", "Önceki", "Yeni", "Tüketim", "Tutar"}
@@ -153,14 +158,170 @@ func (g *PDFGenerator) GenerateConsumptionReport(data *ConsumptionReportData) ([
 	return buf.Bytes(), err
 }
 
+// GenerateStatement - Daire hesap ekstresi (yürüyen bakiyeli)
+func (g *PDFGenerator) GenerateStatement(data *StatementReportData) ([]byte, error) {
+	g.pdf.AddPage()
+	g.addHeader(data.PropertyName, "Hesap Ekstresi")
+
+	g.pdf.SetFont("Arial", "", 10)
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Daire: %s", data.UnitName), "", 1, "L", false, 0, "")
+	if data.ResidentName != "" {
+		g.pdf.CellFormat(0, 6, fmt.Sprintf("Sakin: %s", data.ResidentName), "", 1, "L", false, 0, "")
+	}
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Dönem: %s - %s", data.StartDate, data.EndDate), "", 1, "L", false, 0, "")
+	g.pdf.Ln(4)
+
+	g.addSummaryCards([]SummaryCard{
+		{Label: "Devir Bakiyesi", Value: formatCurrency(data.OpeningBalance)},
+		{Label: "Borç", Value: formatCurrency(data.TotalDebit)},
+		{Label: "Alacak", Value: formatCurrency(data.TotalCredit)},
+		{Label: "Güncel Bakiye", Value: formatCurrency(data.ClosingBalance)},
+	})
+	g.pdf.Ln(6)
+
+	headers := []string{"Tarih", "Belge", "Açıklama", "Borç", "Alacak", "Bakiye"}
+	widths := []float64{22, 22, 71, 25, 25, 25}
+	aligns := []string{"L", "L", "L", "R", "R", "R"}
+	g.addTableHeader(headers, widths)
+
+	g.addAlignedRow([]string{"", "", "Devir", "", "", formatCurrency(data.OpeningBalance)}, widths, aligns)
+	for _, line := range data.Lines {
+		g.addAlignedRow([]string{
+			line.Date,
+			line.DocumentType,
+			line.Description,
+			formatOptionalCurrency(line.Debit),
+			formatOptionalCurrency(line.Credit),
+			formatCurrency(line.Balance),
+		}, widths, aligns)
+	}
+
+	g.pdf.SetFont("Arial", "B", 9)
+	g.pdf.CellFormat(115, 7, "Toplam", "1", 0, "R", false, 0, "")
+	g.pdf.CellFormat(25, 7, formatCurrency(data.TotalDebit), "1", 0, "R", false, 0, "")
+	g.pdf.CellFormat(25, 7, formatCurrency(data.TotalCredit), "1", 0, "R", false, 0, "")
+	g.pdf.CellFormat(25, 7, formatCurrency(data.ClosingBalance), "1", 1, "R", false, 0, "")
+
+	for _, plan := range data.PaymentPlans {
+		g.addStatementPlan(plan)
+	}
+
+	g.addFooter()
+
+	var buf bytes.Buffer
+	err := g.pdf.Output(&buf)
+	return buf.Bytes(), err
+}
+
+// addStatementPlan ekstreye ödeme planı ve taksit tablosunu ekler
+func (g *PDFGenerator) addStatementPlan(plan StatementPlan) {
+	g.pdf.Ln(8)
+	g.pdf.SetFont("Arial", "B", 12)
+	g.pdf.CellFormat(0, 8, fmt.Sprintf("Ödeme Planı (%s) - %s", plan.CreatedAt, plan.Status), "", 1, "L", false, 0, "")
+	g.pdf.SetFont("Arial", "", 9)
+	summary := fmt.Sprintf("Taksitlendirilen: %s   Ödenen: %s", formatCurrency(plan.TotalAmount), formatCurrency(plan.PaidAmount))
+	if plan.WaivedLateFee > 0 {
+		summary += fmt.Sprintf("   Affedilen tazminat: %s", formatCurrency(plan.WaivedLateFee))
+	}
+	g.pdf.CellFormat(0, 6, summary, "", 1, "L", false, 0, "")
+	g.pdf.Ln(2)
+
+	widths := []float64{20, 40, 45, 45, 40}
+	aligns := []string{"C", "L", "R", "R", "L"}
+	g.addTableHeader([]string{"Taksit", "Vade", "Tutar", "Ödenen", "Durum"}, widths)
+	for _, inst := range plan.Installments {
+		g.addAlignedRow([]string{
+			fmt.Sprintf("%d", inst.No),
+			inst.DueDate,
+			formatCurrency(inst.Amount),
+			formatCurrency(inst.PaidAmount),
+			inst.Status,
+		}, widths, aligns)
+	}
+}
+
+// GenerateBudgetReport - İşletme projesi bütçe / gerçekleşen raporu ve dönem işletme hesabı
+func (g *PDFGenerator) GenerateBudgetReport(data *BudgetReportData) ([]byte, error) {
+	g.pdf.AddPage()
+	g.addHeader(data.PropertyName, "Bütçe - Gerçekleşen Raporu")
+
+	g.pdf.SetFont("Arial", "", 10)
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Proje: %s", data.BudgetName), "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Dönem: %s - %s (%d/12 ay)", data.StartDate, data.EndDate, data.MonthsElapsed), "", 1, "L", false, 0, "")
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Rapor Tarihi: %s", data.AsOf), "", 1, "L", false, 0, "")
+	if data.ApprovalReference != "" {
+		g.pdf.CellFormat(0, 6, fmt.Sprintf("Genel Kurul Kararı: %s", data.ApprovalReference), "", 1, "L", false, 0, "")
+	}
+	g.pdf.Ln(4)
+
+	g.addSummaryCards([]SummaryCard{
+		{Label: "Yıllık Bütçe", Value: formatCurrency(data.Total.Planned)},
+		{Label: "Dönem Bütçesi", Value: formatCurrency(data.Total.PlannedToDate)},
+		{Label: "Gerçekleşen", Value: formatCurrency(data.Total.Actual)},
+		{Label: "Kullanım", Value: fmt.Sprintf("%%%.1f", data.Total.Utilization)},
+	})
+	g.pdf.Ln(6)
+
+	headers := []string{"Gider Kalemi", "Yıllık Plan", "Dönem Planı", "Gerçekleşen", "Bekleyen", "Fark", "Kullanım"}
+	widths := []float64{50, 25, 25, 25, 22, 25, 18}
+	aligns := []string{"L", "R", "R", "R", "R", "R", "R"}
+	g.addTableHeader(headers, widths)
+	for _, line := range data.Lines {
+		g.addAlignedRow(budgetRow(line), widths, aligns)
+	}
+	g.pdf.SetFont("Arial", "B", 9)
+	for i, cell := range budgetRow(data.Total) {
+		g.pdf.CellFormat(widths[i], 7, cell, "1", 0, aligns[i], false, 0, "")
+	}
+	g.pdf.Ln(10)
+
+	// İşletme hesabı (gelir - gider)
+	g.pdf.SetFont("Arial", "B", 12)
+	g.pdf.CellFormat(0, 8, "İşletme Hesabı", "", 1, "L", false, 0, "")
+	accountWidths := []float64{140, 50}
+	accountAligns := []string{"L", "R"}
+	g.addAlignedRow([]string{"Aidat Gelirleri", formatCurrency(data.Assessed)}, accountWidths, accountAligns)
+	g.addAlignedRow([]string{"Gecikme Tazminatı Gelirleri", formatCurrency(data.LateFees)}, accountWidths, accountAligns)
+	g.addAlignedRow([]string{"Giderler", formatCurrency(-data.Total.Actual)}, accountWidths, accountAligns)
+	result := "Dönem Fazlası"
+	if data.OperatingResult < 0 {
+		result = "Dönem Açığı"
+	}
+	g.pdf.SetFont("Arial", "B", 9)
+	g.pdf.CellFormat(accountWidths[0], 7, result, "1", 0, "L", false, 0, "")
+	g.pdf.CellFormat(accountWidths[1], 7, formatCurrency(data.OperatingResult), "1", 1, "R", false, 0, "")
+	g.addAlignedRow([]string{"Tahsil Edilen", formatCurrency(data.Collected)}, accountWidths, accountAligns)
+
+	g.addFooter()
+
+	var buf bytes.Buffer
+	err := g.pdf.Output(&buf)
+	return buf.Bytes(), err
+}
+
+func budgetRow(line BudgetLine) []string {
+	return []string{
+		line.Category,
+		formatCurrency(line.Planned),
+		formatCurrency(line.PlannedToDate),
+		formatCurrency(line.Actual),
+		formatOptionalCurrency(line.Pending),
+		formatCurrency(line.Variance),
+		fmt.Sprintf("%%%.1f", line.Utilization),
+	}
+}
+
 func (g *PDFGenerator) addHeader(propertyName, reportTitle string) {
+	if g.logo != "" {
+		g.pdf.ImageOptions(g.logo, 10, 10, 0, 18, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	}
 	g.pdf.SetFont("Arial", "B", 16)
-	g.pdf.Cell(0, 10, propertyName, "", 1, "C", false, 0, "")
+	g.pdf.CellFormat(0, 10, propertyName, "", 1, "C", false, 0, "")
 	g.pdf.SetFont("Arial", "", 14)
-	g.pdf.Cell(0, 8, reportTitle, "", 1, "C", false, 0, "")
+	g.pdf.CellFormat(0, 8, reportTitle, "", 1, "C", false, 0, "")
 	g.pdf.SetFont("Arial", "", 9)
 	g.pdf.SetTextColor(128, 128, 128)
-	g.pdf.Cell(0, 6, fmt.Sprintf("Oluşturulma: %s", time.Now().Format("02.01.2006 15:04")), "", 1, "C", false, 0, "")
+	g.pdf.CellFormat(0, 6, fmt.Sprintf("Oluşturulma: %s", time.Now().Format("02.01.2006 15:04")), "", 1, "C", false, 0, "")
 	g.pdf.SetTextColor(0, 0, 0)
 	g.pdf.Ln(5)
 }
@@ -169,13 +330,13 @@ func (g *PDFGenerator) addFooter() {
 	g.pdf.SetY(-20)
 	g.pdf.SetFont("Arial", "I", 8)
 	g.pdf.SetTextColor(128, 128, 128)
-	g.pdf.Cell(0, 10, "SiteEksen - Site Yönetim Platformu", "", 0, "C", false, 0, "")
+	g.pdf.CellFormat(0, 10, "SiteEksen - Site Yönetim Platformu", "", 0, "C", false, 0, "")
 }
 
 func (g *PDFGenerator) addSummaryCards(cards []SummaryCard) {
 	cardWidth := (g.pageWidth - 20) / float64(len(cards))
 	
-	g.pdf.SetFillColor(240, 240, 245)
+	g.pdf.SetFillColor(g.secondary.r, g.secondary.g, g.secondary.b)
 	for _, card := range cards {
 		g.pdf.SetFont("Arial", "", 9)
 		g.pdf.CellFormat(cardWidth-2, 6, card.Label, "", 0, "C", true, 0, "")
@@ -189,7 +350,7 @@ func (g *PDFGenerator) addSummaryCards(cards []SummaryCard) {
 }
 
 func (g *PDFGenerator) addTableHeader(headers []string, widths []float64) {
-	g.pdf.SetFillColor(37, 99, 235)
+	g.pdf.SetFillColor(g.primary.r, g.primary.g, g.primary.b)
 	g.pdf.SetTextColor(255, 255, 255)
 	g.pdf.SetFont("Arial", "B", 9)
 	
@@ -208,6 +369,33 @@ func (g *PDFGenerator) addTableRow(cells []string, widths []float64) {
 	g.pdf.Ln(-1)
 }
 
+// addAlignedRow - hizalaması sütun bazında verilen satır; sığmayan metin kısaltılır
+func (g *PDFGenerator) addAlignedRow(cells []string, widths []float64, aligns []string) {
+	g.pdf.SetFont("Arial", "", 9)
+	for i, cell := range cells {
+		g.pdf.CellFormat(widths[i], 6, g.fitText(cell, widths[i]-2), "1", 0, aligns[i], false, 0, "")
+	}
+	g.pdf.Ln(-1)
+}
+
+func (g *PDFGenerator) fitText(text string, width float64) string {
+	if g.pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && g.pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func formatOptionalCurrency(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return formatCurrency(amount)
+}
+
 func formatCurrency(amount float64) string {
 	return fmt.Sprintf("₺%.2f", amount)
 }
@@ -259,6 +447,74 @@ type PaymentEntry struct {
 	Method       string
 }
 
+type StatementReportData struct {
+	PropertyName   string
+	UnitName       string
+	ResidentName   string
+	StartDate      string
+	EndDate        string
+	OpeningBalance float64
+	TotalDebit     float64
+	TotalCredit    float64
+	ClosingBalance float64
+	Lines          []StatementEntry
+	PaymentPlans   []StatementPlan
+}
+
+// StatementPlan ekstrede gösterilen ödeme planı (taksitlendirme)
+type StatementPlan struct {
+	Status        string
+	CreatedAt     string
+	TotalAmount   float64
+	WaivedLateFee float64
+	PaidAmount    float64
+	Installments  []StatementInstallment
+}
+
+type StatementInstallment struct {
+	No         int
+	DueDate    string
+	Amount     float64
+	PaidAmount float64
+	Status     string
+}
+
+type StatementEntry struct {
+	Date         string
+	DocumentType string
+	Description  string
+	Debit        float64
+	Credit       float64
+	Balance      float64
+}
+
+type BudgetReportData struct {
+	PropertyName      string
+	BudgetName        string
+	Status            string
+	ApprovalReference string
+	StartDate         string
+	EndDate           string
+	AsOf              string
+	MonthsElapsed     int
+	Lines             []BudgetLine
+	Total             BudgetLine
+	Assessed          float64
+	LateFees          float64
+	Collected         float64
+	OperatingResult   float64
+}
+
+type BudgetLine struct {
+	Category      string
+	Planned       float64
+	PlannedToDate float64
+	Actual        float64
+	Pending       float64
+	Variance      float64
+	Utilization   float64
+}
+
 type ConsumptionReportData struct {
 	PropertyName string
 	MeterType    string
@@ -0,0 +1,128 @@
+package reports
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func testLogo(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 200, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestGenerateStatement_WithBranding(t *testing.T) {
+	g := NewPDFGenerator()
+	require.NoError(t, g.SetBranding(&tenant.TenantBranding{PrimaryColor: "#0F766E", SecondaryColor: "#F59E0B"}, testLogo(t)))
+	assert.Equal(t, rgb{15, 118, 110}, g.primary)
+
+	out, err := g.GenerateStatement(&StatementReportData{
+		PropertyName:   "Mavikent Sitesi",
+		UnitName:       "A-3",
+		StartDate:      "01.01.2026",
+		EndDate:        "31.01.2026",
+		OpeningBalance: 150,
+		TotalDebit:     1200,
+		TotalCredit:    1000,
+		ClosingBalance: 350,
+		Lines: []StatementEntry{
+			{Date: "01.01.2026", DocumentType: "AIDAT", Description: "2026/01 dönemi aidat tahakkuku - A-3", Debit: 1200, Balance: 1350},
+			{Date: "12.01.2026", DocumentType: "TAHSILAT", Description: "Kredi kartı tahsilatı (iyzico çok uzun bir işlem açıklaması ile birlikte)", Credit: 1000, Balance: 350},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF")))
+}
+
+func TestGenerateStatement_WithPaymentPlan(t *testing.T) {
+	out, err := NewPDFGenerator().GenerateStatement(&StatementReportData{
+		PropertyName: "Mavikent Sitesi",
+		UnitName:     "A-3",
+		StartDate:    "01.01.2026",
+		EndDate:      "31.03.2026",
+		PaymentPlans: []StatementPlan{{
+			Status:        "Devam ediyor",
+			CreatedAt:     "15.01.2026",
+			TotalAmount:   3000,
+			WaivedLateFee: 120,
+			PaidAmount:    1000,
+			Installments: []StatementInstallment{
+				{No: 1, DueDate: "01.02.2026", Amount: 1000, PaidAmount: 1000, Status: "Ödendi"},
+				{No: 2, DueDate: "01.03.2026", Amount: 1000, Status: "Bekliyor"},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF")))
+}
+
+func TestSetBranding_InvalidLogo(t *testing.T) {
+	g := NewPDFGenerator()
+	assert.Error(t, g.SetBranding(nil, []byte("not an image")))
+	assert.Empty(t, g.logo)
+	assert.Equal(t, defaultPrimary, g.primary)
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, ok := parseHexColor("#2563EB")
+	assert.True(t, ok)
+	assert.Equal(t, defaultPrimary, c)
+
+	_, ok = parseHexColor("blue")
+	assert.False(t, ok)
+	_, ok = parseHexColor("")
+	assert.False(t, ok)
+}
+
+func testBudgetData() *BudgetReportData {
+	return &BudgetReportData{
+		PropertyName:      "Mavikent Sitesi",
+		BudgetName:        "2026 İşletme Projesi",
+		ApprovalReference: "Olağan genel kurul 15.01.2026 / 3 nolu karar",
+		StartDate:         "01.01.2026",
+		EndDate:           "31.12.2026",
+		AsOf:              "31.03.2026",
+		MonthsElapsed:     3,
+		Lines: []BudgetLine{
+			{Category: "Temizlik", Planned: 12000, PlannedToDate: 3000, Actual: 3200, Variance: -200, Utilization: 26.7},
+			{Category: "Asansör Bakım", Planned: 6000, PlannedToDate: 1500, Actual: 1000, Pending: 500, Variance: 500, Utilization: 16.7},
+		},
+		Total:           BudgetLine{Category: "Toplam", Planned: 18000, PlannedToDate: 4500, Actual: 4200, Pending: 500, Variance: 300, Utilization: 23.3},
+		Assessed:        4500,
+		LateFees:        35.5,
+		Collected:       4100,
+		OperatingResult: 335.5,
+	}
+}
+
+func TestGenerateBudgetReport(t *testing.T) {
+	out, err := NewPDFGenerator().GenerateBudgetReport(testBudgetData())
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF")))
+}
+
+func TestGenerateBudgetExcel(t *testing.T) {
+	out, err := NewExcelGenerator().GenerateBudgetExcel(testBudgetData())
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bütçe - Gerçekleşen", "İşletme Hesabı"}, f.GetSheetList())
+
+	total, err := f.GetCellValue("Bütçe - Gerçekleşen", "A9")
+	require.NoError(t, err)
+	assert.Equal(t, "Toplam", total)
+	result, err := f.GetCellValue("İşletme Hesabı", "B7")
+	require.NoError(t, err)
+	assert.Equal(t, "335.5", result)
+}
@@ -0,0 +1,45 @@
+// Package server servislerin HTTP sunucusunu çalıştırır ve kapanış sinyalinde sunucuyu ve
+// arka plan işlerini düzgünce durdurur.
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout kapanışta süren isteklerin tamamlanması için beklenen en uzun süre
+const ShutdownTimeout = 10 * time.Second
+
+// Context SIGINT veya SIGTERM gelince iptal edilen context döner. Arka plan işleri
+// (kiracı bildirimleri, zamanlanmış işler) bu context ile başlatılır.
+func Context() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// Run handler'ı addr'de sunar. ctx iptal edilince yeni bağlantı kabul edilmez, süren
+// istekler en fazla ShutdownTimeout kadar beklenir.
+func Run(ctx context.Context, addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
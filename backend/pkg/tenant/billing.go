@@ -0,0 +1,609 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/payment"
+)
+
+// Fatura döngüleri (tenants.billing_cycle)
+const (
+	CycleMonthly = "monthly"
+	CycleYearly  = "yearly"
+)
+
+// Fatura türleri ve durumları (invoices)
+const (
+	InvoiceSubscription = "subscription" // Dönem başında kesilen abonelik faturası
+	InvoiceProration    = "proration"    // Dönem içi plan yükseltme farkı
+
+	InvoiceOpen = "open"
+	InvoicePaid = "paid"
+	InvoiceVoid = "void"
+)
+
+var (
+	ErrNoPaymentMethod = errors.New("no saved card for tenant")
+	ErrPaymentDeclined = errors.New("payment declined")
+)
+
+// SavedCard kiracının iyzico'da saklanan kartı; kart numarası tutulmaz
+type SavedCard struct {
+	Token    string `json:"-"`
+	UserKey  string `json:"-"`
+	LastFour string `json:"last_four"`
+}
+
+// BillingAccount kiracının abonelik ve tahsilat bilgileri. Tutarlar kuruş cinsindendir.
+type BillingAccount struct {
+	TenantID         string     `json:"tenant_id"`
+	TenantName       string     `json:"tenant_name"`
+	Plan             string     `json:"plan"`
+	Status           string     `json:"status"`
+	Cycle            string     `json:"billing_cycle"`
+	BillingEmail     string     `json:"billing_email,omitempty"`
+	TrialEndsAt      time.Time  `json:"trial_ends_at,omitzero"`
+	PeriodStart      time.Time  `json:"current_period_start,omitzero"`
+	PeriodEnd        time.Time  `json:"current_period_end,omitzero"`
+	Card             *SavedCard `json:"card,omitempty"`
+	CreditBalance    int64      `json:"credit_balance"`
+	DunningAttempts  int        `json:"dunning_attempts"`
+	NextRetryAt      time.Time  `json:"next_retry_at,omitzero"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+}
+
+// Invoice kiracı faturası (invoices tablosu). AmountTotal = Subtotal - CreditApplied.
+type Invoice struct {
+	ID            string     `json:"id"`
+	TenantID      string     `json:"tenant_id"`
+	Kind          string     `json:"kind"`
+	Plan          string     `json:"plan"`
+	Cycle         string     `json:"billing_cycle"`
+	Description   string     `json:"description"`
+	Subtotal      int64      `json:"subtotal"`
+	CreditApplied int64      `json:"credit_applied"`
+	AmountTotal   int64      `json:"amount_total"`
+	Currency      string     `json:"currency"`
+	Status        string     `json:"status"`
+	PeriodStart   time.Time  `json:"period_start"`
+	PeriodEnd     time.Time  `json:"period_end"`
+	AttemptCount  int        `json:"attempt_count"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	PaymentID     string     `json:"payment_id,omitempty"`
+	TransactionID string     `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
+
+// BillingStore abonelik hesapları ve faturalar
+type BillingStore interface {
+	// Account kiracının abonelik bilgileri; kiracı yoksa ErrTenantNotFound
+	Account(ctx context.Context, tenantID string) (*BillingAccount, error)
+	// DueAccounts deneme süresi veya dönemi bitmiş ya da yeniden deneme zamanı gelmiş kiracılar
+	DueAccounts(ctx context.Context, now time.Time) ([]string, error)
+	// SaveAccount plan, durum, dönem, alacak ve yeniden deneme alanlarını yazar; plan limitleri
+	// ve özellikleri plana göre güncellenir
+	SaveAccount(ctx context.Context, a *BillingAccount) error
+	// SaveCard kiracının kayıtlı kartını ve fatura e-postasını değiştirir
+	SaveCard(ctx context.Context, tenantID string, card SavedCard, email string) error
+	// CreateInvoice faturayı ekler. Dönemin abonelik faturası zaten varsa mevcut fatura
+	// inv'e yüklenir ve false döner.
+	CreateInvoice(ctx context.Context, inv *Invoice) (bool, error)
+	// UpdateInvoice durum, deneme ve ödeme alanlarını yazar
+	UpdateInvoice(ctx context.Context, inv *Invoice) error
+	// OpenInvoices ödenmemiş faturalar (eskiden yeniye)
+	OpenInvoices(ctx context.Context, tenantID string) ([]Invoice, error)
+	// ListInvoices tüm faturalar (yeniden eskiye)
+	ListInvoices(ctx context.Context, tenantID string) ([]Invoice, error)
+	// LockAccount kiracının tahsilat işlemlerini (iş, plan değişikliği, kart güncelleme)
+	// servis örnekleri arasında sıraya koyar; dönen fonksiyon kilidi bırakır
+	LockAccount(ctx context.Context, tenantID string) (func(), error)
+}
+
+// BillingGateway kayıtlı kartla tahsilat yapan ödeme sağlayıcısı (iyzico)
+type BillingGateway interface {
+	ChargeSubscription(input *payment.SubscriptionChargeInput) (*payment.PaymentResult, error)
+	StoreCard(card *payment.CardInput, email string) (*payment.StoredCard, error)
+}
+
+var _ BillingGateway = (*payment.PaymentService)(nil)
+
+// PaymentFailure kiracının tahsilatı alınamadı; Suspended ise kiracı askıya alındı
+type PaymentFailure struct {
+	TenantID    string
+	TenantName  string
+	InvoiceID   string
+	AmountKurus int64
+	Reason      string
+	NextRetryAt time.Time
+	Suspended   bool
+}
+
+// BillingNotifier tahsilat hatasını kiracı yöneticisine iletir
+type BillingNotifier interface {
+	NotifyPaymentFailed(ctx context.Context, f PaymentFailure) error
+}
+
+// BillingConfig faturalandırma işi ayarları
+type BillingConfig struct {
+	// DunningSchedule başarısız tahsilattan sonraki yeniden deneme aralıkları; son deneme de
+	// başarısız olursa kiracı askıya alınır
+	DunningSchedule []time.Duration
+	Interval        time.Duration // Vadesi gelen kiracıların kontrol aralığı
+}
+
+// DefaultBillingConfig varsayılan ayarlar: 1, 3 ve 7 gün sonra yeniden deneme
+func DefaultBillingConfig() BillingConfig {
+	return BillingConfig{
+		DunningSchedule: []time.Duration{24 * time.Hour, 72 * time.Hour, 7 * 24 * time.Hour},
+		Interval:        time.Hour,
+	}
+}
+
+// Suspension nedenleri
+const (
+	reasonTrialExpired  = "Deneme süresi doldu, ödeme yöntemi eklenmedi"
+	reasonPaymentFailed = "Abonelik ödemesi alınamadı"
+)
+
+// Biller kiracı aboneliklerini faturalandırır: deneme bitişi, dönem yenileme, kayıtlı karttan
+// tahsilat, başarısız tahsilatta yeniden deneme ve askıya alma, kıst plan değişikliği
+type Biller struct {
+	store    BillingStore
+	manager  *TenantManager
+	meter    *Meter
+	gateway  BillingGateway
+	notifier BillingNotifier
+	config   BillingConfig
+	now      func() time.Time
+}
+
+// NewBiller yeni faturalandırıcı oluşturur. meter nil ise plan düşürmede kullanım
+// denetlenmez; notifier nil ise tahsilat hataları yalnızca kaydedilir.
+func NewBiller(store BillingStore, manager *TenantManager, meter *Meter, gateway BillingGateway,
+	notifier BillingNotifier, config BillingConfig) *Biller {
+	return &Biller{store: store, manager: manager, meter: meter, gateway: gateway,
+		notifier: notifier, config: config, now: time.Now}
+}
+
+// Account kiracının abonelik bilgileri
+func (b *Biller) Account(ctx context.Context, tenantID string) (*BillingAccount, error) {
+	return b.store.Account(ctx, tenantID)
+}
+
+// Invoices kiracının faturaları (yeniden eskiye)
+func (b *Biller) Invoices(ctx context.Context, tenantID string) ([]Invoice, error) {
+	return b.store.ListInvoices(ctx, tenantID)
+}
+
+// Run vadesi gelen kiracıları faturalandırır. Bir kiracıdaki hata diğerlerini durdurmaz;
+// işlenen kiracı sayısı ve ilk hata döner.
+func (b *Biller) Run(ctx context.Context) (int, error) {
+	tenantIDs, err := b.store.DueAccounts(ctx, b.now())
+	if err != nil {
+		return 0, err
+	}
+	processed := 0
+	var firstErr error
+	for _, tenantID := range tenantIDs {
+		if err := b.process(ctx, tenantID); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("tenant %s: %w", tenantID, err)
+			}
+			continue
+		}
+		processed++
+	}
+	return processed, firstErr
+}
+
+// RunPeriodically Run'ı açılışta ve her Interval'da çalıştırır; ctx iptal edilene kadar sürer
+func (b *Biller) RunPeriodically(ctx context.Context) {
+	for {
+		if processed, err := b.Run(ctx); err != nil {
+			log.Printf("Abonelik faturalandırması eksik tamamlandı (%d kiracı): %v", processed, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.config.Interval):
+		}
+	}
+}
+
+// process kiracının vadesi gelen işlemini yapar: deneme bitişinde ilk dönem, dönem sonunda
+// yenileme, gecikmiş tahsilatta yeniden deneme
+func (b *Biller) process(ctx context.Context, tenantID string) error {
+	unlock, err := b.store.LockAccount(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	a, err := b.store.Account(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	now := b.now()
+	switch a.Status {
+	case StatusTrial:
+		if a.TrialEndsAt.After(now) {
+			return nil
+		}
+		if a.Card == nil {
+			a.Status, a.SuspensionReason = StatusSuspended, reasonTrialExpired
+			if err := b.save(ctx, a); err != nil {
+				return err
+			}
+			b.notify(ctx, PaymentFailure{TenantID: a.TenantID, TenantName: a.TenantName,
+				Reason: reasonTrialExpired, Suspended: true})
+			return nil
+		}
+		return b.startPeriod(ctx, a, a.TrialEndsAt)
+	case StatusActive:
+		if a.PeriodEnd.IsZero() || a.PeriodEnd.After(now) {
+			return nil
+		}
+		return b.startPeriod(ctx, a, a.PeriodEnd)
+	case StatusPastDue:
+		if a.NextRetryAt.After(now) {
+			return nil
+		}
+		return b.collect(ctx, a)
+	}
+	return nil
+}
+
+// startPeriod yeni dönemin faturasını keser (varsa alacak düşülür) ve tahsil eder
+func (b *Biller) startPeriod(ctx context.Context, a *BillingAccount, start time.Time) error {
+	plan, ok := findPlan(a.Plan)
+	if !ok {
+		return fmt.Errorf("%w: unknown plan %q", ErrInvalidTenant, a.Plan)
+	}
+	end := nextPeriodEnd(start, a.Cycle)
+	subtotal := planPrice(plan, a.Cycle)
+	credit := min(a.CreditBalance, subtotal)
+	inv := &Invoice{
+		TenantID:      a.TenantID,
+		Kind:          InvoiceSubscription,
+		Plan:          plan.ID,
+		Cycle:         a.Cycle,
+		Description:   fmt.Sprintf("%s plan %s abonelik", plan.Name, cycleName(a.Cycle)),
+		Subtotal:      subtotal,
+		CreditApplied: credit,
+		AmountTotal:   subtotal - credit,
+		Currency:      "TRY",
+		Status:        InvoiceOpen,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+	}
+	created, err := b.store.CreateInvoice(ctx, inv)
+	if err != nil {
+		return err
+	}
+	// Fatura daha önce kesildiyse alacak o zaman düşülmüştür
+	if created {
+		a.CreditBalance -= inv.CreditApplied
+	}
+	a.PeriodStart, a.PeriodEnd = inv.PeriodStart, inv.PeriodEnd
+	if err := b.save(ctx, a); err != nil {
+		return err
+	}
+	return b.collect(ctx, a)
+}
+
+// collect kiracının açık faturalarını eskiden yeniye tahsil eder. Hepsi ödenirse kiracı
+// aktif olur; bir tahsilat başarısız olursa yeniden deneme planlanır veya plan bittiyse
+// kiracı askıya alınır.
+func (b *Biller) collect(ctx context.Context, a *BillingAccount) error {
+	invoices, err := b.store.OpenInvoices(ctx, a.TenantID)
+	if err != nil {
+		return err
+	}
+	for i := range invoices {
+		inv := &invoices[i]
+		if err := b.charge(ctx, a, inv); err != nil {
+			if errors.Is(err, ErrNoPaymentMethod) || errors.Is(err, ErrPaymentDeclined) {
+				return b.dun(ctx, a, inv)
+			}
+			return err
+		}
+	}
+
+	if a.Status == StatusSuspended && a.DunningAttempts == 0 {
+		// Platform yöneticisinin askıya aldığı kiracı ödemeyle açılmaz
+		return nil
+	}
+	a.Status = StatusActive
+	a.DunningAttempts, a.NextRetryAt, a.SuspensionReason = 0, time.Time{}, ""
+	return b.save(ctx, a)
+}
+
+// charge faturayı kayıtlı karttan çeker. Tutarı alacakla kapanmış fatura kartsız ödenir.
+// Reddedilen tahsilat faturaya işlenir ve ErrPaymentDeclined döner.
+func (b *Biller) charge(ctx context.Context, a *BillingAccount, inv *Invoice) error {
+	if inv.AmountTotal > 0 {
+		if a.Card == nil {
+			inv.AttemptCount++
+			inv.FailureReason = "Kayıtlı kart yok"
+			if err := b.store.UpdateInvoice(ctx, inv); err != nil {
+				return err
+			}
+			return ErrNoPaymentMethod
+		}
+		result, err := b.gateway.ChargeSubscription(&payment.SubscriptionChargeInput{
+			TenantID:    a.TenantID,
+			TenantName:  a.TenantName,
+			Email:       a.BillingEmail,
+			InvoiceID:   inv.ID,
+			Description: inv.Description,
+			AmountKurus: inv.AmountTotal,
+			Card:        &payment.CardInput{Token: a.Card.Token, UserKey: a.Card.UserKey},
+		})
+		inv.AttemptCount++
+		if err != nil || !result.Success {
+			inv.FailureReason = "Ödeme sağlayıcısına ulaşılamadı"
+			if err == nil {
+				inv.FailureReason = result.ErrorMessage
+			} else {
+				log.Printf("Abonelik tahsilatı yapılamadı (%s %s): %v", a.TenantID, inv.ID, err)
+			}
+			if err := b.store.UpdateInvoice(ctx, inv); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s", ErrPaymentDeclined, inv.FailureReason)
+		}
+		inv.PaymentID, inv.TransactionID = result.PaymentID, result.TransactionID
+	}
+	paidAt := b.now()
+	inv.Status, inv.PaidAt, inv.FailureReason = InvoicePaid, &paidAt, ""
+	return b.store.UpdateInvoice(ctx, inv)
+}
+
+// dun başarısız tahsilatı işler: sıradaki yeniden denemeyi planlar veya DunningSchedule
+// bittiyse kiracıyı askıya alır ve yöneticiyi bilgilendirir
+func (b *Biller) dun(ctx context.Context, a *BillingAccount, inv *Invoice) error {
+	a.DunningAttempts++
+	failure := PaymentFailure{TenantID: a.TenantID, TenantName: a.TenantName, InvoiceID: inv.ID,
+		AmountKurus: inv.AmountTotal, Reason: inv.FailureReason}
+	if a.DunningAttempts > len(b.config.DunningSchedule) {
+		a.Status, a.NextRetryAt, a.SuspensionReason = StatusSuspended, time.Time{}, reasonPaymentFailed
+		failure.Suspended = true
+	} else if a.Status != StatusSuspended {
+		a.Status = StatusPastDue
+		a.NextRetryAt = b.now().Add(b.config.DunningSchedule[a.DunningAttempts-1])
+		failure.NextRetryAt = a.NextRetryAt
+	}
+	if err := b.save(ctx, a); err != nil {
+		return err
+	}
+	b.notify(ctx, failure)
+	return nil
+}
+
+// UpdatePaymentMethod kartı iyzico'da saklar ve kiracıya kaydeder. Tahsilatı gecikmiş veya
+// ödeme alınamadığı için askıya alınmış kiracının açık faturaları hemen yeni karttan çekilir;
+// hepsi ödenirse kiracı yeniden aktif olur.
+func (b *Biller) UpdatePaymentMethod(ctx context.Context, tenantID string, card *payment.CardInput, email string) (*BillingAccount, error) {
+	unlock, err := b.store.LockAccount(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	a, err := b.store.Account(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := b.gateway.StoreCard(card, email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentDeclined, err)
+	}
+	a.Card = &SavedCard{Token: stored.Token, UserKey: stored.UserKey, LastFour: stored.LastFour}
+	a.BillingEmail = email
+	if err := b.store.SaveCard(ctx, tenantID, *a.Card, email); err != nil {
+		return nil, err
+	}
+
+	if a.Status == StatusPastDue || (a.Status == StatusSuspended && a.DunningAttempts > 0) {
+		if err := b.collect(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// PlanChange plan değişikliği sonucu. Yükseltme farkı Invoice ile tahsil edilir; düşürmeden
+// kalan tutar CreditAdded olarak sonraki faturalardan düşülür.
+type PlanChange struct {
+	Account     *BillingAccount `json:"account"`
+	Invoice     *Invoice        `json:"invoice,omitempty"`
+	CreditAdded int64           `json:"credit_added"`
+}
+
+// ChangePlan kiracının planını veya fatura döngüsünü değiştirir. Deneme sürümünde plan
+// hemen değişir. Aktif abonelikte kalan sürenin eski plan bedeli alacak yazılır; döngü
+// aynıysa yeni planın kalan süre bedeli, döngü değişiyorsa yeni dönemin tam bedeli
+// borçlanır. Fark pozitifse hemen karttan çekilir (başarısızsa plan değişmez), negatifse
+// alacağa eklenir. Mevcut kullanım yeni planın limitlerini aşıyorsa *LimitError döner.
+func (b *Biller) ChangePlan(ctx context.Context, tenantID, planID, cycle string) (*PlanChange, error) {
+	plan, ok := findPlan(planID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown plan %q", ErrInvalidTenant, planID)
+	}
+
+	unlock, err := b.store.LockAccount(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	a, err := b.store.Account(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle == "" {
+		cycle = a.Cycle
+	}
+	if cycle != CycleMonthly && cycle != CycleYearly {
+		return nil, fmt.Errorf("%w: billing cycle %q", ErrInvalidTenant, cycle)
+	}
+	if plan.ID == a.Plan && cycle == a.Cycle {
+		return nil, fmt.Errorf("%w: already on plan %s (%s)", ErrInvalidTenant, plan.ID, cycle)
+	}
+	if err := b.checkUsage(ctx, a.TenantID, plan); err != nil {
+		return nil, err
+	}
+
+	switch a.Status {
+	case StatusTrial:
+		a.Plan, a.Cycle = plan.ID, cycle
+		if err := b.save(ctx, a); err != nil {
+			return nil, err
+		}
+		return &PlanChange{Account: a}, nil
+	case StatusActive:
+	default:
+		return nil, ErrStatusConflict
+	}
+
+	oldPlan, ok := findPlan(a.Plan)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown plan %q", ErrInvalidTenant, a.Plan)
+	}
+	now := b.now()
+	remaining, total := a.PeriodEnd.Sub(now), a.PeriodEnd.Sub(a.PeriodStart)
+	unused := prorate(planPrice(oldPlan, a.Cycle), remaining, total)
+
+	periodStart, periodEnd := a.PeriodStart, a.PeriodEnd
+	var due int64
+	if cycle == a.Cycle {
+		due = prorate(planPrice(plan, cycle), remaining, total)
+	} else {
+		due = planPrice(plan, cycle)
+		periodStart, periodEnd = now, nextPeriodEnd(now, cycle)
+	}
+
+	change := &PlanChange{Account: a}
+	net := due - unused
+	if net > 0 {
+		credit := min(a.CreditBalance, net)
+		inv := &Invoice{
+			TenantID:      a.TenantID,
+			Kind:          InvoiceProration,
+			Plan:          plan.ID,
+			Cycle:         cycle,
+			Description:   fmt.Sprintf("%s → %s plan değişikliği (kıst)", oldPlan.Name, plan.Name),
+			Subtotal:      net,
+			CreditApplied: credit,
+			AmountTotal:   net - credit,
+			Currency:      "TRY",
+			Status:        InvoiceOpen,
+			PeriodStart:   now,
+			PeriodEnd:     periodEnd,
+		}
+		if _, err := b.store.CreateInvoice(ctx, inv); err != nil {
+			return nil, err
+		}
+		if err := b.charge(ctx, a, inv); err != nil {
+			// Plan değişmez; fatura iptal edilir ki dönem tahsilatında tekrar denenmesin
+			inv.Status = InvoiceVoid
+			if uerr := b.store.UpdateInvoice(ctx, inv); uerr != nil {
+				log.Printf("Kıst fatura iptal edilemedi (%s %s): %v", a.TenantID, inv.ID, uerr)
+			}
+			return nil, err
+		}
+		a.CreditBalance -= credit
+		change.Invoice = inv
+	} else {
+		a.CreditBalance -= net
+		change.CreditAdded = -net
+	}
+
+	a.Plan, a.Cycle = plan.ID, cycle
+	a.PeriodStart, a.PeriodEnd = periodStart, periodEnd
+	if err := b.save(ctx, a); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// checkUsage kiracının güncel kullanımı planın limitlerine sığıyor mu
+func (b *Biller) checkUsage(ctx context.Context, tenantID string, plan SubscriptionPlan) error {
+	if b.meter == nil {
+		return nil
+	}
+	for resource, limit := range map[string]int{ResourceUnits: plan.MaxUnits, ResourceUsers: plan.MaxUsers} {
+		if limit < 0 {
+			continue
+		}
+		current, err := b.meter.store.Count(ctx, tenantID, resource)
+		if err != nil {
+			return err
+		}
+		if current > limit {
+			return &LimitError{Resource: resource, Limit: limit, Current: current, Plan: plan.ID}
+		}
+	}
+	return nil
+}
+
+// save hesabı yazar ve kiracıyı önbellekten düşürür (durum veya plan değişmiş olabilir)
+func (b *Biller) save(ctx context.Context, a *BillingAccount) error {
+	if err := b.store.SaveAccount(ctx, a); err != nil {
+		return err
+	}
+	if b.manager != nil {
+		b.manager.Invalidate(a.TenantID)
+	}
+	return nil
+}
+
+func (b *Biller) notify(ctx context.Context, f PaymentFailure) {
+	if b.notifier == nil {
+		return
+	}
+	if err := b.notifier.NotifyPaymentFailed(ctx, f); err != nil {
+		log.Printf("Tahsilat hatası bildirilemedi (%s): %v", f.TenantID, err)
+	}
+}
+
+// planPrice planın döngü bedeli (kuruş)
+func planPrice(plan SubscriptionPlan, cycle string) int64 {
+	if cycle == CycleYearly {
+		return int64(plan.PriceYearly) * 100
+	}
+	return int64(plan.PriceMonthly) * 100
+}
+
+// nextPeriodEnd dönem başlangıcından bir ay veya bir yıl sonrası
+func nextPeriodEnd(start time.Time, cycle string) time.Time {
+	if cycle == CycleYearly {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// prorate bedelin kalan süreye düşen kısmı (kuruşa yuvarlanır)
+func prorate(price int64, remaining, total time.Duration) int64 {
+	if total <= 0 || remaining <= 0 {
+		return 0
+	}
+	if remaining > total {
+		remaining = total
+	}
+	return int64(math.Round(float64(price) * remaining.Seconds() / total.Seconds()))
+}
+
+func cycleName(cycle string) string {
+	if strings.EqualFold(cycle, CycleYearly) {
+		return "yıllık"
+	}
+	return "aylık"
+}
@@ -0,0 +1,245 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ BillingStore = (*PostgresBillingStore)(nil)
+
+// PostgresBillingStore abonelik bilgilerini tenants, faturaları invoices tablosunda tutar
+// (migration 023). Durum veritabanında küçük harfle saklanır.
+type PostgresBillingStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresBillingStore yeni store oluşturur
+func NewPostgresBillingStore(pool *pgxpool.Pool) *PostgresBillingStore {
+	return &PostgresBillingStore{pool: pool}
+}
+
+// Account kiracının abonelik bilgileri
+func (s *PostgresBillingStore) Account(ctx context.Context, tenantID string) (*BillingAccount, error) {
+	a := &BillingAccount{}
+	var trialEndsAt, periodStart, periodEnd, nextRetryAt *time.Time
+	var cardToken, cardUserKey, cardLastFour string
+	err := s.pool.QueryRow(ctx, `
+		SELECT id::text, name, subscription_plan, subscription_status, billing_cycle, COALESCE(billing_email, ''),
+			   trial_ends_at, current_period_start, current_period_end,
+			   COALESCE(card_token, ''), COALESCE(card_user_key, ''), COALESCE(card_last_four, ''),
+			   credit_balance, dunning_attempts, next_payment_retry_at, COALESCE(suspension_reason, '')
+		FROM tenants
+		WHERE id::text = $1
+	`, tenantID).Scan(&a.TenantID, &a.TenantName, &a.Plan, &a.Status, &a.Cycle, &a.BillingEmail,
+		&trialEndsAt, &periodStart, &periodEnd, &cardToken, &cardUserKey, &cardLastFour,
+		&a.CreditBalance, &a.DunningAttempts, &nextRetryAt, &a.SuspensionReason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.Plan = strings.ToLower(a.Plan)
+	a.Status = strings.ToUpper(a.Status)
+	a.TrialEndsAt, a.PeriodStart, a.PeriodEnd, a.NextRetryAt =
+		timeOrZero(trialEndsAt), timeOrZero(periodStart), timeOrZero(periodEnd), timeOrZero(nextRetryAt)
+	if cardToken != "" {
+		a.Card = &SavedCard{Token: cardToken, UserKey: cardUserKey, LastFour: cardLastFour}
+	}
+	return a, nil
+}
+
+// DueAccounts vadesi gelen kiracılar (en eski önce)
+func (s *PostgresBillingStore) DueAccounts(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id::text
+		FROM tenants
+		WHERE (lower(subscription_status) = 'trial' AND trial_ends_at <= $1)
+		   OR (lower(subscription_status) = 'active' AND current_period_end <= $1)
+		   OR (lower(subscription_status) = 'past_due' AND next_payment_retry_at <= $1)
+		ORDER BY created_at
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// SaveAccount hesabı yazar; plan limitleri ve özellikleri plandan alınır. Askıya alınan
+// kiracının askıya alınma zamanı korunur, askıdan çıkan kiracınınki temizlenir.
+func (s *PostgresBillingStore) SaveAccount(ctx context.Context, a *BillingAccount) error {
+	plan, ok := findPlan(a.Plan)
+	if !ok {
+		return fmt.Errorf("%w: unknown plan %q", ErrInvalidTenant, a.Plan)
+	}
+	features, err := json.Marshal(plan.Features)
+	if err != nil {
+		return err
+	}
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE tenants
+		SET subscription_plan = $2, subscription_status = lower($3), billing_cycle = $4,
+			max_units = $5, max_users = $6, features = $7,
+			current_period_start = $8, current_period_end = $9, credit_balance = $10,
+			dunning_attempts = $11, next_payment_retry_at = $12,
+			suspended_at = CASE WHEN lower($3) = 'suspended' THEN COALESCE(suspended_at, NOW()) END,
+			suspension_reason = CASE WHEN lower($3) = 'suspended' THEN NULLIF($13, '') END,
+			updated_at = NOW()
+		WHERE id::text = $1
+	`, a.TenantID, plan.ID, a.Status, a.Cycle, plan.MaxUnits, plan.MaxUsers, features,
+		nullTime(a.PeriodStart), nullTime(a.PeriodEnd), a.CreditBalance,
+		a.DunningAttempts, nullTime(a.NextRetryAt), a.SuspensionReason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, a.TenantID)
+	}
+	return nil
+}
+
+// SaveCard kayıtlı kartı ve fatura e-postasını değiştirir
+func (s *PostgresBillingStore) SaveCard(ctx context.Context, tenantID string, card SavedCard, email string) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE tenants
+		SET card_token = $2, card_user_key = $3, card_last_four = $4, billing_email = NULLIF($5, ''),
+			updated_at = NOW()
+		WHERE id::text = $1
+	`, tenantID, card.Token, card.UserKey, card.LastFour, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+	}
+	return nil
+}
+
+const invoiceColumns = `
+	SELECT id::text, tenant_id::text, kind, COALESCE(plan, ''), COALESCE(billing_cycle, ''),
+		   COALESCE(description, ''), COALESCE(subtotal, amount_total), credit_applied, amount_total,
+		   COALESCE(currency, 'TRY'), status, period_start, period_end, attempt_count,
+		   COALESCE(failure_reason, ''), COALESCE(payment_id, ''), COALESCE(transaction_id, ''),
+		   created_at, paid_at
+	FROM invoices`
+
+// CreateInvoice faturayı ekler; dönemin abonelik faturası varsa onu yükler
+func (s *PostgresBillingStore) CreateInvoice(ctx context.Context, inv *Invoice) (bool, error) {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO invoices (tenant_id, kind, plan, billing_cycle, description, subtotal, credit_applied,
+							  amount_total, currency, status, period_start, period_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (tenant_id, period_start) WHERE kind = 'subscription' DO NOTHING
+		RETURNING id::text, created_at
+	`, inv.TenantID, inv.Kind, inv.Plan, inv.Cycle, inv.Description, inv.Subtotal, inv.CreditApplied,
+		inv.AmountTotal, inv.Currency, inv.Status, inv.PeriodStart, inv.PeriodEnd).Scan(&inv.ID, &inv.CreatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	existing, err := scanInvoice(s.pool.QueryRow(ctx, invoiceColumns+`
+		WHERE tenant_id::text = $1 AND kind = 'subscription' AND period_start = $2
+	`, inv.TenantID, inv.PeriodStart))
+	if err != nil {
+		return false, err
+	}
+	*inv = *existing
+	return false, nil
+}
+
+// UpdateInvoice durum, deneme ve ödeme alanlarını yazar
+func (s *PostgresBillingStore) UpdateInvoice(ctx context.Context, inv *Invoice) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE invoices
+		SET status = $2, attempt_count = $3, failure_reason = NULLIF($4, ''),
+			payment_id = NULLIF($5, ''), transaction_id = NULLIF($6, ''), paid_at = $7, updated_at = NOW()
+		WHERE id::text = $1
+	`, inv.ID, inv.Status, inv.AttemptCount, inv.FailureReason, inv.PaymentID, inv.TransactionID, inv.PaidAt)
+	return err
+}
+
+// OpenInvoices ödenmemiş faturalar (eskiden yeniye)
+func (s *PostgresBillingStore) OpenInvoices(ctx context.Context, tenantID string) ([]Invoice, error) {
+	return s.listInvoices(ctx, invoiceColumns+`
+		WHERE tenant_id::text = $1 AND status = 'open'
+		ORDER BY period_start, created_at
+	`, tenantID)
+}
+
+// ListInvoices tüm faturalar (yeniden eskiye)
+func (s *PostgresBillingStore) ListInvoices(ctx context.Context, tenantID string) ([]Invoice, error) {
+	return s.listInvoices(ctx, invoiceColumns+`
+		WHERE tenant_id::text = $1
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, tenantID)
+}
+
+// LockAccount kiracıya özel oturum düzeyi advisory lock alır; kilit aynı bağlantıda
+// bırakılması gerektiğinden bağlantı kilit süresince havuzdan ayrılır
+func (s *PostgresBillingStore) LockAccount(ctx context.Context, tenantID string) (func(), error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtext('tenant_billing:' || $1))`, tenantID); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext('tenant_billing:' || $1))`, tenantID); err != nil {
+			// Kilit bırakılamadıysa bağlantı kapatılır; oturum kilidi bağlantıyla düşer
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}, nil
+}
+
+func (s *PostgresBillingStore) listInvoices(ctx context.Context, sql, tenantID string) ([]Invoice, error) {
+	rows, err := s.pool.Query(ctx, sql, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+func scanInvoice(row pgx.Row) (*Invoice, error) {
+	inv := &Invoice{}
+	var periodStart, periodEnd *time.Time
+	if err := row.Scan(&inv.ID, &inv.TenantID, &inv.Kind, &inv.Plan, &inv.Cycle, &inv.Description,
+		&inv.Subtotal, &inv.CreditApplied, &inv.AmountTotal, &inv.Currency, &inv.Status,
+		&periodStart, &periodEnd, &inv.AttemptCount, &inv.FailureReason, &inv.PaymentID,
+		&inv.TransactionID, &inv.CreatedAt, &inv.PaidAt); err != nil {
+		return nil, err
+	}
+	inv.PeriodStart, inv.PeriodEnd = timeOrZero(periodStart), timeOrZero(periodEnd)
+	return inv, nil
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
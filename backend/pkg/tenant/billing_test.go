@@ -0,0 +1,370 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/payment/iyzicotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBilling testler için BillingStore
+type memoryBilling struct {
+	accounts map[string]*BillingAccount
+	invoices []*Invoice
+}
+
+func newMemoryBilling(accounts ...*BillingAccount) *memoryBilling {
+	s := &memoryBilling{accounts: map[string]*BillingAccount{}}
+	for _, a := range accounts {
+		s.accounts[a.TenantID] = a
+	}
+	return s
+}
+
+func (s *memoryBilling) Account(ctx context.Context, tenantID string) (*BillingAccount, error) {
+	a, ok := s.accounts[tenantID]
+	if !ok {
+		return nil, ErrTenantNotFound
+	}
+	clone := *a
+	return &clone, nil
+}
+
+func (s *memoryBilling) DueAccounts(ctx context.Context, now time.Time) ([]string, error) {
+	ids := []string{}
+	for id, a := range s.accounts {
+		if (a.Status == StatusTrial && !a.TrialEndsAt.After(now)) ||
+			(a.Status == StatusActive && !a.PeriodEnd.IsZero() && !a.PeriodEnd.After(now)) ||
+			(a.Status == StatusPastDue && !a.NextRetryAt.After(now)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *memoryBilling) SaveAccount(ctx context.Context, a *BillingAccount) error {
+	clone := *a
+	s.accounts[a.TenantID] = &clone
+	return nil
+}
+
+func (s *memoryBilling) SaveCard(ctx context.Context, tenantID string, card SavedCard, email string) error {
+	s.accounts[tenantID].Card, s.accounts[tenantID].BillingEmail = &card, email
+	return nil
+}
+
+func (s *memoryBilling) CreateInvoice(ctx context.Context, inv *Invoice) (bool, error) {
+	for _, existing := range s.invoices {
+		if inv.Kind == InvoiceSubscription && existing.Kind == InvoiceSubscription &&
+			existing.TenantID == inv.TenantID && existing.PeriodStart.Equal(inv.PeriodStart) {
+			*inv = *existing
+			return false, nil
+		}
+	}
+	inv.ID = fmt.Sprintf("inv-%d", len(s.invoices)+1)
+	clone := *inv
+	s.invoices = append(s.invoices, &clone)
+	return true, nil
+}
+
+func (s *memoryBilling) UpdateInvoice(ctx context.Context, inv *Invoice) error {
+	for i, existing := range s.invoices {
+		if existing.ID == inv.ID {
+			clone := *inv
+			s.invoices[i] = &clone
+		}
+	}
+	return nil
+}
+
+func (s *memoryBilling) OpenInvoices(ctx context.Context, tenantID string) ([]Invoice, error) {
+	invoices := []Invoice{}
+	for _, inv := range s.invoices {
+		if inv.TenantID == tenantID && inv.Status == InvoiceOpen {
+			invoices = append(invoices, *inv)
+		}
+	}
+	return invoices, nil
+}
+
+func (s *memoryBilling) ListInvoices(ctx context.Context, tenantID string) ([]Invoice, error) {
+	invoices := []Invoice{}
+	for i := len(s.invoices) - 1; i >= 0; i-- {
+		if s.invoices[i].TenantID == tenantID {
+			invoices = append(invoices, *s.invoices[i])
+		}
+	}
+	return invoices, nil
+}
+
+func (s *memoryBilling) LockAccount(ctx context.Context, tenantID string) (func(), error) {
+	return func() {}, nil
+}
+
+type recordingBillingNotifier struct {
+	failures []PaymentFailure
+}
+
+func (n *recordingBillingNotifier) NotifyPaymentFailed(ctx context.Context, f PaymentFailure) error {
+	n.failures = append(n.failures, f)
+	return nil
+}
+
+var billingNow = time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC)
+
+type billingFixture struct {
+	biller   *Biller
+	store    *memoryBilling
+	usage    *memoryUsage
+	notifier *recordingBillingNotifier
+	iyzico   *iyzicotest.Server
+	now      time.Time
+}
+
+func newBillingFixture(t *testing.T, accounts ...*BillingAccount) *billingFixture {
+	f := &billingFixture{
+		store:    newMemoryBilling(accounts...),
+		usage:    newMemoryUsage(),
+		notifier: &recordingBillingNotifier{},
+		iyzico:   iyzicotest.NewServer(),
+		now:      billingNow,
+	}
+	t.Cleanup(f.iyzico.Close)
+	manager := NewManager(newMemoryStore(mavikent()), DefaultManagerConfig())
+	f.biller = NewBiller(f.store, manager, NewMeter(f.usage, manager, nil), f.iyzico.PaymentService(),
+		f.notifier, DefaultBillingConfig())
+	f.biller.now = func() time.Time { return f.now }
+	return f
+}
+
+// card sahte iyzico'da saklanmış kart
+func (f *billingFixture) card(t *testing.T, number string) *SavedCard {
+	stored, err := f.iyzico.PaymentService().StoreCard(&payment.CardInput{
+		HolderName: "Mavi Kent", Number: number, ExpireMonth: "12", ExpireYear: "2030",
+	}, "yonetim@mavikent.com")
+	require.NoError(t, err)
+	return &SavedCard{Token: stored.Token, UserKey: stored.UserKey, LastFour: stored.LastFour}
+}
+
+func (f *billingFixture) account(t *testing.T, id string) *BillingAccount {
+	a, err := f.store.Account(context.Background(), id)
+	require.NoError(t, err)
+	return a
+}
+
+func TestBiller_TrialExpiry(t *testing.T) {
+	trialEnd := billingNow.Add(-time.Hour)
+	f := newBillingFixture(t,
+		&BillingAccount{TenantID: "t-card", TenantName: "Mavi Kent", Plan: "pro", Status: StatusTrial,
+			Cycle: CycleMonthly, TrialEndsAt: trialEnd},
+		&BillingAccount{TenantID: "t-nocard", TenantName: "Yeşil Vadi", Plan: "starter", Status: StatusTrial,
+			Cycle: CycleMonthly, TrialEndsAt: trialEnd},
+		&BillingAccount{TenantID: "t-trial", TenantName: "Çınar", Plan: "starter", Status: StatusTrial,
+			Cycle: CycleYearly, TrialEndsAt: billingNow.Add(48 * time.Hour)},
+	)
+	f.store.accounts["t-card"].Card = f.card(t, iyzicotest.CardSuccess)
+	ctx := context.Background()
+
+	processed, err := f.biller.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+
+	// Kartı olan kiracı ilk dönemin bedelini öder ve aktif olur
+	a := f.account(t, "t-card")
+	assert.Equal(t, StatusActive, a.Status)
+	assert.Equal(t, trialEnd, a.PeriodStart)
+	assert.Equal(t, trialEnd.AddDate(0, 1, 0), a.PeriodEnd)
+	invoices, _ := f.store.ListInvoices(ctx, "t-card")
+	require.Len(t, invoices, 1)
+	assert.Equal(t, InvoicePaid, invoices[0].Status)
+	assert.Equal(t, int64(59900), invoices[0].AmountTotal)
+	assert.NotEmpty(t, invoices[0].TransactionID)
+
+	// Kartı olmayan kiracı askıya alınır ve yönetici bilgilendirilir
+	a = f.account(t, "t-nocard")
+	assert.Equal(t, StatusSuspended, a.Status)
+	assert.Equal(t, reasonTrialExpired, a.SuspensionReason)
+	require.Len(t, f.notifier.failures, 1)
+	assert.True(t, f.notifier.failures[0].Suspended)
+
+	assert.Equal(t, StatusTrial, f.account(t, "t-trial").Status)
+
+	// Yeniden çalıştırmak ikinci fatura kesmez
+	processed, err = f.biller.Run(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+	invoices, _ = f.store.ListInvoices(ctx, "t-card")
+	assert.Len(t, invoices, 1)
+}
+
+func TestBiller_DunningAndRecovery(t *testing.T) {
+	f := newBillingFixture(t, &BillingAccount{TenantID: "t-mavi", TenantName: "Mavi Kent", Plan: "starter",
+		Status: StatusActive, Cycle: CycleYearly, PeriodStart: billingNow.AddDate(-1, 0, 0), PeriodEnd: billingNow,
+		CreditBalance: 10000})
+	f.store.accounts["t-mavi"].Card = f.card(t, iyzicotest.CardInsufficientFund)
+	ctx := context.Background()
+
+	_, err := f.biller.Run(ctx)
+	require.NoError(t, err)
+	a := f.account(t, "t-mavi")
+	assert.Equal(t, StatusPastDue, a.Status, "yeniden deneme süresince hizmet devam eder")
+	assert.Equal(t, 1, a.DunningAttempts)
+	assert.Equal(t, billingNow.Add(24*time.Hour), a.NextRetryAt)
+	assert.Equal(t, billingNow.AddDate(1, 0, 0), a.PeriodEnd)
+	assert.Zero(t, a.CreditBalance, "alacak yenileme faturasından düşülür")
+	assert.True(t, (&Tenant{SubscriptionStatus: a.Status}).IsActive())
+
+	invoices, _ := f.store.OpenInvoices(ctx, "t-mavi")
+	require.Len(t, invoices, 1)
+	assert.Equal(t, int64(299000), invoices[0].Subtotal)
+	assert.Equal(t, int64(10000), invoices[0].CreditApplied)
+	assert.Equal(t, int64(289000), invoices[0].AmountTotal)
+	assert.Equal(t, 1, invoices[0].AttemptCount)
+	assert.NotEmpty(t, invoices[0].FailureReason)
+
+	// Yeniden deneme zamanı gelmeden tahsilat denenmez
+	f.now = billingNow.Add(time.Hour)
+	_, err = f.biller.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.account(t, "t-mavi").DunningAttempts)
+
+	// 1, 3 ve 7 gün sonraki denemeler; sonuncusu da başarısızsa askıya alınır
+	for range DefaultBillingConfig().DunningSchedule {
+		f.now = f.account(t, "t-mavi").NextRetryAt
+		_, err = f.biller.Run(ctx)
+		require.NoError(t, err)
+	}
+	a = f.account(t, "t-mavi")
+	assert.Equal(t, StatusSuspended, a.Status)
+	assert.Equal(t, reasonPaymentFailed, a.SuspensionReason)
+	assert.Equal(t, 4, a.DunningAttempts)
+	require.Len(t, f.notifier.failures, 4)
+	assert.False(t, f.notifier.failures[2].Suspended)
+	assert.True(t, f.notifier.failures[3].Suspended)
+	invoices, _ = f.store.OpenInvoices(ctx, "t-mavi")
+	require.Len(t, invoices, 1, "aynı fatura yeniden denenir")
+	assert.Equal(t, 4, invoices[0].AttemptCount)
+
+	// Yeni kart açık faturayı öder ve kiracıyı açar
+	a, err = f.biller.UpdatePaymentMethod(ctx, "t-mavi", &payment.CardInput{
+		HolderName: "Mavi Kent", Number: iyzicotest.CardSuccess, ExpireMonth: "12", ExpireYear: "2030",
+	}, "muhasebe@mavikent.com")
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, a.Status)
+	assert.Equal(t, "0008", a.Card.LastFour)
+	a = f.account(t, "t-mavi")
+	assert.Equal(t, StatusActive, a.Status)
+	assert.Zero(t, a.DunningAttempts)
+	assert.Empty(t, a.SuspensionReason)
+	assert.Equal(t, "muhasebe@mavikent.com", a.BillingEmail)
+	invoices, _ = f.store.OpenInvoices(ctx, "t-mavi")
+	assert.Empty(t, invoices)
+}
+
+func TestBiller_UpdatePaymentMethodKeepsAdminSuspension(t *testing.T) {
+	f := newBillingFixture(t, &BillingAccount{TenantID: "t-mavi", Plan: "starter", Status: StatusSuspended,
+		Cycle: CycleMonthly, SuspensionReason: "Sözleşme ihlali"})
+
+	a, err := f.biller.UpdatePaymentMethod(context.Background(), "t-mavi", &payment.CardInput{
+		Number: iyzicotest.CardSuccess, ExpireMonth: "12", ExpireYear: "2030",
+	}, "yonetim@mavikent.com")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuspended, a.Status)
+	assert.Equal(t, StatusSuspended, f.account(t, "t-mavi").Status)
+}
+
+func TestBiller_ChangePlanProration(t *testing.T) {
+	// 30 günlük dönemin 20 günü kaldı
+	f := newBillingFixture(t, &BillingAccount{TenantID: "t-mavi", TenantName: "Mavi Kent", Plan: "starter",
+		Status: StatusActive, Cycle: CycleMonthly,
+		PeriodStart: billingNow.AddDate(0, 0, -10), PeriodEnd: billingNow.AddDate(0, 0, 20)})
+	f.store.accounts["t-mavi"].Card = f.card(t, iyzicotest.CardSuccess)
+	ctx := context.Background()
+
+	// Yükseltme: (59900 - 29900) × 20/30 hemen tahsil edilir
+	change, err := f.biller.ChangePlan(ctx, "t-mavi", "pro", "")
+	require.NoError(t, err)
+	require.NotNil(t, change.Invoice)
+	assert.Equal(t, InvoiceProration, change.Invoice.Kind)
+	assert.Equal(t, InvoicePaid, change.Invoice.Status)
+	assert.Equal(t, int64(39933-19933), change.Invoice.AmountTotal)
+	a := f.account(t, "t-mavi")
+	assert.Equal(t, "pro", a.Plan)
+	assert.Equal(t, billingNow.AddDate(0, 0, 20), a.PeriodEnd, "dönem değişmez")
+
+	// Düşürme kullanım yeni planın limitini aşıyorsa reddedilir
+	f.usage.counts["t-mavi/units"] = 60
+	_, err = f.biller.ChangePlan(ctx, "t-mavi", "starter", "")
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitError{Resource: ResourceUnits, Limit: 50, Current: 60, Plan: "starter"}, *limitErr)
+
+	// Düşürme: kalan fark alacak yazılır, kart çekilmez
+	f.usage.counts["t-mavi/units"] = 40
+	change, err = f.biller.ChangePlan(ctx, "t-mavi", "starter", "")
+	require.NoError(t, err)
+	assert.Nil(t, change.Invoice)
+	assert.Equal(t, int64(20000), change.CreditAdded)
+	assert.Equal(t, int64(20000), f.account(t, "t-mavi").CreditBalance)
+
+	// Yıllığa geçiş: yeni dönem bugün başlar; kalan aylık bedel ve alacak düşülür
+	change, err = f.biller.ChangePlan(ctx, "t-mavi", "starter", CycleYearly)
+	require.NoError(t, err)
+	require.NotNil(t, change.Invoice)
+	assert.Equal(t, int64(299000-19933), change.Invoice.Subtotal)
+	assert.Equal(t, int64(20000), change.Invoice.CreditApplied)
+	assert.Equal(t, int64(299000-19933-20000), change.Invoice.AmountTotal)
+	a = f.account(t, "t-mavi")
+	assert.Equal(t, CycleYearly, a.Cycle)
+	assert.Equal(t, billingNow, a.PeriodStart)
+	assert.Equal(t, billingNow.AddDate(1, 0, 0), a.PeriodEnd)
+	assert.Zero(t, a.CreditBalance)
+
+	_, err = f.biller.ChangePlan(ctx, "t-mavi", "starter", CycleYearly)
+	assert.ErrorIs(t, err, ErrInvalidTenant)
+	_, err = f.biller.ChangePlan(ctx, "t-mavi", "platinum", "")
+	assert.ErrorIs(t, err, ErrInvalidTenant)
+}
+
+func TestBiller_ChangePlanDeclinedKeepsPlan(t *testing.T) {
+	f := newBillingFixture(t,
+		&BillingAccount{TenantID: "t-mavi", Plan: "starter", Status: StatusActive, Cycle: CycleMonthly,
+			PeriodStart: billingNow.AddDate(0, 0, -10), PeriodEnd: billingNow.AddDate(0, 0, 20)},
+		&BillingAccount{TenantID: "t-trial", Plan: "starter", Status: StatusTrial, Cycle: CycleMonthly,
+			TrialEndsAt: billingNow.Add(time.Hour)},
+		&BillingAccount{TenantID: "t-late", Plan: "starter", Status: StatusPastDue, Cycle: CycleMonthly},
+	)
+	f.store.accounts["t-mavi"].Card = f.card(t, iyzicotest.CardInsufficientFund)
+	ctx := context.Background()
+
+	_, err := f.biller.ChangePlan(ctx, "t-mavi", "enterprise", "")
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, "starter", f.account(t, "t-mavi").Plan)
+	invoices, _ := f.store.ListInvoices(ctx, "t-mavi")
+	require.Len(t, invoices, 1)
+	assert.Equal(t, InvoiceVoid, invoices[0].Status)
+
+	// Deneme sürümünde plan ücretsiz değişir
+	change, err := f.biller.ChangePlan(ctx, "t-trial", "enterprise", CycleYearly)
+	require.NoError(t, err)
+	assert.Nil(t, change.Invoice)
+	assert.Equal(t, "enterprise", f.account(t, "t-trial").Plan)
+
+	_, err = f.biller.ChangePlan(ctx, "t-late", "pro", "")
+	assert.ErrorIs(t, err, ErrStatusConflict)
+}
+
+func TestProrate(t *testing.T) {
+	assert.Equal(t, int64(19933), prorate(29900, 20*24*time.Hour, 30*24*time.Hour))
+	assert.Equal(t, int64(29900), prorate(29900, 40*24*time.Hour, 30*24*time.Hour))
+	assert.Zero(t, prorate(29900, -time.Hour, 30*24*time.Hour))
+	assert.Equal(t, time.Date(2027, 3, 15, 0, 0, 0, 0, time.UTC),
+		nextPeriodEnd(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), CycleYearly))
+}
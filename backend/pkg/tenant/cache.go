@@ -0,0 +1,134 @@
+package tenant
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cache kiracı aramaları için süreli LRU önbellek. Bir kiracı kimlik, slug ve alan adı
+// anahtarlarıyla (site üzerinden arandıysa site anahtarıyla da) ayrı ayrı saklanır;
+// invalidate kiracının tüm anahtarlarını siler.
+//
+// Veritabanından okuma sürerken gelen bir geçersiz kılma, okunan eski kaydın önbelleğe
+// yazılmasını engellemelidir: okumadan önce generation alınır, set yalnızca arada
+// invalidate/purge olmadıysa yazar.
+type cache struct {
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	items      map[string]*list.Element
+	order      *list.List // Önde en son kullanılan
+	generation uint64
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	tenant  *Tenant
+	expires time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+func idKey(id string) string         { return "id:" + id }
+func slugKey(slug string) string     { return "slug:" + strings.ToLower(slug) }
+func domainKey(domain string) string { return "domain:" + strings.ToLower(domain) }
+func propertyKey(id string) string   { return "property:" + id }
+
+// get anahtardaki süresi dolmamış kaydı döner ve en son kullanılan yapar
+func (c *cache) get(key string) (*Tenant, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.tenant, true
+}
+
+// gen veritabanı okumasından önce alınan geçersiz kılma sayacı
+func (c *cache) gen() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// set kiracıyı tüm anahtarlarıyla ve verilen ek anahtarlarla saklar; gen alındıktan sonra
+// geçersiz kılma olduysa yazmaz
+func (c *cache) set(t *Tenant, gen uint64, extra ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.generation || c.size <= 0 {
+		return
+	}
+	keys := []string{idKey(t.ID), slugKey(t.Slug)}
+	if t.CustomDomain != "" {
+		keys = append(keys, domainKey(t.CustomDomain))
+	}
+	keys = append(keys, extra...)
+	expires := c.now().Add(c.ttl)
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+		c.items[key] = c.order.PushFront(&cacheEntry{key: key, tenant: t, expires: expires})
+	}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate kiracının tüm anahtarlarını siler. Kiracının eski slug ve alan adı da
+// kayıtlı olabileceğinden anahtarlar kiracı kimliğiyle taranır.
+func (c *cache) invalidate(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cacheEntry).tenant.ID == tenantID {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// purge önbelleği tamamen boşaltır (bildirim bağlantısı koptuğunda)
+func (c *cache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,64 @@
+package tenant
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotifyChannel tenants tablosundaki değişikliklerin yayınlandığı kanal (migration 020);
+// bildirimin içeriği değişen kiracının kimliğidir
+const NotifyChannel = "tenant_changed"
+
+const maxListenBackoff = 30 * time.Second
+
+// Listen tenant_changed bildirimlerini dinleyip değişen kiracıyı önbellekten düşürür.
+// ctx iptal edilene kadar çalışır; bağlantı koparsa artan aralıklarla yeniden bağlanır.
+// Bağlantı yokken kaçırılan bildirimler yüzünden her bağlanışta önbellek boşaltılır.
+func (m *TenantManager) Listen(ctx context.Context, pool *pgxpool.Pool) {
+	backoff := time.Second
+	for {
+		connected, err := m.listen(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		log.Printf("Kiracı bildirimleri dinlenemiyor, %s sonra yeniden denenecek: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxListenBackoff {
+			backoff = maxListenBackoff
+		}
+	}
+}
+
+// listen tek bir bağlantı üzerinde bildirimleri bekler; bağlantı havuza geri verilmez
+func (m *TenantManager) listen(ctx context.Context, pool *pgxpool.Pool) (connected bool, err error) {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+NotifyChannel); err != nil {
+		return false, err
+	}
+	m.cache.purge()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		m.Invalidate(notification.Payload)
+	}
+}
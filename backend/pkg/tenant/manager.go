@@ -2,8 +2,14 @@ package tenant
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/database"
 )
 
 // Tenant - Kiracı (Site Yönetimi) bilgileri
@@ -13,14 +19,16 @@ type Tenant struct {
 	Slug              string             `json:"slug"` // subdomain için: mavikent.siteeksen.com
 	CustomDomain      string             `json:"custom_domain,omitempty"` // mavikent-siteyonetimi.com
 	SubscriptionPlan  string             `json:"subscription_plan"` // STARTER, PRO, ENTERPRISE
-	SubscriptionStatus string            `json:"subscription_status"` // ACTIVE, TRIAL, SUSPENDED
+	SubscriptionStatus string            `json:"subscription_status"` // ACTIVE, TRIAL, PAST_DUE, SUSPENDED
 	MaxUnits          int                `json:"max_units"` // Plan limiti
 	MaxUsers          int                `json:"max_users"`
 	Features          []string           `json:"features"` // Aktif özellikler
 	Settings          *TenantSettings    `json:"settings"`
 	Branding          *TenantBranding    `json:"branding,omitempty"`
 	CreatedAt         string             `json:"created_at"`
-	ExpiresAt         string             `json:"expires_at,omitempty"`
+	ExpiresAt         string             `json:"expires_at,omitempty"` // Deneme bitişi veya dönem sonu
+	SuspendedAt       string             `json:"suspended_at,omitempty"`
+	SuspensionReason  string             `json:"suspension_reason,omitempty"`
 }
 
 // TenantSettings - Kiracı ayarları
@@ -31,10 +39,13 @@ type TenantSettings struct {
 	DateFormat         string `json:"date_format"`
 	AssessmentDueDay   int    `json:"assessment_due_day"` // Aidat son ödeme günü
 	LateFeePercentage  float64 `json:"late_fee_percentage"`
+	LateFeeMethod      string  `json:"late_fee_method"` // DAILY (gün bazında kıst), MONTHLY (başlayan ay)
+	PaymentAllocationPolicy string `json:"payment_allocation_policy"` // LATE_FEE_FIRST, PRINCIPAL_FIRST
 	MeterReadingDeadline int  `json:"meter_reading_deadline"` // Ay içinde günü
 	EnableReservations bool   `json:"enable_reservations"`
 	EnableSurveys      bool   `json:"enable_surveys"`
 	EnableBulletins    bool   `json:"enable_bulletins"`
+	MFARequiredRoles   []string `json:"mfa_required_roles"` // İki adımlı doğrulamanın zorunlu olduğu roller
 }
 
 // TenantBranding - Marka özelleştirme
@@ -45,6 +56,15 @@ type TenantBranding struct {
 	AppName       string `json:"app_name,omitempty"` // Özel uygulama adı
 }
 
+// Abonelik durumları (veritabanında küçük harfle saklanır)
+const (
+	StatusTrial     = "TRIAL"
+	StatusActive    = "ACTIVE"
+	StatusPastDue   = "PAST_DUE" // Tahsilat başarısız; yeniden deneniyor
+	StatusSuspended = "SUSPENDED"
+	StatusCancelled = "CANCELLED"
+)
+
 // SubscriptionPlan - Abonelik planı
 type SubscriptionPlan struct {
 	ID          string   `json:"id"`
@@ -89,10 +109,29 @@ func GetPlans() []SubscriptionPlan {
 	}
 }
 
-// TenantManager - Kiracı yöneticisi
+// ManagerConfig kiracı önbelleği ayarları
+type ManagerConfig struct {
+	CacheSize   int           // Önbellekteki en fazla anahtar (kimlik, slug, alan adı)
+	CacheTTL    time.Duration // Bildirim kaçırılsa bile kaydın en fazla bayat kalacağı süre
+	TrialPeriod time.Duration // Yeni kiracının deneme süresi
+}
+
+// DefaultManagerConfig varsayılan ayarlar
+func DefaultManagerConfig() ManagerConfig {
+	return ManagerConfig{
+		CacheSize:   1000,
+		CacheTTL:    5 * time.Minute,
+		TrialPeriod: 14 * 24 * time.Hour,
+	}
+}
+
+// TenantManager - Kiracı yöneticisi. Kayıtlar Store'dan (tenants tablosu) okunur ve süreli
+// LRU önbellekte tutulur; tablodaki değişiklikler Listen ile önbellekten düşürülür.
 type TenantManager struct {
-	tenants map[string]*Tenant
-	mu      sync.RWMutex
+	store  Store
+	cache  *cache
+	config ManagerConfig
+	now    func() time.Time
 }
 
 var (
@@ -100,65 +139,170 @@ var (
 	once    sync.Once
 )
 
-// GetManager - Singleton tenant manager
+// NewManager store üzerinde kiracı yöneticisi oluşturur
+func NewManager(store Store, config ManagerConfig) *TenantManager {
+	return &TenantManager{
+		store:  store,
+		cache:  newCache(config.CacheSize, config.CacheTTL),
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// GetManager - Singleton tenant manager. database.Connect ile açılan havuzu kullanır;
+// bağlantıdan sonra çağrılmalıdır.
 func GetManager() *TenantManager {
 	once.Do(func() {
-		manager = &TenantManager{
-			tenants: make(map[string]*Tenant),
+		var store Store
+		if pool := database.GetPool(); pool != nil {
+			store = NewPostgresStore(pool)
 		}
+		manager = NewManager(store, DefaultManagerConfig())
 	})
 	return manager
 }
 
 // GetTenant - Kiracı bilgisi getir
 func (m *TenantManager) GetTenant(ctx context.Context, tenantID string) (*Tenant, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	tenant, ok := m.tenants[tenantID]
-	if !ok {
-		return nil, fmt.Errorf("tenant not found: %s", tenantID)
-	}
-	return tenant, nil
+	return m.lookup(ctx, idKey(tenantID), func(s Store) (*Tenant, error) {
+		return s.Get(ctx, tenantID)
+	})
 }
 
 // GetTenantBySlug - Subdomain ile kiracı bul
 func (m *TenantManager) GetTenantBySlug(ctx context.Context, slug string) (*Tenant, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	for _, tenant := range m.tenants {
-		if tenant.Slug == slug {
-			return tenant, nil
-		}
-	}
-	return nil, fmt.Errorf("tenant not found for slug: %s", slug)
+	return m.lookup(ctx, slugKey(slug), func(s Store) (*Tenant, error) {
+		return s.GetBySlug(ctx, slug)
+	})
 }
 
 // GetTenantByDomain - Custom domain ile kiracı bul
 func (m *TenantManager) GetTenantByDomain(ctx context.Context, domain string) (*Tenant, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.lookup(ctx, domainKey(domain), func(s Store) (*Tenant, error) {
+		return s.GetByDomain(ctx, domain)
+	})
+}
 
-	for _, tenant := range m.tenants {
-		if tenant.CustomDomain == domain {
-			return tenant, nil
-		}
+func (m *TenantManager) lookup(ctx context.Context, key string, load func(Store) (*Tenant, error), extra ...string) (*Tenant, error) {
+	if tenant, ok := m.cache.get(key); ok {
+		return tenant, nil
+	}
+	if m.store == nil {
+		return nil, errors.New("tenant store not configured")
+	}
+	gen := m.cache.gen()
+	tenant, err := load(m.store)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("tenant not found for domain: %s", domain)
+	m.cache.set(tenant, gen, extra...)
+	return tenant, nil
 }
 
-// RegisterTenant - Yeni kiracı kaydet
-func (m *TenantManager) RegisterTenant(tenant *Tenant) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// ListTenants - Kiracıları listele (önbelleğe alınmaz)
+func (m *TenantManager) ListTenants(ctx context.Context, filter ListFilter) ([]Tenant, error) {
+	return m.store.List(ctx, filter)
+}
 
-	if _, exists := m.tenants[tenant.ID]; exists {
-		return fmt.Errorf("tenant already exists: %s", tenant.ID)
+// CreateTenantInput - Yeni kiracı bilgileri
+type CreateTenantInput struct {
+	Name         string `json:"name" binding:"required"`
+	Slug         string `json:"slug" binding:"required"`
+	CustomDomain string `json:"custom_domain"`
+	Plan         string `json:"plan"` // starter, pro, enterprise; boşsa starter
+}
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{1,61}[a-z0-9])$`)
+	domainPattern = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+	// Platformun kendi alt alan adları kiracıya verilmez
+	reservedSlugs = map[string]bool{"www": true, "api": true, "app": true, "admin": true, "mail": true, "status": true}
+)
+
+// CreateTenant - Yeni kiracıyı plan limitleriyle deneme sürümünde oluştur
+func (m *TenantManager) CreateTenant(ctx context.Context, input CreateTenantInput) (*Tenant, error) {
+	tenant, trialEndsAt, err := m.NewTenant(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.Create(ctx, tenant, trialEndsAt); err != nil {
+		return nil, err
 	}
+	m.Invalidate(tenant.ID)
+	return tenant, nil
+}
 
-	m.tenants[tenant.ID] = tenant
-	return nil
+// NewTenant - Girdiyi doğrulayıp deneme sürümündeki kiracıyı ve deneme bitişini hazırlar;
+// kayıt yazılmaz. Kiracıyı başka kayıtlarla aynı transaction'da açan çağıran InsertTenant
+// kullanır ve commit sonrası Invalidate çağırır.
+func (m *TenantManager) NewTenant(input CreateTenantInput) (*Tenant, time.Time, error) {
+	slug := strings.ToLower(strings.TrimSpace(input.Slug))
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, time.Time{}, fmt.Errorf("%w: name is required", ErrInvalidTenant)
+	}
+	if !slugPattern.MatchString(slug) || reservedSlugs[slug] {
+		return nil, time.Time{}, fmt.Errorf("%w: slug %q", ErrInvalidTenant, input.Slug)
+	}
+	domain := strings.ToLower(strings.TrimSpace(input.CustomDomain))
+	if domain != "" && (!domainPattern.MatchString(domain) || strings.HasSuffix(domain, ".siteeksen.com")) {
+		return nil, time.Time{}, fmt.Errorf("%w: custom domain %q", ErrInvalidTenant, input.CustomDomain)
+	}
+	planID := input.Plan
+	if planID == "" {
+		planID = "starter"
+	}
+	plan, ok := findPlan(planID)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%w: unknown plan %q", ErrInvalidTenant, input.Plan)
+	}
+
+	tenant := &Tenant{
+		Name:               strings.TrimSpace(input.Name),
+		Slug:               slug,
+		CustomDomain:       domain,
+		SubscriptionPlan:   plan.ID,
+		SubscriptionStatus: StatusTrial,
+		MaxUnits:           plan.MaxUnits,
+		MaxUsers:           plan.MaxUsers,
+		Features:           plan.Features,
+		Settings: &TenantSettings{
+			Timezone:         "Europe/Istanbul",
+			Currency:         "TRY",
+			Language:         "tr",
+			DateFormat:       "02.01.2006",
+			AssessmentDueDay: 10,
+		},
+	}
+	return tenant, m.now().Add(m.config.TrialPeriod), nil
+}
+
+// SuspendTenant - Kiracıyı askıya al; askıdaki kiracının istekleri DatabaseScope'ta reddedilir
+func (m *TenantManager) SuspendTenant(ctx context.Context, tenantID, reason string) (*Tenant, error) {
+	tenant, err := m.store.Suspend(ctx, tenantID, reason)
+	m.Invalidate(tenantID)
+	return tenant, err
+}
+
+// ReactivateTenant - Askıdaki kiracıyı yeniden etkinleştir
+func (m *TenantManager) ReactivateTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	tenant, err := m.store.Reactivate(ctx, tenantID)
+	m.Invalidate(tenantID)
+	return tenant, err
+}
+
+// Invalidate - Kiracıyı önbellekten düşür
+func (m *TenantManager) Invalidate(tenantID string) {
+	m.cache.invalidate(tenantID)
+}
+
+func findPlan(id string) (SubscriptionPlan, bool) {
+	for _, p := range GetPlans() {
+		if p.ID == strings.ToLower(id) {
+			return p, true
+		}
+	}
+	return SubscriptionPlan{}, false
 }
 
 // HasFeature - Kiracının özelliği var mı kontrol et
@@ -171,9 +315,11 @@ func (t *Tenant) HasFeature(feature string) bool {
 	return false
 }
 
-// IsActive - Abonelik aktif mi
+// IsActive - Abonelik aktif mi. Tahsilatı başarısız olan (PAST_DUE) kiracı yeniden deneme
+// süresince hizmet almaya devam eder.
 func (t *Tenant) IsActive() bool {
-	return t.SubscriptionStatus == "ACTIVE" || t.SubscriptionStatus == "TRIAL"
+	return t.SubscriptionStatus == StatusActive || t.SubscriptionStatus == StatusTrial ||
+		t.SubscriptionStatus == StatusPastDue
 }
 
 // WithinLimits - Limit kontrolü
@@ -0,0 +1,247 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore testler için Store; her aramayı sayar
+type memoryStore struct {
+	tenants map[string]*Tenant
+	loads   int
+	err     error
+}
+
+func newMemoryStore(tenants ...*Tenant) *memoryStore {
+	s := &memoryStore{tenants: map[string]*Tenant{}}
+	for _, t := range tenants {
+		s.tenants[t.ID] = t
+	}
+	return s
+}
+
+func (s *memoryStore) find(match func(*Tenant) bool) (*Tenant, error) {
+	s.loads++
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, t := range s.tenants {
+		if match(t) {
+			clone := *t
+			return &clone, nil
+		}
+	}
+	return nil, ErrTenantNotFound
+}
+
+func (s *memoryStore) Get(ctx context.Context, id string) (*Tenant, error) {
+	return s.find(func(t *Tenant) bool { return t.ID == id })
+}
+
+func (s *memoryStore) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	return s.find(func(t *Tenant) bool { return strings.EqualFold(t.Slug, slug) })
+}
+
+func (s *memoryStore) GetByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	return s.find(func(t *Tenant) bool { return t.CustomDomain != "" && strings.EqualFold(t.CustomDomain, domain) })
+}
+
+func (s *memoryStore) List(ctx context.Context, filter ListFilter) ([]Tenant, error) {
+	var list []Tenant
+	for _, t := range s.tenants {
+		if filter.Status == "" || strings.EqualFold(t.SubscriptionStatus, filter.Status) {
+			list = append(list, *t)
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryStore) Create(ctx context.Context, t *Tenant, trialEndsAt time.Time) error {
+	for _, existing := range s.tenants {
+		if existing.Slug == t.Slug || (t.CustomDomain != "" && existing.CustomDomain == t.CustomDomain) {
+			return ErrTenantExists
+		}
+	}
+	t.ID = fmt.Sprintf("t-%d", len(s.tenants)+1)
+	t.ExpiresAt = trialEndsAt.Format(time.RFC3339)
+	clone := *t
+	s.tenants[t.ID] = &clone
+	return nil
+}
+
+func (s *memoryStore) setStatus(id string, from []string, to string) (*Tenant, error) {
+	t, ok := s.tenants[id]
+	if !ok {
+		return nil, ErrTenantNotFound
+	}
+	for _, status := range from {
+		if t.SubscriptionStatus == status {
+			t.SubscriptionStatus = to
+			clone := *t
+			return &clone, nil
+		}
+	}
+	clone := *t
+	return &clone, ErrStatusConflict
+}
+
+func (s *memoryStore) Suspend(ctx context.Context, id, reason string) (*Tenant, error) {
+	return s.setStatus(id, []string{"ACTIVE", "TRIAL", "PAST_DUE"}, "SUSPENDED")
+}
+
+func (s *memoryStore) Reactivate(ctx context.Context, id string) (*Tenant, error) {
+	return s.setStatus(id, []string{"SUSPENDED"}, "ACTIVE")
+}
+
+func mavikent() *Tenant {
+	return &Tenant{ID: "t-mavi", Name: "Mavi Kent", Slug: "mavikent", CustomDomain: "mavikent-yonetim.com",
+		SubscriptionPlan: "pro", SubscriptionStatus: "ACTIVE"}
+}
+
+func TestManager_CachesLookupsUnderAllKeys(t *testing.T) {
+	store := newMemoryStore(mavikent())
+	m := NewManager(store, DefaultManagerConfig())
+	ctx := context.Background()
+
+	got, err := m.GetTenantBySlug(ctx, "MaviKent")
+	require.NoError(t, err)
+	assert.Equal(t, "t-mavi", got.ID)
+
+	// Slug ile yüklenen kayıt kimlik ve alan adı aramalarını da karşılar
+	_, err = m.GetTenant(ctx, "t-mavi")
+	require.NoError(t, err)
+	_, err = m.GetTenantByDomain(ctx, "MAVIKENT-YONETIM.COM")
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.loads)
+
+	// Bulunamayan kiracı önbelleğe alınmaz
+	_, err = m.GetTenantBySlug(ctx, "yok")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+	_, err = m.GetTenantBySlug(ctx, "yok")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+	assert.Equal(t, 3, store.loads)
+}
+
+func TestManager_InvalidateDropsOldSlug(t *testing.T) {
+	store := newMemoryStore(mavikent())
+	m := NewManager(store, DefaultManagerConfig())
+	ctx := context.Background()
+
+	_, err := m.GetTenantBySlug(ctx, "mavikent")
+	require.NoError(t, err)
+
+	// Slug değişti; bildirim gelene kadar eski kayıt önbellekten döner
+	store.tenants["t-mavi"].Slug = "mavikent-sitesi"
+	_, err = m.GetTenantBySlug(ctx, "mavikent")
+	require.NoError(t, err)
+
+	m.Invalidate("t-mavi")
+	_, err = m.GetTenantBySlug(ctx, "mavikent")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+	got, err := m.GetTenantBySlug(ctx, "mavikent-sitesi")
+	require.NoError(t, err)
+	assert.Equal(t, "t-mavi", got.ID)
+}
+
+func TestCache_TTLAndEviction(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newCache(4, time.Minute)
+	c.now = func() time.Time { return now }
+
+	a := &Tenant{ID: "a", Slug: "a"}
+	b := &Tenant{ID: "b", Slug: "b"}
+	c.set(a, c.gen())
+	c.set(b, c.gen())
+	assert.Equal(t, 4, c.len())
+
+	// a kullanıldı; yeni kayıt en eski kullanılan b'nin anahtarlarını çıkarır
+	_, ok := c.get(idKey("a"))
+	require.True(t, ok)
+	_, ok = c.get(slugKey("a"))
+	require.True(t, ok)
+	c.set(&Tenant{ID: "c", Slug: "c"}, c.gen())
+	assert.Equal(t, 4, c.len())
+	_, ok = c.get(idKey("b"))
+	assert.False(t, ok)
+	_, ok = c.get(idKey("a"))
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = c.get(idKey("a"))
+	assert.False(t, ok, "süresi dolan kayıt dönmemeli")
+}
+
+func TestCache_StaleLoadAfterInvalidateIsNotStored(t *testing.T) {
+	c := newCache(10, time.Minute)
+	gen := c.gen()
+	// Okuma sürerken bildirim geldi
+	c.invalidate("a")
+	c.set(&Tenant{ID: "a", Slug: "a"}, gen)
+	_, ok := c.get(idKey("a"))
+	assert.False(t, ok)
+}
+
+func TestManager_CreateTenant(t *testing.T) {
+	store := newMemoryStore(mavikent())
+	m := NewManager(store, DefaultManagerConfig())
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	created, err := m.CreateTenant(ctx, CreateTenantInput{Name: "Yeşil Vadi", Slug: " YesilVadi ", Plan: "pro"})
+	require.NoError(t, err)
+	assert.Equal(t, "yesilvadi", created.Slug)
+	assert.Equal(t, "TRIAL", created.SubscriptionStatus)
+	assert.Equal(t, 200, created.MaxUnits)
+	assert.Contains(t, created.Features, "surveys")
+	assert.Equal(t, now.Add(14*24*time.Hour).Format(time.RFC3339), created.ExpiresAt)
+
+	_, err = m.CreateTenant(ctx, CreateTenantInput{Name: "Kopya", Slug: "mavikent"})
+	assert.ErrorIs(t, err, ErrTenantExists)
+
+	for _, input := range []CreateTenantInput{
+		{Name: "", Slug: "bos-ad"},
+		{Name: "Ayrılmış", Slug: "admin"},
+		{Name: "Tire", Slug: "-site"},
+		{Name: "Alan", Slug: "alan", CustomDomain: "alan.siteeksen.com"},
+		{Name: "Alan", Slug: "alan", CustomDomain: "http://alan.com"},
+		{Name: "Plan", Slug: "plan", Plan: "gold"},
+	} {
+		_, err := m.CreateTenant(ctx, input)
+		assert.ErrorIs(t, err, ErrInvalidTenant, "%+v", input)
+	}
+}
+
+func TestManager_SuspendAndReactivate(t *testing.T) {
+	store := newMemoryStore(mavikent())
+	m := NewManager(store, DefaultManagerConfig())
+	ctx := context.Background()
+
+	got, err := m.GetTenant(ctx, "t-mavi")
+	require.NoError(t, err)
+	require.True(t, got.IsActive())
+
+	_, err = m.SuspendTenant(ctx, "t-mavi", "Ödeme alınamadı")
+	require.NoError(t, err)
+	got, err = m.GetTenant(ctx, "t-mavi")
+	require.NoError(t, err)
+	assert.False(t, got.IsActive(), "askıya alma önbelleği temizlemeli")
+
+	_, err = m.SuspendTenant(ctx, "t-mavi", "")
+	assert.ErrorIs(t, err, ErrStatusConflict)
+
+	_, err = m.ReactivateTenant(ctx, "t-mavi")
+	require.NoError(t, err)
+	got, err = m.GetTenant(ctx, "t-mavi")
+	require.NoError(t, err)
+	assert.True(t, got.IsActive())
+
+	_, err = m.ReactivateTenant(ctx, "yok")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+}
@@ -1,96 +1,20 @@
 package tenant
 
 import (
-	"context"
+	"errors"
+	"log"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/database"
 )
 
-// TenantContextKey - Context key for tenant
-type TenantContextKey string
-
-const (
-	ContextKeyTenant TenantContextKey = "tenant"
-)
-
-// TenantMiddleware - Her istekte tenant'ı belirle
-func TenantMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var tenant *Tenant
-		var err error
-
-		manager := GetManager()
-		ctx := c.Request.Context()
-
-		// 1. Header'dan tenant ID kontrolü (API kullanımı için)
-		tenantID := c.GetHeader("X-Tenant-ID")
-		if tenantID != "" {
-			tenant, err = manager.GetTenant(ctx, tenantID)
-			if err == nil {
-				setTenantContext(c, tenant)
-				c.Next()
-				return
-			}
-		}
-
-		// 2. Subdomain kontrolü (mavikent.siteeksen.com)
-		host := c.Request.Host
-		if strings.Contains(host, ".siteeksen.com") {
-			parts := strings.Split(host, ".")
-			if len(parts) >= 3 {
-				slug := parts[0]
-				tenant, err = manager.GetTenantBySlug(ctx, slug)
-				if err == nil {
-					setTenantContext(c, tenant)
-					c.Next()
-					return
-				}
-			}
-		}
-
-		// 3. Custom domain kontrolü
-		tenant, err = manager.GetTenantByDomain(ctx, host)
-		if err == nil {
-			setTenantContext(c, tenant)
-			c.Next()
-			return
-		}
-
-		// Tenant bulunamadı
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-			"error": "Tenant not found",
-			"code":  "TENANT_NOT_FOUND",
-		})
-	}
-}
-
-func setTenantContext(c *gin.Context, tenant *Tenant) {
-	// Abonelik kontrolü
-	if !tenant.IsActive() {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-			"error": "Subscription expired or suspended",
-			"code":  "SUBSCRIPTION_INACTIVE",
-		})
-		return
-	}
-
-	// Context'e tenant ekle
-	ctx := context.WithValue(c.Request.Context(), ContextKeyTenant, tenant)
-	c.Request = c.Request.WithContext(ctx)
-	c.Set("tenant", tenant)
-	c.Set("tenant_id", tenant.ID)
-	c.Set("property_id", tenant.ID) // property_id = tenant_id
-}
-
-// GetTenantFromContext - Context'ten tenant al
-func GetTenantFromContext(ctx context.Context) *Tenant {
-	tenant, ok := ctx.Value(ContextKeyTenant).(*Tenant)
-	if !ok {
-		return nil
-	}
-	return tenant
+// abortInactiveTenant askıya alınmış veya iptal edilmiş kiracının isteğini reddeder
+func abortInactiveTenant(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error": "Subscription expired or suspended",
+		"code":  "SUBSCRIPTION_INACTIVE",
+	})
 }
 
 // GetTenantFromGin - Gin context'ten tenant al
@@ -127,10 +51,31 @@ func RequireFeature(feature string) gin.HandlerFunc {
 	}
 }
 
-// EnforceLimits - Limit kontrolü middleware
+// EnforceLimits - Limit kontrolü middleware. Kaynak oluşturan isteklerin önüne konur
+// (ResourceUnits, ResourceUsers); plan limiti doluysa istek 403 PLAN_LIMIT_EXCEEDED ile
+// reddedilir.
 func EnforceLimits(resourceType string) gin.HandlerFunc {
+	return EnforceLimitsWithMeter(GetMeter(), resourceType)
+}
+
+// EnforceLimitsWithMeter - Verilen ölçerle limit kontrolü. Kiracı DatabaseScope'tan
+// alınır; yoksa token'daki aktif sitenin (property_id) kiracısı kullanılır.
+func EnforceLimitsWithMeter(meter *Meter, resourceType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		tenant := GetTenantFromGin(c)
+		if tenant == nil && c.GetString("property_id") != "" {
+			t, err := meter.TenantForProperty(ctx, c.GetString("property_id"))
+			if err != nil && !errors.Is(err, ErrTenantNotFound) {
+				log.Printf("Site kiracısı bulunamadı (%s): %v", c.GetString("property_id"), err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Tenant lookup failed",
+					"code":  "TENANT_LOOKUP_FAILED",
+				})
+				return
+			}
+			tenant = t
+		}
 		if tenant == nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Tenant not found",
@@ -138,10 +83,73 @@ func EnforceLimits(resourceType string) gin.HandlerFunc {
 			return
 		}
 
-		// TODO: Veritabanından mevcut kaynak sayısını al
-		// currentCount := getResourceCount(tenant.ID, resourceType)
+		err := meter.CheckLimit(ctx, tenant, resourceType)
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":    "Plan limit exceeded",
+				"code":     "PLAN_LIMIT_EXCEEDED",
+				"resource": limitErr.Resource,
+				"limit":    limitErr.Limit,
+				"current":  limitErr.Current,
+				"plan":     limitErr.Plan,
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Kullanım sayılamadı (%s %s): %v", tenant.ID, resourceType, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Usage check failed",
+				"code":  "USAGE_CHECK_FAILED",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DatabaseScope - İsteğin kiracısını ve kullanıcısını request context'ine yazar; repository'ler
+// database.InTenantTx veya database.Conn ile sorguları bu kapsamda, satır düzeyi güvenlik
+// politikalarıyla çalıştırır.
+func DatabaseScope() gin.HandlerFunc {
+	return DatabaseScopeWithMeter(GetMeter())
+}
+
+// DatabaseScopeWithMeter - Verilen ölçerle kapsam belirleme. Kiracı token'daki aktif sitenin
+// (property_id) kiracısıdır ve gin context'ine yazılır (GetTenantFromGin). Aboneliği askıya alınmış
+// veya iptal edilmiş kiracının isteği 403 SUBSCRIPTION_INACTIVE ile reddedilir. Kiracı
+// çözülemezse istek kiracısız kapsamla devam eder ve kapsamlı sorgular
+// database.ErrNoTenantScope ile reddedilir.
+func DatabaseScopeWithMeter(meter *Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var tenantID string
+		if c.GetString("property_id") != "" {
+			t, err := meter.TenantForProperty(ctx, c.GetString("property_id"))
+			if err != nil && !errors.Is(err, ErrTenantNotFound) {
+				log.Printf("Site kiracısı bulunamadı (%s): %v", c.GetString("property_id"), err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error": "Tenant lookup failed",
+					"code":  "TENANT_LOOKUP_FAILED",
+				})
+				return
+			}
+			if t != nil && !t.IsActive() {
+				abortInactiveTenant(c)
+				return
+			}
+			if t != nil {
+				tenantID = t.ID
+				c.Set("tenant", t)
+				c.Set("tenant_id", t.ID)
+			}
+		}
 
-		// Şimdilik geç
+		c.Request = c.Request.WithContext(database.WithScope(ctx, database.Scope{
+			TenantID: tenantID,
+			UserID:   c.GetString("user_id"),
+		}))
 		c.Next()
 	}
 }
@@ -0,0 +1,245 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+)
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant slug or domain already in use")
+	ErrStatusConflict = errors.New("tenant status does not allow this change")
+	ErrInvalidTenant  = errors.New("invalid tenant")
+)
+
+// Store kiracı kayıtları (tenants tablosu)
+type Store interface {
+	Get(ctx context.Context, id string) (*Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (*Tenant, error)
+	GetByDomain(ctx context.Context, domain string) (*Tenant, error)
+	List(ctx context.Context, filter ListFilter) ([]Tenant, error)
+	Create(ctx context.Context, t *Tenant, trialEndsAt time.Time) error
+	Suspend(ctx context.Context, id, reason string) (*Tenant, error)
+	Reactivate(ctx context.Context, id string) (*Tenant, error)
+}
+
+// ListFilter kiracı listesi filtresi; Status boşsa tüm durumlar
+type ListFilter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// PostgresStore tenants tablosu üzerinde Store. Slug ve alan adı küçük harfle saklanır;
+// aramalar lower() ifade indekslerini kullanır (migration 020).
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore yeni store oluşturur
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+const tenantColumns = `
+	SELECT id::text, name, slug, COALESCE(custom_domain, ''), subscription_plan, subscription_status,
+		   max_units, max_users, COALESCE(features, '[]'::jsonb), COALESCE(settings, '{}'::jsonb),
+		   COALESCE(branding, '{}'::jsonb), created_at,
+		   CASE WHEN lower(subscription_status) = 'trial' THEN trial_ends_at ELSE current_period_end END,
+		   suspended_at, COALESCE(suspension_reason, '')
+	FROM tenants`
+
+// Get kimlikle kiracı getirir
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Tenant, error) {
+	if uuid.Validate(id) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, id)
+	}
+	return s.getOne(ctx, tenantColumns+` WHERE id = $1::uuid`, id)
+}
+
+// GetBySlug alt alan adıyla kiracı getirir
+func (s *PostgresStore) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	return s.getOne(ctx, tenantColumns+` WHERE lower(slug) = lower($1)`, slug)
+}
+
+// GetByDomain özel alan adıyla kiracı getirir
+func (s *PostgresStore) GetByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	return s.getOne(ctx, tenantColumns+` WHERE lower(custom_domain) = lower($1)`, domain)
+}
+
+// List kiracıları en yeni önce listeler
+func (s *PostgresStore) List(ctx context.Context, filter ListFilter) ([]Tenant, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.pool.Query(ctx, tenantColumns+`
+		WHERE ($1 = '' OR lower(subscription_status) = lower($1))
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, filter.Status, limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []Tenant{}
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, *t)
+	}
+	return tenants, rows.Err()
+}
+
+// Create yeni kiracı ekler; ID ve CreatedAt veritabanından doldurulur
+func (s *PostgresStore) Create(ctx context.Context, t *Tenant, trialEndsAt time.Time) error {
+	return InsertTenant(ctx, s.pool, t, trialEndsAt)
+}
+
+// InsertTenant kiracıyı verilen bağlantı veya transaction üzerinden ekler; kiracıyı ilk
+// sitesiyle birlikte tek transaction'da açan kayıt akışı için
+func InsertTenant(ctx context.Context, q database.Querier, t *Tenant, trialEndsAt time.Time) error {
+	features, err := json.Marshal(t.Features)
+	if err != nil {
+		return err
+	}
+	settings, err := json.Marshal(t.Settings)
+	if err != nil {
+		return err
+	}
+	branding, err := json.Marshal(t.Branding)
+	if err != nil {
+		return err
+	}
+
+	var createdAt time.Time
+	err = q.QueryRow(ctx, `
+		INSERT INTO tenants (name, slug, custom_domain, subscription_plan, subscription_status, max_units, max_users,
+							 features, settings, branding, trial_ends_at)
+		VALUES ($1, lower($2), lower(NULLIF($3, '')), $4, lower($5), $6, $7, $8, $9, NULLIF($10, 'null'::jsonb), $11)
+		RETURNING id::text, created_at
+	`, t.Name, t.Slug, t.CustomDomain, t.SubscriptionPlan, t.SubscriptionStatus, t.MaxUnits, t.MaxUsers,
+		features, settings, branding, nullTime(trialEndsAt)).Scan(&t.ID, &createdAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTenantExists
+	}
+	if err != nil {
+		return err
+	}
+	t.Slug = strings.ToLower(t.Slug)
+	t.CustomDomain = strings.ToLower(t.CustomDomain)
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	if !trialEndsAt.IsZero() {
+		t.ExpiresAt = trialEndsAt.Format(time.RFC3339)
+	}
+	return nil
+}
+
+// Suspend aktif, deneme sürümündeki veya tahsilatı gecikmiş kiracıyı askıya alır
+func (s *PostgresStore) Suspend(ctx context.Context, id, reason string) (*Tenant, error) {
+	return s.transition(ctx, id, `
+		UPDATE tenants
+		SET subscription_status = 'suspended', suspended_at = NOW(), suspension_reason = NULLIF($2, ''),
+			updated_at = NOW()
+		WHERE id::text = $1 AND lower(subscription_status) IN ('active', 'trial', 'past_due')
+	`, id, reason)
+}
+
+// Reactivate askıdaki kiracıyı yeniden etkinleştirir. Deneme süresi bitmemiş ve
+// aboneliği başlamamış kiracı deneme sürümüne, diğerleri aktif aboneliğe döner.
+func (s *PostgresStore) Reactivate(ctx context.Context, id string) (*Tenant, error) {
+	return s.transition(ctx, id, `
+		UPDATE tenants
+		SET subscription_status = CASE
+				WHEN trial_ends_at > NOW() AND current_period_end IS NULL THEN 'trial'
+				ELSE 'active'
+			END,
+			suspended_at = NULL, suspension_reason = NULL, updated_at = NOW()
+		WHERE id::text = $1 AND lower(subscription_status) = 'suspended'
+	`, id)
+}
+
+// transition koşullu durum güncellemesini çalıştırır; kiracı yoksa ErrTenantNotFound,
+// durumu uygun değilse ErrStatusConflict döner
+func (s *PostgresStore) transition(ctx context.Context, id, sql string, args ...any) (*Tenant, error) {
+	tag, err := s.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return t, ErrStatusConflict
+	}
+	return t, nil
+}
+
+func (s *PostgresStore) getOne(ctx context.Context, sql, arg string) (*Tenant, error) {
+	t, err := scanTenant(s.pool.QueryRow(ctx, sql, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, arg)
+	}
+	return t, err
+}
+
+func scanTenant(row pgx.Row) (*Tenant, error) {
+	t := &Tenant{}
+	var features, settings, branding []byte
+	var createdAt time.Time
+	var expiresAt, suspendedAt *time.Time
+	if err := row.Scan(&t.ID, &t.Name, &t.Slug, &t.CustomDomain, &t.SubscriptionPlan, &t.SubscriptionStatus,
+		&t.MaxUnits, &t.MaxUsers, &features, &settings, &branding, &createdAt, &expiresAt,
+		&suspendedAt, &t.SuspensionReason); err != nil {
+		return nil, err
+	}
+
+	// Durum veritabanında küçük harfle (trial, active) saklanır
+	t.SubscriptionStatus = strings.ToUpper(t.SubscriptionStatus)
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	if expiresAt != nil {
+		t.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	if suspendedAt != nil {
+		t.SuspendedAt = suspendedAt.Format(time.RFC3339)
+	}
+
+	if err := json.Unmarshal(features, &t.Features); err != nil {
+		return nil, fmt.Errorf("tenant %s features: %w", t.ID, err)
+	}
+	t.Settings = &TenantSettings{}
+	if err := json.Unmarshal(settings, t.Settings); err != nil {
+		return nil, fmt.Errorf("tenant %s settings: %w", t.ID, err)
+	}
+	if string(branding) != "{}" {
+		t.Branding = &TenantBranding{}
+		if err := json.Unmarshal(branding, t.Branding); err != nil {
+			return nil, fmt.Errorf("tenant %s branding: %w", t.ID, err)
+		}
+	}
+	return t, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,236 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/database"
+)
+
+// Ölçülen kaynaklar (usage_metrics.metric_type)
+const (
+	ResourceUnits     = "units"
+	ResourceUsers     = "users"
+	ResourceSMSSent   = "sms_sent"   // Bu ay gönderilen SMS
+	ResourceStorageMB = "storage_mb" // Yüklenen belgeler
+)
+
+// Resources anlık görüntüsü alınan kaynaklar
+var Resources = []string{ResourceUnits, ResourceUsers, ResourceSMSSent, ResourceStorageMB}
+
+// SoftLimitPercent plan limitinin bu yüzdesine ulaşıldığında kiracı yöneticisi uyarılır
+const SoftLimitPercent = 80
+
+// LimitError plan limitini aşacak oluşturma isteği
+type LimitError struct {
+	Resource string `json:"resource"`
+	Limit    int    `json:"limit"`
+	Current  int    `json:"current"`
+	Plan     string `json:"plan"`
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("plan limit exceeded: %s %d/%d", e.Resource, e.Current, e.Limit)
+}
+
+// LimitWarning kiracının bir kaynakta limitin SoftLimitPercent'ine ulaştığı bildirim
+type LimitWarning struct {
+	TenantID   string
+	TenantName string
+	Plan       string
+	Resource   string
+	Limit      int
+	Current    int
+}
+
+// WarningNotifier limit uyarısını kiracı yöneticisine iletir
+type WarningNotifier interface {
+	NotifyLimitWarning(ctx context.Context, w LimitWarning) error
+}
+
+// UsageStore kiracı kullanım sayıları ve anlık görüntüleri
+type UsageStore interface {
+	// Count kiracının kaynaktaki güncel kullanımı
+	Count(ctx context.Context, tenantID, resource string) (int, error)
+	// TenantIDForProperty sitenin bağlı olduğu kiracı
+	TenantIDForProperty(ctx context.Context, propertyID string) (string, error)
+	// TenantIDs anlık görüntüsü alınacak tüm kiracılar
+	TenantIDs(ctx context.Context) ([]string, error)
+	// RecordSMS sitenin kiracısına gönderilen SMS sayısını ekler
+	RecordSMS(ctx context.Context, propertyID string, day time.Time, count int) error
+	// SaveSnapshot günün kullanım değerlerini usage_metrics'e yazar (aynı gün tekrar yazılırsa günceller)
+	SaveSnapshot(ctx context.Context, tenantID string, day time.Time, values map[string]int) error
+	// RecordWarning limit uyarısını kaydeder; aynı limit için daha önce kaydedildiyse false döner
+	RecordWarning(ctx context.Context, w LimitWarning) (bool, error)
+}
+
+// Meter kiracı kullanımını ölçer, plan limitlerini uygular ve günlük anlık görüntü alır
+type Meter struct {
+	store    UsageStore
+	manager  *TenantManager
+	notifier WarningNotifier
+	now      func() time.Time
+}
+
+var (
+	meter     *Meter
+	meterOnce sync.Once
+)
+
+// NewMeter yeni ölçer oluşturur; notifier nil ise uyarılar yalnızca kaydedilir
+func NewMeter(store UsageStore, manager *TenantManager, notifier WarningNotifier) *Meter {
+	return &Meter{store: store, manager: manager, notifier: notifier, now: time.Now}
+}
+
+// GetMeter - Singleton ölçer. database.Connect ile açılan havuzu ve GetManager'ı kullanır;
+// uyarılar yalnızca kaydedilir.
+func GetMeter() *Meter {
+	meterOnce.Do(func() {
+		var store UsageStore
+		if pool := database.GetPool(); pool != nil {
+			store = NewPostgresUsageStore(pool)
+		}
+		meter = NewMeter(store, GetManager(), nil)
+	})
+	return meter
+}
+
+// Usage kiracının tüm kaynaklardaki güncel kullanımı
+func (m *Meter) Usage(ctx context.Context, tenantID string) (map[string]int, error) {
+	values := make(map[string]int, len(Resources))
+	for _, resource := range Resources {
+		n, err := m.store.Count(ctx, tenantID, resource)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", resource, err)
+		}
+		values[resource] = n
+	}
+	return values, nil
+}
+
+// TenantForProperty sitenin kiracısını getirir (DatabaseScope ve EnforceLimits için). Kiracı
+// site anahtarıyla da önbelleğe alınır; kiracı geçersiz kılınınca site eşleşmesi de düşer.
+func (m *Meter) TenantForProperty(ctx context.Context, propertyID string) (*Tenant, error) {
+	key := propertyKey(propertyID)
+	return m.manager.lookup(ctx, key, func(s Store) (*Tenant, error) {
+		tenantID, err := m.store.TenantIDForProperty(ctx, propertyID)
+		if err != nil {
+			return nil, err
+		}
+		return s.Get(ctx, tenantID)
+	}, key)
+}
+
+// CheckLimit yeni bir kaynak oluşturulmadan önce plan limitini denetler. Oluşturma limiti
+// aşacaksa *LimitError döner. Oluşturmadan sonra kullanım limitin SoftLimitPercent'ine
+// ulaşıyorsa kiracı yöneticisi (limit başına bir kez) uyarılır.
+func (m *Meter) CheckLimit(ctx context.Context, t *Tenant, resource string) error {
+	return m.CheckLimitN(ctx, t, resource, 1)
+}
+
+// CheckLimitN toplu oluşturmada CheckLimit: n kaynak eklendiğinde limit aşılacaksa
+// *LimitError döner (Current eklemeden önceki kullanımdır)
+func (m *Meter) CheckLimitN(ctx context.Context, t *Tenant, resource string, n int) error {
+	limit := resourceLimit(t, resource)
+	if limit < 0 || n <= 0 {
+		return nil
+	}
+	current, err := m.store.Count(ctx, t.ID, resource)
+	if err != nil {
+		return err
+	}
+	if current+n > limit {
+		return &LimitError{Resource: resource, Limit: limit, Current: current, Plan: t.SubscriptionPlan}
+	}
+	if reachesSoftLimit(current+n, limit) {
+		m.warn(ctx, LimitWarning{TenantID: t.ID, TenantName: t.Name, Plan: t.SubscriptionPlan,
+			Resource: resource, Limit: limit, Current: current + n})
+	}
+	return nil
+}
+
+// warn uyarıyı kaydeder ve ilk kez kaydedildiyse isteği bekletmeden bildirir
+func (m *Meter) warn(ctx context.Context, w LimitWarning) {
+	recorded, err := m.store.RecordWarning(ctx, w)
+	if err != nil {
+		log.Printf("Limit uyarısı kaydedilemedi (%s %s): %v", w.TenantID, w.Resource, err)
+		return
+	}
+	if !recorded || m.notifier == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := m.notifier.NotifyLimitWarning(ctx, w); err != nil {
+			log.Printf("Limit uyarısı gönderilemedi (%s %s): %v", w.TenantID, w.Resource, err)
+		}
+	}()
+}
+
+// RecordSMS site adına gönderilen SMS'i kiracının bugünkü sayacına ekler
+func (m *Meter) RecordSMS(ctx context.Context, propertyID string, count int) error {
+	return m.store.RecordSMS(ctx, propertyID, m.now(), count)
+}
+
+// Snapshot tüm kiracıların günlük kullanım değerlerini usage_metrics'e yazar. Bir
+// kiracıdaki hata diğerlerini durdurmaz; yazılan kiracı sayısı ve ilk hata döner.
+func (m *Meter) Snapshot(ctx context.Context, day time.Time) (int, error) {
+	tenantIDs, err := m.store.TenantIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	saved := 0
+	var firstErr error
+	for _, tenantID := range tenantIDs {
+		values, err := m.Usage(ctx, tenantID)
+		if err == nil {
+			err = m.store.SaveSnapshot(ctx, tenantID, day, values)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("tenant %s: %w", tenantID, err)
+			}
+			continue
+		}
+		saved++
+	}
+	return saved, firstErr
+}
+
+// RunDailySnapshots açılışta ve her gece yarısından sonra anlık görüntü alır; ctx iptal
+// edilene kadar çalışır. Aynı günün görüntüsü tekrar alınırsa üzerine yazılır.
+func (m *Meter) RunDailySnapshots(ctx context.Context) {
+	for {
+		now := m.now()
+		if saved, err := m.Snapshot(ctx, now); err != nil {
+			log.Printf("Kullanım görüntüsü eksik alındı (%d kiracı): %v", saved, err)
+		}
+
+		next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 5, 0, 0, now.Location())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+	}
+}
+
+// resourceLimit planın kaynak limiti; -1 sınırsız (limitsiz kaynaklar dahil)
+func resourceLimit(t *Tenant, resource string) int {
+	switch resource {
+	case ResourceUnits:
+		return t.MaxUnits
+	case ResourceUsers:
+		return t.MaxUsers
+	}
+	return -1
+}
+
+// reachesSoftLimit kullanım limitin SoftLimitPercent'ine ulaştı mı
+func reachesSoftLimit(usage, limit int) bool {
+	return limit > 0 && usage*100 >= limit*SoftLimitPercent
+}
@@ -0,0 +1,155 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ UsageStore = (*PostgresUsageStore)(nil)
+
+// PostgresUsageStore kullanımı kiracının sitelerindeki (properties.tenant_id) kayıtlardan sayar
+type PostgresUsageStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresUsageStore yeni store oluşturur
+func NewPostgresUsageStore(pool *pgxpool.Pool) *PostgresUsageStore {
+	return &PostgresUsageStore{pool: pool}
+}
+
+// Kaynak sayım sorguları; $1 kiracı kimliği
+var usageQueries = map[string]string{
+	ResourceUnits: `
+		SELECT COUNT(*)
+		FROM units u
+		JOIN properties p ON p.id = u.property_id
+		WHERE p.tenant_id = $1::uuid`,
+
+	// Aktif sakinler ve site rolü atanmış kadro; birden çok sitede olan kullanıcı bir kez sayılır
+	ResourceUsers: `
+		SELECT COUNT(*) FROM (
+			SELECT ru.resident_id AS user_id
+			FROM resident_units ru
+			JOIN units u ON u.id = ru.unit_id
+			JOIN properties p ON p.id = u.property_id
+			WHERE p.tenant_id = $1::uuid AND ru.is_active = true
+			  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+			UNION
+			SELECT a.user_id
+			FROM property_role_assignments a
+			JOIN properties p ON p.id = a.property_id
+			WHERE p.tenant_id = $1::uuid AND a.revoked_at IS NULL
+		) users`,
+
+	ResourceSMSSent: `
+		SELECT COALESCE(SUM(sent_count), 0)
+		FROM sms_usage
+		WHERE tenant_id = $1::uuid AND usage_date >= date_trunc('month', CURRENT_DATE)`,
+
+	// Vekaletnameler ve gider faturaları; MB'a yukarı yuvarlanır
+	ResourceStorageMB: `
+		SELECT CEIL(COALESCE(SUM(size), 0) / 1048576.0)::int FROM (
+			SELECT d.document_size AS size
+			FROM unit_proxies d
+			JOIN properties p ON p.id = d.property_id
+			WHERE p.tenant_id = $1::uuid
+			UNION ALL
+			SELECT COALESCE(i.file_size, 0)
+			FROM expense_invoices i
+			JOIN expenses e ON e.id = i.expense_id
+			JOIN properties p ON p.id = e.property_id
+			WHERE p.tenant_id = $1::uuid
+		) files`,
+}
+
+// Count kiracının kaynaktaki güncel kullanımı
+func (s *PostgresUsageStore) Count(ctx context.Context, tenantID, resource string) (int, error) {
+	query, ok := usageQueries[resource]
+	if !ok {
+		return 0, fmt.Errorf("unknown resource: %s", resource)
+	}
+	var n int
+	err := s.pool.QueryRow(ctx, query, tenantID).Scan(&n)
+	return n, err
+}
+
+// TenantIDForProperty sitenin bağlı olduğu kiracı
+func (s *PostgresUsageStore) TenantIDForProperty(ctx context.Context, propertyID string) (string, error) {
+	if uuid.Validate(propertyID) != nil {
+		return "", fmt.Errorf("%w: property %s", ErrTenantNotFound, propertyID)
+	}
+	var tenantID string
+	err := s.pool.QueryRow(ctx, `
+		SELECT tenant_id::text FROM properties WHERE id = $1::uuid AND tenant_id IS NOT NULL
+	`, propertyID).Scan(&tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: property %s", ErrTenantNotFound, propertyID)
+	}
+	return tenantID, err
+}
+
+// TenantIDs iptal edilmemiş tüm kiracılar
+func (s *PostgresUsageStore) TenantIDs(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id::text FROM tenants WHERE lower(subscription_status) <> 'cancelled' ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RecordSMS sitenin kiracısının günlük SMS sayacını artırır; kiracısız sitede bir şey yapmaz
+func (s *PostgresUsageStore) RecordSMS(ctx context.Context, propertyID string, day time.Time, count int) error {
+	if uuid.Validate(propertyID) != nil {
+		return nil
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO sms_usage (tenant_id, usage_date, sent_count)
+		SELECT tenant_id, $2::date, $3 FROM properties WHERE id = $1::uuid AND tenant_id IS NOT NULL
+		ON CONFLICT (tenant_id, usage_date) DO UPDATE SET sent_count = sms_usage.sent_count + EXCLUDED.sent_count
+	`, propertyID, day, count)
+	return err
+}
+
+// SaveSnapshot günün kullanım değerlerini usage_metrics'e yazar
+func (s *PostgresUsageStore) SaveSnapshot(ctx context.Context, tenantID string, day time.Time, values map[string]int) error {
+	batch := &pgx.Batch{}
+	for resource, value := range values {
+		batch.Queue(`
+			INSERT INTO usage_metrics (tenant_id, metric_type, metric_value, recorded_at)
+			VALUES ($1, $2, $3, $4::date)
+			ON CONFLICT (tenant_id, metric_type, recorded_at) DO UPDATE SET metric_value = EXCLUDED.metric_value
+		`, tenantID, resource, value, day)
+	}
+	return s.pool.SendBatch(ctx, batch).Close()
+}
+
+// RecordWarning limit uyarısını kaydeder; aynı limit için kayıt varsa false döner
+func (s *PostgresUsageStore) RecordWarning(ctx context.Context, w LimitWarning) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO tenant_limit_warnings (tenant_id, resource_type, limit_value, usage_value)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, resource_type, limit_value) DO NOTHING
+	`, w.TenantID, w.Resource, w.Limit, w.Current)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,267 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUsage testler için UsageStore
+type memoryUsage struct {
+	counts          map[string]int // "tenant/resource"
+	properties      map[string]string
+	propertyLookups int
+	snapshots       map[string]map[string]int // "tenant/2006-01-02"
+	warnings        map[string]bool
+}
+
+func newMemoryUsage() *memoryUsage {
+	return &memoryUsage{
+		counts:     map[string]int{},
+		properties: map[string]string{"p-mavi": "t-mavi"},
+		snapshots:  map[string]map[string]int{},
+		warnings:   map[string]bool{},
+	}
+}
+
+func (s *memoryUsage) Count(ctx context.Context, tenantID, resource string) (int, error) {
+	return s.counts[tenantID+"/"+resource], nil
+}
+
+func (s *memoryUsage) TenantIDForProperty(ctx context.Context, propertyID string) (string, error) {
+	s.propertyLookups++
+	if id, ok := s.properties[propertyID]; ok {
+		return id, nil
+	}
+	return "", ErrTenantNotFound
+}
+
+func (s *memoryUsage) TenantIDs(ctx context.Context) ([]string, error) {
+	return []string{"t-mavi"}, nil
+}
+
+func (s *memoryUsage) RecordSMS(ctx context.Context, propertyID string, day time.Time, count int) error {
+	s.counts[s.properties[propertyID]+"/"+ResourceSMSSent] += count
+	return nil
+}
+
+func (s *memoryUsage) SaveSnapshot(ctx context.Context, tenantID string, day time.Time, values map[string]int) error {
+	s.snapshots[tenantID+"/"+day.Format("2006-01-02")] = values
+	return nil
+}
+
+func (s *memoryUsage) RecordWarning(ctx context.Context, w LimitWarning) (bool, error) {
+	key := fmt.Sprintf("%s/%s/%d", w.TenantID, w.Resource, w.Limit)
+	if s.warnings[key] {
+		return false, nil
+	}
+	s.warnings[key] = true
+	return true, nil
+}
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	warnings []LimitWarning
+	done     chan struct{}
+}
+
+func (n *recordingNotifier) NotifyLimitWarning(ctx context.Context, w LimitWarning) error {
+	n.mu.Lock()
+	n.warnings = append(n.warnings, w)
+	n.mu.Unlock()
+	n.done <- struct{}{}
+	return nil
+}
+
+func newMeterFixture() (*Meter, *memoryUsage, *recordingNotifier) {
+	t := mavikent()
+	t.MaxUnits, t.MaxUsers = 10, -1
+	usage := newMemoryUsage()
+	notifier := &recordingNotifier{done: make(chan struct{}, 4)}
+	return NewMeter(usage, NewManager(newMemoryStore(t), DefaultManagerConfig()), notifier), usage, notifier
+}
+
+func TestMeter_CheckLimit(t *testing.T) {
+	meter, usage, notifier := newMeterFixture()
+	ctx := context.Background()
+	tenant, err := meter.TenantForProperty(ctx, "p-mavi")
+	require.NoError(t, err)
+
+	usage.counts["t-mavi/units"] = 6
+	require.NoError(t, meter.CheckLimit(ctx, tenant, ResourceUnits))
+	assert.Empty(t, notifier.warnings, "7/10 eşiğin altında")
+
+	// 8/10: uyarı bir kez gönderilir
+	usage.counts["t-mavi/units"] = 7
+	require.NoError(t, meter.CheckLimit(ctx, tenant, ResourceUnits))
+	select {
+	case <-notifier.done:
+	case <-time.After(time.Second):
+		t.Fatal("uyarı gönderilmedi")
+	}
+	usage.counts["t-mavi/units"] = 8
+	require.NoError(t, meter.CheckLimit(ctx, tenant, ResourceUnits))
+	require.Len(t, notifier.warnings, 1)
+	assert.Equal(t, LimitWarning{TenantID: "t-mavi", TenantName: "Mavi Kent", Plan: "pro", Resource: ResourceUnits,
+		Limit: 10, Current: 8}, notifier.warnings[0])
+
+	usage.counts["t-mavi/units"] = 10
+	err = meter.CheckLimit(ctx, tenant, ResourceUnits)
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, LimitError{Resource: ResourceUnits, Limit: 10, Current: 10, Plan: "pro"}, *limitErr)
+
+	// Sınırsız plan ve limitsiz kaynaklar engellenmez
+	usage.counts["t-mavi/users"] = 100000
+	assert.NoError(t, meter.CheckLimit(ctx, tenant, ResourceUsers))
+	assert.NoError(t, meter.CheckLimit(ctx, tenant, ResourceSMSSent))
+}
+
+func TestMeter_TenantForPropertyCached(t *testing.T) {
+	meter, usage, _ := newMeterFixture()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		tenant, err := meter.TenantForProperty(ctx, "p-mavi")
+		require.NoError(t, err)
+		assert.Equal(t, "t-mavi", tenant.ID)
+	}
+	assert.Equal(t, 1, usage.propertyLookups, "site eşleşmesi önbellekten dönmeli")
+
+	// Kiracı değişince site eşleşmesi de yeniden okunur
+	meter.manager.Invalidate("t-mavi")
+	_, err := meter.TenantForProperty(ctx, "p-mavi")
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.propertyLookups)
+
+	_, err = meter.TenantForProperty(ctx, "p-yok")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+}
+
+func TestMeter_CheckLimitN(t *testing.T) {
+	meter, usage, _ := newMeterFixture()
+	ctx := context.Background()
+	tenant, err := meter.TenantForProperty(ctx, "p-mavi")
+	require.NoError(t, err)
+
+	usage.counts["t-mavi/units"] = 4
+	require.NoError(t, meter.CheckLimitN(ctx, tenant, ResourceUnits, 6), "4+6 limite eşit")
+	err = meter.CheckLimitN(ctx, tenant, ResourceUnits, 7)
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, 4, limitErr.Current)
+	assert.NoError(t, meter.CheckLimitN(ctx, tenant, ResourceUnits, 0))
+}
+
+func TestMeter_SnapshotAndSMS(t *testing.T) {
+	meter, usage, _ := newMeterFixture()
+	ctx := context.Background()
+	usage.counts["t-mavi/units"] = 42
+	usage.counts["t-mavi/storage_mb"] = 3
+
+	require.NoError(t, meter.RecordSMS(ctx, "p-mavi", 2))
+	require.NoError(t, meter.RecordSMS(ctx, "p-mavi", 1))
+
+	day := time.Date(2026, 3, 1, 0, 5, 0, 0, time.UTC)
+	saved, err := meter.Snapshot(ctx, day)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+	assert.Equal(t, map[string]int{ResourceUnits: 42, ResourceUsers: 0, ResourceSMSSent: 3, ResourceStorageMB: 3},
+		usage.snapshots["t-mavi/2026-03-01"])
+}
+
+func TestEnforceLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	meter, usage, _ := newMeterFixture()
+	usage.counts["t-mavi/units"] = 10
+
+	r := gin.New()
+	r.POST("/units", func(c *gin.Context) {
+		c.Set("property_id", c.GetHeader("X-Property-ID"))
+		c.Next()
+	}, EnforceLimitsWithMeter(meter, ResourceUnits), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	post := func(propertyID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/units", nil)
+		req.Header.Set("X-Property-ID", propertyID)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("p-mavi")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "PLAN_LIMIT_EXCEEDED", body["code"])
+	assert.Equal(t, "units", body["resource"])
+	assert.Equal(t, 10.0, body["limit"])
+	assert.Equal(t, 10.0, body["current"])
+
+	usage.counts["t-mavi/units"] = 3
+	assert.Equal(t, http.StatusCreated, post("p-mavi").Code)
+	assert.Equal(t, http.StatusUnauthorized, post("p-yok").Code)
+}
+
+func TestDatabaseScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	meter, _, _ := newMeterFixture()
+
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set("property_id", c.GetHeader("X-Property-ID"))
+		c.Next()
+	}, DatabaseScopeWithMeter(meter), func(c *gin.Context) {
+		ctx := c.Request.Context()
+		scope, ok := database.ScopeFromContext(ctx)
+		if !ok {
+			// Kapsamlı sorgular havuza düşmez, reddedilir
+			if _, err := database.Conn(ctx, nil).Exec(ctx, "SELECT 1"); !errors.Is(err, database.ErrNoTenantScope) {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.String(http.StatusOK, scope.TenantID+"/"+scope.UserID)
+	})
+	get := func(propertyID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Property-ID", propertyID)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("p-mavi")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "t-mavi/u1", w.Body.String())
+
+	// Kiracısı çözülemeyen istek kapsamsız devam eder; kapsamlı sorgular reddedilir
+	assert.Equal(t, http.StatusNoContent, get("p-yok").Code)
+	assert.Equal(t, http.StatusNoContent, get("").Code)
+
+	// Askıya alınan kiracının istekleri reddedilir, yeniden etkinleştirilince açılır
+	_, err := meter.manager.SuspendTenant(context.Background(), "t-mavi", "Ödeme alınamadı")
+	require.NoError(t, err)
+	w = get("p-mavi")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "SUBSCRIPTION_INACTIVE")
+
+	_, err = meter.manager.ReactivateTenant(context.Background(), "t-mavi")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get("p-mavi").Code)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/middleware"
 )
 
 // =====================================================
@@ -125,6 +126,22 @@ var supportedBanks = []map[string]string{
 // =====================================================
 
 func main() {
+	r := newRouter(middleware.AuthMiddleware())
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8093"
+	}
+
+	log.Printf("Banking Service starting on port %s", port)
+	if err := r.Run(":" + port); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRouter servis rotalarını kurar. auth hesap değişikliklerinden önce access
+// token'ı doğrulayan middleware'dir (testler sabit anahtarla kurar).
+func newRouter(auth gin.HandlerFunc) *gin.Engine {
 	r := gin.Default()
 
 	r.GET("/health", func(c *gin.Context) {
@@ -144,9 +161,10 @@ func main() {
 			accounts.GET("/banks", getSupportedBanks)
 			accounts.GET("/stats", getBankingStats)
 			accounts.GET("/:id", getBankAccount)
-			accounts.POST("", createBankAccount)
-			accounts.PUT("/:id", updateBankAccount)
-			accounts.DELETE("/:id", deleteBankAccount)
+			// Hesap ekleme/değiştirme/silme son 10 dakikada iki adımlı doğrulama ister
+			accounts.POST("", auth, middleware.RequireStepUp(), createBankAccount)
+			accounts.PUT("/:id", auth, middleware.RequireStepUp(), updateBankAccount)
+			accounts.DELETE("/:id", auth, middleware.RequireStepUp(), deleteBankAccount)
 			accounts.POST("/:id/sync", syncBankAccount)
 			accounts.GET("/:id/balance", getAccountBalance)
 			accounts.GET("/:id/transactions", getAccountTransactions)
@@ -168,15 +186,7 @@ func main() {
 		v1.GET("/banking/report", getBankingReport)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8093"
-	}
-
-	log.Printf("Banking Service starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
-	}
+	return r
 }
 
 // Bank Account Handlers
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/middleware"
+)
+
+type bankingFixture struct {
+	router *gin.Engine
+	signer *claims.Signer
+}
+
+func newBankingFixture(t *testing.T) *bankingFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	key, err := claims.GenerateKey("k1")
+	require.NoError(t, err)
+	signer, err := claims.NewSigner("k1", key)
+	require.NoError(t, err)
+	return &bankingFixture{router: newRouter(middleware.AuthMiddlewareWithKeys(signer)), signer: signer}
+}
+
+func (f *bankingFixture) token(t *testing.T, mfaAt *time.Time) string {
+	t.Helper()
+	c := claims.NewAccessClaims("u1", "p1", []string{"admin"}, []string{"*"}, "s1", "j1", time.Now())
+	if mfaAt != nil {
+		c.MFAAt = jwt.NewNumericDate(*mfaAt)
+	}
+	token, err := f.signer.Sign(c)
+	require.NoError(t, err)
+	return token
+}
+
+func (f *bankingFixture) do(method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+const bankAccountBody = `{"bank_code":"0010","bank_name":"Ziraat Bankası","iban":"TR120001001234567890123456","account_name":"ABC Sitesi"}`
+
+func TestBankAccounts_StepUpRequired(t *testing.T) {
+	f := newBankingFixture(t)
+	stale := time.Now().Add(-claims.StepUpMaxAge - time.Minute)
+
+	routes := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/v1/bank-accounts", bankAccountBody},
+		{http.MethodPut, "/api/v1/bank-accounts/a1", bankAccountBody},
+		{http.MethodDelete, "/api/v1/bank-accounts/a1", ""},
+	}
+	for _, r := range routes {
+		w := f.do(r.method, r.path, r.body, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, r.method)
+
+		w = f.do(r.method, r.path, r.body, f.token(t, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, r.method)
+		assert.Contains(t, w.Body.String(), "step_up_required", r.method)
+
+		w = f.do(r.method, r.path, r.body, f.token(t, &stale))
+		assert.Equal(t, http.StatusForbidden, w.Code, r.method)
+	}
+}
+
+func TestBankAccounts_StepUpFresh(t *testing.T) {
+	f := newBankingFixture(t)
+	fresh := time.Now().Add(-time.Minute)
+	token := f.token(t, &fresh)
+
+	w := f.do(http.MethodPost, "/api/v1/bank-accounts", bankAccountBody, token)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = f.do(http.MethodPut, "/api/v1/bank-accounts/a1", bankAccountBody, token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = f.do(http.MethodDelete, "/api/v1/bank-accounts/a1", "", token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestBankAccounts_ReadsDoNotNeedStepUp(t *testing.T) {
+	f := newBankingFixture(t)
+
+	w := f.do(http.MethodGet, "/api/v1/bank-accounts", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
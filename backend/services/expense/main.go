@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/pkg/server"
+	"github.com/siteeksen/backend/pkg/tenant"
 )
 
 // Models
@@ -76,6 +81,21 @@ type AIInvoiceScanResult struct {
 }
 
 func main() {
+	// Veritabanı bağlantısı
+	pool, err := database.Connect(database.NewConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Veritabanı bağlantısı başarısız: %v", err)
+	}
+	defer database.Close()
+	store := newExpenseStore(pool)
+
+	// Kapanış sinyalinde sunucu ve arka plan işleri durdurulur
+	ctx, stop := server.Context()
+	defer stop()
+
+	// Kiracı askıya alma / yeniden etkinleştirme LISTEN/NOTIFY ile önbellekten düşer
+	go tenant.GetManager().Listen(ctx, pool)
+
 	r := gin.Default()
 
 	// Health check
@@ -93,17 +113,18 @@ func main() {
 
 	// Expenses
 	expenses := r.Group("/api/v1/expenses")
+	expenses.Use(middleware.AuthMiddleware(), tenant.DatabaseScope())
 	{
-		expenses.GET("", listExpenses)
-		expenses.GET("/:id", getExpense)
-		expenses.POST("", createExpense)
-		expenses.PUT("/:id", updateExpense)
-		expenses.DELETE("/:id", deleteExpense)
-		expenses.PATCH("/:id/status", updateExpenseStatus)
+		expenses.GET("", middleware.RequirePermission("expense.read"), listExpenses)
+		expenses.GET("/:id", middleware.RequirePermission("expense.read"), getExpense)
+		expenses.POST("", middleware.RequirePermission("expense.manage"), createExpense(store))
+		expenses.PUT("/:id", middleware.RequirePermission("expense.manage"), updateExpense(store))
+		expenses.DELETE("/:id", middleware.RequirePermission("expense.manage"), deleteExpense(store))
+		expenses.PATCH("/:id/status", middleware.RequirePermission("expense.manage"), updateExpenseStatus(store))
 
 		// Invoices
-		expenses.POST("/:id/invoices", uploadInvoice)
-		expenses.DELETE("/:id/invoices/:invoiceId", deleteInvoice)
+		expenses.POST("/:id/invoices", middleware.RequirePermission("expense.manage"), uploadInvoice)
+		expenses.DELETE("/:id/invoices/:invoiceId", middleware.RequirePermission("expense.manage"), deleteInvoice)
 	}
 
 	// AI Invoice Scanning
@@ -122,7 +143,7 @@ func main() {
 	}
 
 	log.Printf("Expense Service starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	if err := server.Run(ctx, ":"+port, r); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -194,39 +215,90 @@ func getExpense(c *gin.Context) {
 	c.JSON(http.StatusOK, expense)
 }
 
-func createExpense(c *gin.Context) {
-	var expense Expense
-	if err := c.ShouldBindJSON(&expense); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	expense.ID = "new-expense-id"
-	expense.Status = "APPROVED"
-	if !expense.IsInvoiced {
-		expense.Status = "PENDING" // Faturasız giderler onay bekler
+func createExpense(store *expenseStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var expense Expense
+		if err := c.ShouldBindJSON(&expense); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		expense.PropertyID = c.GetString("property_id")
+		expense.CreatedBy = c.GetString("user_id")
+		expense.Status = "APPROVED"
+		if !expense.IsInvoiced {
+			expense.Status = "PENDING" // Faturasız giderler onay bekler
+		}
+
+		// Onaylı giderler FATURA kaydıyla defterlenir
+		if err := store.create(c.Request.Context(), &expense); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, expense)
 	}
-	expense.CreatedAt = time.Now()
-	c.JSON(http.StatusCreated, expense)
 }
 
-func updateExpense(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Gider güncellendi"})
+// updateExpense gideri düzenler; onaylı giderin yevmiye kaydı ters çevrilip yeniden defterlenir
+func updateExpense(store *expenseStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var expense Expense
+		if err := c.ShouldBindJSON(&expense); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		expense.ID = c.Param("id")
+		expense.PropertyID = c.GetString("property_id")
+
+		if err := store.update(c.Request.Context(), &expense, c.GetString("user_id")); err != nil {
+			if errors.Is(err, errExpenseNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, expense)
+	}
 }
 
-func deleteExpense(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Gider silindi"})
+func deleteExpense(store *expenseStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := store.delete(c.Request.Context(), c.GetString("property_id"), c.Param("id"), c.GetString("user_id"))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Gider silindi"})
+	}
 }
 
-func updateExpenseStatus(c *gin.Context) {
-	var req struct {
-		Status string `json:"status"`
-		Reason string `json:"reason,omitempty"`
+func updateExpenseStatus(store *expenseStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status string `json:"status" binding:"required,oneof=PENDING APPROVED REJECTED"`
+			Reason string `json:"reason,omitempty"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := store.setStatus(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			req.Status, req.Reason, c.GetString("user_id"))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Durum güncellendi", "status": req.Status})
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func respondStoreError(c *gin.Context, err error) {
+	if errors.Is(err, errExpenseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Durum güncellendi", "status": req.Status})
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 // Invoice handlers
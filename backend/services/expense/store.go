@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+)
+
+var errExpenseNotFound = errors.New("gider bulunamadı")
+
+// expenseStore gider kayıtları ve yevmiye defterlemesi.
+// Onaylı her gider FATURA kaydıyla defterlenir; onayı kaldırılan veya silinen
+// giderlerin kaydı DUZELTME ile ters çevrilir, düzenlenen onaylı gider yeni
+// tutarıyla yeniden defterlenir.
+type expenseStore struct {
+	pool   *pgxpool.Pool
+	ledger *ledger.Ledger
+}
+
+func newExpenseStore(pool *pgxpool.Pool) *expenseStore {
+	return &expenseStore{pool: pool, ledger: ledger.New(pool)}
+}
+
+// db gider yazımlarının bağlantısı; transaction aktif sitenin kiracısıyla (RLS) açılır
+func (s *expenseStore) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, s.pool)
+}
+
+// create gideri kaydeder; onaylı giderleri aynı transaction içinde defterler
+func (s *expenseStore) create(ctx context.Context, e *Expense) error {
+	expenseDate, err := time.Parse("2006-01-02", e.ExpenseDate)
+	if err != nil {
+		return errors.New("geçersiz gider tarihi")
+	}
+	if e.Amount <= 0 {
+		return errors.New("tutar sıfırdan büyük olmalı")
+	}
+
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO expenses
+			(property_id, category_id, description, amount, currency, expense_date,
+			 is_invoiced, invoice_reason, reflects_to_assessment, assessment_period, distribution_type,
+			 status, vendor_name, invoice_number, invoice_date, notes, created_by)
+		VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'TRY'), $6,
+			$7, NULLIF($8, ''), $9, NULLIF($10, ''), COALESCE(NULLIF($11, ''), 'EQUAL'),
+			$12, NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, '')::date, NULLIF($16, ''), $17)
+		RETURNING id, created_at
+	`, e.PropertyID, e.CategoryID, e.Description, e.Amount, e.Currency, expenseDate,
+		e.IsInvoiced, e.InvoiceReason, e.ReflectsToAssessment, e.AssessmentPeriod, e.DistributionType,
+		e.Status, e.VendorName, e.InvoiceNumber, e.InvoiceDate, e.Notes, e.CreatedBy).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("gider kaydedilemedi: %w", err)
+	}
+
+	if e.Status == "APPROVED" {
+		if err := s.postInvoice(ctx, tx, e, expenseDate); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// update giderin bilgilerini düzenler; durum değişmez. Onaylı giderin eski yevmiye kaydı
+// ters çevrilir ve gider yeni bilgileriyle aynı transaction içinde yeniden defterlenir.
+func (s *expenseStore) update(ctx context.Context, e *Expense, userID string) error {
+	expenseDate, err := time.Parse("2006-01-02", e.ExpenseDate)
+	if err != nil {
+		return errors.New("geçersiz gider tarihi")
+	}
+	if e.Amount <= 0 {
+		return errors.New("tutar sıfırdan büyük olmalı")
+	}
+
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	old, _, err := s.lock(ctx, tx, e.PropertyID, e.ID)
+	if err != nil {
+		return err
+	}
+	if old.Status == "APPROVED" {
+		if err := s.reverseInvoice(ctx, tx, old, "Gider düzeltildi: "+old.Description, userID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE expenses SET
+			category_id = $2, description = $3, amount = $4, currency = COALESCE(NULLIF($5, ''), currency),
+			expense_date = $6, is_invoiced = $7, invoice_reason = NULLIF($8, ''), reflects_to_assessment = $9,
+			assessment_period = NULLIF($10, ''), distribution_type = COALESCE(NULLIF($11, ''), distribution_type),
+			vendor_name = NULLIF($12, ''), invoice_number = NULLIF($13, ''), invoice_date = NULLIF($14, '')::date,
+			notes = NULLIF($15, ''), updated_at = NOW()
+		WHERE id = $1
+		RETURNING currency, distribution_type, status, created_by, created_at
+	`, e.ID, e.CategoryID, e.Description, e.Amount, e.Currency, expenseDate,
+		e.IsInvoiced, e.InvoiceReason, e.ReflectsToAssessment, e.AssessmentPeriod, e.DistributionType,
+		e.VendorName, e.InvoiceNumber, e.InvoiceDate, e.Notes).Scan(&e.Currency, &e.DistributionType, &e.Status, &e.CreatedBy, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("gider güncellenemedi: %w", err)
+	}
+
+	if e.Status == "APPROVED" {
+		if err := s.postInvoice(ctx, tx, e, expenseDate); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// setStatus gider durumunu değiştirir ve defteri durumla tutarlı hale getirir
+func (s *expenseStore) setStatus(ctx context.Context, propertyID, id, status, reason, userID string) error {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	e, expenseDate, err := s.lock(ctx, tx, propertyID, id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status == "APPROVED" && e.Status != "APPROVED":
+		if err := s.postInvoice(ctx, tx, e, expenseDate); err != nil {
+			return err
+		}
+	case status != "APPROVED" && e.Status == "APPROVED":
+		if err := s.reverseInvoice(ctx, tx, e, "Gider onayı kaldırıldı: "+e.Description, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE expenses SET status = $2,
+			approved_by = CASE WHEN $2 = 'APPROVED' THEN $3::uuid ELSE NULL END,
+			approved_at = CASE WHEN $2 = 'APPROVED' THEN NOW() ELSE NULL END,
+			rejection_reason = CASE WHEN $2 = 'REJECTED' THEN NULLIF($4, '') ELSE NULL END
+		WHERE id = $1
+	`, id, status, userID, reason)
+	if err != nil {
+		return fmt.Errorf("gider durumu güncellenemedi: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// delete gideri siler; onaylı giderin yevmiye kaydı ters çevrilir
+func (s *expenseStore) delete(ctx context.Context, propertyID, id, userID string) error {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	e, _, err := s.lock(ctx, tx, propertyID, id)
+	if err != nil {
+		return err
+	}
+	if e.Status == "APPROVED" {
+		if err := s.reverseInvoice(ctx, tx, e, "Gider silindi: "+e.Description, userID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM expenses WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("gider silinemedi: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *expenseStore) lock(ctx context.Context, tx pgx.Tx, propertyID, id string) (*Expense, time.Time, error) {
+	var e Expense
+	var expenseDate time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT id, property_id, description, amount, expense_date, is_invoiced, status, COALESCE(invoice_number, '')
+		FROM expenses
+		WHERE id = $1 AND property_id = $2
+		FOR UPDATE
+	`, id, propertyID).Scan(&e.ID, &e.PropertyID, &e.Description, &e.Amount, &expenseDate,
+		&e.IsInvoiced, &e.Status, &e.InvoiceNumber)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, expenseDate, errExpenseNotFound
+	}
+	return &e, expenseDate, err
+}
+
+func (s *expenseStore) postInvoice(ctx context.Context, tx pgx.Tx, e *Expense, expenseDate time.Time) error {
+	entry := ledger.VendorInvoice(e.PropertyID, e.ID, e.Amount, e.IsInvoiced, expenseDate, e.Description, e.InvoiceNumber)
+	if _, err := s.ledger.PostTx(ctx, tx, entry); err != nil {
+		return fmt.Errorf("gider defterlenemedi: %w", err)
+	}
+	return nil
+}
+
+func (s *expenseStore) reverseInvoice(ctx context.Context, tx pgx.Tx, e *Expense, description, userID string) error {
+	entries, err := s.ledger.FindBySource(ctx, tx, ledger.DocInvoice, "expense", e.ID)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if _, err := s.ledger.ReverseTx(ctx, tx, &entries[i], description, userID); err != nil {
+			return fmt.Errorf("gider kaydı ters çevrilemedi: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package allocation ödemelerin tahakkuklara dağıtım kurallarını içerir.
+// Hesaplamalar kuruş cinsinden tam sayılarla yapılır; paket veritabanına erişmez.
+package allocation
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Policy gecikme tazminatı ile ana paranın hangi sırayla kapatılacağı
+type Policy string
+
+const (
+	// LateFeeFirst önce tüm seçili tahakkukların gecikme tazminatı, sonra ana para
+	// kapatılır (TBK md. 100: ödeme önce faiz ve giderlere sayılır). Varsayılan.
+	LateFeeFirst Policy = "LATE_FEE_FIRST"
+	// PrincipalFirst önce ana para, sonra gecikme tazminatı kapatılır
+	PrincipalFirst Policy = "PRINCIPAL_FIRST"
+)
+
+// ParsePolicy site ayarındaki değeri çözer; bilinmeyen değerler için varsayılan döner
+func ParsePolicy(s string) Policy {
+	if Policy(s) == PrincipalFirst {
+		return PrincipalFirst
+	}
+	return LateFeeFirst
+}
+
+// Target ödemenin dağıtılabileceği açık tahakkuk
+type Target struct {
+	AssessmentID string
+	DueDate      time.Time
+	LateFeeDue   float64 // Ödenmemiş gecikme tazminatı
+	PrincipalDue float64 // Ödenmemiş ana para (fon katkı payı dahil)
+	ReserveDue   float64 // PrincipalDue'nun ödenmemiş fon katkı payı kısmı
+}
+
+// Line tek tahakkuka düşen pay. Tahakkuk içinde ana para önce aidata, sonra fon
+// katkı payına sayılır; Reserve, Principal'ın fon katkısına düşen kısmıdır.
+type Line struct {
+	AssessmentID string
+	LateFee      float64
+	Principal    float64
+	Reserve      float64
+}
+
+// Amount satırın toplam tutarı
+func (l Line) Amount() float64 {
+	return float64(toKurus(l.LateFee)+toKurus(l.Principal)) / 100
+}
+
+// Result dağıtım sonucu
+type Result struct {
+	Lines  []Line  // Yalnızca pay alan tahakkuklar, vade sırasıyla
+	Credit float64 // Borçları aşan kısım (daire avansı)
+}
+
+// Applied tahakkuklara dağıtılan toplam
+func (r Result) Applied() float64 {
+	var total int64
+	for _, l := range r.Lines {
+		total += toKurus(l.LateFee) + toKurus(l.Principal)
+	}
+	return float64(total) / 100
+}
+
+// Allocate tutarı vadesi en eski tahakkuktan başlayarak politikaya göre dağıtır.
+// Tüm borçlar kapandıktan sonra kalan tutar Credit olarak döner.
+func Allocate(amount float64, targets []Target, policy Policy) Result {
+	ordered := make([]Target, len(targets))
+	copy(ordered, targets)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].DueDate.Before(ordered[j].DueDate)
+	})
+
+	remaining := toKurus(amount)
+	if remaining < 0 {
+		remaining = 0
+	}
+	fees := make([]int64, len(ordered))
+	principals := make([]int64, len(ordered))
+
+	payFees := func() {
+		for i, t := range ordered {
+			due := toKurus(t.LateFeeDue)
+			take := min64(remaining, max64(due, 0))
+			fees[i] += take
+			remaining -= take
+		}
+	}
+	payPrincipals := func() {
+		for i, t := range ordered {
+			due := toKurus(t.PrincipalDue)
+			take := min64(remaining, max64(due, 0))
+			principals[i] += take
+			remaining -= take
+		}
+	}
+
+	if policy == PrincipalFirst {
+		payPrincipals()
+		payFees()
+	} else {
+		payFees()
+		payPrincipals()
+	}
+
+	result := Result{Credit: float64(remaining) / 100}
+	for i, t := range ordered {
+		if fees[i] == 0 && principals[i] == 0 {
+			continue
+		}
+		result.Lines = append(result.Lines, Line{
+			AssessmentID: t.AssessmentID,
+			LateFee:      float64(fees[i]) / 100,
+			Principal:    float64(principals[i]) / 100,
+			Reserve:      float64(ReserveShare(principals[i], toKurus(t.PrincipalDue), toKurus(t.ReserveDue))) / 100,
+		})
+	}
+	return result
+}
+
+// ReserveShare kuruş cinsinden ana para ödemesinin fon katkı payına düşen kısmı.
+// Ödeme önce aidat kısmını (principalDue - reserveDue) kapatır, kalanı fon katkısına sayılır.
+func ReserveShare(paid, principalDue, reserveDue int64) int64 {
+	share := paid - (principalDue - reserveDue)
+	return max64(min64(share, reserveDue), 0)
+}
+
+// CorrectionSplit kuruş cinsinden tahakkuk düzeltmesinin tahakkuka yansıyan kısmı ile daire
+// avansına aktarılan kısmını ayırır. Artış tamamen tahakkuka eklenir. İndirim önce
+// ödenmemiş aidattan (aidatOpen) düşer; ödenmiş aidata denk gelen kalan kısım avansa
+// aktarılır, böylece tahakkuk hiçbir zaman ödenen tutarın altına inmez.
+func CorrectionSplit(amount, aidatOpen int64) (change, credit int64) {
+	if amount >= 0 {
+		return amount, 0
+	}
+	reduce := min64(-amount, max64(aidatOpen, 0))
+	return -reduce, -amount - reduce
+}
+
+func toKurus(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+package allocation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(m, d int) time.Time {
+	return time.Date(2026, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+}
+
+func targets() []Target {
+	return []Target{
+		{AssessmentID: "feb", DueDate: day(2, 10), PrincipalDue: 1200},
+		{AssessmentID: "jan", DueDate: day(1, 10), LateFeeDue: 60, PrincipalDue: 1200},
+	}
+}
+
+func TestAllocate_OldestFirstLateFeeFirst(t *testing.T) {
+	r := Allocate(700, targets(), LateFeeFirst)
+
+	require.Len(t, r.Lines, 1)
+	assert.Equal(t, "jan", r.Lines[0].AssessmentID)
+	assert.Equal(t, 60.0, r.Lines[0].LateFee)
+	assert.Equal(t, 640.0, r.Lines[0].Principal)
+	assert.Equal(t, 0.0, r.Credit)
+	assert.Equal(t, 700.0, r.Applied())
+}
+
+func TestAllocate_PrincipalFirst(t *testing.T) {
+	r := Allocate(1230, targets(), PrincipalFirst)
+
+	require.Len(t, r.Lines, 2)
+	assert.Equal(t, Line{AssessmentID: "jan", Principal: 1200}, r.Lines[0])
+	assert.Equal(t, Line{AssessmentID: "feb", Principal: 30}, r.Lines[1])
+}
+
+func TestAllocate_LateFeesAcrossAssessmentsBeforeAnyPrincipal(t *testing.T) {
+	ts := []Target{
+		{AssessmentID: "a", DueDate: day(1, 10), LateFeeDue: 120, PrincipalDue: 1200},
+		{AssessmentID: "b", DueDate: day(2, 10), LateFeeDue: 60, PrincipalDue: 1200},
+	}
+	r := Allocate(200, ts, LateFeeFirst)
+
+	require.Len(t, r.Lines, 2)
+	assert.Equal(t, Line{AssessmentID: "a", LateFee: 120, Principal: 20}, r.Lines[0])
+	assert.Equal(t, Line{AssessmentID: "b", LateFee: 60}, r.Lines[1])
+}
+
+func TestAllocate_OverpaymentBecomesCredit(t *testing.T) {
+	r := Allocate(2500.75, targets(), LateFeeFirst)
+
+	assert.Equal(t, 2460.0, r.Applied())
+	assert.Equal(t, 40.75, r.Credit)
+}
+
+func TestAllocate_NoTargetsIsAllCredit(t *testing.T) {
+	r := Allocate(500, nil, LateFeeFirst)
+	assert.Empty(t, r.Lines)
+	assert.Equal(t, 500.0, r.Credit)
+}
+
+func TestAllocate_KurusPrecision(t *testing.T) {
+	ts := []Target{{AssessmentID: "a", DueDate: day(1, 10), PrincipalDue: 0.3}}
+	r := Allocate(0.1+0.2, ts, LateFeeFirst)
+	assert.Equal(t, 0.3, r.Applied())
+	assert.Equal(t, 0.0, r.Credit)
+}
+
+func TestAllocate_ReserveAfterAidatWithinAssessment(t *testing.T) {
+	// 1000 aidat + 100 fon katkısı; ocak tahakkukunun 400 TL'lik aidat kısmı daha önce ödenmiş
+	r := Allocate(750, []Target{
+		{AssessmentID: "jan", DueDate: day(1, 10), PrincipalDue: 700, ReserveDue: 100},
+		{AssessmentID: "feb", DueDate: day(2, 10), PrincipalDue: 1100, ReserveDue: 100},
+	}, LateFeeFirst)
+
+	require.Len(t, r.Lines, 2)
+	assert.Equal(t, Line{AssessmentID: "jan", Principal: 700, Reserve: 100}, r.Lines[0])
+	assert.Equal(t, Line{AssessmentID: "feb", Principal: 50}, r.Lines[1])
+}
+
+func TestReserveShare(t *testing.T) {
+	assert.Equal(t, int64(0), ReserveShare(500, 1100, 100))
+	assert.Equal(t, int64(30), ReserveShare(1030, 1100, 100))
+	assert.Equal(t, int64(100), ReserveShare(1100, 1100, 100))
+	assert.Equal(t, int64(0), ReserveShare(1100, 1100, 0))
+}
+
+func TestCorrectionSplit(t *testing.T) {
+	change, credit := CorrectionSplit(7500, 0)
+	assert.Equal(t, int64(7500), change)
+	assert.Equal(t, int64(0), credit)
+
+	// Açık aidat indirimi karşılıyor
+	change, credit = CorrectionSplit(-4000, 10000)
+	assert.Equal(t, int64(-4000), change)
+	assert.Equal(t, int64(0), credit)
+
+	// 60 TL açık, 100 TL indirim: 40 TL avansa
+	change, credit = CorrectionSplit(-10000, 6000)
+	assert.Equal(t, int64(-6000), change)
+	assert.Equal(t, int64(4000), credit)
+
+	// Tamamı ödenmiş tahakkuk
+	change, credit = CorrectionSplit(-2500, 0)
+	assert.Equal(t, int64(0), change)
+	assert.Equal(t, int64(2500), credit)
+}
+
+func TestParsePolicy(t *testing.T) {
+	assert.Equal(t, PrincipalFirst, ParsePolicy("PRINCIPAL_FIRST"))
+	assert.Equal(t, LateFeeFirst, ParsePolicy(""))
+	assert.Equal(t, LateFeeFirst, ParsePolicy("unknown"))
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/siteeksen/backend/services/finance/service"
+)
+
+// BudgetRequest işletme projesi oluşturma / güncelleme isteği
+type BudgetRequest struct {
+	FiscalYear int                 `json:"fiscal_year"`
+	StartMonth int                 `json:"start_month"` // Boşsa Ocak
+	Name       string              `json:"name"`
+	Notes      string              `json:"notes"`
+	Items      []models.BudgetItem `json:"items" binding:"required,min=1,dive"`
+}
+
+// BudgetDecisionRequest onay / red isteği
+type BudgetDecisionRequest struct {
+	Reference string `json:"approval_reference"` // Genel kurul karar no / tarihi
+	Reason    string `json:"reason"`             // Red gerekçesi
+}
+
+// ListBudgets sitenin işletme projeleri (?year=)
+func ListBudgets(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		year, _ := strconv.Atoi(c.DefaultQuery("year", "0"))
+
+		budgets, err := svc.ListBudgets(c.Request.Context(), c.GetString("property_id"), year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "İşletme projeleri alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, budgets)
+	}
+}
+
+// GetBudget işletme projesi detayı
+func GetBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget, err := svc.GetBudget(c.Request.Context(), c.GetString("property_id"), c.Param("id"))
+		respondBudget(c, budget, err, http.StatusOK)
+	}
+}
+
+// CreateBudget yeni işletme projesi taslağı oluşturur
+func CreateBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BudgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.FiscalYear == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		budget, err := svc.CreateBudget(c.Request.Context(), budgetInput(c, &req))
+		respondBudget(c, budget, err, http.StatusCreated)
+	}
+}
+
+// UpdateBudget taslak işletme projesini günceller
+func UpdateBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BudgetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		budget, err := svc.UpdateBudget(c.Request.Context(), c.Param("id"), budgetInput(c, &req))
+		respondBudget(c, budget, err, http.StatusOK)
+	}
+}
+
+// SubmitBudget taslak projeyi onaya sunar
+func SubmitBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget, err := svc.SubmitBudget(c.Request.Context(), c.GetString("property_id"), c.Param("id"))
+		respondBudget(c, budget, err, http.StatusOK)
+	}
+}
+
+// ApproveBudget projeyi genel kurul kararıyla onaylar
+func ApproveBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BudgetDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		budget, err := svc.ApproveBudget(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			c.GetString("user_id"), req.Reference)
+		respondBudget(c, budget, err, http.StatusOK)
+	}
+}
+
+// RejectBudget onaya sunulan projeyi gerekçesiyle taslağa döndürür
+func RejectBudget(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BudgetDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		budget, err := svc.RejectBudget(c.Request.Context(), c.GetString("property_id"), c.Param("id"), req.Reason)
+		respondBudget(c, budget, err, http.StatusOK)
+	}
+}
+
+// GetBudgetReport bütçe - gerçekleşen raporu (?as_of=YYYY-MM-DD&format=json|pdf|xlsx)
+func GetBudgetReport(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var asOf time.Time
+		if v := c.Query("as_of"); v != "" {
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih"})
+				return
+			}
+			asOf = parsed
+		}
+
+		report, err := svc.GetBudgetReport(c.Request.Context(), c.GetString("property_id"), c.Param("id"), asOf)
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "İşletme projesi bulunamadı"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Rapor hazırlanamadı"})
+			return
+		}
+
+		var data []byte
+		var contentType, ext string
+		switch c.DefaultQuery("format", "json") {
+		case "json":
+			c.JSON(http.StatusOK, report)
+			return
+		case "pdf":
+			data, err = svc.BudgetReportPDF(c.Request.Context(), report)
+			contentType, ext = "application/pdf", "pdf"
+		case "xlsx":
+			data, err = svc.BudgetReportExcel(report)
+			contentType, ext = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Desteklenmeyen format"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Rapor oluşturulamadı"})
+			return
+		}
+		filename := fmt.Sprintf("butce-%d-%s.%s", report.Budget.FiscalYear, report.AsOf.Format("2006-01-02"), ext)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, contentType, data)
+	}
+}
+
+func budgetInput(c *gin.Context, req *BudgetRequest) *service.BudgetInput {
+	return &service.BudgetInput{
+		PropertyID: c.GetString("property_id"),
+		FiscalYear: req.FiscalYear,
+		StartMonth: req.StartMonth,
+		Name:       req.Name,
+		Notes:      req.Notes,
+		Items:      req.Items,
+		UserID:     c.GetString("user_id"),
+	}
+}
+
+func respondBudget(c *gin.Context, budget *models.Budget, err error, status int) {
+	switch {
+	case errors.Is(err, repository.ErrBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "İşletme projesi bulunamadı"})
+	case errors.Is(err, repository.ErrBudgetStatusChanged), errors.Is(err, service.ErrBudgetNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(status, budget)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/siteeksen/backend/services/finance/service"
+)
+
+// AssessmentCorrectionRequest tahakkuk düzeltme isteği
+type AssessmentCorrectionRequest struct {
+	Amount            float64 `json:"amount" binding:"required"` // Pozitif artış, negatif indirim
+	ExpenseCategoryID string  `json:"expense_category_id"`       // Boşsa kalem detayı değişmez
+	Reason            string  `json:"reason" binding:"required"`
+}
+
+// CreditNoteRequest alacak dekontu isteği
+type CreditNoteRequest struct {
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	AssessmentID string  `json:"assessment_id"`
+	Reason       string  `json:"reason" binding:"required"`
+}
+
+// RedistributionRequest gider kalemi yeniden dağıtım isteği
+type RedistributionRequest struct {
+	PeriodYear  int     `json:"period_year" binding:"required"`
+	PeriodMonth int     `json:"period_month" binding:"required"`
+	CategoryID  string  `json:"category_id" binding:"required"`
+	Amount      float64 `json:"amount" binding:"gte=0"` // Kalemin yeni toplam tutarı
+	Reason      string  `json:"reason" binding:"required"`
+	DryRun      bool    `json:"dry_run"`
+}
+
+// CorrectAssessment tahakkuku gerekçeli olarak artırır / indirir
+func CorrectAssessment(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AssessmentCorrectionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		correction, err := svc.CorrectAssessment(c.Request.Context(), &service.AssessmentCorrectionInput{
+			PropertyID:        c.GetString("property_id"),
+			AssessmentID:      c.Param("id"),
+			ExpenseCategoryID: req.ExpenseCategoryID,
+			Amount:            req.Amount,
+			Reason:            req.Reason,
+			UserID:            c.GetString("user_id"),
+		})
+		respondCorrection(c, correction, err)
+	}
+}
+
+// IssueCreditNote daireye alacak dekontu düzenler
+func IssueCreditNote(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreditNoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		note, err := svc.IssueCreditNote(c.Request.Context(), &service.CreditNoteInput{
+			PropertyID:   c.GetString("property_id"),
+			UnitID:       c.Param("id"),
+			AssessmentID: req.AssessmentID,
+			Amount:       req.Amount,
+			Reason:       req.Reason,
+			UserID:       c.GetString("user_id"),
+		})
+		respondCorrection(c, note, err)
+	}
+}
+
+// RedistributeCategory gider kalemini dönem tahakkuklarına yeniden dağıtır
+func RedistributeCategory(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RedistributionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		result, err := svc.RedistributeCategory(c.Request.Context(), &service.RedistributionInput{
+			PropertyID:  c.GetString("property_id"),
+			PeriodYear:  req.PeriodYear,
+			PeriodMonth: req.PeriodMonth,
+			CategoryID:  req.CategoryID,
+			Amount:      req.Amount,
+			Reason:      req.Reason,
+			UserID:      c.GetString("user_id"),
+			DryRun:      req.DryRun,
+		})
+		if err == nil && result.DryRun {
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		respondCorrection(c, result, err)
+	}
+}
+
+// ListAssessmentCorrections düzeltme ve alacak dekontu geçmişi (?unit_id&type)
+func ListAssessmentCorrections(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		corrections, err := svc.ListAssessmentCorrections(c.Request.Context(), c.GetString("property_id"),
+			c.Query("unit_id"), c.Query("type"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Düzeltmeler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, corrections)
+	}
+}
+
+func respondCorrection(c *gin.Context, body any, err error) {
+	switch {
+	case errors.Is(err, repository.ErrAssessmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tahakkuk bulunamadı"})
+	case errors.Is(err, repository.ErrUnitNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Daire bulunamadı"})
+	case errors.Is(err, repository.ErrCorrectionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, body)
+	}
+}
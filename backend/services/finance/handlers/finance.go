@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
 	"github.com/siteeksen/backend/services/finance/service"
 )
 
@@ -42,25 +51,150 @@ func GetAssessments(svc *service.FinanceService) gin.HandlerFunc {
 func GetAssessmentDetails(svc *service.FinanceService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		assessmentID := c.Param("id")
+		managedPropertyID := ""
+		if claims.HasPermission(c.GetStringSlice("permissions"), "finance.ledger.read") {
+			managedPropertyID = c.GetString("property_id")
+		}
 
-		details, err := svc.GetAssessmentDetails(c.Request.Context(), assessmentID)
-		if err != nil {
+		details, err := svc.GetAssessmentDetails(c.Request.Context(), c.GetString("user_id"), managedPropertyID, assessmentID)
+		switch {
+		case errors.Is(err, database.ErrNoTenantScope):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Aktif site seçilmemiş"})
+			return
+		case errors.Is(err, repository.ErrAssessmentNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "Aidat bulunamadı"})
 			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Aidat alınamadı"})
+			return
 		}
 		c.JSON(http.StatusOK, details)
 	}
 }
 
+// GenerateAssessmentsRequest tahakkuk oluşturma isteği
+type GenerateAssessmentsRequest struct {
+	PeriodYear   int                  `json:"period_year" binding:"required"`
+	PeriodMonth  int                  `json:"period_month" binding:"required"`
+	DueDate      string               `json:"due_date"` // YYYY-MM-DD, boşsa site ayarı
+	ExpenseItems []models.ExpenseItem `json:"expense_items" binding:"omitempty,dive"` // Boşsa onaylı işletme projesi
+	DryRun       bool                 `json:"dry_run"`
+}
+
+// GenerateAssessments dönem tahakkuklarını oluşturur (yönetici)
+func GenerateAssessments(svc *service.FinanceService) gin.HandlerFunc {
+	return generateAssessments(svc, false)
+}
+
+// PreviewAssessments tahakkukları kaydetmeden önizler (yönetici)
+func PreviewAssessments(svc *service.FinanceService) gin.HandlerFunc {
+	return generateAssessments(svc, true)
+}
+
+func generateAssessments(svc *service.FinanceService, forceDryRun bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GenerateAssessmentsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		input := &service.GenerateAssessmentsInput{
+			PropertyID:   c.GetString("property_id"),
+			PeriodYear:   req.PeriodYear,
+			PeriodMonth:  req.PeriodMonth,
+			ExpenseItems: req.ExpenseItems,
+			DryRun:       req.DryRun || forceDryRun,
+		}
+		if req.DueDate != "" {
+			dueDate, err := time.Parse("2006-01-02", req.DueDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz son ödeme tarihi"})
+				return
+			}
+			input.DueDate = dueDate
+		}
+
+		result, err := svc.GenerateAssessments(c.Request.Context(), input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status := http.StatusCreated
+		if result.DryRun {
+			status = http.StatusOK
+		}
+		c.JSON(status, result)
+	}
+}
+
+// GetTrialBalance mizan (yönetici)
+func GetTrialBalance(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var asOf time.Time
+		if v := c.Query("as_of"); v != "" {
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih"})
+				return
+			}
+			asOf = parsed
+		}
+
+		tb, err := svc.GetTrialBalance(c.Request.Context(), c.GetString("property_id"), asOf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Mizan alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, tb)
+	}
+}
+
+// AccrueLateFees sitenin vadesi geçmiş aidatlarına gecikme tazminatı işletir (yönetici)
+func AccrueLateFees(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asOf := time.Now()
+		if v := c.Query("as_of"); v != "" {
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih"})
+				return
+			}
+			asOf = parsed
+		}
+
+		result, err := svc.AccrueLateFees(c.Request.Context(), c.GetString("property_id"), asOf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gecikme tazminatı işletilemedi"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 // CreatePaymentRequest ödeme isteği
 type CreatePaymentRequest struct {
-	AssessmentIDs []string `json:"assessment_ids" binding:"required"`
-	PaymentMethod string   `json:"payment_method" binding:"required"` // CREDIT_CARD, SAVED_CARD
-	CardToken     string   `json:"card_token"`
-	SaveCard      bool     `json:"save_card"`
+	AssessmentIDs []string     `json:"assessment_ids"`                    // Boşsa en eski borçtan başlanır
+	UnitID        string       `json:"unit_id"`                           // Seçim yoksa ödenecek daire
+	Amount        float64      `json:"amount"`                            // Boşsa borcun tamamı; kısmi veya fazla ödeme olabilir
+	PaymentMethod string       `json:"payment_method" binding:"required"` // CREDIT_CARD, SAVED_CARD
+	Card          *CardRequest `json:"card"`
+	CardToken     string       `json:"card_token"`
+	CardUserKey   string       `json:"card_user_key"`
+	SaveCard      bool         `json:"save_card"`
+}
+
+// CardRequest kart bilgileri (yalnızca iyzico'ya iletilir, saklanmaz)
+type CardRequest struct {
+	HolderName  string `json:"holder_name"`
+	Number      string `json:"number"`
+	ExpireMonth string `json:"expire_month"`
+	ExpireYear  string `json:"expire_year"`
+	Cvc         string `json:"cvc"`
 }
 
-// CreatePayment ödeme başlatır
+// CreatePayment 3DS ödeme başlatır
 func CreatePayment(svc *service.FinanceService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req CreatePaymentRequest
@@ -69,17 +203,184 @@ func CreatePayment(svc *service.FinanceService) gin.HandlerFunc {
 			return
 		}
 
-		userID := c.GetString("user_id")
-		result, err := svc.CreatePayment(c.Request.Context(), userID, req.AssessmentIDs, req.PaymentMethod, req.CardToken)
+		card := &payment.CardInput{Token: req.CardToken, UserKey: req.CardUserKey}
+		if req.Card != nil {
+			card.HolderName = req.Card.HolderName
+			card.Number = req.Card.Number
+			card.ExpireMonth = req.Card.ExpireMonth
+			card.ExpireYear = req.Card.ExpireYear
+			card.Cvc = req.Card.Cvc
+		}
+
+		result, err := svc.CreatePayment(c.Request.Context(), &service.CreatePaymentInput{
+			UserID:        c.GetString("user_id"),
+			UnitID:        req.UnitID,
+			AssessmentIDs: req.AssessmentIDs,
+			Amount:        req.Amount,
+			Method:        req.PaymentMethod,
+			Card:          card,
+			SaveCard:      req.SaveCard,
+			ClientIP:      c.ClientIP(),
+		})
+		if errors.Is(err, database.ErrNoTenantScope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Aktif site seçilmemiş"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
+		status := http.StatusOK
+		if result.Status == "FAILED" {
+			status = http.StatusPaymentRequired
+		}
+		c.JSON(status, result)
+	}
+}
+
+// PaymentCallback iyzico 3DS callback'i (banka sayfasından form POST ile gelir, JWT yoktur).
+// PAYMENT_RESULT_URL tanımlıysa kullanıcı sonuç sayfasına yönlendirilir.
+func PaymentCallback(svc *service.FinanceService, resultURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var cb models.PaymentCallback
+		if err := c.ShouldBind(&cb); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz callback"})
+			return
+		}
+
+		result, err := svc.HandlePaymentCallback(c.Request.Context(), &cb)
+		if errors.Is(err, repository.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ödeme bulunamadı"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if resultURL != "" {
+			q := url.Values{"payment_id": {result.PaymentID}, "status": {result.Status}}
+			c.Redirect(http.StatusSeeOther, resultURL+"?"+q.Encode())
+			return
+		}
 		c.JSON(http.StatusOK, result)
 	}
 }
 
+// RefundPayment tamamlanmış ya da tutar uyuşmazlığıyla incelemedeki ödemeyi iade eder ve
+// tahakkuklara etkisini geri alır (yönetici)
+func RefundPayment(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refund, err := svc.RefundPayment(c.Request.Context(), c.GetString("property_id"), c.Param("id"), c.GetString("user_id"))
+		if errors.Is(err, repository.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ödeme bulunamadı"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, refund)
+	}
+}
+
+// ListMismatchedPayments iadesi yapılamamış, incelemede bekleyen tutar uyuşmazlığı ödemeleri (yönetici)
+func ListMismatchedPayments(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payments, err := svc.ListMismatchedPayments(c.Request.Context(), c.GetString("property_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ödemeler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, payments)
+	}
+}
+
+// GetStatement sakinin daire hesap ekstresi (?unit_id=&from=&to=)
+func GetStatement(svc *service.FinanceService) gin.HandlerFunc {
+	return statementHandler(svc, false)
+}
+
+// GetStatementPDF sakinin daire hesap ekstresi (PDF)
+func GetStatementPDF(svc *service.FinanceService) gin.HandlerFunc {
+	return statementHandler(svc, true)
+}
+
+// GetUnitStatement sitedeki bir dairenin hesap ekstresi (yönetici)
+func GetUnitStatement(svc *service.FinanceService) gin.HandlerFunc {
+	return unitStatementHandler(svc, false)
+}
+
+// GetUnitStatementPDF sitedeki bir dairenin hesap ekstresi, PDF (yönetici)
+func GetUnitStatementPDF(svc *service.FinanceService) gin.HandlerFunc {
+	return unitStatementHandler(svc, true)
+}
+
+func statementHandler(svc *service.FinanceService, asPDF bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := parseDateRange(c)
+		if !ok {
+			return
+		}
+		st, err := svc.GetResidentStatement(c.Request.Context(), c.GetString("user_id"), c.Query("unit_id"), from, to)
+		respondStatement(c, svc, st, err, asPDF)
+	}
+}
+
+func unitStatementHandler(svc *service.FinanceService, asPDF bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := parseDateRange(c)
+		if !ok {
+			return
+		}
+		st, err := svc.GetUnitStatement(c.Request.Context(), c.GetString("property_id"), c.Param("id"), from, to)
+		respondStatement(c, svc, st, err, asPDF)
+	}
+}
+
+func respondStatement(c *gin.Context, svc *service.FinanceService, st *service.UnitStatement, err error, asPDF bool) {
+	if errors.Is(err, repository.ErrUnitNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Daire bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !asPDF {
+		c.JSON(http.StatusOK, st)
+		return
+	}
+
+	pdf, err := svc.StatementPDF(c.Request.Context(), st)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ekstre oluşturulamadı"})
+		return
+	}
+	filename := fmt.Sprintf("ekstre-%s-%s.pdf", st.UnitName, st.To.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// parseDateRange from/to (YYYY-MM-DD) sorgu parametrelerini okur; hatalıysa 400 döner
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var dates [2]time.Time
+	for i, key := range []string{"from", "to"} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih"})
+			return time.Time{}, time.Time{}, false
+		}
+		dates[i] = parsed
+	}
+	return dates[0], dates[1], true
+}
+
 // GetPaymentHistory ödeme geçmişi
 func GetPaymentHistory(svc *service.FinanceService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1,4 +1,4 @@
-package finance_test
+package handlers_test
 
 import (
 	"bytes"
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/finance/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -164,7 +165,7 @@ func TestLateFeeCalculation(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			lateFee := calculateLateFee(tc.amount, tc.daysOverdue, tc.feePercentage)
+			lateFee := service.CalculateLateFee(tc.amount, tc.daysOverdue, tc.feePercentage, service.LateFeeMonthly)
 			assert.Equal(t, tc.expectedLateFee, lateFee)
 		})
 	}
@@ -277,11 +278,3 @@ func setupFinanceRouter() *gin.Engine {
 
 	return r
 }
-
-func calculateLateFee(amount float64, daysOverdue int, feePercentage float64) float64 {
-	if daysOverdue <= 0 {
-		return 0
-	}
-	// Aylık faiz, günlük bölünmez
-	return amount * feePercentage
-}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/siteeksen/backend/services/finance/service"
+)
+
+// maxMeterReadingFileSize yüklenen okuma listesinin en büyük boyutu
+const maxMeterReadingFileSize = 10 << 20
+
+// MeterReadingApprovalRequest incelemedeki okumanın onayı
+type MeterReadingApprovalRequest struct {
+	Value         *float64 `json:"value"`          // Boşsa listedeki okuma
+	MeterReplaced bool     `json:"meter_replaced"` // Okuma yeni sayacın başlangıç değeri
+	Note          string   `json:"note"`
+}
+
+// MeterReadingRejectionRequest incelemedeki okumanın reddi
+type MeterReadingRejectionRequest struct {
+	Note string `json:"note"`
+}
+
+// GetMeterReadingTemplate sayaç okuma şablonu (?type=HEAT; boşsa tüm sayaçlar)
+func GetMeterReadingTemplate(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := svc.GetMeterReadingTemplate(c.Request.Context(), c.GetString("property_id"), c.Query("type"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Okuma şablonu oluşturulamadı"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="sayac_okuma.xlsx"`)
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+	}
+}
+
+// UploadMeterReadings doldurulmuş okuma şablonunu ("file" alanında Excel ya da CSV) yükler.
+// reading_date (YYYY-MM-DD) boşsa bugün. Doğrulamadan geçmeyen okumalar incelemeye alınır;
+// her satırın sonucu raporda döner.
+func UploadMeterReadings(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var readingDate time.Time
+		if v := c.PostForm("reading_date"); v != "" {
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz okuma tarihi"})
+				return
+			}
+			readingDate = parsed
+		}
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Yüklenecek dosya gerekli"})
+			return
+		}
+		if header.Size > maxMeterReadingFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Dosya en fazla 10 MB olabilir"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya okunamadı"})
+			return
+		}
+		defer file.Close()
+
+		upload, err := svc.UploadMeterReadings(c.Request.Context(), &service.MeterReadingUploadInput{
+			PropertyID:  c.GetString("property_id"),
+			UserID:      c.GetString("user_id"),
+			ReadingDate: readingDate,
+			File:        file,
+		})
+		switch {
+		case err == nil:
+			c.JSON(http.StatusOK, upload)
+		case errors.Is(err, importer.ErrInvalidFile), errors.Is(err, service.ErrMeterReadingsEmpty),
+			errors.Is(err, service.ErrFutureReadingDate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Sayaç okumaları yüklenemedi: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Okumalar yüklenemedi"})
+		}
+	}
+}
+
+// ListMeterReadingReviews okuma incelemeleri (?status=PENDING)
+func ListMeterReadingReviews(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviews, err := svc.ListMeterReadingReviews(c.Request.Context(), c.GetString("property_id"), c.Query("status"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Okuma incelemeleri alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, reviews)
+	}
+}
+
+// ApproveMeterReadingReview incelemedeki okumayı (gerekirse düzeltilmiş değerle) kaydeder
+func ApproveMeterReadingReview(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MeterReadingApprovalRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		review, err := svc.ApproveMeterReadingReview(c.Request.Context(), &service.MeterReadingApprovalInput{
+			PropertyID:    c.GetString("property_id"),
+			ReviewID:      c.Param("id"),
+			UserID:        c.GetString("user_id"),
+			Value:         req.Value,
+			MeterReplaced: req.MeterReplaced,
+			Note:          req.Note,
+		})
+		respondMeterReadingReview(c, review, err)
+	}
+}
+
+// RejectMeterReadingReview incelemedeki okumayı reddeder
+func RejectMeterReadingReview(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MeterReadingRejectionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		review, err := svc.RejectMeterReadingReview(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			c.GetString("user_id"), req.Note)
+		respondMeterReadingReview(c, review, err)
+	}
+}
+
+func respondMeterReadingReview(c *gin.Context, body any, err error) {
+	switch {
+	case errors.Is(err, repository.ErrMeterReadingReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Okuma incelemesi bulunamadı"})
+	case errors.Is(err, repository.ErrMeterReadingReviewResolved), errors.Is(err, repository.ErrMeterReadingConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, body)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/siteeksen/backend/services/finance/service"
+)
+
+// PaymentPlanRequest ödeme planı oluşturma isteği
+type PaymentPlanRequest struct {
+	UnitID           string   `json:"unit_id" binding:"required"`
+	AssessmentIDs    []string `json:"assessment_ids" binding:"required,min=1"`
+	InstallmentCount int      `json:"installment_count" binding:"required"`
+	FirstDueDate     string   `json:"first_due_date" binding:"required"` // YYYY-MM-DD, sonraki taksitler aylık
+	WaiveLateFee     float64  `json:"waive_late_fee"`                    // Plan sürdükçe affedilecek tazminat
+	GraceDays        int      `json:"grace_days"`
+	Notes            string   `json:"notes"`
+}
+
+// PaymentPlanCancelRequest plan iptal isteği
+type PaymentPlanCancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListPaymentPlans sitenin ödeme planları (?unit_id&status)
+func ListPaymentPlans(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plans, err := svc.ListPaymentPlans(c.Request.Context(), c.GetString("property_id"), c.Query("unit_id"), c.Query("status"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ödeme planları alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, plans)
+	}
+}
+
+// GetPaymentPlan ödeme planı detayı
+func GetPaymentPlan(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plan, err := svc.GetPaymentPlan(c.Request.Context(), c.GetString("property_id"), c.Param("id"))
+		respondPaymentPlan(c, plan, err, http.StatusOK)
+	}
+}
+
+// CreatePaymentPlan dairenin vadesi geçmiş tahakkuklarını taksitlendirir
+func CreatePaymentPlan(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PaymentPlanRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+		firstDue, err := time.Parse("2006-01-02", req.FirstDueDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz ilk taksit tarihi"})
+			return
+		}
+
+		plan, err := svc.CreatePaymentPlan(c.Request.Context(), &service.PaymentPlanInput{
+			PropertyID:       c.GetString("property_id"),
+			UnitID:           req.UnitID,
+			AssessmentIDs:    req.AssessmentIDs,
+			InstallmentCount: req.InstallmentCount,
+			FirstDueDate:     firstDue,
+			WaiveLateFee:     req.WaiveLateFee,
+			GraceDays:        req.GraceDays,
+			Notes:            req.Notes,
+			UserID:           c.GetString("user_id"),
+		})
+		respondPaymentPlan(c, plan, err, http.StatusCreated)
+	}
+}
+
+// CancelPaymentPlan aktif planı iptal eder
+func CancelPaymentPlan(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PaymentPlanCancelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		plan, err := svc.CancelPaymentPlan(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			req.Reason, c.GetString("user_id"))
+		respondPaymentPlan(c, plan, err, http.StatusOK)
+	}
+}
+
+// EvaluatePaymentPlans sitenin aktif planlarını ödemelere göre günceller (yönetici)
+func EvaluatePaymentPlans(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := svc.EvaluatePaymentPlans(c.Request.Context(), c.GetString("property_id"), time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ödeme planları değerlendirilemedi"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func respondPaymentPlan(c *gin.Context, plan *models.PaymentPlan, err error, status int) {
+	switch {
+	case errors.Is(err, repository.ErrPaymentPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ödeme planı bulunamadı"})
+	case errors.Is(err, repository.ErrPlanAssessmentsChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(status, plan)
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/siteeksen/backend/services/finance/service"
+)
+
+// ReserveFundRequest fon tanımlama / güncelleme isteği
+type ReserveFundRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	Description          string   `json:"description"`
+	MonthlyAmount        float64  `json:"monthly_amount" binding:"gte=0"`
+	DistributionType     string   `json:"distribution_type"` // Boşsa SHARE_RATIO
+	AppliesToCommercial  *bool    `json:"applies_to_commercial"`
+	AppliesToGroundFloor *bool    `json:"applies_to_ground_floor"`
+	StartsOn             string   `json:"starts_on" binding:"required"` // YYYY-MM-DD, ilk katkı dönemi
+	EndsOn               string   `json:"ends_on"`                      // Boşsa süresiz
+	TargetAmount         *float64 `json:"target_amount"`
+	IsActive             *bool    `json:"is_active"`
+}
+
+// WithdrawalRequest fondan harcama talebi
+type WithdrawalRequest struct {
+	Amount            float64 `json:"amount" binding:"required,gt=0"`
+	Description       string  `json:"description" binding:"required"`
+	DecisionReference string  `json:"decision_reference" binding:"required"` // Karar no
+	DecisionDate      string  `json:"decision_date" binding:"required"`      // YYYY-MM-DD
+	CashAccount       string  `json:"cash_account"`                          // 100 / 102, boşsa 102
+}
+
+// WithdrawalDecisionRequest red isteği
+type WithdrawalDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListReserveFunds sitenin fonları
+func ListReserveFunds(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		funds, err := svc.ListReserveFunds(c.Request.Context(), c.GetString("property_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Fonlar alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, funds)
+	}
+}
+
+// CreateReserveFund yeni fon tanımlar
+func CreateReserveFund(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fund, ok := bindReserveFund(c)
+		if !ok {
+			return
+		}
+
+		fund, err := svc.CreateReserveFund(c.Request.Context(), fund, c.GetString("user_id"))
+		respondReserve(c, fund, err, http.StatusCreated)
+	}
+}
+
+// UpdateReserveFund fon tanımını ve katkı planını günceller
+func UpdateReserveFund(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fund, ok := bindReserveFund(c)
+		if !ok {
+			return
+		}
+		fund.ID = c.Param("id")
+
+		fund, err := svc.UpdateReserveFund(c.Request.Context(), fund)
+		respondReserve(c, fund, err, http.StatusOK)
+	}
+}
+
+// GetReserveFundBalances fon bakiyeleri (?as_of=YYYY-MM-DD)
+func GetReserveFundBalances(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var asOf time.Time
+		if v := c.Query("as_of"); v != "" {
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih"})
+				return
+			}
+			asOf = parsed
+		}
+
+		balances, err := svc.GetReserveFundBalances(c.Request.Context(), c.GetString("property_id"), asOf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Fon bakiyeleri alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, balances)
+	}
+}
+
+// ListReserveWithdrawals fon harcama talepleri (?fund_id&status)
+func ListReserveWithdrawals(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		withdrawals, err := svc.ListReserveWithdrawals(c.Request.Context(), c.GetString("property_id"),
+			c.Query("fund_id"), c.Query("status"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Harcama talepleri alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, withdrawals)
+	}
+}
+
+// RequestReserveWithdrawal fondan karar referanslı harcama talebi açar
+func RequestReserveWithdrawal(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WithdrawalRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+		decisionDate, err := time.Parse("2006-01-02", req.DecisionDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz karar tarihi"})
+			return
+		}
+
+		w, err := svc.RequestReserveWithdrawal(c.Request.Context(), c.GetString("property_id"), &models.ReserveWithdrawal{
+			FundID:            c.Param("id"),
+			Amount:            req.Amount,
+			Description:       req.Description,
+			DecisionReference: req.DecisionReference,
+			DecisionDate:      decisionDate,
+			CashAccount:       req.CashAccount,
+			RequestedBy:       c.GetString("user_id"),
+		})
+		respondReserve(c, w, err, http.StatusCreated)
+	}
+}
+
+// ApproveReserveWithdrawal talebi onaylar ve harcamayı defterler
+func ApproveReserveWithdrawal(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w, err := svc.ApproveReserveWithdrawal(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			c.GetString("user_id"))
+		respondReserve(c, w, err, http.StatusOK)
+	}
+}
+
+// RejectReserveWithdrawal talebi gerekçesiyle reddeder
+func RejectReserveWithdrawal(svc *service.FinanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WithdrawalDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		w, err := svc.RejectReserveWithdrawal(c.Request.Context(), c.GetString("property_id"), c.Param("id"), req.Reason)
+		respondReserve(c, w, err, http.StatusOK)
+	}
+}
+
+func bindReserveFund(c *gin.Context) (*models.ReserveFund, bool) {
+	var req ReserveFundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+		return nil, false
+	}
+	startsOn, err := time.Parse("2006-01-02", req.StartsOn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz başlangıç tarihi"})
+		return nil, false
+	}
+	var endsOn *time.Time
+	if req.EndsOn != "" {
+		parsed, err := time.Parse("2006-01-02", req.EndsOn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz bitiş tarihi"})
+			return nil, false
+		}
+		endsOn = &parsed
+	}
+
+	return &models.ReserveFund{
+		PropertyID:           c.GetString("property_id"),
+		Name:                 req.Name,
+		Description:          req.Description,
+		MonthlyAmount:        req.MonthlyAmount,
+		DistributionType:     req.DistributionType,
+		AppliesToCommercial:  boolOr(req.AppliesToCommercial, true),
+		AppliesToGroundFloor: boolOr(req.AppliesToGroundFloor, true),
+		StartsOn:             startsOn,
+		EndsOn:               endsOn,
+		TargetAmount:         req.TargetAmount,
+		IsActive:             boolOr(req.IsActive, true),
+	}, true
+}
+
+func boolOr(v *bool, def bool) bool {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+func respondReserve(c *gin.Context, body any, err error, status int) {
+	switch {
+	case errors.Is(err, repository.ErrReserveFundNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fon bulunamadı"})
+	case errors.Is(err, repository.ErrWithdrawalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Harcama talebi bulunamadı"})
+	case errors.Is(err, repository.ErrWithdrawalStatusChanged), errors.Is(err, repository.ErrInsufficientReserve):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(status, body)
+	}
+}
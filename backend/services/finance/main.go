@@ -3,10 +3,15 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/encryption"
 	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/server"
+	"github.com/siteeksen/backend/pkg/tenant"
 	"github.com/siteeksen/backend/services/finance/handlers"
 	"github.com/siteeksen/backend/services/finance/repository"
 	"github.com/siteeksen/backend/services/finance/service"
@@ -21,9 +26,31 @@ func main() {
 	}
 	defer database.Close()
 
+	// Ödeme yapanın telefonu kimlik servisiyle aynı anahtarlarla şifreli
+	pii, err := encryption.NewPIICipherFromEnv()
+	if err != nil {
+		log.Fatalf("Kişisel veri şifreleme anahtarları yüklenemedi: %v", err)
+	}
+
+	// Kapanış sinyalinde sunucu ve arka plan işleri durdurulur
+	ctx, stop := server.Context()
+	defer stop()
+
+	// Kiracı askıya alma / yeniden etkinleştirme LISTEN/NOTIFY ile önbellekten düşer
+	go tenant.GetManager().Listen(ctx, pool)
+
 	// Repository ve Service
-	financeRepo := repository.NewFinanceRepository(pool)
-	financeService := service.NewFinanceService(financeRepo)
+	financeRepo := repository.NewFinanceRepository(pool, pii)
+	callbackURL := os.Getenv("PAYMENT_CALLBACK_URL")
+	if callbackURL == "" {
+		callbackURL = "http://localhost:8082/api/v1/finance/payments/callback"
+	}
+	financeService := service.NewFinanceService(financeRepo, payment.NewPaymentService(), callbackURL)
+
+	// Gecikme tazminatı (KMK md. 20) günlük işletme
+	if os.Getenv("LATE_FEE_JOB_DISABLED") != "true" {
+		go financeService.RunLateFeeScheduler(ctx, 24*time.Hour)
+	}
 
 	// Gin router
 	r := gin.Default()
@@ -33,9 +60,12 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok", "service": "finance"})
 	})
 
-	// Protected routes
+	// iyzico 3DS callback (banka yönlendirmesi, JWT yok)
+	r.POST("/api/v1/finance/payments/callback", handlers.PaymentCallback(financeService, os.Getenv("PAYMENT_RESULT_URL")))
+
+	// Protected routes; sakin sorguları aktif sitenin kiracısıyla (RLS) çalışır
 	api := r.Group("/api/v1/finance")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(), tenant.DatabaseScope())
 	{
 		// Borç durumu
 		api.GET("/debt-status", handlers.GetDebtStatus(financeService))
@@ -43,10 +73,67 @@ func main() {
 		// Aidatlar
 		api.GET("/assessments", handlers.GetAssessments(financeService))
 		api.GET("/assessments/:id", handlers.GetAssessmentDetails(financeService))
+
+		// Tahakkuk oluşturma ve mizan (yönetim); her işlem aktif sitedeki yetkiyle açılır
+		management := api.Group("")
+		{
+			management.POST("/assessments", middleware.RequirePermission("finance.assessment.create"), handlers.GenerateAssessments(financeService))
+			management.POST("/assessments/preview", middleware.RequirePermission("finance.assessment.create"), handlers.PreviewAssessments(financeService))
+			management.GET("/ledger/trial-balance", middleware.RequirePermission("finance.ledger.read"), handlers.GetTrialBalance(financeService))
+			management.POST("/late-fees/accrue", middleware.RequirePermission("finance.late_fee.accrue"), handlers.AccrueLateFees(financeService))
+			management.POST("/payments/:id/refund", middleware.RequirePermission("finance.payment.refund"), middleware.RequireStepUp(), handlers.RefundPayment(financeService))
+			management.GET("/payments/mismatches", middleware.RequirePermission("finance.payment.refund"), handlers.ListMismatchedPayments(financeService))
+			management.GET("/units/:id/statement", middleware.RequirePermission("finance.ledger.read"), handlers.GetUnitStatement(financeService))
+			management.GET("/units/:id/statement/pdf", middleware.RequirePermission("finance.ledger.read"), handlers.GetUnitStatementPDF(financeService))
+
+			// İşletme projesi (bütçe)
+			management.GET("/budgets", middleware.RequirePermission("finance.budget.manage"), handlers.ListBudgets(financeService))
+			management.POST("/budgets", middleware.RequirePermission("finance.budget.manage"), handlers.CreateBudget(financeService))
+			management.GET("/budgets/:id", middleware.RequirePermission("finance.budget.manage"), handlers.GetBudget(financeService))
+			management.PUT("/budgets/:id", middleware.RequirePermission("finance.budget.manage"), handlers.UpdateBudget(financeService))
+			management.POST("/budgets/:id/submit", middleware.RequirePermission("finance.budget.manage"), handlers.SubmitBudget(financeService))
+			management.POST("/budgets/:id/approve", middleware.RequirePermission("finance.budget.approve"), handlers.ApproveBudget(financeService))
+			management.POST("/budgets/:id/reject", middleware.RequirePermission("finance.budget.approve"), handlers.RejectBudget(financeService))
+			management.GET("/budgets/:id/report", middleware.RequirePermission("finance.budget.manage"), handlers.GetBudgetReport(financeService))
+
+			// Demirbaş / yedek akçe fonları
+			management.GET("/reserve-funds", middleware.RequirePermission("finance.reserve.manage"), handlers.ListReserveFunds(financeService))
+			management.POST("/reserve-funds", middleware.RequirePermission("finance.reserve.manage"), handlers.CreateReserveFund(financeService))
+			management.PUT("/reserve-funds/:id", middleware.RequirePermission("finance.reserve.manage"), handlers.UpdateReserveFund(financeService))
+			management.POST("/reserve-funds/:id/withdrawals", middleware.RequirePermission("finance.reserve.manage"), handlers.RequestReserveWithdrawal(financeService))
+			management.GET("/reserve-fund-balances", middleware.RequirePermission("finance.reserve.manage"), handlers.GetReserveFundBalances(financeService))
+			management.GET("/reserve-withdrawals", middleware.RequirePermission("finance.reserve.manage"), handlers.ListReserveWithdrawals(financeService))
+			management.POST("/reserve-withdrawals/:id/approve", middleware.RequirePermission("finance.reserve.approve"), handlers.ApproveReserveWithdrawal(financeService))
+			management.POST("/reserve-withdrawals/:id/reject", middleware.RequirePermission("finance.reserve.approve"), handlers.RejectReserveWithdrawal(financeService))
+
+			// Ödeme planları (taksitlendirme)
+			management.GET("/payment-plans", middleware.RequirePermission("finance.payment_plan.manage"), handlers.ListPaymentPlans(financeService))
+			management.POST("/payment-plans", middleware.RequirePermission("finance.payment_plan.manage"), handlers.CreatePaymentPlan(financeService))
+			management.POST("/payment-plans/evaluate", middleware.RequirePermission("finance.payment_plan.manage"), handlers.EvaluatePaymentPlans(financeService))
+			management.GET("/payment-plans/:id", middleware.RequirePermission("finance.payment_plan.manage"), handlers.GetPaymentPlan(financeService))
+			management.POST("/payment-plans/:id/cancel", middleware.RequirePermission("finance.payment_plan.manage"), handlers.CancelPaymentPlan(financeService))
+
+			// Tahakkuk düzeltmeleri ve alacak dekontları
+			management.POST("/assessments/:id/corrections", middleware.RequirePermission("finance.assessment.correct"), handlers.CorrectAssessment(financeService))
+			management.POST("/assessments/redistribute", middleware.RequirePermission("finance.assessment.correct"), handlers.RedistributeCategory(financeService))
+			management.POST("/units/:id/credit-notes", middleware.RequirePermission("finance.assessment.correct"), handlers.IssueCreditNote(financeService))
+			management.GET("/assessment-corrections", middleware.RequirePermission("finance.assessment.correct"), handlers.ListAssessmentCorrections(financeService))
+
+			// Toplu sayaç okuma ve şüpheli okumaların incelenmesi
+			management.GET("/meter-readings/template", middleware.RequirePermission("finance.meter_reading.manage"), handlers.GetMeterReadingTemplate(financeService))
+			management.POST("/meter-readings/upload", middleware.RequirePermission("finance.meter_reading.manage"), handlers.UploadMeterReadings(financeService))
+			management.GET("/meter-reading-reviews", middleware.RequirePermission("finance.meter_reading.manage"), handlers.ListMeterReadingReviews(financeService))
+			management.POST("/meter-reading-reviews/:id/approve", middleware.RequirePermission("finance.meter_reading.manage"), handlers.ApproveMeterReadingReview(financeService))
+			management.POST("/meter-reading-reviews/:id/reject", middleware.RequirePermission("finance.meter_reading.manage"), handlers.RejectMeterReadingReview(financeService))
+		}
 		
 		// Ödemeler
-		api.POST("/payments", handlers.CreatePayment(financeService))
+		api.POST("/payments", middleware.RequirePermission("finance.payment.create"), handlers.CreatePayment(financeService))
 		api.GET("/payments", handlers.GetPaymentHistory(financeService))
+
+		// Hesap ekstresi
+		api.GET("/statement", handlers.GetStatement(financeService))
+		api.GET("/statement/pdf", handlers.GetStatementPDF(financeService))
 		
 		// Tüketim
 		api.GET("/consumption/summary", handlers.GetConsumptionSummary(financeService))
@@ -58,5 +145,7 @@ func main() {
 		port = "8082"
 	}
 	log.Printf("Finance Service başlatıldı: :%s", port)
-	r.Run(":" + port)
+	if err := server.Run(ctx, ":"+port, r); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,191 @@
+// Package metering toplu sayaç okuma listesinin okunmasını ve her okumanın sayacın önceki
+// okumalarına göre doğrulanmasını içerir; paket veritabanına erişmez.
+package metering
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/importer"
+)
+
+// Satır sonuçları
+const (
+	StatusRecorded  = "RECORDED"  // Okuma kaydedildi
+	StatusUnchanged = "UNCHANGED" // Aynı okuma daha önce kaydedilmiş
+	StatusQueued    = "QUEUED"    // Doğrulamadan geçmedi, yönetici incelemesine alındı
+	StatusSkipped   = "SKIPPED"   // Yeni okuma girilmemiş
+	StatusFailed    = "FAILED"    // Sayaç bulunamadı veya hücre okunamadı
+)
+
+// İnceleme gerekçeleri
+const (
+	ReasonRollback = "ROLLBACK" // Yeni okuma öncekinden küçük (sayaç geri gitmiş veya değişmiş)
+	ReasonJump     = "JUMP"     // Tüketim sayacın olağan tüketiminin çok üstünde
+)
+
+const (
+	// JumpFactor tüketim, önceki okumalardan beklenenin bu katını aşarsa okuma incelemeye alınır
+	JumpFactor = 3
+	// MinHistory sıçrama denetimi için gereken en az geçmiş tüketim sayısı
+	MinHistory = 3
+	// HistorySize beklenen tüketimin hesaplandığı son okuma sayısı
+	HistorySize = 6
+)
+
+// SheetColumns reports.GenerateMeterReadingTemplate şablonunun sütunları. Sayaç seri
+// numarasıyla eşleştirilir; daire ve sakin sütunları yalnızca görevli içindir.
+var SheetColumns = []importer.Column{
+	{Field: "unit", Header: "Daire"},
+	{Field: "resident", Header: "Sakin"},
+	{Field: "serial", Header: "Sayaç No", Aliases: []string{"Seri No", "Sayaç Seri No"}, Required: true},
+	{Field: "previous", Header: "Önceki Okuma"},
+	{Field: "value", Header: "Yeni Okuma (Giriniz)", Aliases: []string{"Yeni Okuma", "Okuma"}, Required: true},
+}
+
+// Row listenin bir satırı. Value nil ise okuma girilmemiştir; Error doluysa satır okunamamıştır.
+type Row struct {
+	Num          int
+	Unit         string
+	SerialNumber string
+	Value        *float64
+	Error        string
+}
+
+// ReadSheet doldurulmuş okuma listesini (Excel veya CSV) okur. Okumalar sayaç
+// hassasiyetine (üç ondalık) yuvarlanır; aynı sayacın tekrarı satır hatasıdır.
+func ReadSheet(r io.Reader) ([]Row, error) {
+	table, err := importer.ReadTable(r, SheetColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(table.Rows))
+	seen := map[string]int{}
+	for _, tr := range table.Rows {
+		row := Row{
+			Num:          tr.Num,
+			Unit:         table.Value(tr, "unit"),
+			SerialNumber: table.Value(tr, "serial"),
+		}
+		key := strings.ToUpper(row.SerialNumber)
+		cell := table.Value(tr, "value")
+		value, ok := importer.ParseDecimal(cell)
+		switch {
+		case row.SerialNumber == "":
+			row.Error = "sayaç numarası zorunlu"
+		case seen[key] != 0:
+			row.Error = fmt.Sprintf("aynı sayaç %d. satırda da var", seen[key])
+		case cell == "":
+		case !ok:
+			row.Error = fmt.Sprintf("okuma sayı olmalı: %q", cell)
+		case value < 0:
+			row.Error = "okuma negatif olamaz"
+		default:
+			value = Round(value)
+			row.Value = &value
+		}
+		if row.SerialNumber != "" && seen[key] == 0 {
+			seen[key] = tr.Num
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Previous sayacın son okuması
+type Previous struct {
+	Date  time.Time
+	Value float64
+}
+
+// Usage geçmiş okumanın tüketimi ve kapsadığı gün sayısı
+type Usage struct {
+	Consumption float64
+	Days        int
+}
+
+// Issue okumanın incelemeye alınma gerekçesi
+type Issue struct {
+	Reason  string
+	Message string
+}
+
+// Check date tarihli yeni okumayı sayacın son okumasına ve geçmiş tüketimine göre doğrular;
+// okuma kabul edilebilirse nil döner. Önceki okumadan küçük okuma geri gitmiş sayılır.
+// Tüketim, geçmiş okumaların günlük ortalamasıyla aradaki gün sayısından beklenen
+// tüketimin JumpFactor katını aşarsa sıçrama sayılır. Denetim için en az MinHistory
+// pozitif tüketim gerekir; ilk okumalar ve sıfır tüketimli dönemler (boş daire, sayaç
+// başlangıç değeri) ortalamaya girmez.
+func Check(prev Previous, history []Usage, value float64, date time.Time) *Issue {
+	if value < prev.Value {
+		return &Issue{
+			Reason:  ReasonRollback,
+			Message: fmt.Sprintf("yeni okuma (%s) önceki okumadan (%s) küçük", Format(value), Format(prev.Value)),
+		}
+	}
+
+	var total float64
+	var days, count int
+	for _, h := range history {
+		if h.Consumption > 0 && h.Days > 0 {
+			total += h.Consumption
+			days += h.Days
+			count++
+		}
+	}
+	elapsed := int(date.Sub(prev.Date).Hours() / 24)
+	if count < MinHistory || elapsed <= 0 {
+		return nil
+	}
+	consumption := Round(value - prev.Value)
+	expected := Round(total / float64(days) * float64(elapsed))
+	if consumption > expected*JumpFactor {
+		return &Issue{
+			Reason: ReasonJump,
+			Message: fmt.Sprintf("tüketim (%s) önceki okumalara göre beklenen tüketimin (%s) %d katından fazla",
+				Format(consumption), Format(expected), JumpFactor),
+		}
+	}
+	return nil
+}
+
+// Tariff sayaç türünün okuma tarihinde geçerli tüketim tarifesi (consumption_tariffs)
+type Tariff struct {
+	ID        string
+	UnitPrice float64 // Birim fiyat
+	FixedFee  float64 // Sabit bedel
+	TaxRate   float64 // KDV oranı (yüzde)
+}
+
+// Charge tüketim faturasının tutarları (kuruşa yuvarlanmış)
+type Charge struct {
+	Subtotal float64
+	Tax      float64
+	Total    float64
+}
+
+// Bill tüketimin tarifeye göre bedelini hesaplar: tüketim × birim fiyat + sabit bedel, üzerine KDV
+func Bill(consumption float64, t Tariff) Charge {
+	subtotal := roundKurus(consumption*t.UnitPrice + t.FixedFee)
+	tax := roundKurus(subtotal * t.TaxRate / 100)
+	return Charge{Subtotal: subtotal, Tax: tax, Total: roundKurus(subtotal + tax)}
+}
+
+func roundKurus(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Round değeri sayaç hassasiyetine (üç ondalık) yuvarlar
+func Round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// Format okuma değerini mesajlar için yazar: 1520.5 → "1520,5"
+func Format(v float64) string {
+	return strings.Replace(strconv.FormatFloat(Round(v), 'f', -1, 64), ".", ",", 1)
+}
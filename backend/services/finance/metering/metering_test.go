@@ -0,0 +1,92 @@
+package metering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadSheet_Template(t *testing.T) {
+	data, err := reports.NewExcelGenerator().GenerateMeterReadingTemplate([]reports.MeterTemplateUnit{
+		{UnitName: "A-1", ResidentName: "Ali Demir", MeterSerial: "HM-001", LastReading: 1520.5},
+		{UnitName: "A-2", ResidentName: "Ayşe Kaya", MeterSerial: "HM-002", LastReading: 980},
+		{UnitName: "A-3", MeterSerial: "HM-003", LastReading: 40},
+	})
+	require.NoError(t, err)
+
+	// Görevli yeni okumaları E sütununa girer; A-3 okunmamış
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellValue("Sayaç Okuma", "E2", 1533.25))
+	require.NoError(t, f.SetCellValue("Sayaç Okuma", "E3", "1.002,1"))
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+
+	rows, err := ReadSheet(&buf)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "A-1", rows[0].Unit)
+	assert.Equal(t, "HM-001", rows[0].SerialNumber)
+	require.NotNil(t, rows[0].Value)
+	assert.Equal(t, 1533.25, *rows[0].Value)
+	require.NotNil(t, rows[1].Value)
+	assert.Equal(t, 1002.1, *rows[1].Value)
+	assert.Equal(t, 4, rows[2].Num)
+	assert.Nil(t, rows[2].Value, "boş hücre okunmamış sayaçtır")
+	assert.Empty(t, rows[2].Error)
+}
+
+func TestReadSheet_Errors(t *testing.T) {
+	rows, err := ReadSheet(strings.NewReader("Sayaç No;Yeni Okuma\n;10\nHM-1;on\nhm-1;12\nHM-2;-1\n"))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "sayaç numarası zorunlu", rows[0].Error)
+	assert.Equal(t, `okuma sayı olmalı: "on"`, rows[1].Error)
+	assert.Equal(t, "aynı sayaç 3. satırda da var", rows[2].Error)
+	assert.Equal(t, "okuma negatif olamaz", rows[3].Error)
+
+	_, err = ReadSheet(strings.NewReader("Daire;Sayaç No\nA-1;HM-1\n"))
+	assert.ErrorIs(t, err, importer.ErrInvalidFile)
+}
+
+func TestCheck(t *testing.T) {
+	prev := Previous{Date: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), Value: 1000}
+	date := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	// Günde ortalama 0,5 birim: 30 günde beklenen 15
+	history := []Usage{{Consumption: 15, Days: 31}, {Consumption: 0, Days: 30}, {Consumption: 16, Days: 31}, {Consumption: 14.5, Days: 30}}
+
+	assert.Nil(t, Check(prev, history, 1020, date))
+	assert.Nil(t, Check(prev, history, 1000, date), "tüketim olmaması geçerli")
+
+	issue := Check(prev, history, 999.5, date)
+	require.NotNil(t, issue)
+	assert.Equal(t, ReasonRollback, issue.Reason)
+	assert.Equal(t, "yeni okuma (999,5) önceki okumadan (1000) küçük", issue.Message)
+
+	issue = Check(prev, history, 1060, date)
+	require.NotNil(t, issue)
+	assert.Equal(t, ReasonJump, issue.Reason)
+	assert.Contains(t, issue.Message, "tüketim (60)")
+
+	// İki aylık arada tüketim iki kat beklenir
+	assert.Nil(t, Check(prev, history, 1060, date.AddDate(0, 1, 0)))
+	// Yeterli geçmiş yoksa sıçrama denetlenmez
+	assert.Nil(t, Check(prev, history[:2], 1500, date))
+	assert.NotNil(t, Check(prev, nil, 10, date), "geri giden sayaç her zaman incelenir")
+}
+
+func TestBill(t *testing.T) {
+	tariff := Tariff{UnitPrice: 12.3456, FixedFee: 25, TaxRate: 20}
+
+	// 15 × 12,3456 = 185,184 → 185,18 + 25 sabit bedel; %20 KDV 42,04
+	assert.Equal(t, Charge{Subtotal: 210.18, Tax: 42.04, Total: 252.22}, Bill(15, tariff))
+	assert.Equal(t, Charge{Subtotal: 25, Tax: 5, Total: 30}, Bill(0, tariff), "tüketimsiz dönemde sabit bedel")
+	assert.Equal(t, Charge{Subtotal: 150, Total: 150}, Bill(10, Tariff{UnitPrice: 15}))
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Budget yıllık işletme projesi
+type Budget struct {
+	ID                string       `json:"id"`
+	PropertyID        string       `json:"property_id"`
+	FiscalYear        int          `json:"fiscal_year"`
+	StartMonth        int          `json:"start_month"` // Dönemin başladığı ay (1-12)
+	Name              string       `json:"name"`
+	Status            string       `json:"status"` // DRAFT, PENDING_APPROVAL, APPROVED, SUPERSEDED
+	Notes             string       `json:"notes,omitempty"`
+	RejectionReason   string       `json:"rejection_reason,omitempty"`
+	ApprovalReference string       `json:"approval_reference,omitempty"` // Genel kurul karar no / tarihi
+	ApprovedBy        string       `json:"approved_by,omitempty"`
+	ApprovedAt        *time.Time   `json:"approved_at,omitempty"`
+	CreatedBy         string       `json:"created_by,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	TotalPlanned      float64      `json:"total_planned"`
+	Items             []BudgetItem `json:"items,omitempty"`
+}
+
+// BudgetItem işletme projesinde gider kalemi bazında yıllık planlanan tutar
+type BudgetItem struct {
+	CategoryID    string  `json:"category_id" binding:"required"`
+	Category      string  `json:"category,omitempty"`
+	PlannedAmount float64 `json:"planned_amount" binding:"gte=0"`
+	Notes         string  `json:"notes,omitempty"`
+}
+
+// CategoryAmount gider kalemi bazında gerçekleşen veya bekleyen tutar
+type CategoryAmount struct {
+	CategoryID string
+	Category   string
+	Amount     float64
+}
+
+// BudgetIncome işletme hesabının gelir tarafı (defterden)
+type BudgetIncome struct {
+	Assessed  float64 `json:"assessed"`  // 600 Aidat gelirleri
+	LateFees  float64 `json:"late_fees"` // 642 Gecikme tazminatı gelirleri
+	Collected float64 `json:"collected"` // Kasa/banka tahsilatları
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AssessmentCorrection tahakkuk düzeltmesi veya alacak dekontu. Amount işaretlidir:
+// pozitif borç artışı, negatif indirimdir. İndirimin ödenmiş aidata denk gelen kısmı
+// tahakkuktan düşülmez, CreditAmount olarak daire avansına aktarılır.
+type AssessmentCorrection struct {
+	ID                string    `json:"id"`
+	PropertyID        string    `json:"property_id"`
+	UnitID            string    `json:"unit_id"`
+	UnitName          string    `json:"unit_name,omitempty"`
+	AssessmentID      string    `json:"assessment_id,omitempty"` // Alacak dekontunda boş olabilir
+	Period            string    `json:"period,omitempty"`        // "2026-01"
+	Type              string    `json:"type"`                    // ADJUSTMENT, CREDIT_NOTE, REDISTRIBUTION
+	ExpenseCategoryID string    `json:"expense_category_id,omitempty"`
+	Category          string    `json:"category,omitempty"`
+	Amount            float64   `json:"amount"`
+	AssessmentChange  float64   `json:"assessment_change"` // Tahakkuk tutarına yansıyan kısım
+	CreditAmount      float64   `json:"credit_amount"`     // Daire avansına aktarılan kısım
+	Reason            string    `json:"reason"`
+	OriginalEntryID   string    `json:"original_entry_id,omitempty"` // Düzeltilen AIDAT kaydı
+	LedgerEntryID     string    `json:"ledger_entry_id,omitempty"`   // DUZELTME kaydı
+	CreatedBy         string    `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	CalculationBasis string  `json:"-"` // Yeniden dağıtımda kalemin yeni hesaplama açıklaması
+	CategoryBefore   float64 `json:"-"` // Yeniden dağıtım hazırlanırken kalemin tutarı
+}
+
+// CategoryShare dönem tahakkukunda bir gider kalemine düşen tutar
+type CategoryShare struct {
+	AssessmentID string  `json:"assessment_id"`
+	UnitID       string  `json:"unit_id"`
+	Amount       float64 `json:"amount"`
+}
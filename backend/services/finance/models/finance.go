@@ -1,45 +1,94 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/siteeksen/backend/pkg/tenant"
+)
 
 // Assessment aylık tahakkuk
 type Assessment struct {
-	ID          string    `json:"id"`
-	PropertyID  string    `json:"property_id"`
-	UnitID      string    `json:"unit_id"`
-	PeriodYear  int       `json:"period_year"`
-	PeriodMonth int       `json:"period_month"`
-	BaseAmount  float64   `json:"base_amount"`
-	LateFee     float64   `json:"late_fee"`
-	TotalAmount float64   `json:"total_amount"`
-	PaidAmount  float64   `json:"paid_amount"`
-	DueDate     time.Time `json:"due_date"`
-	Status      string    `json:"status"` // PENDING, PARTIAL, PAID, OVERDUE
-	CreatedAt   time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	PropertyID    string    `json:"property_id"`
+	UnitID        string    `json:"unit_id"`
+	PeriodYear    int       `json:"period_year"`
+	PeriodMonth   int       `json:"period_month"`
+	BaseAmount    float64   `json:"base_amount"`
+	ReserveAmount float64   `json:"reserve_amount"` // Fon katkı payı (aidattan ayrı izlenir)
+	LateFee       float64   `json:"late_fee"`
+	TotalAmount   float64   `json:"total_amount"`
+	PaidAmount    float64   `json:"paid_amount"`
+	DueDate       time.Time `json:"due_date"`
+	Status        string    `json:"status"` // PENDING, PARTIAL, PAID, OVERDUE
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // AssessmentSummary aidat özeti (liste görünümü)
 type AssessmentSummary struct {
-	ID          string    `json:"id"`
-	Period      string    `json:"period"` // "2026-01"
-	BaseAmount  float64   `json:"base_amount"`
-	LateFee     float64   `json:"late_fee"`
-	TotalAmount float64   `json:"total_amount"`
-	PaidAmount  float64   `json:"paid_amount"`
-	Status      string    `json:"status"`
+	ID            string  `json:"id"`
+	Period        string  `json:"period"` // "2026-01"
+	BaseAmount    float64 `json:"base_amount"`
+	ReserveAmount float64 `json:"reserve_amount"`
+	LateFee       float64 `json:"late_fee"`
+	TotalAmount   float64 `json:"total_amount"`
+	PaidAmount    float64 `json:"paid_amount"`
+	Status        string  `json:"status"`
 }
 
 // AssessmentDetail aidat detayı
 type AssessmentDetail struct {
 	Assessment
-	Details []AssessmentDetailItem `json:"details"`
+	Details     []AssessmentDetailItem `json:"details"`
+	Corrections []AssessmentCorrection `json:"corrections"` // Düzeltme geçmişi (eskiden yeniye)
 }
 
-// AssessmentDetailItem gider kalemi detayı
+// AssessmentDetailItem gider kalemi veya fon katkı payı detayı
 type AssessmentDetailItem struct {
+	CategoryID       string  `json:"category_id,omitempty"`
+	ReserveFundID    string  `json:"reserve_fund_id,omitempty"`
 	Category         string  `json:"category"`
 	Amount           float64 `json:"amount"`
 	CalculationBasis string  `json:"calculation_basis"`
+	ShareValue       float64 `json:"share_value,omitempty"`
+}
+
+// Unit tahakkuk dağıtımında kullanılan bağımsız bölüm
+type Unit struct {
+	ID            string  `json:"id"`
+	PropertyID    string  `json:"property_id"`
+	Block         string  `json:"block"`
+	Floor         int     `json:"floor"`
+	DoorNumber    string  `json:"door_number"`
+	ShareRatio    float64 `json:"share_ratio"`
+	GrossAreaM2   float64 `json:"gross_area_m2"`
+	IsCommercial  bool    `json:"is_commercial"`
+	IsGroundFloor bool    `json:"is_ground_floor"`
+}
+
+// Name daire görünen adı ("A-3")
+func (u Unit) Name() string {
+	return u.Block + "-" + u.DoorNumber
+}
+
+// ExpenseItem dönem içinde dağıtılacak gider kalemi tutarı
+type ExpenseItem struct {
+	CategoryID string  `json:"category_id" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// GeneratedAssessment tahakkuk motorunun ürettiği daire tahakkuku
+type GeneratedAssessment struct {
+	ID            string                 `json:"id,omitempty"`
+	UnitID        string                 `json:"unit_id"`
+	UnitName      string                 `json:"unit_name"`
+	PeriodYear    int                    `json:"period_year"`
+	PeriodMonth   int                    `json:"period_month"`
+	BaseAmount    float64                `json:"base_amount"`
+	ReserveAmount float64                `json:"reserve_amount,omitempty"` // Fon katkı payları toplamı
+	DueDate       time.Time              `json:"due_date"`
+	Status        string                 `json:"status"`                   // PREVIEW, CREATED, SKIPPED
+	CreditApplied float64                `json:"credit_applied,omitempty"` // Mahsup edilen daire avansı
+	Details       []AssessmentDetailItem `json:"details"`
 }
 
 // ExpenseCategory gider kalemi
@@ -58,14 +107,76 @@ type ExpenseCategory struct {
 type Payment struct {
 	ID            string    `json:"id"`
 	UserID        string    `json:"user_id"`
+	UnitID        string    `json:"unit_id,omitempty"`
+	PropertyID    string    `json:"property_id,omitempty"`
 	Amount        float64   `json:"amount"`
+	CreditAmount  float64   `json:"credit_amount,omitempty"` // Daire avansına aktarılan kısım
 	PaymentMethod string    `json:"payment_method"`
-	Status        string    `json:"status"` // PENDING, COMPLETED, FAILED, REFUNDED
+	Status        string    `json:"status"` // PENDING, COMPLETED, FAILED, AMOUNT_MISMATCH, REFUNDED
 	TransactionID string    `json:"transaction_id,omitempty"`
+	PaidAmount    float64   `json:"paid_amount,omitempty"` // Tutar uyuşmazlığında sağlayıcının tahsil ettiği tutar
 	CreatedAt     time.Time `json:"created_at"`
 	CompletedAt   time.Time `json:"completed_at,omitempty"`
 }
 
+// PayableAssessment ödemeye konu, sakine ait açık tahakkuk
+type PayableAssessment struct {
+	ID          string
+	PropertyID  string
+	UnitID      string
+	PeriodYear  int
+	PeriodMonth int
+	DueDate     time.Time
+	Outstanding float64 // total_amount - paid_amount
+	LateFeeDue  float64 // Ödenmemiş gecikme tazminatı
+}
+
+// PaymentAllocation ödemenin bir tahakkuka düşen kısmı (payment_assessments.amount).
+// LateFee, Amount'un gecikme tazminatına; Reserve, fon katkı payına sayılan kısmıdır.
+type PaymentAllocation struct {
+	AssessmentID string  `json:"assessment_id"`
+	Amount       float64 `json:"amount"`
+	LateFee      float64 `json:"late_fee_amount,omitempty"`
+	Reserve      float64 `json:"reserve_amount,omitempty"` // Fon katkı payına sayılan kısım
+}
+
+// PaymentRefund ödeme iadesinin tahakkuklara etkisi
+type PaymentRefund struct {
+	PaymentID      string              `json:"payment_id"`
+	Amount         float64             `json:"amount"`
+	Allocations    []PaymentAllocation `json:"allocations"`     // Geri alınan dağılım
+	CreditReversed float64             `json:"credit_reversed"` // Geri alınan daire avansı
+	// Avansı zaten mahsup edilmiş tahakkuklarda geri alınan mahsup tutarları
+	ReopenedAssessments []PaymentAllocation `json:"reopened_assessments,omitempty"`
+}
+
+// Payer ödeme yapan sakin (iyzico alıcı bilgisi)
+type Payer struct {
+	ID        string
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+}
+
+// PaymentCallback iyzico 3DS callback form alanları
+type PaymentCallback struct {
+	Status         string `form:"status" json:"status"`
+	PaymentID      string `form:"paymentId" json:"paymentId"`
+	ConversationID string `form:"conversationId" json:"conversationId"`
+	MDStatus       string `form:"mdStatus" json:"mdStatus"`
+}
+
+// StatementInfo hesap ekstresi başlığı için daire, sakin ve site marka bilgileri
+type StatementInfo struct {
+	UnitID       string
+	PropertyID   string
+	PropertyName string
+	UnitName     string
+	ResidentName string
+	Branding     *tenant.TenantBranding
+}
+
 // OverdueInfo gecikmiş borç bilgisi
 type OverdueInfo struct {
 	Amount float64
@@ -92,3 +203,34 @@ type MeterReading struct {
 	PhotoEvidence  string    `json:"photo_evidence_url,omitempty"`
 	ReaderUserID   string    `json:"reader_user_id,omitempty"`
 }
+
+// LateFeeCandidate gecikme tazminatı hesaplanacak vadesi geçmiş tahakkuk
+type LateFeeCandidate struct {
+	AssessmentID   string
+	PropertyID     string
+	UnitID         string
+	PeriodYear     int
+	PeriodMonth    int
+	BaseAmount     float64
+	LateFee        float64
+	PaidAmount     float64
+	LateFeePaid    float64 // PaidAmount'un tazminata sayılan kısmı
+	ReservePaid    float64 // PaidAmount'un fon katkı payına sayılan kısmı
+	DueDate        time.Time
+	AccruedThrough *time.Time // Son tazminat işletilen tarih
+	Status         string
+	FeePercentage  float64 // Aylık oran (%), site ayarı
+	FeeMethod      string  // DAILY, MONTHLY
+}
+
+// LateFeeAccrual tek bir tahakkuka işletilen gecikme tazminatı
+type LateFeeAccrual struct {
+	AssessmentID   string    `json:"assessment_id"`
+	UnitID         string    `json:"unit_id"`
+	Period         string    `json:"period"`
+	DaysOverdue    int       `json:"days_overdue"`
+	Principal      float64   `json:"principal"`
+	Fee            float64   `json:"fee"`
+	TotalLateFee   float64   `json:"total_late_fee"`
+	AccruedThrough time.Time `json:"accrued_through"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// MeterReadingUpload toplu sayaç okuma yüklemesinin satır raporu
+type MeterReadingUpload struct {
+	ReadingDate time.Time         `json:"reading_date"`
+	Total       int               `json:"total"`
+	Recorded    int               `json:"recorded"`
+	Unchanged   int               `json:"unchanged"`
+	Queued      int               `json:"queued"`  // İncelemeye alınan okumalar
+	Skipped     int               `json:"skipped"` // Yeni okuma girilmemiş satırlar
+	Failed      int               `json:"failed"`
+	Rows        []MeterReadingRow `json:"rows"`
+}
+
+// MeterReadingRow listenin bir satırının sonucu
+type MeterReadingRow struct {
+	Row           int      `json:"row"`
+	SerialNumber  string   `json:"serial_number"`
+	Unit          string   `json:"unit,omitempty"`
+	PreviousValue *float64 `json:"previous_value,omitempty"`
+	Value         *float64 `json:"value,omitempty"`
+	Consumption   *float64 `json:"consumption,omitempty"`
+	Status        string   `json:"status"`           // RECORDED, UNCHANGED, QUEUED, SKIPPED, FAILED
+	Reason        string   `json:"reason,omitempty"` // ROLLBACK, JUMP (QUEUED)
+	Message       string   `json:"message,omitempty"`
+	ReadingID     string   `json:"reading_id,omitempty"`
+	ReviewID      string   `json:"review_id,omitempty"`
+	InvoiceID     string   `json:"invoice_id,omitempty"` // Tüketim faturası (tarife tanımlıysa)
+	Charge        *float64 `json:"charge,omitempty"`     // Fatura tutarı (KDV dahil)
+}
+
+// Add satırı rapora ekler ve sayaçları günceller
+func (u *MeterReadingUpload) Add(row MeterReadingRow) {
+	u.Rows = append(u.Rows, row)
+	u.Total++
+	switch row.Status {
+	case "RECORDED":
+		u.Recorded++
+	case "UNCHANGED":
+		u.Unchanged++
+	case "QUEUED":
+		u.Queued++
+	case "SKIPPED":
+		u.Skipped++
+	default:
+		u.Failed++
+	}
+}
+
+// MeterReadingReview doğrulamadan geçmeyip yönetici incelemesine alınan okuma. Onaylanan
+// okuma meter_readings'e yazılır ve MeterReadingID dolar.
+type MeterReadingReview struct {
+	ID             string     `json:"id"`
+	PropertyID     string     `json:"property_id"`
+	MeterID        string     `json:"meter_id"`
+	SerialNumber   string     `json:"serial_number"`
+	MeterType      string     `json:"meter_type"`
+	UnitID         string     `json:"unit_id"`
+	Unit           string     `json:"unit"`
+	ReadingDate    time.Time  `json:"reading_date"`
+	PreviousValue  float64    `json:"previous_value"` // Yükleme anındaki son okuma
+	ReportedValue  float64    `json:"reported_value"` // Listedeki okuma
+	Reason         string     `json:"reason"`         // ROLLBACK, JUMP
+	Message        string     `json:"message"`
+	SourceRow      int        `json:"source_row"`
+	Status         string     `json:"status"` // PENDING, APPROVED, REJECTED
+	MeterReadingID string     `json:"meter_reading_id,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	UploadedBy     string     `json:"uploaded_by,omitempty"`
+	ReviewedBy     string     `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PaymentPlan vadesi geçmiş borcun taksitlendirilmesi
+type PaymentPlan struct {
+	ID               string                   `json:"id"`
+	PropertyID       string                   `json:"property_id"`
+	UnitID           string                   `json:"unit_id"`
+	UnitName         string                   `json:"unit_name,omitempty"`
+	Status           string                   `json:"status"` // ACTIVE, COMPLETED, BROKEN, CANCELLED
+	TotalAmount      float64                  `json:"total_amount"`
+	WaivedLateFee    float64                  `json:"waived_late_fee"`
+	PaidAmount       float64                  `json:"paid_amount"`
+	InstallmentCount int                      `json:"installment_count"`
+	GraceDays        int                      `json:"grace_days"`
+	Notes            string                   `json:"notes,omitempty"`
+	CreatedBy        string                   `json:"created_by,omitempty"`
+	CreatedAt        time.Time                `json:"created_at"`
+	ClosedAt         *time.Time               `json:"closed_at,omitempty"`
+	CloseReason      string                   `json:"close_reason,omitempty"`
+	Assessments      []PaymentPlanAssessment  `json:"assessments,omitempty"`
+	Installments     []PaymentPlanInstallment `json:"installments,omitempty"`
+}
+
+// PaymentPlanAssessment plana dahil tahakkuk
+type PaymentPlanAssessment struct {
+	AssessmentID  string    `json:"assessment_id"`
+	Period        string    `json:"period"` // "2026-01"
+	DueDate       time.Time `json:"due_date"`
+	Outstanding   float64   `json:"outstanding"`     // Plan anındaki açık tutar (af öncesi)
+	LateFeeDue    float64   `json:"late_fee_due"`    // Ödenmemiş gecikme tazminatı
+	WaivedLateFee float64   `json:"waived_late_fee"` // Plan kapsamında affedilen tazminat
+	PaidAtStart   float64   `json:"-"`
+	PaidAmount    float64   `json:"-"` // Güncel ödenen tutar
+	PlanID        string    `json:"-"` // Tahakkukun bağlı olduğu aktif plan
+}
+
+// PaymentPlanInstallment plan taksidi
+type PaymentPlanInstallment struct {
+	ID            string     `json:"id,omitempty"`
+	InstallmentNo int        `json:"installment_no"`
+	DueDate       time.Time  `json:"due_date"`
+	Amount        float64    `json:"amount"`
+	PaidAmount    float64    `json:"paid_amount"`
+	Status        string     `json:"status"` // PENDING, PARTIAL, PAID, MISSED
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// ReserveFund demirbaş / yedek akçe fonu. Katkılar aidattan ayrı tahakkuk edilir ve
+// fonun hesap planındaki alt hesabında (549.xx) izlenir.
+type ReserveFund struct {
+	ID                   string     `json:"id"`
+	PropertyID           string     `json:"property_id"`
+	Name                 string     `json:"name"`
+	Description          string     `json:"description,omitempty"`
+	AccountCode          string     `json:"account_code"`
+	MonthlyAmount        float64    `json:"monthly_amount"`    // Site toplamı aylık katkı
+	DistributionType     string     `json:"distribution_type"` // SHARE_RATIO, EQUAL, AREA_M2
+	AppliesToCommercial  bool       `json:"applies_to_commercial"`
+	AppliesToGroundFloor bool       `json:"applies_to_ground_floor"`
+	StartsOn             time.Time  `json:"starts_on"`
+	EndsOn               *time.Time `json:"ends_on,omitempty"`
+	TargetAmount         *float64   `json:"target_amount,omitempty"`
+	IsActive             bool       `json:"is_active"`
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+// ReserveWithdrawal fondan harcama talebi
+type ReserveWithdrawal struct {
+	ID                string     `json:"id"`
+	FundID            string     `json:"fund_id"`
+	FundName          string     `json:"fund_name,omitempty"`
+	Amount            float64    `json:"amount"`
+	Description       string     `json:"description"`
+	DecisionReference string     `json:"decision_reference"` // Karar no
+	DecisionDate      time.Time  `json:"decision_date"`
+	CashAccount       string     `json:"cash_account"` // 100 Kasa, 102 Bankalar
+	Status            string     `json:"status"`       // PENDING, APPROVED, REJECTED
+	RequestedBy       string     `json:"requested_by,omitempty"`
+	ApprovedBy        string     `json:"approved_by,omitempty"`
+	ApprovedAt        *time.Time `json:"approved_at,omitempty"`
+	RejectionReason   string     `json:"rejection_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// ReserveFundBalance fonun belirli tarihteki durumu
+type ReserveFundBalance struct {
+	FundID        string  `json:"fund_id"`
+	Name          string  `json:"name"`
+	AccountCode   string  `json:"account_code"`
+	Contributions float64 `json:"contributions"` // Tahakkuk edilen katkı payları
+	Collected     float64 `json:"collected"`     // Tahsil edilen katkı payları
+	Withdrawals   float64 `json:"withdrawals"`   // Onaylı harcamalar
+	Balance       float64 `json:"balance"`       // Defter bakiyesi (katkılar - harcamalar)
+	Uncollected   float64 `json:"uncollected"`   // Tahsil edilmemiş katkılar
+	Available     float64 `json:"available"`     // Kullanılabilir nakit (tahsilat - harcamalar)
+}
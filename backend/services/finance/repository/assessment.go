@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// GetUnitsForDistribution sitedeki tüm bağımsız bölümleri getirir
+func (r *FinanceRepository) GetUnitsForDistribution(ctx context.Context, propertyID string) ([]models.Unit, error) {
+	query := `
+		SELECT id, property_id, COALESCE(block, ''), floor, door_number,
+			   share_ratio, COALESCE(gross_area_m2, 0),
+			   COALESCE(is_commercial, false), COALESCE(is_ground_floor, false)
+		FROM units
+		WHERE property_id = $1
+		ORDER BY block, floor, door_number
+	`
+	rows, err := r.db(ctx).Query(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []models.Unit
+	for rows.Next() {
+		var u models.Unit
+		if err := rows.Scan(&u.ID, &u.PropertyID, &u.Block, &u.Floor, &u.DoorNumber,
+			&u.ShareRatio, &u.GrossAreaM2, &u.IsCommercial, &u.IsGroundFloor); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// GetExpenseCategories sitenin aktif gider kalemlerini getirir
+func (r *FinanceRepository) GetExpenseCategories(ctx context.Context, propertyID string) ([]models.ExpenseCategory, error) {
+	query := `
+		SELECT id, property_id, name, distribution_type,
+			   COALESCE(applies_to_commercial, true), COALESCE(applies_to_ground_floor, true),
+			   COALESCE(custom_formula::text, ''), is_active
+		FROM expense_categories
+		WHERE property_id = $1 AND is_active = true
+		ORDER BY sort_order
+	`
+	rows, err := r.db(ctx).Query(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.ExpenseCategory
+	for rows.Next() {
+		var c models.ExpenseCategory
+		if err := rows.Scan(&c.ID, &c.PropertyID, &c.Name, &c.DistributionType,
+			&c.AppliesToCommercial, &c.AppliesToGroundFloor, &c.CustomFormula, &c.IsActive); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// GetAssessmentDueDay site ayarlarındaki aidat son ödeme gününü getirir
+func (r *FinanceRepository) GetAssessmentDueDay(ctx context.Context, propertyID string) (int, error) {
+	query := `
+		SELECT COALESCE((t.settings->>'assessment_due_day')::int, 10)
+		FROM properties p
+		LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = $1
+	`
+	var day int
+	err := r.db(ctx).QueryRow(ctx, query, propertyID).Scan(&day)
+	return day, err
+}
+
+// GetAssessedUnitIDs dönem için düzenli tahakkuku zaten bulunan daireleri getirir (devir bakiyeleri sayılmaz)
+func (r *FinanceRepository) GetAssessedUnitIDs(ctx context.Context, propertyID string, year, month int) (map[string]string, error) {
+	query := `
+		SELECT unit_id, id FROM monthly_assessments
+		WHERE property_id = $1 AND period_year = $2 AND period_month = $3 AND kind = 'REGULAR'
+	`
+	rows, err := r.db(ctx).Query(ctx, query, propertyID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assessed := make(map[string]string)
+	for rows.Next() {
+		var unitID, assessmentID string
+		if err := rows.Scan(&unitID, &assessmentID); err != nil {
+			return nil, err
+		}
+		assessed[unitID] = assessmentID
+	}
+	return assessed, rows.Err()
+}
+
+// CreateAssessments tahakkukları ve detay kalemlerini tek transaction içinde yazar.
+// Aynı dönem için tahakkuku olan daireler atlanır (Status = SKIPPED), böylece
+// işlem tekrar çalıştırıldığında mükerrer kayıt oluşmaz. Her yeni tahakkuk için
+// aynı transaction içinde AIDAT yevmiye kaydı atılır ve dairenin avans bakiyesi
+// varsa tahakkuka mahsup edilir. Fon katkı payları aidattan ayrı olarak her fonun
+// kendi hesabına (549.xx) FON katkısı şeklinde defterlenir.
+func (r *FinanceRepository) CreateAssessments(ctx context.Context, propertyID string, assessments []models.GeneratedAssessment) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	fundAccounts := make(map[string]string)
+	for i := range assessments {
+		a := &assessments[i]
+
+		var id string
+		err := tx.QueryRow(ctx, `
+			INSERT INTO monthly_assessments
+				(property_id, unit_id, period_year, period_month, base_amount, reserve_amount, late_fee, total_amount, paid_amount, due_date, status)
+			VALUES ($1, $2, $3, $4, $5, $6, 0, $5 + $6, 0, $7, 'PENDING')
+			ON CONFLICT (unit_id, period_year, period_month) WHERE kind = 'REGULAR' DO NOTHING
+			RETURNING id
+		`, propertyID, a.UnitID, a.PeriodYear, a.PeriodMonth, a.BaseAmount, a.ReserveAmount, a.DueDate).Scan(&id)
+		if err == pgx.ErrNoRows {
+			a.Status = "SKIPPED"
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("tahakkuk yazılamadı (%s): %w", a.UnitName, err)
+		}
+
+		for _, d := range a.Details {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO assessment_details
+					(assessment_id, expense_category_id, reserve_fund_id, amount, calculation_basis, share_value)
+				VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, $4, $5, $6)
+			`, id, d.CategoryID, d.ReserveFundID, d.Amount, d.CalculationBasis, d.ShareValue)
+			if err != nil {
+				return fmt.Errorf("tahakkuk detayı yazılamadı (%s): %w", a.UnitName, err)
+			}
+		}
+
+		if a.BaseAmount > 0 {
+			entry := ledger.AssessmentAccrual(propertyID, a.UnitID, id, a.BaseAmount, a.DueDate,
+				fmt.Sprintf("%d/%02d dönemi aidat tahakkuku - %s", a.PeriodYear, a.PeriodMonth, a.UnitName))
+			if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+				return fmt.Errorf("tahakkuk defterlenemedi (%s): %w", a.UnitName, err)
+			}
+		}
+
+		for _, d := range a.Details {
+			if d.ReserveFundID == "" {
+				continue
+			}
+			account, ok := fundAccounts[d.ReserveFundID]
+			if !ok {
+				if err := tx.QueryRow(ctx, `
+					SELECT account_code FROM reserve_funds WHERE id = $1 AND property_id = $2
+				`, d.ReserveFundID, propertyID).Scan(&account); err != nil {
+					return fmt.Errorf("fon hesabı bulunamadı (%s): %w", d.Category, err)
+				}
+				fundAccounts[d.ReserveFundID] = account
+			}
+			entry := ledger.ReserveContribution(propertyID, a.UnitID, id, account, d.Amount, a.DueDate,
+				fmt.Sprintf("%d/%02d dönemi %s katkı payı - %s", a.PeriodYear, a.PeriodMonth, d.Category, a.UnitName))
+			if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+				return fmt.Errorf("fon katkısı defterlenemedi (%s): %w", a.UnitName, err)
+			}
+		}
+
+		credit, err := r.applyUnitCredit(ctx, tx, propertyID, a.UnitID, id,
+			float64(toKurus(a.BaseAmount)+toKurus(a.ReserveAmount))/100, a.ReserveAmount, a.DueDate,
+			fmt.Sprintf("%d/%02d dönemi aidatına avans mahsubu - %s", a.PeriodYear, a.PeriodMonth, a.UnitName))
+		if err != nil {
+			return fmt.Errorf("avans mahsup edilemedi (%s): %w", a.UnitName, err)
+		}
+
+		a.ID = id
+		a.Status = "CREATED"
+		a.CreditApplied = credit
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetTrialBalance belirli tarih itibarıyla sitenin mizanını defterden hesaplar
+func (r *FinanceRepository) GetTrialBalance(ctx context.Context, propertyID string, asOf time.Time) (*ledger.TrialBalance, error) {
+	return r.ledger.TrialBalance(ctx, propertyID, asOf)
+}
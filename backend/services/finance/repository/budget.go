@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrBudgetNotFound işletme projesi bulunamadı
+var ErrBudgetNotFound = errors.New("işletme projesi bulunamadı")
+
+// ErrBudgetStatusChanged işlem sırasında projenin durumu başka bir istekle değişti
+var ErrBudgetStatusChanged = errors.New("işletme projesinin durumu değişmiş, lütfen tekrar deneyin")
+
+const budgetColumns = `
+	b.id, b.property_id, b.fiscal_year, b.start_month, b.name, b.status,
+	COALESCE(b.notes, ''), COALESCE(b.rejection_reason, ''), COALESCE(b.approval_reference, ''),
+	COALESCE(b.approved_by::text, ''), b.approved_at, COALESCE(b.created_by::text, ''),
+	b.created_at, b.updated_at,
+	COALESCE((SELECT SUM(bi.planned_amount) FROM budget_items bi WHERE bi.budget_id = b.id), 0)
+`
+
+func scanBudget(row pgx.Row) (*models.Budget, error) {
+	b := &models.Budget{}
+	err := row.Scan(&b.ID, &b.PropertyID, &b.FiscalYear, &b.StartMonth, &b.Name, &b.Status,
+		&b.Notes, &b.RejectionReason, &b.ApprovalReference, &b.ApprovedBy, &b.ApprovedAt, &b.CreatedBy,
+		&b.CreatedAt, &b.UpdatedAt, &b.TotalPlanned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBudgetNotFound
+	}
+	return b, err
+}
+
+// CreateBudget işletme projesini kalemleriyle birlikte DRAFT olarak kaydeder
+func (r *FinanceRepository) CreateBudget(ctx context.Context, b *models.Budget) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO budgets (property_id, fiscal_year, start_month, name, status, notes, created_by)
+		VALUES ($1, $2, $3, $4, 'DRAFT', NULLIF($5, ''), NULLIF($6, '')::uuid)
+		RETURNING id, status, created_at, updated_at
+	`, b.PropertyID, b.FiscalYear, b.StartMonth, b.Name, b.Notes, b.CreatedBy).Scan(&b.ID, &b.Status, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("işletme projesi kaydedilemedi: %w", err)
+	}
+	if err := insertBudgetItems(ctx, tx, b.ID, b.Items); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateBudget taslak projenin başlık bilgilerini ve kalemlerini günceller.
+// Proje artık DRAFT değilse ErrBudgetStatusChanged döner.
+func (r *FinanceRepository) UpdateBudget(ctx context.Context, b *models.Budget) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE budgets
+		SET name = $3, start_month = $4, notes = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $1 AND property_id = $2 AND status = 'DRAFT'
+		RETURNING updated_at
+	`, b.ID, b.PropertyID, b.Name, b.StartMonth, b.Notes).Scan(&b.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrBudgetStatusChanged
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM budget_items WHERE budget_id = $1`, b.ID); err != nil {
+		return err
+	}
+	if err := insertBudgetItems(ctx, tx, b.ID, b.Items); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func insertBudgetItems(ctx context.Context, q database.Querier, budgetID string, items []models.BudgetItem) error {
+	for _, item := range items {
+		_, err := q.Exec(ctx, `
+			INSERT INTO budget_items (budget_id, category_id, planned_amount, notes)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+		`, budgetID, item.CategoryID, item.PlannedAmount, item.Notes)
+		if err != nil {
+			return fmt.Errorf("proje kalemi kaydedilemedi: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetBudget sitenin işletme projesini kalemleriyle getirir
+func (r *FinanceRepository) GetBudget(ctx context.Context, propertyID, budgetID string) (*models.Budget, error) {
+	b, err := scanBudget(r.db(ctx).QueryRow(ctx, `SELECT `+budgetColumns+` FROM budgets b WHERE b.id = $1 AND b.property_id = $2`,
+		budgetID, propertyID))
+	if err != nil {
+		return nil, err
+	}
+	if b.Items, err = r.getBudgetItems(ctx, b.ID); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListBudgets sitenin işletme projelerini (kalemsiz) getirir; year sıfırsa tüm yıllar döner
+func (r *FinanceRepository) ListBudgets(ctx context.Context, propertyID string, year int) ([]models.Budget, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		WHERE b.property_id = $1 AND ($2 = 0 OR b.fiscal_year = $2)
+		ORDER BY b.fiscal_year DESC, b.created_at DESC
+	`, propertyID, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+// GetApprovedBudgetForPeriod dönemi (yıl/ay) kapsayan onaylı işletme projesini getirir.
+// Proje dönemi fiscal_year/start_month'tan başlayan 12 aydır; yoksa ErrBudgetNotFound döner.
+func (r *FinanceRepository) GetApprovedBudgetForPeriod(ctx context.Context, propertyID string, year, month int) (*models.Budget, error) {
+	b, err := scanBudget(r.db(ctx).QueryRow(ctx, `
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		WHERE b.property_id = $1 AND b.status = 'APPROVED'
+		  AND $2::int * 12 + $3::int BETWEEN b.fiscal_year * 12 + b.start_month AND b.fiscal_year * 12 + b.start_month + 11
+		ORDER BY b.approved_at DESC
+		LIMIT 1
+	`, propertyID, year, month))
+	if err != nil {
+		return nil, err
+	}
+	if b.Items, err = r.getBudgetItems(ctx, b.ID); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *FinanceRepository) getBudgetItems(ctx context.Context, budgetID string) ([]models.BudgetItem, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT bi.category_id, ec.name, bi.planned_amount, COALESCE(bi.notes, '')
+		FROM budget_items bi
+		JOIN expense_categories ec ON ec.id = bi.category_id
+		WHERE bi.budget_id = $1
+		ORDER BY ec.sort_order, ec.name
+	`, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.BudgetItem
+	for rows.Next() {
+		var item models.BudgetItem
+		if err := rows.Scan(&item.CategoryID, &item.Category, &item.PlannedAmount, &item.Notes); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// UpdateBudgetStatus projeyi from durumundan to durumuna geçirir (onaya sunma, red).
+// reason red gerekçesidir; onaya sunulurken önceki gerekçe temizlenir.
+func (r *FinanceRepository) UpdateBudgetStatus(ctx context.Context, propertyID, budgetID, from, to, reason string) error {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE budgets
+		SET status = $4, rejection_reason = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $1 AND property_id = $2 AND status = $3
+	`, budgetID, propertyID, from, to, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBudgetStatusChanged
+	}
+	return nil
+}
+
+// ApproveBudget onaya sunulmuş projeyi onaylar. Aynı dönemin daha önce onaylanmış
+// projesi aynı transaction içinde SUPERSEDED yapılır (revize işletme projesi).
+func (r *FinanceRepository) ApproveBudget(ctx context.Context, propertyID, budgetID, approvedBy, reference string) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var fiscalYear int
+	err = tx.QueryRow(ctx, `
+		SELECT fiscal_year FROM budgets
+		WHERE id = $1 AND property_id = $2 AND status = 'PENDING_APPROVAL'
+		FOR UPDATE
+	`, budgetID, propertyID).Scan(&fiscalYear)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrBudgetStatusChanged
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE budgets SET status = 'SUPERSEDED', updated_at = NOW()
+		WHERE property_id = $1 AND fiscal_year = $2 AND status = 'APPROVED'
+	`, propertyID, fiscalYear)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE budgets
+		SET status = 'APPROVED', approved_by = NULLIF($2, '')::uuid, approved_at = NOW(),
+			approval_reference = NULLIF($3, ''), rejection_reason = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, budgetID, approvedBy, reference)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetExpenseActuals tarih aralığında defterleşmiş giderleri (770) gider kalemi bazında
+// getirir. Silinen veya reddedilen giderlerin ters kayıtları tutardan düşülür.
+func (r *FinanceRepository) GetExpenseActuals(ctx context.Context, propertyID string, from, to time.Time) ([]models.CategoryAmount, error) {
+	return r.categoryAmounts(ctx, `
+		SELECT e.category_id, ec.name, SUM(ll.debit_amount - ll.credit_amount)
+		FROM ledger_lines ll
+		JOIN chart_of_accounts coa ON coa.id = ll.account_id AND coa.account_code = $4
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		JOIN expenses e ON e.id = le.source_id
+		JOIN expense_categories ec ON ec.id = e.category_id
+		WHERE le.property_id = $1 AND le.source_type = 'expense'
+		  AND le.transaction_date BETWEEN $2 AND $3
+		GROUP BY e.category_id, ec.name
+	`, propertyID, from, to, ledger.AccountOperatingExpense)
+}
+
+// GetPendingExpenses onay bekleyen (henüz defterleşmemiş) giderleri gider kalemi bazında getirir
+func (r *FinanceRepository) GetPendingExpenses(ctx context.Context, propertyID string, from, to time.Time) ([]models.CategoryAmount, error) {
+	return r.categoryAmounts(ctx, `
+		SELECT e.category_id, ec.name, SUM(e.amount)
+		FROM expenses e
+		JOIN expense_categories ec ON ec.id = e.category_id
+		WHERE e.property_id = $1 AND e.status = 'PENDING'
+		  AND e.expense_date BETWEEN $2 AND $3
+		GROUP BY e.category_id, ec.name
+	`, propertyID, from, to)
+}
+
+func (r *FinanceRepository) categoryAmounts(ctx context.Context, query string, args ...any) ([]models.CategoryAmount, error) {
+	rows, err := r.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var amounts []models.CategoryAmount
+	for rows.Next() {
+		var a models.CategoryAmount
+		if err := rows.Scan(&a.CategoryID, &a.Category, &a.Amount); err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, a)
+	}
+	return amounts, rows.Err()
+}
+
+// GetBudgetIncome tarih aralığındaki aidat ve gecikme tazminatı gelirlerini ve
+// kasa/banka tahsilatlarını defterden hesaplar. İadeler ters kayıtlarla düşülür.
+func (r *FinanceRepository) GetBudgetIncome(ctx context.Context, propertyID string, from, to time.Time) (*models.BudgetIncome, error) {
+	income := &models.BudgetIncome{}
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(ll.credit_amount - ll.debit_amount) FILTER (WHERE coa.account_code = $4), 0),
+			COALESCE(SUM(ll.credit_amount - ll.debit_amount) FILTER (WHERE coa.account_code = $5), 0),
+			COALESCE(SUM(ll.debit_amount - ll.credit_amount) FILTER (
+				WHERE coa.account_code IN ($6, $7) AND le.source_type = 'payment'), 0)
+		FROM ledger_lines ll
+		JOIN ledger_entries le ON le.id = ll.entry_id
+		JOIN chart_of_accounts coa ON coa.id = ll.account_id
+		WHERE le.property_id = $1 AND le.transaction_date BETWEEN $2 AND $3
+	`, propertyID, from, to, ledger.AccountAssessmentRevenue, ledger.AccountLateFeeRevenue,
+		ledger.AccountCash, ledger.AccountBank).Scan(&income.Assessed, &income.LateFees, &income.Collected)
+	return income, err
+}
+
+// GetPropertyBranding site adını ve bağlı olduğu kiracının marka ayarlarını getirir
+func (r *FinanceRepository) GetPropertyBranding(ctx context.Context, propertyID string) (string, *tenant.TenantBranding, error) {
+	var name string
+	var raw []byte
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT p.name, COALESCE(t.branding, '{}'::jsonb)
+		FROM properties p
+		LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = $1
+	`, propertyID).Scan(&name, &raw)
+	if err != nil {
+		return "", nil, err
+	}
+	branding, err := decodeBranding(raw)
+	return name, branding, err
+}
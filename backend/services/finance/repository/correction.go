@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/allocation"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrAssessmentNotFound tahakkuk bulunamadı veya başka bir siteye / daireye ait
+var ErrAssessmentNotFound = errors.New("tahakkuk bulunamadı")
+
+// ErrCorrectionConflict yeniden dağıtım hazırlanırken kalemin tahakkuktaki tutarı değişti
+var ErrCorrectionConflict = errors.New("tahakkuk düzeltme hazırlanırken değişti, lütfen tekrar deneyin")
+
+const correctionColumns = `
+	ac.id, ac.property_id, ac.unit_id, COALESCE(u.block, '') || '-' || u.door_number,
+	COALESCE(ac.assessment_id::text, ''),
+	COALESCE(TO_CHAR(MAKE_DATE(ma.period_year, ma.period_month, 1), 'YYYY-MM'), ''),
+	ac.correction_type, COALESCE(ac.expense_category_id::text, ''), COALESCE(ec.name, ''),
+	ac.amount, ac.assessment_change, ac.credit_amount, ac.reason,
+	COALESCE(ac.original_entry_id::text, ''), COALESCE(ac.ledger_entry_id::text, ''),
+	COALESCE(ac.created_by::text, ''), ac.created_at`
+
+// CorrectAssessment tahakkuku düzeltir ve DUZELTME kaydını atar. Tahakkuk satırı
+// kilitlenir; c'nin tahakkuka yansıyan ve avansa aktarılan tutarları doldurulur.
+func (r *FinanceRepository) CorrectAssessment(ctx context.Context, c *models.AssessmentCorrection) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.applyAssessmentCorrection(ctx, tx, c); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RedistributeCategory yeniden dağıtımın daire bazlı düzeltmelerini tek transaction
+// içinde uygular. Kalemin tahakkuktaki tutarı hazırlık anından farklıysa
+// ErrCorrectionConflict döner ve hiçbir düzeltme yazılmaz.
+func (r *FinanceRepository) RedistributeCategory(ctx context.Context, corrections []*models.AssessmentCorrection) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, c := range corrections {
+		if err := r.applyAssessmentCorrection(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// CreateCreditNote daireye alacak dekontu düzenler: tutar 600 / 340 kaydıyla daire
+// avansına yazılır ve sonraki tahakkuklara mahsup edilir. Tahakkuk verilmişse dekont
+// o tahakkukun AIDAT kaydına bağlanır, tahakkuk tutarı değişmez.
+func (r *FinanceRepository) CreateCreditNote(ctx context.Context, c *models.AssessmentCorrection) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(block, '') || '-' || door_number FROM units WHERE id = $1 AND property_id = $2 FOR UPDATE
+	`, c.UnitID, c.PropertyID).Scan(&c.UnitName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUnitNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.AssessmentID != "" {
+		err := tx.QueryRow(ctx, `
+			SELECT TO_CHAR(MAKE_DATE(period_year, period_month, 1), 'YYYY-MM')
+			FROM monthly_assessments WHERE id = $1 AND unit_id = $2
+		`, c.AssessmentID, c.UnitID).Scan(&c.Period)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAssessmentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if c.OriginalEntryID, err = r.assessmentEntryID(ctx, tx, c.AssessmentID); err != nil {
+			return err
+		}
+	}
+
+	c.AssessmentChange = 0
+	c.CreditAmount = -c.Amount
+	if err := r.recordCorrection(ctx, tx, c, "Alacak dekontu: "+c.Reason); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetCategoryShares dönem tahakkuklarında gider kalemine düşen tutarlar. Kalemden pay
+// almamış tahakkuklar 0 tutarla döner. Sıra, düzeltmelerde kilit sırası olarak kullanılır.
+func (r *FinanceRepository) GetCategoryShares(ctx context.Context, propertyID string, year, month int, categoryID string) ([]models.CategoryShare, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT ma.id, ma.unit_id, COALESCE(SUM(ad.amount), 0)
+		FROM monthly_assessments ma
+		LEFT JOIN assessment_details ad ON ad.assessment_id = ma.id AND ad.expense_category_id = $4
+		WHERE ma.property_id = $1 AND ma.period_year = $2 AND ma.period_month = $3 AND ma.kind = 'REGULAR'
+		GROUP BY ma.id, ma.unit_id
+		ORDER BY ma.id
+	`, propertyID, year, month, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []models.CategoryShare
+	for rows.Next() {
+		var s models.CategoryShare
+		if err := rows.Scan(&s.AssessmentID, &s.UnitID, &s.Amount); err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+// ListAssessmentCorrections sitenin düzeltme ve alacak dekontları (unitID / type boşsa filtrelenmez)
+func (r *FinanceRepository) ListAssessmentCorrections(ctx context.Context, propertyID, unitID, correctionType string) ([]models.AssessmentCorrection, error) {
+	return queryCorrections(ctx, r.db(ctx), `ac.property_id = $1 AND ($2 = '' OR ac.unit_id::text = $2) AND ($3 = '' OR ac.correction_type = $3)`,
+		propertyID, unitID, correctionType)
+}
+
+func queryCorrections(ctx context.Context, q database.Querier, filter string, args ...any) ([]models.AssessmentCorrection, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+correctionColumns+`
+		FROM assessment_corrections ac
+		JOIN units u ON u.id = ac.unit_id
+		LEFT JOIN monthly_assessments ma ON ma.id = ac.assessment_id
+		LEFT JOIN expense_categories ec ON ec.id = ac.expense_category_id
+		WHERE `+filter+`
+		ORDER BY ac.created_at, ac.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	corrections := []models.AssessmentCorrection{}
+	for rows.Next() {
+		var c models.AssessmentCorrection
+		if err := rows.Scan(&c.ID, &c.PropertyID, &c.UnitID, &c.UnitName, &c.AssessmentID, &c.Period,
+			&c.Type, &c.ExpenseCategoryID, &c.Category, &c.Amount, &c.AssessmentChange, &c.CreditAmount,
+			&c.Reason, &c.OriginalEntryID, &c.LedgerEntryID, &c.CreatedBy, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, c)
+	}
+	return corrections, rows.Err()
+}
+
+// applyAssessmentCorrection tahakkuku kilitler, indirimi ödenmemiş aidat ile avans arasında
+// böler, tahakkuk ve kalem tutarlarını günceller ve düzeltmeyi defterler. Ödeme planındaki
+// tahakkuklar düzeltilemez; planın açık tutarları sabittir.
+func (r *FinanceRepository) applyAssessmentCorrection(ctx context.Context, tx pgx.Tx, c *models.AssessmentCorrection) error {
+	var year, month int
+	var base, aidatPaid float64
+	var planID string
+	err := tx.QueryRow(ctx, `
+		SELECT unit_id, period_year, period_month, base_amount,
+			   COALESCE(paid_amount, 0) - late_fee_paid - reserve_paid,
+			   COALESCE(payment_plan_id::text, '')
+		FROM monthly_assessments
+		WHERE id = $1 AND property_id = $2
+		FOR UPDATE
+	`, c.AssessmentID, c.PropertyID).Scan(&c.UnitID, &year, &month, &base, &aidatPaid, &planID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAssessmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	c.Period = fmt.Sprintf("%d-%02d", year, month)
+	if planID != "" {
+		return fmt.Errorf("%d/%02d dönemi tahakkuku ödeme planında; düzeltmeden önce planı iptal edin", year, month)
+	}
+	if toKurus(base)+toKurus(c.Amount) < 0 {
+		return fmt.Errorf("%d/%02d dönemi için indirim aidat tutarını (%.2f TL) aşamaz", year, month, base)
+	}
+
+	var categoryAmount float64
+	var categoryRows int
+	if c.ExpenseCategoryID != "" {
+		err := tx.QueryRow(ctx, `
+			SELECT ec.name, COALESCE(SUM(ad.amount), 0), COUNT(ad.id)
+			FROM expense_categories ec
+			LEFT JOIN assessment_details ad ON ad.expense_category_id = ec.id AND ad.assessment_id = $3
+			WHERE ec.id = $1 AND ec.property_id = $2
+			GROUP BY ec.name
+		`, c.ExpenseCategoryID, c.PropertyID, c.AssessmentID).Scan(&c.Category, &categoryAmount, &categoryRows)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("gider kalemi bulunamadı")
+		}
+		if err != nil {
+			return err
+		}
+		if c.Type == "REDISTRIBUTION" && toKurus(categoryAmount) != toKurus(c.CategoryBefore) {
+			return ErrCorrectionConflict
+		}
+		if toKurus(categoryAmount)+toKurus(c.Amount) < 0 {
+			return fmt.Errorf("%s kalemi için indirim tahakkuktaki tutarı (%.2f TL) aşamaz", c.Category, categoryAmount)
+		}
+	}
+
+	change, credit := allocation.CorrectionSplit(toKurus(c.Amount), toKurus(base)-toKurus(aidatPaid))
+	c.AssessmentChange = float64(change) / 100
+	c.CreditAmount = float64(credit) / 100
+
+	if change != 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE monthly_assessments
+			SET base_amount = base_amount + $2, total_amount = total_amount + $2, updated_at = NOW()
+			WHERE id = $1
+		`, c.AssessmentID, c.AssessmentChange); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE monthly_assessments SET status = `+assessmentStatusSQL+` WHERE id = $1`, c.AssessmentID); err != nil {
+			return err
+		}
+	}
+	if c.ExpenseCategoryID != "" && (change != 0 || c.CalculationBasis != "") {
+		if categoryRows > 0 {
+			_, err = tx.Exec(ctx, `
+				UPDATE assessment_details
+				SET amount = amount + $3, calculation_basis = COALESCE(NULLIF($4, ''), calculation_basis)
+				WHERE assessment_id = $1 AND expense_category_id = $2
+			`, c.AssessmentID, c.ExpenseCategoryID, c.AssessmentChange, c.CalculationBasis)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO assessment_details (assessment_id, expense_category_id, amount, calculation_basis)
+				VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'Düzeltme'))
+			`, c.AssessmentID, c.ExpenseCategoryID, c.AssessmentChange, c.CalculationBasis)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if credit > 0 {
+		// Avans bakiyesi iade / mahsup işlemleriyle yarışmasın
+		if err := lockUnit(ctx, tx, c.UnitID); err != nil {
+			return err
+		}
+	}
+
+	if c.OriginalEntryID, err = r.assessmentEntryID(ctx, tx, c.AssessmentID); err != nil {
+		return err
+	}
+
+	description := fmt.Sprintf("%d/%02d dönemi aidat düzeltmesi: %s", year, month, c.Reason)
+	if c.Type == "REDISTRIBUTION" {
+		description = fmt.Sprintf("%d/%02d dönemi %s kalemi yeniden dağıtımı: %s", year, month, c.Category, c.Reason)
+	}
+	return r.recordCorrection(ctx, tx, c, description)
+}
+
+// recordCorrection düzeltme satırını yazar ve DUZELTME kaydını atıp satıra bağlar
+func (r *FinanceRepository) recordCorrection(ctx context.Context, tx pgx.Tx, c *models.AssessmentCorrection, description string) error {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO assessment_corrections
+			(property_id, unit_id, assessment_id, correction_type, expense_category_id, amount,
+			 assessment_change, credit_amount, reason, original_entry_id, created_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, NULLIF($5, '')::uuid, $6, $7, $8, $9,
+				NULLIF($10, '')::uuid, NULLIF($11, '')::uuid)
+		RETURNING id, created_at
+	`, c.PropertyID, c.UnitID, c.AssessmentID, c.Type, c.ExpenseCategoryID, c.Amount,
+		c.AssessmentChange, c.CreditAmount, c.Reason, c.OriginalEntryID, c.CreatedBy).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("düzeltme kaydedilemedi: %w", err)
+	}
+
+	entry := ledger.AssessmentCorrection(c.PropertyID, c.UnitID, c.ID, c.OriginalEntryID,
+		c.AssessmentChange, c.CreditAmount, c.CreatedAt, description, c.CreatedBy)
+	if c.LedgerEntryID, err = r.ledger.PostTx(ctx, tx, entry); err != nil {
+		return fmt.Errorf("düzeltme defterlenemedi: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE assessment_corrections SET ledger_entry_id = $2 WHERE id = $1`, c.ID, c.LedgerEntryID)
+	return err
+}
+
+// assessmentEntryID tahakkukun AIDAT kaydı; yalnızca fon katkısı içeren tahakkuklarda boş döner
+func (r *FinanceRepository) assessmentEntryID(ctx context.Context, tx pgx.Tx, assessmentID string) (string, error) {
+	entries, err := r.ledger.FindBySource(ctx, tx, ledger.DocAssessment, "assessment", assessmentID)
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].ID, nil
+}
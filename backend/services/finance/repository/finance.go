@@ -2,22 +2,33 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/pkg/ledger"
 	"github.com/siteeksen/backend/services/finance/models"
 )
 
 // FinanceRepository finans veritabanı işlemleri
 type FinanceRepository struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	ledger *ledger.Ledger
+	pii    *encryption.PIICipher // Ödeme yapanın şifreli telefonunu çözmek için
 }
 
 // NewFinanceRepository yeni repository oluşturur
-func NewFinanceRepository(pool *pgxpool.Pool) *FinanceRepository {
-	return &FinanceRepository{pool: pool}
+func NewFinanceRepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *FinanceRepository {
+	return &FinanceRepository{pool: pool, ledger: ledger.New(pool), pii: pii}
+}
+
+// db isteğin kiracı kapsamındaki (RLS) bağlantı; arka plan işlerinde havuzun kendisi
+func (r *FinanceRepository) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, r.pool)
 }
 
 // GetUnitBalance daire bakiyesini hesaplar
@@ -25,18 +36,18 @@ func (r *FinanceRepository) GetUnitBalance(ctx context.Context, userID string) (
 	query := `
 		SELECT COALESCE(SUM(ll.debit_amount) - SUM(ll.credit_amount), 0) as balance
 		FROM ledger_lines ll
-		JOIN users u ON ll.unit_id = (
+		WHERE ll.unit_id = (
 			SELECT ru.unit_id FROM resident_units ru 
 			WHERE ru.resident_id = $1 AND ru.is_active = true 
 			LIMIT 1
 		)
 	`
 	var balance float64
-	err := r.pool.QueryRow(ctx, query, userID).Scan(&balance)
+	err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&balance)
 	return balance, err
 }
 
-// GetOverdueInfo gecikmiş borç bilgisi
+// GetOverdueInfo gecikmiş borç bilgisi (ödeme planına alınan tahakkuklar hariç)
 func (r *FinanceRepository) GetOverdueInfo(ctx context.Context, userID string) (*models.OverdueInfo, error) {
 	query := `
 		SELECT COALESCE(SUM(total_amount - paid_amount), 0), COUNT(*)
@@ -46,9 +57,10 @@ func (r *FinanceRepository) GetOverdueInfo(ctx context.Context, userID string) (
 		  AND ru.is_active = true
 		  AND ma.due_date < CURRENT_DATE
 		  AND ma.status != 'PAID'
+		  AND ma.payment_plan_id IS NULL
 	`
 	info := &models.OverdueInfo{}
-	err := r.pool.QueryRow(ctx, query, userID).Scan(&info.Amount, &info.Months)
+	err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&info.Amount, &info.Months)
 	return info, err
 }
 
@@ -66,7 +78,7 @@ func (r *FinanceRepository) GetNextDueAssessment(ctx context.Context, userID str
 		LIMIT 1
 	`
 	a := &models.Assessment{}
-	err := r.pool.QueryRow(ctx, query, userID).Scan(&a.ID, &a.TotalAmount, &a.DueDate)
+	err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&a.ID, &a.TotalAmount, &a.DueDate)
 	return a, err
 }
 
@@ -75,7 +87,7 @@ func (r *FinanceRepository) GetAssessments(ctx context.Context, userID string, y
 	query := `
 		SELECT ma.id, 
 			   TO_CHAR(MAKE_DATE(ma.period_year, ma.period_month, 1), 'YYYY-MM'),
-			   ma.base_amount, ma.late_fee, ma.total_amount, 
+			   ma.base_amount, ma.reserve_amount, ma.late_fee, ma.total_amount, 
 			   COALESCE(ma.paid_amount, 0), ma.status
 		FROM monthly_assessments ma
 		JOIN resident_units ru ON ma.unit_id = ru.unit_id
@@ -84,7 +96,7 @@ func (r *FinanceRepository) GetAssessments(ctx context.Context, userID string, y
 		  AND ma.period_year = $2
 		ORDER BY ma.period_month DESC
 	`
-	rows, err := r.pool.Query(ctx, query, userID, year)
+	rows, err := r.db(ctx).Query(ctx, query, userID, year)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +105,7 @@ func (r *FinanceRepository) GetAssessments(ctx context.Context, userID string, y
 	var assessments []models.AssessmentSummary
 	for rows.Next() {
 		var a models.AssessmentSummary
-		if err := rows.Scan(&a.ID, &a.Period, &a.BaseAmount, &a.LateFee, &a.TotalAmount, &a.PaidAmount, &a.Status); err != nil {
+		if err := rows.Scan(&a.ID, &a.Period, &a.BaseAmount, &a.ReserveAmount, &a.LateFee, &a.TotalAmount, &a.PaidAmount, &a.Status); err != nil {
 			return nil, err
 		}
 		assessments = append(assessments, a)
@@ -101,77 +113,113 @@ func (r *FinanceRepository) GetAssessments(ctx context.Context, userID string, y
 	return assessments, nil
 }
 
-// GetAssessmentDetails aidat detayı
-func (r *FinanceRepository) GetAssessmentDetails(ctx context.Context, assessmentID string) (*models.AssessmentDetail, error) {
-	// Ana aidat bilgisi
-	query := `
-		SELECT id, property_id, unit_id, period_year, period_month, 
-			   base_amount, late_fee, total_amount, due_date, status, created_at
-		FROM monthly_assessments WHERE id = $1
-	`
+// GetAssessmentDetails aidat detayı. Tahakkuk, kullanıcının sakini olduğu veya aidat ödeme
+// vekaleti aldığı daireye ait olmalı; managedPropertyID verilirse (yönetici) o sitedeki tüm
+// tahakkuklar görülebilir. Sorgular istek kapsamındaki kiracıyla (RLS) çalışır; başka siteye
+// veya kiracıya ait tahakkuk için ErrAssessmentNotFound döner.
+func (r *FinanceRepository) GetAssessmentDetails(ctx context.Context, userID, managedPropertyID, assessmentID string) (*models.AssessmentDetail, error) {
 	detail := &models.AssessmentDetail{}
-	err := r.pool.QueryRow(ctx, query, assessmentID).Scan(
-		&detail.ID, &detail.PropertyID, &detail.UnitID, &detail.PeriodYear, &detail.PeriodMonth,
-		&detail.BaseAmount, &detail.LateFee, &detail.TotalAmount, &detail.DueDate, &detail.Status, &detail.CreatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
+	err := database.InTenantTx(ctx, r.pool, func(tx pgx.Tx) error {
+		// Ana aidat bilgisi
+		query := `
+			SELECT ma.id, ma.property_id, ma.unit_id, ma.period_year, ma.period_month,
+				   ma.base_amount, ma.reserve_amount, ma.late_fee, ma.total_amount, ma.due_date, ma.status, ma.created_at
+			FROM monthly_assessments ma
+			WHERE ma.id::text = $2
+			  AND (($3 <> '' AND ma.property_id::text = $3)
+			       OR ma.unit_id IN (SELECT pu.unit_id FROM (` + payableUnits + `) pu))
+		`
+		err := tx.QueryRow(ctx, query, userID, assessmentID, managedPropertyID).Scan(
+			&detail.ID, &detail.PropertyID, &detail.UnitID, &detail.PeriodYear, &detail.PeriodMonth,
+			&detail.BaseAmount, &detail.ReserveAmount, &detail.LateFee, &detail.TotalAmount, &detail.DueDate, &detail.Status, &detail.CreatedAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAssessmentNotFound
+		}
+		if err != nil {
+			return err
+		}
 
-	// Detay kalemleri
-	detailQuery := `
-		SELECT ec.name, ad.amount, ad.calculation_basis
-		FROM assessment_details ad
-		JOIN expense_categories ec ON ad.expense_category_id = ec.id
-		WHERE ad.assessment_id = $1
-	`
-	rows, err := r.pool.Query(ctx, detailQuery, assessmentID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+		// Detay kalemleri (gider kalemleri ve fon katkı payları)
+		detailQuery := `
+			SELECT COALESCE(ec.name, rf.name), ad.amount, ad.calculation_basis,
+				   COALESCE(ad.expense_category_id::text, ''), COALESCE(ad.reserve_fund_id::text, '')
+			FROM assessment_details ad
+			LEFT JOIN expense_categories ec ON ad.expense_category_id = ec.id
+			LEFT JOIN reserve_funds rf ON ad.reserve_fund_id = rf.id
+			WHERE ad.assessment_id = $1
+			ORDER BY ad.reserve_fund_id NULLS FIRST
+		`
+		rows, err := tx.Query(ctx, detailQuery, detail.ID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	for rows.Next() {
-		var item models.AssessmentDetailItem
-		if err := rows.Scan(&item.Category, &item.Amount, &item.CalculationBasis); err != nil {
-			return nil, err
+		for rows.Next() {
+			var item models.AssessmentDetailItem
+			if err := rows.Scan(&item.Category, &item.Amount, &item.CalculationBasis, &item.CategoryID, &item.ReserveFundID); err != nil {
+				return err
+			}
+			detail.Details = append(detail.Details, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
 		}
-		detail.Details = append(detail.Details, item)
-	}
 
+		// Düzeltme geçmişi
+		detail.Corrections, err = queryCorrections(ctx, tx, `ac.assessment_id = $1`, detail.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 	return detail, nil
 }
 
-// CalculateTotalAmount seçili aidatların toplam tutarı
-func (r *FinanceRepository) CalculateTotalAmount(ctx context.Context, assessmentIDs []string) (float64, error) {
+// CalculateTotalAmount kullanıcının ödeyebileceği seçili aidatların kalan borç toplamı;
+// başka daireye, siteye veya kiracıya ait kimlikler toplama katılmaz
+func (r *FinanceRepository) CalculateTotalAmount(ctx context.Context, userID string, assessmentIDs []string) (float64, error) {
 	query := `
-		SELECT COALESCE(SUM(total_amount - COALESCE(paid_amount, 0)), 0)
-		FROM monthly_assessments
-		WHERE id = ANY($1)
+		SELECT COALESCE(SUM(ma.total_amount - COALESCE(ma.paid_amount, 0)), 0)
+		FROM monthly_assessments ma
+		WHERE ma.id = ANY($2::uuid[])
+		  AND ma.unit_id IN (SELECT pu.unit_id FROM (` + payableUnits + `) pu)
 	`
 	var total float64
-	err := r.pool.QueryRow(ctx, query, assessmentIDs).Scan(&total)
+	err := database.InTenantTx(ctx, r.pool, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, userID, assessmentIDs).Scan(&total)
+	})
 	return total, err
 }
 
-// CreatePayment ödeme kaydı oluşturur
-func (r *FinanceRepository) CreatePayment(ctx context.Context, userID string, assessmentIDs []string, amount float64, method string) (string, error) {
+// CreatePayment bekleyen ödeme kaydını ve planlanan aidat dağılımını istek kapsamındaki
+// kiracıyla (RLS) tek transaction içinde oluşturur. Kesin dağılım ödeme tamamlanırken
+// CompletePayment'ta yapılır.
+func (r *FinanceRepository) CreatePayment(ctx context.Context, userID, unitID string, allocations []models.PaymentAllocation, amount float64, method string) (string, error) {
 	paymentID := uuid.New().String()
-	query := `
-		INSERT INTO payments (id, user_id, amount, payment_method, status, created_at)
-		VALUES ($1, $2, $3, $4, 'PENDING', NOW())
-	`
-	_, err := r.pool.Exec(ctx, query, paymentID, userID, amount, method)
+	err := database.InTenantTx(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO payments (id, user_id, unit_id, amount, payment_method, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, 'PENDING', NOW())
+		`
+		if _, err := tx.Exec(ctx, query, paymentID, userID, unitID, amount, method); err != nil {
+			return err
+		}
+
+		// Ödeme-aidat ilişkisini kaydet
+		for _, a := range allocations {
+			linkQuery := `INSERT INTO payment_assessments (payment_id, assessment_id, amount, late_fee_amount) VALUES ($1, $2, $3, $4)`
+			if _, err := tx.Exec(ctx, linkQuery, paymentID, a.AssessmentID, a.Amount, a.LateFee); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
-
-	// Ödeme-aidat ilişkisini kaydet
-	for _, aID := range assessmentIDs {
-		linkQuery := `INSERT INTO payment_assessments (payment_id, assessment_id) VALUES ($1, $2)`
-		r.pool.Exec(ctx, linkQuery, paymentID, aID)
-	}
-
 	return paymentID, nil
 }
 
@@ -184,7 +232,7 @@ func (r *FinanceRepository) GetPaymentHistory(ctx context.Context, userID string
 		ORDER BY created_at DESC
 		LIMIT 50
 	`
-	rows, err := r.pool.Query(ctx, query, userID)
+	rows, err := r.db(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -221,7 +269,7 @@ func (r *FinanceRepository) GetConsumptionData(ctx context.Context, userID, mete
 		ORDER BY ci.period_start DESC
 		LIMIT $3
 	`
-	rows, err := r.pool.Query(ctx, query, userID, meterType, months)
+	rows, err := r.db(ctx).Query(ctx, query, userID, meterType, months)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// GetLateFeeCandidates vadesi geçmiş ve tamamen ödenmemiş tahakkukları site
+// ayarlarıyla birlikte getirir. Aktif ödeme planındaki tahakkuklar atlanır.
+// propertyID boşsa tüm siteler taranır.
+func (r *FinanceRepository) GetLateFeeCandidates(ctx context.Context, propertyID string, asOf time.Time) ([]models.LateFeeCandidate, error) {
+	query := `
+		SELECT ma.id, ma.property_id, ma.unit_id, ma.period_year, ma.period_month,
+			   ma.base_amount, COALESCE(ma.late_fee, 0), COALESCE(ma.paid_amount, 0),
+			   ma.late_fee_paid, ma.reserve_paid, ma.due_date, ma.late_fee_accrued_through, ma.status,
+			   COALESCE((t.settings->>'late_fee_percentage')::numeric, 5),
+			   COALESCE(t.settings->>'late_fee_method', 'MONTHLY')
+		FROM monthly_assessments ma
+		JOIN properties p ON p.id = ma.property_id
+		LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE ma.status IN ('PENDING', 'PARTIAL', 'OVERDUE')
+		  AND ma.due_date < $1
+		  AND ma.payment_plan_id IS NULL
+		  AND ($2 = '' OR ma.property_id::text = $2)
+		ORDER BY ma.due_date, ma.id
+	`
+	rows, err := r.db(ctx).Query(ctx, query, asOf, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.LateFeeCandidate
+	for rows.Next() {
+		var c models.LateFeeCandidate
+		if err := rows.Scan(&c.AssessmentID, &c.PropertyID, &c.UnitID, &c.PeriodYear, &c.PeriodMonth,
+			&c.BaseAmount, &c.LateFee, &c.PaidAmount, &c.LateFeePaid, &c.ReservePaid, &c.DueDate, &c.AccruedThrough, &c.Status,
+			&c.FeePercentage, &c.FeeMethod); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// ApplyLateFee tazminatı tahakkuka ekler, durumu OVERDUE yapar ve tutarı deftere işler.
+// Güncelleme, okunan late_fee_accrued_through değerine koşullu yapılır; araya başka
+// bir çalıştırma girdiyse hiçbir şey yazılmaz ve false döner (mükerrer tahsil olmaz).
+func (r *FinanceRepository) ApplyLateFee(ctx context.Context, c *models.LateFeeCandidate, fee float64, through time.Time) (bool, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE monthly_assessments
+		SET late_fee = COALESCE(late_fee, 0) + $2,
+			total_amount = total_amount + $2,
+			late_fee_accrued_through = $3,
+			status = 'OVERDUE',
+			updated_at = NOW()
+		WHERE id = $1
+		  AND late_fee_accrued_through IS NOT DISTINCT FROM $4
+		  AND payment_plan_id IS NULL
+		  AND status IN ('PENDING', 'PARTIAL', 'OVERDUE')
+	`, c.AssessmentID, fee, through, c.AccruedThrough)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if fee > 0 {
+		entry := ledger.LateFeeAccrual(c.PropertyID, c.UnitID, c.AssessmentID, fee, through,
+			fmt.Sprintf("%d/%02d dönemi gecikme tazminatı (KMK md. 20)", c.PeriodYear, c.PeriodMonth))
+		if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+			return false, fmt.Errorf("gecikme tazminatı defterlenemedi: %w", err)
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
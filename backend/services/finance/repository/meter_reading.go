@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/siteeksen/backend/services/finance/metering"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrMeterReadingReviewNotFound okuma incelemesi bulunamadı
+var ErrMeterReadingReviewNotFound = errors.New("okuma incelemesi bulunamadı")
+
+// ErrMeterReadingReviewResolved inceleme başka bir istekle onaylanmış / reddedilmiş
+var ErrMeterReadingReviewResolved = errors.New("okuma incelemesi zaten sonuçlandırılmış")
+
+// ErrMeterReadingConflict sayacın incelenen tarihte veya sonrasında okuması var
+var ErrMeterReadingConflict = errors.New("sayacın bu tarihte veya sonrasında kaydedilmiş okuması var")
+
+// ErrMeterReadingRollback onaylanan okuma sayacın son okumasından küçük
+var ErrMeterReadingRollback = errors.New("okuma sayacın son okumasından küçük olamaz; sayaç değiştiyse meter_replaced ile onaylayın")
+
+const meterReadingReviewColumns = `
+	r.id, r.property_id, r.meter_id, m.serial_number, m.meter_type, u.id,
+	COALESCE(u.block, '') || '-' || u.door_number, r.reading_date, r.previous_value, r.reported_value,
+	r.reason, r.message, COALESCE(r.source_row, 0), r.status, COALESCE(r.meter_reading_id::text, ''),
+	COALESCE(r.resolution_note, ''), COALESCE(r.uploaded_by::text, ''), COALESCE(r.reviewed_by::text, ''),
+	r.reviewed_at, r.created_at
+`
+
+const meterReadingReviewFrom = `
+	FROM meter_reading_reviews r
+	JOIN meters m ON m.id = r.meter_id
+	JOIN units u ON u.id = m.unit_id
+`
+
+func scanMeterReadingReview(row pgx.Row) (*models.MeterReadingReview, error) {
+	r := &models.MeterReadingReview{}
+	err := row.Scan(&r.ID, &r.PropertyID, &r.MeterID, &r.SerialNumber, &r.MeterType, &r.UnitID, &r.Unit,
+		&r.ReadingDate, &r.PreviousValue, &r.ReportedValue, &r.Reason, &r.Message, &r.SourceRow, &r.Status,
+		&r.MeterReadingID, &r.ResolutionNote, &r.UploadedBy, &r.ReviewedBy, &r.ReviewedAt, &r.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMeterReadingReviewNotFound
+	}
+	return r, err
+}
+
+// GetMeterReadingTemplateUnits okuma şablonunun satırları: sitenin kullanımdaki sayaçları,
+// dairede oturan sakin (kiracı varsa kiracı) ve son okuma. meterType boşsa tüm sayaçlar.
+func (r *FinanceRepository) GetMeterReadingTemplateUnits(ctx context.Context, propertyID, meterType string) ([]reports.MeterTemplateUnit, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT COALESCE(u.block, '') || '-' || u.door_number,
+			   COALESCE((SELECT us.first_name || ' ' || us.last_name
+						 FROM resident_units ru JOIN users us ON us.id = ru.resident_id
+						 WHERE ru.unit_id = u.id AND ru.is_active = true AND ru.role IN ('OWNER', 'TENANT')
+						 ORDER BY ru.role = 'TENANT' DESC, ru.start_date
+						 LIMIT 1), ''),
+			   m.serial_number,
+			   COALESCE((SELECT mr.current_value FROM meter_readings mr WHERE mr.meter_id = m.id
+						 ORDER BY mr.reading_date DESC, mr.created_at DESC LIMIT 1), 0)
+		FROM meters m
+		JOIN units u ON u.id = m.unit_id
+		WHERE u.property_id = $1 AND m.is_active = true AND ($2 = '' OR m.meter_type = $2)
+		ORDER BY u.block, u.floor, u.door_number, m.meter_type, m.serial_number
+	`, propertyID, meterType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []reports.MeterTemplateUnit
+	for rows.Next() {
+		var u reports.MeterTemplateUnit
+		if err := rows.Scan(&u.UnitName, &u.ResidentName, &u.MeterSerial, &u.LastReading); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// RecordMeterReadings okuma listesini tek transaction'da işler. Her okuma sayacın son
+// okumasına ve geçmiş tüketimine göre doğrulanır (metering.Check); geçen okumalar
+// meter_readings'e yazılır, geçmeyenler incelemeye alınır. Sayacın ilk okuması başlangıç
+// değeri olarak kaydedilir; kaydedilen tüketim tarifesiyle faturalanıp deftere işlenir.
+// Aynı listenin tekrar yüklenmesi kayıtlı okumaları UNCHANGED döner, bekleyen
+// incelemeleri günceller.
+func (r *FinanceRepository) RecordMeterReadings(ctx context.Context, propertyID, userID string, date time.Time, rows []metering.Row) (*models.MeterReadingUpload, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Aynı sitenin eşzamanlı yüklemeleri ve inceleme onayları sırayla işlenir
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('meter_readings:' || $1))`, propertyID); err != nil {
+		return nil, err
+	}
+
+	upload := &models.MeterReadingUpload{ReadingDate: date}
+	for _, row := range rows {
+		result := models.MeterReadingRow{Row: row.Num, SerialNumber: row.SerialNumber, Unit: row.Unit, Value: row.Value}
+		switch {
+		case row.Error != "":
+			result.Status, result.Message = metering.StatusFailed, row.Error
+		case row.Value == nil:
+			result.Status, result.Message = metering.StatusSkipped, "yeni okuma girilmemiş"
+		default:
+			if err := r.recordMeterReading(ctx, tx, propertyID, userID, date, row, &result); err != nil {
+				return nil, fmt.Errorf("%d. satır işlenemedi: %w", row.Num, err)
+			}
+		}
+		upload.Add(result)
+	}
+	return upload, tx.Commit(ctx)
+}
+
+func (r *FinanceRepository) recordMeterReading(ctx context.Context, tx pgx.Tx, propertyID, userID string, date time.Time, row metering.Row, result *models.MeterReadingRow) error {
+	var meterID string
+	var active bool
+	err := tx.QueryRow(ctx, `
+		SELECT m.id, COALESCE(u.block, '') || '-' || u.door_number, m.is_active
+		FROM meters m
+		JOIN units u ON u.id = m.unit_id
+		WHERE u.property_id = $1 AND upper(m.serial_number) = upper($2)
+	`, propertyID, row.SerialNumber).Scan(&meterID, &result.Unit, &active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		result.Status, result.Message = metering.StatusFailed, "sayaç bulunamadı"
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		result.Status, result.Message = metering.StatusFailed, "sayaç kullanımda değil"
+		return nil
+	}
+
+	value := *row.Value
+	latestID, prev, history, err := meterReadingHistory(ctx, tx, meterID)
+	if err != nil {
+		return err
+	}
+	if latestID == "" {
+		// İlk okuma: önceki değer bilinmediğinden tüketim sıfır kabul edilir
+		if err := r.insertMeterReading(ctx, tx, meterID, userID, date, value, value, result); err != nil {
+			return err
+		}
+		result.Message = "ilk okuma, başlangıç değeri olarak kaydedildi"
+		return closePendingReviews(ctx, tx, meterID, userID, date)
+	}
+
+	result.PreviousValue = &prev.Value
+	if !prev.Date.Before(date) {
+		if prev.Date.Equal(date) && prev.Value == value {
+			result.Status, result.ReadingID = metering.StatusUnchanged, latestID
+			return nil
+		}
+		result.Status = metering.StatusFailed
+		result.Message = fmt.Sprintf("sayacın %s tarihli okuması var", prev.Date.Format("02.01.2006"))
+		return nil
+	}
+
+	if issue := metering.Check(prev, history, value, date); issue != nil {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO meter_reading_reviews
+				(property_id, meter_id, reading_date, previous_value, reported_value, reason, message, source_row, uploaded_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)
+			ON CONFLICT (meter_id, reading_date) WHERE status = 'PENDING' DO UPDATE
+			SET previous_value = EXCLUDED.previous_value, reported_value = EXCLUDED.reported_value,
+				reason = EXCLUDED.reason, message = EXCLUDED.message, source_row = EXCLUDED.source_row,
+				uploaded_by = EXCLUDED.uploaded_by, created_at = NOW()
+			RETURNING id
+		`, propertyID, meterID, date, prev.Value, value, issue.Reason, issue.Message, row.Num, userID).Scan(&result.ReviewID)
+		if err != nil {
+			return fmt.Errorf("okuma incelemeye alınamadı: %w", err)
+		}
+		result.Status, result.Reason, result.Message = metering.StatusQueued, issue.Reason, issue.Message
+		return nil
+	}
+
+	if err := r.insertMeterReading(ctx, tx, meterID, userID, date, prev.Value, value, result); err != nil {
+		return err
+	}
+	return closePendingReviews(ctx, tx, meterID, userID, date)
+}
+
+// meterReadingHistory sayacın son okumasını ve son metering.HistorySize okumanın
+// tüketimlerini (önceki okumadan geçen gün sayısıyla) getirir; okuma yoksa latestID boştur
+func meterReadingHistory(ctx context.Context, q database.Querier, meterID string) (latestID string, prev metering.Previous, history []metering.Usage, err error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, reading_date, current_value, consumption,
+			   COALESCE(reading_date - LAG(reading_date) OVER (ORDER BY reading_date, created_at), 0)
+		FROM meter_readings
+		WHERE meter_id = $1
+		ORDER BY reading_date DESC, created_at DESC
+		LIMIT $2
+	`, meterID, metering.HistorySize)
+	if err != nil {
+		return "", prev, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var date time.Time
+		var value float64
+		var usage metering.Usage
+		if err := rows.Scan(&id, &date, &value, &usage.Consumption, &usage.Days); err != nil {
+			return "", prev, nil, err
+		}
+		if latestID == "" {
+			latestID, prev = id, metering.Previous{Date: date, Value: value}
+		}
+		history = append(history, usage)
+	}
+	return latestID, prev, history, rows.Err()
+}
+
+// insertMeterReading okumayı kaydeder ve tüketimi faturalar (billMeterReading)
+func (r *FinanceRepository) insertMeterReading(ctx context.Context, tx pgx.Tx, meterID, readerID string, date time.Time, previous, value float64, result *models.MeterReadingRow) error {
+	var consumption float64
+	err := tx.QueryRow(ctx, `
+		INSERT INTO meter_readings (meter_id, reading_date, previous_value, current_value, reading_type, reader_user_id)
+		VALUES ($1, $2, $3, $4, 'MANUAL', NULLIF($5, '')::uuid)
+		RETURNING id, consumption
+	`, meterID, date, previous, value, readerID).Scan(&result.ReadingID, &consumption)
+	if err != nil {
+		return fmt.Errorf("okuma kaydedilemedi: %w", err)
+	}
+	result.Status, result.Consumption = metering.StatusRecorded, &consumption
+	return r.billMeterReading(ctx, tx, meterID, date, consumption, result)
+}
+
+// billMeterReading okumanın tüketimini sayaç türünün okuma tarihinde geçerli tarifesiyle
+// faturalar: consumption_invoices kaydı açılır ve aynı transaction'da 120 / 601 (SAYAC)
+// olarak deftere işlenir. Tüketim yoksa (ilk okuma, sayaç değişimi) veya tarife
+// tanımlı değilse fatura kesilmez.
+func (r *FinanceRepository) billMeterReading(ctx context.Context, tx pgx.Tx, meterID string, date time.Time, consumption float64, result *models.MeterReadingRow) error {
+	if consumption <= 0 {
+		return nil
+	}
+	var tariff metering.Tariff
+	var propertyID, unitID, unitName, meterType string
+	err := tx.QueryRow(ctx, `
+		SELECT t.id, t.unit_price, COALESCE(t.fixed_fee, 0), COALESCE(t.tax_rate, 0),
+			   u.property_id, u.id, COALESCE(u.block, '') || '-' || u.door_number, m.meter_type
+		FROM meters m
+		JOIN units u ON u.id = m.unit_id
+		JOIN consumption_tariffs t ON t.property_id = u.property_id AND t.meter_type = m.meter_type
+		WHERE m.id = $1 AND t.effective_from <= $2 AND (t.effective_to IS NULL OR t.effective_to >= $2)
+		ORDER BY t.effective_from DESC
+		LIMIT 1
+	`, meterID, date).Scan(&tariff.ID, &tariff.UnitPrice, &tariff.FixedFee, &tariff.TaxRate,
+		&propertyID, &unitID, &unitName, &meterType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	charge := metering.Bill(consumption, tariff)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO consumption_invoices
+			(meter_reading_id, unit_id, meter_id, tariff_id, period_start, period_end, consumption_amount,
+			 unit_price, subtotal, tax_amount, total_amount, invoice_date)
+		VALUES ($1, $2, $3, $4,
+				(SELECT MAX(reading_date) FROM meter_readings WHERE meter_id = $3 AND reading_date < $5),
+				$5, $6, $7, $8, $9, $10, $5)
+		RETURNING id
+	`, result.ReadingID, unitID, meterID, tariff.ID, date, consumption, tariff.UnitPrice,
+		charge.Subtotal, charge.Tax, charge.Total).Scan(&result.InvoiceID)
+	if err != nil {
+		return fmt.Errorf("tüketim faturası oluşturulamadı: %w", err)
+	}
+	if charge.Total > 0 {
+		entry := ledger.MeterBilling(propertyID, unitID, result.InvoiceID, charge.Total, date,
+			fmt.Sprintf("%s tüketim faturası (%s birim) - %s", meterType, metering.Format(consumption), unitName))
+		if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+			return fmt.Errorf("tüketim faturası defterlenemedi: %w", err)
+		}
+	}
+	result.Charge = &charge.Total
+	return nil
+}
+
+// closePendingReviews düzeltilmiş okuma kaydedilince aynı sayaç ve tarihin bekleyen
+// incelemesini kapatır
+func closePendingReviews(ctx context.Context, tx pgx.Tx, meterID, userID string, date time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE meter_reading_reviews
+		SET status = 'REJECTED', resolution_note = 'Düzeltilmiş okuma yüklendi',
+			reviewed_by = NULLIF($3, '')::uuid, reviewed_at = NOW()
+		WHERE meter_id = $1 AND reading_date = $2 AND status = 'PENDING'
+	`, meterID, date, userID)
+	return err
+}
+
+// ListMeterReadingReviews sitenin okuma incelemeleri (status boşsa filtrelenmez)
+func (r *FinanceRepository) ListMeterReadingReviews(ctx context.Context, propertyID, status string) ([]models.MeterReadingReview, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+meterReadingReviewColumns+meterReadingReviewFrom+`
+		WHERE r.property_id = $1 AND ($2 = '' OR r.status = $2)
+		ORDER BY r.created_at DESC, r.source_row
+	`, propertyID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.MeterReadingReview
+	for rows.Next() {
+		review, err := scanMeterReadingReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+	return reviews, rows.Err()
+}
+
+// ApproveMeterReadingReview bekleyen incelemeyi onaylar ve okumayı kaydeder. value verilirse
+// listedeki okuma yerine düzeltilmiş değer yazılır. Okuma sayacın güncel son okumasına göre
+// yazılır; meterReplaced ise sayaç değişmiş sayılır ve okuma yeni sayacın başlangıç değeri
+// olarak (tüketimsiz) kaydedilir. Sıçrama onayla kabul edilir, geri giden okuma kabul edilmez.
+func (r *FinanceRepository) ApproveMeterReadingReview(ctx context.Context, propertyID, reviewID, userID string, value *float64, meterReplaced bool, note string) (*models.MeterReadingReview, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('meter_readings:' || $1))`, propertyID); err != nil {
+		return nil, err
+	}
+	review, err := scanMeterReadingReview(tx.QueryRow(ctx, `
+		SELECT `+meterReadingReviewColumns+meterReadingReviewFrom+`
+		WHERE r.id = $1 AND r.property_id = $2
+		FOR UPDATE OF r
+	`, reviewID, propertyID))
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != "PENDING" {
+		return nil, ErrMeterReadingReviewResolved
+	}
+
+	reading := review.ReportedValue
+	if value != nil {
+		reading = metering.Round(*value)
+	}
+	latestID, prev, _, err := meterReadingHistory(ctx, tx, review.MeterID)
+	if err != nil {
+		return nil, err
+	}
+	previous := reading
+	if latestID != "" {
+		if !prev.Date.Before(review.ReadingDate) {
+			return nil, ErrMeterReadingConflict
+		}
+		if !meterReplaced {
+			if reading < prev.Value {
+				return nil, ErrMeterReadingRollback
+			}
+			previous = prev.Value
+		}
+	}
+
+	var result models.MeterReadingRow
+	if err := r.insertMeterReading(ctx, tx, review.MeterID, review.UploadedBy, review.ReadingDate, previous, reading, &result); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE meter_reading_reviews
+		SET status = 'APPROVED', meter_reading_id = $2, resolution_note = NULLIF($3, ''),
+			reviewed_by = NULLIF($4, '')::uuid, reviewed_at = NOW()
+		WHERE id = $1
+		RETURNING reviewed_at
+	`, review.ID, result.ReadingID, note, userID).Scan(&review.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	review.Status, review.MeterReadingID, review.ResolutionNote, review.ReviewedBy = "APPROVED", result.ReadingID, note, userID
+	return review, tx.Commit(ctx)
+}
+
+// RejectMeterReadingReview bekleyen incelemeyi reddeder; okuma kaydedilmez
+func (r *FinanceRepository) RejectMeterReadingReview(ctx context.Context, propertyID, reviewID, userID, note string) (*models.MeterReadingReview, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE meter_reading_reviews
+		SET status = 'REJECTED', resolution_note = NULLIF($3, ''), reviewed_by = NULLIF($4, '')::uuid, reviewed_at = NOW()
+		WHERE id = $1 AND property_id = $2 AND status = 'PENDING'
+	`, reviewID, propertyID, note, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := scanMeterReadingReview(r.db(ctx).QueryRow(ctx, `
+		SELECT `+meterReadingReviewColumns+meterReadingReviewFrom+`
+		WHERE r.id = $1 AND r.property_id = $2
+	`, reviewID, propertyID))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrMeterReadingReviewResolved
+	}
+	return review, nil
+}
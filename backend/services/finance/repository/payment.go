@@ -0,0 +1,588 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/services/finance/allocation"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrPaymentNotFound ödeme kaydı bulunamadı
+var ErrPaymentNotFound = errors.New("ödeme bulunamadı")
+
+// ErrUnitNotFound sakine ait aktif daire bulunamadı
+var ErrUnitNotFound = errors.New("aktif daire bulunamadı")
+
+// payableUnits kullanıcının ($1) ödeme yapabileceği daireler: aktif sakini olduğu daireler
+// ve aidat ödeme (PAY_DUES) kapsamlı geçerli vekaletleri
+const payableUnits = `
+	SELECT ru.unit_id, ru.created_at, CASE WHEN ru.role = 'OWNER' THEN 0 ELSE 1 END AS priority
+	FROM resident_units ru
+	WHERE ru.resident_id = $1 AND ru.is_active = true
+	UNION ALL
+	SELECT p.unit_id, p.created_at, 2
+	FROM active_unit_proxies p
+	WHERE p.proxy_id = $1 AND '` + proxy.ScopePayDues + `' = ANY(p.scopes)`
+
+// GetPayableAssessments sakinin aktif dairelerine ve aidat ödeme vekaleti aldığı dairelere
+// ait açık tahakkukları vade sırasıyla getirir. assessmentIDs boşsa unitID dairesinin tüm
+// açık tahakkukları döner. Başka daireye veya kiracıya (RLS) ait ya da tamamen ödenmiş
+// tahakkuklar sonuçta yer almaz.
+func (r *FinanceRepository) GetPayableAssessments(ctx context.Context, userID, unitID string, assessmentIDs []string) ([]models.PayableAssessment, error) {
+	query := `
+		SELECT ma.id, ma.property_id, ma.unit_id, ma.period_year, ma.period_month, ma.due_date,
+			   ma.total_amount - COALESCE(ma.paid_amount, 0),
+			   GREATEST(COALESCE(ma.late_fee, 0) - ma.late_fee_paid, 0)
+		FROM monthly_assessments ma
+		WHERE ma.unit_id IN (SELECT pu.unit_id FROM (` + payableUnits + `) pu)
+		  AND (COALESCE(cardinality($2::uuid[]), 0) = 0 OR ma.id = ANY($2::uuid[]))
+		  AND ($3 = '' OR ma.unit_id::text = $3)
+		  AND ma.status <> 'PAID'
+		  AND ma.total_amount > COALESCE(ma.paid_amount, 0)
+		ORDER BY ma.due_date, ma.id
+	`
+	var assessments []models.PayableAssessment
+	err := database.InTenantTx(ctx, r.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID, assessmentIDs, unitID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var a models.PayableAssessment
+			if err := rows.Scan(&a.ID, &a.PropertyID, &a.UnitID, &a.PeriodYear, &a.PeriodMonth, &a.DueDate,
+				&a.Outstanding, &a.LateFeeDue); err != nil {
+				return err
+			}
+			assessments = append(assessments, a)
+		}
+		return rows.Err()
+	})
+	return assessments, err
+}
+
+// GetResidentUnit sakinin ödeme yapabileceği daireyi ve sitesini getirir.
+// unitID boşsa malik olarak kayıtlı ilk daire, yoksa sakini olduğu ilk daire seçilir;
+// vekaletle ödenen daireler yalnızca unitID ile açıkça seçilir veya başka daire yoksa gelir.
+func (r *FinanceRepository) GetResidentUnit(ctx context.Context, userID, unitID string) (string, string, error) {
+	query := `
+		SELECT u.id, u.property_id
+		FROM (` + payableUnits + `) pu
+		JOIN units u ON u.id = pu.unit_id
+		WHERE ($2 = '' OR u.id::text = $2)
+		ORDER BY pu.priority, pu.created_at
+		LIMIT 1
+	`
+	var id, propertyID string
+	err := r.db(ctx).QueryRow(ctx, query, userID, unitID).Scan(&id, &propertyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", ErrUnitNotFound
+	}
+	return id, propertyID, err
+}
+
+// GetAllocationPolicy sitenin ödeme dağıtım politikasını getirir
+func (r *FinanceRepository) GetAllocationPolicy(ctx context.Context, propertyID string) (allocation.Policy, error) {
+	return allocationPolicy(ctx, r.db(ctx), propertyID)
+}
+
+// GetPayer ödeme yapan kullanıcının alıcı bilgileri. Telefon şifreli saklanır; henüz
+// yeniden şifrelenmemiş kayıtlarda düz metin sütun kullanılır.
+func (r *FinanceRepository) GetPayer(ctx context.Context, userID string) (*models.Payer, error) {
+	query := `
+		SELECT id, first_name, last_name, COALESCE(email, ''), COALESCE(phone, ''), COALESCE(phone_encrypted, '')
+		FROM users
+		WHERE id = $1 AND is_active = true
+	`
+	p := &models.Payer{}
+	var phoneEncrypted string
+	err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&p.ID, &p.FirstName, &p.LastName, &p.Email, &p.Phone,
+		&phoneEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	if phoneEncrypted != "" {
+		if p.Phone, err = r.pii.Decrypt(phoneEncrypted); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// GetPayment ödeme kaydını getirir
+func (r *FinanceRepository) GetPayment(ctx context.Context, paymentID string) (*models.Payment, error) {
+	query := `
+		SELECT p.id, p.user_id, COALESCE(p.unit_id::text, ''), COALESCE(u.property_id::text, ''),
+			   p.amount, p.credit_amount, p.payment_method, p.status,
+			   COALESCE(p.transaction_id, ''), COALESCE(p.paid_amount, 0), p.created_at, p.completed_at
+		FROM payments p
+		LEFT JOIN units u ON u.id = p.unit_id
+		WHERE p.id = $1
+	`
+	p := &models.Payment{}
+	var completedAt *time.Time
+	err := r.db(ctx).QueryRow(ctx, query, paymentID).Scan(&p.ID, &p.UserID, &p.UnitID, &p.PropertyID, &p.Amount,
+		&p.CreditAmount, &p.PaymentMethod, &p.Status, &p.TransactionID, &p.PaidAmount, &p.CreatedAt, &completedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if completedAt != nil {
+		p.CompletedAt = *completedAt
+	}
+	return p, nil
+}
+
+// FailPayment bekleyen ödemeyi FAILED yapar ve gateway yanıtını saklar
+func (r *FinanceRepository) FailPayment(ctx context.Context, paymentID string, gatewayResponse []byte) error {
+	_, err := r.db(ctx).Exec(ctx, `
+		UPDATE payments SET status = 'FAILED', gateway_response = $2::jsonb
+		WHERE id = $1 AND status = 'PENDING'
+	`, paymentID, jsonOrNull(gatewayResponse))
+	return err
+}
+
+// HoldMismatchedPayment tahsil edilen tutarı beklenenden farklı bekleyen ödemeyi
+// AMOUNT_MISMATCH yapar. İade ve inceleme için sağlayıcı işlem numarası, tahsil edilen
+// tutar ve gateway yanıtı saklanır; tutar tahakkuklara dağıtılmaz.
+func (r *FinanceRepository) HoldMismatchedPayment(ctx context.Context, paymentID, transactionID string, paidAmount float64, gatewayResponse []byte) error {
+	_, err := r.db(ctx).Exec(ctx, `
+		UPDATE payments
+		SET status = 'AMOUNT_MISMATCH', transaction_id = NULLIF($2, ''), paid_amount = $3, gateway_response = $4::jsonb
+		WHERE id = $1 AND status = 'PENDING'
+	`, paymentID, transactionID, paidAmount, jsonOrNull(gatewayResponse))
+	return err
+}
+
+// RefundMismatchedPayment sağlayıcıda iade edilen tutar uyuşmazlığı ödemesini REFUNDED yapar.
+// Ödeme tahakkuklara dağıtılmadığı ve deftere işlenmediği için geri alınacak kayıt yoktur.
+func (r *FinanceRepository) RefundMismatchedPayment(ctx context.Context, paymentID string, gatewayResponse []byte) error {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE payments
+		SET status = 'REFUNDED', refunded_at = NOW(),
+			gateway_response = COALESCE(gateway_response, '{}'::jsonb) || jsonb_build_object('refund', $2::jsonb)
+		WHERE id = $1 AND status = 'AMOUNT_MISMATCH'
+	`, paymentID, jsonOrNull(gatewayResponse))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPaymentNotFound
+	}
+	return nil
+}
+
+// ListMismatchedPayments sitenin incelemede bekleyen (iadesi yapılamamış) tutar uyuşmazlığı ödemeleri
+func (r *FinanceRepository) ListMismatchedPayments(ctx context.Context, propertyID string) ([]models.Payment, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT p.id, p.user_id, p.unit_id::text, u.property_id::text, p.amount, p.payment_method, p.status,
+			   COALESCE(p.transaction_id, ''), COALESCE(p.paid_amount, 0), p.created_at
+		FROM payments p
+		JOIN units u ON u.id = p.unit_id
+		WHERE u.property_id = $1 AND p.status = 'AMOUNT_MISMATCH'
+		ORDER BY p.created_at
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.ID, &p.UserID, &p.UnitID, &p.PropertyID, &p.Amount, &p.PaymentMethod, &p.Status,
+			&p.TransactionID, &p.PaidAmount, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// CompletePayment ödemeyi COMPLETED yapar, tutarı tahakkuklara dağıtır ve TAHSILAT kaydını atar.
+// Tüm işlemler tek transaction içindedir; ödeme zaten tamamlanmışsa hiçbir şey yapılmaz.
+// Dağıtım, ödemeye bağlı tahakkukların tamamlanma anındaki borçlarına göre site
+// politikasıyla yeniden hesaplanır; bekleme süresince başka bir ödemeyle kapanan
+// borçlar atlanır. Borçları aşan kısım daire avansı (340) olarak deftere işlenir.
+func (r *FinanceRepository) CompletePayment(ctx context.Context, paymentID, transactionID string, gatewayResponse []byte) ([]models.PaymentAllocation, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status, unitID, propertyID string
+	var amount float64
+	err = tx.QueryRow(ctx, `
+		SELECT p.status, p.unit_id, u.property_id, p.amount
+		FROM payments p
+		JOIN units u ON u.id = p.unit_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, paymentID).Scan(&status, &unitID, &propertyID, &amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "COMPLETED" {
+		return nil, nil
+	}
+	if status != "PENDING" {
+		return nil, fmt.Errorf("%s durumundaki ödeme tamamlanamaz", status)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT pa.assessment_id, ma.due_date,
+			   GREATEST(COALESCE(ma.late_fee, 0) - ma.late_fee_paid, 0),
+			   GREATEST((ma.total_amount - COALESCE(ma.late_fee, 0)) - (COALESCE(ma.paid_amount, 0) - ma.late_fee_paid), 0),
+			   GREATEST(ma.reserve_amount - ma.reserve_paid, 0)
+		FROM payment_assessments pa
+		JOIN monthly_assessments ma ON ma.id = pa.assessment_id
+		WHERE pa.payment_id = $1
+		ORDER BY ma.due_date, ma.id
+		FOR UPDATE OF ma
+	`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	var targets []allocation.Target
+	for rows.Next() {
+		var t allocation.Target
+		if err := rows.Scan(&t.AssessmentID, &t.DueDate, &t.LateFeeDue, &t.PrincipalDue, &t.ReserveDue); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	policy, err := allocationPolicy(ctx, tx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	result := allocation.Allocate(amount, targets, policy)
+
+	lines := make(map[string]allocation.Line, len(result.Lines))
+	for _, l := range result.Lines {
+		lines[l.AssessmentID] = l
+	}
+	allocations := make([]models.PaymentAllocation, 0, len(result.Lines))
+	for _, t := range targets {
+		l := lines[t.AssessmentID]
+		if _, err := tx.Exec(ctx, `
+			UPDATE payment_assessments SET amount = $3, late_fee_amount = $4, reserve_amount = $5
+			WHERE payment_id = $1 AND assessment_id = $2
+		`, paymentID, t.AssessmentID, l.Amount(), l.LateFee, l.Reserve); err != nil {
+			return nil, err
+		}
+		if l.Amount() == 0 {
+			continue
+		}
+		if err := adjustAssessmentPaid(ctx, tx, t.AssessmentID, l.Amount(), l.LateFee, l.Reserve); err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, models.PaymentAllocation{
+			AssessmentID: t.AssessmentID,
+			Amount:       l.Amount(),
+			LateFee:      l.LateFee,
+			Reserve:      l.Reserve,
+		})
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE payments
+		SET status = 'COMPLETED', transaction_id = $2, gateway_response = $3::jsonb,
+			credit_amount = $4, completed_at = NOW()
+		WHERE id = $1
+	`, paymentID, transactionID, jsonOrNull(gatewayResponse), result.Credit)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := ledger.CollectionWithAdvance(propertyID, unitID, paymentID, result.Applied(), result.Credit,
+		ledger.AccountBank, time.Now(), "Kredi kartı tahsilatı (iyzico "+transactionID+")")
+	if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+		return nil, fmt.Errorf("tahsilat defterlenemedi: %w", err)
+	}
+
+	return allocations, tx.Commit(ctx)
+}
+
+// RefundPayment iade edilen ödemenin etkisini geri alır: tahakkuklara dağıtılan tutarlar
+// ve durumlar geri çekilir, TAHSILAT kaydı ters kayıtla iptal edilir ve ödeme REFUNDED
+// olur. Ödemeden doğan avans yeni tahakkuklara mahsup edilmişse, eksik kalan kısım için
+// en son mahsuplar geri alınır ve ilgili tahakkuklar yeniden borçlandırılır.
+func (r *FinanceRepository) RefundPayment(ctx context.Context, paymentID, createdBy string, gatewayResponse []byte) (*models.PaymentRefund, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status, unitID, propertyID string
+	var amount, credit float64
+	err = tx.QueryRow(ctx, `
+		SELECT p.status, p.unit_id, u.property_id, p.amount, p.credit_amount
+		FROM payments p
+		JOIN units u ON u.id = p.unit_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, paymentID).Scan(&status, &unitID, &propertyID, &amount, &credit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != "COMPLETED" {
+		return nil, fmt.Errorf("%s durumundaki ödeme iade edilemez", status)
+	}
+	if err := lockUnit(ctx, tx, unitID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT pa.assessment_id, pa.amount, pa.late_fee_amount, pa.reserve_amount
+		FROM payment_assessments pa
+		JOIN monthly_assessments ma ON ma.id = pa.assessment_id
+		WHERE pa.payment_id = $1 AND pa.amount > 0
+		ORDER BY ma.due_date, ma.id
+		FOR UPDATE OF ma
+	`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	refund := &models.PaymentRefund{PaymentID: paymentID, Amount: amount, CreditReversed: credit}
+	for rows.Next() {
+		var a models.PaymentAllocation
+		if err := rows.Scan(&a.AssessmentID, &a.Amount, &a.LateFee, &a.Reserve); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refund.Allocations = append(refund.Allocations, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range refund.Allocations {
+		if err := adjustAssessmentPaid(ctx, tx, a.AssessmentID, -a.Amount, -a.LateFee, -a.Reserve); err != nil {
+			return nil, err
+		}
+	}
+
+	if credit > 0 {
+		balance, err := r.ledger.UnitAccountBalanceTx(ctx, tx, unitID, ledger.AccountAdvances)
+		if err != nil {
+			return nil, err
+		}
+		// Avans hesabı alacak bakiyelidir; mevcut avans iade edilen avanstan azsa fark mahsuptan geri alınır
+		if shortfall := toKurus(credit) - toKurus(math.Max(-balance, 0)); shortfall > 0 {
+			reopened, err := r.releaseUnitCredit(ctx, tx, propertyID, unitID, float64(shortfall)/100, createdBy)
+			if err != nil {
+				return nil, err
+			}
+			refund.ReopenedAssessments = reopened
+		}
+	}
+
+	entries, err := r.ledger.FindBySource(ctx, tx, ledger.DocCollection, "payment", paymentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if _, err := r.ledger.ReverseTx(ctx, tx, &entries[i], "Ödeme iadesi", createdBy); err != nil {
+			return nil, fmt.Errorf("tahsilat ters kaydı atılamadı: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE payments
+		SET status = 'REFUNDED', refunded_at = NOW(),
+			gateway_response = COALESCE(gateway_response, '{}'::jsonb) || jsonb_build_object('refund', $2::jsonb)
+		WHERE id = $1
+	`, paymentID, jsonOrNull(gatewayResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, tx.Commit(ctx)
+}
+
+// releaseUnitCredit en son yapılan avans mahsuplarını amount kadar geri alır.
+// Tahakkukların ödenen tutarı düşülür ve 120 / 340 düzeltme kaydı atılır. Mahsup
+// tahakkuk içinde önce aidata sayıldığından geri alırken önce fon katkısı açılır.
+func (r *FinanceRepository) releaseUnitCredit(ctx context.Context, tx pgx.Tx, propertyID, unitID string, amount float64, createdBy string) ([]models.PaymentAllocation, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, assessment_id, amount - reversed_amount, reserve_amount - reserve_reversed
+		FROM unit_credit_applications
+		WHERE unit_id = $1 AND amount > reversed_amount
+		ORDER BY created_at DESC, id
+	`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	type application struct {
+		id, assessmentID string
+		open, reserve    float64
+	}
+	var applications []application
+	for rows.Next() {
+		var a application
+		if err := rows.Scan(&a.id, &a.assessmentID, &a.open, &a.reserve); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		applications = append(applications, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	remaining := toKurus(amount)
+	var released []models.PaymentAllocation
+	for _, a := range applications {
+		if remaining == 0 {
+			break
+		}
+		take := toKurus(a.open)
+		if take > remaining {
+			take = remaining
+		}
+		remaining -= take
+		value := float64(take) / 100
+		reserve := float64(min(take, toKurus(a.reserve))) / 100
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE unit_credit_applications
+			SET reversed_amount = reversed_amount + $2, reserve_reversed = reserve_reversed + $3
+			WHERE id = $1
+		`, a.id, value, reserve); err != nil {
+			return nil, err
+		}
+		if err := adjustAssessmentPaid(ctx, tx, a.assessmentID, -value, 0, -reserve); err != nil {
+			return nil, err
+		}
+
+		entry := ledger.Correction(propertyID, "advance_application", a.assessmentID, []ledger.Line{
+			{AccountCode: ledger.AccountResidentReceivable, UnitID: unitID, Debit: value},
+			{AccountCode: ledger.AccountAdvances, UnitID: unitID, Credit: value},
+		}, time.Now(), "İade edilen avansın mahsubu geri alındı", createdBy)
+		if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+			return nil, fmt.Errorf("avans mahsubu geri alınamadı: %w", err)
+		}
+		released = append(released, models.PaymentAllocation{AssessmentID: a.assessmentID, Amount: value, Reserve: reserve})
+	}
+	return released, nil
+}
+
+// applyUnitCredit dairenin avans bakiyesini (340) yeni tahakkuka mahsup eder ve
+// mahsup edilen tutarı döner. amount tahakkukun tamamı, reserve bunun fon katkı
+// payı kısmıdır; mahsup önce aidata sayılır. Aynı dairede eşzamanlı iadelerle
+// yarışmamak için daire satırı kilitlenir.
+func (r *FinanceRepository) applyUnitCredit(ctx context.Context, tx pgx.Tx, propertyID, unitID, assessmentID string, amount, reserve float64, date time.Time, description string) (float64, error) {
+	if err := lockUnit(ctx, tx, unitID); err != nil {
+		return 0, err
+	}
+	balance, err := r.ledger.UnitAccountBalanceTx(ctx, tx, unitID, ledger.AccountAdvances)
+	if err != nil {
+		return 0, err
+	}
+	applied := math.Min(-balance, amount)
+	if applied <= 0 {
+		return 0, nil
+	}
+
+	reserveApplied := float64(allocation.ReserveShare(toKurus(applied), toKurus(amount), toKurus(reserve))) / 100
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO unit_credit_applications (unit_id, assessment_id, amount, reserve_amount) VALUES ($1, $2, $3, $4)
+	`, unitID, assessmentID, applied, reserveApplied); err != nil {
+		return 0, err
+	}
+	if err := adjustAssessmentPaid(ctx, tx, assessmentID, applied, 0, reserveApplied); err != nil {
+		return 0, err
+	}
+	entry := ledger.AdvanceApplication(propertyID, unitID, assessmentID, applied, date, description)
+	if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+		return 0, fmt.Errorf("avans mahsubu defterlenemedi: %w", err)
+	}
+	return applied, nil
+}
+
+// assessmentStatusSQL ödenen tutara göre tahakkuk durumu: tamamı ödenmişse PAID, vadesi
+// geçmişse OVERDUE (tazminat işlemeye devam eder), kısmen ödenmişse PARTIAL, değilse PENDING
+const assessmentStatusSQL = `CASE
+	WHEN COALESCE(paid_amount, 0) >= total_amount THEN 'PAID'
+	WHEN due_date < CURRENT_DATE THEN 'OVERDUE'
+	WHEN COALESCE(paid_amount, 0) > 0 THEN 'PARTIAL'
+	ELSE 'PENDING'
+END`
+
+// adjustAssessmentPaid tahakkukun ödenen tutarını (ve tazminata / fon katkısına sayılan
+// kısımlarını) değiştirir, ardından durumu yeniden hesaplar. İadelerde negatif tutarlarla çağrılır.
+func adjustAssessmentPaid(ctx context.Context, q database.Querier, assessmentID string, amount, lateFee, reserve float64) error {
+	_, err := q.Exec(ctx, `
+		UPDATE monthly_assessments
+		SET paid_amount = COALESCE(paid_amount, 0) + $2,
+			late_fee_paid = late_fee_paid + $3,
+			reserve_paid = reserve_paid + $4,
+			updated_at = NOW()
+		WHERE id = $1
+	`, assessmentID, amount, lateFee, reserve)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `UPDATE monthly_assessments SET status = `+assessmentStatusSQL+` WHERE id = $1`, assessmentID)
+	return err
+}
+
+// allocationPolicy site ayarındaki ödeme dağıtım politikası
+func allocationPolicy(ctx context.Context, q database.Querier, propertyID string) (allocation.Policy, error) {
+	var policy string
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(t.settings->>'payment_allocation_policy', '')
+		FROM properties p
+		LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = $1
+	`, propertyID).Scan(&policy)
+	if err != nil {
+		return "", err
+	}
+	return allocation.ParsePolicy(policy), nil
+}
+
+func lockUnit(ctx context.Context, q database.Querier, unitID string) error {
+	var id string
+	return q.QueryRow(ctx, `SELECT id FROM units WHERE id = $1 FOR UPDATE`, unitID).Scan(&id)
+}
+
+func toKurus(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func jsonOrNull(raw []byte) *string {
+	if len(raw) == 0 {
+		return nil
+	}
+	s := string(raw)
+	return &s
+}
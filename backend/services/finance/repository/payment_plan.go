@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrPaymentPlanNotFound ödeme planı bulunamadı
+var ErrPaymentPlanNotFound = errors.New("ödeme planı bulunamadı")
+
+// ErrPlanAssessmentsChanged plan hazırlanırken tahakkuklardan biri ödendi, tazminat
+// işletildi veya başka bir plana alındı
+var ErrPlanAssessmentsChanged = errors.New("tahakkuklar plan hazırlanırken değişti, lütfen tekrar deneyin")
+
+// GetPlanCandidates dairenin plana alınmak istenen tahakkuklarını güncel açık
+// tutarlarıyla getirir. Başka daireye veya siteye ait tahakkuklar sonuçta yer almaz.
+func (r *FinanceRepository) GetPlanCandidates(ctx context.Context, propertyID, unitID string, assessmentIDs []string) ([]models.PaymentPlanAssessment, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT ma.id, TO_CHAR(MAKE_DATE(ma.period_year, ma.period_month, 1), 'YYYY-MM'), ma.due_date,
+			   ma.total_amount - COALESCE(ma.paid_amount, 0),
+			   GREATEST(COALESCE(ma.late_fee, 0) - ma.late_fee_paid, 0),
+			   COALESCE(ma.paid_amount, 0), COALESCE(ma.payment_plan_id::text, '')
+		FROM monthly_assessments ma
+		WHERE ma.property_id = $1 AND ma.unit_id = $2 AND ma.id = ANY($3::uuid[])
+		ORDER BY ma.due_date, ma.id
+	`, propertyID, unitID, assessmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.PaymentPlanAssessment
+	for rows.Next() {
+		var a models.PaymentPlanAssessment
+		if err := rows.Scan(&a.AssessmentID, &a.Period, &a.DueDate, &a.Outstanding, &a.LateFeeDue,
+			&a.PaidAtStart, &a.PlanID); err != nil {
+			return nil, err
+		}
+		a.PaidAmount = a.PaidAtStart
+		candidates = append(candidates, a)
+	}
+	return candidates, rows.Err()
+}
+
+// CreatePaymentPlan planı, tahakkuk bağlantılarını ve taksitleri tek transaction içinde
+// yazar. Affedilen tazminat tahakkuklardan düşülür ve DUZELTME kaydıyla defterlenir.
+// Tahakkukların ödenen / açık tutarı hazırlık anından farklıysa ErrPlanAssessmentsChanged döner.
+func (r *FinanceRepository) CreatePaymentPlan(ctx context.Context, p *models.PaymentPlan) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockUnit(ctx, tx, p.UnitID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO payment_plans
+			(property_id, unit_id, status, total_amount, waived_late_fee, installment_count, grace_days, notes, created_by)
+		VALUES ($1, $2, 'ACTIVE', $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, '')::uuid)
+		RETURNING id, status, created_at
+	`, p.PropertyID, p.UnitID, p.TotalAmount, p.WaivedLateFee, len(p.Installments), p.GraceDays,
+		p.Notes, p.CreatedBy).Scan(&p.ID, &p.Status, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ödeme planı kaydedilemedi: %w", err)
+	}
+
+	for _, a := range p.Assessments {
+		tag, err := tx.Exec(ctx, `
+			UPDATE monthly_assessments
+			SET payment_plan_id = $2,
+				late_fee = COALESCE(late_fee, 0) - $3,
+				total_amount = total_amount - $3,
+				updated_at = NOW()
+			WHERE id = $1 AND payment_plan_id IS NULL
+			  AND COALESCE(paid_amount, 0) = $4
+			  AND total_amount - COALESCE(paid_amount, 0) = $5
+		`, a.AssessmentID, p.ID, a.WaivedLateFee, a.PaidAtStart, a.Outstanding)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrPlanAssessmentsChanged
+		}
+		if _, err := tx.Exec(ctx, `UPDATE monthly_assessments SET status = `+assessmentStatusSQL+` WHERE id = $1`, a.AssessmentID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO payment_plan_assessments (plan_id, assessment_id, outstanding_amount, paid_at_start, waived_late_fee)
+			VALUES ($1, $2, $3, $4, $5)
+		`, p.ID, a.AssessmentID, a.Outstanding, a.PaidAtStart, a.WaivedLateFee); err != nil {
+			return err
+		}
+	}
+
+	for i := range p.Installments {
+		inst := &p.Installments[i]
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO payment_plan_installments (plan_id, installment_no, due_date, amount)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, status
+		`, p.ID, inst.InstallmentNo, inst.DueDate, inst.Amount).Scan(&inst.ID, &inst.Status); err != nil {
+			return fmt.Errorf("taksit kaydedilemedi: %w", err)
+		}
+	}
+
+	if p.WaivedLateFee > 0 {
+		entry := ledger.LateFeeWaiver(p.PropertyID, p.UnitID, p.ID, p.WaivedLateFee, p.CreatedAt,
+			fmt.Sprintf("Ödeme planı kapsamında gecikme tazminatı affı (%d taksit)", len(p.Installments)), p.CreatedBy)
+		if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+			return fmt.Errorf("tazminat affı defterlenemedi: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetPaymentPlan planı tahakkuk ve taksitleriyle getirir
+func (r *FinanceRepository) GetPaymentPlan(ctx context.Context, propertyID, planID string) (*models.PaymentPlan, error) {
+	plans, err := r.queryPaymentPlans(ctx, `pp.id = $1 AND pp.property_id = $2`, planID, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, ErrPaymentPlanNotFound
+	}
+	return &plans[0], nil
+}
+
+// ListPaymentPlans sitenin ödeme planları (unitID / status boşsa filtrelenmez)
+func (r *FinanceRepository) ListPaymentPlans(ctx context.Context, propertyID, unitID, status string) ([]models.PaymentPlan, error) {
+	return r.queryPaymentPlans(ctx, `pp.property_id = $1 AND ($2 = '' OR pp.unit_id::text = $2) AND ($3 = '' OR pp.status = $3)`,
+		propertyID, unitID, status)
+}
+
+// GetActivePaymentPlans takip edilen (ACTIVE) planları getirir. propertyID ve unitID
+// boşsa tüm siteler taranır (zamanlanmış görev).
+func (r *FinanceRepository) GetActivePaymentPlans(ctx context.Context, propertyID, unitID string) ([]models.PaymentPlan, error) {
+	return r.queryPaymentPlans(ctx, `pp.status = 'ACTIVE' AND ($1 = '' OR pp.property_id::text = $1) AND ($2 = '' OR pp.unit_id::text = $2)`,
+		propertyID, unitID)
+}
+
+// GetUnitPaymentPlans dairenin aktif planlarını ve since tarihinden sonra kapanan planlarını getirir (ekstre)
+func (r *FinanceRepository) GetUnitPaymentPlans(ctx context.Context, unitID string, since time.Time) ([]models.PaymentPlan, error) {
+	return r.queryPaymentPlans(ctx, `pp.unit_id = $1 AND (pp.status = 'ACTIVE' OR pp.closed_at >= $2)`, unitID, since)
+}
+
+// GetResidentPaymentPlans sakinin aktif dairelerine ait takipteki planlar (borç durumu)
+func (r *FinanceRepository) GetResidentPaymentPlans(ctx context.Context, userID string) ([]models.PaymentPlan, error) {
+	return r.queryPaymentPlans(ctx, `pp.status = 'ACTIVE' AND pp.unit_id IN (
+		SELECT ru.unit_id FROM resident_units ru WHERE ru.resident_id = $1 AND ru.is_active = true)`, userID)
+}
+
+// queryPaymentPlans filtreye uyan planları tahakkuk ve taksitleriyle birlikte yükler
+func (r *FinanceRepository) queryPaymentPlans(ctx context.Context, filter string, args ...any) ([]models.PaymentPlan, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT pp.id, pp.property_id, pp.unit_id, COALESCE(u.block, '') || '-' || u.door_number, pp.status,
+			   pp.total_amount, pp.waived_late_fee, pp.paid_amount, pp.installment_count, pp.grace_days,
+			   COALESCE(pp.notes, ''), COALESCE(pp.created_by::text, ''), pp.created_at, pp.closed_at,
+			   COALESCE(pp.close_reason, '')
+		FROM payment_plans pp
+		JOIN units u ON u.id = pp.unit_id
+		WHERE `+filter+`
+		ORDER BY pp.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []models.PaymentPlan
+	index := make(map[string]int)
+	var ids []string
+	for rows.Next() {
+		var p models.PaymentPlan
+		if err := rows.Scan(&p.ID, &p.PropertyID, &p.UnitID, &p.UnitName, &p.Status,
+			&p.TotalAmount, &p.WaivedLateFee, &p.PaidAmount, &p.InstallmentCount, &p.GraceDays,
+			&p.Notes, &p.CreatedBy, &p.CreatedAt, &p.ClosedAt, &p.CloseReason); err != nil {
+			return nil, err
+		}
+		index[p.ID] = len(plans)
+		ids = append(ids, p.ID)
+		plans = append(plans, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(plans) == 0 {
+		return plans, nil
+	}
+
+	aRows, err := r.db(ctx).Query(ctx, `
+		SELECT ppa.plan_id, ma.id, TO_CHAR(MAKE_DATE(ma.period_year, ma.period_month, 1), 'YYYY-MM'), ma.due_date,
+			   ppa.outstanding_amount, GREATEST(COALESCE(ma.late_fee, 0) - ma.late_fee_paid, 0),
+			   ppa.waived_late_fee, ppa.paid_at_start, COALESCE(ma.paid_amount, 0)
+		FROM payment_plan_assessments ppa
+		JOIN monthly_assessments ma ON ma.id = ppa.assessment_id
+		WHERE ppa.plan_id = ANY($1::uuid[])
+		ORDER BY ma.due_date, ma.id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer aRows.Close()
+	for aRows.Next() {
+		var a models.PaymentPlanAssessment
+		if err := aRows.Scan(&a.PlanID, &a.AssessmentID, &a.Period, &a.DueDate, &a.Outstanding, &a.LateFeeDue,
+			&a.WaivedLateFee, &a.PaidAtStart, &a.PaidAmount); err != nil {
+			return nil, err
+		}
+		p := &plans[index[a.PlanID]]
+		p.Assessments = append(p.Assessments, a)
+	}
+	if err := aRows.Err(); err != nil {
+		return nil, err
+	}
+	aRows.Close()
+
+	iRows, err := r.db(ctx).Query(ctx, `
+		SELECT plan_id, id, installment_no, due_date, amount, paid_amount, status, paid_at
+		FROM payment_plan_installments
+		WHERE plan_id = ANY($1::uuid[])
+		ORDER BY plan_id, installment_no
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer iRows.Close()
+	for iRows.Next() {
+		var planID string
+		var inst models.PaymentPlanInstallment
+		if err := iRows.Scan(&planID, &inst.ID, &inst.InstallmentNo, &inst.DueDate, &inst.Amount,
+			&inst.PaidAmount, &inst.Status, &inst.PaidAt); err != nil {
+			return nil, err
+		}
+		p := &plans[index[planID]]
+		p.Installments = append(p.Installments, inst)
+	}
+	return plans, iRows.Err()
+}
+
+// UpdatePaymentPlanProgress planın tahsil edilen tutarını, taksit durumlarını ve plan
+// durumunu yazar. Güncelleme planın hâlâ ACTIVE olmasına koşulludur; araya başka bir
+// işlem girdiyse hiçbir şey yazılmaz ve false döner. Plan kapanırsa tahakkukların plan
+// bağlantısı kaldırılır (tazminat işletmesi kaldığı yerden devam eder); bozulan veya
+// iptal edilen planlarda affedilen tazminat tahakkuklara geri yüklenir ve af kaydı ters
+// kayıtla geri alınır.
+func (r *FinanceRepository) UpdatePaymentPlanProgress(ctx context.Context, p *models.PaymentPlan, createdBy string) (bool, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE payment_plans
+		SET paid_amount = $2, status = $3,
+			closed_at = CASE WHEN $3 <> 'ACTIVE' THEN NOW() END,
+			close_reason = NULLIF($4, '')
+		WHERE id = $1 AND status = 'ACTIVE'
+	`, p.ID, p.PaidAmount, p.Status, p.CloseReason)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	for _, inst := range p.Installments {
+		if _, err := tx.Exec(ctx, `
+			UPDATE payment_plan_installments
+			SET paid_amount = $2, status = $3,
+				paid_at = CASE WHEN $3 = 'PAID' THEN COALESCE(paid_at, NOW()) END
+			WHERE id = $1
+		`, inst.ID, inst.PaidAmount, inst.Status); err != nil {
+			return false, err
+		}
+	}
+
+	if p.Status == "ACTIVE" {
+		return true, tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE monthly_assessments SET payment_plan_id = NULL WHERE payment_plan_id = $1`, p.ID); err != nil {
+		return false, err
+	}
+
+	if p.Status != "COMPLETED" && p.WaivedLateFee > 0 {
+		for _, a := range p.Assessments {
+			if a.WaivedLateFee <= 0 {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `
+				UPDATE monthly_assessments
+				SET late_fee = COALESCE(late_fee, 0) + $2, total_amount = total_amount + $2, updated_at = NOW()
+				WHERE id = $1
+			`, a.AssessmentID, a.WaivedLateFee); err != nil {
+				return false, err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE monthly_assessments SET status = `+assessmentStatusSQL+` WHERE id = $1`, a.AssessmentID); err != nil {
+				return false, err
+			}
+		}
+
+		waivers, err := r.ledger.FindBySource(ctx, tx, ledger.DocCorrection, "payment_plan_waiver", p.ID)
+		if err != nil {
+			return false, err
+		}
+		for i := range waivers {
+			if _, err := r.ledger.ReverseTx(ctx, tx, &waivers[i], "Ödeme planı kapandı, affedilen gecikme tazminatı geri yüklendi", createdBy); err != nil {
+				return false, fmt.Errorf("tazminat affı geri alınamadı: %w", err)
+			}
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrReserveFundNotFound fon bulunamadı
+var ErrReserveFundNotFound = errors.New("fon bulunamadı")
+
+// ErrWithdrawalNotFound fon harcama talebi bulunamadı
+var ErrWithdrawalNotFound = errors.New("fon harcama talebi bulunamadı")
+
+// ErrWithdrawalStatusChanged talep başka bir istekle onaylanmış / reddedilmiş
+var ErrWithdrawalStatusChanged = errors.New("harcama talebi zaten sonuçlandırılmış")
+
+// ErrInsufficientReserve fonda tahsil edilmiş yeterli bakiye yok
+var ErrInsufficientReserve = errors.New("fonda yeterli tahsil edilmiş bakiye yok")
+
+const reserveFundColumns = `
+	id, property_id, name, COALESCE(description, ''), account_code, monthly_amount, distribution_type,
+	applies_to_commercial, applies_to_ground_floor, starts_on, ends_on, target_amount, is_active, created_at
+`
+
+func scanReserveFund(row pgx.Row) (*models.ReserveFund, error) {
+	f := &models.ReserveFund{}
+	err := row.Scan(&f.ID, &f.PropertyID, &f.Name, &f.Description, &f.AccountCode, &f.MonthlyAmount,
+		&f.DistributionType, &f.AppliesToCommercial, &f.AppliesToGroundFloor, &f.StartsOn, &f.EndsOn,
+		&f.TargetAmount, &f.IsActive, &f.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReserveFundNotFound
+	}
+	return f, err
+}
+
+// CreateReserveFund fonu kaydeder ve hesap planında 549 altında alt hesabını açar
+func (r *FinanceRepository) CreateReserveFund(ctx context.Context, f *models.ReserveFund, createdBy string) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	code, err := ledger.OpenSubAccount(ctx, tx, f.PropertyID, ledger.AccountReserveFunds, f.Name)
+	if err != nil {
+		return err
+	}
+	f.AccountCode = code
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO reserve_funds
+			(property_id, name, description, account_code, monthly_amount, distribution_type,
+			 applies_to_commercial, applies_to_ground_floor, starts_on, ends_on, target_amount, is_active, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, '')::uuid)
+		RETURNING id, created_at
+	`, f.PropertyID, f.Name, f.Description, f.AccountCode, f.MonthlyAmount, f.DistributionType,
+		f.AppliesToCommercial, f.AppliesToGroundFloor, f.StartsOn, f.EndsOn, f.TargetAmount, f.IsActive,
+		createdBy).Scan(&f.ID, &f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("fon kaydedilemedi: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateReserveFund fonun tanımını ve katkı planını günceller. Hesap kodu değişmez;
+// geçmiş dönem tahakkukları yeni plandan etkilenmez.
+func (r *FinanceRepository) UpdateReserveFund(ctx context.Context, f *models.ReserveFund) error {
+	err := r.db(ctx).QueryRow(ctx, `
+		UPDATE reserve_funds
+		SET name = $3, description = NULLIF($4, ''), monthly_amount = $5, distribution_type = $6,
+			applies_to_commercial = $7, applies_to_ground_floor = $8, starts_on = $9, ends_on = $10,
+			target_amount = $11, is_active = $12, updated_at = NOW()
+		WHERE id = $1 AND property_id = $2
+		RETURNING account_code, created_at
+	`, f.ID, f.PropertyID, f.Name, f.Description, f.MonthlyAmount, f.DistributionType,
+		f.AppliesToCommercial, f.AppliesToGroundFloor, f.StartsOn, f.EndsOn, f.TargetAmount,
+		f.IsActive).Scan(&f.AccountCode, &f.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReserveFundNotFound
+	}
+	return err
+}
+
+// GetReserveFund tek fonu getirir
+func (r *FinanceRepository) GetReserveFund(ctx context.Context, propertyID, fundID string) (*models.ReserveFund, error) {
+	return scanReserveFund(r.db(ctx).QueryRow(ctx, `
+		SELECT `+reserveFundColumns+` FROM reserve_funds WHERE id = $1 AND property_id = $2
+	`, fundID, propertyID))
+}
+
+// ListReserveFunds sitenin tüm fonlarını getirir
+func (r *FinanceRepository) ListReserveFunds(ctx context.Context, propertyID string) ([]models.ReserveFund, error) {
+	return r.queryReserveFunds(ctx, `
+		SELECT `+reserveFundColumns+` FROM reserve_funds WHERE property_id = $1 ORDER BY account_code
+	`, propertyID)
+}
+
+// GetReserveFundsForPeriod dönemde katkı planı işleyen aktif fonları getirir
+func (r *FinanceRepository) GetReserveFundsForPeriod(ctx context.Context, propertyID string, year, month int) ([]models.ReserveFund, error) {
+	period := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return r.queryReserveFunds(ctx, `
+		SELECT `+reserveFundColumns+`
+		FROM reserve_funds
+		WHERE property_id = $1 AND is_active = true AND monthly_amount > 0
+		  AND date_trunc('month', starts_on) <= $2
+		  AND (ends_on IS NULL OR ends_on >= $2)
+		ORDER BY account_code
+	`, propertyID, period)
+}
+
+func (r *FinanceRepository) queryReserveFunds(ctx context.Context, query string, args ...any) ([]models.ReserveFund, error) {
+	rows, err := r.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var funds []models.ReserveFund
+	for rows.Next() {
+		f, err := scanReserveFund(rows)
+		if err != nil {
+			return nil, err
+		}
+		funds = append(funds, *f)
+	}
+	return funds, rows.Err()
+}
+
+const withdrawalColumns = `
+	w.id, w.fund_id, rf.name, w.amount, w.description, w.decision_reference, w.decision_date,
+	w.cash_account, w.status, COALESCE(w.requested_by::text, ''), COALESCE(w.approved_by::text, ''),
+	w.approved_at, COALESCE(w.rejection_reason, ''), w.created_at
+`
+
+func scanWithdrawal(row pgx.Row) (*models.ReserveWithdrawal, error) {
+	w := &models.ReserveWithdrawal{}
+	err := row.Scan(&w.ID, &w.FundID, &w.FundName, &w.Amount, &w.Description, &w.DecisionReference,
+		&w.DecisionDate, &w.CashAccount, &w.Status, &w.RequestedBy, &w.ApprovedBy, &w.ApprovedAt,
+		&w.RejectionReason, &w.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWithdrawalNotFound
+	}
+	return w, err
+}
+
+// CreateWithdrawal fondan harcama talebini PENDING olarak kaydeder
+func (r *FinanceRepository) CreateWithdrawal(ctx context.Context, propertyID string, w *models.ReserveWithdrawal) error {
+	err := r.db(ctx).QueryRow(ctx, `
+		INSERT INTO reserve_fund_withdrawals
+			(fund_id, amount, description, decision_reference, decision_date, cash_account, requested_by)
+		SELECT rf.id, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid
+		FROM reserve_funds rf
+		WHERE rf.id = $1 AND rf.property_id = $2
+		RETURNING id, status, created_at
+	`, w.FundID, propertyID, w.Amount, w.Description, w.DecisionReference, w.DecisionDate,
+		w.CashAccount, w.RequestedBy).Scan(&w.ID, &w.Status, &w.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReserveFundNotFound
+	}
+	return err
+}
+
+// ListWithdrawals sitenin fon harcama talepleri (fundID / status boşsa filtrelenmez)
+func (r *FinanceRepository) ListWithdrawals(ctx context.Context, propertyID, fundID, status string) ([]models.ReserveWithdrawal, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+withdrawalColumns+`
+		FROM reserve_fund_withdrawals w
+		JOIN reserve_funds rf ON rf.id = w.fund_id
+		WHERE rf.property_id = $1
+		  AND ($2 = '' OR w.fund_id::text = $2)
+		  AND ($3 = '' OR w.status = $3)
+		ORDER BY w.created_at DESC
+	`, propertyID, fundID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []models.ReserveWithdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, *w)
+	}
+	return withdrawals, rows.Err()
+}
+
+// ApproveWithdrawal bekleyen talebi onaylar ve FON yevmiye kaydını atar. Fon satırı
+// kilitlenir; tutar, fonun tahsil edilmiş katkılarından önceki onaylı harcamalar
+// düşüldükten sonra kalan bakiyeyi aşarsa ErrInsufficientReserve döner.
+func (r *FinanceRepository) ApproveWithdrawal(ctx context.Context, propertyID, withdrawalID, approvedBy string) (*models.ReserveWithdrawal, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	w, err := scanWithdrawal(tx.QueryRow(ctx, `
+		SELECT `+withdrawalColumns+`
+		FROM reserve_fund_withdrawals w
+		JOIN reserve_funds rf ON rf.id = w.fund_id
+		WHERE w.id = $1 AND rf.property_id = $2
+		FOR UPDATE OF w, rf
+	`, withdrawalID, propertyID))
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != "PENDING" {
+		return nil, ErrWithdrawalStatusChanged
+	}
+
+	var accountCode string
+	var withdrawn float64
+	err = tx.QueryRow(ctx, `
+		SELECT rf.account_code,
+			   COALESCE((SELECT SUM(amount) FROM reserve_fund_withdrawals
+						 WHERE fund_id = rf.id AND status = 'APPROVED'), 0)
+		FROM reserve_funds rf WHERE rf.id = $1
+	`, w.FundID).Scan(&accountCode, &withdrawn)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	collected, err := reserveCollected(ctx, tx, propertyID, now)
+	if err != nil {
+		return nil, err
+	}
+	if toKurus(w.Amount) > toKurus(collected[w.FundID])-toKurus(withdrawn) {
+		return nil, ErrInsufficientReserve
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE reserve_fund_withdrawals
+		SET status = 'APPROVED', approved_by = NULLIF($2, '')::uuid, approved_at = $3
+		WHERE id = $1
+		RETURNING approved_at
+	`, w.ID, approvedBy, now).Scan(&w.ApprovedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.Status = "APPROVED"
+	w.ApprovedBy = approvedBy
+
+	entry := ledger.ReserveWithdrawal(propertyID, w.ID, accountCode, w.CashAccount, w.Amount, now,
+		fmt.Sprintf("%s fonundan harcama - %s", w.FundName, w.Description), w.DecisionReference, approvedBy)
+	if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+		return nil, fmt.Errorf("fon harcaması defterlenemedi: %w", err)
+	}
+	return w, tx.Commit(ctx)
+}
+
+// RejectWithdrawal bekleyen talebi gerekçesiyle reddeder
+func (r *FinanceRepository) RejectWithdrawal(ctx context.Context, propertyID, withdrawalID, reason string) (*models.ReserveWithdrawal, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE reserve_fund_withdrawals w
+		SET status = 'REJECTED', rejection_reason = NULLIF($3, '')
+		FROM reserve_funds rf
+		WHERE w.id = $1 AND rf.id = w.fund_id AND rf.property_id = $2 AND w.status = 'PENDING'
+	`, withdrawalID, propertyID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := scanWithdrawal(r.db(ctx).QueryRow(ctx, `
+		SELECT `+withdrawalColumns+`
+		FROM reserve_fund_withdrawals w
+		JOIN reserve_funds rf ON rf.id = w.fund_id
+		WHERE w.id = $1 AND rf.property_id = $2
+	`, withdrawalID, propertyID))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrWithdrawalStatusChanged
+	}
+	return w, nil
+}
+
+// GetReserveFundBalances fonların asOf günü sonundaki durumunu getirir. Katkı, harcama ve
+// bakiye fon alt hesabından; tahsil edilen kısım ödeme dağılımlarından hesaplanır.
+func (r *FinanceRepository) GetReserveFundBalances(ctx context.Context, propertyID string, asOf time.Time) ([]models.ReserveFundBalance, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT rf.id, rf.name, rf.account_code,
+			   COALESCE(SUM(l.credit - l.debit) FILTER (WHERE l.source_type = 'assessment_reserve'), 0),
+			   COALESCE(SUM(l.debit - l.credit) FILTER (WHERE l.source_type = 'reserve_withdrawal'), 0),
+			   COALESCE(SUM(l.credit - l.debit), 0)
+		FROM reserve_funds rf
+		LEFT JOIN chart_of_accounts coa ON coa.property_id = rf.property_id AND coa.account_code = rf.account_code
+		LEFT JOIN (
+			SELECT ll.account_id, ll.debit_amount AS debit, ll.credit_amount AS credit, le.source_type
+			FROM ledger_lines ll
+			JOIN ledger_entries le ON le.id = ll.entry_id
+			WHERE le.property_id = $1 AND le.transaction_date <= $2
+		) l ON l.account_id = coa.id
+		WHERE rf.property_id = $1
+		GROUP BY rf.id, rf.name, rf.account_code
+		ORDER BY rf.account_code
+	`, propertyID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []models.ReserveFundBalance
+	for rows.Next() {
+		var b models.ReserveFundBalance
+		if err := rows.Scan(&b.FundID, &b.Name, &b.AccountCode, &b.Contributions, &b.Withdrawals, &b.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	collected, err := reserveCollected(ctx, r.db(ctx), propertyID, asOf.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		b := &balances[i]
+		b.Collected = collected[b.FundID]
+		b.Uncollected = float64(toKurus(b.Contributions)-toKurus(b.Collected)) / 100
+		b.Available = float64(toKurus(b.Collected)-toKurus(b.Withdrawals)) / 100
+	}
+	return balances, nil
+}
+
+// reserveCollected before anından önce tahsil edilmiş fon katkılarını fon bazında döner.
+// Ödemelerin ve avans mahsuplarının fon katkısına sayılan kısmı, tahakkuktaki fon
+// satırlarına tutarları oranında paylaştırılır. İade edilen ödemeler iade tarihinden
+// itibaren düşülür.
+func reserveCollected(ctx context.Context, q database.Querier, propertyID string, before time.Time) (map[string]float64, error) {
+	rows, err := q.Query(ctx, `
+		WITH collected AS (
+			SELECT pa.assessment_id, pa.reserve_amount AS amount
+			FROM payment_assessments pa
+			JOIN payments p ON p.id = pa.payment_id
+			WHERE pa.reserve_amount > 0 AND p.completed_at < $2
+			  AND (p.refunded_at IS NULL OR p.refunded_at >= $2)
+			UNION ALL
+			SELECT assessment_id, reserve_amount - reserve_reversed
+			FROM unit_credit_applications
+			WHERE reserve_amount > reserve_reversed AND created_at < $2
+		)
+		SELECT ad.reserve_fund_id, SUM(c.amount * ad.amount / ma.reserve_amount)
+		FROM collected c
+		JOIN monthly_assessments ma ON ma.id = c.assessment_id AND ma.reserve_amount > 0
+		JOIN assessment_details ad ON ad.assessment_id = ma.id AND ad.reserve_fund_id IS NOT NULL
+		WHERE ma.property_id = $1
+		GROUP BY ad.reserve_fund_id
+	`, propertyID, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collected := make(map[string]float64)
+	for rows.Next() {
+		var fundID string
+		var amount float64
+		if err := rows.Scan(&fundID, &amount); err != nil {
+			return nil, err
+		}
+		collected[fundID] = math.Round(amount*100) / 100
+	}
+	return collected, rows.Err()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// GetStatementInfo ekstre başlığı için daireyi, malik/sakin adını ve sitenin markasını getirir
+func (r *FinanceRepository) GetStatementInfo(ctx context.Context, unitID string) (*models.StatementInfo, error) {
+	query := `
+		SELECT u.id, u.property_id, p.name, COALESCE(u.block, '') || '-' || u.door_number,
+			   COALESCE((
+				   SELECT us.first_name || ' ' || us.last_name
+				   FROM resident_units ru
+				   JOIN users us ON us.id = ru.resident_id
+				   WHERE ru.unit_id = u.id AND ru.is_active = true
+				   ORDER BY (ru.role = 'OWNER') DESC, ru.created_at
+				   LIMIT 1
+			   ), ''),
+			   COALESCE(t.branding, '{}'::jsonb)
+		FROM units u
+		JOIN properties p ON p.id = u.property_id
+		LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE u.id = $1
+	`
+	info := &models.StatementInfo{}
+	var branding []byte
+	err := r.db(ctx).QueryRow(ctx, query, unitID).Scan(&info.UnitID, &info.PropertyID, &info.PropertyName,
+		&info.UnitName, &info.ResidentName, &branding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnitNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Branding, err = decodeBranding(branding); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// decodeBranding tenants.branding JSON kolonunu çözer
+func decodeBranding(raw []byte) (*tenant.TenantBranding, error) {
+	branding := &tenant.TenantBranding{}
+	if err := json.Unmarshal(raw, branding); err != nil {
+		return nil, err
+	}
+	return branding, nil
+}
+
+// GetUnitStatement dairenin tarih aralığındaki hesap ekstresini defterden oluşturur
+func (r *FinanceRepository) GetUnitStatement(ctx context.Context, unitID string, from, to time.Time) (*ledger.Statement, error) {
+	return r.ledger.UnitStatement(ctx, unitID, from, to)
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+)
+
+// Dağıtım tipleri
+const (
+	DistributionShareRatio = "SHARE_RATIO"
+	DistributionEqual      = "EQUAL"
+	DistributionAreaM2     = "AREA_M2"
+)
+
+// GenerateAssessmentsInput tahakkuk oluşturma parametreleri
+type GenerateAssessmentsInput struct {
+	PropertyID   string
+	PeriodYear   int
+	PeriodMonth  int
+	DueDate      time.Time            // Boşsa site ayarındaki son ödeme günü kullanılır
+	ExpenseItems []models.ExpenseItem // Boşsa dönemi kapsayan onaylı işletme projesinden türetilir
+	DryRun       bool
+}
+
+// GenerationResult tahakkuk oluşturma sonucu
+type GenerationResult struct {
+	PeriodYear   int                          `json:"period_year"`
+	PeriodMonth  int                          `json:"period_month"`
+	DryRun       bool                         `json:"dry_run"`
+	BudgetID     string                       `json:"budget_id,omitempty"` // Kalemlerin türetildiği işletme projesi
+	TotalAmount  float64                      `json:"total_amount"`
+	CreatedCount int                          `json:"created_count"`
+	SkippedCount int                          `json:"skipped_count"`
+	Assessments  []models.GeneratedAssessment `json:"assessments"`
+}
+
+// GenerateAssessments dönem giderlerini dairelere dağıtarak aylık tahakkukları oluşturur.
+// Gider kalemi verilmezse onaylı işletme projesinin aylık payları kullanılır. Dönemde
+// katkı planı işleyen fonların payları aidattan ayrı satırlar olarak eklenir.
+// DryRun ile çağrıldığında hiçbir kayıt yazılmaz, yalnızca önizleme döner.
+func (s *FinanceService) GenerateAssessments(ctx context.Context, in *GenerateAssessmentsInput) (*GenerationResult, error) {
+	if in.PeriodMonth < 1 || in.PeriodMonth > 12 {
+		return nil, errors.New("geçersiz dönem ayı")
+	}
+	if in.PeriodYear < 2000 {
+		return nil, errors.New("geçersiz dönem yılı")
+	}
+
+	var budgetID string
+	if len(in.ExpenseItems) == 0 {
+		budget, err := s.repo.GetApprovedBudgetForPeriod(ctx, in.PropertyID, in.PeriodYear, in.PeriodMonth)
+		if errors.Is(err, repository.ErrBudgetNotFound) {
+			return nil, errors.New("gider kalemi girilmedi ve dönem için onaylı işletme projesi yok")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if in.ExpenseItems, err = budgetExpenseItems(budget, in.PeriodYear, in.PeriodMonth); err != nil {
+			return nil, err
+		}
+		budgetID = budget.ID
+	}
+
+	categories, err := s.repo.GetExpenseCategories(ctx, in.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	categoryByID := make(map[string]models.ExpenseCategory, len(categories))
+	for _, c := range categories {
+		categoryByID[c.ID] = c
+	}
+
+	lines := make([]expenseLine, 0, len(in.ExpenseItems))
+	seen := make(map[string]bool)
+	for _, item := range in.ExpenseItems {
+		category, ok := categoryByID[item.CategoryID]
+		if !ok {
+			return nil, fmt.Errorf("gider kalemi bulunamadı: %s", item.CategoryID)
+		}
+		if seen[item.CategoryID] {
+			return nil, fmt.Errorf("%s kalemi birden fazla kez girilmiş", category.Name)
+		}
+		if item.Amount <= 0 {
+			return nil, fmt.Errorf("%s kalemi için tutar sıfırdan büyük olmalı", category.Name)
+		}
+		seen[item.CategoryID] = true
+		lines = append(lines, expenseLine{Category: category, Amount: item.Amount})
+	}
+
+	funds, err := s.repo.GetReserveFundsForPeriod(ctx, in.PropertyID, in.PeriodYear, in.PeriodMonth)
+	if err != nil {
+		return nil, err
+	}
+	for i := range funds {
+		lines = append(lines, reserveLine(&funds[i]))
+	}
+
+	units, err := s.repo.GetUnitsForDistribution(ctx, in.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		return nil, errors.New("sitede tanımlı bağımsız bölüm yok")
+	}
+
+	dueDate := in.DueDate
+	if dueDate.IsZero() {
+		dueDay, err := s.repo.GetAssessmentDueDay(ctx, in.PropertyID)
+		if err != nil {
+			return nil, err
+		}
+		dueDate = periodDueDate(in.PeriodYear, in.PeriodMonth, dueDay)
+	}
+
+	assessments, err := buildAssessments(units, lines, in.PeriodYear, in.PeriodMonth, dueDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.DryRun {
+		existing, err := s.repo.GetAssessedUnitIDs(ctx, in.PropertyID, in.PeriodYear, in.PeriodMonth)
+		if err != nil {
+			return nil, err
+		}
+		for i := range assessments {
+			if id, ok := existing[assessments[i].UnitID]; ok {
+				assessments[i].ID = id
+				assessments[i].Status = "SKIPPED"
+			} else {
+				assessments[i].Status = "PREVIEW"
+			}
+		}
+	} else if err := s.repo.CreateAssessments(ctx, in.PropertyID, assessments); err != nil {
+		return nil, err
+	}
+
+	result := &GenerationResult{
+		PeriodYear:  in.PeriodYear,
+		PeriodMonth: in.PeriodMonth,
+		DryRun:      in.DryRun,
+		BudgetID:    budgetID,
+		Assessments: assessments,
+	}
+	for _, a := range assessments {
+		if a.Status == "SKIPPED" {
+			result.SkippedCount++
+			continue
+		}
+		result.CreatedCount++
+		result.TotalAmount = roundKurus(result.TotalAmount + a.BaseAmount + a.ReserveAmount)
+	}
+	return result, nil
+}
+
+// expenseLine dağıtılacak tek bir gider kalemi. Fund doluysa satır aidat değil
+// fon katkı payıdır; Category fonun dağıtım ayarlarından türetilir.
+type expenseLine struct {
+	Category models.ExpenseCategory
+	Fund     *models.ReserveFund
+	Amount   float64
+}
+
+// reserveLine fonun aylık katkısını dağıtılacak satıra çevirir
+func reserveLine(f *models.ReserveFund) expenseLine {
+	return expenseLine{
+		Category: models.ExpenseCategory{
+			Name:                 f.Name,
+			DistributionType:     f.DistributionType,
+			AppliesToCommercial:  f.AppliesToCommercial,
+			AppliesToGroundFloor: f.AppliesToGroundFloor,
+		},
+		Fund:   f,
+		Amount: f.MonthlyAmount,
+	}
+}
+
+// expenseShare bir gider kaleminin tek daireye düşen payı
+type expenseShare struct {
+	UnitID string
+	Amount float64
+	Weight float64
+	Basis  string
+}
+
+// buildAssessments tüm gider kalemlerini dağıtıp daire bazlı tahakkukları oluşturur.
+// Hiçbir kalemden pay almayan daireler için tahakkuk üretilmez.
+func buildAssessments(units []models.Unit, lines []expenseLine, year, month int, dueDate time.Time) ([]models.GeneratedAssessment, error) {
+	byUnit := make(map[string]*models.GeneratedAssessment, len(units))
+	for _, line := range lines {
+		allocations, err := distributeExpense(line.Amount, line.Category, units)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range allocations {
+			ga, ok := byUnit[a.UnitID]
+			if !ok {
+				ga = &models.GeneratedAssessment{UnitID: a.UnitID, PeriodYear: year, PeriodMonth: month, DueDate: dueDate}
+				byUnit[a.UnitID] = ga
+			}
+			detail := models.AssessmentDetailItem{
+				CategoryID:       line.Category.ID,
+				Category:         line.Category.Name,
+				Amount:           a.Amount,
+				CalculationBasis: a.Basis,
+				ShareValue:       a.Weight,
+			}
+			if line.Fund != nil {
+				detail.ReserveFundID = line.Fund.ID
+				ga.ReserveAmount = roundKurus(ga.ReserveAmount + a.Amount)
+			} else {
+				ga.BaseAmount = roundKurus(ga.BaseAmount + a.Amount)
+			}
+			ga.Details = append(ga.Details, detail)
+		}
+	}
+
+	// Daire sırasını koru
+	assessments := make([]models.GeneratedAssessment, 0, len(byUnit))
+	for _, u := range units {
+		if ga, ok := byUnit[u.ID]; ok {
+			ga.UnitName = u.Name()
+			assessments = append(assessments, *ga)
+		}
+	}
+	return assessments, nil
+}
+
+// distributeExpense gider tutarını kalemin dağıtım tipine göre dairelere böler.
+// Tutar kuruşa yuvarlanır; yuvarlama farkı en büyük küsurata sahip dairelerden
+// başlanarak dağıtılır, böylece payların toplamı her zaman gider tutarına eşittir.
+func distributeExpense(amount float64, category models.ExpenseCategory, units []models.Unit) ([]expenseShare, error) {
+	var eligible []models.Unit
+	var weights []float64
+	var totalWeight float64
+
+	for _, u := range units {
+		if u.IsCommercial && !category.AppliesToCommercial {
+			continue
+		}
+		if u.IsGroundFloor && !category.AppliesToGroundFloor {
+			continue
+		}
+
+		var w float64
+		switch category.DistributionType {
+		case DistributionShareRatio:
+			w = u.ShareRatio
+			if w <= 0 {
+				return nil, fmt.Errorf("%s dairesi için arsa payı tanımlı değil", u.Name())
+			}
+		case DistributionEqual:
+			w = 1
+		case DistributionAreaM2:
+			w = u.GrossAreaM2
+			if w <= 0 {
+				return nil, fmt.Errorf("%s dairesi için brüt alan tanımlı değil", u.Name())
+			}
+		default:
+			return nil, fmt.Errorf("%s kalemi için %s dağıtımı desteklenmiyor", category.Name, category.DistributionType)
+		}
+
+		eligible = append(eligible, u)
+		weights = append(weights, w)
+		totalWeight += w
+	}
+
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("%s kalemi için dağıtılacak daire yok", category.Name)
+	}
+
+	totalKurus := int64(math.Round(amount * 100))
+	shares := make([]int64, len(eligible))
+	fractions := make([]float64, len(eligible))
+	var assigned int64
+	for i, w := range weights {
+		exact := float64(totalKurus) * w / totalWeight
+		shares[i] = int64(math.Floor(exact))
+		fractions[i] = exact - float64(shares[i])
+		assigned += shares[i]
+	}
+
+	order := make([]int, len(eligible))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fractions[order[a]] > fractions[order[b]]
+	})
+	for i := int64(0); i < totalKurus-assigned; i++ {
+		shares[order[int(i)%len(order)]]++
+	}
+
+	allocations := make([]expenseShare, len(eligible))
+	for i, u := range eligible {
+		allocations[i] = expenseShare{
+			UnitID: u.ID,
+			Amount: float64(shares[i]) / 100,
+			Weight: weights[i],
+			Basis:  calculationBasis(category.DistributionType, weights[i], totalWeight, len(eligible)),
+		}
+	}
+	return allocations, nil
+}
+
+// calculationBasis assessment_details.calculation_basis açıklaması
+func calculationBasis(distributionType string, weight, totalWeight float64, unitCount int) string {
+	switch distributionType {
+	case DistributionShareRatio:
+		return fmt.Sprintf("Arsa payı %s / %s", formatWeight(weight), formatWeight(totalWeight))
+	case DistributionAreaM2:
+		return fmt.Sprintf("Brüt alan %.2f m² / %.2f m²", weight, totalWeight)
+	default:
+		return fmt.Sprintf("Eşit dağıtım 1 / %d", unitCount)
+	}
+}
+
+func formatWeight(w float64) string {
+	return strconv.FormatFloat(w, 'f', -1, 64)
+}
+
+// periodDueDate dönemin son ödeme tarihi; gün ay sonunu aşarsa ayın son günü kullanılır
+func periodDueDate(year, month, day int) time.Time {
+	lastDay := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day < 1 {
+		day = 1
+	}
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func roundKurus(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
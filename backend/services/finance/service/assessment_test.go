@@ -0,0 +1,144 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUnits() []models.Unit {
+	return []models.Unit{
+		{ID: "u1", Block: "A", DoorNumber: "1", ShareRatio: 400, GrossAreaM2: 85, IsGroundFloor: true},
+		{ID: "u2", Block: "A", DoorNumber: "2", ShareRatio: 420, GrossAreaM2: 95},
+		{ID: "u3", Block: "A", DoorNumber: "3", ShareRatio: 420, GrossAreaM2: 95},
+		{ID: "u4", Block: "A", DoorNumber: "D1", ShareRatio: 260, GrossAreaM2: 60, IsCommercial: true, IsGroundFloor: true},
+	}
+}
+
+func sumAllocations(allocations []expenseShare) float64 {
+	var total float64
+	for _, a := range allocations {
+		total = roundKurus(total + a.Amount)
+	}
+	return total
+}
+
+func TestDistributeExpense_ShareRatio(t *testing.T) {
+	category := models.ExpenseCategory{Name: "Genel Yönetim", DistributionType: DistributionShareRatio, AppliesToCommercial: true, AppliesToGroundFloor: true}
+
+	allocations, err := distributeExpense(1500, category, testUnits())
+	require.NoError(t, err)
+	require.Len(t, allocations, 4)
+
+	assert.Equal(t, 400.0, allocations[0].Amount)
+	assert.Equal(t, 420.0, allocations[1].Amount)
+	assert.Equal(t, 260.0, allocations[3].Amount)
+	assert.Equal(t, "Arsa payı 400 / 1500", allocations[0].Basis)
+	assert.Equal(t, 1500.0, sumAllocations(allocations))
+}
+
+func TestDistributeExpense_EqualRoundingRemainder(t *testing.T) {
+	category := models.ExpenseCategory{Name: "Temizlik", DistributionType: DistributionEqual, AppliesToCommercial: true, AppliesToGroundFloor: true}
+	units := testUnits()[:3]
+
+	allocations, err := distributeExpense(100, category, units)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100.0, sumAllocations(allocations))
+	for _, a := range allocations {
+		assert.InDelta(t, 33.33, a.Amount, 0.011)
+		assert.Equal(t, "Eşit dağıtım 1 / 3", a.Basis)
+	}
+}
+
+func TestDistributeExpense_Exclusions(t *testing.T) {
+	category := models.ExpenseCategory{Name: "Asansör Bakım", DistributionType: DistributionAreaM2, AppliesToCommercial: false, AppliesToGroundFloor: false}
+
+	allocations, err := distributeExpense(1900, category, testUnits())
+	require.NoError(t, err)
+	require.Len(t, allocations, 2)
+
+	assert.Equal(t, "u2", allocations[0].UnitID)
+	assert.Equal(t, "u3", allocations[1].UnitID)
+	assert.Equal(t, 950.0, allocations[0].Amount)
+	assert.Equal(t, "Brüt alan 95.00 m² / 190.00 m²", allocations[0].Basis)
+}
+
+func TestDistributeExpense_Errors(t *testing.T) {
+	t.Run("unsupported type", func(t *testing.T) {
+		category := models.ExpenseCategory{Name: "Isınma", DistributionType: "METER_READING", AppliesToCommercial: true, AppliesToGroundFloor: true}
+		_, err := distributeExpense(100, category, testUnits())
+		assert.Error(t, err)
+	})
+
+	t.Run("missing area", func(t *testing.T) {
+		category := models.ExpenseCategory{Name: "Bahçe", DistributionType: DistributionAreaM2, AppliesToCommercial: true, AppliesToGroundFloor: true}
+		units := []models.Unit{{ID: "u1", Block: "A", DoorNumber: "1", ShareRatio: 100}}
+		_, err := distributeExpense(100, category, units)
+		assert.Error(t, err)
+	})
+
+	t.Run("no eligible unit", func(t *testing.T) {
+		category := models.ExpenseCategory{Name: "Asansör", DistributionType: DistributionEqual}
+		units := []models.Unit{{ID: "u1", Block: "A", DoorNumber: "1", ShareRatio: 100, IsGroundFloor: true}}
+		_, err := distributeExpense(100, category, units)
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildAssessments(t *testing.T) {
+	lines := []expenseLine{
+		{Category: models.ExpenseCategory{ID: "c1", Name: "Genel Yönetim", DistributionType: DistributionShareRatio, AppliesToCommercial: true, AppliesToGroundFloor: true}, Amount: 1500},
+		{Category: models.ExpenseCategory{ID: "c2", Name: "Asansör", DistributionType: DistributionEqual, AppliesToCommercial: false, AppliesToGroundFloor: false}, Amount: 200},
+	}
+	due := periodDueDate(2026, 2, 10)
+
+	assessments, err := buildAssessments(testUnits(), lines, 2026, 2, due)
+	require.NoError(t, err)
+	require.Len(t, assessments, 4)
+
+	assert.Equal(t, "A-1", assessments[0].UnitName)
+	assert.Equal(t, 400.0, assessments[0].BaseAmount)
+	assert.Len(t, assessments[0].Details, 1)
+
+	assert.Equal(t, 520.0, assessments[1].BaseAmount)
+	assert.Len(t, assessments[1].Details, 2)
+	assert.Equal(t, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), assessments[1].DueDate)
+}
+
+func TestBuildAssessments_ReserveLinesTrackedSeparately(t *testing.T) {
+	fund := &models.ReserveFund{ID: "f1", Name: "Demirbaş Fonu", MonthlyAmount: 100,
+		DistributionType: DistributionEqual, AppliesToCommercial: true, AppliesToGroundFloor: true}
+	lines := []expenseLine{
+		{Category: models.ExpenseCategory{ID: "c1", Name: "Genel Yönetim", DistributionType: DistributionShareRatio, AppliesToCommercial: true, AppliesToGroundFloor: true}, Amount: 1500},
+		reserveLine(fund),
+	}
+
+	assessments, err := buildAssessments(testUnits(), lines, 2026, 2, periodDueDate(2026, 2, 10))
+	require.NoError(t, err)
+	require.Len(t, assessments, 4)
+
+	a := assessments[0]
+	assert.Equal(t, 400.0, a.BaseAmount)
+	assert.Equal(t, 25.0, a.ReserveAmount)
+	require.Len(t, a.Details, 2)
+	assert.Equal(t, "c1", a.Details[0].CategoryID)
+	assert.Empty(t, a.Details[0].ReserveFundID)
+	assert.Equal(t, "f1", a.Details[1].ReserveFundID)
+	assert.Empty(t, a.Details[1].CategoryID)
+	assert.Equal(t, "Demirbaş Fonu", a.Details[1].Category)
+
+	var reserve float64
+	for _, a := range assessments {
+		reserve += a.ReserveAmount
+	}
+	assert.Equal(t, 100.0, reserve)
+}
+
+func TestPeriodDueDate_ClampsToMonthEnd(t *testing.T) {
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), periodDueDate(2026, 2, 31))
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), periodDueDate(2026, 1, 0))
+}
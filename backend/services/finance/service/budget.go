@@ -0,0 +1,454 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// İşletme projesi durumları
+const (
+	BudgetDraft           = "DRAFT"
+	BudgetPendingApproval = "PENDING_APPROVAL"
+	BudgetApproved        = "APPROVED"
+	BudgetSuperseded      = "SUPERSEDED"
+)
+
+// İşletme projesi durum geçişleri
+const (
+	budgetSubmit  = "SUBMIT"
+	budgetApprove = "APPROVE"
+	budgetReject  = "REJECT"
+)
+
+// ErrBudgetNotEditable yalnızca taslak projeler düzenlenebilir
+var ErrBudgetNotEditable = errors.New("yalnızca taslak işletme projesi düzenlenebilir")
+
+// BudgetInput işletme projesi oluşturma / güncelleme parametreleri
+type BudgetInput struct {
+	PropertyID string
+	FiscalYear int
+	StartMonth int // Boşsa Ocak
+	Name       string
+	Notes      string
+	Items      []models.BudgetItem
+	UserID     string
+}
+
+// CreateBudget yeni işletme projesini taslak olarak kaydeder
+func (s *FinanceService) CreateBudget(ctx context.Context, in *BudgetInput) (*models.Budget, error) {
+	b, err := s.budgetFromInput(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	b.CreatedBy = in.UserID
+	if err := s.repo.CreateBudget(ctx, b); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudget(ctx, in.PropertyID, b.ID)
+}
+
+// UpdateBudget taslak projenin adını, başlangıç ayını, notlarını ve kalemlerini günceller.
+// Dönem yılı değiştirilemez; farklı yıl için yeni proje oluşturulmalıdır.
+func (s *FinanceService) UpdateBudget(ctx context.Context, budgetID string, in *BudgetInput) (*models.Budget, error) {
+	current, err := s.repo.GetBudget(ctx, in.PropertyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != BudgetDraft {
+		return nil, ErrBudgetNotEditable
+	}
+
+	in.FiscalYear = current.FiscalYear
+	b, err := s.budgetFromInput(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	b.ID = budgetID
+	if err := s.repo.UpdateBudget(ctx, b); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudget(ctx, in.PropertyID, budgetID)
+}
+
+func (s *FinanceService) budgetFromInput(ctx context.Context, in *BudgetInput) (*models.Budget, error) {
+	if in.FiscalYear < 2000 {
+		return nil, errors.New("geçersiz dönem yılı")
+	}
+	if in.StartMonth == 0 {
+		in.StartMonth = 1
+	}
+	if in.StartMonth < 1 || in.StartMonth > 12 {
+		return nil, errors.New("geçersiz dönem başlangıç ayı")
+	}
+	if in.Name == "" {
+		in.Name = fmt.Sprintf("%d İşletme Projesi", in.FiscalYear)
+	}
+
+	categories, err := s.repo.GetExpenseCategories(ctx, in.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBudgetItems(in.Items, categories); err != nil {
+		return nil, err
+	}
+
+	return &models.Budget{
+		PropertyID: in.PropertyID,
+		FiscalYear: in.FiscalYear,
+		StartMonth: in.StartMonth,
+		Name:       in.Name,
+		Notes:      in.Notes,
+		Items:      in.Items,
+	}, nil
+}
+
+// validateBudgetItems kalemlerin sitenin aktif gider kalemleri olduğunu, tekrar
+// etmediğini ve toplamın sıfırdan büyük olduğunu doğrular
+func validateBudgetItems(items []models.BudgetItem, categories []models.ExpenseCategory) error {
+	if len(items) == 0 {
+		return errors.New("en az bir gider kalemi gerekli")
+	}
+	categoryByID := make(map[string]models.ExpenseCategory, len(categories))
+	for _, c := range categories {
+		categoryByID[c.ID] = c
+	}
+
+	seen := make(map[string]bool, len(items))
+	var total float64
+	for _, item := range items {
+		category, ok := categoryByID[item.CategoryID]
+		if !ok {
+			return fmt.Errorf("gider kalemi bulunamadı: %s", item.CategoryID)
+		}
+		if seen[item.CategoryID] {
+			return fmt.Errorf("%s kalemi birden fazla kez girilmiş", category.Name)
+		}
+		if item.PlannedAmount < 0 {
+			return fmt.Errorf("%s kalemi için tutar negatif olamaz", category.Name)
+		}
+		seen[item.CategoryID] = true
+		total += item.PlannedAmount
+	}
+	if total <= 0 {
+		return errors.New("işletme projesinin toplam tutarı sıfırdan büyük olmalı")
+	}
+	return nil
+}
+
+// GetBudget işletme projesini kalemleriyle getirir
+func (s *FinanceService) GetBudget(ctx context.Context, propertyID, budgetID string) (*models.Budget, error) {
+	return s.repo.GetBudget(ctx, propertyID, budgetID)
+}
+
+// ListBudgets sitenin işletme projelerini listeler
+func (s *FinanceService) ListBudgets(ctx context.Context, propertyID string, year int) ([]models.Budget, error) {
+	return s.repo.ListBudgets(ctx, propertyID, year)
+}
+
+// SubmitBudget taslak projeyi genel kurul onayına sunar
+func (s *FinanceService) SubmitBudget(ctx context.Context, propertyID, budgetID string) (*models.Budget, error) {
+	return s.transitionBudget(ctx, propertyID, budgetID, budgetSubmit, func(b *models.Budget, next string) error {
+		return s.repo.UpdateBudgetStatus(ctx, propertyID, budgetID, b.Status, next, "")
+	})
+}
+
+// ApproveBudget onaya sunulan projeyi genel kurul kararıyla onaylar; aynı dönemin
+// önceki onaylı projesi revize edilmiş sayılır (SUPERSEDED)
+func (s *FinanceService) ApproveBudget(ctx context.Context, propertyID, budgetID, userID, reference string) (*models.Budget, error) {
+	return s.transitionBudget(ctx, propertyID, budgetID, budgetApprove, func(b *models.Budget, next string) error {
+		return s.repo.ApproveBudget(ctx, propertyID, budgetID, userID, reference)
+	})
+}
+
+// RejectBudget onaya sunulan projeyi gerekçesiyle yeniden taslağa çevirir
+func (s *FinanceService) RejectBudget(ctx context.Context, propertyID, budgetID, reason string) (*models.Budget, error) {
+	if reason == "" {
+		return nil, errors.New("red gerekçesi gerekli")
+	}
+	return s.transitionBudget(ctx, propertyID, budgetID, budgetReject, func(b *models.Budget, next string) error {
+		return s.repo.UpdateBudgetStatus(ctx, propertyID, budgetID, b.Status, next, reason)
+	})
+}
+
+func (s *FinanceService) transitionBudget(ctx context.Context, propertyID, budgetID, action string, apply func(*models.Budget, string) error) (*models.Budget, error) {
+	b, err := s.repo.GetBudget(ctx, propertyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := nextBudgetStatus(b.Status, action)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(b, next); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudget(ctx, propertyID, budgetID)
+}
+
+// nextBudgetStatus durum geçişini doğrular: DRAFT → PENDING_APPROVAL → APPROVED,
+// red ile PENDING_APPROVAL → DRAFT. Onaylı proje değiştirilemez; revizyon için
+// yeni proje hazırlanıp onaylanır.
+func nextBudgetStatus(current, action string) (string, error) {
+	switch {
+	case action == budgetSubmit && current == BudgetDraft:
+		return BudgetPendingApproval, nil
+	case action == budgetApprove && current == BudgetPendingApproval:
+		return BudgetApproved, nil
+	case action == budgetReject && current == BudgetPendingApproval:
+		return BudgetDraft, nil
+	case action == budgetSubmit:
+		return "", errors.New("yalnızca taslak işletme projesi onaya sunulabilir")
+	default:
+		return "", errors.New("yalnızca onaya sunulmuş işletme projesi onaylanabilir veya reddedilebilir")
+	}
+}
+
+// fiscalMonthIndex dönemin (yıl/ay) proje dönemi içindeki sırası; 0 ilk ay, 11 son aydır.
+// Dönem proje dışındaysa 0-11 aralığı dışında bir değer döner.
+func fiscalMonthIndex(b *models.Budget, year, month int) int {
+	return (year*12 + month) - (b.FiscalYear*12 + b.StartMonth)
+}
+
+// fiscalPeriod proje döneminin ilk ve son günü
+func fiscalPeriod(b *models.Budget) (time.Time, time.Time) {
+	from := time.Date(b.FiscalYear, time.Month(b.StartMonth), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(1, 0, -1)
+}
+
+// monthlyBudgetAmount yıllık planlanan tutarın dönemin index'inci ayına (0-11) düşen
+// kısmı. Tutar kuruşa bölünür; 12'ye bölünmeyen kuruşlar dönemin ilk aylarına eklenir,
+// böylece 12 ayın toplamı yıllık tutara eşittir.
+func monthlyBudgetAmount(annual float64, index int) float64 {
+	total := int64(math.Round(annual * 100))
+	share := total / 12
+	if int64(index) < total%12 {
+		share++
+	}
+	return float64(share) / 100
+}
+
+// plannedToDate yıllık tutarın proje döneminin ilk months ayına düşen kısmı
+func plannedToDate(annual float64, months int) float64 {
+	if months >= 12 {
+		return annual
+	}
+	var total int64
+	for i := 0; i < months; i++ {
+		total += int64(math.Round(monthlyBudgetAmount(annual, i) * 100))
+	}
+	return float64(total) / 100
+}
+
+// budgetExpenseItems onaylı projeden dönemin aylık gider kalemlerini türetir
+func budgetExpenseItems(b *models.Budget, year, month int) ([]models.ExpenseItem, error) {
+	index := fiscalMonthIndex(b, year, month)
+	if index < 0 || index > 11 {
+		return nil, fmt.Errorf("%d/%02d dönemi %s kapsamında değil", year, month, b.Name)
+	}
+	var items []models.ExpenseItem
+	for _, item := range b.Items {
+		if amount := monthlyBudgetAmount(item.PlannedAmount, index); amount > 0 {
+			items = append(items, models.ExpenseItem{CategoryID: item.CategoryID, Amount: amount})
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s projesinde dağıtılacak tutar yok", b.Name)
+	}
+	return items, nil
+}
+
+// BudgetReport bütçe - gerçekleşen karşılaştırması ve dönem işletme hesabı
+type BudgetReport struct {
+	Budget          *models.Budget      `json:"budget"`
+	PropertyName    string              `json:"property_name"`
+	From            time.Time           `json:"from"` // Proje döneminin ilk günü
+	To              time.Time           `json:"to"`   // Proje döneminin son günü
+	AsOf            time.Time           `json:"as_of"`
+	MonthsElapsed   int                 `json:"months_elapsed"`
+	Lines           []BudgetReportLine  `json:"lines"`
+	Total           BudgetReportLine    `json:"total"`
+	Income          models.BudgetIncome `json:"income"`
+	OperatingResult float64             `json:"operating_result"` // Gelirler - giderler (fazla / açık)
+
+	branding *tenant.TenantBranding
+}
+
+// BudgetReportLine gider kalemi bazında bütçe - gerçekleşen satırı
+type BudgetReportLine struct {
+	CategoryID    string  `json:"category_id,omitempty"`
+	Category      string  `json:"category"`
+	Planned       float64 `json:"planned"`         // Yıllık planlanan
+	PlannedToDate float64 `json:"planned_to_date"` // Geçen aylara düşen plan
+	Actual        float64 `json:"actual"`          // Defterleşmiş gider
+	Pending       float64 `json:"pending"`         // Onay bekleyen gider
+	Variance      float64 `json:"variance"`        // PlannedToDate - Actual (pozitifse bütçe altında)
+	Utilization   float64 `json:"utilization"`     // Actual / Planned (%)
+}
+
+// GetBudgetReport projenin asOf tarihine kadarki bütçe - gerçekleşen raporunu hazırlar.
+// Gerçekleşen giderler ve gelirler defterden, onay bekleyen giderler expenses tablosundan okunur.
+func (s *FinanceService) GetBudgetReport(ctx context.Context, propertyID, budgetID string, asOf time.Time) (*BudgetReport, error) {
+	b, err := s.repo.GetBudget(ctx, propertyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	asOf = truncateDay(asOf)
+
+	from, to := fiscalPeriod(b)
+	end := asOf
+	if end.After(to) {
+		end = to
+	}
+
+	actuals, err := s.repo.GetExpenseActuals(ctx, propertyID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.repo.GetPendingExpenses(ctx, propertyID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	income, err := s.repo.GetBudgetIncome(ctx, propertyID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	name, branding, err := s.repo.GetPropertyBranding(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildBudgetReport(b, actuals, pending, asOf)
+	report.PropertyName = name
+	report.Income = *income
+	report.OperatingResult = roundKurus(income.Assessed + income.LateFees - report.Total.Actual)
+	report.branding = branding
+	return report, nil
+}
+
+// buildBudgetReport proje kalemlerini gerçekleşen ve bekleyen giderlerle eşleştirir.
+// Projede olmayan kalemlerdeki giderler planı sıfır satırlar olarak eklenir.
+func buildBudgetReport(b *models.Budget, actuals, pending []models.CategoryAmount, asOf time.Time) *BudgetReport {
+	from, to := fiscalPeriod(b)
+	report := &BudgetReport{Budget: b, From: from, To: to, AsOf: asOf}
+	switch {
+	case asOf.Before(from):
+		report.MonthsElapsed = 0
+	case asOf.After(to):
+		report.MonthsElapsed = 12
+	default:
+		report.MonthsElapsed = fiscalMonthIndex(b, asOf.Year(), int(asOf.Month())) + 1
+	}
+
+	index := make(map[string]int, len(b.Items))
+	for _, item := range b.Items {
+		index[item.CategoryID] = len(report.Lines)
+		report.Lines = append(report.Lines, BudgetReportLine{
+			CategoryID:    item.CategoryID,
+			Category:      item.Category,
+			Planned:       item.PlannedAmount,
+			PlannedToDate: plannedToDate(item.PlannedAmount, report.MonthsElapsed),
+		})
+	}
+
+	var unbudgeted []BudgetReportLine
+	line := func(a models.CategoryAmount) *BudgetReportLine {
+		if i, ok := index[a.CategoryID]; ok {
+			return &report.Lines[i]
+		}
+		for i := range unbudgeted {
+			if unbudgeted[i].CategoryID == a.CategoryID {
+				return &unbudgeted[i]
+			}
+		}
+		unbudgeted = append(unbudgeted, BudgetReportLine{CategoryID: a.CategoryID, Category: a.Category})
+		return &unbudgeted[len(unbudgeted)-1]
+	}
+	for _, a := range actuals {
+		l := line(a)
+		l.Actual = roundKurus(l.Actual + a.Amount)
+	}
+	for _, a := range pending {
+		l := line(a)
+		l.Pending = roundKurus(l.Pending + a.Amount)
+	}
+	sort.SliceStable(unbudgeted, func(i, j int) bool { return unbudgeted[i].Category < unbudgeted[j].Category })
+	report.Lines = append(report.Lines, unbudgeted...)
+
+	report.Total.Category = "Toplam"
+	for i := range report.Lines {
+		l := &report.Lines[i]
+		l.Variance = roundKurus(l.PlannedToDate - l.Actual)
+		l.Utilization = utilization(l.Actual, l.Planned)
+
+		report.Total.Planned = roundKurus(report.Total.Planned + l.Planned)
+		report.Total.PlannedToDate = roundKurus(report.Total.PlannedToDate + l.PlannedToDate)
+		report.Total.Actual = roundKurus(report.Total.Actual + l.Actual)
+		report.Total.Pending = roundKurus(report.Total.Pending + l.Pending)
+	}
+	report.Total.Variance = roundKurus(report.Total.PlannedToDate - report.Total.Actual)
+	report.Total.Utilization = utilization(report.Total.Actual, report.Total.Planned)
+	return report
+}
+
+func utilization(actual, planned float64) float64 {
+	if planned <= 0 {
+		return 0
+	}
+	return math.Round(actual/planned*1000) / 10
+}
+
+// BudgetReportPDF raporu sitenin logosu ve renkleriyle PDF olarak üretir
+func (s *FinanceService) BudgetReportPDF(ctx context.Context, rep *BudgetReport) ([]byte, error) {
+	return brandedPDF(ctx, rep.branding).GenerateBudgetReport(budgetReportData(rep))
+}
+
+// BudgetReportExcel raporu Excel olarak üretir
+func (s *FinanceService) BudgetReportExcel(rep *BudgetReport) ([]byte, error) {
+	return reports.NewExcelGenerator().GenerateBudgetExcel(budgetReportData(rep))
+}
+
+func budgetReportData(rep *BudgetReport) *reports.BudgetReportData {
+	reportLine := func(l BudgetReportLine) reports.BudgetLine {
+		return reports.BudgetLine{
+			Category:      l.Category,
+			Planned:       l.Planned,
+			PlannedToDate: l.PlannedToDate,
+			Actual:        l.Actual,
+			Pending:       l.Pending,
+			Variance:      l.Variance,
+			Utilization:   l.Utilization,
+		}
+	}
+
+	data := &reports.BudgetReportData{
+		PropertyName:      rep.PropertyName,
+		BudgetName:        rep.Budget.Name,
+		Status:            rep.Budget.Status,
+		ApprovalReference: rep.Budget.ApprovalReference,
+		StartDate:         rep.From.Format("02.01.2006"),
+		EndDate:           rep.To.Format("02.01.2006"),
+		AsOf:              rep.AsOf.Format("02.01.2006"),
+		MonthsElapsed:     rep.MonthsElapsed,
+		Lines:             make([]reports.BudgetLine, len(rep.Lines)),
+		Total:             reportLine(rep.Total),
+		Assessed:          rep.Income.Assessed,
+		LateFees:          rep.Income.LateFees,
+		Collected:         rep.Income.Collected,
+		OperatingResult:   rep.OperatingResult,
+	}
+	for i, l := range rep.Lines {
+		data.Lines[i] = reportLine(l)
+	}
+	return data
+}
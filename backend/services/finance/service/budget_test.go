@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBudget() *models.Budget {
+	return &models.Budget{
+		ID:         "b1",
+		FiscalYear: 2026,
+		StartMonth: 7,
+		Name:       "2026-2027 İşletme Projesi",
+		Status:     BudgetApproved,
+		Items: []models.BudgetItem{
+			{CategoryID: "c1", Category: "Temizlik", PlannedAmount: 12000},
+			{CategoryID: "c2", Category: "Asansör Bakım", PlannedAmount: 1000.05},
+			{CategoryID: "c3", Category: "Peyzaj", PlannedAmount: 0},
+		},
+	}
+}
+
+func TestNextBudgetStatus(t *testing.T) {
+	next, err := nextBudgetStatus(BudgetDraft, budgetSubmit)
+	require.NoError(t, err)
+	assert.Equal(t, BudgetPendingApproval, next)
+
+	next, err = nextBudgetStatus(BudgetPendingApproval, budgetApprove)
+	require.NoError(t, err)
+	assert.Equal(t, BudgetApproved, next)
+
+	next, err = nextBudgetStatus(BudgetPendingApproval, budgetReject)
+	require.NoError(t, err)
+	assert.Equal(t, BudgetDraft, next)
+
+	_, err = nextBudgetStatus(BudgetDraft, budgetApprove)
+	assert.Error(t, err)
+	_, err = nextBudgetStatus(BudgetApproved, budgetSubmit)
+	assert.Error(t, err)
+	_, err = nextBudgetStatus(BudgetSuperseded, budgetReject)
+	assert.Error(t, err)
+}
+
+func TestMonthlyBudgetAmount_SumsToAnnual(t *testing.T) {
+	// 1000,05 TL = 100005 kuruş; 12'ye bölümünden kalan 9 kuruş ilk 9 aya eklenir
+	assert.Equal(t, 83.34, monthlyBudgetAmount(1000.05, 0))
+	assert.Equal(t, 83.34, monthlyBudgetAmount(1000.05, 8))
+	assert.Equal(t, 83.33, monthlyBudgetAmount(1000.05, 9))
+
+	var total float64
+	for i := 0; i < 12; i++ {
+		total = roundKurus(total + monthlyBudgetAmount(1000.05, i))
+	}
+	assert.Equal(t, 1000.05, total)
+	assert.Equal(t, 1000.05, plannedToDate(1000.05, 12))
+	assert.Equal(t, 250.02, plannedToDate(1000.05, 3))
+}
+
+func TestBudgetExpenseItems(t *testing.T) {
+	b := testBudget()
+
+	// Temmuz başlangıçlı projede 2027/06 son aydır
+	items, err := budgetExpenseItems(b, 2027, 6)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExpenseItem{
+		{CategoryID: "c1", Amount: 1000},
+		{CategoryID: "c2", Amount: 83.33},
+	}, items)
+
+	_, err = budgetExpenseItems(b, 2026, 6)
+	assert.Error(t, err)
+	_, err = budgetExpenseItems(b, 2027, 7)
+	assert.Error(t, err)
+}
+
+func TestValidateBudgetItems(t *testing.T) {
+	categories := []models.ExpenseCategory{{ID: "c1", Name: "Temizlik"}, {ID: "c2", Name: "Asansör Bakım"}}
+
+	assert.NoError(t, validateBudgetItems([]models.BudgetItem{{CategoryID: "c1", PlannedAmount: 100}, {CategoryID: "c2"}}, categories))
+	assert.Error(t, validateBudgetItems(nil, categories))
+	assert.Error(t, validateBudgetItems([]models.BudgetItem{{CategoryID: "c9", PlannedAmount: 100}}, categories))
+	assert.Error(t, validateBudgetItems([]models.BudgetItem{{CategoryID: "c1", PlannedAmount: 100}, {CategoryID: "c1", PlannedAmount: 5}}, categories))
+	assert.Error(t, validateBudgetItems([]models.BudgetItem{{CategoryID: "c1", PlannedAmount: -1}}, categories))
+	assert.Error(t, validateBudgetItems([]models.BudgetItem{{CategoryID: "c1"}}, categories))
+}
+
+func TestBuildBudgetReport(t *testing.T) {
+	b := testBudget()
+	actuals := []models.CategoryAmount{
+		{CategoryID: "c1", Category: "Temizlik", Amount: 3500},
+		{CategoryID: "c9", Category: "Boya Badana", Amount: 800},
+	}
+	pending := []models.CategoryAmount{{CategoryID: "c2", Category: "Asansör Bakım", Amount: 150}}
+
+	report := buildBudgetReport(b, actuals, pending, time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 3, report.MonthsElapsed)
+	assert.Equal(t, time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC), report.To)
+	require.Len(t, report.Lines, 4)
+
+	cleaning := report.Lines[0]
+	assert.Equal(t, 3000.0, cleaning.PlannedToDate)
+	assert.Equal(t, 3500.0, cleaning.Actual)
+	assert.Equal(t, -500.0, cleaning.Variance)
+	assert.Equal(t, 29.2, cleaning.Utilization)
+
+	assert.Equal(t, 150.0, report.Lines[1].Pending)
+
+	unbudgeted := report.Lines[3]
+	assert.Equal(t, "Boya Badana", unbudgeted.Category)
+	assert.Equal(t, 0.0, unbudgeted.Planned)
+	assert.Equal(t, -800.0, unbudgeted.Variance)
+
+	assert.Equal(t, 13000.05, report.Total.Planned)
+	assert.Equal(t, 3250.02, report.Total.PlannedToDate)
+	assert.Equal(t, 4300.0, report.Total.Actual)
+	assert.Equal(t, -1049.98, report.Total.Variance)
+
+	before := buildBudgetReport(b, nil, nil, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 0, before.MonthsElapsed)
+	after := buildBudgetReport(b, nil, nil, time.Date(2027, 8, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 12, after.MonthsElapsed)
+	assert.Equal(t, 12000.0, after.Lines[0].PlannedToDate)
+}
+
+func TestBudgetReportData(t *testing.T) {
+	report := buildBudgetReport(testBudget(), nil, nil, time.Date(2026, 7, 31, 0, 0, 0, 0, time.UTC))
+	report.OperatingResult = 120
+
+	data := budgetReportData(report)
+	assert.Equal(t, "01.07.2026", data.StartDate)
+	assert.Equal(t, "30.06.2027", data.EndDate)
+	assert.Len(t, data.Lines, 3)
+	assert.Equal(t, "Toplam", data.Total.Category)
+	assert.Equal(t, 120.0, data.OperatingResult)
+}
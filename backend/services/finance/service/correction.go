@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// Düzeltme tipleri
+const (
+	CorrectionAdjustment     = "ADJUSTMENT"     // Tahakkuk artırımı / indirimi
+	CorrectionCreditNote     = "CREDIT_NOTE"    // Daireye alacak dekontu
+	CorrectionRedistribution = "REDISTRIBUTION" // Gider kaleminin yeniden dağıtımı
+)
+
+// AssessmentCorrectionInput tahakkuk düzeltme parametreleri. Amount pozitifse borç
+// artar, negatifse indirim yapılır. Kalem verilirse tahakkuk detayındaki tutarı da düzeltilir.
+type AssessmentCorrectionInput struct {
+	PropertyID        string
+	AssessmentID      string
+	ExpenseCategoryID string
+	Amount            float64
+	Reason            string
+	UserID            string
+}
+
+// CreditNoteInput alacak dekontu parametreleri
+type CreditNoteInput struct {
+	PropertyID   string
+	UnitID       string
+	AssessmentID string // İsteğe bağlı; dekontun ilgili olduğu tahakkuk
+	Amount       float64
+	Reason       string
+	UserID       string
+}
+
+// RedistributionInput tek gider kaleminin dönem tahakkuklarına yeniden dağıtımı.
+// Amount kalemin yeni toplam tutarıdır (ör. düzeltilen fatura tutarı).
+type RedistributionInput struct {
+	PropertyID  string
+	PeriodYear  int
+	PeriodMonth int
+	CategoryID  string
+	Amount      float64
+	Reason      string
+	UserID      string
+	DryRun      bool
+}
+
+// RedistributionResult yeniden dağıtım sonucu; yalnızca payı değişen daireler için düzeltme üretilir
+type RedistributionResult struct {
+	PeriodYear     int                            `json:"period_year"`
+	PeriodMonth    int                            `json:"period_month"`
+	CategoryID     string                         `json:"category_id"`
+	Category       string                         `json:"category"`
+	PreviousAmount float64                        `json:"previous_amount"`
+	NewAmount      float64                        `json:"new_amount"`
+	DryRun         bool                           `json:"dry_run"`
+	Corrections    []*models.AssessmentCorrection `json:"corrections"`
+}
+
+// CorrectAssessment tahakkuku gerekçeli olarak artırır veya indirir. İndirimin ödenmiş
+// aidata denk gelen kısmı daire avansına aktarılır.
+func (s *FinanceService) CorrectAssessment(ctx context.Context, in *AssessmentCorrectionInput) (*models.AssessmentCorrection, error) {
+	if err := validateCorrection(in.Amount, in.Reason); err != nil {
+		return nil, err
+	}
+	c := &models.AssessmentCorrection{
+		PropertyID:        in.PropertyID,
+		AssessmentID:      in.AssessmentID,
+		Type:              CorrectionAdjustment,
+		ExpenseCategoryID: in.ExpenseCategoryID,
+		Amount:            roundKurus(in.Amount),
+		Reason:            strings.TrimSpace(in.Reason),
+		CreatedBy:         in.UserID,
+	}
+	if err := s.repo.CorrectAssessment(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// IssueCreditNote daireye alacak dekontu düzenler; tutar sonraki tahakkuklara mahsup edilir
+func (s *FinanceService) IssueCreditNote(ctx context.Context, in *CreditNoteInput) (*models.AssessmentCorrection, error) {
+	if in.Amount <= 0 {
+		return nil, errors.New("dekont tutarı sıfırdan büyük olmalı")
+	}
+	if err := validateCorrection(in.Amount, in.Reason); err != nil {
+		return nil, err
+	}
+	c := &models.AssessmentCorrection{
+		PropertyID:   in.PropertyID,
+		UnitID:       in.UnitID,
+		AssessmentID: in.AssessmentID,
+		Type:         CorrectionCreditNote,
+		Amount:       -roundKurus(in.Amount),
+		Reason:       strings.TrimSpace(in.Reason),
+		CreatedBy:    in.UserID,
+	}
+	if err := s.repo.CreateCreditNote(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RedistributeCategory gider kalemini yeni tutarla dönemin dairelerine yeniden dağıtır
+// ve her dairenin eski payı ile yeni payı arasındaki farkı düzeltme olarak işler.
+// Dağıtım, tahakkuk motorundaki kurallarla güncel daire bilgilerine göre yapılır.
+func (s *FinanceService) RedistributeCategory(ctx context.Context, in *RedistributionInput) (*RedistributionResult, error) {
+	if in.PeriodMonth < 1 || in.PeriodMonth > 12 || in.PeriodYear < 2000 {
+		return nil, errors.New("geçersiz dönem")
+	}
+	if in.Amount < 0 {
+		return nil, errors.New("kalem tutarı negatif olamaz")
+	}
+	if strings.TrimSpace(in.Reason) == "" {
+		return nil, errors.New("düzeltme gerekçesi girilmeli")
+	}
+
+	categories, err := s.repo.GetExpenseCategories(ctx, in.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	var category *models.ExpenseCategory
+	for i := range categories {
+		if categories[i].ID == in.CategoryID {
+			category = &categories[i]
+			break
+		}
+	}
+	if category == nil {
+		return nil, fmt.Errorf("gider kalemi bulunamadı: %s", in.CategoryID)
+	}
+
+	current, err := s.repo.GetCategoryShares(ctx, in.PropertyID, in.PeriodYear, in.PeriodMonth, in.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return nil, fmt.Errorf("%d/%02d dönemi için tahakkuk bulunamadı", in.PeriodYear, in.PeriodMonth)
+	}
+
+	units, err := s.repo.GetUnitsForDistribution(ctx, in.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	var next []expenseShare
+	if in.Amount > 0 {
+		if next, err = distributeExpense(in.Amount, *category, units); err != nil {
+			return nil, err
+		}
+	}
+
+	corrections, previous, err := redistributionCorrections(current, next, units)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range corrections {
+		c.PropertyID = in.PropertyID
+		c.Type = CorrectionRedistribution
+		c.ExpenseCategoryID = category.ID
+		c.Category = category.Name
+		c.Reason = strings.TrimSpace(in.Reason)
+		c.CreatedBy = in.UserID
+	}
+
+	if !in.DryRun && len(corrections) > 0 {
+		if err := s.repo.RedistributeCategory(ctx, corrections); err != nil {
+			return nil, err
+		}
+	}
+
+	return &RedistributionResult{
+		PeriodYear:     in.PeriodYear,
+		PeriodMonth:    in.PeriodMonth,
+		CategoryID:     category.ID,
+		Category:       category.Name,
+		PreviousAmount: previous,
+		NewAmount:      roundKurus(in.Amount),
+		DryRun:         in.DryRun,
+		Corrections:    corrections,
+	}, nil
+}
+
+// ListAssessmentCorrections sitenin düzeltme ve alacak dekontu geçmişi
+func (s *FinanceService) ListAssessmentCorrections(ctx context.Context, propertyID, unitID, correctionType string) ([]models.AssessmentCorrection, error) {
+	return s.repo.ListAssessmentCorrections(ctx, propertyID, unitID, correctionType)
+}
+
+// validateCorrection düzeltme tutarını ve gerekçesini doğrular
+func validateCorrection(amount float64, reason string) error {
+	if toKurus(amount) == 0 {
+		return errors.New("düzeltme tutarı sıfır olamaz")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("düzeltme gerekçesi girilmeli")
+	}
+	return nil
+}
+
+// redistributionCorrections dönem tahakkuklarındaki mevcut kalem payları ile yeni dağıtımı
+// karşılaştırır; payı değişen her tahakkuk için fark tutarında düzeltme üretir ve kalemin
+// önceki toplamını döner. Yeni dağıtımda pay alan ama dönem tahakkuku olmayan daire hata verir.
+func redistributionCorrections(current []models.CategoryShare, next []expenseShare, units []models.Unit) ([]*models.AssessmentCorrection, float64, error) {
+	names := make(map[string]string, len(units))
+	for _, u := range units {
+		names[u.ID] = u.Name()
+	}
+	nextByUnit := make(map[string]expenseShare, len(next))
+	for _, n := range next {
+		nextByUnit[n.UnitID] = n
+	}
+
+	var previous int64
+	corrections := []*models.AssessmentCorrection{}
+	for _, c := range current {
+		previous += toKurus(c.Amount)
+		n := nextByUnit[c.UnitID] // Yeni dağıtımda pay almayan dairenin payı sıfırlanır
+		delete(nextByUnit, c.UnitID)
+
+		delta := toKurus(n.Amount) - toKurus(c.Amount)
+		if delta == 0 {
+			continue
+		}
+		corrections = append(corrections, &models.AssessmentCorrection{
+			AssessmentID:     c.AssessmentID,
+			UnitID:           c.UnitID,
+			UnitName:         names[c.UnitID],
+			Amount:           float64(delta) / 100,
+			CategoryBefore:   c.Amount,
+			CalculationBasis: n.Basis,
+		})
+	}
+	for _, n := range next {
+		if _, missing := nextByUnit[n.UnitID]; missing {
+			return nil, 0, fmt.Errorf("%s dairesinin bu dönem için tahakkuku yok", names[n.UnitID])
+		}
+	}
+	return corrections, float64(previous) / 100, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCorrection(t *testing.T) {
+	assert.NoError(t, validateCorrection(-150, "Fatura düzeltmesi"))
+	assert.Error(t, validateCorrection(0.004, "Yuvarlama"))
+	assert.Error(t, validateCorrection(100, "  "))
+}
+
+func TestRedistributionCorrections(t *testing.T) {
+	units := testUnits()
+	category := models.ExpenseCategory{Name: "Asansör Bakımı", DistributionType: DistributionEqual,
+		AppliesToCommercial: false, AppliesToGroundFloor: true}
+
+	// 900 TL üç daireye 300'er dağıtılmıştı; fatura 1200 TL'ye düzeltildi
+	current := []models.CategoryShare{
+		{AssessmentID: "a1", UnitID: "u1", Amount: 300},
+		{AssessmentID: "a2", UnitID: "u2", Amount: 300},
+		{AssessmentID: "a3", UnitID: "u3", Amount: 300},
+		{AssessmentID: "a4", UnitID: "u4", Amount: 0},
+	}
+	next, err := distributeExpense(1200, category, units)
+	require.NoError(t, err)
+
+	corrections, previous, err := redistributionCorrections(current, next, units)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, previous)
+	require.Len(t, corrections, 3)
+	for _, c := range corrections {
+		assert.Equal(t, 100.0, c.Amount)
+		assert.Equal(t, 300.0, c.CategoryBefore)
+		assert.NotEmpty(t, c.CalculationBasis)
+	}
+	assert.Equal(t, "a1", corrections[0].AssessmentID)
+	assert.Equal(t, "A-1", corrections[0].UnitName)
+}
+
+func TestRedistributionCorrections_RemovedShareAndUnchanged(t *testing.T) {
+	units := testUnits()
+	current := []models.CategoryShare{
+		{AssessmentID: "a1", UnitID: "u1", Amount: 250},
+		{AssessmentID: "a2", UnitID: "u2", Amount: 250},
+	}
+	next := []expenseShare{{UnitID: "u1", Amount: 250, Basis: "Eşit"}}
+
+	corrections, _, err := redistributionCorrections(current, next, units)
+	require.NoError(t, err)
+	require.Len(t, corrections, 1)
+	assert.Equal(t, "a2", corrections[0].AssessmentID)
+	assert.Equal(t, -250.0, corrections[0].Amount)
+	assert.Empty(t, corrections[0].CalculationBasis)
+
+	// Kalem tamamen kaldırıldı
+	corrections, previous, err := redistributionCorrections(current, nil, units)
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, previous)
+	assert.Len(t, corrections, 2)
+}
+
+func TestRedistributionCorrections_UnitWithoutAssessment(t *testing.T) {
+	units := testUnits()
+	current := []models.CategoryShare{{AssessmentID: "a1", UnitID: "u1", Amount: 100}}
+	next := []expenseShare{{UnitID: "u1", Amount: 50}, {UnitID: "u3", Amount: 50}}
+
+	_, _, err := redistributionCorrections(current, next, units)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "A-3")
+}
@@ -4,18 +4,22 @@ import (
 	"context"
 	"time"
 
+	"github.com/siteeksen/backend/pkg/ledger"
 	"github.com/siteeksen/backend/services/finance/models"
 	"github.com/siteeksen/backend/services/finance/repository"
 )
 
 // FinanceService finans servisi
 type FinanceService struct {
-	repo *repository.FinanceRepository
+	repo        *repository.FinanceRepository
+	gateway     PaymentGateway
+	callbackURL string
 }
 
-// NewFinanceService yeni servis oluşturur
-func NewFinanceService(repo *repository.FinanceRepository) *FinanceService {
-	return &FinanceService{repo: repo}
+// NewFinanceService yeni servis oluşturur. callbackURL, bankanın 3DS doğrulaması
+// sonrasında kullanıcıyı yönlendireceği ödeme callback adresidir.
+func NewFinanceService(repo *repository.FinanceRepository, gateway PaymentGateway, callbackURL string) *FinanceService {
+	return &FinanceService{repo: repo, gateway: gateway, callbackURL: callbackURL}
 }
 
 // DebtStatusResponse borç durumu yanıtı
@@ -26,6 +30,8 @@ type DebtStatusResponse struct {
 	OverdueMonths  int       `json:"overdue_months"`
 	NextDueDate    time.Time `json:"next_due_date"`
 	NextDueAmount  float64   `json:"next_due_amount"`
+	// Takipteki ödeme planları; plana alınan tahakkuklar OverdueAmount'a dahil edilmez
+	PaymentPlans []*PaymentPlanStatus `json:"payment_plans,omitempty"`
 }
 
 // GetDebtStatus anlık borç durumu hesaplar
@@ -46,14 +52,24 @@ func (s *FinanceService) GetDebtStatus(ctx context.Context, userID, propertyID s
 		nextDue = &models.Assessment{}
 	}
 
-	return &DebtStatusResponse{
+	plans, err := s.repo.GetResidentPaymentPlans(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &DebtStatusResponse{
 		HasDebt:        balance > 0,
 		CurrentBalance: balance,
 		OverdueAmount:  overdueInfo.Amount,
 		OverdueMonths:  overdueInfo.Months,
 		NextDueDate:    nextDue.DueDate,
 		NextDueAmount:  nextDue.TotalAmount,
-	}, nil
+	}
+	today := truncateDay(time.Now())
+	for i := range plans {
+		status.PaymentPlans = append(status.PaymentPlans, paymentPlanStatus(&plans[i], today))
+	}
+	return status, nil
 }
 
 // GetAssessments aidat listesi getirir
@@ -64,44 +80,10 @@ func (s *FinanceService) GetAssessments(ctx context.Context, userID string, year
 	return s.repo.GetAssessments(ctx, userID, year)
 }
 
-// GetAssessmentDetails aidat detayı getirir
-func (s *FinanceService) GetAssessmentDetails(ctx context.Context, assessmentID string) (*models.AssessmentDetail, error) {
-	return s.repo.GetAssessmentDetails(ctx, assessmentID)
-}
-
-// PaymentResult ödeme sonucu
-type PaymentResult struct {
-	PaymentID   string `json:"payment_id"`
-	CheckoutURL string `json:"checkout_url,omitempty"`
-	Status      string `json:"status"`
-}
-
-// CreatePayment ödeme başlatır
-func (s *FinanceService) CreatePayment(ctx context.Context, userID string, assessmentIDs []string, method, cardToken string) (*PaymentResult, error) {
-	// Toplam tutar hesapla
-	totalAmount, err := s.repo.CalculateTotalAmount(ctx, assessmentIDs)
-	if err != nil {
-		return nil, err
-	}
-
-	// Ödeme kaydı oluştur
-	paymentID, err := s.repo.CreatePayment(ctx, userID, assessmentIDs, totalAmount, method)
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO: Ödeme gateway entegrasyonu (iyzico, Param vb.)
-	// Şimdilik mock checkout URL döndür
-	checkoutURL := ""
-	if method == "CREDIT_CARD" {
-		checkoutURL = "https://checkout.siteeksen.com/pay/" + paymentID
-	}
-
-	return &PaymentResult{
-		PaymentID:   paymentID,
-		CheckoutURL: checkoutURL,
-		Status:      "PENDING",
-	}, nil
+// GetAssessmentDetails aidat detayı getirir. Sakin yalnızca kendi (veya vekaleten ödediği)
+// dairesinin tahakkukunu görür; managedPropertyID yöneticinin defter okuma yetkisi olan site.
+func (s *FinanceService) GetAssessmentDetails(ctx context.Context, userID, managedPropertyID, assessmentID string) (*models.AssessmentDetail, error) {
+	return s.repo.GetAssessmentDetails(ctx, userID, managedPropertyID, assessmentID)
 }
 
 // GetPaymentHistory ödeme geçmişi getirir
@@ -109,6 +91,14 @@ func (s *FinanceService) GetPaymentHistory(ctx context.Context, userID string) (
 	return s.repo.GetPaymentHistory(ctx, userID)
 }
 
+// GetTrialBalance sitenin mizanını getirir; tarih verilmezse bugün itibarıyla
+func (s *FinanceService) GetTrialBalance(ctx context.Context, propertyID string, asOf time.Time) (*ledger.TrialBalance, error) {
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	return s.repo.GetTrialBalance(ctx, propertyID, asOf)
+}
+
 // ConsumptionSummary tüketim özeti
 type ConsumptionSummary struct {
 	MeterType string                   `json:"meter_type"`
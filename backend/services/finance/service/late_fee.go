@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// Gecikme tazminatı işletme yöntemleri (site ayarı: late_fee_method)
+const (
+	LateFeeDaily   = "DAILY"   // Gün bazında kıst hesap
+	LateFeeMonthly = "MONTHLY" // Başlayan her ay için tam oran
+)
+
+// KMK md. 20: ödemede geciken kat maliki aylık %5 gecikme tazminatı öder
+const maxLateFeePercentage = 5.0
+
+// LateFeeRunResult gecikme tazminatı çalıştırma sonucu
+type LateFeeRunResult struct {
+	AsOf     time.Time               `json:"as_of"`
+	Scanned  int                     `json:"scanned"`
+	Updated  int                     `json:"updated"`
+	TotalFee float64                 `json:"total_fee"`
+	Accruals []models.LateFeeAccrual `json:"accruals"`
+}
+
+// CalculateLateFee vadeden itibaren geçen gün sayısına göre toplam gecikme tazminatını hesaplar.
+// monthlyRate aylık oran (0.05 = %5). MONTHLY yönteminde başlayan her 30 günlük dönem
+// tam ay sayılır; DAILY yönteminde aylık oran 30 güne bölünerek gün bazında uygulanır.
+func CalculateLateFee(amount float64, daysOverdue int, monthlyRate float64, method string) float64 {
+	if daysOverdue <= 0 || amount <= 0 || monthlyRate <= 0 {
+		return 0
+	}
+	if method == LateFeeDaily {
+		return roundKurus(amount * monthlyRate * float64(daysOverdue) / 30)
+	}
+	months := (daysOverdue + 29) / 30
+	return roundKurus(amount * monthlyRate * float64(months))
+}
+
+// AccrueLateFees vadesi geçmiş tahakkuklara asOf tarihine kadar gecikme tazminatı işletir.
+// Her tahakkuk için en son işletilen tarih saklanır ve yalnızca aradaki süre için
+// tazminat hesaplanır; aynı gün tekrar çalıştırmak ek tutar doğurmaz.
+// propertyID boşsa tüm siteler işlenir (zamanlanmış görev).
+func (s *FinanceService) AccrueLateFees(ctx context.Context, propertyID string, asOf time.Time) (*LateFeeRunResult, error) {
+	asOf = truncateDay(asOf)
+
+	candidates, err := s.repo.GetLateFeeCandidates(ctx, propertyID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LateFeeRunResult{AsOf: asOf, Scanned: len(candidates), Accruals: []models.LateFeeAccrual{}}
+	for i := range candidates {
+		c := &candidates[i]
+
+		accrual, ok := computeLateFee(c, asOf)
+		if !ok {
+			continue
+		}
+
+		applied, err := s.repo.ApplyLateFee(ctx, c, accrual.Fee, accrual.AccruedThrough)
+		if err != nil {
+			return result, fmt.Errorf("gecikme tazminatı işlenemedi (%s): %w", c.AssessmentID, err)
+		}
+		if !applied {
+			continue
+		}
+
+		result.Updated++
+		if accrual.Fee > 0 {
+			result.TotalFee = roundKurus(result.TotalFee + accrual.Fee)
+			result.Accruals = append(result.Accruals, accrual)
+		}
+	}
+	return result, nil
+}
+
+// computeLateFee son işletme tarihinden asOf'a kadar doğan ek tazminatı hesaplar.
+// Tazminat ödenmemiş ana para üzerinden hesaplanır; ödemelerin tazminata sayılan
+// kısmı (dağıtım politikasına göre) ana paradan düşülmez. Fon katkı payı aidattan
+// ayrı izlendiğinden tazminat yalnızca aidat (base_amount) üzerinden işletilir.
+// İşlem gerektirmeyen (zaten OVERDUE ve yeni gün yok) tahakkuklar için false döner.
+func computeLateFee(c *models.LateFeeCandidate, asOf time.Time) (models.LateFeeAccrual, bool) {
+	dueDate := truncateDay(c.DueDate)
+	from := dueDate
+	if c.AccruedThrough != nil && c.AccruedThrough.After(from) {
+		from = truncateDay(*c.AccruedThrough)
+	}
+
+	daysFrom := daysBetween(dueDate, from)
+	daysTo := daysBetween(dueDate, asOf)
+	if daysTo <= daysFrom && c.Status == "OVERDUE" {
+		return models.LateFeeAccrual{}, false
+	}
+
+	principal := math.Min(c.BaseAmount, c.BaseAmount-(c.PaidAmount-c.LateFeePaid-c.ReservePaid))
+	if principal < 0 {
+		principal = 0
+	}
+	principal = roundKurus(principal)
+
+	rate := math.Min(c.FeePercentage, maxLateFeePercentage) / 100
+	fee := 0.0
+	through := from
+	if daysTo > daysFrom {
+		through = asOf
+		fee = roundKurus(CalculateLateFee(principal, daysTo, rate, c.FeeMethod) -
+			CalculateLateFee(principal, daysFrom, rate, c.FeeMethod))
+	}
+
+	return models.LateFeeAccrual{
+		AssessmentID:   c.AssessmentID,
+		UnitID:         c.UnitID,
+		Period:         fmt.Sprintf("%d-%02d", c.PeriodYear, c.PeriodMonth),
+		DaysOverdue:    daysTo,
+		Principal:      principal,
+		Fee:            fee,
+		TotalLateFee:   roundKurus(c.LateFee + fee),
+		AccruedThrough: through,
+	}, true
+}
+
+// RunLateFeeScheduler gecikme tazminatı işletmesini başlangıçta ve ardından her interval'de çalıştırır.
+// Önce ödeme planları değerlendirilir; böylece bozulan planların tahakkuklarına aynı
+// çalıştırmada tazminat işletilir. ctx iptal edilene kadar bloklar; goroutine içinde başlatılmalıdır.
+func (s *FinanceService) RunLateFeeScheduler(ctx context.Context, interval time.Duration) {
+	run := func() {
+		plans, err := s.EvaluatePaymentPlans(ctx, "", time.Now())
+		if err != nil {
+			log.Printf("Ödeme planları değerlendirilemedi: %v", err)
+		} else if plans.Updated > 0 {
+			log.Printf("Ödeme planları değerlendirildi: %d plan tarandı, %d tamamlandı, %d bozuldu",
+				plans.Scanned, plans.Completed, plans.Broken)
+		}
+
+		result, err := s.AccrueLateFees(ctx, "", time.Now())
+		if err != nil {
+			log.Printf("Gecikme tazminatı işletilemedi: %v", err)
+			return
+		}
+		log.Printf("Gecikme tazminatı işletildi: %d tahakkuk tarandı, %d güncellendi, toplam %.2f TL",
+			result.Scanned, result.Updated, result.TotalFee)
+	}
+
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
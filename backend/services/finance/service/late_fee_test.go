@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateLateFee_Daily(t *testing.T) {
+	assert.Equal(t, 0.0, CalculateLateFee(1000, 0, 0.05, LateFeeDaily))
+	assert.Equal(t, 16.67, CalculateLateFee(1000, 10, 0.05, LateFeeDaily))
+	assert.Equal(t, 50.0, CalculateLateFee(1000, 30, 0.05, LateFeeDaily))
+	assert.Equal(t, 75.0, CalculateLateFee(1000, 45, 0.05, LateFeeDaily))
+}
+
+func TestCalculateLateFee_MonthlyStartedMonths(t *testing.T) {
+	assert.Equal(t, 50.0, CalculateLateFee(1000, 1, 0.05, LateFeeMonthly))
+	assert.Equal(t, 50.0, CalculateLateFee(1000, 30, 0.05, LateFeeMonthly))
+	assert.Equal(t, 100.0, CalculateLateFee(1000, 31, 0.05, LateFeeMonthly))
+}
+
+func lateFeeCandidate(method string) *models.LateFeeCandidate {
+	return &models.LateFeeCandidate{
+		AssessmentID:  "a1",
+		UnitID:        "u1",
+		PeriodYear:    2026,
+		PeriodMonth:   1,
+		BaseAmount:    1200,
+		DueDate:       time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC),
+		Status:        "PENDING",
+		FeePercentage: 5,
+		FeeMethod:     method,
+	}
+}
+
+func TestComputeLateFee_IncrementalAndRerunnable(t *testing.T) {
+	c := lateFeeCandidate(LateFeeDaily)
+
+	first, ok := computeLateFee(c, time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, 15, first.DaysOverdue)
+	assert.Equal(t, 30.0, first.Fee)
+
+	// İlk çalıştırma uygulanmış gibi ilerlet
+	c.LateFee = first.TotalLateFee
+	c.AccruedThrough = &first.AccruedThrough
+	c.Status = "OVERDUE"
+
+	_, ok = computeLateFee(c, time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok, "aynı gün tekrar çalıştırma tazminat doğurmamalı")
+
+	second, ok := computeLateFee(c, time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, 30.0, second.Fee)
+	assert.Equal(t, 60.0, second.TotalLateFee)
+}
+
+func TestComputeLateFee_MonthlyChargesOncePerStartedMonth(t *testing.T) {
+	c := lateFeeCandidate(LateFeeMonthly)
+
+	first, _ := computeLateFee(c, time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 60.0, first.Fee)
+
+	c.LateFee = first.TotalLateFee
+	c.AccruedThrough = &first.AccruedThrough
+	c.Status = "OVERDUE"
+
+	same, _ := computeLateFee(c, time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 0.0, same.Fee)
+
+	next, _ := computeLateFee(c, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 60.0, next.Fee)
+}
+
+func TestComputeLateFee_PaymentsReducePrincipal(t *testing.T) {
+	c := lateFeeCandidate(LateFeeMonthly)
+	c.LateFee = 60
+	c.PaidAmount = 660 // 60 tazminat + 600 ana para
+	c.LateFeePaid = 60
+	accrued := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	c.AccruedThrough = &accrued
+	c.Status = "OVERDUE"
+
+	accrual, ok := computeLateFee(c, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, 600.0, accrual.Principal)
+	assert.Equal(t, 30.0, accrual.Fee)
+}
+
+func TestComputeLateFee_PrincipalFirstPayments(t *testing.T) {
+	c := lateFeeCandidate(LateFeeMonthly)
+	c.LateFee = 60
+	c.PaidAmount = 660 // Tamamı ana paraya sayıldı, tazminat açık
+	accrued := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	c.AccruedThrough = &accrued
+	c.Status = "OVERDUE"
+
+	accrual, _ := computeLateFee(c, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 540.0, accrual.Principal)
+	assert.Equal(t, 27.0, accrual.Fee)
+}
+
+func TestComputeLateFee_RateCappedAtLegalMaximum(t *testing.T) {
+	c := lateFeeCandidate(LateFeeMonthly)
+	c.FeePercentage = 10
+
+	accrual, _ := computeLateFee(c, time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 60.0, accrual.Fee)
+}
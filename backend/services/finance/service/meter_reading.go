@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/siteeksen/backend/services/finance/metering"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ErrMeterReadingsEmpty yüklenen listede okuma satırı yok
+var ErrMeterReadingsEmpty = errors.New("listede sayaç satırı yok")
+
+// ErrFutureReadingDate okuma tarihi bugünden sonra
+var ErrFutureReadingDate = errors.New("okuma tarihi ileri bir tarih olamaz")
+
+// MeterReadingUploadInput doldurulmuş sayaç okuma listesi. ReadingDate boşsa bugün.
+type MeterReadingUploadInput struct {
+	PropertyID  string
+	UserID      string
+	ReadingDate time.Time
+	File        io.Reader
+}
+
+// MeterReadingApprovalInput incelemedeki okumanın onayı. Value verilirse listedeki okuma
+// yerine yazılır; MeterReplaced sayacın değiştiğini, okumanın yeni sayacın başlangıç
+// değeri olduğunu belirtir.
+type MeterReadingApprovalInput struct {
+	PropertyID    string
+	ReviewID      string
+	UserID        string
+	Value         *float64
+	MeterReplaced bool
+	Note          string
+}
+
+// GetMeterReadingTemplate sahada doldurulacak okuma şablonu (Excel); meterType boşsa tüm sayaçlar
+func (s *FinanceService) GetMeterReadingTemplate(ctx context.Context, propertyID, meterType string) ([]byte, error) {
+	units, err := s.repo.GetMeterReadingTemplateUnits(ctx, propertyID, meterType)
+	if err != nil {
+		return nil, err
+	}
+	return reports.NewExcelGenerator().GenerateMeterReadingTemplate(units)
+}
+
+// UploadMeterReadings doldurulmuş okuma şablonunu yükler. Geçerli okumalar tüketimleriyle
+// kaydedilir; önceki okumadan küçük veya olağan tüketimin çok üstündeki okumalar
+// incelemeye alınır. Her satırın sonucu raporda döner.
+func (s *FinanceService) UploadMeterReadings(ctx context.Context, in *MeterReadingUploadInput) (*models.MeterReadingUpload, error) {
+	date, err := meterReadingDate(in.ReadingDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := metering.ReadSheet(in.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrMeterReadingsEmpty
+	}
+	return s.repo.RecordMeterReadings(ctx, in.PropertyID, in.UserID, date, rows)
+}
+
+// ListMeterReadingReviews okuma incelemeleri (status: PENDING, APPROVED, REJECTED; boşsa tümü)
+func (s *FinanceService) ListMeterReadingReviews(ctx context.Context, propertyID, status string) ([]models.MeterReadingReview, error) {
+	return s.repo.ListMeterReadingReviews(ctx, propertyID, status)
+}
+
+// ApproveMeterReadingReview incelemedeki okumayı onaylar ve kaydeder
+func (s *FinanceService) ApproveMeterReadingReview(ctx context.Context, in *MeterReadingApprovalInput) (*models.MeterReadingReview, error) {
+	if in.Value != nil && *in.Value < 0 {
+		return nil, errors.New("okuma negatif olamaz")
+	}
+	return s.repo.ApproveMeterReadingReview(ctx, in.PropertyID, in.ReviewID, in.UserID, in.Value, in.MeterReplaced, in.Note)
+}
+
+// RejectMeterReadingReview incelemedeki okumayı reddeder
+func (s *FinanceService) RejectMeterReadingReview(ctx context.Context, propertyID, reviewID, userID, note string) (*models.MeterReadingReview, error) {
+	return s.repo.RejectMeterReadingReview(ctx, propertyID, reviewID, userID, note)
+}
+
+// meterReadingDate okuma tarihini doğrular; boşsa bugün, ileri tarih olamaz
+func meterReadingDate(date, now time.Time) (time.Time, error) {
+	today := truncateDay(now)
+	if date.IsZero() {
+		return today, nil
+	}
+	date = truncateDay(date)
+	if date.After(today) {
+		return time.Time{}, ErrFutureReadingDate
+	}
+	return date, nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterReadingDate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+
+	date, err := meterReadingDate(time.Time{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), date)
+
+	date, err = meterReadingDate(time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), date)
+
+	_, err = meterReadingDate(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), now)
+	assert.ErrorIs(t, err, ErrFutureReadingDate)
+}
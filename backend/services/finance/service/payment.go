@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/services/finance/allocation"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+)
+
+// Ödeme yöntemleri
+const (
+	PaymentMethodCreditCard = "CREDIT_CARD"
+	PaymentMethodSavedCard  = "SAVED_CARD"
+)
+
+// PaymentStatusAmountMismatch tahsil edilen tutarı beklenenden farklı, tahakkuklara
+// dağıtılmamış ödeme; iade edilene kadar yönetici incelemesinde kalır
+const PaymentStatusAmountMismatch = "AMOUNT_MISMATCH"
+
+// PaymentGateway 3DS ödeme sağlayıcısı (pkg/payment.PaymentService)
+type PaymentGateway interface {
+	ProcessAssessmentPayment(input *payment.AssessmentPaymentInput) (*payment.PaymentResult, error)
+	Complete3DSPayment(paymentID, conversationID string) (*payment.PaymentResult, error)
+	RefundPayment(transactionID string, amount float64) (*payment.PaymentResult, error)
+}
+
+// CreatePaymentInput ödeme başlatma parametreleri
+type CreatePaymentInput struct {
+	UserID        string
+	UnitID        string   // Seçim yoksa ödeme yapılacak daire; boşsa sakinin dairesi
+	AssessmentIDs []string // Boşsa dairenin tüm açık tahakkukları vade sırasıyla kapatılır
+	Amount        float64  // Boşsa seçilen tahakkukların toplam borcu; fazlası avansa aktarılır
+	Method        string   // CREDIT_CARD, SAVED_CARD
+	Card          *payment.CardInput
+	SaveCard      bool
+	ClientIP      string
+}
+
+// PaymentResult ödeme sonucu
+type PaymentResult struct {
+	PaymentID          string                     `json:"payment_id"`
+	Status             string                     `json:"status"` // PENDING, COMPLETED, FAILED
+	Amount             float64                    `json:"amount"`
+	ThreeDSHTMLContent string                     `json:"three_ds_html_content,omitempty"` // Base64 HTML, tarayıcıda açılır
+	ErrorMessage       string                     `json:"error_message,omitempty"`
+	Allocations        []models.PaymentAllocation `json:"allocations,omitempty"`
+	Credit             float64                    `json:"credit,omitempty"` // Daire avansına aktarılan tutar
+}
+
+// CreatePayment seçilen (veya dairenin tüm açık) aidatları için 3DS ödeme başlatır.
+// Tutar site politikasına göre vadesi en eski tahakkuktan başlayarak dağıtılır; kısmi
+// ödeme yapılabilir, borcu aşan kısım daire avansı olur. Ödeme PENDING olarak
+// kaydedilir; sonuç bankanın callback'i ile HandlePaymentCallback'te kesinleşir.
+func (s *FinanceService) CreatePayment(ctx context.Context, in *CreatePaymentInput) (*PaymentResult, error) {
+	if s.gateway == nil {
+		return nil, errors.New("ödeme altyapısı yapılandırılmamış")
+	}
+	if err := validatePaymentCard(in.Method, in.Card); err != nil {
+		return nil, err
+	}
+	if in.Amount < 0 {
+		return nil, errors.New("geçersiz ödeme tutarı")
+	}
+
+	ids := uniqueIDs(in.AssessmentIDs)
+	unitID, propertyID := in.UnitID, ""
+	if len(ids) == 0 {
+		var err error
+		unitID, propertyID, err = s.repo.GetResidentUnit(ctx, in.UserID, in.UnitID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	assessments, err := s.repo.GetPayableAssessments(ctx, in.UserID, unitID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if len(assessments) != len(ids) {
+			return nil, errors.New("seçilen aidatlardan bazıları bulunamadı veya ödenmiş")
+		}
+		unitID, propertyID = assessments[0].UnitID, assessments[0].PropertyID
+	}
+
+	targets := make([]allocation.Target, len(assessments))
+	var outstanding int64
+	for i, a := range assessments {
+		if a.UnitID != unitID {
+			return nil, errors.New("tek ödemede yalnızca bir dairenin aidatları ödenebilir")
+		}
+		targets[i] = payableTarget(a)
+		outstanding += toKurus(a.Outstanding)
+	}
+
+	total := roundKurus(in.Amount)
+	if total == 0 {
+		total = float64(outstanding) / 100
+	}
+	if total <= 0 {
+		return nil, errors.New("ödenecek borç bulunmuyor")
+	}
+
+	policy, err := s.repo.GetAllocationPolicy(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	allocations, items := planPayment(total, unitID, assessments, targets, policy)
+
+	payer, err := s.repo.GetPayer(ctx, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentID, err := s.repo.CreatePayment(ctx, in.UserID, unitID, allocations, total, in.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.gateway.ProcessAssessmentPayment(&payment.AssessmentPaymentInput{
+		UserID:         in.UserID,
+		UserName:       payer.FirstName,
+		UserSurname:    payer.LastName,
+		UserEmail:      payer.Email,
+		UserPhone:      payer.Phone,
+		UserIP:         in.ClientIP,
+		ConversationID: paymentID,
+		AssessmentIDs:  ids,
+		Items:          items,
+		TotalAmount:    total,
+		Card:           in.Card,
+		SaveCard:       in.SaveCard,
+		Use3DSecure:    true,
+		CallbackURL:    s.callbackURL,
+	})
+	if err != nil {
+		raw, _ := json.Marshal(map[string]string{"error": err.Error()})
+		if ferr := s.repo.FailPayment(ctx, paymentID, raw); ferr != nil {
+			log.Printf("Ödeme %s FAILED yapılamadı: %v", paymentID, ferr)
+		}
+		return nil, errors.New("ödeme sağlayıcısına ulaşılamadı")
+	}
+	if !result.Success {
+		if err := s.repo.FailPayment(ctx, paymentID, result.RawResponse); err != nil {
+			return nil, err
+		}
+		return &PaymentResult{PaymentID: paymentID, Status: "FAILED", Amount: total, ErrorMessage: result.ErrorMessage}, nil
+	}
+
+	return &PaymentResult{
+		PaymentID:          paymentID,
+		Status:             "PENDING",
+		Amount:             total,
+		ThreeDSHTMLContent: result.ThreeDSContent,
+	}, nil
+}
+
+// HandlePaymentCallback bankanın 3DS callback'ini işler: doğrulama başarılıysa ödemeyi
+// iyzico'da tamamlar, tutarı tahakkuklara dağıtır ve deftere işler; değilse ödemeyi
+// FAILED yapar. Tahsil edilen tutar beklenenden farklıysa ödeme AMOUNT_MISMATCH olur ve
+// tutar iade edilir. Aynı callback birden fazla kez gelirse mevcut durum döner.
+func (s *FinanceService) HandlePaymentCallback(ctx context.Context, cb *models.PaymentCallback) (*PaymentResult, error) {
+	if s.gateway == nil {
+		return nil, errors.New("ödeme altyapısı yapılandırılmamış")
+	}
+	if cb.ConversationID == "" {
+		return nil, errors.New("ödeme referansı eksik")
+	}
+
+	p, err := s.repo.GetPayment(ctx, cb.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != "PENDING" {
+		return &PaymentResult{PaymentID: p.ID, Status: p.Status, Amount: p.Amount}, nil
+	}
+
+	if err := checkCallback(cb); err != nil {
+		raw, _ := json.Marshal(cb)
+		if ferr := s.repo.FailPayment(ctx, p.ID, raw); ferr != nil {
+			return nil, ferr
+		}
+		return &PaymentResult{PaymentID: p.ID, Status: "FAILED", Amount: p.Amount, ErrorMessage: err.Error()}, nil
+	}
+
+	result, err := s.gateway.Complete3DSPayment(cb.PaymentID, p.ID)
+	if err != nil {
+		// Ağ hatası: ödeme PENDING kalır, callback tekrarlandığında yeniden denenir
+		return nil, fmt.Errorf("ödeme tamamlanamadı: %w", err)
+	}
+	if !result.Success {
+		if err := s.repo.FailPayment(ctx, p.ID, result.RawResponse); err != nil {
+			return nil, err
+		}
+		return &PaymentResult{PaymentID: p.ID, Status: "FAILED", Amount: p.Amount, ErrorMessage: result.ErrorMessage}, nil
+	}
+	if result.PaidPrice > 0 && toKurus(result.PaidPrice) != toKurus(p.Amount) {
+		// Tahsil edilen tutar beklenenden farklı: tahakkuklara dağıtılmaz, iyzico'dan iade edilir.
+		// İade başarısız olursa ödeme AMOUNT_MISMATCH durumunda yönetici incelemesinde kalır.
+		log.Printf("Ödeme %s tutar uyuşmazlığı: beklenen %.2f, tahsil edilen %.2f", p.ID, p.Amount, result.PaidPrice)
+		if err := s.repo.HoldMismatchedPayment(ctx, p.ID, result.TransactionID, result.PaidPrice, result.RawResponse); err != nil {
+			return nil, err
+		}
+		status := PaymentStatusAmountMismatch
+		if raw, err := gatewayRefund(s.gateway, result.TransactionID, result.PaidPrice); err != nil {
+			log.Printf("Ödeme %s iade edilemedi, incelemeye alındı: %v", p.ID, err)
+		} else if err := s.repo.RefundMismatchedPayment(ctx, p.ID, raw); err != nil {
+			return nil, err
+		} else {
+			status = "REFUNDED"
+		}
+		return &PaymentResult{PaymentID: p.ID, Status: status, Amount: p.Amount, ErrorMessage: "tahsil edilen tutar uyuşmuyor"}, nil
+	}
+
+	allocations, err := s.repo.CompletePayment(ctx, p.ID, result.TransactionID, result.RawResponse)
+	if err != nil {
+		return nil, err
+	}
+	s.syncUnitPaymentPlans(ctx, p.UnitID)
+	var applied int64
+	for _, a := range allocations {
+		applied += toKurus(a.Amount)
+	}
+	return &PaymentResult{
+		PaymentID:   p.ID,
+		Status:      "COMPLETED",
+		Amount:      p.Amount,
+		Allocations: allocations,
+		Credit:      float64(toKurus(p.Amount)-applied) / 100,
+	}, nil
+}
+
+// RefundPayment tamamlanmış ödemeyi iade eder. Kartla yapılan ödemeler önce iyzico'da
+// iade edilir; ardından tahakkuklara dağılım, avans ve defter kayıtları geri alınır.
+// İncelemedeki tutar uyuşmazlığı ödemelerinde yalnızca tahsil edilen tutar iade edilir.
+func (s *FinanceService) RefundPayment(ctx context.Context, propertyID, paymentID, userID string) (*models.PaymentRefund, error) {
+	p, err := s.repo.GetPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p.PropertyID != propertyID {
+		return nil, repository.ErrPaymentNotFound
+	}
+	if p.Status == PaymentStatusAmountMismatch {
+		return s.refundMismatchedPayment(ctx, p)
+	}
+	if p.Status != "COMPLETED" {
+		return nil, fmt.Errorf("%s durumundaki ödeme iade edilemez", p.Status)
+	}
+
+	var raw []byte
+	if p.PaymentMethod == PaymentMethodCreditCard || p.PaymentMethod == PaymentMethodSavedCard {
+		if s.gateway == nil {
+			return nil, errors.New("ödeme altyapısı yapılandırılmamış")
+		}
+		raw, err = gatewayRefund(s.gateway, p.TransactionID, p.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refund, err := s.repo.RefundPayment(ctx, p.ID, userID, raw)
+	if err != nil {
+		return nil, err
+	}
+	s.syncUnitPaymentPlans(ctx, p.UnitID)
+	return refund, nil
+}
+
+// ListMismatchedPayments otomatik iadesi yapılamamış, incelemede bekleyen tutar uyuşmazlığı ödemeleri
+func (s *FinanceService) ListMismatchedPayments(ctx context.Context, propertyID string) ([]models.Payment, error) {
+	return s.repo.ListMismatchedPayments(ctx, propertyID)
+}
+
+// refundMismatchedPayment incelemedeki tutar uyuşmazlığı ödemesinde tahsil edilen tutarı
+// iyzico'dan iade eder. Ödeme tahakkuklara dağıtılmadığından geri alınacak dağılım yoktur.
+func (s *FinanceService) refundMismatchedPayment(ctx context.Context, p *models.Payment) (*models.PaymentRefund, error) {
+	if s.gateway == nil {
+		return nil, errors.New("ödeme altyapısı yapılandırılmamış")
+	}
+	if p.TransactionID == "" {
+		return nil, errors.New("ödemenin sağlayıcı işlem numarası yok")
+	}
+	raw, err := gatewayRefund(s.gateway, p.TransactionID, p.PaidAmount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RefundMismatchedPayment(ctx, p.ID, raw); err != nil {
+		return nil, err
+	}
+	return &models.PaymentRefund{PaymentID: p.ID, Amount: p.PaidAmount, Allocations: []models.PaymentAllocation{}}, nil
+}
+
+// gatewayRefund tutarı ödeme sağlayıcısında iade eder ve sağlayıcı yanıtını döner
+func gatewayRefund(gw PaymentGateway, transactionID string, amount float64) ([]byte, error) {
+	result, err := gw.RefundPayment(transactionID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("iade ödeme sağlayıcısına iletilemedi: %w", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("iade reddedildi: %s", result.ErrorMessage)
+	}
+	return result.RawResponse, nil
+}
+
+// planPayment tutarın ödeme anındaki borçlara dağılımını planlar. Tüm açık tahakkuklar
+// (pay almayanlar sıfır tutarla) ödemeye bağlanır; böylece tamamlanma anında dağıtım
+// aynı küme üzerinde yeniden hesaplanabilir. Sepet kalemleri yalnızca pay alan
+// tahakkuklardan ve varsa avans kaleminden oluşur.
+func planPayment(total float64, unitID string, assessments []models.PayableAssessment, targets []allocation.Target, policy allocation.Policy) ([]models.PaymentAllocation, []payment.PaymentItem) {
+	plan := allocation.Allocate(total, targets, policy)
+	lines := make(map[string]allocation.Line, len(plan.Lines))
+	for _, l := range plan.Lines {
+		lines[l.AssessmentID] = l
+	}
+
+	allocations := make([]models.PaymentAllocation, len(assessments))
+	var items []payment.PaymentItem
+	for i, a := range assessments {
+		l := lines[a.ID]
+		allocations[i] = models.PaymentAllocation{AssessmentID: a.ID, Amount: l.Amount(), LateFee: l.LateFee}
+		if l.Amount() > 0 {
+			items = append(items, payment.PaymentItem{
+				ID:     a.ID,
+				Name:   fmt.Sprintf("%d/%02d Aidat", a.PeriodYear, a.PeriodMonth),
+				Amount: l.Amount(),
+			})
+		}
+	}
+	if plan.Credit > 0 {
+		items = append(items, payment.PaymentItem{ID: unitID, Name: "Aidat Avansı", Amount: plan.Credit})
+	}
+	return allocations, items
+}
+
+// payableTarget açık tahakkuku dağıtım hedefine çevirir
+func payableTarget(a models.PayableAssessment) allocation.Target {
+	lateFee := math.Min(a.LateFeeDue, a.Outstanding)
+	return allocation.Target{
+		AssessmentID: a.ID,
+		DueDate:      a.DueDate,
+		LateFeeDue:   lateFee,
+		PrincipalDue: roundKurus(a.Outstanding - lateFee),
+	}
+}
+
+// checkCallback banka 3DS doğrulama sonucunu kontrol eder (mdStatus=1 başarılı doğrulama)
+func checkCallback(cb *models.PaymentCallback) error {
+	if cb.Status != "success" {
+		return errors.New("3D Secure doğrulaması başarısız")
+	}
+	if cb.MDStatus != "1" {
+		return fmt.Errorf("3D Secure doğrulaması başarısız (mdStatus=%s)", cb.MDStatus)
+	}
+	if cb.PaymentID == "" {
+		return errors.New("iyzico ödeme numarası eksik")
+	}
+	return nil
+}
+
+func validatePaymentCard(method string, card *payment.CardInput) error {
+	switch method {
+	case PaymentMethodCreditCard:
+		if card == nil || card.Number == "" || card.ExpireMonth == "" || card.ExpireYear == "" || card.Cvc == "" {
+			return errors.New("kart bilgileri eksik")
+		}
+	case PaymentMethodSavedCard:
+		if card == nil || card.Token == "" || card.UserKey == "" {
+			return errors.New("kayıtlı kart bilgisi eksik")
+		}
+	default:
+		return fmt.Errorf("desteklenmeyen ödeme yöntemi: %s", method)
+	}
+	return nil
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+func toKurus(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// Ödeme planı durumları
+const (
+	PlanActive    = "ACTIVE"
+	PlanCompleted = "COMPLETED"
+	PlanBroken    = "BROKEN"
+	PlanCancelled = "CANCELLED"
+)
+
+// Taksit durumları
+const (
+	InstallmentPending = "PENDING"
+	InstallmentPartial = "PARTIAL"
+	InstallmentPaid    = "PAID"
+	InstallmentMissed  = "MISSED"
+)
+
+// Taksit sayısı ve ödemesiz süre sınırları
+const (
+	maxInstallments  = 36
+	maxPlanGraceDays = 30
+)
+
+// PaymentPlanInput ödeme planı oluşturma parametreleri
+type PaymentPlanInput struct {
+	PropertyID       string
+	UnitID           string
+	AssessmentIDs    []string
+	InstallmentCount int
+	FirstDueDate     time.Time // İlk taksit vadesi; sonrakiler aylık
+	WaiveLateFee     float64   // Plan sürdükçe affedilecek tazminat tutarı
+	GraceDays        int       // Taksit vadesinden sonra planın bozulmadan beklediği gün
+	Notes            string
+	UserID           string
+}
+
+// PaymentPlanRunResult ödeme planı takip çalıştırması sonucu
+type PaymentPlanRunResult struct {
+	AsOf      time.Time `json:"as_of"`
+	Scanned   int       `json:"scanned"`
+	Updated   int       `json:"updated"`
+	Completed int       `json:"completed"`
+	Broken    int       `json:"broken"`
+}
+
+// PaymentPlanStatus borç durumu kartındaki plan özeti
+type PaymentPlanStatus struct {
+	PlanID                string    `json:"plan_id"`
+	UnitName              string    `json:"unit_name"`
+	TotalAmount           float64   `json:"total_amount"`
+	PaidAmount            float64   `json:"paid_amount"`
+	RemainingAmount       float64   `json:"remaining_amount"`
+	PaidInstallments      int       `json:"paid_installments"`
+	InstallmentCount      int       `json:"installment_count"`
+	NextInstallmentDate   time.Time `json:"next_installment_date,omitempty"`
+	NextInstallmentAmount float64   `json:"next_installment_amount"`
+	OverdueAmount         float64   `json:"overdue_amount"` // Vadesi geçmiş taksit tutarı (ödemesiz süre içinde)
+}
+
+// CreatePaymentPlan seçilen vadesi geçmiş tahakkukları aylık taksitlere böler. İstenirse
+// ödenmemiş gecikme tazminatının bir kısmı plan sürdüğü müddetçe affedilir; plan
+// bozulursa affedilen tutar geri yüklenir.
+func (s *FinanceService) CreatePaymentPlan(ctx context.Context, in *PaymentPlanInput) (*models.PaymentPlan, error) {
+	if len(in.AssessmentIDs) == 0 {
+		return nil, errors.New("plana alınacak tahakkuk seçilmedi")
+	}
+	if in.InstallmentCount < 1 || in.InstallmentCount > maxInstallments {
+		return nil, fmt.Errorf("taksit sayısı 1 ile %d arasında olmalı", maxInstallments)
+	}
+	if in.GraceDays < 0 || in.GraceDays > maxPlanGraceDays {
+		return nil, fmt.Errorf("ödemesiz süre 0 ile %d gün arasında olmalı", maxPlanGraceDays)
+	}
+	if in.WaiveLateFee < 0 {
+		return nil, errors.New("affedilecek tazminat negatif olamaz")
+	}
+	today := truncateDay(time.Now())
+	first := truncateDay(in.FirstDueDate)
+	if in.FirstDueDate.IsZero() || first.Before(today) {
+		return nil, errors.New("ilk taksit vadesi bugünden önce olamaz")
+	}
+
+	assessments, err := s.repo.GetPlanCandidates(ctx, in.PropertyID, in.UnitID, uniqueStrings(in.AssessmentIDs))
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePlanAssessments(assessments, len(uniqueStrings(in.AssessmentIDs)), today); err != nil {
+		return nil, err
+	}
+
+	fees := make([]float64, len(assessments))
+	var outstanding, feeDue int64
+	for i, a := range assessments {
+		fees[i] = a.LateFeeDue
+		outstanding += toKurus(a.Outstanding)
+		feeDue += toKurus(a.LateFeeDue)
+	}
+	if toKurus(in.WaiveLateFee) > feeDue {
+		return nil, fmt.Errorf("affedilecek tutar ödenmemiş tazminatı (%.2f TL) aşamaz", float64(feeDue)/100)
+	}
+	waivers := distributeWaiver(fees, in.WaiveLateFee)
+	for i := range assessments {
+		assessments[i].WaivedLateFee = waivers[i]
+	}
+
+	total := float64(outstanding-toKurus(in.WaiveLateFee)) / 100
+	if total <= 0 {
+		return nil, errors.New("taksitlendirilecek borç kalmadı")
+	}
+
+	plan := &models.PaymentPlan{
+		PropertyID:       in.PropertyID,
+		UnitID:           in.UnitID,
+		TotalAmount:      total,
+		WaivedLateFee:    roundKurus(in.WaiveLateFee),
+		InstallmentCount: in.InstallmentCount,
+		GraceDays:        in.GraceDays,
+		Notes:            in.Notes,
+		CreatedBy:        in.UserID,
+		Assessments:      assessments,
+		Installments:     splitInstallments(total, in.InstallmentCount, first),
+	}
+	if err := s.repo.CreatePaymentPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPaymentPlan(ctx, in.PropertyID, plan.ID)
+}
+
+// ListPaymentPlans sitenin ödeme planları
+func (s *FinanceService) ListPaymentPlans(ctx context.Context, propertyID, unitID, status string) ([]models.PaymentPlan, error) {
+	return s.repo.ListPaymentPlans(ctx, propertyID, unitID, status)
+}
+
+// GetPaymentPlan ödeme planı detayı
+func (s *FinanceService) GetPaymentPlan(ctx context.Context, propertyID, planID string) (*models.PaymentPlan, error) {
+	return s.repo.GetPaymentPlan(ctx, propertyID, planID)
+}
+
+// CancelPaymentPlan aktif planı iptal eder; affedilen tazminat geri yüklenir
+func (s *FinanceService) CancelPaymentPlan(ctx context.Context, propertyID, planID, reason, userID string) (*models.PaymentPlan, error) {
+	plan, err := s.repo.GetPaymentPlan(ctx, propertyID, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Status != PlanActive {
+		return nil, fmt.Errorf("%s durumundaki plan iptal edilemez", plan.Status)
+	}
+
+	evaluatePaymentPlan(plan, truncateDay(time.Now()))
+	if plan.Status == PlanActive || plan.Status == PlanBroken {
+		plan.Status = PlanCancelled
+		plan.CloseReason = reason
+		if plan.CloseReason == "" {
+			plan.CloseReason = "Yönetim tarafından iptal edildi"
+		}
+	}
+	ok, err := s.repo.UpdatePaymentPlanProgress(ctx, plan, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("plan başka bir işlemle güncellendi, lütfen tekrar deneyin")
+	}
+	return s.repo.GetPaymentPlan(ctx, propertyID, planID)
+}
+
+// EvaluatePaymentPlans aktif planlara yapılan ödemeleri taksitlere işler; tamamlanan
+// planları kapatır, ödemesiz süre dolmuş taksidi olan planları bozulmuş sayar.
+// propertyID boşsa tüm siteler işlenir (zamanlanmış görev).
+func (s *FinanceService) EvaluatePaymentPlans(ctx context.Context, propertyID string, asOf time.Time) (*PaymentPlanRunResult, error) {
+	asOf = truncateDay(asOf)
+	plans, err := s.repo.GetActivePaymentPlans(ctx, propertyID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaymentPlanRunResult{AsOf: asOf, Scanned: len(plans)}
+	for i := range plans {
+		p := &plans[i]
+		if !evaluatePaymentPlan(p, asOf) {
+			continue
+		}
+		ok, err := s.repo.UpdatePaymentPlanProgress(ctx, p, "")
+		if err != nil {
+			return result, fmt.Errorf("ödeme planı güncellenemedi (%s): %w", p.ID, err)
+		}
+		if !ok {
+			continue
+		}
+		result.Updated++
+		switch p.Status {
+		case PlanCompleted:
+			result.Completed++
+		case PlanBroken:
+			result.Broken++
+		}
+	}
+	return result, nil
+}
+
+// syncUnitPaymentPlans ödeme / iade sonrası dairenin aktif planlarını günceller.
+// Hata ödeme akışını bozmaz, yalnızca loglanır; günlük görev tutarlılığı sağlar.
+func (s *FinanceService) syncUnitPaymentPlans(ctx context.Context, unitID string) {
+	if unitID == "" {
+		return
+	}
+	plans, err := s.repo.GetActivePaymentPlans(ctx, "", unitID)
+	if err != nil {
+		log.Printf("Ödeme planları alınamadı (%s): %v", unitID, err)
+		return
+	}
+	now := truncateDay(time.Now())
+	for i := range plans {
+		if !evaluatePaymentPlan(&plans[i], now) {
+			continue
+		}
+		if _, err := s.repo.UpdatePaymentPlanProgress(ctx, &plans[i], ""); err != nil {
+			log.Printf("Ödeme planı güncellenemedi (%s): %v", plans[i].ID, err)
+		}
+	}
+}
+
+// validatePlanAssessments seçilen tahakkukların plana alınabilirliğini kontrol eder
+func validatePlanAssessments(assessments []models.PaymentPlanAssessment, requested int, today time.Time) error {
+	if len(assessments) != requested {
+		return errors.New("seçilen tahakkuklardan bazıları bu daireye ait değil")
+	}
+	for _, a := range assessments {
+		if a.PlanID != "" {
+			return fmt.Errorf("%s dönemi zaten bir ödeme planında", a.Period)
+		}
+		if toKurus(a.Outstanding) <= 0 {
+			return fmt.Errorf("%s dönemi borcu ödenmiş", a.Period)
+		}
+		if !truncateDay(a.DueDate).Before(today) {
+			return fmt.Errorf("%s döneminin vadesi henüz geçmedi", a.Period)
+		}
+	}
+	return nil
+}
+
+// splitInstallments toplamı aylık taksitlere böler. Kuruş farkı ilk taksitlere
+// eklenir; vade günü ay sonunu aşarsa ayın son günü kullanılır.
+func splitInstallments(total float64, count int, first time.Time) []models.PaymentPlanInstallment {
+	totalKurus := toKurus(total)
+	base := totalKurus / int64(count)
+	remainder := totalKurus % int64(count)
+
+	installments := make([]models.PaymentPlanInstallment, count)
+	for i := range installments {
+		amount := base
+		if int64(i) < remainder {
+			amount++
+		}
+		installments[i] = models.PaymentPlanInstallment{
+			InstallmentNo: i + 1,
+			DueDate:       periodDueDate(first.Year(), int(first.Month())+i, first.Day()),
+			Amount:        float64(amount) / 100,
+			Status:        InstallmentPending,
+		}
+	}
+	return installments
+}
+
+// distributeWaiver affedilecek tutarı tahakkuklara ödenmemiş tazminatları oranında
+// dağıtır; kuruş farkı en eski tahakkuklardan başlanarak eklenir.
+func distributeWaiver(fees []float64, waive float64) []float64 {
+	waivers := make([]float64, len(fees))
+	var totalFee int64
+	for _, f := range fees {
+		totalFee += toKurus(f)
+	}
+	waiveKurus := min(toKurus(waive), totalFee)
+	if waiveKurus <= 0 || totalFee == 0 {
+		return waivers
+	}
+
+	shares := make([]int64, len(fees))
+	var assigned int64
+	for i, f := range fees {
+		shares[i] = waiveKurus * toKurus(f) / totalFee
+		assigned += shares[i]
+	}
+	for i := 0; assigned < waiveKurus; i = (i + 1) % len(fees) {
+		if shares[i] < toKurus(fees[i]) {
+			shares[i]++
+			assigned++
+		}
+	}
+	for i, s := range shares {
+		waivers[i] = float64(s) / 100
+	}
+	return waivers
+}
+
+// evaluatePaymentPlan plan tahakkuklarına plan açıldıktan sonra yapılan ödemeleri
+// taksitlere sırayla dağıtır ve plan durumunu belirler. Toplam ödenmişse plan
+// COMPLETED, ödemesiz süresi asOf'tan önce dolmuş eksik bir taksit varsa BROKEN olur.
+// Planda değişiklik olduysa true döner.
+func evaluatePaymentPlan(p *models.PaymentPlan, asOf time.Time) bool {
+	before := *p
+	beforeInstallments := append([]models.PaymentPlanInstallment(nil), p.Installments...)
+
+	var paid int64
+	for _, a := range p.Assessments {
+		if d := toKurus(a.PaidAmount) - toKurus(a.PaidAtStart); d > 0 {
+			paid += d
+		}
+	}
+	paid = min(paid, toKurus(p.TotalAmount))
+	p.PaidAmount = float64(paid) / 100
+
+	remaining := paid
+	missed := 0
+	for i := range p.Installments {
+		inst := &p.Installments[i]
+		amount := toKurus(inst.Amount)
+		covered := min(remaining, amount)
+		remaining -= covered
+		inst.PaidAmount = float64(covered) / 100
+
+		switch {
+		case covered == amount:
+			inst.Status = InstallmentPaid
+		case truncateDay(inst.DueDate).AddDate(0, 0, p.GraceDays).Before(asOf):
+			inst.Status = InstallmentMissed
+			if missed == 0 {
+				missed = inst.InstallmentNo
+			}
+		case covered > 0:
+			inst.Status = InstallmentPartial
+		default:
+			inst.Status = InstallmentPending
+		}
+	}
+
+	switch {
+	case paid >= toKurus(p.TotalAmount):
+		p.Status = PlanCompleted
+		p.CloseReason = ""
+	case missed > 0:
+		p.Status = PlanBroken
+		p.CloseReason = fmt.Sprintf("%d. taksit ödenmedi", missed)
+	default:
+		p.Status = PlanActive
+	}
+
+	if before.Status != p.Status || toKurus(before.PaidAmount) != paid {
+		return true
+	}
+	for i := range p.Installments {
+		if beforeInstallments[i].Status != p.Installments[i].Status ||
+			toKurus(beforeInstallments[i].PaidAmount) != toKurus(p.Installments[i].PaidAmount) {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentPlanStatus borç durumu kartı için planın güncel özeti
+func paymentPlanStatus(p *models.PaymentPlan, asOf time.Time) *PaymentPlanStatus {
+	st := &PaymentPlanStatus{
+		PlanID:           p.ID,
+		UnitName:         p.UnitName,
+		TotalAmount:      p.TotalAmount,
+		PaidAmount:       p.PaidAmount,
+		RemainingAmount:  float64(toKurus(p.TotalAmount)-toKurus(p.PaidAmount)) / 100,
+		InstallmentCount: len(p.Installments),
+	}
+	var overdue int64
+	for _, inst := range p.Installments {
+		if inst.Status == InstallmentPaid {
+			st.PaidInstallments++
+			continue
+		}
+		open := toKurus(inst.Amount) - toKurus(inst.PaidAmount)
+		if truncateDay(inst.DueDate).Before(asOf) {
+			overdue += open
+			continue
+		}
+		if st.NextInstallmentDate.IsZero() {
+			st.NextInstallmentDate = inst.DueDate
+			st.NextInstallmentAmount = float64(open) / 100
+		}
+	}
+	st.OverdueAmount = float64(overdue) / 100
+	return st
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
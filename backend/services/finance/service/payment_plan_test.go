@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPaymentPlan() *models.PaymentPlan {
+	first := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	return &models.PaymentPlan{
+		ID:          "plan1",
+		Status:      PlanActive,
+		TotalAmount: 1000.01,
+		GraceDays:   5,
+		Assessments: []models.PaymentPlanAssessment{
+			{AssessmentID: "a1", PaidAtStart: 100, PaidAmount: 100},
+			{AssessmentID: "a2", PaidAtStart: 0, PaidAmount: 0},
+		},
+		Installments: splitInstallments(1000.01, 3, first),
+	}
+}
+
+func TestSplitInstallments(t *testing.T) {
+	installments := splitInstallments(1000.01, 3, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC))
+	require.Len(t, installments, 3)
+
+	assert.Equal(t, 333.34, installments[0].Amount)
+	assert.Equal(t, 333.34, installments[1].Amount)
+	assert.Equal(t, 333.33, installments[2].Amount)
+	assert.Equal(t, 3, installments[2].InstallmentNo)
+	assert.Equal(t, InstallmentPending, installments[0].Status)
+
+	// Vade günü ay sonuna çekilir
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), installments[1].DueDate)
+	assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), installments[2].DueDate)
+}
+
+func TestDistributeWaiver(t *testing.T) {
+	assert.Equal(t, []float64{0, 0}, distributeWaiver([]float64{50, 100}, 0))
+	assert.Equal(t, []float64{25, 50}, distributeWaiver([]float64{50, 100}, 75))
+	// 10 kuruş: 3,33 / 6,66 sonrası kalan 1 kuruş en eski tahakkuka
+	assert.Equal(t, []float64{0.04, 0.06}, distributeWaiver([]float64{1, 2}, 0.1))
+	// Tazminatı olmayan tahakkuka af düşmez
+	assert.Equal(t, []float64{0, 30}, distributeWaiver([]float64{0, 30}, 30))
+}
+
+func TestValidatePlanAssessments(t *testing.T) {
+	today := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	overdue := models.PaymentPlanAssessment{Period: "2026-01", DueDate: time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), Outstanding: 500}
+
+	assert.NoError(t, validatePlanAssessments([]models.PaymentPlanAssessment{overdue}, 1, today))
+	assert.Error(t, validatePlanAssessments([]models.PaymentPlanAssessment{overdue}, 2, today))
+
+	inPlan := overdue
+	inPlan.PlanID = "plan0"
+	assert.Error(t, validatePlanAssessments([]models.PaymentPlanAssessment{inPlan}, 1, today))
+
+	paid := overdue
+	paid.Outstanding = 0
+	assert.Error(t, validatePlanAssessments([]models.PaymentPlanAssessment{paid}, 1, today))
+
+	notDue := overdue
+	notDue.DueDate = today
+	assert.Error(t, validatePlanAssessments([]models.PaymentPlanAssessment{notDue}, 1, today))
+}
+
+func TestEvaluatePaymentPlan_TracksPaymentsAgainstInstallments(t *testing.T) {
+	p := testPaymentPlan()
+	p.Assessments[0].PaidAmount = 300 // Plan sonrası 200
+	p.Assessments[1].PaidAmount = 250 // Plan sonrası 250
+
+	changed := evaluatePaymentPlan(p, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	assert.True(t, changed)
+	assert.Equal(t, PlanActive, p.Status)
+	assert.Equal(t, 450.0, p.PaidAmount)
+	assert.Equal(t, InstallmentPaid, p.Installments[0].Status)
+	assert.Equal(t, 116.66, p.Installments[1].PaidAmount)
+	assert.Equal(t, InstallmentPartial, p.Installments[1].Status)
+	assert.Equal(t, InstallmentPending, p.Installments[2].Status)
+
+	assert.False(t, evaluatePaymentPlan(p, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestEvaluatePaymentPlan_BrokenAfterGracePeriod(t *testing.T) {
+	p := testPaymentPlan()
+
+	// İlk taksit 31.01 vadeli, 5 gün ödemesiz süre 05.02'de doluyor
+	assert.False(t, evaluatePaymentPlan(p, time.Date(2026, 2, 5, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, PlanActive, p.Status)
+
+	assert.True(t, evaluatePaymentPlan(p, time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, PlanBroken, p.Status)
+	assert.Equal(t, InstallmentMissed, p.Installments[0].Status)
+	assert.Equal(t, "1. taksit ödenmedi", p.CloseReason)
+}
+
+func TestEvaluatePaymentPlan_Completed(t *testing.T) {
+	p := testPaymentPlan()
+	p.Assessments[0].PaidAmount = 600
+	p.Assessments[1].PaidAmount = 600 // Fazlası plan toplamıyla sınırlanır
+
+	assert.True(t, evaluatePaymentPlan(p, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, PlanCompleted, p.Status)
+	assert.Equal(t, 1000.01, p.PaidAmount)
+	for _, inst := range p.Installments {
+		assert.Equal(t, InstallmentPaid, inst.Status)
+	}
+}
+
+func TestEvaluatePaymentPlan_RefundReopensInstallment(t *testing.T) {
+	p := testPaymentPlan()
+	p.Assessments[1].PaidAmount = 333.34
+	evaluatePaymentPlan(p, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, InstallmentPaid, p.Installments[0].Status)
+
+	p.Assessments[1].PaidAmount = 0
+	assert.True(t, evaluatePaymentPlan(p, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, InstallmentPending, p.Installments[0].Status)
+	assert.Equal(t, 0.0, p.PaidAmount)
+}
+
+func TestPaymentPlanStatus(t *testing.T) {
+	p := testPaymentPlan()
+	p.Assessments[1].PaidAmount = 400
+	asOf := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	evaluatePaymentPlan(p, asOf)
+	require.Equal(t, PlanActive, p.Status)
+
+	st := paymentPlanStatus(p, asOf)
+	assert.Equal(t, 1, st.PaidInstallments)
+	assert.Equal(t, 600.01, st.RemainingAmount)
+	assert.Equal(t, 266.68, st.OverdueAmount) // 2. taksit 28.02 vadeli, ödemesiz sürede
+	assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), st.NextInstallmentDate)
+	assert.Equal(t, 333.33, st.NextInstallmentAmount)
+}
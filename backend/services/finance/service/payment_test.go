@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/payment/iyzicotest"
+	"github.com/siteeksen/backend/services/finance/allocation"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ PaymentGateway = (*payment.PaymentService)(nil)
+
+func TestCheckCallback(t *testing.T) {
+	assert.NoError(t, checkCallback(&models.PaymentCallback{Status: "success", MDStatus: "1", PaymentID: "p1", ConversationID: "c1"}))
+	assert.Error(t, checkCallback(&models.PaymentCallback{Status: "failure", MDStatus: "1", PaymentID: "p1"}))
+	assert.Error(t, checkCallback(&models.PaymentCallback{Status: "success", MDStatus: "0", PaymentID: "p1"}))
+	assert.Error(t, checkCallback(&models.PaymentCallback{Status: "success", MDStatus: "1"}))
+}
+
+func TestValidatePaymentCard(t *testing.T) {
+	card := &payment.CardInput{Number: iyzicotest.CardSuccess, ExpireMonth: "12", ExpireYear: "2030", Cvc: "123"}
+	assert.NoError(t, validatePaymentCard(PaymentMethodCreditCard, card))
+	assert.Error(t, validatePaymentCard(PaymentMethodCreditCard, &payment.CardInput{Number: iyzicotest.CardSuccess}))
+	assert.NoError(t, validatePaymentCard(PaymentMethodSavedCard, &payment.CardInput{Token: "t", UserKey: "k"}))
+	assert.Error(t, validatePaymentCard(PaymentMethodSavedCard, &payment.CardInput{Token: "t"}))
+	assert.Error(t, validatePaymentCard("CASH", card))
+}
+
+// Callback'te kullanılan gateway akışı sahte iyzico sunucusuna karşı uçtan uca çalışır
+func TestGatewayCallbackFlow(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	var gw PaymentGateway = srv.PaymentService()
+
+	init, err := gw.ProcessAssessmentPayment(&payment.AssessmentPaymentInput{
+		UserID:         "user-1",
+		ConversationID: "payment-1",
+		Items:          []payment.PaymentItem{{ID: "a1", Amount: 1200}},
+		TotalAmount:    1200,
+		Card:           &payment.CardInput{Number: iyzicotest.CardSuccess, ExpireMonth: "12", ExpireYear: "2030", Cvc: "123"},
+		Use3DSecure:    true,
+		CallbackURL:    "http://localhost/api/v1/finance/payments/callback",
+	})
+	require.NoError(t, err)
+	require.True(t, init.Success)
+
+	form, ok := srv.CallbackForm("payment-1")
+	require.True(t, ok)
+	cb := &models.PaymentCallback{
+		Status:         form.Get("status"),
+		PaymentID:      form.Get("paymentId"),
+		ConversationID: form.Get("conversationId"),
+		MDStatus:       form.Get("mdStatus"),
+	}
+	require.NoError(t, checkCallback(cb))
+
+	done, err := gw.Complete3DSPayment(cb.PaymentID, cb.ConversationID)
+	require.NoError(t, err)
+	assert.True(t, done.Success)
+	assert.Equal(t, toKurus(1200), toKurus(done.PaidPrice))
+}
+
+func TestUniqueIDs(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, uniqueIDs([]string{"a", "", "b", "a"}))
+}
+
+func TestPlanPayment_PartialOldestFirst(t *testing.T) {
+	jan := models.PayableAssessment{ID: "jan", PeriodYear: 2026, PeriodMonth: 1, DueDate: time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), Outstanding: 1260, LateFeeDue: 60}
+	feb := models.PayableAssessment{ID: "feb", PeriodYear: 2026, PeriodMonth: 2, DueDate: time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), Outstanding: 1200}
+	assessments := []models.PayableAssessment{jan, feb}
+	targets := []allocation.Target{payableTarget(jan), payableTarget(feb)}
+
+	allocations, items := planPayment(1000, "u1", assessments, targets, allocation.LateFeeFirst)
+
+	require.Len(t, allocations, 2)
+	assert.Equal(t, models.PaymentAllocation{AssessmentID: "jan", Amount: 1000, LateFee: 60}, allocations[0])
+	assert.Equal(t, models.PaymentAllocation{AssessmentID: "feb"}, allocations[1])
+	require.Len(t, items, 1)
+	assert.Equal(t, "2026/01 Aidat", items[0].Name)
+}
+
+func TestPlanPayment_OverpaymentAddsAdvanceItem(t *testing.T) {
+	a := models.PayableAssessment{ID: "jan", PeriodYear: 2026, PeriodMonth: 1, Outstanding: 1200}
+
+	allocations, items := planPayment(1500, "u1", []models.PayableAssessment{a}, []allocation.Target{payableTarget(a)}, allocation.LateFeeFirst)
+
+	assert.Equal(t, 1200.0, allocations[0].Amount)
+	require.Len(t, items, 2)
+	assert.Equal(t, payment.PaymentItem{ID: "u1", Name: "Aidat Avansı", Amount: 300}, items[1])
+}
+
+func TestPayableTarget_SplitsLateFeeAndPrincipal(t *testing.T) {
+	target := payableTarget(models.PayableAssessment{ID: "a", Outstanding: 700.5, LateFeeDue: 60})
+	assert.Equal(t, 60.0, target.LateFeeDue)
+	assert.Equal(t, 640.5, target.PrincipalDue)
+
+	// Tazminat kalan borçtan fazla görünemez
+	target = payableTarget(models.PayableAssessment{ID: "b", Outstanding: 40, LateFeeDue: 60})
+	assert.Equal(t, 40.0, target.LateFeeDue)
+	assert.Equal(t, 0.0, target.PrincipalDue)
+}
+
+func TestGatewayRefund(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+	var gw PaymentGateway = srv.PaymentService()
+
+	result, err := gw.RefundPayment("tx-payment-1", 1200)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 1200.0, result.PaidPrice)
+	assert.NotEmpty(t, result.RawResponse)
+}
+
+// rejectingGateway iadeyi reddeden ödeme sağlayıcısı
+type rejectingGateway struct{ PaymentGateway }
+
+func (rejectingGateway) RefundPayment(transactionID string, amount float64) (*payment.PaymentResult, error) {
+	return &payment.PaymentResult{Success: false, ErrorMessage: "iade süresi geçmiş"}, nil
+}
+
+func TestGatewayRefund_AmountMismatch(t *testing.T) {
+	srv := iyzicotest.NewServer()
+	defer srv.Close()
+
+	// Uyuşmazlıkta beklenen değil, sağlayıcının tahsil ettiği tutar iade edilir
+	raw, err := gatewayRefund(srv.PaymentService(), "tx-payment-1", 1250.5)
+	require.NoError(t, err)
+	var resp payment.RefundResponse
+	require.NoError(t, json.Unmarshal(raw, &resp))
+	assert.Equal(t, 1250.5, resp.Price)
+
+	_, err = gatewayRefund(rejectingGateway{}, "tx-payment-1", 1250.5)
+	assert.ErrorContains(t, err, "iade reddedildi: iade süresi geçmiş")
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/models"
+)
+
+// ListReserveFunds sitenin fonları
+func (s *FinanceService) ListReserveFunds(ctx context.Context, propertyID string) ([]models.ReserveFund, error) {
+	return s.repo.ListReserveFunds(ctx, propertyID)
+}
+
+// CreateReserveFund yeni fon tanımlar; hesap planında 549 altında alt hesap açılır
+func (s *FinanceService) CreateReserveFund(ctx context.Context, f *models.ReserveFund, userID string) (*models.ReserveFund, error) {
+	if err := normalizeReserveFund(f); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateReserveFund(ctx, f, userID); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// UpdateReserveFund fonun tanımını ve katkı planını günceller
+func (s *FinanceService) UpdateReserveFund(ctx context.Context, f *models.ReserveFund) (*models.ReserveFund, error) {
+	if err := normalizeReserveFund(f); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateReserveFund(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// GetReserveFundBalances fonların asOf tarihindeki bakiyeleri; asOf boşsa bugün
+func (s *FinanceService) GetReserveFundBalances(ctx context.Context, propertyID string, asOf time.Time) ([]models.ReserveFundBalance, error) {
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	return s.repo.GetReserveFundBalances(ctx, propertyID, truncateDay(asOf))
+}
+
+// ListReserveWithdrawals fon harcama talepleri
+func (s *FinanceService) ListReserveWithdrawals(ctx context.Context, propertyID, fundID, status string) ([]models.ReserveWithdrawal, error) {
+	return s.repo.ListWithdrawals(ctx, propertyID, fundID, status)
+}
+
+// RequestReserveWithdrawal karar referansıyla fondan harcama talebi açar.
+// Talep onaylanana kadar deftere işlenmez.
+func (s *FinanceService) RequestReserveWithdrawal(ctx context.Context, propertyID string, w *models.ReserveWithdrawal) (*models.ReserveWithdrawal, error) {
+	if err := validateWithdrawal(w); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateWithdrawal(ctx, propertyID, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ApproveReserveWithdrawal talebi onaylar ve fondan harcamayı defterler
+func (s *FinanceService) ApproveReserveWithdrawal(ctx context.Context, propertyID, withdrawalID, userID string) (*models.ReserveWithdrawal, error) {
+	return s.repo.ApproveWithdrawal(ctx, propertyID, withdrawalID, userID)
+}
+
+// RejectReserveWithdrawal talebi gerekçesiyle reddeder
+func (s *FinanceService) RejectReserveWithdrawal(ctx context.Context, propertyID, withdrawalID, reason string) (*models.ReserveWithdrawal, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, errors.New("red gerekçesi girilmeli")
+	}
+	return s.repo.RejectWithdrawal(ctx, propertyID, withdrawalID, reason)
+}
+
+// normalizeReserveFund fon tanımını doğrular; katkı dönemleri ayın ilk gününe çekilir
+func normalizeReserveFund(f *models.ReserveFund) error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return errors.New("fon adı girilmeli")
+	}
+	if f.MonthlyAmount < 0 {
+		return errors.New("aylık katkı tutarı negatif olamaz")
+	}
+	if f.TargetAmount != nil && *f.TargetAmount <= 0 {
+		return errors.New("hedef tutar sıfırdan büyük olmalı")
+	}
+	if f.DistributionType == "" {
+		f.DistributionType = DistributionShareRatio
+	}
+	switch f.DistributionType {
+	case DistributionShareRatio, DistributionEqual, DistributionAreaM2:
+	default:
+		return errors.New("geçersiz dağıtım tipi")
+	}
+	if f.StartsOn.IsZero() {
+		return errors.New("katkı başlangıç dönemi girilmeli")
+	}
+	f.StartsOn = monthStart(f.StartsOn)
+	if f.EndsOn != nil {
+		end := monthStart(*f.EndsOn)
+		if end.Before(f.StartsOn) {
+			return errors.New("katkı bitiş dönemi başlangıçtan önce olamaz")
+		}
+		f.EndsOn = &end
+	}
+	return nil
+}
+
+// validateWithdrawal harcama talebinin karar bilgilerini ve ödeme hesabını doğrular
+func validateWithdrawal(w *models.ReserveWithdrawal) error {
+	if w.Amount <= 0 {
+		return errors.New("harcama tutarı sıfırdan büyük olmalı")
+	}
+	if strings.TrimSpace(w.Description) == "" {
+		return errors.New("harcama açıklaması girilmeli")
+	}
+	if strings.TrimSpace(w.DecisionReference) == "" {
+		return errors.New("yönetim kurulu / genel kurul karar numarası girilmeli")
+	}
+	if w.DecisionDate.IsZero() {
+		return errors.New("karar tarihi girilmeli")
+	}
+	if w.DecisionDate.After(time.Now()) {
+		return errors.New("karar tarihi ileri bir tarih olamaz")
+	}
+	if w.CashAccount == "" {
+		w.CashAccount = ledger.AccountBank
+	}
+	if w.CashAccount != ledger.AccountBank && w.CashAccount != ledger.AccountCash {
+		return errors.New("harcama yalnızca kasa (100) veya banka (102) hesabından yapılabilir")
+	}
+	return nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeReserveFund(t *testing.T) {
+	end := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	f := &models.ReserveFund{
+		Name:          "  Çatı Onarım Fonu ",
+		MonthlyAmount: 5000,
+		StartsOn:      time.Date(2026, 7, 15, 0, 0, 0, 0, time.UTC),
+		EndsOn:        &end,
+	}
+	require.NoError(t, normalizeReserveFund(f))
+	assert.Equal(t, "Çatı Onarım Fonu", f.Name)
+	assert.Equal(t, DistributionShareRatio, f.DistributionType)
+	assert.Equal(t, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), f.StartsOn)
+	assert.Equal(t, time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC), *f.EndsOn)
+
+	before := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	assert.Error(t, normalizeReserveFund(&models.ReserveFund{Name: "Fon", StartsOn: f.StartsOn, EndsOn: &before}))
+	assert.Error(t, normalizeReserveFund(&models.ReserveFund{Name: "Fon"}))
+	assert.Error(t, normalizeReserveFund(&models.ReserveFund{Name: "Fon", StartsOn: f.StartsOn, DistributionType: "CUSTOM"}))
+	assert.Error(t, normalizeReserveFund(&models.ReserveFund{Name: "Fon", StartsOn: f.StartsOn, MonthlyAmount: -1}))
+	assert.Error(t, normalizeReserveFund(&models.ReserveFund{StartsOn: f.StartsOn}))
+}
+
+func TestValidateWithdrawal(t *testing.T) {
+	valid := func() *models.ReserveWithdrawal {
+		return &models.ReserveWithdrawal{
+			Amount:            25000,
+			Description:       "Çatı su yalıtımı",
+			DecisionReference: "YK-2026/14",
+			DecisionDate:      time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	w := valid()
+	require.NoError(t, validateWithdrawal(w))
+	assert.Equal(t, "102", w.CashAccount)
+
+	w = valid()
+	w.CashAccount = "100"
+	assert.NoError(t, validateWithdrawal(w))
+
+	w = valid()
+	w.DecisionReference = " "
+	assert.Error(t, validateWithdrawal(w))
+
+	w = valid()
+	w.CashAccount = "770"
+	assert.Error(t, validateWithdrawal(w))
+
+	w = valid()
+	w.DecisionDate = time.Now().AddDate(0, 0, 2)
+	assert.Error(t, validateWithdrawal(w))
+
+	w = valid()
+	w.Amount = 0
+	assert.Error(t, validateWithdrawal(w))
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+)
+
+// UnitStatement dairenin yürüyen bakiyeli hesap ekstresi
+type UnitStatement struct {
+	*ledger.Statement
+	PropertyName string `json:"property_name"`
+	UnitName     string `json:"unit_name"`
+	ResidentName string `json:"resident_name,omitempty"`
+	// Dairenin takipteki ve ekstre döneminde kapanan ödeme planları
+	PaymentPlans []models.PaymentPlan `json:"payment_plans,omitempty"`
+
+	branding *tenant.TenantBranding
+}
+
+// GetResidentStatement sakinin kendi dairesinin ekstresini getirir; unitID boşsa
+// sakinin malik olduğu ilk daire kullanılır
+func (s *FinanceService) GetResidentStatement(ctx context.Context, userID, unitID string, from, to time.Time) (*UnitStatement, error) {
+	unitID, _, err := s.repo.GetResidentUnit(ctx, userID, unitID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.repo.GetStatementInfo(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildStatement(ctx, info, from, to)
+}
+
+// GetUnitStatement yöneticinin sitesindeki bir dairenin ekstresini getirir
+func (s *FinanceService) GetUnitStatement(ctx context.Context, propertyID, unitID string, from, to time.Time) (*UnitStatement, error) {
+	info, err := s.repo.GetStatementInfo(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if info.PropertyID != propertyID {
+		return nil, repository.ErrUnitNotFound
+	}
+	return s.buildStatement(ctx, info, from, to)
+}
+
+func (s *FinanceService) buildStatement(ctx context.Context, info *models.StatementInfo, from, to time.Time) (*UnitStatement, error) {
+	from, to, err := statementRange(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.repo.GetUnitStatement(ctx, info.UnitID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := s.repo.GetUnitPaymentPlans(ctx, info.UnitID, from)
+	if err != nil {
+		return nil, err
+	}
+	return &UnitStatement{
+		Statement:    st,
+		PropertyName: info.PropertyName,
+		UnitName:     info.UnitName,
+		ResidentName: info.ResidentName,
+		PaymentPlans: plans,
+		branding:     info.Branding,
+	}, nil
+}
+
+// StatementPDF ekstreyi sitenin logosu ve renkleriyle PDF olarak üretir. Logo
+// indirilemezse ekstre logosuz oluşturulur.
+func (s *FinanceService) StatementPDF(ctx context.Context, st *UnitStatement) ([]byte, error) {
+	return brandedPDF(ctx, st.branding).GenerateStatement(statementReportData(st))
+}
+
+// brandedPDF sitenin marka ayarlarıyla PDF oluşturucu hazırlar. Logo isteğin kiracısı
+// için önbellekten gelir; alınamazsa yalnızca loglanır ve rapor logosuz üretilir
+func brandedPDF(ctx context.Context, branding *tenant.TenantBranding) *reports.PDFGenerator {
+	gen := reports.NewPDFGenerator()
+
+	var logo []byte
+	if branding != nil && branding.LogoURL != "" {
+		scope, _ := database.ScopeFromContext(ctx)
+		data, err := reports.FetchTenantLogo(ctx, scope.TenantID, branding.LogoURL)
+		if err != nil {
+			log.Printf("Rapor logosu alınamadı (%s): %v", branding.LogoURL, err)
+		}
+		logo = data
+	}
+	if err := gen.SetBranding(branding, logo); err != nil {
+		log.Printf("Rapor logosu eklenemedi: %v", err)
+	}
+	return gen
+}
+
+func statementReportData(st *UnitStatement) *reports.StatementReportData {
+	data := &reports.StatementReportData{
+		PropertyName:   st.PropertyName,
+		UnitName:       st.UnitName,
+		ResidentName:   st.ResidentName,
+		StartDate:      st.From.Format("02.01.2006"),
+		EndDate:        st.To.Format("02.01.2006"),
+		OpeningBalance: st.OpeningBalance,
+		TotalDebit:     st.TotalDebit,
+		TotalCredit:    st.TotalCredit,
+		ClosingBalance: st.ClosingBalance,
+		Lines:          make([]reports.StatementEntry, len(st.Lines)),
+	}
+	for i, l := range st.Lines {
+		data.Lines[i] = reports.StatementEntry{
+			Date:         l.Date.Format("02.01.2006"),
+			DocumentType: string(l.DocumentType),
+			Description:  l.Description,
+			Debit:        l.Debit,
+			Credit:       l.Credit,
+			Balance:      l.Balance,
+		}
+	}
+	for _, p := range st.PaymentPlans {
+		plan := reports.StatementPlan{
+			Status:        paymentPlanStatusLabel(p.Status),
+			CreatedAt:     p.CreatedAt.Format("02.01.2006"),
+			TotalAmount:   p.TotalAmount,
+			WaivedLateFee: p.WaivedLateFee,
+			PaidAmount:    p.PaidAmount,
+		}
+		for _, inst := range p.Installments {
+			plan.Installments = append(plan.Installments, reports.StatementInstallment{
+				No:         inst.InstallmentNo,
+				DueDate:    inst.DueDate.Format("02.01.2006"),
+				Amount:     inst.Amount,
+				PaidAmount: inst.PaidAmount,
+				Status:     installmentStatusLabel(inst.Status),
+			})
+		}
+		data.PaymentPlans = append(data.PaymentPlans, plan)
+	}
+	return data
+}
+
+func paymentPlanStatusLabel(status string) string {
+	switch status {
+	case PlanActive:
+		return "Devam ediyor"
+	case PlanCompleted:
+		return "Tamamlandı"
+	case PlanBroken:
+		return "Bozuldu"
+	case PlanCancelled:
+		return "İptal edildi"
+	}
+	return status
+}
+
+func installmentStatusLabel(status string) string {
+	switch status {
+	case InstallmentPaid:
+		return "Ödendi"
+	case InstallmentPartial:
+		return "Kısmi"
+	case InstallmentMissed:
+		return "Ödenmedi"
+	}
+	return "Bekliyor"
+}
+
+// statementRange ekstre tarih aralığını doğrular; bitiş verilmezse bugün,
+// başlangıç verilmezse bitiş yılının ilk günü kullanılır
+func statementRange(from, to, now time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = now
+	}
+	to = truncateDay(to)
+	if from.IsZero() {
+		from = time.Date(to.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	from = truncateDay(from)
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("başlangıç tarihi bitiş tarihinden sonra olamaz")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/reports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementRange(t *testing.T) {
+	now := time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC)
+
+	from, to, err := statementRange(time.Time{}, time.Time{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = statementRange(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), now)
+	assert.Error(t, err)
+}
+
+func TestStatementReportData(t *testing.T) {
+	day := time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC)
+	st := &UnitStatement{
+		Statement: ledger.NewStatement("u1", day.AddDate(0, 0, -11), day, 0, []ledger.StatementLine{
+			{Date: day, DocumentType: ledger.DocCollection, Description: "Tahsilat", Credit: 500},
+		}),
+		PropertyName: "Mavikent",
+		UnitName:     "A-3",
+	}
+
+	data := statementReportData(st)
+	assert.Equal(t, "01.01.2026", data.StartDate)
+	require.Len(t, data.Lines, 1)
+	assert.Equal(t, reports.StatementEntry{Date: "12.01.2026", DocumentType: "TAHSILAT", Description: "Tahsilat", Credit: 500, Balance: -500}, data.Lines[0])
+
+	pdf, err := (&FinanceService{}).StatementPDF(t.Context(), st)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF")))
+}
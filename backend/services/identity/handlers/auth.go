@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/repository"
 	"github.com/siteeksen/backend/services/identity/service"
 )
 
@@ -22,7 +24,10 @@ func Login(svc *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		tokens, user, err := svc.Login(c.Request.Context(), req.Phone, req.Password)
+		tokens, user, err := svc.Login(c.Request.Context(), req.Phone, req.Password, clientInfo(c))
+		if respondMFARequired(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Geçersiz telefon veya şifre"})
 			return
@@ -48,7 +53,11 @@ func RefreshToken(svc *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		tokens, err := svc.RefreshToken(c.Request.Context(), req.RefreshToken)
+		tokens, err := svc.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c))
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Geçersiz refresh token"})
 			return
@@ -58,19 +67,79 @@ func RefreshToken(svc *service.AuthService) gin.HandlerFunc {
 	}
 }
 
-// Logout çıkış işlemi
+// Logout refresh tokenın ait olduğu oturumu kapatır
 func Logout(svc *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Token'ı blacklist'e ekle
+		var req struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		err := svc.Logout(c.Request.Context(), req.RefreshToken)
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Geçersiz refresh token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Çıkış yapılamadı"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Çıkış başarılı"})
 	}
 }
 
-// GetCurrentUser mevcut kullanıcı bilgisi
+// LogoutAll tüm cihazlardaki oturumları kapatır
+func LogoutAll(svc *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		count, err := svc.LogoutAll(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Oturumlar kapatılamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Tüm cihazlardan çıkış yapıldı", "revoked_sessions": count})
+	}
+}
+
+// ListSessions kullanıcının açık oturumları
+func ListSessions(svc *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		sessions, err := svc.ListSessions(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Oturumlar alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// RevokeSession tek bir oturumu kapatır
+func RevokeSession(svc *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		err := svc.RevokeSession(c.Request.Context(), userID, c.Param("id"))
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Oturum kapatılamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Oturum kapatıldı"})
+	}
+}
+
+// GetCurrentUser mevcut kullanıcı bilgisi; telefon ve TCKN ?reveal=true verilmedikçe maskeli
 func GetCurrentUser(svc *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.GetString("user_id")
-		user, err := svc.GetUserByID(c.Request.Context(), userID)
+		user, err := svc.GetUserByID(c.Request.Context(), userID, c.Query("reveal") == "true")
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Kullanıcı bulunamadı"})
 			return
@@ -79,6 +148,33 @@ func GetCurrentUser(svc *service.AuthService) gin.HandlerFunc {
 	}
 }
 
+// SetTCKN kullanıcının TC kimlik numarasını kaydeder
+func SetTCKN(svc *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			TCKN string `json:"tckn" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		err := svc.SetTCKN(c.Request.Context(), c.GetString("user_id"), req.TCKN)
+		switch {
+		case errors.Is(err, service.ErrInvalidTCKN):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, repository.ErrTCKNTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "TC kimlik numarası kaydedilemedi"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "TC kimlik numarası kaydedildi"})
+	}
+}
+
 // GetUserProperties kullanıcının kayıtlı siteleri
 func GetUserProperties(svc *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -104,12 +200,41 @@ func SetActiveProperty(svc *service.AuthService) gin.HandlerFunc {
 		}
 
 		userID := c.GetString("user_id")
-		err := svc.SetActiveProperty(c.Request.Context(), userID, req.PropertyID)
+		tokens, err := svc.SetActiveProperty(c.Request.Context(), userID, req.PropertyID, c.GetString("session_id"))
+		if errors.Is(err, service.ErrSessionRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrPropertyAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Site seçilemedi"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Aktif site güncellendi"})
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Aktif site güncellendi",
+			"access_token": tokens.AccessToken,
+			"expires_in":   tokens.ExpiresIn,
+		})
+	}
+}
+
+// clientInfo oturum kaydı için istemci bilgisi
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+		DeviceToken: c.GetHeader("X-Device-Token"),
+	}
+}
+
+// JWKS token doğrulama anahtarları (RFC 7517)
+func JWKS(svc *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, svc.JWKS())
 	}
 }
@@ -1,4 +1,4 @@
-package auth_test
+package handlers_test
 
 import (
 	"bytes"
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/tenant"
+)
+
+// GetBilling aktif sitenin kiracısının abonelik bilgileri ve planlar
+func GetBilling(biller *tenant.Biller, meter *tenant.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := billingTenant(c, meter)
+		if !ok {
+			return
+		}
+		account, err := biller.Account(c.Request.Context(), tenantID)
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"account": account, "plans": tenant.GetPlans()})
+	}
+}
+
+// ListBillingInvoices aktif sitenin kiracısının faturaları
+func ListBillingInvoices(biller *tenant.Biller, meter *tenant.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := billingTenant(c, meter)
+		if !ok {
+			return
+		}
+		invoices, err := biller.Invoices(c.Request.Context(), tenantID)
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, invoices)
+	}
+}
+
+// UpdatePaymentMethod abonelik kartını değiştirir. Gecikmiş veya ödeme alınamadığı için
+// askıya alınmış aboneliğin açık faturaları yeni karttan hemen çekilir.
+func UpdatePaymentMethod(biller *tenant.Biller, meter *tenant.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := billingTenant(c, meter)
+		if !ok {
+			return
+		}
+		var req struct {
+			HolderName  string `json:"holder_name" binding:"required"`
+			Number      string `json:"number" binding:"required"`
+			ExpireMonth string `json:"expire_month" binding:"required"`
+			ExpireYear  string `json:"expire_year" binding:"required"`
+			Email       string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		account, err := biller.UpdatePaymentMethod(c.Request.Context(), tenantID, &payment.CardInput{
+			HolderName:  req.HolderName,
+			Number:      req.Number,
+			ExpireMonth: req.ExpireMonth,
+			ExpireYear:  req.ExpireYear,
+		}, req.Email)
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+// ChangePlan aboneliğin planını veya fatura döngüsünü kıst hesapla değiştirir
+func ChangePlan(biller *tenant.Biller, meter *tenant.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := billingTenant(c, meter)
+		if !ok {
+			return
+		}
+		var req struct {
+			Plan  string `json:"plan" binding:"required"`
+			Cycle string `json:"billing_cycle"` // monthly, yearly; boşsa değişmez
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		change, err := biller.ChangePlan(c.Request.Context(), tenantID, req.Plan, req.Cycle)
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, change)
+	}
+}
+
+// GetTenantBilling kiracının abonelik bilgileri (platform yönetimi)
+func GetTenantBilling(biller *tenant.Biller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account, err := biller.Account(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+// ListTenantInvoices kiracının faturaları (platform yönetimi)
+func ListTenantInvoices(biller *tenant.Biller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoices, err := biller.Invoices(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondBillingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, invoices)
+	}
+}
+
+// billingTenant token'daki aktif sitenin kiracısı. Askıdaki kiracı da bulunur; yönetici
+// kartını güncelleyerek aboneliği yeniden açabilmelidir.
+func billingTenant(c *gin.Context, meter *tenant.Meter) (string, bool) {
+	propertyID := c.GetString("property_id")
+	if propertyID == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Aktif site seçilmemiş"})
+		return "", false
+	}
+	t, err := meter.TenantForProperty(c.Request.Context(), propertyID)
+	if errors.Is(err, tenant.ErrTenantNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site bir kiracıya bağlı değil"})
+		return "", false
+	}
+	if err != nil {
+		log.Printf("Site kiracısı bulunamadı (%s): %v", propertyID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Kiracı bulunamadı"})
+		return "", false
+	}
+	return t.ID, true
+}
+
+func respondBillingError(c *gin.Context, err error) {
+	var limitErr *tenant.LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Mevcut kullanım yeni planın limitini aşıyor",
+			"code":     "PLAN_LIMIT_EXCEEDED",
+			"resource": limitErr.Resource,
+			"limit":    limitErr.Limit,
+			"current":  limitErr.Current,
+			"plan":     limitErr.Plan,
+		})
+	case errors.Is(err, tenant.ErrNoPaymentMethod):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Kayıtlı ödeme yöntemi yok"})
+	case errors.Is(err, tenant.ErrPaymentDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	default:
+		respondTenantError(c, err)
+	}
+}
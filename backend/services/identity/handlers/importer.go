@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// maxImportFileSize yüklenen listenin en büyük boyutu
+const maxImportFileSize = 10 << 20
+
+// Import aktif sitenin daire, sakin, devir bakiyesi veya sayaç listesini ("file" alanında
+// Excel ya da CSV) yükler. ?dry_run=true önizlemedir ve hiçbir şey yazmaz. Hatalı satır varsa
+// hiçbir satır yazılmaz ve 422 ile satır raporu döner.
+func Import(svc *service.ImportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, ok := importer.ParseKind(c.Param("kind"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bilinmeyen liste türü"})
+			return
+		}
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Yüklenecek dosya gerekli"})
+			return
+		}
+		if header.Size > maxImportFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Dosya en fazla 10 MB olabilir"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya okunamadı"})
+			return
+		}
+		defer file.Close()
+
+		result, err := svc.Import(c.Request.Context(), &service.ImportRequest{
+			Kind:       kind,
+			PropertyID: c.GetString("property_id"),
+			UserID:     c.GetString("user_id"),
+			File:       file,
+			DryRun:     c.Query("dry_run") == "true",
+		})
+		var limitErr *tenant.LimitError
+		switch {
+		case err == nil:
+		case errors.Is(err, importer.ErrInvalidFile), errors.Is(err, service.ErrImportEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.As(err, &limitErr):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":    "Yükleme planın limitini aşıyor",
+				"code":     "PLAN_LIMIT_EXCEEDED",
+				"resource": limitErr.Resource,
+				"limit":    limitErr.Limit,
+				"current":  limitErr.Current,
+				"plan":     limitErr.Plan,
+			})
+			return
+		case errors.Is(err, repository.ErrPropertyNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		default:
+			log.Printf("Toplu yükleme başarısız (%s): %v", kind, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Yükleme tamamlanamadı"})
+			return
+		}
+
+		if !result.DryRun && !result.Applied {
+			c.JSON(http.StatusUnprocessableEntity, result)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ImportTemplate liste türünün Excel şablonu
+func ImportTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, ok := importer.ParseKind(c.Param("kind"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bilinmeyen liste türü"})
+			return
+		}
+		data, err := importer.Template(kind)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Şablon oluşturulamadı"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_sablon.xlsx"`, kind))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+	}
+}
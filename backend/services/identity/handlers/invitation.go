@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// InvitationRequest yöneticinin sakin davet isteği
+type InvitationRequest struct {
+	UnitID     string `json:"unit_id" binding:"required"`
+	Role       string `json:"role" binding:"required"` // OWNER, TENANT, PROXY
+	FirstName  string `json:"first_name" binding:"required"`
+	LastName   string `json:"last_name" binding:"required"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+	MoveInDate string `json:"move_in_date"` // 2006-01-02, boşsa bugün
+}
+
+// AcceptInvitationRequest hesabı olmayan kişinin davet kabulü
+type AcceptInvitationRequest struct {
+	Phone     string `json:"phone"` // Davet e-posta ile gönderildiyse zorunlu
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password" binding:"required"`
+}
+
+// MoveOutRequest taşınma (çıkış) isteği
+type MoveOutRequest struct {
+	MoveOutDate string `json:"move_out_date"` // 2006-01-02, boşsa bugün
+}
+
+// CreateInvitation aktif sitedeki daireye sakin davet eder
+func CreateInvitation(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req InvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+		var moveIn time.Time
+		if req.MoveInDate != "" {
+			parsed, err := time.Parse("2006-01-02", req.MoveInDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz taşınma tarihi"})
+				return
+			}
+			moveIn = parsed
+		}
+
+		invitation, err := svc.CreateInvitation(c.Request.Context(), &service.InvitationInput{
+			PropertyID: c.GetString("property_id"),
+			UnitID:     req.UnitID,
+			Role:       req.Role,
+			FirstName:  req.FirstName,
+			LastName:   req.LastName,
+			Phone:      req.Phone,
+			Email:      req.Email,
+			MoveInDate: moveIn,
+			InvitedBy:  c.GetString("user_id"),
+		})
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, invitation)
+	}
+}
+
+// ListInvitations aktif sitedeki davetler (telefonlar ?reveal=true verilmedikçe maskeli)
+func ListInvitations(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitations, err := svc.ListInvitations(c.Request.Context(), c.GetString("property_id"),
+			c.Query("reveal") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Davetler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, invitations)
+	}
+}
+
+// RevokeInvitation bekleyen daveti iptal eder
+func RevokeInvitation(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := svc.RevokeInvitation(c.Request.Context(), c.GetString("property_id"), c.Param("id"), c.GetString("user_id"))
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Davet iptal edildi"})
+	}
+}
+
+// GetInvitation davet bağlantısının ön izlemesi (public)
+func GetInvitation(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitation, err := svc.GetInvitation(c.Request.Context(), c.Param("token"))
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, invitation)
+	}
+}
+
+// AcceptInvitation daveti yeni hesapla kabul eder ve oturum açar (public)
+func AcceptInvitation(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		tokens, user, err := svc.AcceptAsNewUser(c.Request.Context(), &service.AcceptInput{
+			Token:     c.Param("token"),
+			Phone:     req.Phone,
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Password:  req.Password,
+		}, clientInfo(c))
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          user,
+		})
+	}
+}
+
+// AcceptInvitationAsUser daveti giriş yapmış kullanıcı adına kabul eder
+func AcceptInvitationAsUser(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		residency, err := svc.AcceptAsUser(c.Request.Context(), req.Token, c.GetString("user_id"))
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, residency)
+	}
+}
+
+// ListUnitResidents dairenin sakinleri (?include_history=true ile taşınmış sakinler de,
+// ?reveal=true ile maskesiz telefonlar)
+func ListUnitResidents(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		residents, err := svc.ListUnitResidents(c.Request.Context(), c.GetString("property_id"), c.Param("id"),
+			c.Query("include_history") == "true", c.Query("reveal") == "true")
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, residents)
+	}
+}
+
+// MoveOut sakinin daireden çıkışını kaydeder
+func MoveOut(svc *service.InvitationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MoveOutRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+		var endDate time.Time
+		if req.MoveOutDate != "" {
+			parsed, err := time.Parse("2006-01-02", req.MoveOutDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz çıkış tarihi"})
+				return
+			}
+			endDate = parsed
+		}
+
+		residency, err := svc.MoveOut(c.Request.Context(), c.GetString("property_id"), c.Param("id"), endDate,
+			c.GetString("user_id"))
+		if err != nil {
+			respondInvitationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, residency)
+	}
+}
+
+func respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvitationNotFound), errors.Is(err, repository.ErrUnitNotFound),
+		errors.Is(err, repository.ErrResidentUnitNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrInvitationUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvitationLoginRequired), errors.Is(err, repository.ErrResidentExists),
+		errors.Is(err, service.ErrAlreadyMovedOut):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvitationPhoneMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// MFAVerifyRequest girişin ikinci adımı
+type MFAVerifyRequest struct {
+	MFAToken       string `json:"mfa_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP veya kurtarma kodu
+	RememberDevice bool   `json:"remember_device"`
+}
+
+// MFACodeRequest oturum açmış kullanıcının TOTP kodu
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyMFALogin girişi TOTP veya kurtarma koduyla tamamlar
+func VerifyMFALogin(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFAVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		result, err := svc.VerifyLogin(c.Request.Context(), req.MFAToken, req.Code, req.RememberDevice, clientInfo(c))
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, mfaLoginResponse(result))
+	}
+}
+
+// BeginMFAEnrollmentLogin rolü gereği zorunlu olan kullanıcı için girişte kurulumu başlatır
+func BeginMFAEnrollmentLogin(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		enrollment, err := svc.BeginEnrollmentLogin(c.Request.Context(), req.MFAToken)
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// ConfirmMFAEnrollmentLogin girişte başlatılan kurulumu ilk kodla tamamlar ve oturumu açar
+func ConfirmMFAEnrollmentLogin(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+			Code     string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		result, err := svc.CompleteEnrollmentLogin(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, mfaLoginResponse(result))
+	}
+}
+
+// GetMFAStatus kullanıcının iki adımlı doğrulama durumu
+func GetMFAStatus(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := svc.Status(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "İki adımlı doğrulama durumu alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// BeginMFAEnrollment doğrulayıcı uygulama için yeni anahtar ve QR adresi üretir
+func BeginMFAEnrollment(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enrollment, err := svc.BeginEnrollment(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// ConfirmMFAEnrollment ilk kodla kurulumu tamamlar; kurtarma kodları bir kez gösterilir
+func ConfirmMFAEnrollment(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		codes, err := svc.ConfirmEnrollment(c.Request.Context(), c.GetString("user_id"), req.Code)
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "İki adımlı doğrulama etkinleştirildi",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// DisableMFA iki adımlı doğrulamayı kapatır
+func DisableMFA(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		if err := svc.Disable(c.Request.Context(), c.GetString("user_id"), req.Code); err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "İki adımlı doğrulama kapatıldı"})
+	}
+}
+
+// RegenerateRecoveryCodes yeni kurtarma kodları üretir
+func RegenerateRecoveryCodes(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		codes, err := svc.RegenerateRecoveryCodes(c.Request.Context(), c.GetString("user_id"), req.Code)
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// StepUpMFA hassas işlem öncesi ikinci adımı doğrular ve yeni access token verir
+func StepUpMFA(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		tokens, err := svc.StepUp(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), req.Code)
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": tokens.AccessToken,
+			"expires_in":   tokens.ExpiresIn,
+		})
+	}
+}
+
+// ListTrustedDevices ikinci adımın hatırlandığı cihazlar
+func ListTrustedDevices(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		devices, err := svc.ListDevices(c.Request.Context(), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Cihazlar alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, devices)
+	}
+}
+
+// RevokeTrustedDevice hatırlanan cihazı iptal eder
+func RevokeTrustedDevice(svc *service.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := svc.RevokeDevice(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Cihaz iptal edilemedi"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Cihaz iptal edildi"})
+	}
+}
+
+func mfaLoginResponse(result *service.MFALoginResult) gin.H {
+	response := gin.H{
+		"access_token":  result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"user":          result.User,
+	}
+	if result.DeviceToken != "" {
+		response["device_token"] = result.DeviceToken
+	}
+	if len(result.RecoveryCodes) > 0 {
+		response["recovery_codes"] = result.RecoveryCodes
+	}
+	return response
+}
+
+// respondMFARequired ilk adımı geçen girişe ikinci adım bilgisini döner
+func respondMFARequired(c *gin.Context, err error) bool {
+	var mfaErr *service.MFARequiredError
+	if !errors.As(err, &mfaErr) {
+		return false
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":        true,
+		"mfa_token":           mfaErr.Challenge.Token,
+		"enrollment_required": mfaErr.Challenge.EnrollmentRequired,
+		"expires_in":          mfaErr.Challenge.ExpiresIn,
+	})
+	return true
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMFALocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFAInvalidCode), errors.Is(err, service.ErrInvalidMFAToken),
+		errors.Is(err, service.ErrSessionRevoked):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFANotEnabled), errors.Is(err, service.ErrMFAAlreadyEnabled),
+		errors.Is(err, service.ErrMFAEnrollmentNotStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFARequiredByPolicy):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "İki adımlı doğrulama işlemi başarısız"})
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// maxUnitSheetSize yüklenen daire listesinin en büyük boyutu
+const maxUnitSheetSize = 5 << 20
+
+// SignupRequest yeni müşteri kaydı. Daireler units listesinde veya multipart istekte
+// "units_file" alanında Excel olarak gelir; multipart istekte bu gövde "payload" alanındadır.
+type SignupRequest struct {
+	OrganizationName string              `json:"organization_name"` // Boşsa site adı
+	Slug             string              `json:"slug" binding:"required"`
+	Plan             string              `json:"plan"` // starter, pro, enterprise; boşsa starter
+	Property         models.SiteProperty `json:"property"`
+	Units            []models.SiteUnit   `json:"units"`
+	Admin            struct {
+		FirstName string `json:"first_name" binding:"required"`
+		LastName  string `json:"last_name" binding:"required"`
+		Phone     string `json:"phone" binding:"required"`
+		Email     string `json:"email"`
+		Password  string `json:"password" binding:"required"`
+	} `json:"admin"`
+}
+
+// Signup kiracıyı deneme sürümünde açar, ilk siteyi daireleriyle kurar ve yönetici olarak
+// oturum açar (public)
+func Signup(svc *service.OnboardingService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SignupRequest
+		var units []service.UnitRow
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			if err := json.Unmarshal([]byte(c.PostForm("payload")), &req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+				return
+			}
+			if err := binding.Validator.ValidateStruct(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+				return
+			}
+			header, err := c.FormFile("units_file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Daire listesi dosyası gerekli"})
+				return
+			}
+			if header.Size > maxUnitSheetSize {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Daire listesi en fazla 5 MB olabilir"})
+				return
+			}
+			file, err := header.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Daire listesi okunamadı"})
+				return
+			}
+			defer file.Close()
+			if units, err = service.ParseUnitSheet(file); err != nil {
+				respondSignupError(c, err)
+				return
+			}
+		} else {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+				return
+			}
+			for i, u := range req.Units {
+				units = append(units, service.UnitRow{Row: i + 1, SiteUnit: u})
+			}
+		}
+
+		tokens, result, err := svc.Signup(c.Request.Context(), &service.SignupInput{
+			OrganizationName: req.OrganizationName,
+			Slug:             req.Slug,
+			Plan:             req.Plan,
+			Property:         req.Property,
+			Units:            units,
+			FirstName:        req.Admin.FirstName,
+			LastName:         req.Admin.LastName,
+			Phone:            req.Admin.Phone,
+			Email:            req.Admin.Email,
+			Password:         req.Admin.Password,
+		}, clientInfo(c))
+		if err != nil {
+			respondSignupError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          result.User,
+			"tenant":        result.Tenant,
+			"property":      result.Property,
+			"blocks":        result.Blocks,
+			"unit_count":    result.UnitCount,
+		})
+	}
+}
+
+// UnitSheetTemplate kayıtta yüklenecek daire listesinin Excel şablonu (public)
+func UnitSheetTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := service.UnitSheetTemplate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Şablon oluşturulamadı"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="daire_listesi.xlsx"`)
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+	}
+}
+
+func respondSignupError(c *gin.Context, err error) {
+	var listErr *service.UnitListError
+	var limitErr *tenant.LimitError
+	switch {
+	case errors.As(err, &listErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Daire listesinde hatalı satırlar var", "rows": listErr.Rows})
+	case errors.As(err, &limitErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Daire sayısı planın limitini aşıyor",
+			"code":     "PLAN_LIMIT_EXCEEDED",
+			"resource": limitErr.Resource,
+			"limit":    limitErr.Limit,
+			"current":  limitErr.Current,
+			"plan":     limitErr.Plan,
+		})
+	case errors.Is(err, service.ErrSignupInvalid), errors.Is(err, service.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSignupPhoneTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, tenant.ErrTenantExists), errors.Is(err, tenant.ErrInvalidTenant):
+		respondTenantError(c, err)
+	default:
+		log.Printf("Kayıt başarısız: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Kayıt tamamlanamadı"})
+	}
+}
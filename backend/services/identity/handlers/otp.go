@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// OTPRequest doğrulama kodu isteği
+type OTPRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Purpose string `json:"purpose"` // LOGIN (varsayılan) veya PASSWORD_RESET
+}
+
+// OTPLoginRequest kod ile giriş isteği
+type OTPLoginRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// PasswordResetRequest kod ile şifre sıfırlama isteği
+type PasswordResetRequest struct {
+	Phone       string `json:"phone" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// RequestOTP telefona doğrulama kodu gönderir
+func RequestOTP(svc *service.OTPService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req OTPRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+		if req.Purpose == "" {
+			req.Purpose = service.OTPPurposeLogin
+		}
+
+		challenge, err := svc.RequestOTP(c.Request.Context(), req.Phone, req.Purpose, c.ClientIP())
+		if err != nil {
+			respondOTPError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Numara kayıtlıysa doğrulama kodu gönderildi",
+			"expires_in":   challenge.ExpiresIn,
+			"resend_after": challenge.ResendAfter,
+		})
+	}
+}
+
+// LoginWithOTP doğrulama kodu ile giriş
+func LoginWithOTP(svc *service.OTPService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req OTPLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		tokens, user, err := svc.LoginWithOTP(c.Request.Context(), req.Phone, req.Code, clientInfo(c))
+		if respondMFARequired(c, err) {
+			return
+		}
+		if err != nil {
+			respondOTPError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          user,
+		})
+	}
+}
+
+// ResetPassword doğrulama kodu ile şifre sıfırlama
+func ResetPassword(svc *service.OTPService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PasswordResetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		if err := svc.ResetPassword(c.Request.Context(), req.Phone, req.Code, req.NewPassword, c.ClientIP()); err != nil {
+			respondOTPError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Şifre güncellendi"})
+	}
+}
+
+func respondOTPError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOTPRateLimited), errors.Is(err, service.ErrOTPLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOTPInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOTPDelivery):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
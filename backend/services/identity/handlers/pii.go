@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// ReencryptPII kişisel verileri aktif anahtar sürümüne taşır (anahtar değişiminden sonra)
+func ReencryptPII(svc *service.PIIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := svc.Reencrypt(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Yeniden şifreleme tamamlanamadı", "result": result})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// GrantProxy aktif sitedeki dairesi için vekalet verir. İstek multipart/form-data'dır:
+// unit_id, proxy_phone, scopes (PAY_DUES, VOTE, NOTIFICATIONS; tekrarlanan alan veya
+// virgülle), starts_at (boşsa hemen), expires_at, note ve document (PDF, JPG, PNG).
+// Tarihler RFC 3339 veya 2006-01-02 biçimindedir; yalnızca gün verilen bitiş tarihi o
+// günün sonuna kadar geçerlidir.
+func GrantProxy(svc *service.ProxyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startsAt, err := parseProxyTime(c.PostForm("starts_at"), false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz başlangıç tarihi"})
+			return
+		}
+		expiresAt, err := parseProxyTime(c.PostForm("expires_at"), true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz bitiş tarihi"})
+			return
+		}
+		var scopes []string
+		for _, value := range c.PostFormArray("scopes") {
+			scopes = append(scopes, strings.Split(value, ",")...)
+		}
+
+		header, err := c.FormFile("document")
+		if err != nil {
+			respondProxyError(c, service.ErrProxyDocumentRequired)
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Vekaletname okunamadı"})
+			return
+		}
+		defer file.Close()
+
+		granted, err := svc.Grant(c.Request.Context(), &service.ProxyInput{
+			PropertyID: c.GetString("property_id"),
+			UnitID:     c.PostForm("unit_id"),
+			GrantorID:  c.GetString("user_id"),
+			ProxyPhone: c.PostForm("proxy_phone"),
+			Scopes:     scopes,
+			StartsAt:   startsAt,
+			ExpiresAt:  expiresAt,
+			Note:       c.PostForm("note"),
+		}, &service.ProxyDocument{Name: header.Filename, Content: file})
+		if err != nil {
+			respondProxyError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, granted)
+	}
+}
+
+// ListMyProxies kullanıcının verdiği ve aldığı vekaletler (?include_inactive=true ile
+// iptal edilmiş ve süresi dolmuşlar da)
+func ListMyProxies(svc *service.ProxyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, received, err := svc.ListMine(c.Request.Context(), c.GetString("user_id"),
+			c.Query("include_inactive") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Vekaletler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"granted": granted, "received": received})
+	}
+}
+
+// RevokeMyProxy kullanıcının verdiği vekaleti iptal eder veya aldığı vekaletten çekilir
+func RevokeMyProxy(svc *service.ProxyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := svc.Revoke(c.Request.Context(), "", c.Param("id"), c.GetString("user_id"), false); err != nil {
+			respondProxyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Vekalet iptal edildi"})
+	}
+}
+
+// GetMyProxyDocument vekaletin taraflarına vekaletname dosyası
+func GetMyProxyDocument(svc *service.ProxyService) gin.HandlerFunc {
+	return proxyDocument(svc, false)
+}
+
+// ListPropertyProxies aktif sitedeki vekaletler (?unit_id ile daire, ?include_inactive=true
+// ile geçmiş vekaletler)
+func ListPropertyProxies(svc *service.ProxyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		proxies, err := svc.ListProperty(c.Request.Context(), c.GetString("property_id"), c.Query("unit_id"),
+			c.Query("include_inactive") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Vekaletler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, proxies)
+	}
+}
+
+// RevokePropertyProxy site yönetiminin vekaleti iptal etmesi
+func RevokePropertyProxy(svc *service.ProxyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := svc.Revoke(c.Request.Context(), c.GetString("property_id"), c.Param("id"), c.GetString("user_id"), true)
+		if err != nil {
+			respondProxyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Vekalet iptal edildi"})
+	}
+}
+
+// GetPropertyProxyDocument site yönetimine vekaletname dosyası
+func GetPropertyProxyDocument(svc *service.ProxyService) gin.HandlerFunc {
+	return proxyDocument(svc, true)
+}
+
+func proxyDocument(svc *service.ProxyService, manager bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		propertyID := ""
+		if manager {
+			propertyID = c.GetString("property_id")
+		}
+		p, content, err := svc.Document(c.Request.Context(), propertyID, c.Param("id"), c.GetString("user_id"), manager)
+		if err != nil {
+			respondProxyError(c, err)
+			return
+		}
+		defer content.Close()
+		c.DataFromReader(http.StatusOK, p.DocumentSize, p.DocumentType, content, map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", p.DocumentName),
+		})
+	}
+}
+
+// parseProxyTime RFC 3339 veya gün olarak verilen tarihi çözer; endOfDay ile yalnızca
+// gün verilen tarih ertesi günün başlangıcına (günün sonuna) taşınır
+func parseProxyTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func respondProxyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrProxyNotFound), errors.Is(err, repository.ErrUnitNotFound),
+		errors.Is(err, repository.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotUnitOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProxyLimitExceeded), errors.Is(err, service.ErrProxyAlreadyRevoked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProxyUserNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
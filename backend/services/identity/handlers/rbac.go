@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/siteeksen/backend/services/identity/service"
+)
+
+// GetMyPermissions aktif sitedeki güncel rol ve yetkiler
+func GetMyPermissions(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		access, err := svc.GetAccess(c.Request.Context(), c.GetString("user_id"), c.GetString("property_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Yetkiler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, access)
+	}
+}
+
+// ListPermissions yetki kataloğu
+func ListPermissions(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := svc.ListPermissions(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Yetkiler alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, permissions)
+	}
+}
+
+// ListRoles roller ve yetkileri
+func ListRoles(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := svc.ListRoles(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Roller alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, roles)
+	}
+}
+
+// SetRolePermissions rolün yetkilerini değiştirir
+func SetRolePermissions(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Permissions []string `json:"permissions" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		role, err := svc.SetRolePermissions(c.Request.Context(), c.Param("code"), req.Permissions)
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, role)
+	}
+}
+
+// ListRoleAssignments aktif sitedeki personel rol atamaları
+func ListRoleAssignments(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		assignments, err := svc.ListAssignments(c.Request.Context(), c.GetString("property_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Rol atamaları alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, assignments)
+	}
+}
+
+// AssignRole aktif sitede kullanıcıya personel rolü atar
+func AssignRole(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			UserID string `json:"user_id" binding:"required"`
+			Role   string `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz istek formatı"})
+			return
+		}
+
+		assignment, err := svc.AssignRole(c.Request.Context(), c.GetString("property_id"), req.UserID, req.Role,
+			c.GetString("user_id"))
+		if errors.Is(err, repository.ErrAssignmentExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, assignment)
+	}
+}
+
+// RevokeRole aktif sitedeki rol atamasını kaldırır
+func RevokeRole(svc *service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := svc.RevokeRole(c.Request.Context(), c.GetString("property_id"), c.Param("id"), c.GetString("user_id"))
+		if errors.Is(err, service.ErrAssignmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Rol ataması kaldırılamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Rol ataması kaldırıldı"})
+	}
+}
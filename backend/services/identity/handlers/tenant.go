@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/tenant"
+)
+
+// ListTenants kiracıları listeler (?status=ACTIVE|TRIAL|PAST_DUE|SUSPENDED, ?limit, ?offset)
+func ListTenants(manager *tenant.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		offset, _ := strconv.Atoi(c.Query("offset"))
+		tenants, err := manager.ListTenants(c.Request.Context(), tenant.ListFilter{
+			Status: c.Query("status"),
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Kiracılar alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, tenants)
+	}
+}
+
+// GetTenant kiracı ayrıntısı
+func GetTenant(manager *tenant.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := manager.GetTenant(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondTenantError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// CreateTenant plan limitleriyle deneme sürümünde yeni kiracı oluşturur
+func CreateTenant(manager *tenant.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req tenant.CreateTenantInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		t, err := manager.CreateTenant(c.Request.Context(), req)
+		if err != nil {
+			respondTenantError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, t)
+	}
+}
+
+// SuspendTenant kiracıyı askıya alır; kiracının istekleri 403 SUBSCRIPTION_INACTIVE ile reddedilir
+func SuspendTenant(manager *tenant.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		t, err := manager.SuspendTenant(c.Request.Context(), c.Param("id"), req.Reason)
+		if err != nil {
+			respondTenantError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// ReactivateTenant askıdaki kiracıyı yeniden etkinleştirir
+func ReactivateTenant(manager *tenant.TenantManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := manager.ReactivateTenant(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondTenantError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// GetTenantUsage kiracının güncel kullanımı ve plan limitleri
+func GetTenantUsage(manager *tenant.TenantManager, meter *tenant.Meter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := manager.GetTenant(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondTenantError(c, err)
+			return
+		}
+		usage, err := meter.Usage(c.Request.Context(), t.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Kullanım alınamadı"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"tenant_id": t.ID,
+			"plan":      t.SubscriptionPlan,
+			"usage":     usage,
+			"limits": gin.H{
+				tenant.ResourceUnits: t.MaxUnits,
+				tenant.ResourceUsers: t.MaxUsers,
+			},
+		})
+	}
+}
+
+func respondTenantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, tenant.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kiracı bulunamadı"})
+	case errors.Is(err, tenant.ErrTenantExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Slug veya alan adı kullanımda"})
+	case errors.Is(err, tenant.ErrStatusConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Kiracının durumu bu işleme uygun değil"})
+	case errors.Is(err, tenant.ErrInvalidTenant):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Kiracı işlemi başarısız"})
+	}
+}
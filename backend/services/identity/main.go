@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/claims"
 	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
 	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/pkg/payment"
+	"github.com/siteeksen/backend/pkg/server"
+	"github.com/siteeksen/backend/pkg/tenant"
 	"github.com/siteeksen/backend/services/identity/handlers"
 	"github.com/siteeksen/backend/services/identity/repository"
 	"github.com/siteeksen/backend/services/identity/service"
@@ -21,9 +31,111 @@ func main() {
 	}
 	defer database.Close()
 
+	// Kapanış sinyalinde sunucu ve arka plan işleri durdurulur
+	ctx, stop := server.Context()
+	defer stop()
+
+	// TCKN ve telefon şifreleme anahtarları; anahtarsız kişisel veri okunamaz
+	pii, err := encryption.NewPIICipherFromEnv()
+	if err != nil {
+		log.Fatalf("Kişisel veri şifreleme anahtarları yüklenemedi: %v", err)
+	}
+
 	// Repository ve Service
-	userRepo := repository.NewUserRepository(pool)
-	authService := service.NewAuthService(userRepo, os.Getenv("JWT_SECRET"))
+	userRepo := repository.NewUserRepository(pool, pii)
+	sessionRepo := repository.NewSessionRepository(pool)
+
+	// Token imza anahtarları; SIGHUP ile anahtar dizini yeniden okunur
+	signer, err := claims.NewSignerFromEnv()
+	if err != nil {
+		log.Fatalf("İmza anahtarları yüklenemedi: %v", err)
+	}
+	go reloadKeysOnSignal(signer)
+
+	rbacRepo := repository.NewRBACRepository(pool)
+	authService := service.NewAuthService(userRepo, sessionRepo, rbacRepo, signer)
+	rbacService := service.NewRBACService(rbacRepo)
+
+	// SMS doğrulama kodu özetleri için anahtar; anahtarsız kodlar örnekler arasında doğrulanamaz
+	otpSecret := os.Getenv("OTP_SECRET")
+	if otpSecret == "" {
+		log.Fatal("OTP_SECRET tanımlı değil")
+	}
+	otpRepo := repository.NewOTPRepository(pool, pii.BlindIndex)
+	smsService := sms.NewServiceFromEnv()
+	otpService := service.NewOTPService(authService, otpRepo, smsService, service.DefaultOTPConfig(otpSecret))
+
+	// Sakin davetleri; bağlantı mobil uygulama/web tarafından açılır
+	inviteBaseURL := os.Getenv("INVITE_BASE_URL")
+	if inviteBaseURL == "" {
+		inviteBaseURL = "https://app.siteeksen.com/davet"
+	}
+	invitationRepo := repository.NewInvitationRepository(pool, pii)
+	invitationService := service.NewInvitationService(authService, invitationRepo, smsService,
+		service.DefaultInvitationConfig(inviteBaseURL))
+
+	// İki adımlı doğrulama; site rolleri için zorunluluk site yönetimi ayarındadır
+	// (mfa_required_roles), platform rolleri için MFA_REQUIRED_PLATFORM_ROLES
+	platformRoles := []string{"ADMIN"}
+	if v, ok := os.LookupEnv("MFA_REQUIRED_PLATFORM_ROLES"); ok {
+		platformRoles = splitList(v)
+	}
+	mfaService := service.NewMFAService(authService, repository.NewMFARepository(pool, pii),
+		service.DefaultMFAConfig(platformRoles))
+
+	// Daire vekaletleri; vekaletname dosyaları PROXY_DOCUMENT_DIR altında saklanır
+	proxyDocumentDir := os.Getenv("PROXY_DOCUMENT_DIR")
+	if proxyDocumentDir == "" {
+		proxyDocumentDir = "/var/lib/siteeksen/documents"
+	}
+	documentStore, err := repository.NewFileStore(proxyDocumentDir)
+	if err != nil {
+		log.Fatalf("Belge dizini hazırlanamadı: %v", err)
+	}
+	proxyService := service.NewProxyService(authService, repository.NewProxyRepository(pool), documentStore, smsService,
+		service.DefaultProxyConfig())
+
+	// Aktif anahtar sürümünde olmayan (eski anahtarlı veya düz metin) kayıtlar açılışta taşınır
+	piiService := service.NewPIIService(repository.NewPIIRepository(pool, pii))
+	if os.Getenv("PII_REENCRYPT_ON_START") != "false" {
+		go piiService.RunReencryption(ctx)
+	}
+
+	// Kiracı kaydı; tenants tablosundaki değişiklikler LISTEN/NOTIFY ile önbellekten düşer
+	tenantManager := tenant.NewManager(tenant.NewPostgresStore(pool), tenant.DefaultManagerConfig())
+	go tenantManager.Listen(ctx, pool)
+
+	// Kullanım ölçümü: plan limitleri, %80 uyarıları, site adına gönderilen SMS'ler ve
+	// günlük usage_metrics görüntüleri
+	meter := tenant.NewMeter(tenant.NewPostgresUsageStore(pool), tenantManager,
+		service.NewUsageWarningNotifier(userRepo, smsService))
+	smsService.OnSent(func(ctx context.Context, req *sms.SendRequest) {
+		if req.PropertyID == "" {
+			return
+		}
+		if err := meter.RecordSMS(ctx, req.PropertyID, 1); err != nil {
+			log.Printf("SMS kullanımı kaydedilemedi: %v", err)
+		}
+	})
+	if os.Getenv("USAGE_SNAPSHOTS") != "false" {
+		go meter.RunDailySnapshots(ctx)
+	}
+
+	// Abonelik faturalandırması: deneme bitişi, dönem yenileme, kayıtlı karttan tahsilat ve
+	// başarısız tahsilatta yeniden deneme; birden çok örnekte kiracı başına kilitle çalışır
+	biller := tenant.NewBiller(tenant.NewPostgresBillingStore(pool), tenantManager, meter, payment.NewPaymentService(),
+		service.NewBillingFailureNotifier(userRepo, smsService), tenant.DefaultBillingConfig())
+	if os.Getenv("BILLING_JOB_DISABLED") != "true" {
+		go biller.RunPeriodically(ctx)
+	}
+
+	// Self-servis kayıt: kiracı, ilk site, daireler, varsayılan kategoriler, hesap planı ve
+	// yönetici tek transaction'da açılır
+	onboardingService := service.NewOnboardingService(authService,
+		repository.NewOnboardingRepository(pool, pii), tenantManager)
+
+	// Eski yazılımdan taşınma: daire, sakin, devir bakiyesi ve sayaç listeleri
+	importService := service.NewImportService(repository.NewImportRepository(pool, pii), meter)
 
 	// Gin router
 	r := gin.Default()
@@ -33,6 +145,9 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok", "service": "identity"})
 	})
 
+	// Diğer servislerin token doğrulaması için açık anahtarlar
+	r.GET("/.well-known/jwks.json", handlers.JWKS(authService))
+
 	// Public routes
 	api := r.Group("/api/v1")
 	{
@@ -41,16 +156,120 @@ func main() {
 			auth.POST("/login", handlers.Login(authService))
 			auth.POST("/refresh", handlers.RefreshToken(authService))
 			auth.POST("/logout", handlers.Logout(authService))
+			auth.POST("/otp/request", handlers.RequestOTP(otpService))
+			auth.POST("/otp/login", handlers.LoginWithOTP(otpService))
+			auth.POST("/password/reset", handlers.ResetPassword(otpService))
+			auth.POST("/mfa/verify", handlers.VerifyMFALogin(mfaService))
+			auth.POST("/mfa/enroll", handlers.BeginMFAEnrollmentLogin(mfaService))
+			auth.POST("/mfa/enroll/confirm", handlers.ConfirmMFAEnrollmentLogin(mfaService))
+		}
+		api.GET("/invitations/:token", handlers.GetInvitation(invitationService))
+		api.POST("/invitations/:token/accept", handlers.AcceptInvitation(invitationService))
+		if os.Getenv("SIGNUP_DISABLED") != "true" {
+			api.POST("/signup", handlers.Signup(onboardingService))
+			api.GET("/signup/units-template", handlers.UnitSheetTemplate())
 		}
 	}
 
 	// Protected routes
 	protected := api.Group("/users")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()))
 	{
 		protected.GET("/me", handlers.GetCurrentUser(authService))
+		protected.PUT("/me/tckn", handlers.SetTCKN(authService))
 		protected.GET("/me/properties", handlers.GetUserProperties(authService))
 		protected.POST("/me/active-property", handlers.SetActiveProperty(authService))
+		protected.GET("/me/sessions", handlers.ListSessions(authService))
+		protected.DELETE("/me/sessions/:id", handlers.RevokeSession(authService))
+		protected.POST("/me/logout-all", handlers.LogoutAll(authService))
+		protected.GET("/me/permissions", handlers.GetMyPermissions(rbacService))
+		protected.POST("/me/invitations/accept", handlers.AcceptInvitationAsUser(invitationService))
+		protected.GET("/me/mfa", handlers.GetMFAStatus(mfaService))
+		protected.POST("/me/mfa/enroll", handlers.BeginMFAEnrollment(mfaService))
+		protected.POST("/me/mfa/enroll/confirm", handlers.ConfirmMFAEnrollment(mfaService))
+		protected.DELETE("/me/mfa", handlers.DisableMFA(mfaService))
+		protected.POST("/me/mfa/recovery-codes", handlers.RegenerateRecoveryCodes(mfaService))
+		protected.POST("/me/mfa/step-up", handlers.StepUpMFA(mfaService))
+		protected.GET("/me/mfa/devices", handlers.ListTrustedDevices(mfaService))
+		protected.DELETE("/me/mfa/devices/:id", handlers.RevokeTrustedDevice(mfaService))
+		protected.GET("/me/proxies", handlers.ListMyProxies(proxyService))
+		protected.POST("/me/proxies", middleware.RequirePermission("identity.proxy.grant"), tenant.DatabaseScopeWithMeter(meter), handlers.GrantProxy(proxyService))
+		protected.DELETE("/me/proxies/:id", handlers.RevokeMyProxy(proxyService))
+		protected.GET("/me/proxies/:id/document", handlers.GetMyProxyDocument(proxyService))
+	}
+
+	// Sitedeki vekaletlerin yönetimi (aktif site); site yönetimi sorguları aktif sitenin
+	// kiracısıyla (RLS) çalışır
+	proxies := api.Group("/proxies")
+	proxies.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()), middleware.RequirePermission("identity.proxy.manage"),
+		tenant.DatabaseScopeWithMeter(meter))
+	{
+		proxies.GET("", handlers.ListPropertyProxies(proxyService))
+		proxies.DELETE("/:id", handlers.RevokePropertyProxy(proxyService))
+		proxies.GET("/:id/document", handlers.GetPropertyProxyDocument(proxyService))
+	}
+
+	// Sakin davetleri, daire sakinleri ve taşınma (aktif site)
+	residents := api.Group("/residents")
+	residents.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()), middleware.RequirePermission("identity.resident.manage"),
+		tenant.DatabaseScopeWithMeter(meter))
+	{
+		residents.GET("/invitations", handlers.ListInvitations(invitationService))
+		residents.POST("/invitations", tenant.EnforceLimitsWithMeter(meter, tenant.ResourceUsers), handlers.CreateInvitation(invitationService))
+		residents.DELETE("/invitations/:id", handlers.RevokeInvitation(invitationService))
+		residents.GET("/units/:id", handlers.ListUnitResidents(invitationService))
+		residents.POST("/:id/move-out", handlers.MoveOut(invitationService))
+	}
+
+	// Toplu içe aktarma (aktif site); ?dry_run=true önizleme
+	imports := api.Group("/imports")
+	imports.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()), middleware.RequirePermission("identity.import.manage"),
+		tenant.DatabaseScopeWithMeter(meter))
+	{
+		imports.GET("/:kind/template", handlers.ImportTemplate())
+		imports.POST("/:kind", handlers.Import(importService))
+	}
+
+	// Abonelik, ödeme yöntemi ve faturalar (aktif sitenin kiracısı). Askıdaki kiracının
+	// yöneticisi de erişir; kartını güncelleyerek aboneliği yeniden açabilir.
+	billing := api.Group("/billing")
+	billing.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()), middleware.RequirePermission("tenant.billing.manage"))
+	{
+		billing.GET("", handlers.GetBilling(biller, meter))
+		billing.GET("/invoices", handlers.ListBillingInvoices(biller, meter))
+		billing.PUT("/payment-method", middleware.RequireStepUp(), handlers.UpdatePaymentMethod(biller, meter))
+		billing.POST("/plan", handlers.ChangePlan(biller, meter))
+	}
+
+	// Rol ve yetki yönetimi (aktif site)
+	rbac := api.Group("/rbac")
+	rbac.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()), tenant.DatabaseScopeWithMeter(meter))
+	{
+		rbac.GET("/permissions", handlers.ListPermissions(rbacService))
+		rbac.GET("/roles", handlers.ListRoles(rbacService))
+		rbac.PUT("/roles/:code/permissions", middleware.RequirePermission("identity.role.admin"), handlers.SetRolePermissions(rbacService))
+		rbac.GET("/assignments", middleware.RequirePermission("identity.role.manage"), handlers.ListRoleAssignments(rbacService))
+		rbac.POST("/assignments", middleware.RequirePermission("identity.role.manage"), handlers.AssignRole(rbacService))
+		rbac.DELETE("/assignments/:id", middleware.RequirePermission("identity.role.manage"), handlers.RevokeRole(rbacService))
+	}
+
+	// Platform yönetimi
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddlewareWithKeys(authService.Keys()))
+	{
+		// Anahtar değişiminden sonra kişisel verilerin yeniden şifrelenmesi
+		admin.POST("/pii/reencrypt", middleware.RequirePermission("identity.pii.rotate"), handlers.ReencryptPII(piiService))
+
+		// Kiracılar (site yönetimleri)
+		tenants := admin.Group("/tenants", middleware.RequirePermission("platform.tenant.manage"))
+		tenants.GET("", handlers.ListTenants(tenantManager))
+		tenants.POST("", handlers.CreateTenant(tenantManager))
+		tenants.GET("/:id", handlers.GetTenant(tenantManager))
+		tenants.POST("/:id/suspend", handlers.SuspendTenant(tenantManager))
+		tenants.POST("/:id/reactivate", handlers.ReactivateTenant(tenantManager))
+		tenants.GET("/:id/usage", handlers.GetTenantUsage(tenantManager, meter))
+		tenants.GET("/:id/billing", handlers.GetTenantBilling(biller))
+		tenants.GET("/:id/invoices", handlers.ListTenantInvoices(biller))
 	}
 
 	// Sunucuyu başlat
@@ -59,5 +278,34 @@ func main() {
 		port = "8081"
 	}
 	log.Printf("Identity Service başlatıldı: :%s", port)
-	r.Run(":" + port)
+	if err := server.Run(ctx, ":"+port, r); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func reloadKeysOnSignal(signer *claims.Signer) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	for range ch {
+		dir := os.Getenv("JWT_KEYS_DIR")
+		if dir == "" {
+			continue
+		}
+		if err := signer.LoadDir(dir); err != nil {
+			log.Printf("İmza anahtarları yeniden yüklenemedi: %v", err)
+			continue
+		}
+		log.Printf("İmza anahtarları yeniden yüklendi, aktif anahtar: %s", signer.ActiveKeyID())
+	}
+}
+
+// splitList virgülle ayrılmış ortam değişkenini boş girdileri atlayarak böler
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Invitation bir daireye sakin davetinin kaydı (bağlantı tokenı yalnızca özet olarak saklanır)
+type Invitation struct {
+	ID             string     `json:"id"`
+	PropertyID     string     `json:"property_id"`
+	PropertyName   string     `json:"property_name,omitempty"`
+	UnitID         string     `json:"unit_id"`
+	UnitName       string     `json:"unit_name,omitempty"`
+	Role           string     `json:"role"` // OWNER, TENANT, PROXY
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Phone          string     `json:"phone,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	MoveInDate     time.Time  `json:"move_in_date"`
+	TokenHash      string     `json:"-"`
+	Delivery       string     `json:"delivery"` // SMS, MANUAL
+	InvitedBy      string     `json:"invited_by,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy     string     `json:"accepted_by,omitempty"`
+	ResidentUnitID string     `json:"resident_unit_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	Status         string     `json:"status"` // PENDING, ACCEPTED, REVOKED, EXPIRED
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ResidentUnit sakin-daire ilişkisi; taşınan sakinin kaydı pasif olarak kalır
+type ResidentUnit struct {
+	ID           string     `json:"id"`
+	ResidentID   string     `json:"resident_id"`
+	ResidentName string     `json:"resident_name,omitempty"`
+	Phone        string     `json:"phone,omitempty"`
+	UnitID       string     `json:"unit_id"`
+	Role         string     `json:"role"` // OWNER, TENANT, PROXY
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      *time.Time `json:"end_date,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
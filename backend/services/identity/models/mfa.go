@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// MFA kullanıcının TOTP kaydı; Secret çözülmüş anahtardır
+type MFA struct {
+	UserID         string     `json:"-"`
+	Secret         string     `json:"-"`
+	EnabledAt      *time.Time `json:"enabled_at,omitempty"` // nil: onay kodu bekleniyor
+	LastUsedStep   int64      `json:"-"`
+	FailedAttempts int        `json:"-"`
+	LockedUntil    *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// MFAStatus kullanıcının iki adımlı doğrulama durumu
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // Rolü gereği zorunlu
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TrustedDevice girişte ikinci adımın istenmediği hatırlanan cihaz
+type TrustedDevice struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	TokenHash  string     `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/siteeksen/backend/pkg/tenant"
+)
+
+// Daire tipleri (units.unit_type)
+const (
+	UnitApartment = "APARTMENT"
+	UnitShop      = "SHOP"
+	UnitOffice    = "OFFICE"
+	UnitParking   = "PARKING"
+)
+
+// SiteProperty kayıtta açılan ilk site
+type SiteProperty struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	District   string `json:"district,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+}
+
+// SiteBlock sitenin bloğu; kat sayısı dairelerden hesaplanır
+type SiteBlock struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	FloorCount int    `json:"floor_count"`
+}
+
+// SiteUnit kayıtta açılan bağımsız bölüm. Blok adı boşsa daire bloksuz açılır.
+type SiteUnit struct {
+	ID           string  `json:"id,omitempty"`
+	Block        string  `json:"block"`
+	Floor        int     `json:"floor"`
+	DoorNumber   string  `json:"door_number"`
+	ShareRatio   float64 `json:"share_ratio"` // Arsa payı
+	GrossAreaM2  float64 `json:"gross_area_m2,omitempty"`
+	NetAreaM2    float64 `json:"net_area_m2,omitempty"`
+	UnitType     string  `json:"unit_type"` // APARTMENT, SHOP, OFFICE, PARKING
+	IsCommercial bool    `json:"is_commercial"`
+}
+
+// ExpenseCategorySeed yeni siteye açılan varsayılan gider kalemi
+type ExpenseCategorySeed struct {
+	Name                 string
+	DistributionType     string // SHARE_RATIO, EQUAL, AREA_M2, METER_READING
+	AppliesToGroundFloor bool
+}
+
+// RequestCategorySeed yeni siteye açılan varsayılan talep kategorisi
+type RequestCategorySeed struct {
+	Name     string
+	Icon     string
+	SLAHours int
+}
+
+// SiteBootstrap kayıt akışının tek transaction'da yazdığı kayıtlar: kiracı, site, bloklar,
+// daireler, varsayılan kategoriler, hesap planı ve yönetici. Kimlikler yazılırken doldurulur.
+type SiteBootstrap struct {
+	Tenant            *tenant.Tenant
+	TrialEndsAt       time.Time
+	Property          SiteProperty
+	Blocks            []SiteBlock
+	Units             []SiteUnit
+	ExpenseCategories []ExpenseCategorySeed
+	RequestCategories []RequestCategorySeed
+	Admin             *User
+	AdminRole         string
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// UnitProxy kat malikinin bir dairesi için verdiği vekalet. Vekaletname belgesi
+// kimlik servisinin belge deposunda saklanır; DocumentKey dışarıya verilmez.
+type UnitProxy struct {
+	ID             string     `json:"id"`
+	PropertyID     string     `json:"property_id"`
+	UnitID         string     `json:"unit_id"`
+	UnitName       string     `json:"unit_name,omitempty"`
+	GrantorID      string     `json:"grantor_id"`
+	GrantorName    string     `json:"grantor_name,omitempty"`
+	ProxyID        string     `json:"proxy_id"`
+	ProxyName      string     `json:"proxy_name,omitempty"`
+	Scopes         []string   `json:"scopes"` // PAY_DUES, VOTE, NOTIFICATIONS
+	StartsAt       time.Time  `json:"starts_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	DocumentKey    string     `json:"-"`
+	DocumentName   string     `json:"document_name"`
+	DocumentType   string     `json:"document_type"`
+	DocumentSize   int64      `json:"document_size"`
+	DocumentSHA256 string     `json:"document_sha256"`
+	Note           string     `json:"note,omitempty"`
+	GrantorIsOwner bool       `json:"-"` // Veren kişi hâlâ dairenin aktif kat maliki mi
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy      string     `json:"revoked_by,omitempty"`
+	Status         string     `json:"status"` // SCHEDULED, ACTIVE, EXPIRED, REVOKED, LAPSED
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ProxyFilter vekalet listeleme filtresi; boş alanlar filtrelenmez
+type ProxyFilter struct {
+	PropertyID      string
+	UnitID          string
+	GrantorID       string
+	ProxyID         string
+	IncludeInactive bool // İptal edilmiş ve süresi dolmuş vekaletler de döner
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Permission yetki kataloğundaki kayıt (ör. finance.assessment.create)
+type Permission struct {
+	Code        string `json:"code"`
+	Module      string `json:"module"`
+	Description string `json:"description"`
+}
+
+// Role rol ve yetkileri
+type Role struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"` // PLATFORM, STAFF, RESIDENT
+	Permissions []string `json:"permissions"`
+}
+
+// RoleAssignment personel rolünün siteye atanması
+type RoleAssignment struct {
+	ID         string    `json:"id"`
+	PropertyID string    `json:"property_id"`
+	UserID     string    `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	RoleCode   string    `json:"role_code"`
+	GrantedBy  string    `json:"granted_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Access kullanıcının bir sitedeki rolleri ve bu rollerden çözülen yetkiler
+type Access struct {
+	PropertyID  string   `json:"property_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
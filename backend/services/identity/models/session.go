@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RefreshToken veritabanında saklanan refresh token kaydı (token yalnızca özet olarak saklanır)
+type RefreshToken struct {
+	ID           string     `json:"id"` // JWT jti
+	UserID       string     `json:"user_id"`
+	FamilyID     string     `json:"family_id"`
+	ParentID     string     `json:"parent_id,omitempty"`
+	TokenHash    string     `json:"-"`
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `json:"ip_address"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RotatedAt    *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"` // LOGOUT, LOGOUT_ALL, REUSE, PASSWORD_RESET
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Session kullanıcının açık oturumu (bir refresh token ailesi)
+type Session struct {
+	ID         string    `json:"id"` // family_id
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"` // Son kullanımdaki IP
+	CreatedAt  time.Time `json:"created_at"` // Giriş zamanı
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
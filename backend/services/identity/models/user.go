@@ -5,12 +5,14 @@ import "time"
 // User veritabanı modeli
 type User struct {
 	ID               string    `json:"id"`
+	TCKN             string    `json:"-"` // Çözülmüş TCKN; veritabanında yalnızca şifreli ve özet olarak durur
 	TCEncrypted      string    `json:"-"`
 	TCHash           string    `json:"-"`
 	FirstName        string    `json:"first_name"`
 	LastName         string    `json:"last_name"`
-	Phone            string    `json:"phone"`
+	Phone            string    `json:"phone"` // Çözülmüş telefon
 	PhoneEncrypted   string    `json:"-"`
+	PhoneHash        string    `json:"-"`
 	Email            string    `json:"email"`
 	PasswordHash     string    `json:"-"`
 	ActivePropertyID string    `json:"active_property_id"`
@@ -33,7 +35,8 @@ type UserResponse struct {
 	ID         string         `json:"id"`
 	FirstName  string         `json:"first_name"`
 	LastName   string         `json:"last_name"`
-	Phone      string         `json:"phone"`
+	Phone      string         `json:"phone"`          // Varsayılan olarak maskeli
+	TCKN       string         `json:"tckn,omitempty"` // Varsayılan olarak maskeli
 	Email      string         `json:"email"`
 	Properties []UserProperty `json:"properties"`
 }
@@ -61,3 +64,16 @@ type Unit struct {
 	UnitType     string  `json:"unit_type"`
 	IsCommercial bool    `json:"is_commercial"`
 }
+
+// OTPCode SMS ile gönderilen tek kullanımlık doğrulama kodu (kod yalnızca özet olarak saklanır)
+type OTPCode struct {
+	ID         string     `json:"id"`
+	Phone      string     `json:"phone"`
+	Purpose    string     `json:"purpose"` // LOGIN, PASSWORD_RESET
+	CodeHash   string     `json:"-"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	Attempts   int        `json:"attempts"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFileNotFound depoda bu anahtarla dosya yok
+var ErrFileNotFound = errors.New("dosya bulunamadı")
+
+// FileStore yüklenen belgeleri (vekaletname) yerel dizinde saklar. Anahtarlar servis
+// tarafından üretilir; dizin dışına çıkan anahtarlar reddedilir.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore dizini gerekirse oluşturur
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	p := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(s.dir)+string(os.PathSeparator)) {
+		return "", errors.New("geçersiz dosya anahtarı")
+	}
+	return p, nil
+}
+
+// Save içeriği anahtarın altına yazar ve yazılan bayt sayısını döner. Yazma
+// tamamlanamazsa yarım dosya silinir.
+func (s *FileStore) Save(ctx context.Context, key string, r io.Reader) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(p)
+		return 0, err
+	}
+	return n, nil
+}
+
+// Open anahtardaki dosyayı okumak için açar
+func (s *FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+// Remove anahtardaki dosyayı siler; dosya yoksa hata dönmez
+func (s *FileStore) Remove(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
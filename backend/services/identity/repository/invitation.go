@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+var (
+	// ErrUnitNotFound daire sitede yok
+	ErrUnitNotFound = errors.New("daire bulunamadı")
+	// ErrInvitationNotFound bu tokenla davet yok
+	ErrInvitationNotFound = errors.New("davet bulunamadı")
+	// ErrInvitationUnavailable davet kabul edilmiş, iptal edilmiş veya süresi dolmuş
+	ErrInvitationUnavailable = errors.New("davet artık geçerli değil")
+	// ErrPhoneTaken telefon numarası başka bir hesaba kayıtlı
+	ErrPhoneTaken = errors.New("bu telefon numarası zaten kayıtlı")
+	// ErrResidentExists kullanıcı dairede bu rolle zaten kayıtlı
+	ErrResidentExists = errors.New("kullanıcı bu dairede bu rolle zaten kayıtlı")
+	// ErrResidentUnitNotFound sitede bu kimlikte sakin-daire kaydı yok
+	ErrResidentUnitNotFound = errors.New("sakin kaydı bulunamadı")
+)
+
+// InvitationRepository sakin davetleri ve sakin-daire ilişkileri. Davet ve sakin
+// telefonları UserRepository ile aynı şekilde şifreli saklanır.
+type InvitationRepository struct {
+	pool *pgxpool.Pool
+	pii  *encryption.PIICipher
+}
+
+// NewInvitationRepository yeni repository oluşturur
+func NewInvitationRepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *InvitationRepository {
+	return &InvitationRepository{pool: pool, pii: pii}
+}
+
+// db istek kapsamındaki bağlantı; token ile açılan davetlerde kapsam yoktur, havuz kullanılır
+func (r *InvitationRepository) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, r.pool)
+}
+
+// GetUnit sitedeki daireyi site ve daire adıyla getirir
+func (r *InvitationRepository) GetUnit(ctx context.Context, propertyID, unitID string) (*models.UserProperty, error) {
+	unit := &models.UserProperty{}
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT p.id, p.name, u.id, COALESCE(u.block || '-', '') || u.door_number
+		FROM units u
+		JOIN properties p ON p.id = u.property_id
+		WHERE u.id::text = $2 AND u.property_id::text = $1
+	`, propertyID, unitID).Scan(&unit.PropertyID, &unit.PropertyName, &unit.UnitID, &unit.UnitName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnitNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// CreateInvitation daveti kaydeder
+func (r *InvitationRepository) CreateInvitation(ctx context.Context, inv *models.Invitation) error {
+	phone, err := sealPhone(r.pii, inv.Phone)
+	if err != nil {
+		return err
+	}
+	var keyVersion *int
+	if phone.Encrypted != "" {
+		v := r.pii.ActiveVersion()
+		keyVersion = &v
+	}
+	return r.db(ctx).QueryRow(ctx, `
+		INSERT INTO resident_invitations (property_id, unit_id, role, first_name, last_name, phone_encrypted,
+			phone_hash, pii_key_version, email, move_in_date, token_hash, delivery, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), $10, $11, $12,
+			NULLIF($13, '')::uuid, $14, $15)
+		RETURNING id
+	`, inv.PropertyID, inv.UnitID, inv.Role, inv.FirstName, inv.LastName, phone.Encrypted, phone.Hash, keyVersion,
+		inv.Email, inv.MoveInDate, inv.TokenHash, inv.Delivery, inv.InvitedBy, inv.ExpiresAt, inv.CreatedAt).Scan(&inv.ID)
+}
+
+// SetInvitationDelivery davetin iletilme şeklini günceller (SMS gönderilemediğinde MANUAL)
+func (r *InvitationRepository) SetInvitationDelivery(ctx context.Context, id, delivery string) error {
+	_, err := r.db(ctx).Exec(ctx, `UPDATE resident_invitations SET delivery = $2 WHERE id = $1`, id, delivery)
+	return err
+}
+
+const invitationColumns = `
+	i.id, i.property_id, p.name, i.unit_id, COALESCE(u.block || '-', '') || u.door_number, i.role,
+	i.first_name, i.last_name, COALESCE(i.phone, ''), COALESCE(i.phone_encrypted, ''), COALESCE(i.email, ''),
+	i.move_in_date, i.token_hash,
+	i.delivery, COALESCE(i.invited_by::text, ''), i.expires_at, i.accepted_at, COALESCE(i.accepted_by::text, ''),
+	COALESCE(i.resident_unit_id::text, ''), i.revoked_at, i.created_at`
+
+func (r *InvitationRepository) scanInvitation(row pgx.Row) (*models.Invitation, error) {
+	inv := &models.Invitation{}
+	var phoneEncrypted string
+	err := row.Scan(&inv.ID, &inv.PropertyID, &inv.PropertyName, &inv.UnitID, &inv.UnitName, &inv.Role,
+		&inv.FirstName, &inv.LastName, &inv.Phone, &phoneEncrypted, &inv.Email, &inv.MoveInDate, &inv.TokenHash,
+		&inv.Delivery, &inv.InvitedBy, &inv.ExpiresAt, &inv.AcceptedAt, &inv.AcceptedBy,
+		&inv.ResidentUnitID, &inv.RevokedAt, &inv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Phone, err = decryptPhone(r.pii, inv.Phone, phoneEncrypted); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetInvitationByToken token özetine göre daveti getirir
+func (r *InvitationRepository) GetInvitationByToken(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	inv, err := r.scanInvitation(r.db(ctx).QueryRow(ctx, `
+		SELECT `+invitationColumns+`
+		FROM resident_invitations i
+		JOIN properties p ON p.id = i.property_id
+		JOIN units u ON u.id = i.unit_id
+		WHERE i.token_hash = $1
+	`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	return inv, err
+}
+
+// ListInvitations sitedeki davetler, en yeni önce
+func (r *InvitationRepository) ListInvitations(ctx context.Context, propertyID string) ([]models.Invitation, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+invitationColumns+`
+		FROM resident_invitations i
+		JOIN properties p ON p.id = i.property_id
+		JOIN units u ON u.id = i.unit_id
+		WHERE i.property_id = $1
+		ORDER BY i.created_at DESC
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		inv, err := r.scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *inv)
+	}
+	return invitations, rows.Err()
+}
+
+// RevokeInvitation bekleyen daveti iptal eder; davet yoksa veya kabul edilmişse false döner
+func (r *InvitationRepository) RevokeInvitation(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE resident_invitations SET revoked_at = $4, revoked_by = NULLIF($3, '')::uuid
+		WHERE id = $2 AND property_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, propertyID, id, revokedBy, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// AcceptInvitation daveti tek işlemde kabul eder: davet kullanılmış olarak işaretlenir,
+// user.ID boşsa kullanıcı oluşturulur, sakin-daire ilişkisi taşınma tarihiyle eklenir ve
+// aktif sitesi olmayan kullanıcının aktif sitesi davetin sitesi yapılır. phoneVerified
+// verilirse (davet SMS ile bu numaraya gitmişse) telefon doğrulanmış sayılır.
+func (r *InvitationRepository) AcceptInvitation(ctx context.Context, inv *models.Invitation, user *models.User, phoneVerified bool, now time.Time) (*models.ResidentUnit, error) {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE resident_invitations SET accepted_at = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $2
+	`, inv.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrInvitationUnavailable
+	}
+
+	var verifiedAt *time.Time
+	if phoneVerified {
+		verifiedAt = &now
+	}
+	var pgErr *pgconn.PgError
+	if user.ID == "" {
+		phone, err := sealPhone(r.pii, user.Phone)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (first_name, last_name, phone_encrypted, phone_hash, pii_key_version, email,
+				password_hash, active_property_id, roles, phone_verified_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, ARRAY['RESIDENT'], $9)
+			RETURNING id, roles, created_at, updated_at
+		`, user.FirstName, user.LastName, phone.Encrypted, phone.Hash, r.pii.ActiveVersion(), user.Email,
+			user.PasswordHash, inv.PropertyID, verifiedAt,
+		).Scan(&user.ID, &user.Roles, &user.CreatedAt, &user.UpdatedAt)
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrPhoneTaken
+		}
+		if err != nil {
+			return nil, err
+		}
+		user.ActivePropertyID = inv.PropertyID
+	} else {
+		err = tx.QueryRow(ctx, `
+			UPDATE users SET active_property_id = COALESCE(active_property_id, $2),
+				phone_verified_at = COALESCE(phone_verified_at, $3), updated_at = NOW()
+			WHERE id = $1
+			RETURNING active_property_id::text
+		`, user.ID, inv.PropertyID, verifiedAt).Scan(&user.ActivePropertyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ru := &models.ResidentUnit{
+		ResidentID: user.ID,
+		UnitID:     inv.UnitID,
+		Role:       inv.Role,
+		StartDate:  inv.MoveInDate,
+		IsActive:   true,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO resident_units (resident_id, unit_id, role, start_date, is_active)
+		VALUES ($1, $2, $3, $4, true)
+		RETURNING id, created_at
+	`, ru.ResidentID, ru.UnitID, ru.Role, ru.StartDate).Scan(&ru.ID, &ru.CreatedAt)
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrResidentExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE resident_invitations SET accepted_by = $2, resident_unit_id = $3 WHERE id = $1
+	`, inv.ID, user.ID, ru.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ru, nil
+}
+
+const residentUnitColumns = `
+	ru.id, ru.resident_id, us.first_name || ' ' || us.last_name, COALESCE(us.phone, ''),
+	COALESCE(us.phone_encrypted, ''), ru.unit_id, ru.role,
+	ru.start_date, ru.end_date, COALESCE(ru.is_active, false), ru.created_at`
+
+func (r *InvitationRepository) scanResidentUnit(row pgx.Row) (*models.ResidentUnit, error) {
+	ru := &models.ResidentUnit{}
+	var phoneEncrypted string
+	err := row.Scan(&ru.ID, &ru.ResidentID, &ru.ResidentName, &ru.Phone, &phoneEncrypted, &ru.UnitID, &ru.Role,
+		&ru.StartDate, &ru.EndDate, &ru.IsActive, &ru.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if ru.Phone, err = decryptPhone(r.pii, ru.Phone, phoneEncrypted); err != nil {
+		return nil, err
+	}
+	return ru, nil
+}
+
+// ListUnitResidents dairenin sakinleri; includeHistory ile taşınmış sakinler de döner
+func (r *InvitationRepository) ListUnitResidents(ctx context.Context, propertyID, unitID string, includeHistory bool) ([]models.ResidentUnit, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+residentUnitColumns+`
+		FROM resident_units ru
+		JOIN units u ON u.id = ru.unit_id
+		JOIN users us ON us.id = ru.resident_id
+		WHERE ru.unit_id::text = $2 AND u.property_id::text = $1 AND ($3 OR ru.is_active = true)
+		ORDER BY ru.is_active DESC, ru.start_date DESC
+	`, propertyID, unitID, includeHistory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	residents := []models.ResidentUnit{}
+	for rows.Next() {
+		ru, err := r.scanResidentUnit(rows)
+		if err != nil {
+			return nil, err
+		}
+		residents = append(residents, *ru)
+	}
+	return residents, rows.Err()
+}
+
+// GetResidentUnit sitedeki sakin-daire kaydını getirir
+func (r *InvitationRepository) GetResidentUnit(ctx context.Context, propertyID, id string) (*models.ResidentUnit, error) {
+	ru, err := r.scanResidentUnit(r.db(ctx).QueryRow(ctx, `
+		SELECT `+residentUnitColumns+`
+		FROM resident_units ru
+		JOIN units u ON u.id = ru.unit_id
+		JOIN users us ON us.id = ru.resident_id
+		WHERE ru.id::text = $2 AND u.property_id::text = $1
+	`, propertyID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResidentUnitNotFound
+	}
+	return ru, err
+}
+
+// MoveOut sakin-daire ilişkisini çıkış tarihiyle pasifleştirir; kayıt geçmiş için
+// silinmez. İlişki zaten pasifse false döner.
+func (r *InvitationRepository) MoveOut(ctx context.Context, propertyID, id string, endDate time.Time, endedBy string, now time.Time) (bool, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE resident_units ru
+		SET is_active = false, end_date = $3, ended_by = NULLIF($4, '')::uuid, ended_at = $5
+		FROM units u
+		WHERE ru.id = $2 AND u.id = ru.unit_id AND u.property_id = $1 AND ru.is_active = true
+	`, propertyID, id, endDate, endedBy, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
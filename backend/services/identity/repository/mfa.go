@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+// ErrMFANotFound kullanıcının TOTP kaydı yok
+var ErrMFANotFound = errors.New("iki adımlı doğrulama kaydı bulunamadı")
+
+// MFARepository TOTP anahtarları, kurtarma kodları ve hatırlanan cihazlar. TOTP anahtarı
+// kişisel veri anahtarlarıyla şifreli saklanır.
+type MFARepository struct {
+	pool *pgxpool.Pool
+	pii  *encryption.PIICipher
+}
+
+// NewMFARepository yeni repository oluşturur
+func NewMFARepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *MFARepository {
+	return &MFARepository{pool: pool, pii: pii}
+}
+
+// GetMFA kullanıcının TOTP kaydını getirir (onay bekleyen kayıt dahil)
+func (r *MFARepository) GetMFA(ctx context.Context, userID string) (*models.MFA, error) {
+	m := &models.MFA{UserID: userID}
+	var secretEncrypted string
+	err := r.pool.QueryRow(ctx, `
+		SELECT secret_encrypted, enabled_at, last_used_step, failed_attempts, locked_until, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`, userID).Scan(&secretEncrypted, &m.EnabledAt, &m.LastUsedStep, &m.FailedAttempts, &m.LockedUntil, &m.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMFANotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Secret, err = r.pii.Decrypt(secretEncrypted); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SaveMFASecret onay bekleyen TOTP anahtarını kaydeder. Etkin bir kayıt varsa
+// değiştirilmez ve false döner.
+func (r *MFARepository) SaveMFASecret(ctx context.Context, userID, secret string, now time.Time) (bool, error) {
+	encrypted, err := r.pii.Encrypt(secret)
+	if err != nil {
+		return false, err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO user_mfa (user_id, secret_encrypted, pii_key_version, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, pii_key_version = EXCLUDED.pii_key_version,
+			last_used_step = 0, failed_attempts = 0, locked_until = NULL, created_at = EXCLUDED.created_at
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, encrypted, r.pii.ActiveVersion(), now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// EnableMFA onay koduyla doğrulanan kaydı etkinleştirir ve kurtarma kodlarını yazar
+func (r *MFARepository) EnableMFA(ctx context.Context, userID string, step int64, recoveryHashes []string, now time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_mfa SET enabled_at = $3, last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step, now)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFANotFound
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes, now); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ReplaceRecoveryCodes kullanılmamış kurtarma kodlarını yenileriyle değiştirir
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string, now time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes, now); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, hashes []string, now time.Time) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)
+		`, userID, h, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountRecoveryCodes kullanılmamış kurtarma kodu sayısı
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// UseTOTPStep kodun zaman adımını kullanılmış işaretler. Aynı veya daha eski adım daha
+// önce kullanıldıysa (tekrar oynatma) false döner.
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_mfa SET last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode kurtarma kodunu tüketir; kod yoksa veya kullanılmışsa false döner
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID, codeHash string, now time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = $3
+		WHERE id = (
+			SELECT id FROM mfa_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`, userID, codeHash, now)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_mfa SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1
+	`, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// RegisterMFAFailure hatalı kodu sayar; maxAttempts'a ulaşınca sayaç sıfırlanır ve kayıt
+// lockedUntil'e kadar kilitlenir
+func (r *MFARepository) RegisterMFAFailure(ctx context.Context, userID string, maxAttempts int, lockedUntil time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE user_mfa
+		SET locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END,
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END
+		WHERE user_id = $1
+	`, userID, maxAttempts, lockedUntil)
+	return err
+}
+
+// DisableMFA TOTP kaydını, kurtarma kodlarını ve hatırlanan cihazları siler
+func (r *MFARepository) DisableMFA(ctx context.Context, userID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, q := range []string{
+		`DELETE FROM mfa_trusted_devices WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// MFARequired kullanıcının rollerinden biri için iki adımlı doğrulama zorunlu mu: aktif
+// site rolü (personel ataması veya sakinlik) sitenin bağlı olduğu site yönetiminin
+// mfa_required_roles ayarında geçiyorsa ya da platform rolü platformRoles içindeyse.
+func (r *MFARepository) MFARequired(ctx context.Context, userID string, platformRoles []string) (bool, error) {
+	var required bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM (
+				SELECT property_id, role_code FROM property_role_assignments
+				WHERE user_id = $1 AND revoked_at IS NULL
+				UNION ALL
+				SELECT u.property_id, ru.role FROM resident_units ru
+				JOIN units u ON u.id = ru.unit_id
+				WHERE ru.resident_id = $1 AND ru.is_active = true
+			) pr
+			JOIN properties p ON p.id = pr.property_id
+			JOIN tenants t ON t.id = p.tenant_id
+			WHERE COALESCE(t.settings->'mfa_required_roles', '[]'::jsonb) ? pr.role_code
+		) OR EXISTS (
+			SELECT 1 FROM users WHERE id = $1 AND roles && $2::text[]
+		)
+	`, userID, platformRoles).Scan(&required)
+	return required, err
+}
+
+// CreateTrustedDevice hatırlanan cihazı kaydeder
+func (r *MFARepository) CreateTrustedDevice(ctx context.Context, d *models.TrustedDevice) error {
+	return r.pool.QueryRow(ctx, `
+		INSERT INTO mfa_trusted_devices (user_id, token_hash, user_agent, ip_address, expires_at, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+		RETURNING id
+	`, d.UserID, d.TokenHash, d.UserAgent, d.IPAddress, d.ExpiresAt, d.CreatedAt).Scan(&d.ID)
+}
+
+// UseTrustedDevice cihaz tokenı kullanıcıya ait, süresi dolmamış ve iptal edilmemişse
+// son kullanım zamanını günceller ve true döner
+func (r *MFARepository) UseTrustedDevice(ctx context.Context, userID, tokenHash string, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE mfa_trusted_devices SET last_used_at = $3
+		WHERE user_id = $1 AND token_hash = $2 AND revoked_at IS NULL AND expires_at > $3
+	`, userID, tokenHash, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ListTrustedDevices kullanıcının geçerli hatırlanan cihazları, en yeni önce
+func (r *MFARepository) ListTrustedDevices(ctx context.Context, userID string, now time.Time) ([]models.TrustedDevice, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), expires_at, last_used_at, created_at
+		FROM mfa_trusted_devices
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.TrustedDevice{}
+	for rows.Next() {
+		d := models.TrustedDevice{UserID: userID}
+		if err := rows.Scan(&d.ID, &d.UserAgent, &d.IPAddress, &d.ExpiresAt, &d.LastUsedAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// RevokeTrustedDevice kullanıcının hatırlanan cihazını iptal eder; cihaz yoksa false döner
+func (r *MFARepository) RevokeTrustedDevice(ctx context.Context, userID, id string, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE mfa_trusted_devices SET revoked_at = $3
+		WHERE id::text = $2 AND user_id = $1 AND revoked_at IS NULL
+	`, userID, id, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+// OnboardingRepository yeni müşterinin kiracı, site ve yönetici kayıtlarını açar
+type OnboardingRepository struct {
+	pool *pgxpool.Pool
+	pii  *encryption.PIICipher
+}
+
+// NewOnboardingRepository yeni repository oluşturur
+func NewOnboardingRepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *OnboardingRepository {
+	return &OnboardingRepository{pool: pool, pii: pii}
+}
+
+// Bootstrap kurulumun tüm kayıtlarını tek transaction'da yazar; herhangi bir adım hata
+// verirse hiçbir kayıt kalmaz. Tablo sahibi rolüyle çalışır (RLS'e takılmaz), site kiracıya
+// tenant_id ile bağlanır. Slug veya alan adı kullanımdaysa tenant.ErrTenantExists, telefon
+// kayıtlıysa ErrPhoneTaken döner.
+func (r *OnboardingRepository) Bootstrap(ctx context.Context, site *models.SiteBootstrap) error {
+	phone, err := sealPhone(r.pii, site.Admin.Phone)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tenant.InsertTenant(ctx, tx, site.Tenant, site.TrialEndsAt); err != nil {
+		return err
+	}
+
+	var totalShare float64
+	for _, u := range site.Units {
+		totalShare += u.ShareRatio
+	}
+	p := &site.Property
+	err = tx.QueryRow(ctx, `
+		INSERT INTO properties (name, address, city, district, postal_code, total_share_ratio, total_units, tenant_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+		RETURNING id::text
+	`, p.Name, p.Address, p.City, p.District, p.PostalCode, totalShare, len(site.Units), site.Tenant.ID).Scan(&p.ID)
+	if err != nil {
+		return err
+	}
+
+	blockIDs := make(map[string]string, len(site.Blocks))
+	for i := range site.Blocks {
+		b := &site.Blocks[i]
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO blocks (property_id, name, floor_count) VALUES ($1, $2, $3) RETURNING id::text
+		`, p.ID, b.Name, b.FloorCount).Scan(&b.ID); err != nil {
+			return err
+		}
+		blockIDs[b.Name] = b.ID
+	}
+
+	batch := &pgx.Batch{}
+	for i := range site.Units {
+		u := &site.Units[i]
+		var blockID *string
+		if id, ok := blockIDs[u.Block]; ok {
+			blockID = &id
+		}
+		batch.Queue(`
+			INSERT INTO units (property_id, block_id, block, floor, door_number, share_ratio, gross_area_m2,
+							   net_area_m2, unit_type, is_commercial, is_ground_floor)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, 0), NULLIF($8, 0), $9, $10, $11)
+			RETURNING id::text
+		`, p.ID, blockID, u.Block, u.Floor, u.DoorNumber, u.ShareRatio, u.GrossAreaM2, u.NetAreaM2,
+			u.UnitType, u.IsCommercial, u.Floor == 0).QueryRow(func(row pgx.Row) error {
+			return row.Scan(&u.ID)
+		})
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("daireler oluşturulamadı: %w", err)
+	}
+
+	for i, c := range site.ExpenseCategories {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO expense_categories (property_id, name, distribution_type, applies_to_ground_floor, sort_order)
+			VALUES ($1, $2, $3, $4, $5)
+		`, p.ID, c.Name, c.DistributionType, c.AppliesToGroundFloor, i+1); err != nil {
+			return err
+		}
+	}
+	for _, c := range site.RequestCategories {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO request_categories (property_id, name, icon, sla_hours) VALUES ($1, $2, $3, $4)
+		`, p.ID, c.Name, c.Icon, c.SLAHours); err != nil {
+			return err
+		}
+	}
+	if err := ledger.EnsureChartOfAccounts(ctx, tx, p.ID); err != nil {
+		return err
+	}
+
+	// Yönetici rolü siteye atanır; users.roles yalnızca platform rollerini taşır
+	user := site.Admin
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (first_name, last_name, phone_encrypted, phone_hash, pii_key_version, email,
+			password_hash, active_property_id, roles)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, ARRAY[]::text[])
+		RETURNING id, roles, created_at, updated_at
+	`, user.FirstName, user.LastName, phone.Encrypted, phone.Hash, r.pii.ActiveVersion(), user.Email,
+		user.PasswordHash, p.ID,
+	).Scan(&user.ID, &user.Roles, &user.CreatedAt, &user.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrPhoneTaken
+	}
+	if err != nil {
+		return err
+	}
+	user.PhoneEncrypted, user.PhoneHash = phone.Encrypted, phone.Hash
+	user.ActivePropertyID = p.ID
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO property_role_assignments (property_id, user_id, role_code, granted_by)
+		VALUES ($1, $2, $3, $2)
+	`, p.ID, user.ID, site.AdminRole); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+// ErrOTPNotFound telefon ve amaç için kullanılabilir (süresi dolmamış, tüketilmemiş) kod yok
+var ErrOTPNotFound = errors.New("doğrulama kodu bulunamadı")
+
+// OTPRepository doğrulama kodu ve hatalı deneme kayıtları. Telefon numarası
+// saklanmaz; kayıtlar telefonun arama özetiyle (phone_hash) tutulur.
+type OTPRepository struct {
+	pool  *pgxpool.Pool
+	index *encryption.BlindIndex
+}
+
+// NewOTPRepository yeni repository oluşturur
+func NewOTPRepository(pool *pgxpool.Pool, index *encryption.BlindIndex) *OTPRepository {
+	return &OTPRepository{pool: pool, index: index}
+}
+
+func (r *OTPRepository) phoneHash(phone string) string {
+	return r.index.Hash(PhoneField, phone)
+}
+
+// CreateOTP yeni kodu kaydeder. Aynı telefon ve amaç için açık kalan eski kodlar
+// geçersiz kılınır; her zaman yalnızca son gönderilen kod kullanılabilir.
+func (r *OTPRepository) CreateOTP(ctx context.Context, c *models.OTPCode) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE otp_codes SET consumed_at = $3
+		WHERE phone_hash = $1 AND purpose = $2 AND consumed_at IS NULL
+	`, r.phoneHash(c.Phone), c.Purpose, c.CreatedAt)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO otp_codes (phone_hash, purpose, code_hash, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		RETURNING id
+	`, r.phoneHash(c.Phone), c.Purpose, c.CodeHash, c.IPAddress, c.ExpiresAt, c.CreatedAt).Scan(&c.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetActiveOTP telefon ve amaç için kullanılabilir son kodu getirir
+func (r *OTPRepository) GetActiveOTP(ctx context.Context, phone, purpose string, now time.Time) (*models.OTPCode, error) {
+	c := &models.OTPCode{Phone: phone}
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, purpose, code_hash, COALESCE(ip_address, ''), attempts, expires_at, created_at
+		FROM otp_codes
+		WHERE phone_hash = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, r.phoneHash(phone), purpose, now).Scan(&c.ID, &c.Purpose, &c.CodeHash, &c.IPAddress, &c.Attempts,
+		&c.ExpiresAt, &c.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOTPNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LatestOTPAt telefon ve amaç için son kod gönderim zamanı (hiç yoksa sıfır)
+func (r *OTPRepository) LatestOTPAt(ctx context.Context, phone, purpose string) (time.Time, error) {
+	var at *time.Time
+	err := r.pool.QueryRow(ctx, `
+		SELECT MAX(created_at) FROM otp_codes WHERE phone_hash = $1 AND purpose = $2
+	`, r.phoneHash(phone), purpose).Scan(&at)
+	if err != nil || at == nil {
+		return time.Time{}, err
+	}
+	return *at, nil
+}
+
+// RegisterOTPFailure hatalı denemeyi kaydeder. codeID verilmişse kodun deneme sayısı
+// artırılır; maxAttempts'a ulaşan kod tüketilmiş sayılır.
+func (r *OTPRepository) RegisterOTPFailure(ctx context.Context, codeID, phone, ip string, maxAttempts int, now time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if codeID != "" {
+		_, err := tx.Exec(ctx, `
+			UPDATE otp_codes
+			SET attempts = attempts + 1,
+				consumed_at = CASE WHEN attempts + 1 >= $2 THEN $3 ELSE consumed_at END
+			WHERE id = $1
+		`, codeID, maxAttempts, now)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO otp_failures (phone_hash, ip_address, created_at) VALUES ($1, NULLIF($2, ''), $3)
+	`, r.phoneHash(phone), ip, now); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConsumeOTP kodu kullanılmış işaretler; kod bu arada başka bir istekte kullanıldıysa false döner
+func (r *OTPRepository) ConsumeOTP(ctx context.Context, codeID string, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE otp_codes SET consumed_at = $2 WHERE id = $1 AND consumed_at IS NULL
+	`, codeID, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// CountOTPRequests since'ten bu yana telefona ve IP adresine gönderilen kod sayıları
+func (r *OTPRepository) CountOTPRequests(ctx context.Context, phone, ip string, since time.Time) (int, int, error) {
+	var byPhone, byIP int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE phone_hash = $1),
+			   COUNT(*) FILTER (WHERE $2 <> '' AND ip_address = $2)
+		FROM otp_codes
+		WHERE created_at >= $3 AND (phone_hash = $1 OR ip_address = $2)
+	`, r.phoneHash(phone), ip, since).Scan(&byPhone, &byIP)
+	return byPhone, byIP, err
+}
+
+// CountOTPFailures since'ten bu yana telefon için hatalı deneme sayısı
+func (r *OTPRepository) CountOTPFailures(ctx context.Context, phone string, since time.Time) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM otp_failures WHERE phone_hash = $1 AND created_at >= $2
+	`, r.phoneHash(phone), since).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
+)
+
+// PIIRepository kişisel veri alanlarının aktif anahtar sürümüne taşınması. Anahtar
+// sürümü aktif sürümden farklı (veya hiç şifrelenmemiş) kayıtlar partiler halinde
+// çözülüp aktif anahtarla yeniden şifrelenir; düz metin telefon silinir.
+type PIIRepository struct {
+	pool *pgxpool.Pool
+	pii  *encryption.PIICipher
+}
+
+// NewPIIRepository yeni repository oluşturur
+func NewPIIRepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *PIIRepository {
+	return &PIIRepository{pool: pool, pii: pii}
+}
+
+// ActiveKeyVersion yeni verinin şifrelendiği anahtar sürümü
+func (r *PIIRepository) ActiveKeyVersion() int {
+	return r.pii.ActiveVersion()
+}
+
+type pendingUserPII struct {
+	id             string
+	phone          string
+	phoneEncrypted string
+	tcEncrypted    string
+}
+
+// ReencryptUsers en fazla limit kullanıcıyı aktif anahtara taşır ve taşınan kayıt
+// sayısını döner. Satırlar SKIP LOCKED ile kilitlendiği için birden çok örnek aynı
+// anda çalışabilir. Aynı özet anahtarıyla özetler de yeniden hesaplanır; eski
+// SHA-256 tc_hash değerleri böylece anahtarlı özete döner.
+func (r *PIIRepository) ReencryptUsers(ctx context.Context, limit int) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, COALESCE(phone, ''), COALESCE(phone_encrypted, ''), COALESCE(tc_encrypted, '')
+		FROM users
+		WHERE pii_key_version IS DISTINCT FROM $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, r.pii.ActiveVersion(), limit)
+	if err != nil {
+		return 0, err
+	}
+	var pending []pendingUserPII
+	for rows.Next() {
+		var p pendingUserPII
+		if err := rows.Scan(&p.id, &p.phone, &p.phoneEncrypted, &p.tcEncrypted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range pending {
+		phone, err := decryptPhone(r.pii, p.phone, p.phoneEncrypted)
+		if err != nil {
+			return 0, fmt.Errorf("kullanıcı %s telefon: %w", p.id, err)
+		}
+		sealed, err := sealPhone(r.pii, phone)
+		if err != nil {
+			return 0, err
+		}
+		var tcEncrypted, tcHash string
+		if p.tcEncrypted != "" {
+			tckn, err := r.pii.Decrypt(p.tcEncrypted)
+			if err != nil {
+				return 0, fmt.Errorf("kullanıcı %s TCKN: %w", p.id, err)
+			}
+			if tcEncrypted, err = r.pii.Encrypt(tckn); err != nil {
+				return 0, err
+			}
+			tcHash = r.pii.Hash(TCKNField, tckn)
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE users
+			SET phone = NULL, phone_encrypted = NULLIF($2, ''), phone_hash = NULLIF($3, ''),
+				tc_encrypted = NULLIF($4, ''), tc_hash = NULLIF($5, ''), pii_key_version = $6
+			WHERE id = $1
+		`, p.id, sealed.Encrypted, sealed.Hash, tcEncrypted, tcHash, r.pii.ActiveVersion()); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// ReencryptInvitations en fazla limit davetin telefonunu aktif anahtara taşır.
+// Yalnızca e-postayla gönderilmiş davetlerde şifrelenecek veri yoktur, bunlar atlanır.
+func (r *PIIRepository) ReencryptInvitations(ctx context.Context, limit int) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, COALESCE(phone, ''), COALESCE(phone_encrypted, '')
+		FROM resident_invitations
+		WHERE pii_key_version IS DISTINCT FROM $1 AND (phone IS NOT NULL OR phone_encrypted IS NOT NULL)
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, r.pii.ActiveVersion(), limit)
+	if err != nil {
+		return 0, err
+	}
+	type pendingInvitation struct{ id, phone, phoneEncrypted string }
+	var pending []pendingInvitation
+	for rows.Next() {
+		var p pendingInvitation
+		if err := rows.Scan(&p.id, &p.phone, &p.phoneEncrypted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range pending {
+		phone, err := decryptPhone(r.pii, p.phone, p.phoneEncrypted)
+		if err != nil {
+			return 0, fmt.Errorf("davet %s telefon: %w", p.id, err)
+		}
+		sealed, err := sealPhone(r.pii, phone)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE resident_invitations
+			SET phone = NULL, phone_encrypted = $2, phone_hash = $3, pii_key_version = $4
+			WHERE id = $1
+		`, p.id, sealed.Encrypted, sealed.Hash, r.pii.ActiveVersion()); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// ReencryptMFASecrets en fazla limit TOTP anahtarını aktif anahtara taşır
+func (r *PIIRepository) ReencryptMFASecrets(ctx context.Context, limit int) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT user_id, secret_encrypted
+		FROM user_mfa
+		WHERE pii_key_version IS DISTINCT FROM $1
+		ORDER BY user_id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, r.pii.ActiveVersion(), limit)
+	if err != nil {
+		return 0, err
+	}
+	type pendingSecret struct{ userID, secretEncrypted string }
+	var pending []pendingSecret
+	for rows.Next() {
+		var p pendingSecret
+		if err := rows.Scan(&p.userID, &p.secretEncrypted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range pending {
+		secret, err := r.pii.Decrypt(p.secretEncrypted)
+		if err != nil {
+			return 0, fmt.Errorf("kullanıcı %s TOTP anahtarı: %w", p.userID, err)
+		}
+		encrypted, err := r.pii.Encrypt(secret)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE user_mfa SET secret_encrypted = $2, pii_key_version = $3 WHERE user_id = $1
+		`, p.userID, encrypted, r.pii.ActiveVersion()); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+var (
+	// ErrNotUnitOwner kullanıcı dairenin aktif kat maliki değil
+	ErrNotUnitOwner = errors.New("yalnızca dairenin kat maliki vekalet verebilir")
+	// ErrProxyNotFound sitede bu kimlikte vekalet yok
+	ErrProxyNotFound = errors.New("vekalet bulunamadı")
+)
+
+// ProxyRepository kat maliklerinin daireleri için verdiği vekaletler
+type ProxyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewProxyRepository yeni repository oluşturur
+func NewProxyRepository(pool *pgxpool.Pool) *ProxyRepository {
+	return &ProxyRepository{pool: pool}
+}
+
+// db vekalet sorgularının bağlantısı; yönetim isteklerinde aktif sitenin kiracısıyla (RLS)
+func (r *ProxyRepository) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, r.pool)
+}
+
+// GetGrantorUnit sitedeki daireyi, kullanıcı dairenin aktif kat malikiyse getirir
+func (r *ProxyRepository) GetGrantorUnit(ctx context.Context, propertyID, unitID, grantorID string) (*models.UserProperty, error) {
+	unit := &models.UserProperty{Role: "OWNER"}
+	var isOwner bool
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT p.id, p.name, u.id, COALESCE(u.block || '-', '') || u.door_number,
+			   EXISTS (
+				   SELECT 1 FROM resident_units ru
+				   WHERE ru.unit_id = u.id AND ru.resident_id::text = $3 AND ru.role = 'OWNER'
+					 AND ru.is_active = true AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+			   )
+		FROM units u
+		JOIN properties p ON p.id = u.property_id
+		WHERE u.id::text = $2 AND u.property_id::text = $1
+	`, propertyID, unitID, grantorID).Scan(&unit.PropertyID, &unit.PropertyName, &unit.UnitID, &unit.UnitName, &isOwner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnitNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner {
+		return nil, ErrNotUnitOwner
+	}
+	return unit, nil
+}
+
+// CountOwners sitedeki aktif kat maliki sayısı
+func (r *ProxyRepository) CountOwners(ctx context.Context, propertyID string) (int, error) {
+	var count int
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT COUNT(DISTINCT ru.resident_id)
+		FROM resident_units ru
+		JOIN units u ON u.id = ru.unit_id
+		WHERE u.property_id::text = $1 AND ru.role = 'OWNER' AND ru.is_active = true
+		  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+	`, propertyID).Scan(&count)
+	return count, err
+}
+
+// CountVoteGrantors vekilin sitede [from, until) aralığıyla çakışan oy vekaletlerini veren
+// farklı malik sayısı; exceptGrantor sayılmaz (aynı malikin ikinci dairesi yeni malik değildir)
+func (r *ProxyRepository) CountVoteGrantors(ctx context.Context, propertyID, proxyID, exceptGrantor string, from, until time.Time) (int, error) {
+	var count int
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT COUNT(DISTINCT grantor_id)
+		FROM unit_proxies
+		WHERE property_id::text = $1 AND proxy_id::text = $2 AND grantor_id::text <> $3
+		  AND $4 = ANY(scopes) AND revoked_at IS NULL
+		  AND starts_at < $6 AND expires_at > $5
+	`, propertyID, proxyID, exceptGrantor, proxy.ScopeVote, from, until).Scan(&count)
+	return count, err
+}
+
+// CreateProxy vekaleti kaydeder. Aktif sitesi olmayan vekilin aktif sitesi vekaletin
+// sitesi yapılır ki girişte temsil ettiği daireye erişebilsin.
+func (r *ProxyRepository) CreateProxy(ctx context.Context, p *models.UnitProxy) error {
+	tx, err := r.db(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO unit_proxies (property_id, unit_id, grantor_id, proxy_id, scopes, starts_at, expires_at,
+			document_key, document_name, document_type, document_size, document_sha256, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14)
+		RETURNING id
+	`, p.PropertyID, p.UnitID, p.GrantorID, p.ProxyID, p.Scopes, p.StartsAt, p.ExpiresAt,
+		p.DocumentKey, p.DocumentName, p.DocumentType, p.DocumentSize, p.DocumentSHA256, p.Note, p.CreatedAt).Scan(&p.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET active_property_id = $2, updated_at = NOW()
+		WHERE id = $1 AND active_property_id IS NULL
+	`, p.ProxyID, p.PropertyID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+const proxyColumns = `
+	p.id, p.property_id, p.unit_id, COALESCE(u.block || '-', '') || u.door_number,
+	p.grantor_id, g.first_name || ' ' || g.last_name, p.proxy_id, v.first_name || ' ' || v.last_name,
+	p.scopes, p.starts_at, p.expires_at, p.document_key, p.document_name, p.document_type, p.document_size,
+	p.document_sha256, COALESCE(p.note, ''),
+	EXISTS (
+		SELECT 1 FROM resident_units ru
+		WHERE ru.resident_id = p.grantor_id AND ru.unit_id = p.unit_id AND ru.role = 'OWNER'
+		  AND ru.is_active = true AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+	),
+	p.revoked_at, COALESCE(p.revoked_by::text, ''), p.created_at`
+
+const proxyJoins = `
+	FROM unit_proxies p
+	JOIN units u ON u.id = p.unit_id
+	JOIN users g ON g.id = p.grantor_id
+	JOIN users v ON v.id = p.proxy_id`
+
+func scanProxy(row pgx.Row) (*models.UnitProxy, error) {
+	p := &models.UnitProxy{}
+	err := row.Scan(&p.ID, &p.PropertyID, &p.UnitID, &p.UnitName, &p.GrantorID, &p.GrantorName, &p.ProxyID,
+		&p.ProxyName, &p.Scopes, &p.StartsAt, &p.ExpiresAt, &p.DocumentKey, &p.DocumentName, &p.DocumentType,
+		&p.DocumentSize, &p.DocumentSHA256, &p.Note, &p.GrantorIsOwner, &p.RevokedAt, &p.RevokedBy, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetProxy sitedeki vekaleti getirir; propertyID boşsa site kısıtı uygulanmaz
+func (r *ProxyRepository) GetProxy(ctx context.Context, propertyID, id string) (*models.UnitProxy, error) {
+	p, err := scanProxy(r.db(ctx).QueryRow(ctx, `
+		SELECT `+proxyColumns+proxyJoins+`
+		WHERE p.id::text = $2 AND ($1 = '' OR p.property_id::text = $1)
+	`, propertyID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProxyNotFound
+	}
+	return p, err
+}
+
+// ListProxies filtreye uyan vekaletler, en yeni önce. IncludeInactive verilmedikçe
+// iptal edilmiş ve now itibarıyla süresi dolmuş vekaletler dönmez.
+func (r *ProxyRepository) ListProxies(ctx context.Context, f models.ProxyFilter, now time.Time) ([]models.UnitProxy, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT `+proxyColumns+proxyJoins+`
+		WHERE ($1 = '' OR p.property_id::text = $1)
+		  AND ($2 = '' OR p.unit_id::text = $2)
+		  AND ($3 = '' OR p.grantor_id::text = $3)
+		  AND ($4 = '' OR p.proxy_id::text = $4)
+		  AND ($5 OR (p.revoked_at IS NULL AND p.expires_at > $6))
+		ORDER BY p.created_at DESC
+	`, f.PropertyID, f.UnitID, f.GrantorID, f.ProxyID, f.IncludeInactive, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proxies := []models.UnitProxy{}
+	for rows.Next() {
+		p, err := scanProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, *p)
+	}
+	return proxies, rows.Err()
+}
+
+// RevokeProxy vekaleti iptal eder; vekalet yoksa veya zaten iptal edilmişse false döner
+func (r *ProxyRepository) RevokeProxy(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE unit_proxies SET revoked_at = $4, revoked_by = NULLIF($3, '')::uuid
+		WHERE id::text = $2 AND ($1 = '' OR property_id::text = $1) AND revoked_at IS NULL
+	`, propertyID, id, revokedBy, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
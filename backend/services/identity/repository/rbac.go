@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+var (
+	// ErrRoleNotFound rol tanımlı değil
+	ErrRoleNotFound = errors.New("rol bulunamadı")
+	// ErrRoleNotAssignable yalnızca personel (STAFF) rolleri siteye atanabilir
+	ErrRoleNotAssignable = errors.New("bu rol siteye atanamaz")
+	// ErrAssignmentExists kullanıcının sitede bu rolü zaten var
+	ErrAssignmentExists = errors.New("kullanıcının bu sitede bu rolü zaten var")
+)
+
+// RBACRepository rol, yetki ve site rol atamaları
+type RBACRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRBACRepository yeni repository oluşturur
+func NewRBACRepository(pool *pgxpool.Pool) *RBACRepository {
+	return &RBACRepository{pool: pool}
+}
+
+// db site rol atamalarının bağlantısı (RLS). Rol kataloğu ve kullanıcının tüm sitelerdeki
+// erişimi kiracıdan bağımsızdır; bu sorgular havuzu kullanır.
+func (r *RBACRepository) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, r.pool)
+}
+
+// ResolveAccess kullanıcının sitedeki rollerini ve yetkilerini çözer. Roller dört kaynaktan
+// gelir: sitedeki personel atamaları, sitedeki dairelerle ilişkisi (resident_units.role),
+// sitede geçerli bir vekaleti varsa PROXY ve tüm sitelerde geçerli platform rolleri (users.roles).
+func (r *RBACRepository) ResolveAccess(ctx context.Context, userID, propertyID string) (*models.Access, error) {
+	access := &models.Access{PropertyID: propertyID, Roles: []string{}, Permissions: []string{}}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT role FROM (
+			SELECT a.role_code AS role
+			FROM property_role_assignments a
+			WHERE a.user_id = $1 AND a.property_id::text = $2 AND a.revoked_at IS NULL
+			UNION
+			SELECT ru.role
+			FROM resident_units ru
+			JOIN units u ON u.id = ru.unit_id
+			WHERE ru.resident_id = $1 AND u.property_id::text = $2 AND ru.is_active = true
+			  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+			UNION
+			SELECT 'PROXY'
+			FROM active_unit_proxies p
+			WHERE p.proxy_id = $1 AND p.property_id::text = $2
+			UNION
+			SELECT ro.code
+			FROM users us
+			JOIN roles ro ON ro.code = ANY(us.roles) AND ro.kind = 'PLATFORM'
+			WHERE us.id = $1
+		) r
+		ORDER BY role
+	`, userID, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		access.Roles = append(access.Roles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(access.Roles) == 0 {
+		return access, nil
+	}
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT DISTINCT permission_code FROM role_permissions
+		WHERE role_code = ANY($1)
+		ORDER BY permission_code
+	`, access.Roles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		access.Permissions = append(access.Permissions, p)
+	}
+	return access, rows.Err()
+}
+
+// ListPermissions yetki kataloğu
+func (r *RBACRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	rows, err := r.pool.Query(ctx, `SELECT code, module, description FROM permissions ORDER BY module, code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.Code, &p.Module, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// ListRoles roller ve yetkileri
+func (r *RBACRepository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT ro.code, ro.name, ro.kind,
+			   COALESCE(array_agg(rp.permission_code ORDER BY rp.permission_code)
+			            FILTER (WHERE rp.permission_code IS NOT NULL), '{}')
+		FROM roles ro
+		LEFT JOIN role_permissions rp ON rp.role_code = ro.code
+		GROUP BY ro.code, ro.name, ro.kind
+		ORDER BY ro.kind, ro.code
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Code, &role.Name, &role.Kind, &role.Permissions); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole rolü yetkileriyle getirir
+func (r *RBACRepository) GetRole(ctx context.Context, code string) (*models.Role, error) {
+	role := &models.Role{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT ro.code, ro.name, ro.kind,
+			   COALESCE(array_agg(rp.permission_code ORDER BY rp.permission_code)
+			            FILTER (WHERE rp.permission_code IS NOT NULL), '{}')
+		FROM roles ro
+		LEFT JOIN role_permissions rp ON rp.role_code = ro.code
+		WHERE ro.code = $1
+		GROUP BY ro.code, ro.name, ro.kind
+	`, code).Scan(&role.Code, &role.Name, &role.Kind, &role.Permissions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// SetRolePermissions rolün yetkilerini verilen liste ile değiştirir
+func (r *RBACRepository) SetRolePermissions(ctx context.Context, code string, permissions []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_code = $1`, code); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_code, permission_code)
+		SELECT $1, unnest($2::text[])
+	`, code, permissions); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListAssignments sitedeki geçerli personel rol atamaları
+func (r *RBACRepository) ListAssignments(ctx context.Context, propertyID string) ([]models.RoleAssignment, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT a.id, a.property_id, a.user_id, u.first_name || ' ' || u.last_name, a.role_code,
+			   COALESCE(a.granted_by::text, ''), a.created_at
+		FROM property_role_assignments a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.property_id = $1 AND a.revoked_at IS NULL
+		ORDER BY a.role_code, u.first_name, u.last_name
+	`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []models.RoleAssignment{}
+	for rows.Next() {
+		var a models.RoleAssignment
+		if err := rows.Scan(&a.ID, &a.PropertyID, &a.UserID, &a.UserName, &a.RoleCode, &a.GrantedBy, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// CreateAssignment personel rolünü siteye atar
+func (r *RBACRepository) CreateAssignment(ctx context.Context, a *models.RoleAssignment) error {
+	err := r.db(ctx).QueryRow(ctx, `
+		INSERT INTO property_role_assignments (property_id, user_id, role_code, granted_by)
+		SELECT $1, $2, code, NULLIF($4, '')::uuid FROM roles WHERE code = $3 AND kind = 'STAFF'
+		RETURNING id, created_at
+	`, a.PropertyID, a.UserID, a.RoleCode, a.GrantedBy).Scan(&a.ID, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrRoleNotAssignable
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrAssignmentExists
+	}
+	return err
+}
+
+// RevokeAssignment sitedeki rol atamasını kaldırır; atama yoksa false döner
+func (r *RBACRepository) RevokeAssignment(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error) {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE property_role_assignments SET revoked_at = $4, revoked_by = NULLIF($3, '')::uuid
+		WHERE id = $2 AND property_id = $1 AND revoked_at IS NULL
+	`, propertyID, id, revokedBy, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/services/identity/models"
+)
+
+// ErrRefreshTokenNotFound token kaydı yok
+var ErrRefreshTokenNotFound = errors.New("refresh token bulunamadı")
+
+// ErrSessionNotActive oturum yok, iptal edilmiş veya süresi dolmuş
+var ErrSessionNotActive = errors.New("oturum açık değil")
+
+// SessionRepository refresh token ve oturum kayıtları
+type SessionRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewSessionRepository yeni repository oluşturur
+func NewSessionRepository(pool *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{pool: pool}
+}
+
+// CreateRefreshToken yeni oturumun ilk tokenını kaydeder
+func (r *SessionRepository) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, parent_id, token_hash, user_agent, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+	`, t.ID, t.UserID, t.FamilyID, t.ParentID, t.TokenHash, t.UserAgent, t.IPAddress, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+// GetRefreshToken jti ile token kaydını getirir
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	t := &models.RefreshToken{}
+	var parentID *string
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, user_id, family_id, parent_id, token_hash, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+			   expires_at, rotated_at, revoked_at, COALESCE(revoke_reason, ''), created_at
+		FROM refresh_tokens
+		WHERE id = $1
+	`, id).Scan(&t.ID, &t.UserID, &t.FamilyID, &parentID, &t.TokenHash, &t.UserAgent, &t.IPAddress,
+		&t.ExpiresAt, &t.RotatedAt, &t.RevokedAt, &t.RevokeReason, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		t.ParentID = *parentID
+	}
+	return t, nil
+}
+
+// RotateRefreshToken eski tokenı kapatıp aynı ailede yenisini kaydeder. Eski token bu
+// arada başka bir istekte yenilenmiş ya da iptal edilmişse hiçbir şey yazılmaz ve false döner.
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET rotated_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`, oldID, next.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, parent_id, token_hash, user_agent, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9)
+	`, next.ID, next.UserID, next.FamilyID, oldID, next.TokenHash, next.UserAgent, next.IPAddress,
+		next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// RevokeSession kullanıcının bir oturumundaki (token ailesi) tüm tokenları iptal eder.
+// Açık token kalmamışsa false döner.
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID, familyID, reason string, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = $4, revoke_reason = $3
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`, userID, familyID, reason, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeUserSessions kullanıcının tüm oturumlarını iptal eder, iptal edilen oturum sayısını döner
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID, reason string, now time.Time) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx, `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = $3, revoke_reason = $2
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING family_id, rotated_at
+		)
+		SELECT COUNT(DISTINCT family_id) FILTER (WHERE rotated_at IS NULL) FROM revoked
+	`, userID, reason, now).Scan(&n)
+	return n, err
+}
+
+// GetActiveSession kullanıcının açık oturumunu getirir; ailenin geçerli (son) tokenı iptal
+// edilmiş, yenilenmiş veya süresi dolmuşsa ErrSessionNotActive döner
+func (r *SessionRepository) GetActiveSession(ctx context.Context, userID, familyID string, now time.Time) (*models.Session, error) {
+	var s models.Session
+	err := r.pool.QueryRow(ctx, `
+		SELECT t.family_id, COALESCE(t.user_agent, ''), COALESCE(t.ip_address, ''),
+			   (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			   t.created_at, t.expires_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.family_id = $2
+		  AND t.rotated_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > $3
+	`, userID, familyID, now).Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSessions kullanıcının açık oturumları; her oturum için ailenin geçerli (son) tokenı esas alınır
+func (r *SessionRepository) ListSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT t.family_id, COALESCE(t.user_agent, ''), COALESCE(t.ip_address, ''),
+			   (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			   t.created_at, t.expires_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.rotated_at IS NULL AND t.revoked_at IS NULL AND t.expires_at > $2
+		ORDER BY t.created_at DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
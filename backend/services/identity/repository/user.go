@@ -2,62 +2,123 @@ package repository
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
 	"github.com/siteeksen/backend/services/identity/models"
 )
 
-// UserRepository kullanıcı veritabanı işlemleri
+// Arama özetlerinin alan adları (encryption.BlindIndex.Hash)
+const (
+	PhoneField = "phone"
+	TCKNField  = "tckn"
+)
+
+var (
+	// ErrUserNotFound telefon numarasına kayıtlı kullanıcı yok
+	ErrUserNotFound = errors.New("kullanıcı bulunamadı")
+	// ErrTCKNTaken TC kimlik numarası başka bir hesaba kayıtlı
+	ErrTCKNTaken = errors.New("bu TC kimlik numarası başka bir hesaba kayıtlı")
+)
+
+// UserRepository kullanıcı veritabanı işlemleri. Telefon ve TCKN şifreli saklanır,
+// aramalar anahtarlı özetle (phone_hash, tc_hash) yapılır. Henüz yeniden şifreleme
+// işinden geçmemiş kayıtlarda düz metin phone sütunu okunmaya devam eder.
 type UserRepository struct {
 	pool *pgxpool.Pool
+	pii  *encryption.PIICipher
 }
 
 // NewUserRepository yeni repository oluşturur
-func NewUserRepository(pool *pgxpool.Pool) *UserRepository {
-	return &UserRepository{pool: pool}
+func NewUserRepository(pool *pgxpool.Pool, pii *encryption.PIICipher) *UserRepository {
+	return &UserRepository{pool: pool, pii: pii}
 }
 
-// GetByPhone telefon numarasına göre kullanıcı getirir
-func (r *UserRepository) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
-	query := `
-		SELECT id, tc_encrypted, tc_hash, first_name, last_name, 
-			   phone, email, password_hash, active_property_id, roles, created_at, updated_at
-		FROM users 
-		WHERE phone = $1
-	`
+const userColumns = `
+	id, COALESCE(tc_encrypted, ''), COALESCE(tc_hash, ''), first_name, last_name,
+	COALESCE(phone, ''), COALESCE(phone_encrypted, ''), COALESCE(phone_hash, ''), COALESCE(email, ''),
+	password_hash, COALESCE(active_property_id::text, ''), roles, created_at, updated_at`
+
+func (r *UserRepository) scanUser(row pgx.Row) (*models.User, error) {
 	user := &models.User{}
-	err := r.pool.QueryRow(ctx, query, phone).Scan(
+	err := row.Scan(
 		&user.ID, &user.TCEncrypted, &user.TCHash, &user.FirstName, &user.LastName,
-		&user.Phone, &user.Email, &user.PasswordHash, &user.ActivePropertyID, &user.Roles,
-		&user.CreatedAt, &user.UpdatedAt,
+		&user.Phone, &user.PhoneEncrypted, &user.PhoneHash, &user.Email, &user.PasswordHash,
+		&user.ActivePropertyID, &user.Roles, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if user.Phone, err = decryptPhone(r.pii, user.Phone, user.PhoneEncrypted); err != nil {
+		return nil, err
+	}
+	if user.TCEncrypted != "" {
+		if user.TCKN, err = r.pii.Decrypt(user.TCEncrypted); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// GetByPhone telefon numarasına göre kullanıcı getirir
+func (r *UserRepository) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	user, err := r.scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE phone_hash = $1 OR (phone_hash IS NULL AND phone = $2)
+	`, r.pii.Hash(PhoneField, phone), phone))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 // GetByID ID'ye göre kullanıcı getirir
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-	query := `
-		SELECT id, tc_encrypted, tc_hash, first_name, last_name, 
-			   phone, email, password_hash, active_property_id, roles, created_at, updated_at
-		FROM users 
+	return r.scanUser(r.pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users
 		WHERE id = $1
-	`
-	user := &models.User{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.TCEncrypted, &user.TCHash, &user.FirstName, &user.LastName,
-		&user.Phone, &user.Email, &user.PasswordHash, &user.ActivePropertyID, &user.Roles,
-		&user.CreatedAt, &user.UpdatedAt,
-	)
+	`, id))
+}
+
+// ListTenantAdmins kiracının sitelerinde MANAGER rolü atanmış kullanıcılar
+func (r *UserRepository) ListTenantAdmins(ctx context.Context, tenantID string) ([]models.User, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE id IN (
+			SELECT a.user_id
+			FROM property_role_assignments a
+			JOIN properties p ON p.id = a.property_id
+			WHERE p.tenant_id::text = $1 AND a.role_code = 'MANAGER' AND a.revoked_at IS NULL
+		)
+		ORDER BY created_at
+	`, tenantID)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
 }
 
-// GetUserProperties kullanıcının bağlı olduğu siteleri getirir
+// GetUserProperties kullanıcının bağlı olduğu siteleri getirir; geçerli vekaletle temsil
+// ettiği daireler PROXY rolüyle döner
 func (r *UserRepository) GetUserProperties(ctx context.Context, userID string) ([]models.UserProperty, error) {
 	query := `
 		SELECT p.id, p.name, u.id, u.block || '-' || u.door_number, ru.role
@@ -65,6 +126,12 @@ func (r *UserRepository) GetUserProperties(ctx context.Context, userID string) (
 		JOIN units u ON ru.unit_id = u.id
 		JOIN properties p ON u.property_id = p.id
 		WHERE ru.resident_id = $1 AND ru.is_active = true
+		UNION ALL
+		SELECT p.id, p.name, u.id, u.block || '-' || u.door_number, 'PROXY'
+		FROM active_unit_proxies ap
+		JOIN units u ON ap.unit_id = u.id
+		JOIN properties p ON u.property_id = p.id
+		WHERE ap.proxy_id = $1
 	`
 	rows, err := r.pool.Query(ctx, query, userID)
 	if err != nil {
@@ -90,15 +157,98 @@ func (r *UserRepository) SetActiveProperty(ctx context.Context, userID, property
 	return err
 }
 
-// Create yeni kullanıcı oluşturur
+// UpdatePassword şifre özetini değiştirir
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.pool.Exec(ctx, query, passwordHash, userID)
+	return err
+}
+
+// MarkPhoneVerified telefonun OTP ile doğrulandığını kaydeder
+func (r *UserRepository) MarkPhoneVerified(ctx context.Context, userID string) error {
+	query := `UPDATE users SET phone_verified_at = NOW(), updated_at = NOW() WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, userID)
+	return err
+}
+
+// SetTCKN kullanıcının TCKN'sini şifreleyip kaydeder. TCKN başka bir hesapta
+// kayıtlıysa ErrTCKNTaken döner.
+func (r *UserRepository) SetTCKN(ctx context.Context, userID, tckn string) error {
+	encrypted, err := r.pii.Encrypt(tckn)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		UPDATE users SET tc_encrypted = $2, tc_hash = $3, updated_at = NOW() WHERE id = $1
+	`, userID, encrypted, r.pii.Hash(TCKNField, tckn))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTCKNTaken
+	}
+	return err
+}
+
+// Create yeni kullanıcı oluşturur; telefon ve TCKN (varsa) şifrelenir
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	phone, err := sealPhone(r.pii, user.Phone)
+	if err != nil {
+		return err
+	}
+	var tcEncrypted, tcHash string
+	if user.TCKN != "" {
+		if tcEncrypted, err = r.pii.Encrypt(user.TCKN); err != nil {
+			return err
+		}
+		tcHash = r.pii.Hash(TCKNField, user.TCKN)
+	}
+
 	query := `
-		INSERT INTO users (id, tc_encrypted, tc_hash, first_name, last_name, phone, email, password_hash, roles)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, tc_encrypted, tc_hash, first_name, last_name, phone_encrypted, phone_hash,
+			pii_key_version, email, password_hash, roles)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
 	`
-	_, err := r.pool.Exec(ctx, query,
-		user.ID, user.TCEncrypted, user.TCHash, user.FirstName, user.LastName,
-		user.Phone, user.Email, user.PasswordHash, user.Roles,
+	_, err = r.pool.Exec(ctx, query,
+		user.ID, tcEncrypted, tcHash, user.FirstName, user.LastName, phone.Encrypted, phone.Hash,
+		r.pii.ActiveVersion(), user.Email, user.PasswordHash, user.Roles,
 	)
-	return err
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if pgErr.ConstraintName == "idx_users_tc_hash" {
+			return ErrTCKNTaken
+		}
+		return ErrPhoneTaken
+	}
+	if err != nil {
+		return err
+	}
+	user.PhoneEncrypted, user.PhoneHash = phone.Encrypted, phone.Hash
+	user.TCEncrypted, user.TCHash = tcEncrypted, tcHash
+	return nil
+}
+
+// sealedPhone telefonun şifreli hali ve arama özeti
+type sealedPhone struct {
+	Encrypted string
+	Hash      string
+}
+
+// sealPhone telefonu aktif anahtarla şifreler; boş telefon boş döner
+func sealPhone(pii *encryption.PIICipher, phone string) (sealedPhone, error) {
+	if phone == "" {
+		return sealedPhone{}, nil
+	}
+	encrypted, err := pii.Encrypt(phone)
+	if err != nil {
+		return sealedPhone{}, err
+	}
+	return sealedPhone{Encrypted: encrypted, Hash: pii.Hash(PhoneField, phone)}, nil
+}
+
+// decryptPhone şifreli telefonu çözer; şifreli değer yoksa (yeniden şifreleme işinden
+// geçmemiş kayıt) düz metin değeri döner
+func decryptPhone(pii *encryption.PIICipher, plain, encrypted string) (string, error) {
+	if encrypted == "" {
+		return plain, nil
+	}
+	return pii.Decrypt(encrypted)
 }
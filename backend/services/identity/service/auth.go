@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/encryption"
 	"github.com/siteeksen/backend/services/identity/models"
 	"github.com/siteeksen/backend/services/identity/repository"
 	"golang.org/x/crypto/bcrypt"
@@ -19,22 +24,92 @@ type TokenPair struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
+// UserStore kullanıcı kayıtlarına erişim (repository.UserRepository). Testlerde
+// bellek içi bir uygulamayla değiştirilebilir.
+type UserStore interface {
+	GetByPhone(ctx context.Context, phone string) (*models.User, error)
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetUserProperties(ctx context.Context, userID string) ([]models.UserProperty, error)
+	SetActiveProperty(ctx context.Context, userID, propertyID string) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	MarkPhoneVerified(ctx context.Context, userID string) error
+	SetTCKN(ctx context.Context, userID, tckn string) error
+}
+
+var _ UserStore = (*repository.UserRepository)(nil)
+
+// SessionStore refresh token ve oturum kayıtları (repository.SessionRepository)
+type SessionStore interface {
+	CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) (bool, error)
+	RevokeSession(ctx context.Context, userID, familyID, reason string, now time.Time) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID, reason string, now time.Time) (int, error)
+	ListSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error)
+	GetActiveSession(ctx context.Context, userID, familyID string, now time.Time) (*models.Session, error)
+}
+
+var _ SessionStore = (*repository.SessionRepository)(nil)
+
+// Oturum iptal nedenleri
+const (
+	RevokeLogout        = "LOGOUT"
+	RevokeLogoutAll     = "LOGOUT_ALL"
+	RevokeReuse         = "REUSE"
+	RevokePasswordReset = "PASSWORD_RESET"
+)
+
+var (
+	// ErrInvalidRefreshToken token imzası, süresi veya kaydı geçersiz; oturum kapatılmış olabilir
+	ErrInvalidRefreshToken = errors.New("geçersiz refresh token")
+	// ErrRefreshTokenReused daha önce yenilenmiş bir token tekrar kullanıldı; oturum iptal edildi
+	ErrRefreshTokenReused = errors.New("refresh token tekrar kullanıldı, oturum sonlandırıldı")
+	// ErrSessionNotFound kullanıcının bu kimlikte açık oturumu yok
+	ErrSessionNotFound = errors.New("oturum bulunamadı")
+	// ErrSessionRevoked access tokenın oturumu kapatılmış veya süresi dolmuş
+	ErrSessionRevoked = errors.New("oturum sonlandırılmış, yeniden giriş yapın")
+	// ErrInvalidTCKN TC kimlik numarası 11 hane değil veya kontrol haneleri tutmuyor
+	ErrInvalidTCKN = errors.New("geçersiz TC kimlik numarası")
+)
+
+// ClientInfo oturumu açan veya yenileyen istemci (oturum listesinde gösterilir)
+type ClientInfo struct {
+	UserAgent   string
+	IP          string
+	DeviceToken string // İkinci adımın hatırlandığı cihazın tokenı (X-Device-Token)
+}
+
 // AuthService kimlik doğrulama servisi
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret []byte
+	userRepo UserStore
+	sessions SessionStore
+	access   AccessResolver
+	signer   *claims.Signer
+	mfa      *MFAService // nil ise girişte ikinci adım istenmez
 }
 
 // NewAuthService yeni servis oluşturur
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string) *AuthService {
+func NewAuthService(userRepo UserStore, sessions SessionStore, access AccessResolver, signer *claims.Signer) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo: userRepo,
+		sessions: sessions,
+		access:   access,
+		signer:   signer,
 	}
 }
 
+// JWKS tokenları doğrulamak için yayınlanan açık anahtarlar
+func (s *AuthService) JWKS() claims.JWKSet {
+	return s.signer.JWKS()
+}
+
+// Keys kimlik servisinin kendi access token doğrulamasında kullandığı anahtar halkası
+func (s *AuthService) Keys() claims.KeySource {
+	return s.signer
+}
+
 // Login kullanıcı girişi yapar
-func (s *AuthService) Login(ctx context.Context, phone, password string) (*TokenPair, *models.UserResponse, error) {
+func (s *AuthService) Login(ctx context.Context, phone, password string, client ClientInfo) (*TokenPair, *models.UserResponse, error) {
 	user, err := s.userRepo.GetByPhone(ctx, phone)
 	if err != nil {
 		return nil, nil, errors.New("kullanıcı bulunamadı")
@@ -44,47 +119,147 @@ func (s *AuthService) Login(ctx context.Context, phone, password string) (*Token
 		return nil, nil, errors.New("geçersiz şifre")
 	}
 
-	tokens, err := s.generateTokens(user)
+	return s.completeLogin(ctx, user, client)
+}
+
+// completeLogin ilk adımı (şifre veya SMS kodu) geçen kullanıcı için oturum açar.
+// İki adımlı doğrulama etkinse veya rolü gereği zorunluysa oturum yerine
+// *MFARequiredError döner.
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, client ClientInfo) (*TokenPair, *models.UserResponse, error) {
+	if s.mfa != nil {
+		challenge, err := s.mfa.challenge(ctx, user, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		if challenge != nil {
+			return nil, nil, &MFARequiredError{Challenge: challenge}
+		}
+	}
+	return s.startSession(ctx, user, client, nil)
+}
+
+// startSession doğrulanmış kullanıcı için yeni bir oturum (token ailesi) açar, token
+// çifti ve kullanıcı yanıtı üretir. mfaAt girişte ikinci adımın doğrulandığı zamandır.
+func (s *AuthService) startSession(ctx context.Context, user *models.User, client ClientInfo, mfaAt *time.Time) (*TokenPair, *models.UserResponse, error) {
+	tokens, refresh, err := s.generateTokens(ctx, user, uuid.New().String(), mfaAt)
 	if err != nil {
 		return nil, nil, err
 	}
+	refresh.UserAgent = client.UserAgent
+	refresh.IPAddress = client.IP
+	if err := s.sessions.CreateRefreshToken(ctx, refresh); err != nil {
+		return nil, nil, err
+	}
 
 	// Kullanıcının sitelerini al
 	properties, _ := s.userRepo.GetUserProperties(ctx, user.ID)
 
-	response := &models.UserResponse{
-		ID:         user.ID,
-		FirstName:  user.FirstName,
-		LastName:   user.LastName,
-		Phone:      user.Phone,
-		Email:      user.Email,
-		Properties: properties,
+	return tokens, userResponse(user, properties, false), nil
+}
+
+// RefreshToken refresh tokenı tek kullanımlık olarak yeniler. Her yenilemede eski token
+// kapanır ve aynı oturumda yenisi verilir. Kapanmış bir tokenın tekrar gelmesi tokenın
+// çalındığına işaret eder; bu durumda oturumun tamamı iptal edilir.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	current, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if current.RotatedAt != nil {
+		return nil, s.revokeReused(ctx, current)
 	}
 
-	return tokens, response, nil
+	user, err := s.userRepo.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, next, err := s.generateTokens(ctx, user, current.FamilyID, nil)
+	if err != nil {
+		return nil, err
+	}
+	next.UserAgent = client.UserAgent
+	next.IPAddress = client.IP
+
+	rotated, err := s.sessions.RotateRefreshToken(ctx, current.ID, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Aynı token eşzamanlı başka bir istekte kullanıldı
+		return nil, s.revokeReused(ctx, current)
+	}
+	return tokens, nil
 }
 
-// RefreshToken token yeniler
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
-	claims := &jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(refreshToken, claims, func(t *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+// Logout refresh tokenın ait olduğu oturumu kapatır. Oturum zaten kapalıysa hata dönmez.
+// Verilmiş access tokenlar süreleri (15 dk) dolana kadar geçerli kalır.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	current, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	_, err = s.sessions.RevokeSession(ctx, current.UserID, current.FamilyID, RevokeLogout, time.Now())
+	return err
+}
 
-	if err != nil || !token.Valid {
-		return nil, errors.New("geçersiz refresh token")
+// LogoutAll kullanıcının tüm cihazlardaki oturumlarını kapatır, kapatılan oturum sayısını döner
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) (int, error) {
+	return s.sessions.RevokeUserSessions(ctx, userID, RevokeLogoutAll, time.Now())
+}
+
+// ListSessions kullanıcının açık oturumları (cihaz, IP ve son kullanım)
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	return s.sessions.ListSessions(ctx, userID, time.Now())
+}
+
+// RevokeSession kullanıcının tek bir oturumunu kapatır
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+	ok, err := s.sessions.RevokeSession(ctx, userID, sessionID, RevokeLogout, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// lookupRefreshToken imzayı ve süreyi doğrular, token kaydını getirir ve özetini karşılaştırır
+func (s *AuthService) lookupRefreshToken(ctx context.Context, refreshToken string) (*models.RefreshToken, error) {
+	tokenClaims, err := claims.Parse(refreshToken, s.signer, claims.TokenRefresh)
+	if err != nil || tokenClaims.ID == "" {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	user, err := s.userRepo.GetByID(ctx, claims.Subject)
+	current, err := s.sessions.GetRefreshToken(ctx, tokenClaims.ID)
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
 		return nil, err
 	}
+	if current.UserID != tokenClaims.Subject || current.TokenHash != hashToken(refreshToken) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return current, nil
+}
 
-	return s.generateTokens(user)
+func (s *AuthService) revokeReused(ctx context.Context, t *models.RefreshToken) error {
+	if _, err := s.sessions.RevokeSession(ctx, t.UserID, t.FamilyID, RevokeReuse, time.Now()); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
 }
 
-// GetUserByID ID ile kullanıcı getirir
-func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*models.UserResponse, error) {
+// GetUserByID ID ile kullanıcı getirir. Telefon ve TCKN reveal verilmedikçe maskelenir.
+func (s *AuthService) GetUserByID(ctx context.Context, userID string, reveal bool) (*models.UserResponse, error) {
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -92,14 +267,36 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*models.U
 
 	properties, _ := s.userRepo.GetUserProperties(ctx, user.ID)
 
-	return &models.UserResponse{
+	return userResponse(user, properties, reveal), nil
+}
+
+// SetTCKN kullanıcının TC kimlik numarasını kontrol hanelerini doğrulayarak kaydeder
+func (s *AuthService) SetTCKN(ctx context.Context, userID, tckn string) error {
+	tckn = strings.TrimSpace(tckn)
+	if !encryption.ValidateTCKN(tckn) {
+		return ErrInvalidTCKN
+	}
+	return s.userRepo.SetTCKN(ctx, userID, tckn)
+}
+
+// userResponse kullanıcı yanıtı; kişisel veriler reveal verilmedikçe maskelenir
+func userResponse(user *models.User, properties []models.UserProperty, reveal bool) *models.UserResponse {
+	response := &models.UserResponse{
 		ID:         user.ID,
 		FirstName:  user.FirstName,
 		LastName:   user.LastName,
 		Phone:      user.Phone,
+		TCKN:       user.TCKN,
 		Email:      user.Email,
 		Properties: properties,
-	}, nil
+	}
+	if !reveal {
+		response.Phone = maskPhone(user.Phone)
+		if user.TCKN != "" {
+			response.TCKN = encryption.MaskTCKN(user.TCKN)
+		}
+	}
+	return response
 }
 
 // GetUserProperties kullanıcının sitelerini getirir
@@ -107,47 +304,108 @@ func (s *AuthService) GetUserProperties(ctx context.Context, userID string) ([]m
 	return s.userRepo.GetUserProperties(ctx, userID)
 }
 
-// SetActiveProperty aktif siteyi değiştirir
-func (s *AuthService) SetActiveProperty(ctx context.Context, userID, propertyID string) error {
-	return s.userRepo.SetActiveProperty(ctx, userID, propertyID)
+// SetActiveProperty aktif siteyi değiştirir ve oturum için o sitenin rol ve yetkilerini
+// taşıyan yeni bir access token döner. Kullanıcının sitede rolü yoksa reddedilir. Oturum
+// kapatılmışsa ErrSessionRevoked döner; yeni tokenın süresi oturumun süresini aşmaz.
+func (s *AuthService) SetActiveProperty(ctx context.Context, userID, propertyID, sessionID string) (*TokenPair, error) {
+	now := time.Now()
+	session, err := s.activeSession(ctx, userID, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.access.ResolveAccess(ctx, userID, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(access.Roles) == 0 {
+		return nil, ErrPropertyAccessDenied
+	}
+	if err := s.userRepo.SetActiveProperty(ctx, userID, propertyID); err != nil {
+		return nil, err
+	}
+
+	return s.sessionAccessToken(userID, propertyID, access, session, nil, now)
 }
 
-func (s *AuthService) generateTokens(user *models.User) (*TokenPair, error) {
-	now := time.Now()
-	accessExpiry := now.Add(15 * time.Minute)
-	refreshExpiry := now.Add(7 * 24 * time.Hour)
+// activeSession access tokenın oturumunun hâlâ açık olduğunu doğrular. Çıkış, tüm
+// cihazlardan çıkış veya refresh token tekrarı oturumu kapattıysa ErrSessionRevoked döner.
+func (s *AuthService) activeSession(ctx context.Context, userID, sessionID string, now time.Time) (*models.Session, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, ErrSessionRevoked
+	}
+	session, err := s.sessions.GetActiveSession(ctx, userID, sessionID, now)
+	if errors.Is(err, repository.ErrSessionNotActive) {
+		return nil, ErrSessionRevoked
+	}
+	return session, err
+}
 
-	// Access token
-	accessClaims := jwt.MapClaims{
-		"sub":         user.ID,
-		"property_id": user.ActivePropertyID,
-		"roles":       user.Roles,
-		"iat":         now.Unix(),
-		"exp":         accessExpiry.Unix(),
-		"jti":         uuid.New().String(),
+// sessionAccessToken açık oturum için yeni access token imzalar; tokenın süresi oturumun
+// (geçerli refresh tokenın) bitişini aşmaz
+func (s *AuthService) sessionAccessToken(userID, propertyID string, access *models.Access, session *models.Session, mfaAt *time.Time, now time.Time) (*TokenPair, error) {
+	accessClaims := s.accessClaims(userID, propertyID, access, session.ID, mfaAt, now)
+	if session.ExpiresAt.Before(accessClaims.ExpiresAt.Time) {
+		accessClaims.ExpiresAt = jwt.NewNumericDate(session.ExpiresAt)
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(s.jwtSecret)
+	accessToken, err := s.signer.Sign(accessClaims)
 	if err != nil {
 		return nil, err
 	}
+	return &TokenPair{AccessToken: accessToken, ExpiresIn: int64(accessClaims.ExpiresAt.Sub(now).Seconds())}, nil
+}
+
+// generateTokens oturum (sessionID) için access ve refresh token üretir. Access token
+// aktif sitedeki rolleri ve yetkileri taşır. Dönen refresh kaydının istemci bilgisini
+// çağıran doldurur.
+func (s *AuthService) generateTokens(ctx context.Context, user *models.User, sessionID string, mfaAt *time.Time) (*TokenPair, *models.RefreshToken, error) {
+	now := time.Now()
 
-	// Refresh token
-	refreshClaims := jwt.RegisteredClaims{
-		Subject:   user.ID,
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(refreshExpiry),
-		ID:        uuid.New().String(),
+	access, err := s.access.ResolveAccess(ctx, user.ID, user.ActivePropertyID)
+	if err != nil {
+		return nil, nil, err
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(s.jwtSecret)
+	accessToken, err := s.signAccessToken(user.ID, user.ActivePropertyID, access, sessionID, mfaAt, now)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	refresh := claims.NewRefreshClaims(user.ID, sessionID, uuid.New().String(), now)
+	refreshToken, err := s.signer.Sign(refresh)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-		ExpiresIn:    int64(accessExpiry.Sub(now).Seconds()),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(claims.AccessTokenTTL.Seconds()),
+	}, &models.RefreshToken{
+		ID:        refresh.ID,
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: refresh.ExpiresAt.Time,
+		CreatedAt: now,
 	}, nil
 }
+
+// signAccessToken sitedeki rol ve yetkileri taşıyan access token imzalar. mfaAt
+// verilirse hassas işlemler için son iki adımlı doğrulama zamanı olarak eklenir.
+func (s *AuthService) signAccessToken(userID, propertyID string, access *models.Access, sessionID string, mfaAt *time.Time, now time.Time) (string, error) {
+	return s.signer.Sign(s.accessClaims(userID, propertyID, access, sessionID, mfaAt, now))
+}
+
+func (s *AuthService) accessClaims(userID, propertyID string, access *models.Access, sessionID string, mfaAt *time.Time, now time.Time) *claims.Claims {
+	accessClaims := claims.NewAccessClaims(userID, propertyID, access.Roles, access.Permissions,
+		sessionID, uuid.New().String(), now)
+	if mfaAt != nil {
+		accessClaims.MFAAt = jwt.NewNumericDate(*mfaAt)
+	}
+	return accessClaims
+}
+
+// hashToken refresh tokenın veritabanında saklanan SHA-256 özeti
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/pkg/tenant"
+)
+
+var _ tenant.BillingNotifier = (*BillingFailureNotifier)(nil)
+
+// BillingFailureNotifier abonelik ödemesi alınamayan kiracının yöneticilerine SMS gönderir
+type BillingFailureNotifier struct {
+	store TenantAdminStore
+	sms   *sms.Service
+}
+
+// NewBillingFailureNotifier yeni bildirici oluşturur
+func NewBillingFailureNotifier(store TenantAdminStore, smsService *sms.Service) *BillingFailureNotifier {
+	return &BillingFailureNotifier{store: store, sms: smsService}
+}
+
+// NotifyPaymentFailed yöneticilere yeniden deneme tarihini veya askıya alındığını bildirir
+func (n *BillingFailureNotifier) NotifyPaymentFailed(ctx context.Context, f tenant.PaymentFailure) error {
+	return notifyTenantAdmins(ctx, n.store, n.sms, f.TenantID, paymentFailureMessage(f))
+}
+
+func paymentFailureMessage(f tenant.PaymentFailure) string {
+	if f.Suspended && f.InvoiceID == "" {
+		return fmt.Sprintf("SiteEksen: %s deneme süresi sona erdi ve hesap askıya alındı. Ödeme yöntemi ekleyerek aboneliğinizi başlatabilirsiniz.",
+			f.TenantName)
+	}
+	amount := fmt.Sprintf("%d,%02d TL", f.AmountKurus/100, f.AmountKurus%100)
+	if f.Suspended {
+		return fmt.Sprintf("SiteEksen: %s aboneliğinin %s tutarındaki ödemesi alınamadığından hesap askıya alındı. Kartınızı güncellediğinizde hesap yeniden açılır.",
+			f.TenantName, amount)
+	}
+	return fmt.Sprintf("SiteEksen: %s aboneliğinin %s tutarındaki ödemesi alınamadı (%s). %s tarihinde yeniden denenecek; kartınızı güncelleyebilirsiniz.",
+		f.TenantName, amount, f.Reason, f.NextRetryAt.Format("02.01.2006"))
+}
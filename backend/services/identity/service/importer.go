@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// ErrImportEmpty yüklenen dosyada veri satırı yok
+var ErrImportEmpty = errors.New("dosyada veri satırı yok")
+
+// ImportStore toplu içe aktarma transaction'ını açar (repository.ImportRepository)
+type ImportStore interface {
+	Begin(ctx context.Context, propertyID, userID string) (importer.Session, error)
+}
+
+var _ ImportStore = (*repository.ImportRepository)(nil)
+
+// ImportLimits yüklemeyle eklenen daire ve kullanıcıların plan limiti (tenant.Meter)
+type ImportLimits interface {
+	TenantForProperty(ctx context.Context, propertyID string) (*tenant.Tenant, error)
+	CheckLimitN(ctx context.Context, t *tenant.Tenant, resource string, n int) error
+}
+
+var _ ImportLimits = (*tenant.Meter)(nil)
+
+// ImportRequest yönetici yüklemesi. DryRun önizlemedir: satırların sonucu hesaplanır, hiçbir
+// değişiklik yazılmaz.
+type ImportRequest struct {
+	Kind       importer.Kind
+	PropertyID string
+	UserID     string
+	File       io.Reader
+	DryRun     bool
+}
+
+// ImportService yeni sitenin eski yazılımdan taşınması: daire, sakin, devir bakiyesi ve sayaç
+// listelerini toplu yükler
+type ImportService struct {
+	store  ImportStore
+	limits ImportLimits
+	now    func() time.Time
+}
+
+// NewImportService yeni servis oluşturur; limits nil ise plan limiti denetlenmez
+func NewImportService(store ImportStore, limits ImportLimits) *ImportService {
+	return &ImportService{store: store, limits: limits, now: time.Now}
+}
+
+// importItem dosyanın bir satırı: hataları veya transaction'da uygulanacak kaydı
+type importItem struct {
+	row    int
+	key    string
+	errors []string
+	apply  func(ctx context.Context, s importer.Session) (importer.Action, error)
+}
+
+// Import listeyi okur, satırları doğrular ve tek transaction'da doğal anahtarlarına göre
+// ekler veya günceller. Hatalı satır varsa ya da istek önizlemeyse transaction geri alınır;
+// yükleme ya tamamen uygulanır ya hiç uygulanmaz. Aynı dosyanın tekrar yüklenmesi satırları
+// "unchanged" olarak döner. Eklenen daire veya kullanıcılar plan limitini aşıyorsa
+// *tenant.LimitError döner.
+func (s *ImportService) Import(ctx context.Context, req *ImportRequest) (*importer.Result, error) {
+	items, err := s.read(req.Kind, req.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrImportEmpty
+	}
+
+	session, err := s.store.Begin(ctx, req.PropertyID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			session.Rollback(ctx)
+		}
+	}()
+
+	result := &importer.Result{Kind: req.Kind, DryRun: req.DryRun}
+	for _, item := range items {
+		row := importer.RowResult{Row: item.row, Key: item.key, Action: importer.ActionFailed, Errors: item.errors}
+		if len(item.errors) == 0 {
+			action, err := item.apply(ctx, session)
+			var invalid *importer.InvalidError
+			switch {
+			case errors.As(err, &invalid):
+				row.Errors = []string{invalid.Message}
+			case err != nil:
+				return nil, fmt.Errorf("%d. satır yüklenemedi: %w", item.row, err)
+			default:
+				row.Action = action
+			}
+		}
+		result.Add(row)
+	}
+	if req.DryRun || result.Failed > 0 {
+		return result, nil
+	}
+
+	if s.limits != nil {
+		t, err := s.limits.TenantForProperty(ctx, req.PropertyID)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range []string{tenant.ResourceUnits, tenant.ResourceUsers} {
+			if err := s.limits.CheckLimitN(ctx, t, resource, session.Added(resource)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := session.Commit(ctx); err != nil {
+		return nil, err
+	}
+	committed = true
+	result.Applied = true
+	return result, nil
+}
+
+// read dosyayı türüne göre okur; hücre hataları ve dosya içindeki tekrarlar satıra yazılır.
+// Satırlar dosyadaki sırayla döner.
+func (s *ImportService) read(kind importer.Kind, file io.Reader) ([]importItem, error) {
+	var items []importItem
+	var rowErrs []importer.RowError
+	var err error
+	seen := map[string]int{}
+	add := func(row int, key, label string, apply func(ctx context.Context, s importer.Session) (importer.Action, error)) {
+		item := importItem{row: row, key: label, apply: apply}
+		if first, ok := seen[key]; ok {
+			item.errors = []string{fmt.Sprintf("aynı kayıt %d. satırda da var", first)}
+		} else {
+			seen[key] = row
+		}
+		items = append(items, item)
+	}
+
+	switch kind {
+	case importer.KindUnits:
+		var units []importer.UnitRecord
+		units, rowErrs, err = importer.ReadUnits(file)
+		for i := range units {
+			u := &units[i]
+			add(u.Row, importer.UnitKey(u.Block, u.DoorNumber), importer.UnitLabel(u.Block, u.DoorNumber),
+				func(ctx context.Context, s importer.Session) (importer.Action, error) { return s.Unit(ctx, u) })
+		}
+	case importer.KindResidents:
+		var residents []importer.ResidentRecord
+		residents, rowErrs, err = importer.ReadResidents(file)
+		today := s.now()
+		for i := range residents {
+			r := &residents[i]
+			label := fmt.Sprintf("%s %s %s (%s)", importer.UnitLabel(r.Block, r.DoorNumber), r.FirstName, r.LastName, roleLabel(r.Role))
+			phone, ok := NormalizePhone(r.Phone)
+			if !ok {
+				rowErrs = append(rowErrs, importer.RowError{Row: r.Row, Message: fmt.Sprintf("telefon geçersiz: %q", r.Phone)})
+				continue
+			}
+			r.Phone = phone
+			if r.StartDate.IsZero() {
+				r.StartDate = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			add(r.Row, importer.UnitKey(r.Block, r.DoorNumber)+"/"+phone+"/"+r.Role, label,
+				func(ctx context.Context, s importer.Session) (importer.Action, error) { return s.Resident(ctx, r) })
+		}
+	case importer.KindBalances:
+		var balances []importer.BalanceRecord
+		balances, rowErrs, err = importer.ReadBalances(file)
+		for i := range balances {
+			b := &balances[i]
+			add(b.Row, importer.UnitKey(b.Block, b.DoorNumber), importer.UnitLabel(b.Block, b.DoorNumber),
+				func(ctx context.Context, s importer.Session) (importer.Action, error) { return s.Balance(ctx, b) })
+		}
+	case importer.KindMeters:
+		var meters []importer.MeterRecord
+		meters, rowErrs, err = importer.ReadMeters(file)
+		for i := range meters {
+			m := &meters[i]
+			add(m.Row, strings.ToUpper(m.SerialNumber), m.SerialNumber,
+				func(ctx context.Context, s importer.Session) (importer.Action, error) { return s.Meter(ctx, m) })
+		}
+	default:
+		return nil, fmt.Errorf("%w: bilinmeyen liste türü %q", importer.ErrInvalidFile, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	failed := map[int]int{} // satır → items indeksi
+	for _, e := range rowErrs {
+		i, ok := failed[e.Row]
+		if !ok {
+			i = len(items)
+			failed[e.Row] = i
+			items = append(items, importItem{row: e.Row})
+		}
+		items[i].errors = append(items[i].errors, e.Message)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].row < items[j].row })
+	return items, nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryImport repository.ImportRepository davranışının bellek içi karşılığı: kayıtlar
+// yalnızca Commit ile kalıcı olur
+type memoryImport struct {
+	units     map[string]importer.UnitRecord
+	residents map[string]importer.ResidentRecord
+	commits   int
+}
+
+func newMemoryImport() *memoryImport {
+	return &memoryImport{units: map[string]importer.UnitRecord{}, residents: map[string]importer.ResidentRecord{}}
+}
+
+func (m *memoryImport) Begin(ctx context.Context, propertyID, userID string) (importer.Session, error) {
+	return &memorySession{store: m, units: maps.Clone(m.units), residents: maps.Clone(m.residents), added: map[string]int{}}, nil
+}
+
+type memorySession struct {
+	store     *memoryImport
+	units     map[string]importer.UnitRecord
+	residents map[string]importer.ResidentRecord
+	added     map[string]int
+}
+
+func (s *memorySession) Unit(ctx context.Context, u *importer.UnitRecord) (importer.Action, error) {
+	key := importer.UnitKey(u.Block, u.DoorNumber)
+	current, ok := s.units[key]
+	record := *u
+	record.Row = 0
+	s.units[key] = record
+	switch {
+	case !ok:
+		s.added[tenant.ResourceUnits]++
+		return importer.ActionCreated, nil
+	case current == record:
+		return importer.ActionUnchanged, nil
+	}
+	return importer.ActionUpdated, nil
+}
+
+func (s *memorySession) Resident(ctx context.Context, r *importer.ResidentRecord) (importer.Action, error) {
+	unitKey := importer.UnitKey(r.Block, r.DoorNumber)
+	if _, ok := s.units[unitKey]; !ok {
+		return "", importer.Invalid("%s dairesi bulunamadı", importer.UnitLabel(r.Block, r.DoorNumber))
+	}
+	key := unitKey + "/" + r.Phone + "/" + r.Role
+	if _, ok := s.residents[key]; ok {
+		return importer.ActionUnchanged, nil
+	}
+	s.residents[key] = *r
+	s.added[tenant.ResourceUsers]++
+	return importer.ActionCreated, nil
+}
+
+func (s *memorySession) Balance(ctx context.Context, b *importer.BalanceRecord) (importer.Action, error) {
+	return importer.ActionCreated, nil
+}
+
+func (s *memorySession) Meter(ctx context.Context, m *importer.MeterRecord) (importer.Action, error) {
+	return importer.ActionCreated, nil
+}
+
+func (s *memorySession) Added(resource string) int {
+	return s.added[resource]
+}
+
+func (s *memorySession) Commit(ctx context.Context) error {
+	s.store.units, s.store.residents = s.units, s.residents
+	s.store.commits++
+	return nil
+}
+
+func (s *memorySession) Rollback(ctx context.Context) error {
+	return nil
+}
+
+// staticLimits tek kiracının daire limiti
+type staticLimits struct {
+	tenant  *tenant.Tenant
+	current map[string]int
+}
+
+func (l *staticLimits) TenantForProperty(ctx context.Context, propertyID string) (*tenant.Tenant, error) {
+	return l.tenant, nil
+}
+
+func (l *staticLimits) CheckLimitN(ctx context.Context, t *tenant.Tenant, resource string, n int) error {
+	limit := t.MaxUsers
+	if resource == tenant.ResourceUnits {
+		limit = t.MaxUnits
+	}
+	if limit >= 0 && l.current[resource]+n > limit {
+		return &tenant.LimitError{Resource: resource, Limit: limit, Current: l.current[resource], Plan: t.SubscriptionPlan}
+	}
+	return nil
+}
+
+const unitsCSV = "Blok;Kat;Kapı No;Arsa Payı\nA;0;1;10\nA;1;2;12,5\nB;0;1;8\n"
+
+func runImport(t *testing.T, svc *ImportService, kind importer.Kind, data string, dryRun bool) *importer.Result {
+	t.Helper()
+	result, err := svc.Import(context.Background(), &ImportRequest{
+		Kind: kind, PropertyID: "p1", UserID: "m1", File: strings.NewReader(data), DryRun: dryRun,
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestImportUnitsDryRunAndIdempotency(t *testing.T) {
+	store := newMemoryImport()
+	svc := NewImportService(store, nil)
+
+	preview := runImport(t, svc, importer.KindUnits, unitsCSV, true)
+	assert.True(t, preview.DryRun)
+	assert.False(t, preview.Applied)
+	assert.Equal(t, 3, preview.Created)
+	assert.Equal(t, "A-1", preview.Rows[0].Key)
+	assert.Empty(t, store.units, "önizleme yazmaz")
+
+	applied := runImport(t, svc, importer.KindUnits, unitsCSV, false)
+	assert.True(t, applied.Applied)
+	assert.Equal(t, 3, applied.Created)
+	assert.Len(t, store.units, 3)
+
+	// Aynı dosya tekrar yüklendiğinde değişiklik olmaz; değişen satır güncellenir
+	again := runImport(t, svc, importer.KindUnits, strings.Replace(unitsCSV, "B;0;1;8", "b;0;1;9", 1), false)
+	assert.Equal(t, 2, again.Unchanged)
+	assert.Equal(t, 1, again.Updated)
+	assert.Equal(t, 0, again.Created)
+}
+
+func TestImportRejectsWholeFileOnRowErrors(t *testing.T) {
+	store := newMemoryImport()
+	svc := NewImportService(store, nil)
+
+	data := unitsCSV + "A;2;1;11\nC;x;7;5\n"
+	result := runImport(t, svc, importer.KindUnits, data, false)
+	assert.False(t, result.Applied)
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, 3, result.Created)
+	assert.Equal(t, 2, result.Failed)
+	assert.Equal(t, []string{"aynı kayıt 2. satırda da var"}, result.Rows[3].Errors)
+	assert.Equal(t, 6, result.Rows[4].Row)
+	assert.Equal(t, []string{`kat sayı olmalı: "x"`}, result.Rows[4].Errors)
+	assert.Empty(t, store.units, "hatalı satır varsa hiçbir satır yazılmaz")
+	assert.Zero(t, store.commits)
+}
+
+func TestImportResidents(t *testing.T) {
+	store := newMemoryImport()
+	svc := NewImportService(store, nil)
+	svc.now = func() time.Time { return time.Date(2026, 1, 5, 10, 0, 0, 0, time.Local) }
+	runImport(t, svc, importer.KindUnits, unitsCSV, false)
+
+	data := "Blok,Kapı No,Ad,Soyad,Telefon,Rol\n" +
+		"A,1,Ali,Demir,0532 111 22 33,Malik\n" +
+		"A,1,Ali,Demir,+905321112233,Kat Maliki\n" +
+		"A,2,Veli,Kaya,123,Kiracı\n" +
+		"C,9,Can,Ak,05321112299,Kiracı\n"
+	result := runImport(t, svc, importer.KindResidents, data, false)
+	assert.False(t, result.Applied)
+	assert.Equal(t, []string{"aynı kayıt 2. satırda da var"}, result.Rows[1].Errors, "telefon normalleştirilerek karşılaştırılır")
+	assert.Equal(t, []string{`telefon geçersiz: "123"`}, result.Rows[2].Errors)
+	assert.Equal(t, []string{"C-9 dairesi bulunamadı"}, result.Rows[3].Errors, "depodan dönen satır hatası")
+	assert.Equal(t, importer.ActionCreated, result.Rows[0].Action)
+	assert.Equal(t, "A-1 Ali Demir (kat maliki)", result.Rows[0].Key)
+
+	result = runImport(t, svc, importer.KindResidents, "Kapı No,Blok,Ad,Soyad,Telefon,Rol\n1,A,Ali,Demir,0532 111 22 33,Malik\n", false)
+	require.True(t, result.Applied)
+	resident := store.residents["a/1/+905321112233/OWNER"]
+	assert.Equal(t, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), resident.StartDate, "giriş tarihi boşsa yükleme günü")
+}
+
+func TestImportPlanLimit(t *testing.T) {
+	store := newMemoryImport()
+	limits := &staticLimits{
+		tenant:  &tenant.Tenant{ID: "t1", SubscriptionPlan: "starter", MaxUnits: 4, MaxUsers: -1},
+		current: map[string]int{tenant.ResourceUnits: 2},
+	}
+	svc := NewImportService(store, limits)
+
+	// Önizleme limit denetlemez; yükleme limiti aşarsa hiçbir şey yazılmaz
+	assert.Equal(t, 3, runImport(t, svc, importer.KindUnits, unitsCSV, true).Created)
+	_, err := svc.Import(context.Background(), &ImportRequest{
+		Kind: importer.KindUnits, PropertyID: "p1", File: strings.NewReader(unitsCSV),
+	})
+	var limitErr *tenant.LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, tenant.ResourceUnits, limitErr.Resource)
+	assert.Empty(t, store.units)
+
+	limits.current[tenant.ResourceUnits] = 1
+	assert.True(t, runImport(t, svc, importer.KindUnits, unitsCSV, false).Applied)
+}
+
+func TestImportFileErrors(t *testing.T) {
+	svc := NewImportService(newMemoryImport(), nil)
+	ctx := context.Background()
+
+	_, err := svc.Import(ctx, &ImportRequest{Kind: importer.KindUnits, File: strings.NewReader("Blok;Kat\n")})
+	assert.ErrorIs(t, err, importer.ErrInvalidFile)
+	_, err = svc.Import(ctx, &ImportRequest{Kind: importer.KindUnits, File: strings.NewReader("Kat;Kapı No;Arsa Payı\n")})
+	assert.ErrorIs(t, err, ErrImportEmpty)
+	_, err = svc.Import(ctx, &ImportRequest{Kind: "payments", File: strings.NewReader(unitsCSV)})
+	assert.ErrorIs(t, err, importer.ErrInvalidFile)
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Davet durumları
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationRevoked  = "REVOKED"
+	InvitationExpired  = "EXPIRED"
+)
+
+// Davetin iletilme şekli
+const (
+	DeliverySMS    = "SMS"
+	DeliveryManual = "MANUAL" // E-posta davetleri: bağlantıyı yönetici iletir
+)
+
+var (
+	// ErrInvitationLoginRequired davetteki telefon zaten kayıtlı; davet giriş yapılarak kabul edilmeli
+	ErrInvitationLoginRequired = errors.New("bu telefon numarası kayıtlı, daveti giriş yaparak kabul edin")
+	// ErrInvitationPhoneMismatch davet başka bir telefon numarasına gönderilmiş
+	ErrInvitationPhoneMismatch = errors.New("davet başka bir telefon numarasına gönderilmiş")
+	// ErrAlreadyMovedOut sakin-daire ilişkisi zaten pasif
+	ErrAlreadyMovedOut = errors.New("sakin bu daireden zaten çıkış yapmış")
+)
+
+// InvitationStore davetler ve sakin-daire ilişkileri (repository.InvitationRepository)
+type InvitationStore interface {
+	GetUnit(ctx context.Context, propertyID, unitID string) (*models.UserProperty, error)
+	CreateInvitation(ctx context.Context, inv *models.Invitation) error
+	SetInvitationDelivery(ctx context.Context, id, delivery string) error
+	GetInvitationByToken(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	ListInvitations(ctx context.Context, propertyID string) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error)
+	AcceptInvitation(ctx context.Context, inv *models.Invitation, user *models.User, phoneVerified bool, now time.Time) (*models.ResidentUnit, error)
+	ListUnitResidents(ctx context.Context, propertyID, unitID string, includeHistory bool) ([]models.ResidentUnit, error)
+	GetResidentUnit(ctx context.Context, propertyID, id string) (*models.ResidentUnit, error)
+	MoveOut(ctx context.Context, propertyID, id string, endDate time.Time, endedBy string, now time.Time) (bool, error)
+}
+
+var _ InvitationStore = (*repository.InvitationRepository)(nil)
+
+// InvitationConfig davet bağlantısı ayarları
+type InvitationConfig struct {
+	BaseURL string        // Davet bağlantısının ön eki; token sonuna eklenir
+	TTL     time.Duration // Davetin geçerlilik süresi
+}
+
+// DefaultInvitationConfig 7 gün geçerli davetler
+func DefaultInvitationConfig(baseURL string) InvitationConfig {
+	return InvitationConfig{BaseURL: strings.TrimRight(baseURL, "/"), TTL: 7 * 24 * time.Hour}
+}
+
+// InvitationInput yöneticinin davet isteği. Telefon veya e-postadan en az biri gerekir;
+// telefon varsa davet SMS ile gönderilir.
+type InvitationInput struct {
+	PropertyID string
+	UnitID     string
+	Role       string
+	FirstName  string
+	LastName   string
+	Phone      string
+	Email      string
+	MoveInDate time.Time // Boşsa bugün
+	InvitedBy  string
+}
+
+// CreatedInvitation oluşturulan davet ve bağlantısı. Bağlantı yalnızca bu yanıtta döner.
+type CreatedInvitation struct {
+	*models.Invitation
+	URL string `json:"invite_url"`
+}
+
+// AcceptInput giriş yapmamış kişinin daveti kabul ederken oluşturacağı hesap. Davette
+// telefon yoksa telefon zorunludur; ad ve soyad boşsa davettekiler kullanılır.
+type AcceptInput struct {
+	Token     string
+	Phone     string
+	FirstName string
+	LastName  string
+	Password  string
+}
+
+// InvitationService sakin daveti, daireye kayıt ve taşınma
+type InvitationService struct {
+	auth   *AuthService
+	store  InvitationStore
+	sms    *sms.Service
+	config InvitationConfig
+	now    func() time.Time
+}
+
+// NewInvitationService yeni servis oluşturur
+func NewInvitationService(auth *AuthService, store InvitationStore, smsService *sms.Service, config InvitationConfig) *InvitationService {
+	return &InvitationService{
+		auth:   auth,
+		store:  store,
+		sms:    smsService,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// CreateInvitation daireye sakin daveti oluşturur. Telefon verilmişse bağlantı SMS ile
+// gönderilir; gönderim başarısız olursa davet MANUAL olarak kalır ve bağlantıyı yönetici iletir.
+func (s *InvitationService) CreateInvitation(ctx context.Context, in *InvitationInput) (*CreatedInvitation, error) {
+	if in.Role != "OWNER" && in.Role != "TENANT" && in.Role != "PROXY" {
+		return nil, errors.New("rol OWNER, TENANT veya PROXY olmalı")
+	}
+	if strings.TrimSpace(in.FirstName) == "" || strings.TrimSpace(in.LastName) == "" {
+		return nil, errors.New("ad ve soyad gerekli")
+	}
+	var phone string
+	if in.Phone != "" {
+		normalized, ok := NormalizePhone(in.Phone)
+		if !ok {
+			return nil, ErrInvalidPhone
+		}
+		phone = normalized
+	}
+	email := strings.TrimSpace(in.Email)
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return nil, errors.New("geçersiz e-posta adresi")
+		}
+	}
+	if phone == "" && email == "" {
+		return nil, errors.New("telefon veya e-posta gerekli")
+	}
+
+	unit, err := s.store.GetUnit(ctx, in.PropertyID, in.UnitID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	moveIn := in.MoveInDate
+	if moveIn.IsZero() {
+		moveIn = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	inv := &models.Invitation{
+		PropertyID:   in.PropertyID,
+		PropertyName: unit.PropertyName,
+		UnitID:       in.UnitID,
+		UnitName:     unit.UnitName,
+		Role:         in.Role,
+		FirstName:    strings.TrimSpace(in.FirstName),
+		LastName:     strings.TrimSpace(in.LastName),
+		Phone:        phone,
+		Email:        email,
+		MoveInDate:   moveIn,
+		TokenHash:    hashToken(token),
+		Delivery:     DeliveryManual,
+		InvitedBy:    in.InvitedBy,
+		ExpiresAt:    now.Add(s.config.TTL),
+		Status:       InvitationPending,
+		CreatedAt:    now,
+	}
+	if phone != "" {
+		inv.Delivery = DeliverySMS
+	}
+	if err := s.store.CreateInvitation(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	url := s.config.BaseURL + "/" + token
+	if inv.Delivery == DeliverySMS {
+		resp, err := s.sms.Send(ctx, &sms.SendRequest{To: phone, Message: invitationMessage(inv, url), PropertyID: inv.PropertyID})
+		if err != nil || resp == nil || !resp.Success {
+			log.Printf("Davet SMS gönderilemedi (%s): %v", maskPhone(phone), err)
+			// Bağlantı telefona ulaşmadı: kabulde telefon doğrulanmış sayılmaz
+			if err := s.store.SetInvitationDelivery(ctx, inv.ID, DeliveryManual); err != nil {
+				return nil, err
+			}
+			inv.Delivery = DeliveryManual
+		}
+	}
+	inv.Phone = maskPhone(inv.Phone)
+	return &CreatedInvitation{Invitation: inv, URL: url}, nil
+}
+
+// GetInvitation davet bağlantısı açıldığında gösterilecek bilgiler; yalnızca bekleyen
+// davetler döner
+func (s *InvitationService) GetInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	inv.Phone = maskPhone(inv.Phone)
+	inv.InvitedBy = ""
+	return inv, nil
+}
+
+// ListInvitations sitedeki davetler ve durumları. Telefonlar reveal verilmedikçe maskelenir.
+func (s *InvitationService) ListInvitations(ctx context.Context, propertyID string, reveal bool) ([]models.Invitation, error) {
+	invitations, err := s.store.ListInvitations(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for i := range invitations {
+		invitations[i].Status = invitationStatus(&invitations[i], now)
+		if !reveal {
+			invitations[i].Phone = maskPhone(invitations[i].Phone)
+		}
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation bekleyen daveti iptal eder
+func (s *InvitationService) RevokeInvitation(ctx context.Context, propertyID, id, revokedBy string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return repository.ErrInvitationNotFound
+	}
+	ok, err := s.store.RevokeInvitation(ctx, propertyID, id, revokedBy, s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return repository.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptAsNewUser daveti yeni hesap oluşturarak kabul eder ve oturum açar. Telefon
+// kayıtlıysa hesabın sahibi giriş yapıp AcceptAsUser ile kabul etmelidir.
+func (s *InvitationService) AcceptAsNewUser(ctx context.Context, in *AcceptInput, client ClientInfo) (*TokenPair, *models.UserResponse, error) {
+	inv, err := s.pendingInvitation(ctx, in.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	phone := inv.Phone
+	if phone == "" {
+		normalized, ok := NormalizePhone(in.Phone)
+		if !ok {
+			return nil, nil, ErrInvalidPhone
+		}
+		phone = normalized
+	}
+	if err := validatePassword(in.Password); err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.auth.userRepo.GetByPhone(ctx, phone); err == nil {
+		return nil, nil, ErrInvitationLoginRequired
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := &models.User{
+		FirstName:    firstNonEmpty(strings.TrimSpace(in.FirstName), inv.FirstName),
+		LastName:     firstNonEmpty(strings.TrimSpace(in.LastName), inv.LastName),
+		Phone:        phone,
+		Email:        inv.Email,
+		PasswordHash: string(hash),
+	}
+	// Bağlantı SMS ile bu numaraya gittiyse numaranın sahibi bağlantıyı açmıştır
+	verified := inv.Delivery == DeliverySMS && inv.Phone == phone
+	ru, err := s.store.AcceptInvitation(ctx, inv, user, verified, s.now())
+	if errors.Is(err, repository.ErrPhoneTaken) {
+		return nil, nil, ErrInvitationLoginRequired
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.notifyAccepted(ctx, inv, user, ru)
+	return s.auth.startSession(ctx, user, client, nil)
+}
+
+// AcceptAsUser daveti giriş yapmış kullanıcı adına kabul eder. Davet bir telefona
+// gönderilmişse kullanıcının telefonu aynı olmalıdır.
+func (s *InvitationService) AcceptAsUser(ctx context.Context, token, userID string) (*models.ResidentUnit, error) {
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.auth.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Phone != "" && inv.Phone != user.Phone {
+		return nil, ErrInvitationPhoneMismatch
+	}
+
+	ru, err := s.store.AcceptInvitation(ctx, inv, user, inv.Delivery == DeliverySMS, s.now())
+	if err != nil {
+		return nil, err
+	}
+	s.notifyAccepted(ctx, inv, user, ru)
+	ru.Phone = maskPhone(ru.Phone)
+	return ru, nil
+}
+
+// ListUnitResidents dairenin sakinleri; includeHistory ile taşınmış sakinler de döner.
+// Telefonlar reveal verilmedikçe maskelenir.
+func (s *InvitationService) ListUnitResidents(ctx context.Context, propertyID, unitID string, includeHistory, reveal bool) ([]models.ResidentUnit, error) {
+	if _, err := s.store.GetUnit(ctx, propertyID, unitID); err != nil {
+		return nil, err
+	}
+	residents, err := s.store.ListUnitResidents(ctx, propertyID, unitID, includeHistory)
+	if err != nil {
+		return nil, err
+	}
+	if !reveal {
+		for i := range residents {
+			residents[i].Phone = maskPhone(residents[i].Phone)
+		}
+	}
+	return residents, nil
+}
+
+// MoveOut sakinin daireden çıkışını kaydeder. İlişki pasifleşir ama silinmez; çıkış
+// tarihi boşsa bugün kabul edilir, taşınma tarihinden önce olamaz. Sakinin sitedeki
+// yetkileri bir sonraki token yenilemesinde düşer.
+func (s *InvitationService) MoveOut(ctx context.Context, propertyID, residentUnitID string, endDate time.Time, endedBy string) (*models.ResidentUnit, error) {
+	if _, err := uuid.Parse(residentUnitID); err != nil {
+		return nil, repository.ErrResidentUnitNotFound
+	}
+	ru, err := s.store.GetResidentUnit(ctx, propertyID, residentUnitID)
+	if err != nil {
+		return nil, err
+	}
+	if !ru.IsActive {
+		return nil, ErrAlreadyMovedOut
+	}
+
+	now := s.now()
+	if endDate.IsZero() {
+		endDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if endDate.Before(ru.StartDate) {
+		return nil, errors.New("çıkış tarihi taşınma tarihinden önce olamaz")
+	}
+
+	ok, err := s.store.MoveOut(ctx, propertyID, residentUnitID, endDate, endedBy, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAlreadyMovedOut
+	}
+	ru.IsActive = false
+	ru.EndDate = &endDate
+	ru.Phone = maskPhone(ru.Phone)
+	return ru, nil
+}
+
+// pendingInvitation tokenı bekleyen bir davete çözer
+func (s *InvitationService) pendingInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, repository.ErrInvitationNotFound
+	}
+	inv, err := s.store.GetInvitationByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	inv.Status = invitationStatus(inv, s.now())
+	if inv.Status != InvitationPending {
+		return nil, repository.ErrInvitationUnavailable
+	}
+	return inv, nil
+}
+
+// notifyAccepted kayıt tamamlandığında sakine SMS gönderir; gönderim hatası kaydı etkilemez
+func (s *InvitationService) notifyAccepted(ctx context.Context, inv *models.Invitation, user *models.User, ru *models.ResidentUnit) {
+	message := fmt.Sprintf("SiteEksen: %s %s dairesine %s olarak kaydınız tamamlandı. Taşınma tarihi: %s.",
+		inv.PropertyName, inv.UnitName, roleLabel(ru.Role), ru.StartDate.Format("02.01.2006"))
+	resp, err := s.sms.Send(ctx, &sms.SendRequest{To: user.Phone, Message: message, PropertyID: inv.PropertyID})
+	if err != nil || resp == nil || !resp.Success {
+		log.Printf("Kayıt bildirimi gönderilemedi (%s): %v", maskPhone(user.Phone), err)
+	}
+}
+
+func invitationStatus(inv *models.Invitation, now time.Time) string {
+	switch {
+	case inv.AcceptedAt != nil:
+		return InvitationAccepted
+	case inv.RevokedAt != nil:
+		return InvitationRevoked
+	case !now.Before(inv.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+func invitationMessage(inv *models.Invitation, url string) string {
+	return fmt.Sprintf("SiteEksen: %s %s dairesine %s olarak davet edildiniz. Kaydınızı tamamlamak için: %s",
+		inv.PropertyName, inv.UnitName, roleLabel(inv.Role), url)
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "OWNER":
+		return "kat maliki"
+	case "TENANT":
+		return "kiracı"
+	case "PROXY":
+		return "vekil"
+	}
+	return role
+}
+
+// generateInvitationToken bağlantıda taşınan 256 bit rastgele token
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProperty = "11111111-1111-1111-1111-111111111111"
+	testUnit     = "33333333-3333-3333-3333-333333333303"
+)
+
+// memoryInvitations repository.InvitationRepository davranışının bellek içi karşılığı
+type memoryInvitations struct {
+	users       *memoryUsers
+	access      *memoryAccess
+	invitations []*models.Invitation
+	residents   []*models.ResidentUnit
+	verified    map[string]bool
+}
+
+func (m *memoryInvitations) GetUnit(ctx context.Context, propertyID, unitID string) (*models.UserProperty, error) {
+	if propertyID != testProperty || unitID != testUnit {
+		return nil, repository.ErrUnitNotFound
+	}
+	return &models.UserProperty{PropertyID: propertyID, PropertyName: "Güneş Sitesi", UnitID: unitID, UnitName: "A-3"}, nil
+}
+
+func (m *memoryInvitations) CreateInvitation(ctx context.Context, inv *models.Invitation) error {
+	inv.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.invitations)+1)
+	stored := *inv
+	m.invitations = append(m.invitations, &stored)
+	return nil
+}
+
+func (m *memoryInvitations) SetInvitationDelivery(ctx context.Context, id, delivery string) error {
+	for _, inv := range m.invitations {
+		if inv.ID == id {
+			inv.Delivery = delivery
+		}
+	}
+	return nil
+}
+
+func (m *memoryInvitations) GetInvitationByToken(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	for _, inv := range m.invitations {
+		if inv.TokenHash == tokenHash {
+			copied := *inv
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrInvitationNotFound
+}
+
+func (m *memoryInvitations) ListInvitations(ctx context.Context, propertyID string) ([]models.Invitation, error) {
+	var result []models.Invitation
+	for _, inv := range m.invitations {
+		if inv.PropertyID == propertyID {
+			result = append(result, *inv)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryInvitations) RevokeInvitation(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error) {
+	for _, inv := range m.invitations {
+		if inv.ID == id && inv.PropertyID == propertyID && inv.AcceptedAt == nil && inv.RevokedAt == nil {
+			inv.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryInvitations) AcceptInvitation(ctx context.Context, inv *models.Invitation, user *models.User, phoneVerified bool, now time.Time) (*models.ResidentUnit, error) {
+	var stored *models.Invitation
+	for _, i := range m.invitations {
+		if i.ID == inv.ID && i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt) {
+			stored = i
+		}
+	}
+	if stored == nil {
+		return nil, repository.ErrInvitationUnavailable
+	}
+	if user.ID == "" {
+		if _, err := m.users.GetByPhone(ctx, user.Phone); err == nil {
+			return nil, repository.ErrPhoneTaken
+		}
+		user.ID = fmt.Sprintf("u%d", len(m.users.users)+1)
+		user.ActivePropertyID = inv.PropertyID
+		m.users.users[user.ID] = user
+	} else if user.ActivePropertyID == "" {
+		user.ActivePropertyID = inv.PropertyID
+	}
+	if phoneVerified {
+		m.verified[user.ID] = true
+	}
+	for _, ru := range m.residents {
+		if ru.ResidentID == user.ID && ru.UnitID == inv.UnitID && ru.Role == inv.Role && ru.IsActive {
+			return nil, repository.ErrResidentExists
+		}
+	}
+
+	stored.AcceptedAt = &now
+	stored.AcceptedBy = user.ID
+	ru := &models.ResidentUnit{
+		ID:         fmt.Sprintf("10000000-0000-0000-0000-%012d", len(m.residents)+1),
+		ResidentID: user.ID,
+		Phone:      user.Phone,
+		UnitID:     inv.UnitID,
+		Role:       inv.Role,
+		StartDate:  inv.MoveInDate,
+		IsActive:   true,
+		CreatedAt:  now,
+	}
+	m.residents = append(m.residents, ru)
+	m.access.grant(user.ID, inv.PropertyID, inv.Role)
+	copied := *ru
+	return &copied, nil
+}
+
+func (m *memoryInvitations) ListUnitResidents(ctx context.Context, propertyID, unitID string, includeHistory bool) ([]models.ResidentUnit, error) {
+	var result []models.ResidentUnit
+	for _, ru := range m.residents {
+		if ru.UnitID == unitID && (includeHistory || ru.IsActive) {
+			result = append(result, *ru)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryInvitations) GetResidentUnit(ctx context.Context, propertyID, id string) (*models.ResidentUnit, error) {
+	for _, ru := range m.residents {
+		if ru.ID == id {
+			copied := *ru
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrResidentUnitNotFound
+}
+
+func (m *memoryInvitations) MoveOut(ctx context.Context, propertyID, id string, endDate time.Time, endedBy string, now time.Time) (bool, error) {
+	for _, ru := range m.residents {
+		if ru.ID == id && ru.IsActive {
+			ru.IsActive = false
+			ru.EndDate = &endDate
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type invitationFixture struct {
+	*otpFixture
+	invitations *InvitationService
+	store       *memoryInvitations
+}
+
+func newInvitationFixture(t *testing.T) *invitationFixture {
+	t.Helper()
+	f := newOTPFixture(t)
+	store := &memoryInvitations{users: f.users, access: f.access, verified: map[string]bool{}}
+	smsService := sms.NewService("fake")
+	smsService.RegisterProvider("fake", f.fake)
+
+	svc := NewInvitationService(f.svc.auth, store, smsService, DefaultInvitationConfig("https://app.test/davet/"))
+	svc.now = func() time.Time { return f.clock }
+	return &invitationFixture{otpFixture: f, invitations: svc, store: store}
+}
+
+var inviteLinkPattern = regexp.MustCompile(`https://app\.test/davet/(\S+)`)
+
+func (f *invitationFixture) invite(t *testing.T, phone, role string) *CreatedInvitation {
+	t.Helper()
+	inv, err := f.invitations.CreateInvitation(context.Background(), &InvitationInput{
+		PropertyID: testProperty,
+		UnitID:     testUnit,
+		Role:       role,
+		FirstName:  "Ayşe",
+		LastName:   "Kaya",
+		Phone:      phone,
+		MoveInDate: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		InvitedBy:  "manager",
+	})
+	require.NoError(t, err)
+	return inv
+}
+
+func tokenFromURL(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+func TestInvitationNewResident(t *testing.T) {
+	f := newInvitationFixture(t)
+	ctx := context.Background()
+
+	created := f.invite(t, "0532 111 22 33", "TENANT")
+	assert.Equal(t, DeliverySMS, created.Delivery)
+	assert.Equal(t, "https://app.test/davet/"+tokenFromURL(created.URL), created.URL)
+
+	msg, ok := f.fake.LastMessage("+905321112233")
+	require.True(t, ok)
+	assert.Contains(t, msg.Message, "A-3")
+	link := inviteLinkPattern.FindStringSubmatch(msg.Message)
+	require.Len(t, link, 2)
+	token := link[1]
+
+	preview, err := f.invitations.GetInvitation(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "Güneş Sitesi", preview.PropertyName)
+	assert.NotContains(t, preview.Phone, "532111")
+
+	tokens, user, err := f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: token, Password: "Yeni123!"},
+		ClientInfo{UserAgent: "iPhone"})
+	require.NoError(t, err)
+	assert.Equal(t, "Ayşe", user.FirstName)
+	assert.True(t, f.store.verified[user.ID], "SMS ile gelen bağlantı telefonu doğrular")
+
+	c, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, testProperty, c.PropertyID)
+	assert.Equal(t, []string{"TENANT"}, c.Roles)
+
+	residents, err := f.invitations.ListUnitResidents(ctx, testProperty, testUnit, false, false)
+	require.NoError(t, err)
+	require.Len(t, residents, 1)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), residents[0].StartDate)
+	assert.Equal(t, "+90******2233", residents[0].Phone, "telefon varsayılan olarak maskeli")
+	residents, err = f.invitations.ListUnitResidents(ctx, testProperty, testUnit, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, "+905321112233", residents[0].Phone)
+
+	msg, _ = f.fake.LastMessage("+905321112233")
+	assert.Contains(t, msg.Message, "kaydınız tamamlandı")
+
+	// Bağlantı tek kullanımlık
+	_, _, err = f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: token, Password: "Yeni123!"}, ClientInfo{})
+	assert.ErrorIs(t, err, repository.ErrInvitationUnavailable)
+	_, err = f.invitations.GetInvitation(ctx, "yanlis")
+	assert.ErrorIs(t, err, repository.ErrInvitationNotFound)
+}
+
+func TestInvitationExistingUser(t *testing.T) {
+	f := newInvitationFixture(t)
+	ctx := context.Background()
+	f.users.users["u9"] = &models.User{ID: "u9", Phone: "+905320000000"}
+
+	created := f.invite(t, testPhone, "OWNER")
+	token := tokenFromURL(created.URL)
+
+	_, _, err := f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: token, Password: "Yeni123!"}, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvitationLoginRequired)
+
+	_, err = f.invitations.AcceptAsUser(ctx, token, "u9")
+	assert.ErrorIs(t, err, ErrInvitationPhoneMismatch)
+
+	ru, err := f.invitations.AcceptAsUser(ctx, token, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "OWNER", ru.Role)
+	assert.Equal(t, testProperty, f.users.users["u1"].ActivePropertyID)
+}
+
+func TestInvitationExpiryAndRevoke(t *testing.T) {
+	f := newInvitationFixture(t)
+	ctx := context.Background()
+
+	expiring := f.invite(t, "05321112233", "TENANT")
+	revoked := f.invite(t, "05321112244", "TENANT")
+	require.NoError(t, f.invitations.RevokeInvitation(ctx, testProperty, revoked.ID, "manager"))
+	assert.ErrorIs(t, f.invitations.RevokeInvitation(ctx, testProperty, revoked.ID, "manager"), repository.ErrInvitationNotFound)
+
+	f.clock = f.clock.Add(8 * 24 * time.Hour)
+	_, _, err := f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: tokenFromURL(expiring.URL), Password: "Yeni123!"}, ClientInfo{})
+	assert.ErrorIs(t, err, repository.ErrInvitationUnavailable)
+	_, _, err = f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: tokenFromURL(revoked.URL), Password: "Yeni123!"}, ClientInfo{})
+	assert.ErrorIs(t, err, repository.ErrInvitationUnavailable)
+
+	list, err := f.invitations.ListInvitations(ctx, testProperty, false)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "+90******2233", list[0].Phone)
+	assert.Equal(t, InvitationExpired, list[0].Status)
+	assert.Equal(t, InvitationRevoked, list[1].Status)
+}
+
+func TestInvitationValidation(t *testing.T) {
+	f := newInvitationFixture(t)
+	ctx := context.Background()
+	base := InvitationInput{PropertyID: testProperty, UnitID: testUnit, Role: "TENANT", FirstName: "Ayşe", LastName: "Kaya"}
+
+	for name, mutate := range map[string]func(in *InvitationInput){
+		"iletişim yok": func(in *InvitationInput) {},
+		"rol":          func(in *InvitationInput) { in.Role = "MANAGER"; in.Phone = "05321112233" },
+		"telefon":      func(in *InvitationInput) { in.Phone = "12345" },
+		"e-posta":      func(in *InvitationInput) { in.Email = "ayse" },
+		"başka site":   func(in *InvitationInput) { in.PropertyID = "diger"; in.Phone = "05321112233" },
+	} {
+		in := base
+		mutate(&in)
+		_, err := f.invitations.CreateInvitation(ctx, &in)
+		assert.Error(t, err, name)
+	}
+
+	// E-posta daveti SMS gönderilmeden oluşur; bağlantıyı yönetici iletir
+	in := base
+	in.Email = "ayse@example.com"
+	created, err := f.invitations.CreateInvitation(ctx, &in)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryManual, created.Delivery)
+	assert.Empty(t, f.fake.Messages())
+
+	// Davette telefon yoksa kabul ederken telefon gerekir; telefon doğrulanmış sayılmaz
+	_, _, err = f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: tokenFromURL(created.URL), Password: "Yeni123!"}, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidPhone)
+	_, user, err := f.invitations.AcceptAsNewUser(ctx, &AcceptInput{Token: tokenFromURL(created.URL),
+		Phone: "0532 111 22 33", Password: "Yeni123!"}, ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "ayse@example.com", user.Email)
+	assert.False(t, f.store.verified[user.ID])
+}
+
+func TestInvitationSMSFailureFallsBackToManual(t *testing.T) {
+	f := newInvitationFixture(t)
+	f.fake.SetFailing(true)
+
+	created := f.invite(t, "05321112233", "TENANT")
+	assert.Equal(t, DeliveryManual, created.Delivery)
+	assert.Equal(t, DeliveryManual, f.store.invitations[0].Delivery)
+}
+
+func TestMoveOutKeepsHistory(t *testing.T) {
+	f := newInvitationFixture(t)
+	ctx := context.Background()
+
+	ru, err := f.invitations.AcceptAsUser(ctx, tokenFromURL(f.invite(t, testPhone, "OWNER").URL), "u1")
+	require.NoError(t, err)
+
+	_, err = f.invitations.MoveOut(ctx, testProperty, ru.ID, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), "manager")
+	assert.Error(t, err, "taşınmadan önceki tarih")
+
+	out := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	moved, err := f.invitations.MoveOut(ctx, testProperty, ru.ID, out, "manager")
+	require.NoError(t, err)
+	assert.False(t, moved.IsActive)
+	assert.Equal(t, out, *moved.EndDate)
+
+	_, err = f.invitations.MoveOut(ctx, testProperty, ru.ID, out, "manager")
+	assert.ErrorIs(t, err, ErrAlreadyMovedOut)
+
+	active, err := f.invitations.ListUnitResidents(ctx, testProperty, testUnit, false, false)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	history, err := f.invitations.ListUnitResidents(ctx, testProperty, testUnit, true, false)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	// Taşınan sakin aynı daireye yeniden davet edilebilir
+	_, err = f.invitations.AcceptAsUser(ctx, tokenFromURL(f.invite(t, testPhone, "OWNER").URL), "u1")
+	assert.NoError(t, err)
+}
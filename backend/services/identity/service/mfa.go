@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// MFAStore TOTP kayıtları, kurtarma kodları ve hatırlanan cihazlar (repository.MFARepository)
+type MFAStore interface {
+	GetMFA(ctx context.Context, userID string) (*models.MFA, error)
+	SaveMFASecret(ctx context.Context, userID, secret string, now time.Time) (bool, error)
+	EnableMFA(ctx context.Context, userID string, step int64, recoveryHashes []string, now time.Time) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string, now time.Time) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, codeHash string, now time.Time) (bool, error)
+	RegisterMFAFailure(ctx context.Context, userID string, maxAttempts int, lockedUntil time.Time) error
+	DisableMFA(ctx context.Context, userID string) error
+	MFARequired(ctx context.Context, userID string, platformRoles []string) (bool, error)
+	CreateTrustedDevice(ctx context.Context, d *models.TrustedDevice) error
+	UseTrustedDevice(ctx context.Context, userID, tokenHash string, now time.Time) (bool, error)
+	ListTrustedDevices(ctx context.Context, userID string, now time.Time) ([]models.TrustedDevice, error)
+	RevokeTrustedDevice(ctx context.Context, userID, id string, now time.Time) (bool, error)
+}
+
+var _ MFAStore = (*repository.MFARepository)(nil)
+
+var (
+	// ErrMFAInvalidCode doğrulama veya kurtarma kodu hatalı ya da daha önce kullanılmış
+	ErrMFAInvalidCode = errors.New("iki adımlı doğrulama kodu hatalı")
+	// ErrMFALocked art arda hatalı kodlar nedeniyle ikinci adım geçici olarak kilitli
+	ErrMFALocked = errors.New("çok fazla hatalı kod girildi, lütfen daha sonra tekrar deneyin")
+	// ErrMFANotEnabled kullanıcının etkin iki adımlı doğrulaması yok
+	ErrMFANotEnabled = errors.New("iki adımlı doğrulama etkin değil")
+	// ErrMFAAlreadyEnabled iki adımlı doğrulama zaten etkin; önce kapatılmalı
+	ErrMFAAlreadyEnabled = errors.New("iki adımlı doğrulama zaten etkin")
+	// ErrMFAEnrollmentNotStarted onay kodu geldi ama kurulum başlatılmamış
+	ErrMFAEnrollmentNotStarted = errors.New("önce iki adımlı doğrulama kurulumunu başlatın")
+	// ErrMFARequiredByPolicy rolü gereği iki adımlı doğrulama kapatılamaz
+	ErrMFARequiredByPolicy = errors.New("rolünüz gereği iki adımlı doğrulama kapatılamaz")
+	// ErrInvalidMFAToken giriş adımı tokenı geçersiz veya süresi dolmuş
+	ErrInvalidMFAToken = errors.New("giriş oturumu geçersiz veya süresi dolmuş, lütfen tekrar giriş yapın")
+	// ErrDeviceNotFound kullanıcının bu kimlikte hatırlanan cihazı yok
+	ErrDeviceNotFound = errors.New("cihaz bulunamadı")
+)
+
+// MFAChallenge ilk adımı geçen girişin ikinci adım bilgisi. Token yalnızca
+// /auth/mfa uçlarında geçerlidir.
+type MFAChallenge struct {
+	Token              string `json:"mfa_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // Rolü gereği zorunlu ama henüz kurulmamış
+	ExpiresIn          int64  `json:"expires_in"`          // Saniye
+}
+
+// MFARequiredError girişin tamamlanması için ikinci adım gerekir
+type MFARequiredError struct {
+	Challenge *MFAChallenge
+}
+
+func (e *MFARequiredError) Error() string {
+	return "iki adımlı doğrulama gerekli"
+}
+
+// MFAEnrollment kurulum bilgisi; URI doğrulayıcı uygulamaya QR kodu olarak gösterilir
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFALoginResult ikinci adımla tamamlanan giriş
+type MFALoginResult struct {
+	Tokens        *TokenPair
+	User          *models.UserResponse
+	DeviceToken   string   // Cihaz hatırlansın istendiyse; sonraki girişlerde X-Device-Token ile gönderilir
+	RecoveryCodes []string // Kurulum girişle tamamlandıysa bir kez gösterilir
+}
+
+// MFAConfig iki adımlı doğrulama ayarları. Site rolleri için zorunluluk site
+// yönetiminin mfa_required_roles ayarındadır; platform rolleri burada verilir.
+type MFAConfig struct {
+	PlatformRoles     []string      // Her zaman zorunlu olan platform rolleri
+	DeviceTTL         time.Duration // Hatırlanan cihazın geçerlilik süresi
+	RecoveryCodeCount int           // Üretilen kurtarma kodu sayısı
+	MaxFailures       int           // Kilit için art arda hatalı kod sayısı
+	LockoutDuration   time.Duration // Kilit süresi
+}
+
+// DefaultMFAConfig varsayılanlar: cihaz 30 gün hatırlanır, 10 kurtarma kodu, art arda
+// 5 hatalı kodda 15 dakika kilit
+func DefaultMFAConfig(platformRoles []string) MFAConfig {
+	return MFAConfig{
+		PlatformRoles:     platformRoles,
+		DeviceTTL:         30 * 24 * time.Hour,
+		RecoveryCodeCount: 10,
+		MaxFailures:       5,
+		LockoutDuration:   15 * time.Minute,
+	}
+}
+
+// MFAService TOTP (RFC 6238) ile iki adımlı doğrulama: kurulum, girişte ikinci adım,
+// kurtarma kodları, hatırlanan cihazlar ve hassas işlemler için yeniden doğrulama
+type MFAService struct {
+	auth   *AuthService
+	store  MFAStore
+	config MFAConfig
+	now    func() time.Time
+}
+
+// NewMFAService yeni servis oluşturur ve kimlik doğrulama servisinin girişlerine bağlar
+func NewMFAService(auth *AuthService, store MFAStore, config MFAConfig) *MFAService {
+	s := &MFAService{
+		auth:   auth,
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+	auth.mfa = s
+	return s
+}
+
+// challenge ilk adımı geçen kullanıcı için ikinci adım gerekiyorsa giriş tokenı üretir.
+// Etkin doğrulaması olan kullanıcı hatırlanan cihazdan geliyorsa ikinci adım atlanır;
+// doğrulaması olmayan ama rolü gereği zorunlu olan kullanıcı kuruluma yönlendirilir.
+func (s *MFAService) challenge(ctx context.Context, user *models.User, client ClientInfo) (*MFAChallenge, error) {
+	now := s.now()
+	enabled, err := s.enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		if client.DeviceToken != "" {
+			trusted, err := s.store.UseTrustedDevice(ctx, user.ID, hashToken(client.DeviceToken), now)
+			if err != nil {
+				return nil, err
+			}
+			if trusted {
+				return nil, nil
+			}
+		}
+		return s.newChallenge(user.ID, false, now)
+	}
+
+	required, err := s.store.MFARequired(ctx, user.ID, s.config.PlatformRoles)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return nil, nil
+	}
+	return s.newChallenge(user.ID, true, now)
+}
+
+func (s *MFAService) newChallenge(userID string, enrollment bool, now time.Time) (*MFAChallenge, error) {
+	token, err := s.auth.signer.Sign(claims.NewMFAClaims(userID, uuid.New().String(), now))
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{
+		Token:              token,
+		EnrollmentRequired: enrollment,
+		ExpiresIn:          int64(claims.MFATokenTTL.Seconds()),
+	}, nil
+}
+
+// challengeUser giriş tokenını doğrular ve kullanıcısını getirir
+func (s *MFAService) challengeUser(ctx context.Context, mfaToken string) (*models.User, error) {
+	tokenClaims, err := claims.ParseAt(mfaToken, s.auth.signer, claims.TokenMFA, s.now())
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	user, err := s.auth.userRepo.GetByID(ctx, tokenClaims.UserID())
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidMFAToken
+	}
+	return user, err
+}
+
+// VerifyLogin girişin ikinci adımını TOTP veya kurtarma koduyla tamamlar. remember
+// verilirse cihaz hatırlanır ve sonraki girişlerde ikinci adım istenmez.
+func (s *MFAService) VerifyLogin(ctx context.Context, mfaToken, code string, remember bool, client ClientInfo) (*MFALoginResult, error) {
+	user, err := s.challengeUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.enabledMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, m, code, true); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	result := &MFALoginResult{}
+	if remember {
+		if result.DeviceToken, err = s.rememberDevice(ctx, user.ID, client, now); err != nil {
+			return nil, err
+		}
+	}
+	if result.Tokens, result.User, err = s.auth.startSession(ctx, user, client, &now); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BeginEnrollment yeni TOTP anahtarı üretir. Anahtar onay koduyla doğrulanana kadar
+// etkin değildir; kurulum yeniden başlatılırsa önceki anahtar geçersizleşir.
+func (s *MFAService) BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	user, err := s.auth.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.store.SaveMFASecret(ctx, userID, secret, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return &MFAEnrollment{Secret: secret, URI: totpURI(secret, user.Phone)}, nil
+}
+
+// BeginEnrollmentLogin rolü gereği zorunlu olan kullanıcının kurulumunu giriş tokenıyla başlatır
+func (s *MFAService) BeginEnrollmentLogin(ctx context.Context, mfaToken string) (*MFAEnrollment, error) {
+	user, err := s.challengeUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginEnrollment(ctx, user.ID)
+}
+
+// ConfirmEnrollment doğrulayıcı uygulamadaki ilk kodla kurulumu tamamlar ve kurtarma
+// kodlarını döner. Kodlar yalnızca bu yanıtta düz metin olarak bulunur.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	m, err := s.store.GetMFA(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotFound) {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	now := s.now()
+	if m.LockedUntil != nil && now.Before(*m.LockedUntil) {
+		return nil, ErrMFALocked
+	}
+	step, ok := verifyTOTP(m.Secret, code, now)
+	if !ok {
+		return nil, s.registerFailure(ctx, userID, now)
+	}
+
+	codes, hashes, err := generateRecoveryCodes(s.config.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableMFA(ctx, userID, step, hashes, now); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteEnrollmentLogin kurulumu giriş tokenıyla tamamlar ve oturumu açar
+func (s *MFAService) CompleteEnrollmentLogin(ctx context.Context, mfaToken, code string, client ClientInfo) (*MFALoginResult, error) {
+	user, err := s.challengeUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.ConfirmEnrollment(ctx, user.ID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	result := &MFALoginResult{RecoveryCodes: codes}
+	if result.Tokens, result.User, err = s.auth.startSession(ctx, user, client, &now); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Disable geçerli kodla iki adımlı doğrulamayı kapatır; kurtarma kodları ve hatırlanan
+// cihazlar da silinir. Rolü gereği zorunlu olan kullanıcı kapatamaz.
+func (s *MFAService) Disable(ctx context.Context, userID, code string) error {
+	required, err := s.store.MFARequired(ctx, userID, s.config.PlatformRoles)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByPolicy
+	}
+	m, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(ctx, m, code, true); err != nil {
+		return err
+	}
+	return s.store.DisableMFA(ctx, userID)
+}
+
+// RegenerateRecoveryCodes geçerli TOTP koduyla yeni kurtarma kodları üretir; eskiler geçersizleşir
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	m, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, m, code, false); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes(s.config.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes, s.now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// StepUp hassas işlemler öncesi ikinci adımı yeniden doğrular ve oturum için son
+// doğrulama zamanını taşıyan yeni access token döner. Oturum kapatılmışsa ErrSessionRevoked
+// döner; yeni tokenın süresi oturumun süresini aşmaz.
+func (s *MFAService) StepUp(ctx context.Context, userID, sessionID, code string) (*TokenPair, error) {
+	now := s.now()
+	session, err := s.auth.activeSession(ctx, userID, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, m, code, true); err != nil {
+		return nil, err
+	}
+
+	user, err := s.auth.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.auth.access.ResolveAccess(ctx, userID, user.ActivePropertyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.auth.sessionAccessToken(userID, user.ActivePropertyID, access, session, &now, now)
+}
+
+// Status kullanıcının iki adımlı doğrulama durumu
+func (s *MFAService) Status(ctx context.Context, userID string) (*models.MFAStatus, error) {
+	status := &models.MFAStatus{}
+	required, err := s.store.MFARequired(ctx, userID, s.config.PlatformRoles)
+	if err != nil {
+		return nil, err
+	}
+	status.Required = required
+
+	m, err := s.store.GetMFA(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotFound) || (err == nil && m.EnabledAt == nil) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled = true
+	status.EnabledAt = m.EnabledAt
+	if status.RecoveryCodesRemaining, err = s.store.CountRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListDevices kullanıcının hatırlanan cihazları
+func (s *MFAService) ListDevices(ctx context.Context, userID string) ([]models.TrustedDevice, error) {
+	return s.store.ListTrustedDevices(ctx, userID, s.now())
+}
+
+// RevokeDevice hatırlanan cihazı iptal eder; cihazdan sonraki girişte ikinci adım istenir
+func (s *MFAService) RevokeDevice(ctx context.Context, userID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrDeviceNotFound
+	}
+	ok, err := s.store.RevokeTrustedDevice(ctx, userID, id, s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *MFAService) enabled(ctx context.Context, userID string) (bool, error) {
+	_, err := s.enabledMFA(ctx, userID)
+	if errors.Is(err, ErrMFANotEnabled) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// enabledMFA kullanıcının etkin TOTP kaydı; onay bekleyen kayıt etkin sayılmaz
+func (s *MFAService) enabledMFA(ctx context.Context, userID string) (*models.MFA, error) {
+	m, err := s.store.GetMFA(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotFound) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.EnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	return m, nil
+}
+
+// verifyCode TOTP kodunu (ve allowRecovery ise kurtarma kodunu) doğrular. Aynı zaman
+// adımındaki kod ikinci kez kabul edilmez; hatalı kodlar sayılır ve sınırda kayıt kilitlenir.
+func (s *MFAService) verifyCode(ctx context.Context, m *models.MFA, code string, allowRecovery bool) error {
+	now := s.now()
+	if m.LockedUntil != nil && now.Before(*m.LockedUntil) {
+		return ErrMFALocked
+	}
+
+	if step, ok := verifyTOTP(m.Secret, code, now); ok {
+		used, err := s.store.UseTOTPStep(ctx, m.UserID, step)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	} else if normalized := normalizeRecoveryCode(code); allowRecovery && len(normalized) == recoveryCodeLength {
+		used, err := s.store.UseRecoveryCode(ctx, m.UserID, hashToken(normalized), now)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+	return s.registerFailure(ctx, m.UserID, now)
+}
+
+func (s *MFAService) registerFailure(ctx context.Context, userID string, now time.Time) error {
+	if err := s.store.RegisterMFAFailure(ctx, userID, s.config.MaxFailures, now.Add(s.config.LockoutDuration)); err != nil {
+		return err
+	}
+	return ErrMFAInvalidCode
+}
+
+// rememberDevice cihaz için rastgele token üretir ve özetini saklar
+func (s *MFAService) rememberDevice(ctx context.Context, userID string, client ClientInfo, now time.Time) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	err := s.store.CreateTrustedDevice(ctx, &models.TrustedDevice{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		UserAgent: client.UserAgent,
+		IPAddress: client.IP,
+		ExpiresAt: now.Add(s.config.DeviceTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Kurtarma kodları "xxxxx-xxxxx" biçimindedir; karışabilecek karakterler (0/o, 1/l/i) kullanılmaz
+const (
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+)
+
+// generateRecoveryCodes n kurtarma kodu ve saklanacak özetlerini üretir
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for range n {
+		var b strings.Builder
+		for i := range recoveryCodeLength {
+			if i == recoveryCodeLength/2 {
+				b.WriteByte('-')
+			}
+			idx, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			b.WriteByte(recoveryCodeAlphabet[idx.Int64()])
+		}
+		code := b.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode kullanıcının girdiği kurtarma kodunu karşılaştırılabilir hale getirir
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
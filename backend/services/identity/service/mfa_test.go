@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryMFA repository.MFARepository davranışının bellek içi karşılığı
+type memoryMFA struct {
+	mu       sync.Mutex
+	records  map[string]*models.MFA
+	recovery map[string]map[string]bool // kullanıcı → kod özeti → kullanıldı
+	devices  []*models.TrustedDevice
+	revoked  map[string]bool
+	required map[string]bool
+}
+
+func newMemoryMFA() *memoryMFA {
+	return &memoryMFA{
+		records:  map[string]*models.MFA{},
+		recovery: map[string]map[string]bool{},
+		revoked:  map[string]bool{},
+		required: map[string]bool{},
+	}
+}
+
+func (m *memoryMFA) GetMFA(ctx context.Context, userID string) (*models.MFA, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.records[userID]
+	if !ok {
+		return nil, repository.ErrMFANotFound
+	}
+	c := *r
+	return &c, nil
+}
+
+func (m *memoryMFA) SaveMFASecret(ctx context.Context, userID, secret string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[userID]; ok && r.EnabledAt != nil {
+		return false, nil
+	}
+	m.records[userID] = &models.MFA{UserID: userID, Secret: secret, CreatedAt: now}
+	return true, nil
+}
+
+func (m *memoryMFA) EnableMFA(ctx context.Context, userID string, step int64, hashes []string, now time.Time) error {
+	m.mu.Lock()
+	r, ok := m.records[userID]
+	if !ok || r.EnabledAt != nil {
+		m.mu.Unlock()
+		return repository.ErrMFANotFound
+	}
+	r.EnabledAt = &now
+	r.LastUsedStep = step
+	r.FailedAttempts, r.LockedUntil = 0, nil
+	m.mu.Unlock()
+	return m.ReplaceRecoveryCodes(ctx, userID, hashes, now)
+}
+
+func (m *memoryMFA) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recovery[userID] = map[string]bool{}
+	for _, h := range hashes {
+		m.recovery[userID][h] = false
+	}
+	return nil
+}
+
+func (m *memoryMFA) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, used := range m.recovery[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryMFA) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.records[userID]
+	if r == nil || r.LastUsedStep >= step {
+		return false, nil
+	}
+	r.LastUsedStep = step
+	r.FailedAttempts, r.LockedUntil = 0, nil
+	return true, nil
+}
+
+func (m *memoryMFA) UseRecoveryCode(ctx context.Context, userID, codeHash string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	used, ok := m.recovery[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recovery[userID][codeHash] = true
+	m.records[userID].FailedAttempts, m.records[userID].LockedUntil = 0, nil
+	return true, nil
+}
+
+func (m *memoryMFA) RegisterMFAFailure(ctx context.Context, userID string, maxAttempts int, lockedUntil time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.records[userID]
+	if r == nil {
+		return nil
+	}
+	r.FailedAttempts++
+	if r.FailedAttempts >= maxAttempts {
+		r.FailedAttempts = 0
+		r.LockedUntil = &lockedUntil
+	}
+	return nil
+}
+
+func (m *memoryMFA) DisableMFA(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, userID)
+	delete(m.recovery, userID)
+	for _, d := range m.devices {
+		if d.UserID == userID {
+			m.revoked[d.ID] = true
+		}
+	}
+	return nil
+}
+
+func (m *memoryMFA) MFARequired(ctx context.Context, userID string, platformRoles []string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.required[userID], nil
+}
+
+func (m *memoryMFA) CreateTrustedDevice(ctx context.Context, d *models.TrustedDevice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d.ID = uuid.New().String()
+	c := *d
+	m.devices = append(m.devices, &c)
+	return nil
+}
+
+func (m *memoryMFA) UseTrustedDevice(ctx context.Context, userID, tokenHash string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.devices {
+		if d.UserID == userID && d.TokenHash == tokenHash && !m.revoked[d.ID] && d.ExpiresAt.After(now) {
+			d.LastUsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryMFA) ListTrustedDevices(ctx context.Context, userID string, now time.Time) ([]models.TrustedDevice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	devices := []models.TrustedDevice{}
+	for _, d := range m.devices {
+		if d.UserID == userID && !m.revoked[d.ID] && d.ExpiresAt.After(now) {
+			devices = append(devices, *d)
+		}
+	}
+	return devices, nil
+}
+
+func (m *memoryMFA) RevokeTrustedDevice(ctx context.Context, userID, id string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.devices {
+		if d.ID == id && d.UserID == userID && !m.revoked[id] {
+			m.revoked[id] = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type mfaFixture struct {
+	*otpFixture
+	mfa   *MFAService
+	store *memoryMFA
+}
+
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+	f := &mfaFixture{otpFixture: newOTPFixture(t), store: newMemoryMFA()}
+	f.mfa = NewMFAService(f.svc.auth, f.store, DefaultMFAConfig([]string{"ADMIN"}))
+	f.mfa.now = func() time.Time { return f.clock }
+	return f
+}
+
+// code kullanıcının anahtarıyla fixture saatindeki TOTP kodu
+func (f *mfaFixture) code(t *testing.T, userID string) string {
+	t.Helper()
+	code, err := totpCode(f.store.records[userID].Secret, totpStep(f.clock))
+	require.NoError(t, err)
+	return code
+}
+
+// enroll kurulumu tamamlar ve kurtarma kodlarını döner
+func (f *mfaFixture) enroll(t *testing.T, userID string) []string {
+	t.Helper()
+	_, err := f.mfa.BeginEnrollment(context.Background(), userID)
+	require.NoError(t, err)
+	codes, err := f.mfa.ConfirmEnrollment(context.Background(), userID, f.code(t, userID))
+	require.NoError(t, err)
+	f.clock = f.clock.Add(totpPeriod) // Onay kodu kullanıldı; sonraki kod yeni adımdan
+	return codes
+}
+
+func (f *mfaFixture) challenge(t *testing.T, client ClientInfo) *MFAChallenge {
+	t.Helper()
+	tokens, _, err := f.svc.auth.Login(context.Background(), testPhone, "Eski123!", client)
+	var mfaErr *MFARequiredError
+	require.True(t, errors.As(err, &mfaErr), "ikinci adım istenmedi: %v", err)
+	assert.Nil(t, tokens)
+	return mfaErr.Challenge
+}
+
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := totpCode(secret, totpStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "T=%d", unix)
+	}
+
+	// Bir adım saat kayması kabul edilir, iki adım edilmez
+	now := time.Unix(1234567890, 0)
+	step, ok := verifyTOTP(secret, "005924", now.Add(totpPeriod))
+	assert.True(t, ok)
+	assert.Equal(t, totpStep(now), step)
+	_, ok = verifyTOTP(secret, "005924", now.Add(2*totpPeriod))
+	assert.False(t, ok)
+	_, ok = verifyTOTP(secret, "12345", now)
+	assert.False(t, ok)
+
+	uri := totpURI(secret, "+905551234567")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/SiteEksen:+905551234567?"), uri)
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=SiteEksen")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
+
+func TestMFAEnrollmentAndLogin(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+
+	enrollment, err := f.mfa.BeginEnrollment(ctx, "u1")
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	// Onaylanmamış kurulum girişi etkilemez
+	loginForTest(t, f.otpFixture, ClientInfo{})
+
+	_, err = f.mfa.ConfirmEnrollment(ctx, "u1", "000000")
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	confirmCode := f.code(t, "u1")
+	codes, err := f.mfa.ConfirmEnrollment(ctx, "u1", confirmCode)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	_, err = f.mfa.BeginEnrollment(ctx, "u1")
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	challenge := f.challenge(t, ClientInfo{})
+	assert.False(t, challenge.EnrollmentRequired)
+	assert.Equal(t, int64(300), challenge.ExpiresIn)
+
+	// Onayda kullanılan kod tekrar kabul edilmez
+	_, err = f.mfa.VerifyLogin(ctx, challenge.Token, confirmCode, false, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+
+	f.clock = f.clock.Add(totpPeriod)
+	result, err := f.mfa.VerifyLogin(ctx, challenge.Token, f.code(t, "u1"), false, ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "u1", result.User.ID)
+	assert.Empty(t, result.DeviceToken)
+	access, err := claims.ParseAccess(result.Tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	require.NotNil(t, access.MFAAt)
+	assert.True(t, access.StepUpFresh(f.clock))
+
+	// Kurtarma kodu bir kez kullanılır
+	_, err = f.mfa.VerifyLogin(ctx, challenge.Token, strings.ToUpper(codes[0]), false, ClientInfo{})
+	require.NoError(t, err)
+	_, err = f.mfa.VerifyLogin(ctx, challenge.Token, codes[0], false, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	status, err := f.mfa.Status(ctx, "u1")
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 9, status.RecoveryCodesRemaining)
+
+	// Access token giriş adımı yerine kullanılamaz
+	_, err = f.mfa.VerifyLogin(ctx, result.Tokens.AccessToken, f.code(t, "u1"), false, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMFAToken)
+
+	// SMS koduyla girişte de ikinci adım istenir
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	_, _, err = f.svc.LoginWithOTP(ctx, testPhone, f.lastCode(t), ClientInfo{})
+	var mfaErr *MFARequiredError
+	assert.True(t, errors.As(err, &mfaErr))
+
+	// Kapatıldıktan sonra yalnızca şifre yeterli
+	f.clock = f.clock.Add(totpPeriod)
+	require.NoError(t, f.mfa.Disable(ctx, "u1", f.code(t, "u1")))
+	loginForTest(t, f.otpFixture, ClientInfo{})
+}
+
+func TestMFARequiredByRole(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+	f.store.required["u1"] = true
+
+	challenge := f.challenge(t, ClientInfo{})
+	assert.True(t, challenge.EnrollmentRequired)
+	_, err := f.mfa.VerifyLogin(ctx, challenge.Token, "123456", false, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFANotEnabled)
+
+	enrollment, err := f.mfa.BeginEnrollmentLogin(ctx, challenge.Token)
+	require.NoError(t, err)
+	assert.NotEmpty(t, enrollment.Secret)
+	result, err := f.mfa.CompleteEnrollmentLogin(ctx, challenge.Token, f.code(t, "u1"), ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Tokens.RefreshToken)
+	assert.Len(t, result.RecoveryCodes, 10)
+
+	f.clock = f.clock.Add(totpPeriod)
+	assert.ErrorIs(t, f.mfa.Disable(ctx, "u1", f.code(t, "u1")), ErrMFARequiredByPolicy)
+	status, err := f.mfa.Status(ctx, "u1")
+	require.NoError(t, err)
+	assert.True(t, status.Required)
+	assert.True(t, status.Enabled)
+}
+
+func TestMFALockout(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+	f.enroll(t, "u1")
+	challenge := f.challenge(t, ClientInfo{})
+
+	for i := 0; i < 5; i++ {
+		_, err := f.mfa.VerifyLogin(ctx, challenge.Token, "000000", false, ClientInfo{})
+		assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	}
+	_, err := f.mfa.VerifyLogin(ctx, challenge.Token, f.code(t, "u1"), false, ClientInfo{})
+	assert.ErrorIs(t, err, ErrMFALocked)
+
+	f.clock = f.clock.Add(15 * time.Minute)
+	challenge = f.challenge(t, ClientInfo{}) // Giriş tokenı 5 dakikada dolar
+	_, err = f.mfa.VerifyLogin(ctx, challenge.Token, f.code(t, "u1"), false, ClientInfo{})
+	assert.NoError(t, err)
+}
+
+func TestMFARememberedDevice(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+	f.enroll(t, "u1")
+
+	client := ClientInfo{UserAgent: "iPhone", IP: "10.0.0.1"}
+	challenge := f.challenge(t, client)
+	result, err := f.mfa.VerifyLogin(ctx, challenge.Token, f.code(t, "u1"), true, client)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.DeviceToken)
+
+	// Hatırlanan cihazdan ikinci adım istenmez ama hassas işlemler için doğrulama taşınmaz
+	client.DeviceToken = result.DeviceToken
+	tokens := loginForTest(t, f.otpFixture, client)
+	access, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Nil(t, access.MFAAt)
+
+	// Başka kullanıcının cihaz tokenı veya yanlış token işe yaramaz
+	f.challenge(t, ClientInfo{DeviceToken: "baska-cihaz"})
+
+	devices, err := f.mfa.ListDevices(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "iPhone", devices[0].UserAgent)
+	assert.ErrorIs(t, f.mfa.RevokeDevice(ctx, "u1", "yok"), ErrDeviceNotFound)
+	require.NoError(t, f.mfa.RevokeDevice(ctx, "u1", devices[0].ID))
+	f.challenge(t, client)
+
+	// Süresi dolan cihaz hatırlanmaz
+	f.clock = f.clock.Add(totpPeriod)
+	challenge = f.challenge(t, ClientInfo{})
+	result, err = f.mfa.VerifyLogin(ctx, challenge.Token, f.code(t, "u1"), true, ClientInfo{})
+	require.NoError(t, err)
+	f.clock = f.clock.Add(31 * 24 * time.Hour)
+	f.challenge(t, ClientInfo{DeviceToken: result.DeviceToken})
+}
+
+func TestMFAStepUp(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+	f.users.users["u1"].ActivePropertyID = "p1"
+	f.access.grant("u1", "p1", "MANAGER")
+	tokens := loginForTest(t, f.otpFixture, ClientInfo{})
+	session, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	s1 := session.SessionID
+
+	_, err = f.mfa.StepUp(ctx, "u1", s1, "123456")
+	assert.ErrorIs(t, err, ErrMFANotEnabled)
+
+	codes := f.enroll(t, "u1")
+	_, err = f.mfa.StepUp(ctx, "u1", s1, "000000")
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+
+	tokens, err = f.mfa.StepUp(ctx, "u1", s1, f.code(t, "u1"))
+	require.NoError(t, err)
+	// Token süresi servisin saatine göre hesaplanır
+	access, err := claims.ParseAt(tokens.AccessToken, f.svc.auth.Keys(), claims.TokenAccess, f.clock)
+	require.NoError(t, err)
+	assert.Equal(t, f.clock.Add(claims.AccessTokenTTL).Unix(), access.ExpiresAt.Unix())
+	assert.Equal(t, "p1", access.PropertyID)
+	assert.Equal(t, s1, access.SessionID)
+	assert.Contains(t, access.Permissions, "identity.role.manage")
+	assert.True(t, access.StepUpFresh(f.clock))
+	assert.False(t, access.StepUpFresh(f.clock.Add(claims.StepUpMaxAge+time.Second)))
+
+	// Yeni kurtarma kodları yalnızca TOTP koduyla üretilir; eskiler geçersizleşir
+	_, err = f.mfa.RegenerateRecoveryCodes(ctx, "u1", codes[0])
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	f.clock = f.clock.Add(totpPeriod)
+	fresh, err := f.mfa.RegenerateRecoveryCodes(ctx, "u1", f.code(t, "u1"))
+	require.NoError(t, err)
+	assert.Len(t, fresh, 10)
+	_, err = f.mfa.StepUp(ctx, "u1", s1, codes[1])
+	assert.ErrorIs(t, err, ErrMFAInvalidCode)
+	_, err = f.mfa.StepUp(ctx, "u1", s1, fresh[0])
+	assert.NoError(t, err)
+
+	// Kapatılmış oturum ikinci adımla yeni token alamaz
+	require.NoError(t, f.svc.auth.RevokeSession(ctx, "u1", s1))
+	f.clock = f.clock.Add(totpPeriod)
+	_, err = f.mfa.StepUp(ctx, "u1", s1, f.code(t, "u1"))
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrSignupInvalid kayıt bilgileri eksik veya hatalı
+	ErrSignupInvalid = errors.New("kayıt bilgileri geçersiz")
+	// ErrSignupPhoneTaken yönetici telefonuna kayıtlı hesap var; yeni site giriş yapılarak eklenmeli
+	ErrSignupPhoneTaken = errors.New("bu telefon numarasına kayıtlı bir hesap var")
+)
+
+// SignupAdminRole kayıt olan kişiye yeni sitede verilen rol
+const SignupAdminRole = "MANAGER"
+
+// DefaultExpenseCategories yeni siteye açılan gider kalemleri (sıra sort_order olur)
+var DefaultExpenseCategories = []models.ExpenseCategorySeed{
+	{Name: "Genel Yönetim", DistributionType: "SHARE_RATIO", AppliesToGroundFloor: true},
+	{Name: "Asansör Bakım", DistributionType: "EQUAL", AppliesToGroundFloor: false},
+	{Name: "Temizlik Personeli", DistributionType: "EQUAL", AppliesToGroundFloor: true},
+	{Name: "Bahçe Bakım", DistributionType: "AREA_M2", AppliesToGroundFloor: true},
+	{Name: "Ortak Elektrik", DistributionType: "EQUAL", AppliesToGroundFloor: true},
+	{Name: "Isınma", DistributionType: "METER_READING", AppliesToGroundFloor: true},
+}
+
+// DefaultRequestCategories yeni siteye açılan talep kategorileri
+var DefaultRequestCategories = []models.RequestCategorySeed{
+	{Name: "Asansör", Icon: "elevator", SLAHours: 4},
+	{Name: "Temizlik", Icon: "cleaning", SLAHours: 24},
+	{Name: "Güvenlik", Icon: "security", SLAHours: 2},
+	{Name: "Bahçe", Icon: "garden", SLAHours: 48},
+	{Name: "Diğer", Icon: "other", SLAHours: 72},
+}
+
+// OnboardingStore kurulum kayıtlarını tek transaction'da yazan yer (repository.OnboardingRepository)
+type OnboardingStore interface {
+	Bootstrap(ctx context.Context, site *models.SiteBootstrap) error
+}
+
+var _ OnboardingStore = (*repository.OnboardingRepository)(nil)
+
+// UnitRow daire listesinin bir satırı. Row hata mesajlarında kullanılır: Excel'de sayfadaki
+// satır numarası, JSON listesinde 1'den başlayan sıra.
+type UnitRow struct {
+	Row int
+	models.SiteUnit
+}
+
+// RowError daire listesindeki hatalı satır
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// UnitListError daire listesindeki hatalı satırların tamamı; ErrSignupInvalid ile eşleşir
+type UnitListError struct {
+	Rows []RowError
+}
+
+func (e *UnitListError) Error() string {
+	return fmt.Sprintf("%s: %d daire satırı hatalı", ErrSignupInvalid, len(e.Rows))
+}
+
+func (e *UnitListError) Unwrap() error {
+	return ErrSignupInvalid
+}
+
+func (e *UnitListError) add(row int, format string, args ...any) {
+	e.Rows = append(e.Rows, RowError{Row: row, Message: fmt.Sprintf(format, args...)})
+}
+
+// SignupInput yeni müşterinin kaydı: kiracı, ilk site, daireleri ve site yöneticisi
+type SignupInput struct {
+	OrganizationName string // Kiracı adı; boşsa site adı
+	Slug             string
+	Plan             string // Boşsa starter
+	Property         models.SiteProperty
+	Units            []UnitRow
+	FirstName        string
+	LastName         string
+	Phone            string
+	Email            string
+	Password         string
+}
+
+// SignupResult açılan kiracı ve site
+type SignupResult struct {
+	Tenant    *tenant.Tenant       `json:"tenant"`
+	Property  models.SiteProperty  `json:"property"`
+	Blocks    []models.SiteBlock   `json:"blocks"`
+	UnitCount int                  `json:"unit_count"`
+	User      *models.UserResponse `json:"user"`
+}
+
+// OnboardingService self-servis kayıt: kiracıyı deneme sürümünde açar, ilk siteyi blok ve
+// daireleriyle kurar, varsayılan kategorileri ve hesap planını ekler, yöneticiyi oluşturur
+type OnboardingService struct {
+	auth    *AuthService
+	store   OnboardingStore
+	tenants *tenant.TenantManager
+}
+
+// NewOnboardingService yeni servis oluşturur
+func NewOnboardingService(auth *AuthService, store OnboardingStore, tenants *tenant.TenantManager) *OnboardingService {
+	return &OnboardingService{auth: auth, store: store, tenants: tenants}
+}
+
+// Signup kaydı doğrular, tüm kayıtları tek transaction'da açar ve yönetici için oturum
+// başlatır. Hata durumunda hiçbir kayıt kalmaz.
+func (s *OnboardingService) Signup(ctx context.Context, in *SignupInput, client ClientInfo) (*TokenPair, *SignupResult, error) {
+	property := models.SiteProperty{
+		Name:       strings.TrimSpace(in.Property.Name),
+		Address:    strings.TrimSpace(in.Property.Address),
+		City:       strings.TrimSpace(in.Property.City),
+		District:   strings.TrimSpace(in.Property.District),
+		PostalCode: strings.TrimSpace(in.Property.PostalCode),
+	}
+	if property.Name == "" || property.Address == "" || property.City == "" {
+		return nil, nil, fmt.Errorf("%w: site adı, adresi ve ili zorunlu", ErrSignupInvalid)
+	}
+	firstName, lastName := strings.TrimSpace(in.FirstName), strings.TrimSpace(in.LastName)
+	if firstName == "" || lastName == "" {
+		return nil, nil, fmt.Errorf("%w: yöneticinin adı ve soyadı zorunlu", ErrSignupInvalid)
+	}
+	phone, ok := NormalizePhone(in.Phone)
+	if !ok {
+		return nil, nil, ErrInvalidPhone
+	}
+	if err := validatePassword(in.Password); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSignupInvalid, err)
+	}
+	units, blocks, err := prepareUnits(in.Units)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t, trialEndsAt, err := s.tenants.NewTenant(tenant.CreateTenantInput{
+		Name: firstNonEmpty(strings.TrimSpace(in.OrganizationName), property.Name),
+		Slug: in.Slug,
+		Plan: in.Plan,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.MaxUnits != -1 && len(units) > t.MaxUnits {
+		return nil, nil, &tenant.LimitError{
+			Resource: tenant.ResourceUnits, Limit: t.MaxUnits, Current: len(units), Plan: t.SubscriptionPlan,
+		}
+	}
+
+	if _, err := s.auth.userRepo.GetByPhone(ctx, phone); err == nil {
+		return nil, nil, ErrSignupPhoneTaken
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	site := &models.SiteBootstrap{
+		Tenant:            t,
+		TrialEndsAt:       trialEndsAt,
+		Property:          property,
+		Blocks:            blocks,
+		Units:             units,
+		ExpenseCategories: DefaultExpenseCategories,
+		RequestCategories: DefaultRequestCategories,
+		Admin: &models.User{
+			FirstName:    firstName,
+			LastName:     lastName,
+			Phone:        phone,
+			Email:        strings.TrimSpace(in.Email),
+			PasswordHash: string(hash),
+		},
+		AdminRole: SignupAdminRole,
+	}
+	if err := s.store.Bootstrap(ctx, site); err != nil {
+		if errors.Is(err, repository.ErrPhoneTaken) {
+			return nil, nil, ErrSignupPhoneTaken
+		}
+		return nil, nil, err
+	}
+	s.tenants.Invalidate(t.ID)
+
+	tokens, user, err := s.auth.startSession(ctx, site.Admin, client, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, &SignupResult{
+		Tenant:    t,
+		Property:  site.Property,
+		Blocks:    site.Blocks,
+		UnitCount: len(site.Units),
+		User:      user,
+	}, nil
+}
+
+// Daire alanlarının sütun uzunlukları (units tablosu)
+const (
+	maxBlockName  = 50
+	maxDoorNumber = 20
+)
+
+// prepareUnits daireleri doğrular ve normalleştirir, blokları dairelerdeki sıralarıyla
+// çıkarır. Tüm hatalı satırlar tek UnitListError'da döner.
+func prepareUnits(rows []UnitRow) ([]models.SiteUnit, []models.SiteBlock, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: en az bir daire gerekli", ErrSignupInvalid)
+	}
+
+	listErr := &UnitListError{}
+	units := make([]models.SiteUnit, 0, len(rows))
+	seen := map[string]int{}
+	var blocks []models.SiteBlock
+	blockIndex := map[string]int{}
+	for _, r := range rows {
+		u := r.SiteUnit
+		u.Block = strings.TrimSpace(u.Block)
+		u.DoorNumber = strings.TrimSpace(u.DoorNumber)
+		u.UnitType = strings.ToUpper(strings.TrimSpace(u.UnitType))
+		if u.UnitType == "" {
+			u.UnitType = models.UnitApartment
+		}
+
+		valid := true
+		check := func(ok bool, format string, args ...any) {
+			if !ok {
+				listErr.add(r.Row, format, args...)
+				valid = false
+			}
+		}
+		check(u.DoorNumber != "", "kapı numarası zorunlu")
+		check(utf8.RuneCountInString(u.DoorNumber) <= maxDoorNumber, "kapı numarası en fazla %d karakter olabilir", maxDoorNumber)
+		check(utf8.RuneCountInString(u.Block) <= maxBlockName, "blok adı en fazla %d karakter olabilir", maxBlockName)
+		check(u.ShareRatio > 0, "arsa payı sıfırdan büyük olmalı")
+		check(u.GrossAreaM2 >= 0 && u.NetAreaM2 >= 0, "alan negatif olamaz")
+		switch u.UnitType {
+		case models.UnitApartment, models.UnitParking:
+		case models.UnitShop, models.UnitOffice:
+			u.IsCommercial = true
+		default:
+			check(false, "bilinmeyen bölüm tipi %q", r.UnitType)
+		}
+		if !valid {
+			continue
+		}
+
+		key := strings.ToLower(u.Block) + "/" + strings.ToLower(u.DoorNumber)
+		if first, ok := seen[key]; ok {
+			listErr.add(r.Row, "%s kapı numarası %d. satırda da var", unitLabel(u), first)
+			continue
+		}
+		seen[key] = r.Row
+
+		if u.Block != "" {
+			i, ok := blockIndex[u.Block]
+			if !ok {
+				i = len(blocks)
+				blockIndex[u.Block] = i
+				blocks = append(blocks, models.SiteBlock{Name: u.Block})
+			}
+			// Katlar zeminden (0) sayılır; bodrum katlar kat sayısına eklenmez
+			if u.Floor+1 > blocks[i].FloorCount {
+				blocks[i].FloorCount = u.Floor + 1
+			}
+		}
+		units = append(units, u)
+	}
+	if len(listErr.Rows) > 0 {
+		return nil, nil, listErr
+	}
+	return units, blocks, nil
+}
+
+func unitLabel(u models.SiteUnit) string {
+	if u.Block == "" {
+		return u.DoorNumber
+	}
+	return u.Block + "-" + u.DoorNumber
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// memoryOnboarding repository.OnboardingRepository davranışının bellek içi karşılığı;
+// hata verdiğinde hiçbir kayıt yazılmaz
+type memoryOnboarding struct {
+	users  *memoryUsers
+	access *memoryAccess
+	slugs  map[string]bool
+	sites  []*models.SiteBootstrap
+	fail   error
+}
+
+func (m *memoryOnboarding) Bootstrap(ctx context.Context, site *models.SiteBootstrap) error {
+	if m.fail != nil {
+		return m.fail
+	}
+	if m.slugs[site.Tenant.Slug] {
+		return tenant.ErrTenantExists
+	}
+	if _, err := m.users.GetByPhone(ctx, site.Admin.Phone); err == nil {
+		return repository.ErrPhoneTaken
+	}
+
+	n := len(m.sites) + 1
+	site.Tenant.ID = fmt.Sprintf("t%d", n)
+	site.Property.ID = fmt.Sprintf("p%d", n)
+	for i := range site.Blocks {
+		site.Blocks[i].ID = fmt.Sprintf("p%d-b%d", n, i+1)
+	}
+	for i := range site.Units {
+		site.Units[i].ID = fmt.Sprintf("p%d-u%d", n, i+1)
+	}
+	site.Admin.ID = fmt.Sprintf("u%d", len(m.users.users)+1)
+	site.Admin.ActivePropertyID = site.Property.ID
+	m.users.users[site.Admin.ID] = site.Admin
+	m.access.grant(site.Admin.ID, site.Property.ID, site.AdminRole)
+	m.slugs[site.Tenant.Slug] = true
+	m.sites = append(m.sites, site)
+	return nil
+}
+
+type onboardingFixture struct {
+	*otpFixture
+	onboarding *OnboardingService
+	store      *memoryOnboarding
+}
+
+func newOnboardingFixture(t *testing.T) *onboardingFixture {
+	t.Helper()
+	f := newOTPFixture(t)
+	store := &memoryOnboarding{users: f.users, access: f.access, slugs: map[string]bool{}}
+	svc := NewOnboardingService(f.svc.auth, store, tenant.NewManager(nil, tenant.DefaultManagerConfig()))
+	return &onboardingFixture{otpFixture: f, onboarding: svc, store: store}
+}
+
+func signupInput(units ...models.SiteUnit) *SignupInput {
+	in := &SignupInput{
+		Slug: "gunes-sitesi",
+		Property: models.SiteProperty{
+			Name:    "Güneş Sitesi",
+			Address: "Atatürk Cad. No: 5",
+			City:    "İstanbul",
+		},
+		FirstName: "Zeynep",
+		LastName:  "Arslan",
+		Phone:     "0532 444 55 66",
+		Password:  "Yonetici1!",
+	}
+	for i, u := range units {
+		in.Units = append(in.Units, UnitRow{Row: i + 1, SiteUnit: u})
+	}
+	return in
+}
+
+func TestSignup(t *testing.T) {
+	f := newOnboardingFixture(t)
+	ctx := context.Background()
+
+	tokens, result, err := f.onboarding.Signup(ctx, signupInput(
+		models.SiteUnit{Block: "A", Floor: 0, DoorNumber: "1", ShareRatio: 10},
+		models.SiteUnit{Block: "A", Floor: 2, DoorNumber: "5", ShareRatio: 12.5, GrossAreaM2: 120},
+		models.SiteUnit{Block: "B", Floor: 0, DoorNumber: "1", ShareRatio: 8, UnitType: "shop"},
+		models.SiteUnit{Floor: -1, DoorNumber: "D1", ShareRatio: 2, UnitType: models.UnitParking},
+	), ClientInfo{UserAgent: "web"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "t1", result.Tenant.ID)
+	assert.Equal(t, "Güneş Sitesi", result.Tenant.Name, "kiracı adı verilmezse site adı")
+	assert.Equal(t, tenant.StatusTrial, result.Tenant.SubscriptionStatus)
+	assert.Equal(t, "starter", result.Tenant.SubscriptionPlan)
+	assert.Equal(t, 4, result.UnitCount)
+	assert.Equal(t, []models.SiteBlock{
+		{ID: "p1-b1", Name: "A", FloorCount: 3},
+		{ID: "p1-b2", Name: "B", FloorCount: 1},
+	}, result.Blocks)
+
+	require.Len(t, f.store.sites, 1)
+	site := f.store.sites[0]
+	assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), site.TrialEndsAt, time.Minute)
+	assert.True(t, site.Units[2].IsCommercial, "dükkan ticari sayılır")
+	assert.Equal(t, models.UnitShop, site.Units[2].UnitType)
+	assert.Equal(t, models.UnitApartment, site.Units[0].UnitType)
+	assert.Len(t, site.ExpenseCategories, 6)
+	assert.Len(t, site.RequestCategories, 5)
+	assert.Equal(t, "+905324445566", site.Admin.Phone)
+	assert.NotEqual(t, "Yonetici1!", site.Admin.PasswordHash)
+
+	c, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, "p1", c.PropertyID)
+	assert.Equal(t, []string{"MANAGER"}, c.Roles)
+	assert.Equal(t, "Zeynep", result.User.FirstName)
+
+	// Aynı slug ikinci kez alınamaz
+	in := signupInput(models.SiteUnit{DoorNumber: "1", ShareRatio: 1})
+	in.Phone = "0532 999 88 77"
+	_, _, err = f.onboarding.Signup(ctx, in, ClientInfo{})
+	assert.ErrorIs(t, err, tenant.ErrTenantExists)
+}
+
+func TestSignupUnitErrors(t *testing.T) {
+	f := newOnboardingFixture(t)
+
+	_, _, err := f.onboarding.Signup(context.Background(), signupInput(
+		models.SiteUnit{Block: "A", DoorNumber: "1", ShareRatio: 10},
+		models.SiteUnit{Block: "A", DoorNumber: "", ShareRatio: 10},
+		models.SiteUnit{Block: "a", DoorNumber: "1", ShareRatio: 10},
+		models.SiteUnit{Block: "A", DoorNumber: "2", ShareRatio: 0, UnitType: "villa"},
+	), ClientInfo{})
+	require.ErrorIs(t, err, ErrSignupInvalid)
+	var listErr *UnitListError
+	require.ErrorAs(t, err, &listErr)
+	assert.Equal(t, []RowError{
+		{Row: 2, Message: "kapı numarası zorunlu"},
+		{Row: 3, Message: "a-1 kapı numarası 1. satırda da var"},
+		{Row: 4, Message: "arsa payı sıfırdan büyük olmalı"},
+		{Row: 4, Message: `bilinmeyen bölüm tipi "villa"`},
+	}, listErr.Rows)
+	assert.Empty(t, f.store.sites)
+
+	_, _, err = f.onboarding.Signup(context.Background(), signupInput(), ClientInfo{})
+	assert.ErrorIs(t, err, ErrSignupInvalid, "daire olmadan site açılmaz")
+}
+
+func TestSignupRejections(t *testing.T) {
+	f := newOnboardingFixture(t)
+	ctx := context.Background()
+	unit := models.SiteUnit{DoorNumber: "1", ShareRatio: 1}
+
+	in := signupInput(unit)
+	in.Phone = testPhone
+	_, _, err := f.onboarding.Signup(ctx, in, ClientInfo{})
+	assert.ErrorIs(t, err, ErrSignupPhoneTaken, "kayıtlı telefon giriş yapmalı")
+
+	in = signupInput(unit)
+	in.Slug = "admin"
+	_, _, err = f.onboarding.Signup(ctx, in, ClientInfo{})
+	assert.ErrorIs(t, err, tenant.ErrInvalidTenant)
+
+	in = signupInput(unit)
+	in.Password = "kisa"
+	_, _, err = f.onboarding.Signup(ctx, in, ClientInfo{})
+	assert.ErrorIs(t, err, ErrSignupInvalid)
+
+	var units []models.SiteUnit
+	for i := range 51 {
+		units = append(units, models.SiteUnit{DoorNumber: fmt.Sprint(i + 1), ShareRatio: 1})
+	}
+	_, _, err = f.onboarding.Signup(ctx, signupInput(units...), ClientInfo{})
+	var limitErr *tenant.LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, tenant.LimitError{Resource: tenant.ResourceUnits, Limit: 50, Current: 51, Plan: "starter"}, *limitErr)
+
+	in = signupInput(units...)
+	in.Plan = "pro"
+	f.store.fail = fmt.Errorf("daireler oluşturulamadı: bağlantı koptu")
+	_, _, err = f.onboarding.Signup(ctx, in, ClientInfo{})
+	assert.Error(t, err)
+	assert.Empty(t, f.store.sites)
+	assert.Empty(t, f.sessions.tokens, "kayıt yazılamazsa oturum açılmaz")
+	assert.Len(t, f.users.users, 1)
+}
+
+func unitSheet(t *testing.T, rows [][]any) *bytes.Reader {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParseUnitSheet(t *testing.T) {
+	rows, err := ParseUnitSheet(unitSheet(t, [][]any{
+		{},
+		{"BLOK", "Kat", "Kapı No.", "Arsa Payı", "Brüt m2", "Tip", "Ticari"},
+		{"A", "Zemin", "1", "12,5", "1.234,5", "Daire", ""},
+		{},
+		{"A", 3, 7, 10.25, 95, "DÜKKAN", "evet"},
+		{"", "-1", "D1", "2", "", "otopark", "hayır"},
+	}))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, UnitRow{Row: 3, SiteUnit: models.SiteUnit{
+		Block: "A", Floor: 0, DoorNumber: "1", ShareRatio: 12.5, GrossAreaM2: 1234.5, UnitType: models.UnitApartment,
+	}}, rows[0])
+	assert.Equal(t, UnitRow{Row: 5, SiteUnit: models.SiteUnit{
+		Block: "A", Floor: 3, DoorNumber: "7", ShareRatio: 10.25, GrossAreaM2: 95, UnitType: models.UnitShop, IsCommercial: true,
+	}}, rows[1])
+	assert.Equal(t, UnitRow{Row: 6, SiteUnit: models.SiteUnit{
+		Floor: -1, DoorNumber: "D1", ShareRatio: 2, UnitType: models.UnitParking,
+	}}, rows[2])
+
+	_, err = ParseUnitSheet(unitSheet(t, [][]any{
+		{"Blok", "Kat", "Kapı No", "Arsa Payı"},
+		{"A", "ikinci", "1", "10"},
+		{"A", "1", "2", "on"},
+	}))
+	var listErr *UnitListError
+	require.ErrorAs(t, err, &listErr)
+	assert.Equal(t, []RowError{
+		{Row: 2, Message: `kat sayı olmalı: "ikinci"`},
+		{Row: 3, Message: `arsa payı sayı olmalı: "on"`},
+	}, listErr.Rows)
+
+	_, err = ParseUnitSheet(unitSheet(t, [][]any{{"Blok", "Kapı No"}, {"A", "1"}}))
+	assert.ErrorIs(t, err, ErrSignupInvalid)
+	assert.Contains(t, err.Error(), "Kat, Arsa Payı")
+
+	_, err = ParseUnitSheet(bytes.NewReader([]byte("excel değil")))
+	assert.ErrorIs(t, err, ErrSignupInvalid)
+}
+
+func TestUnitSheetTemplateRoundTrip(t *testing.T) {
+	data, err := UnitSheetTemplate()
+	require.NoError(t, err)
+
+	rows, err := ParseUnitSheet(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	units, blocks, err := prepareUnits(rows)
+	require.NoError(t, err)
+	assert.Equal(t, models.SiteUnit{Block: "A", DoorNumber: "1", ShareRatio: 12.5, GrossAreaM2: 110, NetAreaM2: 95,
+		UnitType: models.UnitApartment}, units[0])
+	assert.Equal(t, []models.SiteBlock{{Name: "A", FloorCount: 1}}, blocks)
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// OTP amaçları
+const (
+	OTPPurposeLogin         = "LOGIN"
+	OTPPurposePasswordReset = "PASSWORD_RESET"
+)
+
+var (
+	// ErrInvalidPhone telefon numarası Türkiye cep telefonu biçiminde değil
+	ErrInvalidPhone = errors.New("geçersiz telefon numarası")
+	// ErrOTPInvalid kod hatalı, süresi dolmuş veya kullanılmış
+	ErrOTPInvalid = errors.New("doğrulama kodu geçersiz veya süresi dolmuş")
+	// ErrOTPRateLimited telefon veya IP için kod gönderim sınırı aşıldı
+	ErrOTPRateLimited = errors.New("çok fazla doğrulama kodu istendi, lütfen daha sonra tekrar deneyin")
+	// ErrOTPLocked telefon, art arda hatalı denemeler nedeniyle geçici olarak kilitli
+	ErrOTPLocked = errors.New("çok fazla hatalı deneme yapıldı, lütfen daha sonra tekrar deneyin")
+	// ErrOTPDelivery SMS sağlayıcısı kodu iletemedi
+	ErrOTPDelivery = errors.New("doğrulama kodu gönderilemedi")
+)
+
+// OTPStore doğrulama kodlarının ve hatalı denemelerin saklandığı yer (repository.OTPRepository)
+type OTPStore interface {
+	CreateOTP(ctx context.Context, c *models.OTPCode) error
+	GetActiveOTP(ctx context.Context, phone, purpose string, now time.Time) (*models.OTPCode, error)
+	LatestOTPAt(ctx context.Context, phone, purpose string) (time.Time, error)
+	RegisterOTPFailure(ctx context.Context, codeID, phone, ip string, maxAttempts int, now time.Time) error
+	ConsumeOTP(ctx context.Context, codeID string, now time.Time) (bool, error)
+	CountOTPRequests(ctx context.Context, phone, ip string, since time.Time) (int, int, error)
+	CountOTPFailures(ctx context.Context, phone string, since time.Time) (int, error)
+}
+
+var _ OTPStore = (*repository.OTPRepository)(nil)
+
+// OTPConfig kod uzunluğu, geçerlilik süresi ve kötüye kullanım sınırları
+type OTPConfig struct {
+	Secret         []byte        // Kod özetleri için HMAC anahtarı
+	CodeLength     int           // Kod hane sayısı
+	TTL            time.Duration // Kodun geçerlilik süresi
+	MaxAttempts    int           // Tek koda izin verilen hatalı deneme
+	ResendInterval time.Duration // Aynı telefona iki kod arasındaki en kısa süre
+	RateWindow     time.Duration // Gönderim sınırlarının sayıldığı pencere
+	PhoneLimit     int           // Pencere içinde telefon başına en fazla kod
+	IPLimit        int           // Pencere içinde IP başına en fazla kod
+	MaxFailures    int           // Kilit için pencere içindeki hatalı deneme sayısı
+	LockoutWindow  time.Duration // Hatalı denemelerin sayıldığı pencere (kilit süresi)
+}
+
+// DefaultOTPConfig varsayılan sınırlar: 6 haneli, 5 dakika geçerli kod; saatte telefon
+// başına 5, IP başına 20 kod; 30 dakika içinde 5 hatalı denemede kilit
+func DefaultOTPConfig(secret string) OTPConfig {
+	return OTPConfig{
+		Secret:         []byte(secret),
+		CodeLength:     6,
+		TTL:            5 * time.Minute,
+		MaxAttempts:    3,
+		ResendInterval: time.Minute,
+		RateWindow:     time.Hour,
+		PhoneLimit:     5,
+		IPLimit:        20,
+		MaxFailures:    5,
+		LockoutWindow:  30 * time.Minute,
+	}
+}
+
+// OTPChallenge kod isteğinin yanıtı. Numara kayıtlı olmasa da aynı yanıt döner.
+type OTPChallenge struct {
+	ExpiresIn   int64 `json:"expires_in"`   // Saniye
+	ResendAfter int64 `json:"resend_after"` // Saniye
+}
+
+// OTPService SMS doğrulama kodu ile giriş ve şifre sıfırlama
+type OTPService struct {
+	auth   *AuthService
+	store  OTPStore
+	sms    *sms.Service
+	config OTPConfig
+	now    func() time.Time
+}
+
+// NewOTPService yeni servis oluşturur
+func NewOTPService(auth *AuthService, store OTPStore, smsService *sms.Service, config OTPConfig) *OTPService {
+	return &OTPService{
+		auth:   auth,
+		store:  store,
+		sms:    smsService,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// RequestOTP telefona doğrulama kodu gönderir. Kilit ve gönderim sınırları kontrol edilir;
+// numara kayıtlı değilse kod üretilip saklanır ama gönderilmez, böylece yanıt ve
+// sınır sayımı kayıtlı numaralarla aynı kalır.
+func (s *OTPService) RequestOTP(ctx context.Context, phone, purpose, ip string) (*OTPChallenge, error) {
+	phone, ok := NormalizePhone(phone)
+	if !ok {
+		return nil, ErrInvalidPhone
+	}
+	if purpose != OTPPurposeLogin && purpose != OTPPurposePasswordReset {
+		return nil, errors.New("geçersiz doğrulama amacı")
+	}
+	now := s.now()
+	if err := s.checkLock(ctx, phone, now); err != nil {
+		return nil, err
+	}
+
+	byPhone, byIP, err := s.store.CountOTPRequests(ctx, phone, ip, now.Add(-s.config.RateWindow))
+	if err != nil {
+		return nil, err
+	}
+	if byPhone >= s.config.PhoneLimit || (ip != "" && byIP >= s.config.IPLimit) {
+		return nil, ErrOTPRateLimited
+	}
+	last, err := s.store.LatestOTPAt(ctx, phone, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !last.IsZero() && now.Sub(last) < s.config.ResendInterval {
+		return nil, ErrOTPRateLimited
+	}
+
+	user, err := s.auth.userRepo.GetByPhone(ctx, phone)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	code, err := generateOTP(s.config.CodeLength)
+	if err != nil {
+		return nil, err
+	}
+	otp := &models.OTPCode{
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  s.hashOTP(phone, purpose, code),
+		IPAddress: ip,
+		ExpiresAt: now.Add(s.config.TTL),
+		CreatedAt: now,
+	}
+	if err := s.store.CreateOTP(ctx, otp); err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		resp, err := s.sms.Send(ctx, &sms.SendRequest{To: phone, Message: otpMessage(code, purpose, s.config.TTL)})
+		if err != nil || resp == nil || !resp.Success {
+			log.Printf("OTP SMS gönderilemedi (%s): %v", maskPhone(phone), err)
+			return nil, ErrOTPDelivery
+		}
+	}
+
+	return &OTPChallenge{
+		ExpiresIn:   int64(s.config.TTL.Seconds()),
+		ResendAfter: int64(s.config.ResendInterval.Seconds()),
+	}, nil
+}
+
+// LoginWithOTP kodu doğrular ve oturum açar; telefon doğrulanmış olarak işaretlenir
+func (s *OTPService) LoginWithOTP(ctx context.Context, phone, code string, client ClientInfo) (*TokenPair, *models.UserResponse, error) {
+	user, err := s.verify(ctx, phone, OTPPurposeLogin, code, client.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.auth.userRepo.MarkPhoneVerified(ctx, user.ID); err != nil {
+		return nil, nil, err
+	}
+	return s.auth.completeLogin(ctx, user, client)
+}
+
+// ResetPassword kodu doğrular ve şifreyi değiştirir. Şifre kuralları kod
+// harcanmadan önce kontrol edilir; açık oturumların tamamı kapatılır.
+func (s *OTPService) ResetPassword(ctx context.Context, phone, code, newPassword, ip string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	user, err := s.verify(ctx, phone, OTPPurposePasswordReset, code, ip)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.auth.userRepo.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+		return err
+	}
+	if _, err := s.auth.sessions.RevokeUserSessions(ctx, user.ID, RevokePasswordReset, s.now()); err != nil {
+		return err
+	}
+	return s.auth.userRepo.MarkPhoneVerified(ctx, user.ID)
+}
+
+// verify kodu son gönderilen kodla karşılaştırır ve başarılıysa tüketir. Her hatalı
+// deneme kaydedilir; kodun deneme hakkı biterse kod geçersizleşir, telefon bazında
+// pencere sınırı aşılırsa telefon kilitlenir.
+func (s *OTPService) verify(ctx context.Context, phone, purpose, code, ip string) (*models.User, error) {
+	phone, ok := NormalizePhone(phone)
+	if !ok {
+		return nil, ErrInvalidPhone
+	}
+	now := s.now()
+	if err := s.checkLock(ctx, phone, now); err != nil {
+		return nil, err
+	}
+
+	otp, err := s.store.GetActiveOTP(ctx, phone, purpose, now)
+	if err != nil && !errors.Is(err, repository.ErrOTPNotFound) {
+		return nil, err
+	}
+	if otp == nil || !hmac.Equal([]byte(s.hashOTP(phone, purpose, strings.TrimSpace(code))), []byte(otp.CodeHash)) {
+		var codeID string
+		if otp != nil {
+			codeID = otp.ID
+		}
+		if err := s.store.RegisterOTPFailure(ctx, codeID, phone, ip, s.config.MaxAttempts, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrOTPInvalid
+	}
+
+	consumed, err := s.store.ConsumeOTP(ctx, otp.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrOTPInvalid
+	}
+
+	user, err := s.auth.userRepo.GetByPhone(ctx, phone)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrOTPInvalid
+	}
+	return user, err
+}
+
+func (s *OTPService) checkLock(ctx context.Context, phone string, now time.Time) error {
+	failures, err := s.store.CountOTPFailures(ctx, phone, now.Add(-s.config.LockoutWindow))
+	if err != nil {
+		return err
+	}
+	if failures >= s.config.MaxFailures {
+		return ErrOTPLocked
+	}
+	return nil
+}
+
+// hashOTP kodun telefon ve amaca bağlı HMAC-SHA256 özeti
+func (s *OTPService) hashOTP(phone, purpose, code string) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte(phone + "|" + purpose + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateOTP kriptografik rastgele, baştaki sıfırları korunan sayısal kod üretir
+func generateOTP(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func otpMessage(code, purpose string, ttl time.Duration) string {
+	action := "giriş"
+	if purpose == OTPPurposePasswordReset {
+		action = "şifre sıfırlama"
+	}
+	return fmt.Sprintf("SiteEksen %s kodunuz: %s. Kod %d dakika geçerlidir, kimseyle paylaşmayın.",
+		action, code, int(ttl.Minutes()))
+}
+
+// NormalizePhone Türkiye cep telefonu numarasını +90XXXXXXXXXX biçimine getirir
+func NormalizePhone(phone string) (string, bool) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	switch {
+	case strings.HasPrefix(phone, "+90"):
+		phone = phone[3:]
+	case strings.HasPrefix(phone, "90") && len(phone) == 12:
+		phone = phone[2:]
+	case strings.HasPrefix(phone, "0"):
+		phone = phone[1:]
+	}
+	if len(phone) != 10 || phone[0] != '5' {
+		return "", false
+	}
+	for _, c := range phone {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return "+90" + phone, true
+}
+
+// maskPhone yanıtlar ve loglar için telefonu maskeler; boş telefon boş kalır
+func maskPhone(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	return encryption.MaskPhone(phone)
+}
+
+// validatePassword şifre kuralları: en az 8 karakter; büyük harf, küçük harf, rakam ve özel karakter
+func validatePassword(password string) error {
+	if len(password) < 8 {
+		return errors.New("şifre en az 8 karakter olmalı")
+	}
+	var upper, lower, digit, special bool
+	for _, c := range password {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		default:
+			special = true
+		}
+	}
+	if !upper || !lower || !digit || !special {
+		return errors.New("şifre büyük harf, küçük harf, rakam ve özel karakter içermeli")
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPhone = "+905551234567"
+
+type memoryUsers struct {
+	users map[string]*models.User
+}
+
+func (m *memoryUsers) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	for _, u := range m.users {
+		if u.Phone == phone {
+			return u, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *memoryUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	if u, ok := m.users[id]; ok {
+		return u, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *memoryUsers) GetUserProperties(ctx context.Context, userID string) ([]models.UserProperty, error) {
+	return nil, nil
+}
+
+func (m *memoryUsers) SetActiveProperty(ctx context.Context, userID, propertyID string) error {
+	return nil
+}
+
+func (m *memoryUsers) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	m.users[userID].PasswordHash = passwordHash
+	return nil
+}
+
+func (m *memoryUsers) MarkPhoneVerified(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (m *memoryUsers) SetTCKN(ctx context.Context, userID, tckn string) error {
+	for id, u := range m.users {
+		if id != userID && u.TCKN == tckn {
+			return repository.ErrTCKNTaken
+		}
+	}
+	m.users[userID].TCKN = tckn
+	return nil
+}
+
+// memoryOTPs repository.OTPRepository davranışının bellek içi karşılığı
+type memoryOTPs struct {
+	mu       sync.Mutex
+	codes    []*models.OTPCode
+	failures []time.Time
+}
+
+func (m *memoryOTPs) CreateOTP(ctx context.Context, c *models.OTPCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, old := range m.codes {
+		if old.Phone == c.Phone && old.Purpose == c.Purpose && old.ConsumedAt == nil {
+			at := c.CreatedAt
+			old.ConsumedAt = &at
+		}
+	}
+	c.ID = string(rune('a' + len(m.codes)))
+	m.codes = append(m.codes, c)
+	return nil
+}
+
+func (m *memoryOTPs) GetActiveOTP(ctx context.Context, phone, purpose string, now time.Time) (*models.OTPCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.codes) - 1; i >= 0; i-- {
+		c := m.codes[i]
+		if c.Phone == phone && c.Purpose == purpose && c.ConsumedAt == nil && c.ExpiresAt.After(now) {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrOTPNotFound
+}
+
+func (m *memoryOTPs) LatestOTPAt(ctx context.Context, phone, purpose string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest time.Time
+	for _, c := range m.codes {
+		if c.Phone == phone && c.Purpose == purpose && c.CreatedAt.After(latest) {
+			latest = c.CreatedAt
+		}
+	}
+	return latest, nil
+}
+
+func (m *memoryOTPs) RegisterOTPFailure(ctx context.Context, codeID, phone, ip string, maxAttempts int, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.codes {
+		if c.ID == codeID {
+			c.Attempts++
+			if c.Attempts >= maxAttempts {
+				c.ConsumedAt = &now
+			}
+		}
+	}
+	m.failures = append(m.failures, now)
+	return nil
+}
+
+func (m *memoryOTPs) ConsumeOTP(ctx context.Context, codeID string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.codes {
+		if c.ID == codeID && c.ConsumedAt == nil {
+			c.ConsumedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryOTPs) CountOTPRequests(ctx context.Context, phone, ip string, since time.Time) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var byPhone, byIP int
+	for _, c := range m.codes {
+		if c.CreatedAt.Before(since) {
+			continue
+		}
+		if c.Phone == phone {
+			byPhone++
+		}
+		if ip != "" && c.IPAddress == ip {
+			byIP++
+		}
+	}
+	return byPhone, byIP, nil
+}
+
+func (m *memoryOTPs) CountOTPFailures(ctx context.Context, phone string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, at := range m.failures {
+		if !at.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type otpFixture struct {
+	svc      *OTPService
+	users    *memoryUsers
+	sessions *memorySessions
+	access   *memoryAccess
+	fake     *sms.FakeProvider
+	clock    time.Time
+}
+
+func newOTPFixture(t *testing.T) *otpFixture {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Eski123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+	users := &memoryUsers{users: map[string]*models.User{
+		"u1": {ID: "u1", Phone: testPhone, FirstName: "Ahmet", PasswordHash: string(hash)},
+	}}
+
+	fake := sms.NewFakeProvider()
+	smsService := sms.NewService("fake")
+	smsService.RegisterProvider("fake", fake)
+
+	f := &otpFixture{users: users, sessions: newMemorySessions(), fake: fake,
+		clock: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)}
+	key, err := claims.GenerateKey("test")
+	require.NoError(t, err)
+	signer, err := claims.NewSigner("", key)
+	require.NoError(t, err)
+
+	f.access = newMemoryAccess()
+	f.svc = NewOTPService(NewAuthService(users, f.sessions, f.access, signer), &memoryOTPs{}, smsService,
+		DefaultOTPConfig("otp-secret"))
+	f.svc.now = func() time.Time { return f.clock }
+	return f
+}
+
+var codePattern = regexp.MustCompile(`\b(\d{6})\b`)
+
+func (f *otpFixture) lastCode(t *testing.T) string {
+	t.Helper()
+	msg, ok := f.fake.LastMessage(testPhone)
+	require.True(t, ok, "SMS gönderilmedi")
+	m := codePattern.FindStringSubmatch(msg.Message)
+	require.Len(t, m, 2)
+	return m[1]
+}
+
+func TestOTPLoginFlow(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	challenge, err := f.svc.RequestOTP(ctx, "0555 123 45 67", OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), challenge.ExpiresIn)
+	code := f.lastCode(t)
+
+	tokens, user, err := f.svc.LoginWithOTP(ctx, testPhone, code, ClientInfo{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Equal(t, "u1", user.ID)
+
+	// Kod tek kullanımlık
+	_, _, err = f.svc.LoginWithOTP(ctx, testPhone, code, ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrOTPInvalid)
+}
+
+func TestOTPPasswordReset(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	_, err := f.svc.RequestOTP(ctx, testPhone, OTPPurposePasswordReset, "10.0.0.1")
+	require.NoError(t, err)
+	code := f.lastCode(t)
+
+	// Zayıf şifre kodu harcamaz
+	assert.Error(t, f.svc.ResetPassword(ctx, testPhone, code, "zayif", "10.0.0.1"))
+	require.NoError(t, f.svc.ResetPassword(ctx, testPhone, code, "Yeni1234!", "10.0.0.1"))
+
+	_, _, err = f.svc.auth.Login(ctx, testPhone, "Yeni1234!", ClientInfo{})
+	assert.NoError(t, err)
+
+	// Giriş kodu şifre sıfırlamada kullanılamaz
+	f.clock = f.clock.Add(2 * time.Minute)
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, f.svc.ResetPassword(ctx, testPhone, f.lastCode(t), "Baska123!", "10.0.0.1"), ErrOTPInvalid)
+}
+
+func TestOTPUnknownPhoneNotSent(t *testing.T) {
+	f := newOTPFixture(t)
+
+	_, err := f.svc.RequestOTP(context.Background(), "+905550000000", OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Empty(t, f.fake.Messages())
+
+	_, err = f.svc.RequestOTP(context.Background(), "12345", OTPPurposeLogin, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidPhone)
+}
+
+func TestOTPExpiry(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	_, err := f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	code := f.lastCode(t)
+
+	f.clock = f.clock.Add(6 * time.Minute)
+	_, _, err = f.svc.LoginWithOTP(ctx, testPhone, code, ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrOTPInvalid)
+}
+
+func TestOTPRateLimits(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	_, err := f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+
+	// Yeniden gönderim aralığı
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrOTPRateLimited)
+
+	// Telefon başına saatlik sınır
+	for i := 0; i < 4; i++ {
+		f.clock = f.clock.Add(2 * time.Minute)
+		_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+		require.NoError(t, err)
+	}
+	f.clock = f.clock.Add(2 * time.Minute)
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.2")
+	assert.ErrorIs(t, err, ErrOTPRateLimited)
+
+	// Pencere kayınca yeniden izin verilir
+	f.clock = f.clock.Add(time.Hour)
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestOTPLockout(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	_, err := f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	require.NoError(t, err)
+	code := f.lastCode(t)
+
+	// Üç hatalı denemede kod geçersizleşir
+	for i := 0; i < 3; i++ {
+		_, _, err = f.svc.LoginWithOTP(ctx, testPhone, "000000", ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, ErrOTPInvalid)
+	}
+	_, _, err = f.svc.LoginWithOTP(ctx, testPhone, code, ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrOTPInvalid)
+
+	// Beşinci hatadan sonra telefon kilitlenir; yeni kod da istenemez
+	_, _, err = f.svc.LoginWithOTP(ctx, testPhone, "000000", ClientInfo{IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrOTPInvalid)
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrOTPLocked)
+
+	f.clock = f.clock.Add(31 * time.Minute)
+	_, err = f.svc.RequestOTP(ctx, testPhone, OTPPurposeLogin, "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestOTPDeliveryFailure(t *testing.T) {
+	f := newOTPFixture(t)
+	f.fake.SetFailing(true)
+
+	_, err := f.svc.RequestOTP(context.Background(), testPhone, OTPPurposeLogin, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrOTPDelivery)
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for _, in := range []string{"+905551234567", "905551234567", "05551234567", "555 123 45 67", "(555) 123-4567"} {
+		got, ok := NormalizePhone(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, testPhone, got, in)
+	}
+	for _, in := range []string{"", "2121234567", "+90555123456", "05551234abc"} {
+		_, ok := NormalizePhone(in)
+		assert.False(t, ok, in)
+	}
+}
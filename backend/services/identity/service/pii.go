@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// reencryptBatchSize yeniden şifrelemede tek işlemde taşınan kayıt sayısı
+const reencryptBatchSize = 500
+
+// PIIStore kişisel veri alanlarının yeniden şifrelenmesi (repository.PIIRepository)
+type PIIStore interface {
+	ActiveKeyVersion() int
+	ReencryptUsers(ctx context.Context, limit int) (int, error)
+	ReencryptInvitations(ctx context.Context, limit int) (int, error)
+	ReencryptMFASecrets(ctx context.Context, limit int) (int, error)
+}
+
+var _ PIIStore = (*repository.PIIRepository)(nil)
+
+// ReencryptResult yeniden şifreleme sonucu
+type ReencryptResult struct {
+	KeyVersion  int `json:"key_version"`
+	Users       int `json:"users"`
+	Invitations int `json:"invitations"`
+	MFASecrets  int `json:"mfa_secrets"`
+}
+
+// PIIService anahtar değişiminden sonra kişisel verilerin yeni anahtara taşınması.
+// Yeni anahtar PII_ENCRYPTION_KEYS'e eklenip PII_ACTIVE_KEY_VERSION yükseltildikten
+// sonra çalıştırılır; tüm kayıtlar taşındığında eski anahtar halkadan çıkarılabilir.
+type PIIService struct {
+	store PIIStore
+}
+
+// NewPIIService yeni servis oluşturur
+func NewPIIService(store PIIStore) *PIIService {
+	return &PIIService{store: store}
+}
+
+// Reencrypt aktif sürümde olmayan tüm kayıtları partiler halinde yeniden şifreler.
+// İş kesilirse kaldığı yerden devam eder; taşınmış kayıtlar tekrar işlenmez.
+func (s *PIIService) Reencrypt(ctx context.Context) (*ReencryptResult, error) {
+	result := &ReencryptResult{KeyVersion: s.store.ActiveKeyVersion()}
+	for {
+		n, err := s.store.ReencryptUsers(ctx, reencryptBatchSize)
+		if err != nil {
+			return result, err
+		}
+		result.Users += n
+		if n < reencryptBatchSize {
+			break
+		}
+	}
+	for {
+		n, err := s.store.ReencryptInvitations(ctx, reencryptBatchSize)
+		if err != nil {
+			return result, err
+		}
+		result.Invitations += n
+		if n < reencryptBatchSize {
+			break
+		}
+	}
+	for {
+		n, err := s.store.ReencryptMFASecrets(ctx, reencryptBatchSize)
+		if err != nil {
+			return result, err
+		}
+		result.MFASecrets += n
+		if n < reencryptBatchSize {
+			break
+		}
+	}
+	return result, nil
+}
+
+// RunReencryption servis açılışında bekleyen kayıtları taşır ve sonucu loglar
+func (s *PIIService) RunReencryption(ctx context.Context) {
+	result, err := s.Reencrypt(ctx)
+	if err != nil {
+		log.Printf("Kişisel veri yeniden şifreleme hatası (v%d, %d kullanıcı, %d davet, %d TOTP anahtarı taşındı): %v",
+			result.KeyVersion, result.Users, result.Invitations, result.MFASecrets, err)
+		return
+	}
+	if result.Users > 0 || result.Invitations > 0 || result.MFASecrets > 0 {
+		log.Printf("Kişisel veriler v%d anahtarına taşındı: %d kullanıcı, %d davet, %d TOTP anahtarı",
+			result.KeyVersion, result.Users, result.Invitations, result.MFASecrets)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPII repository.PIIRepository davranışının bellek içi karşılığı: bekleyen kayıt
+// sayıları partiler halinde azalır
+type memoryPII struct {
+	version     int
+	users       int
+	invitations int
+	mfaSecrets  int
+	failUsers   error
+}
+
+func (m *memoryPII) ActiveKeyVersion() int { return m.version }
+
+func (m *memoryPII) ReencryptUsers(ctx context.Context, limit int) (int, error) {
+	if m.failUsers != nil {
+		return 0, m.failUsers
+	}
+	n := min(limit, m.users)
+	m.users -= n
+	return n, nil
+}
+
+func (m *memoryPII) ReencryptInvitations(ctx context.Context, limit int) (int, error) {
+	n := min(limit, m.invitations)
+	m.invitations -= n
+	return n, nil
+}
+
+func (m *memoryPII) ReencryptMFASecrets(ctx context.Context, limit int) (int, error) {
+	n := min(limit, m.mfaSecrets)
+	m.mfaSecrets -= n
+	return n, nil
+}
+
+func TestReencryptProcessesAllBatches(t *testing.T) {
+	store := &memoryPII{version: 2, users: 2*reencryptBatchSize + 7, invitations: 3, mfaSecrets: 2}
+	result, err := NewPIIService(store).Reencrypt(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ReencryptResult{KeyVersion: 2, Users: 2*reencryptBatchSize + 7, Invitations: 3, MFASecrets: 2}, result)
+	assert.Zero(t, store.users)
+
+	// Taşınmış kayıtlar tekrar işlenmez
+	result, err = NewPIIService(store).Reencrypt(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.Users)
+
+	store.failUsers = errors.New("bilinmeyen anahtar")
+	_, err = NewPIIService(store).Reencrypt(context.Background())
+	assert.Error(t, err)
+}
+
+func TestUserResponseMasksPII(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, f.svc.auth.SetTCKN(ctx, "u1", "10000000147"), ErrInvalidTCKN)
+	assert.ErrorIs(t, f.svc.auth.SetTCKN(ctx, "u1", "1000000014"), ErrInvalidTCKN)
+	require.NoError(t, f.svc.auth.SetTCKN(ctx, "u1", " 10000000146 "))
+
+	masked, err := f.svc.auth.GetUserByID(ctx, "u1", false)
+	require.NoError(t, err)
+	assert.Equal(t, "+90******4567", masked.Phone)
+	assert.Equal(t, "*******0146", masked.TCKN)
+
+	revealed, err := f.svc.auth.GetUserByID(ctx, "u1", true)
+	require.NoError(t, err)
+	assert.Equal(t, testPhone, revealed.Phone)
+	assert.Equal(t, "10000000146", revealed.TCKN)
+
+	_, user, err := f.svc.auth.Login(ctx, testPhone, "Eski123!", ClientInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "+90******4567", user.Phone)
+
+	f.users.users["u2"] = &models.User{ID: "u2", Phone: "+905320000000"}
+	assert.ErrorIs(t, f.svc.auth.SetTCKN(ctx, "u2", "10000000146"), repository.ErrTCKNTaken)
+}
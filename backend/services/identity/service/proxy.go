@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// Vekalet durumları
+const (
+	ProxyScheduled = "SCHEDULED" // Başlangıç tarihi gelmemiş
+	ProxyActive    = "ACTIVE"
+	ProxyExpired   = "EXPIRED"
+	ProxyRevoked   = "REVOKED"
+	ProxyLapsed    = "LAPSED" // Veren kişi artık dairenin kat maliki değil
+)
+
+var (
+	// ErrProxyUserNotFound vekil olarak gösterilen telefonla kayıtlı hesap yok
+	ErrProxyUserNotFound = errors.New("vekilin SiteEksen hesabı bulunamadı; vekil önce kayıt olmalı")
+	// ErrProxySelf malik kendisini vekil tayin edemez
+	ErrProxySelf = errors.New("kendinizi vekil tayin edemezsiniz")
+	// ErrProxyLimitExceeded vekil KMK md. 31 sınırından fazla malike vekil olamaz
+	ErrProxyLimitExceeded = errors.New("vekil bu sitede izin verilenden fazla kat malikini temsil edemez")
+	// ErrProxyDocumentRequired vekaletname belgesi yüklenmeli
+	ErrProxyDocumentRequired = errors.New("vekaletname belgesi gerekli")
+	// ErrProxyDocumentInvalid belge türü desteklenmiyor veya boyutu sınırı aşıyor
+	ErrProxyDocumentInvalid = errors.New("vekaletname PDF, JPG veya PNG olmalı ve boyut sınırını aşmamalı")
+	// ErrProxyAlreadyRevoked vekalet zaten iptal edilmiş
+	ErrProxyAlreadyRevoked = errors.New("vekalet zaten iptal edilmiş")
+)
+
+// ProxyStore vekalet kayıtları (repository.ProxyRepository)
+type ProxyStore interface {
+	GetGrantorUnit(ctx context.Context, propertyID, unitID, grantorID string) (*models.UserProperty, error)
+	CountOwners(ctx context.Context, propertyID string) (int, error)
+	CountVoteGrantors(ctx context.Context, propertyID, proxyID, exceptGrantor string, from, until time.Time) (int, error)
+	CreateProxy(ctx context.Context, p *models.UnitProxy) error
+	GetProxy(ctx context.Context, propertyID, id string) (*models.UnitProxy, error)
+	ListProxies(ctx context.Context, f models.ProxyFilter, now time.Time) ([]models.UnitProxy, error)
+	RevokeProxy(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error)
+}
+
+// DocumentStore yüklenen belgelerin saklandığı depo (repository.FileStore)
+type DocumentStore interface {
+	Save(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Remove(ctx context.Context, key string) error
+}
+
+var (
+	_ ProxyStore    = (*repository.ProxyRepository)(nil)
+	_ DocumentStore = (*repository.FileStore)(nil)
+)
+
+// ProxyConfig vekalet sınırları
+type ProxyConfig struct {
+	MaxDuration     time.Duration // Tek vekaletin en uzun süresi
+	MaxDocumentSize int64         // Vekaletname dosyasının en büyük boyutu (bayt)
+}
+
+// DefaultProxyConfig en fazla bir yıllık vekalet ve 10 MB belge
+func DefaultProxyConfig() ProxyConfig {
+	return ProxyConfig{MaxDuration: 366 * 24 * time.Hour, MaxDocumentSize: 10 << 20}
+}
+
+// proxyDocumentTypes kabul edilen vekaletname uzantıları ve içerik türleri
+var proxyDocumentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+}
+
+// ProxyInput kat malikinin vekalet isteği. Vekil SiteEksen'e kayıtlı telefonuyla belirtilir.
+type ProxyInput struct {
+	PropertyID string
+	UnitID     string
+	GrantorID  string
+	ProxyPhone string
+	Scopes     []string
+	StartsAt   time.Time // Boşsa hemen
+	ExpiresAt  time.Time
+	Note       string
+}
+
+// ProxyDocument yüklenen vekaletname
+type ProxyDocument struct {
+	Name    string
+	Content io.Reader
+}
+
+// ProxyService kat maliklerinin daire bazlı vekaletleri
+type ProxyService struct {
+	auth   *AuthService
+	store  ProxyStore
+	files  DocumentStore
+	sms    *sms.Service
+	config ProxyConfig
+	now    func() time.Time
+}
+
+// NewProxyService yeni servis oluşturur
+func NewProxyService(auth *AuthService, store ProxyStore, files DocumentStore, smsService *sms.Service, config ProxyConfig) *ProxyService {
+	return &ProxyService{
+		auth:   auth,
+		store:  store,
+		files:  files,
+		sms:    smsService,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Grant kat malikinin dairesi için vekalet verir. Vekaletname yüklenmeden vekalet
+// verilemez. Oy kapsamında vekilin temsil edebileceği malik sayısı KMK md. 31 ile
+// sınırlıdır. Vekile SMS ile bilgi verilir; gönderim hatası vekaleti etkilemez.
+func (s *ProxyService) Grant(ctx context.Context, in *ProxyInput, doc *ProxyDocument) (*models.UnitProxy, error) {
+	scopes, err := normalizeScopes(in.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	startsAt := in.StartsAt
+	if startsAt.IsZero() || startsAt.Before(now) {
+		startsAt = now
+	}
+	if in.ExpiresAt.IsZero() {
+		return nil, errors.New("vekalet bitiş tarihi gerekli")
+	}
+	if !in.ExpiresAt.After(startsAt) {
+		return nil, errors.New("vekalet bitiş tarihi başlangıçtan sonra olmalı")
+	}
+	if in.ExpiresAt.Sub(startsAt) > s.config.MaxDuration {
+		return nil, fmt.Errorf("vekalet en fazla %d gün için verilebilir", int(s.config.MaxDuration.Hours()/24))
+	}
+	if doc == nil || doc.Content == nil {
+		return nil, ErrProxyDocumentRequired
+	}
+	ext := strings.ToLower(filepath.Ext(doc.Name))
+	contentType, ok := proxyDocumentTypes[ext]
+	if !ok {
+		return nil, ErrProxyDocumentInvalid
+	}
+
+	unit, err := s.store.GetGrantorUnit(ctx, in.PropertyID, in.UnitID, in.GrantorID)
+	if err != nil {
+		return nil, err
+	}
+	phone, ok := NormalizePhone(in.ProxyPhone)
+	if !ok {
+		return nil, ErrInvalidPhone
+	}
+	proxyUser, err := s.auth.userRepo.GetByPhone(ctx, phone)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrProxyUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if proxyUser.ID == in.GrantorID {
+		return nil, ErrProxySelf
+	}
+
+	if containsString(scopes, proxy.ScopeVote) {
+		owners, err := s.store.CountOwners(ctx, in.PropertyID)
+		if err != nil {
+			return nil, err
+		}
+		others, err := s.store.CountVoteGrantors(ctx, in.PropertyID, proxyUser.ID, in.GrantorID, startsAt, in.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if others+1 > proxy.MaxGrantorsPerProxy(owners) {
+			return nil, ErrProxyLimitExceeded
+		}
+	}
+
+	// Belge önce depoya yazılır; kayıt başarısız olursa silinir
+	key := "proxies/" + uuid.NewString() + ext
+	hash := sha256.New()
+	size, err := s.files.Save(ctx, key, io.TeeReader(&maxBytesReader{r: doc.Content, remaining: s.config.MaxDocumentSize}, hash))
+	if errors.Is(err, errDocumentTooLarge) {
+		return nil, ErrProxyDocumentInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		s.files.Remove(ctx, key)
+		return nil, ErrProxyDocumentRequired
+	}
+
+	p := &models.UnitProxy{
+		PropertyID:     in.PropertyID,
+		UnitID:         in.UnitID,
+		UnitName:       unit.UnitName,
+		GrantorID:      in.GrantorID,
+		ProxyID:        proxyUser.ID,
+		ProxyName:      proxyUser.FirstName + " " + proxyUser.LastName,
+		Scopes:         scopes,
+		StartsAt:       startsAt,
+		ExpiresAt:      in.ExpiresAt,
+		DocumentKey:    key,
+		DocumentName:   filepath.Base(doc.Name),
+		DocumentType:   contentType,
+		DocumentSize:   size,
+		DocumentSHA256: hex.EncodeToString(hash.Sum(nil)),
+		Note:           strings.TrimSpace(in.Note),
+		GrantorIsOwner: true,
+		CreatedAt:      now,
+	}
+	if err := s.store.CreateProxy(ctx, p); err != nil {
+		if removeErr := s.files.Remove(ctx, key); removeErr != nil {
+			log.Printf("Vekaletname silinemedi (%s): %v", key, removeErr)
+		}
+		return nil, err
+	}
+	if grantor, err := s.auth.userRepo.GetByID(ctx, in.GrantorID); err == nil {
+		p.GrantorName = grantor.FirstName + " " + grantor.LastName
+	}
+	p.Status = proxyStatus(p, now)
+
+	s.notifyGranted(ctx, p, unit, proxyUser.Phone)
+	return p, nil
+}
+
+// ListMine kullanıcının verdiği ve kendisine verilen vekaletler (tüm sitelerde)
+func (s *ProxyService) ListMine(ctx context.Context, userID string, includeInactive bool) (granted, received []models.UnitProxy, err error) {
+	now := s.now()
+	granted, err = s.store.ListProxies(ctx, models.ProxyFilter{GrantorID: userID, IncludeInactive: includeInactive}, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	received, err = s.store.ListProxies(ctx, models.ProxyFilter{ProxyID: userID, IncludeInactive: includeInactive}, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	setProxyStatuses(granted, now)
+	setProxyStatuses(received, now)
+	return granted, received, nil
+}
+
+// ListProperty sitedeki vekaletler; unitID verilirse yalnızca o dairenin
+func (s *ProxyService) ListProperty(ctx context.Context, propertyID, unitID string, includeInactive bool) ([]models.UnitProxy, error) {
+	now := s.now()
+	proxies, err := s.store.ListProxies(ctx, models.ProxyFilter{
+		PropertyID:      propertyID,
+		UnitID:          unitID,
+		IncludeInactive: includeInactive,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	setProxyStatuses(proxies, now)
+	return proxies, nil
+}
+
+// Revoke vekaleti iptal eder. Vekaleti veren malik, vekil (istifa) veya manager=true
+// ile site yönetimi iptal edebilir; propertyID boşsa vekalet tüm sitelerde aranır.
+func (s *ProxyService) Revoke(ctx context.Context, propertyID, id, userID string, manager bool) error {
+	p, err := s.accessibleProxy(ctx, propertyID, id, userID, manager)
+	if err != nil {
+		return err
+	}
+	if p.RevokedAt != nil {
+		return ErrProxyAlreadyRevoked
+	}
+	ok, err := s.store.RevokeProxy(ctx, p.PropertyID, p.ID, userID, s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrProxyAlreadyRevoked
+	}
+	return nil
+}
+
+// Document vekaletname dosyasını açar; erişim Revoke ile aynı kurala tabidir.
+// Dönen içeriği çağıran kapatır.
+func (s *ProxyService) Document(ctx context.Context, propertyID, id, userID string, manager bool) (*models.UnitProxy, io.ReadCloser, error) {
+	p, err := s.accessibleProxy(ctx, propertyID, id, userID, manager)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.files.Open(ctx, p.DocumentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, content, nil
+}
+
+// accessibleProxy vekaleti getirir; taraflardan biri olmayan kullanıcıya vekalet yok görünür
+func (s *ProxyService) accessibleProxy(ctx context.Context, propertyID, id, userID string, manager bool) (*models.UnitProxy, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, repository.ErrProxyNotFound
+	}
+	p, err := s.store.GetProxy(ctx, propertyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !manager && p.GrantorID != userID && p.ProxyID != userID {
+		return nil, repository.ErrProxyNotFound
+	}
+	p.Status = proxyStatus(p, s.now())
+	return p, nil
+}
+
+// notifyGranted vekile SMS ile bilgi verir; gönderim hatası vekaleti etkilemez
+func (s *ProxyService) notifyGranted(ctx context.Context, p *models.UnitProxy, unit *models.UserProperty, phone string) {
+	labels := make([]string, len(p.Scopes))
+	for i, scope := range p.Scopes {
+		labels[i] = scopeLabel(scope)
+	}
+	message := fmt.Sprintf("SiteEksen: %s, %s %s dairesi için sizi %s konusunda %s tarihine kadar vekil tayin etti.",
+		p.GrantorName, unit.PropertyName, unit.UnitName, strings.Join(labels, ", "), p.ExpiresAt.Format("02.01.2006"))
+	resp, err := s.sms.Send(ctx, &sms.SendRequest{To: phone, Message: message, PropertyID: p.PropertyID})
+	if err != nil || resp == nil || !resp.Success {
+		log.Printf("Vekalet bildirimi gönderilemedi (%s): %v", maskPhone(phone), err)
+	}
+}
+
+// normalizeScopes kapsamları doğrular, tekrarları atar ve sabit sıraya dizer
+func normalizeScopes(scopes []string) ([]string, error) {
+	requested := map[string]bool{}
+	for _, scope := range scopes {
+		scope = strings.ToUpper(strings.TrimSpace(scope))
+		if !proxy.ValidScope(scope) {
+			return nil, fmt.Errorf("geçersiz vekalet kapsamı: %s", scope)
+		}
+		requested[scope] = true
+	}
+	var normalized []string
+	for _, scope := range proxy.Scopes {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, errors.New("en az bir vekalet kapsamı seçilmeli")
+	}
+	return normalized, nil
+}
+
+func proxyStatus(p *models.UnitProxy, now time.Time) string {
+	switch {
+	case p.RevokedAt != nil:
+		return ProxyRevoked
+	case !now.Before(p.ExpiresAt):
+		return ProxyExpired
+	case !p.GrantorIsOwner:
+		return ProxyLapsed
+	case now.Before(p.StartsAt):
+		return ProxyScheduled
+	default:
+		return ProxyActive
+	}
+}
+
+func setProxyStatuses(proxies []models.UnitProxy, now time.Time) {
+	for i := range proxies {
+		proxies[i].Status = proxyStatus(&proxies[i], now)
+	}
+}
+
+func scopeLabel(scope string) string {
+	switch scope {
+	case proxy.ScopePayDues:
+		return "aidat ödeme"
+	case proxy.ScopeVote:
+		return "oy kullanma"
+	case proxy.ScopeNotifications:
+		return "bildirim alma"
+	}
+	return scope
+}
+
+func containsString(values []string, v string) bool {
+	for _, item := range values {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+var errDocumentTooLarge = errors.New("belge boyut sınırını aşıyor")
+
+// maxBytesReader remaining bayttan fazlası okunursa errDocumentTooLarge döner
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	if int64(n) > m.remaining {
+		return int(m.remaining), errDocumentTooLarge
+	}
+	m.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	proxyPhone  = "+905329998877"
+	secondUnit  = "33333333-3333-3333-3333-333333333304"
+	otherOwner  = "u3"
+	proxyUserID = "u2"
+)
+
+// memoryProxies repository.ProxyRepository davranışının bellek içi karşılığı
+type memoryProxies struct {
+	owners     map[string]string // daire -> aktif kat maliki
+	ownerCount int
+	proxies    []*models.UnitProxy
+}
+
+func (m *memoryProxies) GetGrantorUnit(ctx context.Context, propertyID, unitID, grantorID string) (*models.UserProperty, error) {
+	owner, ok := m.owners[unitID]
+	if propertyID != testProperty || !ok {
+		return nil, repository.ErrUnitNotFound
+	}
+	if owner != grantorID {
+		return nil, repository.ErrNotUnitOwner
+	}
+	return &models.UserProperty{PropertyID: propertyID, PropertyName: "Güneş Sitesi", UnitID: unitID,
+		UnitName: "A-" + unitID[len(unitID)-1:], Role: "OWNER"}, nil
+}
+
+func (m *memoryProxies) CountOwners(ctx context.Context, propertyID string) (int, error) {
+	return m.ownerCount, nil
+}
+
+func (m *memoryProxies) CountVoteGrantors(ctx context.Context, propertyID, proxyID, exceptGrantor string, from, until time.Time) (int, error) {
+	grantors := map[string]bool{}
+	for _, p := range m.proxies {
+		if p.ProxyID == proxyID && p.GrantorID != exceptGrantor && p.RevokedAt == nil &&
+			containsString(p.Scopes, proxy.ScopeVote) && p.StartsAt.Before(until) && p.ExpiresAt.After(from) {
+			grantors[p.GrantorID] = true
+		}
+	}
+	return len(grantors), nil
+}
+
+func (m *memoryProxies) CreateProxy(ctx context.Context, p *models.UnitProxy) error {
+	p.ID = fmt.Sprintf("20000000-0000-0000-0000-%012d", len(m.proxies)+1)
+	stored := *p
+	m.proxies = append(m.proxies, &stored)
+	return nil
+}
+
+func (m *memoryProxies) GetProxy(ctx context.Context, propertyID, id string) (*models.UnitProxy, error) {
+	for _, p := range m.proxies {
+		if p.ID == id && (propertyID == "" || p.PropertyID == propertyID) {
+			copied := *p
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrProxyNotFound
+}
+
+func (m *memoryProxies) ListProxies(ctx context.Context, f models.ProxyFilter, now time.Time) ([]models.UnitProxy, error) {
+	result := []models.UnitProxy{}
+	for _, p := range m.proxies {
+		if (f.PropertyID == "" || p.PropertyID == f.PropertyID) && (f.UnitID == "" || p.UnitID == f.UnitID) &&
+			(f.GrantorID == "" || p.GrantorID == f.GrantorID) && (f.ProxyID == "" || p.ProxyID == f.ProxyID) &&
+			(f.IncludeInactive || (p.RevokedAt == nil && p.ExpiresAt.After(now))) {
+			result = append(result, *p)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryProxies) RevokeProxy(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error) {
+	for _, p := range m.proxies {
+		if p.ID == id && p.PropertyID == propertyID && p.RevokedAt == nil {
+			p.RevokedAt = &now
+			p.RevokedBy = revokedBy
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// memoryFiles repository.FileStore davranışının bellek içi karşılığı
+type memoryFiles struct {
+	files map[string][]byte
+}
+
+func (m *memoryFiles) Save(ctx context.Context, key string, r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	m.files[key] = data
+	return int64(len(data)), nil
+}
+
+func (m *memoryFiles) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := m.files[key]
+	if !ok {
+		return nil, repository.ErrFileNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryFiles) Remove(ctx context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+type proxyFixture struct {
+	*otpFixture
+	proxies *ProxyService
+	store   *memoryProxies
+	files   *memoryFiles
+}
+
+func newProxyFixture(t *testing.T) *proxyFixture {
+	t.Helper()
+	f := newOTPFixture(t)
+	f.users.users[proxyUserID] = &models.User{ID: proxyUserID, Phone: proxyPhone, FirstName: "Zeynep", LastName: "Demir"}
+	f.users.users[otherOwner] = &models.User{ID: otherOwner, Phone: "+905320000003", FirstName: "Can", LastName: "Ak"}
+	store := &memoryProxies{owners: map[string]string{testUnit: "u1", secondUnit: otherOwner}, ownerCount: 12}
+	files := &memoryFiles{files: map[string][]byte{}}
+	smsService := sms.NewService("fake")
+	smsService.RegisterProvider("fake", f.fake)
+
+	svc := NewProxyService(f.svc.auth, store, files, smsService, DefaultProxyConfig())
+	svc.now = func() time.Time { return f.clock }
+	return &proxyFixture{otpFixture: f, proxies: svc, store: store, files: files}
+}
+
+func (f *proxyFixture) input(grantor, unit string, scopes ...string) *ProxyInput {
+	return &ProxyInput{
+		PropertyID: testProperty,
+		UnitID:     unit,
+		GrantorID:  grantor,
+		ProxyPhone: "0532 999 88 77",
+		Scopes:     scopes,
+		ExpiresAt:  f.clock.Add(30 * 24 * time.Hour),
+	}
+}
+
+func pdf(content string) *ProxyDocument {
+	return &ProxyDocument{Name: "vekaletname.pdf", Content: strings.NewReader(content)}
+}
+
+func TestProxyGrant(t *testing.T) {
+	f := newProxyFixture(t)
+	ctx := context.Background()
+
+	granted, err := f.proxies.Grant(ctx, f.input("u1", testUnit, "vote", "PAY_DUES", "VOTE"), pdf("noter onaylı"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{proxy.ScopePayDues, proxy.ScopeVote}, granted.Scopes, "kapsamlar tekilleşir ve sıralanır")
+	assert.Equal(t, proxyUserID, granted.ProxyID)
+	assert.Equal(t, ProxyActive, granted.Status)
+	assert.Equal(t, "application/pdf", granted.DocumentType)
+	sum := sha256.Sum256([]byte("noter onaylı"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), granted.DocumentSHA256)
+	assert.Len(t, f.files.files, 1)
+
+	msg, ok := f.fake.LastMessage(proxyPhone)
+	require.True(t, ok)
+	assert.Contains(t, msg.Message, "aidat ödeme, oy kullanma")
+	assert.Contains(t, msg.Message, "17.11.2026")
+
+	granted2, received, err := f.proxies.ListMine(ctx, "u1", false)
+	require.NoError(t, err)
+	assert.Len(t, granted2, 1)
+	assert.Empty(t, received)
+	_, received, err = f.proxies.ListMine(ctx, proxyUserID, false)
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, ProxyActive, received[0].Status)
+
+	// Belgeyi yalnızca taraflar ve site yönetimi görür
+	_, content, err := f.proxies.Document(ctx, "", granted.ID, proxyUserID, false)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	assert.Equal(t, "noter onaylı", string(data))
+	_, _, err = f.proxies.Document(ctx, "", granted.ID, otherOwner, false)
+	assert.ErrorIs(t, err, repository.ErrProxyNotFound)
+	_, _, err = f.proxies.Document(ctx, testProperty, granted.ID, "manager", true)
+	assert.NoError(t, err)
+}
+
+func TestProxyGrantValidation(t *testing.T) {
+	f := newProxyFixture(t)
+	ctx := context.Background()
+
+	_, err := f.proxies.Grant(ctx, f.input(otherOwner, testUnit, "VOTE"), pdf("x"))
+	assert.ErrorIs(t, err, repository.ErrNotUnitOwner)
+
+	_, err = f.proxies.Grant(ctx, f.input("u1", testUnit, "VOTE"), nil)
+	assert.ErrorIs(t, err, ErrProxyDocumentRequired)
+
+	_, err = f.proxies.Grant(ctx, f.input("u1", testUnit, "VOTE"),
+		&ProxyDocument{Name: "vekalet.exe", Content: strings.NewReader("x")})
+	assert.ErrorIs(t, err, ErrProxyDocumentInvalid)
+
+	f.proxies.config.MaxDocumentSize = 4
+	_, err = f.proxies.Grant(ctx, f.input("u1", testUnit, "VOTE"), pdf("çok büyük"))
+	assert.ErrorIs(t, err, ErrProxyDocumentInvalid)
+	f.proxies.config = DefaultProxyConfig()
+
+	_, err = f.proxies.Grant(ctx, f.input("u1", testUnit, "ADMIN"), pdf("x"))
+	assert.ErrorContains(t, err, "geçersiz vekalet kapsamı")
+	_, err = f.proxies.Grant(ctx, f.input("u1", testUnit), pdf("x"))
+	assert.Error(t, err)
+
+	in := f.input("u1", testUnit, "VOTE")
+	in.ProxyPhone = testPhone
+	_, err = f.proxies.Grant(ctx, in, pdf("x"))
+	assert.ErrorIs(t, err, ErrProxySelf)
+
+	in = f.input("u1", testUnit, "VOTE")
+	in.ProxyPhone = "+905551110000"
+	_, err = f.proxies.Grant(ctx, in, pdf("x"))
+	assert.ErrorIs(t, err, ErrProxyUserNotFound)
+
+	in = f.input("u1", testUnit, "VOTE")
+	in.ExpiresAt = f.clock.Add(-time.Hour)
+	_, err = f.proxies.Grant(ctx, in, pdf("x"))
+	assert.Error(t, err)
+
+	in = f.input("u1", testUnit, "VOTE")
+	in.ExpiresAt = f.clock.AddDate(2, 0, 0)
+	_, err = f.proxies.Grant(ctx, in, pdf("x"))
+	assert.ErrorContains(t, err, "en fazla")
+
+	assert.Empty(t, f.store.proxies)
+	assert.Empty(t, f.files.files, "reddedilen belgeler saklanmaz")
+}
+
+func TestProxyVoteLimit(t *testing.T) {
+	f := newProxyFixture(t)
+	ctx := context.Background()
+
+	_, err := f.proxies.Grant(ctx, f.input("u1", testUnit, "VOTE"), pdf("1"))
+	require.NoError(t, err)
+
+	// Yirmi malikli sitede bir kişi yalnızca bir malike oy vekili olabilir
+	_, err = f.proxies.Grant(ctx, f.input(otherOwner, secondUnit, "VOTE"), pdf("2"))
+	assert.ErrorIs(t, err, ErrProxyLimitExceeded)
+	_, err = f.proxies.Grant(ctx, f.input(otherOwner, secondUnit, "PAY_DUES", "NOTIFICATIONS"), pdf("2"))
+	assert.NoError(t, err, "sınır yalnızca oy vekaletine uygulanır")
+
+	// Kalabalık sitede %5'e kadar
+	f.store.ownerCount = 40
+	_, err = f.proxies.Grant(ctx, f.input(otherOwner, secondUnit, "VOTE"), pdf("3"))
+	assert.NoError(t, err)
+}
+
+func TestProxyRevokeAndStatus(t *testing.T) {
+	f := newProxyFixture(t)
+	ctx := context.Background()
+
+	granted, err := f.proxies.Grant(ctx, f.input("u1", testUnit, "NOTIFICATIONS"), pdf("x"))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, f.proxies.Revoke(ctx, "", granted.ID, otherOwner, false), repository.ErrProxyNotFound)
+	assert.ErrorIs(t, f.proxies.Revoke(ctx, "", "yok", "u1", false), repository.ErrProxyNotFound)
+	require.NoError(t, f.proxies.Revoke(ctx, "", granted.ID, proxyUserID, false), "vekil vekaletten çekilebilir")
+	assert.ErrorIs(t, f.proxies.Revoke(ctx, testProperty, granted.ID, "manager", true), ErrProxyAlreadyRevoked)
+
+	active, err := f.proxies.ListProperty(ctx, testProperty, "", false)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	all, err := f.proxies.ListProperty(ctx, testProperty, testUnit, true)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, ProxyRevoked, all[0].Status)
+
+	// Süresi dolan ve malikin taşındığı vekaletler
+	in := f.input("u1", testUnit, "VOTE")
+	in.StartsAt = f.clock.Add(24 * time.Hour)
+	scheduled, err := f.proxies.Grant(ctx, in, pdf("y"))
+	require.NoError(t, err)
+	assert.Equal(t, ProxyScheduled, scheduled.Status)
+
+	f.store.proxies[1].GrantorIsOwner = false
+	_, received, err := f.proxies.ListMine(ctx, proxyUserID, false)
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, ProxyLapsed, received[0].Status)
+
+	f.clock = f.clock.Add(31 * 24 * time.Hour)
+	_, received, err = f.proxies.ListMine(ctx, proxyUserID, true)
+	require.NoError(t, err)
+	for _, p := range received {
+		assert.NotEqual(t, ProxyActive, p.Status)
+	}
+	assert.Equal(t, ProxyExpired, received[1].Status)
+}
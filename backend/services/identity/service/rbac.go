@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// AccessResolver kullanıcının sitedeki rol ve yetkilerini çözer (repository.RBACRepository)
+type AccessResolver interface {
+	ResolveAccess(ctx context.Context, userID, propertyID string) (*models.Access, error)
+}
+
+// RBACStore rol, yetki ve atama kayıtları (repository.RBACRepository)
+type RBACStore interface {
+	AccessResolver
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	GetRole(ctx context.Context, code string) (*models.Role, error)
+	SetRolePermissions(ctx context.Context, code string, permissions []string) error
+	ListAssignments(ctx context.Context, propertyID string) ([]models.RoleAssignment, error)
+	CreateAssignment(ctx context.Context, a *models.RoleAssignment) error
+	RevokeAssignment(ctx context.Context, propertyID, id, revokedBy string, now time.Time) (bool, error)
+}
+
+var _ RBACStore = (*repository.RBACRepository)(nil)
+
+var (
+	// ErrPropertyAccessDenied kullanıcının sitede hiçbir rolü yok
+	ErrPropertyAccessDenied = errors.New("bu sitede yetkiniz yok")
+	// ErrAssignmentNotFound sitede bu kimlikte geçerli rol ataması yok
+	ErrAssignmentNotFound = errors.New("rol ataması bulunamadı")
+)
+
+// PermissionAll tüm yetkiler; yalnızca platform rollerine verilebilir
+const PermissionAll = "*"
+
+// RBACService site bazlı rol ve yetki yönetimi
+type RBACService struct {
+	store RBACStore
+}
+
+// NewRBACService yeni servis oluşturur
+func NewRBACService(store RBACStore) *RBACService {
+	return &RBACService{store: store}
+}
+
+// GetAccess kullanıcının sitedeki güncel rolleri ve yetkileri (token yenilenmeden önceki değişiklikler dahil)
+func (s *RBACService) GetAccess(ctx context.Context, userID, propertyID string) (*models.Access, error) {
+	return s.store.ResolveAccess(ctx, userID, propertyID)
+}
+
+// ListPermissions yetki kataloğu
+func (s *RBACService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.store.ListPermissions(ctx)
+}
+
+// ListRoles roller ve yetkileri
+func (s *RBACService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.store.ListRoles(ctx)
+}
+
+// SetRolePermissions rolün yetkilerini değiştirir. Yetkiler katalogda olmalı; "*" yalnızca
+// platform rollerine verilebilir. Değişiklik kullanıcıların bir sonraki token yenilemesinde geçerli olur.
+func (s *RBACService) SetRolePermissions(ctx context.Context, code string, permissions []string) (*models.Role, error) {
+	role, err := s.store.GetRole(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.store.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err = validateRolePermissions(role, permissions, catalog)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetRolePermissions(ctx, code, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+	return role, nil
+}
+
+// ListAssignments sitedeki personel rol atamaları
+func (s *RBACService) ListAssignments(ctx context.Context, propertyID string) ([]models.RoleAssignment, error) {
+	return s.store.ListAssignments(ctx, propertyID)
+}
+
+// AssignRole kullanıcıya sitede personel rolü atar
+func (s *RBACService) AssignRole(ctx context.Context, propertyID, userID, roleCode, grantedBy string) (*models.RoleAssignment, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, errors.New("geçersiz kullanıcı")
+	}
+	a := &models.RoleAssignment{
+		PropertyID: propertyID,
+		UserID:     userID,
+		RoleCode:   roleCode,
+		GrantedBy:  grantedBy,
+	}
+	if err := s.store.CreateAssignment(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// RevokeRole sitedeki rol atamasını kaldırır
+func (s *RBACService) RevokeRole(ctx context.Context, propertyID, assignmentID, revokedBy string) error {
+	if _, err := uuid.Parse(assignmentID); err != nil {
+		return ErrAssignmentNotFound
+	}
+	ok, err := s.store.RevokeAssignment(ctx, propertyID, assignmentID, revokedBy, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// validateRolePermissions yetki listesini katalogla karşılaştırır, tekrarları ayıklar ve sıralar
+func validateRolePermissions(role *models.Role, permissions []string, catalog []models.Permission) ([]string, error) {
+	known := make(map[string]bool, len(catalog))
+	for _, p := range catalog {
+		known[p.Code] = true
+	}
+
+	seen := map[string]bool{}
+	result := []string{}
+	for _, p := range permissions {
+		if !known[p] {
+			return nil, fmt.Errorf("tanımsız yetki: %s", p)
+		}
+		if p == PermissionAll && role.Kind != "PLATFORM" {
+			return nil, fmt.Errorf("%s yetkisi yalnızca platform rollerine verilebilir", PermissionAll)
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAccess site bazlı rol çözümlemesinin bellek içi karşılığı
+type memoryAccess struct {
+	roles       map[string]map[string][]string // kullanıcı → site → roller
+	permissions map[string][]string            // rol → yetkiler
+}
+
+func newMemoryAccess() *memoryAccess {
+	return &memoryAccess{
+		roles: map[string]map[string][]string{},
+		permissions: map[string][]string{
+			"MANAGER": {"finance.assessment.create", "identity.role.manage"},
+			"TENANT":  {"finance.assessment.read", "finance.payment.create"},
+		},
+	}
+}
+
+func (m *memoryAccess) grant(userID, propertyID string, roles ...string) {
+	if m.roles[userID] == nil {
+		m.roles[userID] = map[string][]string{}
+	}
+	m.roles[userID][propertyID] = append(m.roles[userID][propertyID], roles...)
+}
+
+func (m *memoryAccess) ResolveAccess(ctx context.Context, userID, propertyID string) (*models.Access, error) {
+	access := &models.Access{PropertyID: propertyID, Roles: []string{}, Permissions: []string{}}
+	seen := map[string]bool{}
+	for _, role := range m.roles[userID][propertyID] {
+		access.Roles = append(access.Roles, role)
+		for _, p := range m.permissions[role] {
+			if !seen[p] {
+				seen[p] = true
+				access.Permissions = append(access.Permissions, p)
+			}
+		}
+	}
+	sort.Strings(access.Permissions)
+	return access, nil
+}
+
+func TestTokensCarryActivePropertyPermissions(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+	f.users.users["u1"].ActivePropertyID = "p1"
+	f.access.grant("u1", "p1", "MANAGER")
+	f.access.grant("u1", "p2", "TENANT")
+
+	tokens := loginForTest(t, f, ClientInfo{})
+	c, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, "p1", c.PropertyID)
+	assert.Equal(t, []string{"MANAGER"}, c.Roles)
+	assert.True(t, claims.HasPermission(c.Permissions, "finance.assessment.create"))
+
+	// Başka sitede kiracı: yönetim yetkisi taşınmaz
+	switched, err := f.svc.auth.SetActiveProperty(ctx, "u1", "p2", c.SessionID)
+	require.NoError(t, err)
+	c, err = claims.ParseAccess(switched.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, "p2", c.PropertyID)
+	assert.Equal(t, []string{"TENANT"}, c.Roles)
+	assert.False(t, claims.HasPermission(c.Permissions, "finance.assessment.create"))
+	assert.True(t, claims.HasPermission(c.Permissions, "finance.payment.create"))
+
+	_, err = f.svc.auth.SetActiveProperty(ctx, "u1", "p3", c.SessionID)
+	assert.ErrorIs(t, err, ErrPropertyAccessDenied)
+
+	// Yeni token oturumun bitişini aşmaz
+	sessionEnd := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	for _, rt := range f.sessions.tokens {
+		if rt.FamilyID == c.SessionID {
+			rt.ExpiresAt = sessionEnd
+		}
+	}
+	switched, err = f.svc.auth.SetActiveProperty(ctx, "u1", "p1", c.SessionID)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, switched.ExpiresIn, int64(5*60))
+	capped, err := claims.ParseAccess(switched.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.True(t, capped.ExpiresAt.Time.Equal(sessionEnd))
+
+	// Kapatılmış oturum site değiştirerek yeni token alamaz
+	require.NoError(t, f.svc.auth.RevokeSession(ctx, "u1", c.SessionID))
+	_, err = f.svc.auth.SetActiveProperty(ctx, "u1", "p1", c.SessionID)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+	_, err = f.svc.auth.SetActiveProperty(ctx, "u1", "p1", "")
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
+
+func TestValidateRolePermissions(t *testing.T) {
+	catalog := []models.Permission{{Code: "*"}, {Code: "visitor.read"}, {Code: "visitor.checkin"}}
+	security := &models.Role{Code: "SECURITY", Kind: "STAFF"}
+
+	got, err := validateRolePermissions(security, []string{"visitor.read", "visitor.checkin", "visitor.read"}, catalog)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"visitor.checkin", "visitor.read"}, got)
+
+	_, err = validateRolePermissions(security, []string{"visitor.delete"}, catalog)
+	assert.Error(t, err)
+	_, err = validateRolePermissions(security, []string{"*"}, catalog)
+	assert.Error(t, err)
+
+	got, err = validateRolePermissions(&models.Role{Code: "ADMIN", Kind: "PLATFORM"}, []string{"*"}, catalog)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, got)
+
+	// Boş liste rolün tüm yetkilerini kaldırır
+	got, err = validateRolePermissions(security, nil, catalog)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
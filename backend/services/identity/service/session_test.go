@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/claims"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySessions repository.SessionRepository davranışının bellek içi karşılığı
+type memorySessions struct {
+	mu     sync.Mutex
+	tokens map[string]*models.RefreshToken
+}
+
+func newMemorySessions() *memorySessions {
+	return &memorySessions{tokens: map[string]*models.RefreshToken{}}
+}
+
+func (m *memorySessions) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *t
+	m.tokens[t.ID] = &copied
+	return nil
+}
+
+func (m *memorySessions) GetRefreshToken(ctx context.Context, id string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[id]
+	if !ok {
+		return nil, repository.ErrRefreshTokenNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (m *memorySessions) RotateRefreshToken(ctx context.Context, oldID string, next *models.RefreshToken) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.tokens[oldID]
+	if !ok || old.RotatedAt != nil || old.RevokedAt != nil {
+		return false, nil
+	}
+	at := next.CreatedAt
+	old.RotatedAt = &at
+	copied := *next
+	copied.ParentID = oldID
+	m.tokens[next.ID] = &copied
+	return true, nil
+}
+
+func (m *memorySessions) RevokeSession(ctx context.Context, userID, familyID, reason string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	revoked := false
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt, t.RevokeReason = &now, reason
+			revoked = true
+		}
+	}
+	return revoked, nil
+}
+
+func (m *memorySessions) RevokeUserSessions(ctx context.Context, userID, reason string, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	families := map[string]bool{}
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt, t.RevokeReason = &now, reason
+			if t.RotatedAt == nil {
+				families[t.FamilyID] = true
+			}
+		}
+	}
+	return len(families), nil
+}
+
+func (m *memorySessions) GetActiveSession(ctx context.Context, userID, familyID string, now time.Time) (*models.Session, error) {
+	sessions, _ := m.ListSessions(ctx, userID, now)
+	for _, s := range sessions {
+		if s.ID == familyID {
+			return &s, nil
+		}
+	}
+	return nil, repository.ErrSessionNotActive
+}
+
+func (m *memorySessions) ListSessions(ctx context.Context, userID string, now time.Time) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := []models.Session{}
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.RotatedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(now) {
+			sessions = append(sessions, models.Session{ID: t.FamilyID, UserAgent: t.UserAgent,
+				IPAddress: t.IPAddress, LastUsedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+		}
+	}
+	return sessions, nil
+}
+
+func loginForTest(t *testing.T, f *otpFixture, client ClientInfo) *TokenPair {
+	t.Helper()
+	tokens, _, err := f.svc.auth.Login(context.Background(), testPhone, "Eski123!", client)
+	require.NoError(t, err)
+	return tokens
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	f := newOTPFixture(t)
+	auth := f.svc.auth
+	ctx := context.Background()
+
+	first := loginForTest(t, f, ClientInfo{UserAgent: "iPhone", IP: "10.0.0.1"})
+	second, err := auth.RefreshToken(ctx, first.RefreshToken, ClientInfo{UserAgent: "iPhone", IP: "10.0.0.2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	sessions, err := auth.ListSessions(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "10.0.0.2", sessions[0].IPAddress)
+	assert.Equal(t, "iPhone", sessions[0].UserAgent)
+
+	third, err := auth.RefreshToken(ctx, second.RefreshToken, ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, third.AccessToken)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	f := newOTPFixture(t)
+	auth := f.svc.auth
+	ctx := context.Background()
+
+	stolen := loginForTest(t, f, ClientInfo{})
+	other := loginForTest(t, f, ClientInfo{UserAgent: "Tablet"})
+	rotated, err := auth.RefreshToken(ctx, stolen.RefreshToken, ClientInfo{})
+	require.NoError(t, err)
+
+	_, err = auth.RefreshToken(ctx, stolen.RefreshToken, ClientInfo{})
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	// Aynı oturumun yeni tokenı da iptal edildi, diğer cihaz etkilenmedi
+	_, err = auth.RefreshToken(ctx, rotated.RefreshToken, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = auth.RefreshToken(ctx, other.RefreshToken, ClientInfo{})
+	assert.NoError(t, err)
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	f := newOTPFixture(t)
+	auth := f.svc.auth
+	ctx := context.Background()
+
+	tokens := loginForTest(t, f, ClientInfo{})
+	require.NoError(t, auth.Logout(ctx, tokens.RefreshToken))
+	// Tekrar çıkış hata vermez
+	require.NoError(t, auth.Logout(ctx, tokens.RefreshToken))
+
+	_, err := auth.RefreshToken(ctx, tokens.RefreshToken, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	assert.ErrorIs(t, auth.Logout(ctx, "bozuk-token"), ErrInvalidRefreshToken)
+}
+
+func TestLogoutAllAndRevokeSession(t *testing.T) {
+	f := newOTPFixture(t)
+	auth := f.svc.auth
+	ctx := context.Background()
+
+	phone := loginForTest(t, f, ClientInfo{UserAgent: "iPhone"})
+	_ = loginForTest(t, f, ClientInfo{UserAgent: "Chrome"})
+	_, err := auth.RefreshToken(ctx, phone.RefreshToken, ClientInfo{UserAgent: "iPhone"})
+	require.NoError(t, err)
+
+	sessions, err := auth.ListSessions(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	require.NoError(t, auth.RevokeSession(ctx, "u1", sessions[0].ID))
+	assert.ErrorIs(t, auth.RevokeSession(ctx, "u1", sessions[0].ID), ErrSessionNotFound)
+	assert.ErrorIs(t, auth.RevokeSession(ctx, "u1", "gecersiz"), ErrSessionNotFound)
+
+	count, err := auth.LogoutAll(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	sessions, err = auth.ListSessions(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestPasswordResetRevokesSessions(t *testing.T) {
+	f := newOTPFixture(t)
+	ctx := context.Background()
+
+	tokens := loginForTest(t, f, ClientInfo{})
+	_, err := f.svc.RequestOTP(ctx, testPhone, OTPPurposePasswordReset, "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, f.svc.ResetPassword(ctx, testPhone, f.lastCode(t), "Yeni1234!", "10.0.0.1"))
+
+	_, err = f.svc.auth.RefreshToken(ctx, tokens.RefreshToken, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAccessTokenContract(t *testing.T) {
+	f := newOTPFixture(t)
+	tokens := loginForTest(t, f, ClientInfo{})
+
+	c, err := claims.ParseAccess(tokens.AccessToken, f.svc.auth.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, "u1", c.UserID())
+	assert.NotEmpty(t, c.SessionID)
+
+	_, err = claims.ParseAccess(tokens.RefreshToken, f.svc.auth.Keys())
+	assert.Error(t, err)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/siteeksen/backend/pkg/importer"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxUnitSheetRows daire listesinde okunan en fazla satır
+const MaxUnitSheetRows = 5000
+
+// Daire listesi sütunları; başlıklar büyük/küçük harf, boşluk ve noktalama farkı gözetmeden
+// eşleştirilir
+const (
+	colBlock = iota
+	colFloor
+	colDoor
+	colShare
+	colGross
+	colNet
+	colType
+	colCommercial
+)
+
+// unitSheetHeaders şablondaki başlıklar (sütun sırasıyla)
+var unitSheetHeaders = []string{"Blok", "Kat", "Kapı No", "Arsa Payı", "Brüt m²", "Net m²", "Bölüm Tipi", "Ticari"}
+
+var unitSheetAliases = map[string]int{
+	"blok":      colBlock,
+	"kat":       colFloor,
+	"kapıno":    colDoor,
+	"kapı":      colDoor,
+	"daireno":   colDoor,
+	"arsapayı":  colShare,
+	"pay":       colShare,
+	"brütm2":    colGross,
+	"brütalan":  colGross,
+	"netm2":     colNet,
+	"netalan":   colNet,
+	"bölümtipi": colType,
+	"tip":       colType,
+	"ticari":    colCommercial,
+}
+
+// Bölüm tipi hücresinin Türkçe karşılıkları
+var unitTypeNames = map[string]string{
+	"daire":   models.UnitApartment,
+	"dükkan":  models.UnitShop,
+	"dükkân":  models.UnitShop,
+	"mağaza":  models.UnitShop,
+	"ofis":    models.UnitOffice,
+	"büro":    models.UnitOffice,
+	"otopark": models.UnitParking,
+}
+
+// ParseUnitSheet Excel daire listesinin ilk sayfasını okur. İlk dolu satır başlıktır; Kat,
+// Kapı No ve Arsa Payı sütunları zorunludur. Boş satırlar atlanır. Okunamayan hücreler
+// sayfadaki satır numaralarıyla tek UnitListError'da döner.
+func ParseUnitSheet(r io.Reader) ([]UnitRow, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Excel dosyası okunamadı", ErrSignupInvalid)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: Excel dosyasında sayfa yok", ErrSignupInvalid)
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: Excel dosyası okunamadı", ErrSignupInvalid)
+	}
+
+	header := -1
+	for i, row := range rows {
+		if !blankRow(row) {
+			header = i
+			break
+		}
+	}
+	if header == -1 {
+		return nil, fmt.Errorf("%w: daire listesi boş", ErrSignupInvalid)
+	}
+	columns := map[int]int{} // alan → sütun
+	for i, cell := range rows[header] {
+		if field, ok := unitSheetAliases[importer.HeaderKey(cell)]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	var missing []string
+	for _, field := range []int{colFloor, colDoor, colShare} {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, unitSheetHeaders[field])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: daire listesinde %s sütunu yok", ErrSignupInvalid, strings.Join(missing, ", "))
+	}
+
+	listErr := &UnitListError{}
+	var units []UnitRow
+	for i := header + 1; i < len(rows); i++ {
+		if blankRow(rows[i]) {
+			continue
+		}
+		if len(units) == MaxUnitSheetRows {
+			return nil, fmt.Errorf("%w: daire listesi en fazla %d satır olabilir", ErrSignupInvalid, MaxUnitSheetRows)
+		}
+		rowNum := i + 1
+		cell := func(field int) string {
+			col, ok := columns[field]
+			if !ok || col >= len(rows[i]) {
+				return ""
+			}
+			return strings.TrimSpace(rows[i][col])
+		}
+
+		u := UnitRow{Row: rowNum}
+		u.Block = cell(colBlock)
+		u.DoorNumber = cell(colDoor)
+		floor, ok := importer.ParseFloor(cell(colFloor))
+		if !ok {
+			listErr.add(rowNum, "kat sayı olmalı: %q", cell(colFloor))
+		}
+		u.Floor = floor
+		for _, f := range []struct {
+			field int
+			name  string
+			dest  *float64
+		}{
+			{colShare, "arsa payı", &u.ShareRatio},
+			{colGross, "brüt alan", &u.GrossAreaM2},
+			{colNet, "net alan", &u.NetAreaM2},
+		} {
+			value := cell(f.field)
+			if value == "" {
+				continue
+			}
+			n, ok := importer.ParseDecimal(value)
+			if !ok {
+				listErr.add(rowNum, "%s sayı olmalı: %q", f.name, value)
+			}
+			*f.dest = n
+		}
+		unitType := cell(colType)
+		if code, ok := unitTypeNames[strings.ToLowerSpecial(unicode.TurkishCase, unitType)]; ok {
+			unitType = code
+		}
+		u.UnitType = unitType
+		switch strings.ToLowerSpecial(unicode.TurkishCase, cell(colCommercial)) {
+		case "evet", "e", "x", "1", "true":
+			u.IsCommercial = true
+		}
+		units = append(units, u)
+	}
+	if len(listErr.Rows) > 0 {
+		return nil, listErr
+	}
+	return units, nil
+}
+
+// UnitSheetTemplate kayıtta yüklenecek daire listesinin boş şablonu (örnek satırla)
+func UnitSheetTemplate() ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Daireler"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+	for i, h := range unitSheetHeaders {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, h)
+	}
+	example := []any{"A", 0, "1", 12.5, 110, 95, "Daire", "Hayır"}
+	for i, v := range example {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 2)
+		f.SetCellValue(sheet, cell, v)
+	}
+	style, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err == nil {
+		f.SetRowStyle(sheet, 1, 1, style)
+	}
+	f.SetColWidth(sheet, "A", "H", 14)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func blankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+)
+
+// TenantAdminStore kiracı yöneticileri (repository.UserRepository)
+type TenantAdminStore interface {
+	ListTenantAdmins(ctx context.Context, tenantID string) ([]models.User, error)
+}
+
+var _ TenantAdminStore = (*repository.UserRepository)(nil)
+
+var _ tenant.WarningNotifier = (*UsageWarningNotifier)(nil)
+
+// UsageWarningNotifier plan limitine yaklaşan kiracının yöneticilerine SMS gönderir
+type UsageWarningNotifier struct {
+	store TenantAdminStore
+	sms   *sms.Service
+}
+
+// NewUsageWarningNotifier yeni bildirici oluşturur
+func NewUsageWarningNotifier(store TenantAdminStore, smsService *sms.Service) *UsageWarningNotifier {
+	return &UsageWarningNotifier{store: store, sms: smsService}
+}
+
+// NotifyLimitWarning telefonu kayıtlı her yöneticiye uyarı gönderir. Uyarı platform
+// adına gönderildiğinden kiracının SMS kullanımına yazılmaz.
+func (n *UsageWarningNotifier) NotifyLimitWarning(ctx context.Context, w tenant.LimitWarning) error {
+	return notifyTenantAdmins(ctx, n.store, n.sms, w.TenantID, limitWarningMessage(w))
+}
+
+// notifyTenantAdmins mesajı kiracının telefonu kayıtlı her yöneticisine gönderir; hiçbirine
+// gönderilemezse hata döner
+func notifyTenantAdmins(ctx context.Context, store TenantAdminStore, smsService *sms.Service, tenantID, message string) error {
+	admins, err := store.ListTenantAdmins(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	sent := 0
+	for _, admin := range admins {
+		if admin.Phone == "" {
+			continue
+		}
+		resp, err := smsService.Send(ctx, &sms.SendRequest{To: admin.Phone, Message: message})
+		if err != nil || resp == nil || !resp.Success {
+			log.Printf("Kiracı yöneticisine SMS gönderilemedi (%s): %v", maskPhone(admin.Phone), err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("kiracı %s için bildirim gönderilecek yönetici yok", tenantID)
+	}
+	return nil
+}
+
+func limitWarningMessage(w tenant.LimitWarning) string {
+	label := "kullanıcı"
+	if w.Resource == tenant.ResourceUnits {
+		label = "daire"
+	}
+	return fmt.Sprintf("SiteEksen: %s için %s planında %s sayısı %d/%d oldu (%%%d). Limit dolduğunda yeni %s eklenemez; planınızı yükseltebilirsiniz.",
+		w.TenantName, w.Plan, label, w.Current, w.Limit, w.Current*100/w.Limit, label)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/siteeksen/backend/pkg/integrations/sms"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryTenantAdmins map[string][]models.User
+
+func (s memoryTenantAdmins) ListTenantAdmins(ctx context.Context, tenantID string) ([]models.User, error) {
+	return s[tenantID], nil
+}
+
+func TestUsageWarningNotifier(t *testing.T) {
+	fake := sms.NewFakeProvider()
+	smsService := sms.NewService("fake")
+	smsService.RegisterProvider("fake", fake)
+	var metered []string
+	smsService.OnSent(func(ctx context.Context, req *sms.SendRequest) { metered = append(metered, req.PropertyID) })
+
+	admins := memoryTenantAdmins{"t-mavi": {
+		{ID: "m1", Phone: "+905321112233"},
+		{ID: "m2"}, // telefonsuz yönetici atlanır
+	}}
+	notifier := NewUsageWarningNotifier(admins, smsService)
+	w := tenant.LimitWarning{TenantID: "t-mavi", TenantName: "Mavi Kent", Plan: "starter",
+		Resource: tenant.ResourceUnits, Limit: 50, Current: 40}
+
+	require.NoError(t, notifier.NotifyLimitWarning(context.Background(), w))
+	require.Len(t, fake.Messages(), 1)
+	msg, ok := fake.LastMessage("+905321112233")
+	require.True(t, ok)
+	assert.Contains(t, msg.Message, "Mavi Kent")
+	assert.Contains(t, msg.Message, "daire sayısı 40/50 oldu (%80)")
+	assert.Equal(t, []string{""}, metered, "platform uyarısı kiracı kullanımına yazılmamalı")
+
+	w.TenantID = "t-yok"
+	assert.Error(t, notifier.NotifyLimitWarning(context.Background(), w))
+}
+
+func TestBillingFailureNotifier(t *testing.T) {
+	fake := sms.NewFakeProvider()
+	smsService := sms.NewService("fake")
+	smsService.RegisterProvider("fake", fake)
+
+	admins := memoryTenantAdmins{"t-mavi": {{ID: "m1", Phone: "+905321112233"}}}
+	notifier := NewBillingFailureNotifier(admins, smsService)
+	f := tenant.PaymentFailure{TenantID: "t-mavi", TenantName: "Mavi Kent", InvoiceID: "inv-1",
+		AmountKurus: 59900, Reason: "Yetersiz bakiye", NextRetryAt: time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)}
+
+	require.NoError(t, notifier.NotifyPaymentFailed(context.Background(), f))
+	msg, ok := fake.LastMessage("+905321112233")
+	require.True(t, ok)
+	assert.Contains(t, msg.Message, "599,00 TL")
+	assert.Contains(t, msg.Message, "16.03.2026 tarihinde yeniden denenecek")
+
+	f.Suspended = true
+	require.NoError(t, notifier.NotifyPaymentFailed(context.Background(), f))
+	msg, _ = fake.LastMessage("+905321112233")
+	assert.Contains(t, msg.Message, "askıya alındı")
+
+	f.TenantID = "t-yok"
+	assert.Error(t, notifier.NotifyPaymentFailed(context.Background(), f))
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/pkg/server"
+	"github.com/siteeksen/backend/pkg/tenant"
 )
 
 // AI-Powered Meeting Wizard Service
@@ -33,15 +40,30 @@ type Meeting struct {
 }
 
 type Attendee struct {
-	ID         string  `json:"id"`
-	MeetingID  string  `json:"meeting_id"`
-	ResidentID string  `json:"resident_id,omitempty"`
-	Name       string  `json:"name"`
-	UnitNumber string  `json:"unit_number,omitempty"`
-	Role       string  `json:"role,omitempty"`  // CHAIR, SECRETARY, MEMBER
-	Votes      float64 `json:"votes,omitempty"` // m² bazlı oy
-	Attended   bool    `json:"attended"`
-	ProxyFor   string  `json:"proxy_for,omitempty"`
+	ID          string     `json:"id"`
+	MeetingID   string     `json:"meeting_id"`
+	ResidentID  string     `json:"resident_id,omitempty"`
+	Name        string     `json:"name"`
+	UnitID      string     `json:"unit_id,omitempty"`
+	UnitNumber  string     `json:"unit_number,omitempty"`
+	Role        string     `json:"role,omitempty"`  // CHAIR, SECRETARY, MEMBER
+	Votes       float64    `json:"votes,omitempty"` // Arsa payı bazlı oy
+	Attended    bool       `json:"attended"`
+	ProxyFor    string     `json:"proxy_for,omitempty"` // Vekaleten temsil edilen kat maliki
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+}
+
+// AttendanceRequest genel kurul yoklaması. unit_ids boşsa kullanıcının temsil edebileceği
+// tüm daireler (kendi daireleri ve vekaleten temsil ettikleri) yoklamaya yazılır.
+type AttendanceRequest struct {
+	UnitIDs []string `json:"unit_ids"`
+}
+
+// MeetingVoteRequest gündem maddesi oyu
+type MeetingVoteRequest struct {
+	AgendaOrder int      `json:"agenda_order" binding:"required"`
+	Choice      string   `json:"choice" binding:"required"` // FOR, AGAINST, ABSTAIN
+	UnitIDs     []string `json:"unit_ids"`
 }
 
 type MeetingTranscript struct {
@@ -95,6 +117,21 @@ type ActionItem struct {
 }
 
 func main() {
+	// Veritabanı bağlantısı
+	pool, err := database.Connect(database.NewConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Veritabanı bağlantısı başarısız: %v", err)
+	}
+	defer database.Close()
+	store := newMeetingStore(pool)
+
+	// Kapanış sinyalinde sunucu ve arka plan işleri durdurulur
+	ctx, stop := server.Context()
+	defer stop()
+
+	// Kiracı askıya alma / yeniden etkinleştirme LISTEN/NOTIFY ile önbellekten düşer
+	go tenant.GetManager().Listen(ctx, pool)
+
 	r := gin.Default()
 
 	r.GET("/health", func(c *gin.Context) {
@@ -115,9 +152,13 @@ func main() {
 			meetings.POST("/:id/end", endMeeting)
 
 			// Attendees
-			meetings.GET("/:id/attendees", getAttendees)
+			meetings.GET("/:id/attendees", middleware.AuthMiddleware(), tenant.DatabaseScope(), getAttendees(store))
 			meetings.POST("/:id/attendees", addAttendee)
-			meetings.POST("/:id/attendance", recordAttendance)
+			meetings.POST("/:id/attendance", middleware.AuthMiddleware(), tenant.DatabaseScope(), middleware.RequirePermission("meeting.vote"), recordAttendance(store))
+
+			// Gündem oylaması
+			meetings.POST("/:id/votes", middleware.AuthMiddleware(), tenant.DatabaseScope(), middleware.RequirePermission("meeting.vote"), castMeetingVote(store))
+			meetings.GET("/:id/votes/results", middleware.AuthMiddleware(), tenant.DatabaseScope(), getMeetingVoteResults(store))
 
 			// AI Features
 			meetings.POST("/:id/transcribe", transcribeMeeting)
@@ -139,7 +180,9 @@ func main() {
 		port = "8103"
 	}
 	log.Printf("Meeting Wizard Service starting on port %s", port)
-	r.Run(":" + port)
+	if err := server.Run(ctx, ":"+port, r); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func listMeetings(c *gin.Context) {
@@ -181,20 +224,256 @@ func endMeeting(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "status": "COMPLETED", "ended_at": time.Now()})
 }
 
-func getAttendees(c *gin.Context) {
-	attendees := []Attendee{
-		{ID: uuid.New().String(), MeetingID: c.Param("id"), Name: "Ali Veli", UnitNumber: "D.101", Role: "CHAIR", Votes: 95.5, Attended: true},
-		{ID: uuid.New().String(), MeetingID: c.Param("id"), Name: "Ayşe Kaya", UnitNumber: "D.205", Role: "SECRETARY", Votes: 120.0, Attended: true},
+// getAttendees hazirun: yoklaması alınan daireler ve temsil edenler, toplantı yeter
+// sayısıyla (kat maliki sayısı ve arsa payı çoğunluğu)
+func getAttendees(store *meetingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		attendees, err := store.attendees(ctx, m.ID)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		eligibleUnits, eligibleShare, err := store.eligible(ctx, m.PropertyID)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+
+		attendedShare := 0.0
+		proxies := 0
+		for _, a := range attendees {
+			attendedShare += a.Votes
+			if a.ProxyFor != "" {
+				proxies++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"attendees":      attendees,
+			"attended_units": len(attendees),
+			"proxy_units":    proxies,
+			"attended_share": attendedShare,
+			"eligible_units": eligibleUnits,
+			"eligible_share": eligibleShare,
+			"quorum":         majority(len(attendees), eligibleUnits, attendedShare, eligibleShare),
+		})
 	}
-	c.JSON(http.StatusOK, gin.H{"attendees": attendees})
 }
 
 func addAttendee(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"id": uuid.New().String(), "message": "Katılımcı eklendi"})
 }
 
-func recordAttendance(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"meeting_id": c.Param("id"), "attendance_recorded": true})
+// recordAttendance genel kurul günü kullanıcının kendi daireleri ve VOTE kapsamlı vekaletle
+// temsil ettiği daireler için yoklama alır. Yoklaması alınmış daireler atlanır.
+func recordAttendance(store *meetingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AttendanceRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		ctx := c.Request.Context()
+		m, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		if m.MeetingType != "GENERAL_ASSEMBLY" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Daire bazlı yoklama yalnızca genel kurulda alınır"})
+			return
+		}
+		if !meetingDay(m, time.Now()) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Yoklama yalnızca toplantı günü alınır"})
+			return
+		}
+
+		units, err := store.votingUnits(ctx, m, c.GetString("user_id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		units = selectUnits(units, req.UnitIDs)
+		if len(units) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Bu toplantıda temsil edebileceğiniz daire yok"})
+			return
+		}
+
+		checked, skipped, err := store.checkIn(ctx, m, c.GetString("user_id"), units)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"meeting_id":          m.ID,
+			"attendance_recorded": len(checked) > 0,
+			"checked_in_units":    checked,
+			"skipped_units":       skipped,
+		})
+	}
+}
+
+// castMeetingVote gündem maddesi için oy kullanır. Yalnızca yoklaması alınmış daireler oy
+// kullanabilir; her dairenin oyu madde başına bir kez sayılır ve arsa payıyla ağırlıklandırılır.
+func castMeetingVote(store *meetingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MeetingVoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !validChoice(req.Choice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz oy seçeneği"})
+			return
+		}
+		ctx := c.Request.Context()
+		m, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		if !hasAgendaItem(m, req.AgendaOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Gündemde bu madde yok"})
+			return
+		}
+		if !meetingDay(m, time.Now()) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Oylama yalnızca toplantı günü yapılır"})
+			return
+		}
+
+		units, err := store.votingUnits(ctx, m, c.GetString("user_id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		units = selectUnits(units, req.UnitIDs)
+		checkedIn, err := store.checkedIn(ctx, m.ID, units)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		var present []proxy.Unit
+		for _, u := range units {
+			if checkedIn[u.UnitID] {
+				present = append(present, u)
+			}
+		}
+		if len(present) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Yoklaması alınmış temsil ettiğiniz daire yok"})
+			return
+		}
+
+		voted, skipped, err := store.vote(ctx, m, req.AgendaOrder, c.GetString("user_id"), req.Choice, present)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		if len(voted) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Seçilen daireler bu maddede oy kullanmış", "skipped_units": skipped})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"meeting_id":    m.ID,
+			"agenda_order":  req.AgendaOrder,
+			"choice":        req.Choice,
+			"voted_units":   voted,
+			"skipped_units": skipped,
+		})
+	}
+}
+
+// getMeetingVoteResults gündem maddesinin sonucu (?agenda_order=N). Oylar daire sayısı ve
+// arsa payıyla ayrı ayrı sayılır; vekaletle kullanılanlar proxy_votes olarak gösterilir.
+// Karar, kullanılan oyların hem sayı hem arsa payı çoğunluğuyla alınır.
+func getMeetingVoteResults(store *meetingStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, err := strconv.Atoi(c.Query("agenda_order"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "agenda_order gerekli"})
+			return
+		}
+		ctx := c.Request.Context()
+		m, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+		if !hasAgendaItem(m, order) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Gündemde bu madde yok"})
+			return
+		}
+		ballots, err := store.ballots(ctx, m.ID, order)
+		if err != nil {
+			respondMeetingError(c, err)
+			return
+		}
+
+		tally := proxy.Count(ballots, voteChoices)
+		results := make([]gin.H, len(tally.Results))
+		for i, r := range tally.Results {
+			results[i] = gin.H{
+				"choice":           r.Choice,
+				"votes":            r.Units,
+				"proxy_votes":      r.ProxyUnits,
+				"share":            r.Weight,
+				"percentage":       r.Percentage,
+				"share_percentage": r.WeightPercentage,
+			}
+		}
+		votesFor := tally.Results[0]
+		c.JSON(http.StatusOK, gin.H{
+			"meeting_id":   m.ID,
+			"agenda_order": order,
+			"results":      results,
+			"total_votes":  tally.Units,
+			"proxy_votes":  tally.ProxyUnits,
+			"total_share":  tally.TotalWeight,
+			"approved":     majority(votesFor.Units, tally.Units, votesFor.Weight, tally.TotalWeight),
+		})
+	}
+}
+
+func validChoice(choice string) bool {
+	for _, c := range voteChoices {
+		if c == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// selectUnits istenen daireleri süzer; istek boşsa tüm daireler döner
+func selectUnits(units []proxy.Unit, unitIDs []string) []proxy.Unit {
+	if len(unitIDs) == 0 {
+		return units
+	}
+	requested := map[string]bool{}
+	for _, id := range unitIDs {
+		requested[id] = true
+	}
+	var selected []proxy.Unit
+	for _, u := range units {
+		if requested[u.UnitID] {
+			selected = append(selected, u)
+		}
+	}
+	return selected
+}
+
+func respondMeetingError(c *gin.Context, err error) {
+	if errors.Is(err, errMeetingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Toplantı işlemi başarısız: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Toplantı işlemi başarısız"})
 }
 
 func transcribeMeeting(c *gin.Context) {
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/proxy"
+)
+
+var errMeetingNotFound = errors.New("toplantı bulunamadı")
+
+// Gündem maddesi oy seçenekleri
+var voteChoices = []string{"FOR", "AGAINST", "ABSTAIN"}
+
+// meetingRecord oy ve yoklama kuralları için gereken toplantı bilgileri
+type meetingRecord struct {
+	ID          string
+	PropertyID  string
+	MeetingType string
+	MeetingDate time.Time
+	Agenda      []int // Gündem maddelerinin sıra numaraları
+}
+
+// meetingStore genel kurul yoklaması ve gündem oyları. Yoklama ve oylar daire başınadır;
+// daireyi kat maliki veya VOTE kapsamlı vekili temsil eder, oy ağırlığı arsa payıdır.
+type meetingStore struct {
+	pool *pgxpool.Pool
+}
+
+func newMeetingStore(pool *pgxpool.Pool) *meetingStore {
+	return &meetingStore{pool: pool}
+}
+
+// db toplantı sorgularının bağlantısı (isteğin kiracı kapsamı, RLS)
+func (s *meetingStore) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, s.pool)
+}
+
+// get sitedeki toplantıyı getirir
+func (s *meetingStore) get(ctx context.Context, propertyID, id string) (*meetingRecord, error) {
+	m := &meetingRecord{}
+	var agenda []byte
+	err := s.db(ctx).QueryRow(ctx, `
+		SELECT id::text, property_id::text, meeting_type, meeting_date, COALESCE(agenda, '[]'::jsonb)
+		FROM meetings
+		WHERE id::text = $2 AND property_id::text = $1
+	`, propertyID, id).Scan(&m.ID, &m.PropertyID, &m.MeetingType, &m.MeetingDate, &agenda)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errMeetingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		Order int `json:"order"`
+	}
+	if err := json.Unmarshal(agenda, &items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		m.Agenda = append(m.Agenda, item.Order)
+	}
+	return m, nil
+}
+
+// votingUnits kullanıcının genel kurulda temsil edebileceği daireler (kiracılar hariç)
+func (s *meetingStore) votingUnits(ctx context.Context, m *meetingRecord, userID string) ([]proxy.Unit, error) {
+	return proxy.VotingUnits(ctx, s.db(ctx), userID, m.PropertyID, false)
+}
+
+// attendees toplantıya katılan daireler (hazirun), temsil edenler ve vekaleti verenlerle
+func (s *meetingStore) attendees(ctx context.Context, meetingID string) ([]Attendee, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT a.id::text, a.meeting_id::text, a.attendee_id::text, us.first_name || ' ' || us.last_name,
+			   a.unit_id::text, COALESCE(u.block || '-', '') || u.door_number, a.share_ratio,
+			   COALESCE(g.first_name || ' ' || g.last_name, ''), a.checked_in_at
+		FROM meeting_attendance a
+		JOIN users us ON us.id = a.attendee_id
+		JOIN units u ON u.id = a.unit_id
+		LEFT JOIN users g ON g.id = a.on_behalf_of
+		WHERE a.meeting_id::text = $1
+		ORDER BY u.block, u.door_number
+	`, meetingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attendees := []Attendee{}
+	for rows.Next() {
+		a := Attendee{Attended: true}
+		if err := rows.Scan(&a.ID, &a.MeetingID, &a.ResidentID, &a.Name, &a.UnitID, &a.UnitNumber, &a.Votes,
+			&a.ProxyFor, &a.CheckedInAt); err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, a)
+	}
+	return attendees, rows.Err()
+}
+
+// eligible sitede kat maliki olan daire sayısı ve arsa payları toplamı (yeter sayı için)
+func (s *meetingStore) eligible(ctx context.Context, propertyID string) (units int, share float64, err error) {
+	err = s.db(ctx).QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(u.share_ratio), 0)
+		FROM units u
+		WHERE u.property_id::text = $1 AND EXISTS (
+			SELECT 1 FROM resident_units ru
+			WHERE ru.unit_id = u.id AND ru.role = 'OWNER' AND ru.is_active = true
+			  AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+		)
+	`, propertyID).Scan(&units, &share)
+	return units, share, err
+}
+
+// checkIn daireleri toplantıya katılmış olarak kaydeder. Yoklaması alınmış daireler atlanır;
+// toplantının katılımcı sayısı güncellenir.
+func (s *meetingStore) checkIn(ctx context.Context, m *meetingRecord, attendeeID string, units []proxy.Unit) (checked, skipped []proxy.Unit, err error) {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, u := range units {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO meeting_attendance (meeting_id, unit_id, attendee_id, proxy_grant_id, on_behalf_of, share_ratio)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, $6)
+			ON CONFLICT (meeting_id, unit_id) DO NOTHING
+		`, m.ID, u.UnitID, attendeeID, u.GrantID, u.OnBehalfOf, u.ShareRatio)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			skipped = append(skipped, u)
+			continue
+		}
+		checked = append(checked, u)
+	}
+
+	if len(checked) > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE meetings
+			SET total_attended = (SELECT COUNT(*) FROM meeting_attendance WHERE meeting_id = $1), updated_at = NOW()
+			WHERE id = $1
+		`, m.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return checked, skipped, nil
+}
+
+// checkedIn verilen dairelerden yoklaması alınmış olanlar
+func (s *meetingStore) checkedIn(ctx context.Context, meetingID string, units []proxy.Unit) (map[string]bool, error) {
+	ids := make([]string, len(units))
+	for i, u := range units {
+		ids[i] = u.UnitID
+	}
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT unit_id::text FROM meeting_attendance WHERE meeting_id::text = $1 AND unit_id::text = ANY($2)
+	`, meetingID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checked := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		checked[id] = true
+	}
+	return checked, rows.Err()
+}
+
+// vote gündem maddesi için dairelerin oyunu kaydeder; oyu kullanılmış daireler atlanır
+func (s *meetingStore) vote(ctx context.Context, m *meetingRecord, agendaOrder int, voterID, choice string, units []proxy.Unit) (voted, skipped []proxy.Unit, err error) {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, u := range units {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO meeting_votes (meeting_id, agenda_order, unit_id, voter_id, proxy_grant_id, on_behalf_of, choice, share_ratio)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid, $7, $8)
+			ON CONFLICT (meeting_id, agenda_order, unit_id) DO NOTHING
+		`, m.ID, agendaOrder, u.UnitID, voterID, u.GrantID, u.OnBehalfOf, choice, u.ShareRatio)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			skipped = append(skipped, u)
+			continue
+		}
+		voted = append(voted, u)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return voted, skipped, nil
+}
+
+// ballots gündem maddesinin oyları
+func (s *meetingStore) ballots(ctx context.Context, meetingID string, agendaOrder int) ([]proxy.Ballot, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT unit_id::text, choice, share_ratio, proxy_grant_id IS NOT NULL
+		FROM meeting_votes
+		WHERE meeting_id::text = $1 AND agenda_order = $2
+		ORDER BY voted_at
+	`, meetingID, agendaOrder)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ballots []proxy.Ballot
+	for rows.Next() {
+		var b proxy.Ballot
+		if err := rows.Scan(&b.UnitID, &b.Choice, &b.Weight, &b.ViaProxy); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, b)
+	}
+	return ballots, rows.Err()
+}
+
+// meetingDay bugün toplantı günü mü; yoklama ve oylar yalnızca toplantı günü alınır
+func meetingDay(m *meetingRecord, now time.Time) bool {
+	return m.MeetingDate.Format("2006-01-02") == now.Format("2006-01-02")
+}
+
+// hasAgendaItem gündemde bu sıra numaralı madde var mı
+func hasAgendaItem(m *meetingRecord, order int) bool {
+	for _, o := range m.Agenda {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+// majority sayı ve arsa payı çoğunluğu (KMK md. 29-30): birimlerin yarıdan fazlası ve
+// ağırlığın yarıdan fazlası
+func majority(units, totalUnits int, weight, totalWeight float64) bool {
+	return totalUnits > 0 && units*2 > totalUnits && weight*2 > totalWeight
+}
@@ -1,20 +1,23 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/proxy"
 )
 
-// NotificationRequest - Bildirim isteği
+// NotificationRequest - Bildirim isteği. Alıcılar kullanıcı olarak (recipients) veya daire
+// olarak (unit_ids) verilir; daireye giden bildirim sakinlere ve NOTIFICATIONS kapsamlı
+// vekillere iletilir.
 type NotificationRequest struct {
 	Type       string            `json:"type" binding:"required"` // PUSH, SMS, EMAIL
-	Recipients []string          `json:"recipients" binding:"required"`
+	Recipients []string          `json:"recipients"`
+	UnitIDs    []string          `json:"unit_ids,omitempty"`
 	Title      string            `json:"title"`
 	Body       string            `json:"body" binding:"required"`
 	Data       map[string]string `json:"data,omitempty"`
@@ -35,6 +38,13 @@ type NotificationLog struct {
 }
 
 func main() {
+	// Veritabanı bağlantısı (daire alıcıları için)
+	pool, err := database.Connect(database.NewConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Veritabanı bağlantısı başarısız: %v", err)
+	}
+	defer database.Close()
+
 	r := gin.Default()
 
 	// Health check
@@ -43,7 +53,7 @@ func main() {
 	})
 
 	// Notification endpoints
-	r.POST("/api/v1/notifications/send", sendNotification)
+	r.POST("/api/v1/notifications/send", sendNotification(pool))
 	r.POST("/api/v1/notifications/send-bulk", sendBulkNotification)
 	r.GET("/api/v1/notifications/logs", getNotificationLogs)
 	r.GET("/api/v1/notifications/stats", getNotificationStats)
@@ -72,13 +82,46 @@ func main() {
 	}
 }
 
-func sendNotification(c *gin.Context) {
-	var req NotificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+func sendNotification(q database.Querier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req NotificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.UnitIDs) > 0 {
+			unitRecipients, err := proxy.UnitRecipients(c.Request.Context(), q, req.UnitIDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Daire alıcıları alınamadı"})
+				return
+			}
+			req.Recipients = mergeRecipients(req.Recipients, unitRecipients)
+		}
+		if len(req.Recipients) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Alıcı bulunamadı"})
+			return
+		}
+		deliver(c, &req)
 	}
+}
+
+// mergeRecipients alıcı listelerini tekrarsız birleştirir
+func mergeRecipients(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var merged []string
+	for _, list := range lists {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				merged = append(merged, id)
+			}
+		}
+	}
+	return merged
+}
 
+// deliver bildirimi tipine göre alıcılara gönderir
+func deliver(c *gin.Context, req *NotificationRequest) {
 	// Bildirim tipine göre gönderim
 	switch req.Type {
 	case "PUSH":
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/middleware"
 )
 
 // =====================================================
@@ -98,11 +99,14 @@ func main() {
 		})
 	})
 
+	// Kargo işlemleri aktif sitedeki package.manage yetkisiyle açılır
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.AuthMiddleware())
 	{
 		v1.GET("/carriers", getCarriers)
 
 		packages := v1.Group("/packages")
+		packages.Use(middleware.RequirePermission("package.manage"))
 		{
 			packages.GET("", listPackages)
 			packages.GET("/pending", getPendingPackages)
@@ -117,7 +121,7 @@ func main() {
 		}
 
 		// Unit packages
-		v1.GET("/units/:unit_id/packages", getUnitPackages)
+		v1.GET("/units/:unit_id/packages", middleware.RequirePermission("package.manage"), getUnitPackages)
 	}
 
 	port := os.Getenv("PORT")
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/middleware"
 )
 
 type PatrolRoute struct {
@@ -66,7 +67,9 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy", "service": "patrol"})
 	})
 
+	// Devriye rotaları ve turları aktif sitedeki patrol.manage yetkisiyle açılır
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.AuthMiddleware(), middleware.RequirePermission("patrol.manage"))
 	{
 		routes := v1.Group("/patrol-routes")
 		{
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/middleware"
 )
 
 // =====================================================
@@ -102,39 +103,42 @@ func main() {
 		})
 	})
 
+	// Tesis ve takvim görüntüleme tüm oturumlara açık; rezervasyon yapma sakinlerin
+	// reservation.create, onay ve tesis yönetimi reservation.manage yetkisiyle
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.AuthMiddleware())
 	{
 		// Facilities
 		facilities := v1.Group("/facilities")
 		{
 			facilities.GET("", listFacilities)
 			facilities.GET("/:id", getFacility)
-			facilities.POST("", createFacility)
-			facilities.PUT("/:id", updateFacility)
-			facilities.DELETE("/:id", deleteFacility)
+			facilities.POST("", middleware.RequirePermission("reservation.manage"), createFacility)
+			facilities.PUT("/:id", middleware.RequirePermission("reservation.manage"), updateFacility)
+			facilities.DELETE("/:id", middleware.RequirePermission("reservation.manage"), deleteFacility)
 			facilities.GET("/:id/availability", getFacilityAvailability)
-			facilities.GET("/:id/reservations", getFacilityReservations)
-			facilities.POST("/:id/maintenance", setMaintenanceMode)
+			facilities.GET("/:id/reservations", middleware.RequirePermission("reservation.manage"), getFacilityReservations)
+			facilities.POST("/:id/maintenance", middleware.RequirePermission("reservation.manage"), setMaintenanceMode)
 		}
 
 		// Reservations
 		reservations := v1.Group("/reservations")
 		{
-			reservations.GET("", listReservations)
-			reservations.GET("/pending", getPendingReservations)
-			reservations.GET("/today", getTodayReservations)
+			reservations.GET("", middleware.RequirePermission("reservation.manage"), listReservations)
+			reservations.GET("/pending", middleware.RequirePermission("reservation.manage"), getPendingReservations)
+			reservations.GET("/today", middleware.RequirePermission("reservation.manage"), getTodayReservations)
 			reservations.GET("/calendar", getCalendarView)
 			reservations.GET("/:id", getReservation)
-			reservations.POST("", createReservation)
-			reservations.PUT("/:id", updateReservation)
-			reservations.DELETE("/:id", cancelReservation)
-			reservations.POST("/:id/review", reviewReservation)
-			reservations.POST("/:id/complete", completeReservation)
+			reservations.POST("", middleware.RequirePermission("reservation.create"), createReservation)
+			reservations.PUT("/:id", middleware.RequirePermission("reservation.create"), updateReservation)
+			reservations.DELETE("/:id", middleware.RequirePermission("reservation.create"), cancelReservation)
+			reservations.POST("/:id/review", middleware.RequirePermission("reservation.manage"), reviewReservation)
+			reservations.POST("/:id/complete", middleware.RequirePermission("reservation.manage"), completeReservation)
 		}
 
 		// Unit reservations
-		v1.GET("/units/:unit_id/reservations", getUnitReservations)
-		v1.GET("/residents/:resident_id/reservations", getResidentReservations)
+		v1.GET("/units/:unit_id/reservations", middleware.RequirePermission("reservation.create"), getUnitReservations)
+		v1.GET("/residents/:resident_id/reservations", middleware.RequirePermission("reservation.create"), getResidentReservations)
 	}
 
 	port := os.Getenv("PORT")
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +9,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/middleware"
+	"github.com/siteeksen/backend/pkg/proxy"
+	"github.com/siteeksen/backend/pkg/server"
+	"github.com/siteeksen/backend/pkg/tenant"
 )
 
 // =====================================================
@@ -48,16 +54,18 @@ type SurveyOption struct {
 }
 
 type SurveyVote struct {
-	ID         string    `json:"id"`
-	SurveyID   string    `json:"survey_id"`
-	OptionID   string    `json:"option_id"`
-	VoterID    string    `json:"voter_id"`
-	VoterName  string    `json:"voter_name,omitempty"`
-	UnitID     string    `json:"unit_id,omitempty"`
-	UnitNumber string    `json:"unit_number,omitempty"`
-	Weight     float64   `json:"weight"`
-	Comment    string    `json:"comment,omitempty"`
-	VotedAt    time.Time `json:"voted_at"`
+	ID           string    `json:"id"`
+	SurveyID     string    `json:"survey_id"`
+	OptionID     string    `json:"option_id"`
+	VoterID      string    `json:"voter_id"`
+	VoterName    string    `json:"voter_name,omitempty"`
+	UnitID       string    `json:"unit_id,omitempty"`
+	UnitNumber   string    `json:"unit_number,omitempty"`
+	Weight       float64   `json:"weight"`
+	Comment      string    `json:"comment,omitempty"`
+	ProxyGrantID string    `json:"proxy_grant_id,omitempty"` // Vekaletle kullanılan oy
+	OnBehalfOf   string    `json:"on_behalf_of,omitempty"`   // Adına oy verilen kat maliki
+	VotedAt      time.Time `json:"voted_at"`
 }
 
 type SurveyRequest struct {
@@ -74,9 +82,12 @@ type SurveyRequest struct {
 	Options              []string `json:"options" binding:"required,min=2"`
 }
 
+// VoteRequest oy isteği. unit_ids boşsa kullanıcının oy kullanabileceği tüm daireler
+// (kendi daireleri ve vekaleten temsil ettikleri) için aynı oy verilir.
 type VoteRequest struct {
 	OptionIDs []string `json:"option_ids" binding:"required"`
 	Comment   string   `json:"comment"`
+	UnitIDs   []string `json:"unit_ids"`
 }
 
 type SurveyStats struct {
@@ -91,6 +102,21 @@ type SurveyStats struct {
 // =====================================================
 
 func main() {
+	// Veritabanı bağlantısı
+	pool, err := database.Connect(database.NewConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Veritabanı bağlantısı başarısız: %v", err)
+	}
+	defer database.Close()
+	store := newSurveyStore(pool)
+
+	// Kapanış sinyalinde sunucu ve arka plan işleri durdurulur
+	ctx, stop := server.Context()
+	defer stop()
+
+	// Kiracı askıya alma / yeniden etkinleştirme LISTEN/NOTIFY ile önbellekten düşer
+	go tenant.GetManager().Listen(ctx, pool)
+
 	r := gin.Default()
 
 	r.GET("/health", func(c *gin.Context) {
@@ -110,15 +136,15 @@ func main() {
 			surveys.GET("/active", getActiveSurveys)
 			surveys.GET("/stats", getSurveyStats)
 			surveys.GET("/:id", getSurvey)
-			surveys.GET("/:id/results", getSurveyResults)
+			surveys.GET("/:id/results", middleware.AuthMiddleware(), tenant.DatabaseScope(), getSurveyResults(store))
 			surveys.GET("/:id/votes", getSurveyVotes)
 			surveys.POST("", createSurvey)
 			surveys.PUT("/:id", updateSurvey)
 			surveys.DELETE("/:id", deleteSurvey)
 			surveys.POST("/:id/publish", publishSurvey)
 			surveys.POST("/:id/end", endSurvey)
-			surveys.POST("/:id/vote", voteSurvey)
-			surveys.GET("/:id/my-vote", getMyVote)
+			surveys.POST("/:id/vote", middleware.AuthMiddleware(), tenant.DatabaseScope(), middleware.RequirePermission("survey.vote"), voteSurvey(store))
+			surveys.GET("/:id/my-vote", middleware.AuthMiddleware(), tenant.DatabaseScope(), getMyVote(store))
 		}
 
 		// Resident surveys
@@ -132,7 +158,7 @@ func main() {
 	}
 
 	log.Printf("Survey Service starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	if err := server.Run(ctx, ":"+port, r); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -273,18 +299,64 @@ func getSurvey(c *gin.Context) {
 	c.JSON(http.StatusOK, survey)
 }
 
-func getSurveyResults(c *gin.Context) {
-	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{
-		"survey_id": id,
-		"results": []SurveyOption{
-			{ID: "opt-1", OptionText: "08:00 - 20:00", VoteCount: 12, Percentage: 26.7},
-			{ID: "opt-2", OptionText: "09:00 - 21:00", VoteCount: 25, Percentage: 55.6},
-			{ID: "opt-3", OptionText: "10:00 - 22:00", VoteCount: 8, Percentage: 17.8},
-		},
-		"total_votes":        45,
-		"participation_rate": 36.3,
-	})
+// getSurveyResults seçenek bazlı sonuçlar. Oylar daire başına sayılır; vekaletle
+// kullanılan oylar proxy_votes olarak ayrıca gösterilir. Ağırlıklı anketlerde yüzdeler
+// dairelerin m² toplamına göredir.
+func getSurveyResults(store *surveyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		sv, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		ballots, err := store.ballots(ctx, sv.ID)
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		eligible, err := store.eligibleUnits(ctx, sv)
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+
+		choices := make([]string, len(sv.Options))
+		for i, o := range sv.Options {
+			choices[i] = o.ID
+		}
+		tally := proxy.Count(ballots, choices)
+		results := make([]gin.H, len(sv.Options))
+		for i, o := range sv.Options {
+			r := tally.Results[i]
+			percentage := r.Percentage
+			if sv.IsWeighted {
+				percentage = r.WeightPercentage
+			}
+			results[i] = gin.H{
+				"id":                  o.ID,
+				"option_text":         o.OptionText,
+				"vote_count":          r.Units,
+				"proxy_votes":         r.ProxyUnits,
+				"weighted_vote_count": r.Weight,
+				"percentage":          percentage,
+			}
+		}
+		participation := 0.0
+		if eligible > 0 {
+			participation = float64(int(float64(tally.Units)*10000/float64(eligible)+0.5)) / 100
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"survey_id":             sv.ID,
+			"is_weighted":           sv.IsWeighted,
+			"results":               results,
+			"total_votes":           tally.Units,
+			"proxy_votes":           tally.ProxyUnits,
+			"total_weight":          tally.TotalWeight,
+			"total_eligible_voters": eligible,
+			"participation_rate":    participation,
+		})
+	}
 }
 
 func getSurveyVotes(c *gin.Context) {
@@ -380,33 +452,143 @@ func endSurvey(c *gin.Context) {
 	})
 }
 
-func voteSurvey(c *gin.Context) {
-	id := c.Param("id")
-	var req VoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// voteSurvey kullanıcının kendi daireleri ve VOTE kapsamlı vekaletle temsil ettiği daireler
+// için oy kaydeder. Her dairenin oyu bir kez kullanılır: oyu kullanılmış daireler atlanır.
+func voteSurvey(store *surveyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req VoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx := c.Request.Context()
+		sv, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		if !votingOpen(sv, time.Now()) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Anket oylamaya açık değil"})
+			return
+		}
+		if err := validateChoice(sv, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		units, err := store.votingUnits(ctx, sv, c.GetString("user_id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		units = selectUnits(units, req.UnitIDs)
+		if len(units) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Bu ankette oy kullanabileceğiniz daire yok"})
+			return
+		}
+
+		voted, skipped, err := store.vote(ctx, sv, c.GetString("user_id"), units, req.OptionIDs, req.Comment)
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		if len(voted) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Seçilen daireler için oy zaten kullanılmış", "skipped_units": skipped})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"survey_id":     sv.ID,
+			"option_ids":    req.OptionIDs,
+			"voted_units":   voted,
+			"skipped_units": skipped,
+			"voted_at":      time.Now(),
+			"message":       "Oyunuz kaydedildi",
+		})
 	}
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"survey_id":  id,
-		"option_ids": req.OptionIDs,
-		"voted_at":   time.Now(),
-		"message":    "Oyunuz kaydedildi",
-	})
+// getMyVote kullanıcının ankette kullandığı oylar ve oy kullanabileceği daireler
+func getMyVote(store *surveyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		sv, err := store.get(ctx, c.GetString("property_id"), c.Param("id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		votes, err := store.votesBy(ctx, sv.ID, c.GetString("user_id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		units, err := store.votingUnits(ctx, sv, c.GetString("user_id"))
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		voted, err := store.votedUnits(ctx, sv.ID, units)
+		if err != nil {
+			respondSurveyError(c, err)
+			return
+		}
+		eligible := make([]gin.H, len(units))
+		for i, u := range units {
+			eligible[i] = gin.H{"unit": u, "voted": voted[u.UnitID]}
+		}
+		c.JSON(http.StatusOK, gin.H{"votes": votes, "eligible_units": eligible})
+	}
 }
 
-func getMyVote(c *gin.Context) {
-	id := c.Param("id")
-	now := time.Now()
+// validateChoice seçeneklerin ankete ait olduğunu ve anket ayarlarına uyduğunu doğrular
+func validateChoice(sv *Survey, req *VoteRequest) error {
+	if len(req.OptionIDs) == 0 {
+		return errors.New("en az bir seçenek seçilmeli")
+	}
+	if len(req.OptionIDs) > 1 && !sv.AllowMultiple {
+		return errors.New("bu ankette yalnızca bir seçenek seçilebilir")
+	}
+	if req.Comment != "" && !sv.AllowComments {
+		return errors.New("bu ankette yorum yazılamaz")
+	}
+	valid := map[string]bool{}
+	for _, o := range sv.Options {
+		valid[o.ID] = true
+	}
+	seen := map[string]bool{}
+	for _, id := range req.OptionIDs {
+		if !valid[id] || seen[id] {
+			return errors.New("geçersiz seçenek")
+		}
+		seen[id] = true
+	}
+	return nil
+}
 
-	vote := SurveyVote{
-		ID:       uuid.New().String(),
-		SurveyID: id,
-		OptionID: "opt-2",
-		VotedAt:  now.Add(-2 * time.Hour),
+// selectUnits istenen daireleri süzer; istek boşsa tüm daireler döner
+func selectUnits(units []proxy.Unit, unitIDs []string) []proxy.Unit {
+	if len(unitIDs) == 0 {
+		return units
+	}
+	requested := map[string]bool{}
+	for _, id := range unitIDs {
+		requested[id] = true
+	}
+	var selected []proxy.Unit
+	for _, u := range units {
+		if requested[u.UnitID] {
+			selected = append(selected, u)
+		}
+	}
+	return selected
+}
+
+func respondSurveyError(c *gin.Context, err error) {
+	if errors.Is(err, errSurveyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
 	}
-	c.JSON(http.StatusOK, vote)
+	log.Printf("Anket işlemi başarısız: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Anket işlemi başarısız"})
 }
 
 func getMySurveys(c *gin.Context) {
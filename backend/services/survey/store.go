@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/pkg/proxy"
+)
+
+var errSurveyNotFound = errors.New("anket bulunamadı")
+
+// surveyStore anket oyları. Oylar daire başınadır: kat maliki, oylamaya göre kiracı veya
+// VOTE kapsamlı vekil dairenin oyunu bir kez kullanır. Vekaletle verilen oyda vekalet ve
+// adına oy verilen malik saklanır.
+type surveyStore struct {
+	pool *pgxpool.Pool
+}
+
+func newSurveyStore(pool *pgxpool.Pool) *surveyStore {
+	return &surveyStore{pool: pool}
+}
+
+// db oylama sorgularının bağlantısı; istekte aktif sitenin kiracısıyla (RLS) çalışır
+func (s *surveyStore) db(ctx context.Context) database.DB {
+	return database.Conn(ctx, s.pool)
+}
+
+// get sitedeki anketi seçenekleriyle getirir
+func (s *surveyStore) get(ctx context.Context, propertyID, id string) (*Survey, error) {
+	sv := &Survey{}
+	err := s.db(ctx).QueryRow(ctx, `
+		SELECT id, property_id, title, COALESCE(description, ''), survey_type, COALESCE(is_anonymous, true),
+			   COALESCE(is_weighted, false), COALESCE(allow_multiple, false), COALESCE(allow_comments, true),
+			   COALESCE(show_results_before_end, false), starts_at, ends_at, status, COALESCE(created_by::text, ''),
+			   created_at
+		FROM surveys
+		WHERE id::text = $2 AND property_id::text = $1
+	`, propertyID, id).Scan(&sv.ID, &sv.PropertyID, &sv.Title, &sv.Description, &sv.SurveyType, &sv.IsAnonymous,
+		&sv.IsWeighted, &sv.AllowMultiple, &sv.AllowComments, &sv.ShowResultsBeforeEnd, &sv.StartsAt, &sv.EndsAt,
+		&sv.Status, &sv.CreatedBy, &sv.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errSurveyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT id, survey_id, option_text, COALESCE(description, ''), COALESCE(display_order, 0)
+		FROM survey_options
+		WHERE survey_id = $1
+		ORDER BY display_order, created_at
+	`, sv.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o SurveyOption
+		if err := rows.Scan(&o.ID, &o.SurveyID, &o.OptionText, &o.Description, &o.DisplayOrder); err != nil {
+			return nil, err
+		}
+		sv.Options = append(sv.Options, o)
+	}
+	return sv, rows.Err()
+}
+
+// votingUnits kullanıcının ankette oy kullanabileceği daireler. Anket ve kamuoyu
+// yoklamalarında (POLL, SURVEY) kiracılar da oy verir; oylama ve genel kurulda yalnızca
+// kat malikleri ve vekilleri.
+func (s *surveyStore) votingUnits(ctx context.Context, sv *Survey, userID string) ([]proxy.Unit, error) {
+	return proxy.VotingUnits(ctx, s.db(ctx), userID, sv.PropertyID, tenantsMayVote(sv.SurveyType))
+}
+
+// eligibleUnits ankette oy kullanabilecek daire sayısı (katılım oranı için)
+func (s *surveyStore) eligibleUnits(ctx context.Context, sv *Survey) (int, error) {
+	var count int
+	err := s.db(ctx).QueryRow(ctx, `
+		SELECT COUNT(DISTINCT u.id)
+		FROM units u
+		JOIN resident_units ru ON ru.unit_id = u.id AND ru.is_active = true
+			AND (ru.end_date IS NULL OR ru.end_date >= CURRENT_DATE)
+		WHERE u.property_id = $1 AND (ru.role = 'OWNER' OR ($2 AND ru.role = 'TENANT'))
+	`, sv.PropertyID, tenantsMayVote(sv.SurveyType)).Scan(&count)
+	return count, err
+}
+
+// vote seçilen seçenekler için her dairenin oyunu tek transaction'da kaydeder. Daha önce
+// oy kullanılmış daireler atlanır ve skipped olarak döner; seçenek sayaçları ve anketin
+// toplam oyu yalnızca yeni oy kullanan dairelerle artar.
+func (s *surveyStore) vote(ctx context.Context, sv *Survey, voterID string, units []proxy.Unit, optionIDs []string, comment string) (voted, skipped []proxy.Unit, err error) {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Aynı anketteki eşzamanlı oylar sıraya girer; bir dairenin oyu iki kez yazılamaz
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM surveys WHERE id = $1 FOR UPDATE`, sv.ID); err != nil {
+		return nil, nil, err
+	}
+	for _, u := range units {
+		var already bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM survey_votes WHERE survey_id = $1 AND unit_id = $2)
+		`, sv.ID, u.UnitID).Scan(&already); err != nil {
+			return nil, nil, err
+		}
+		if already {
+			skipped = append(skipped, u)
+			continue
+		}
+
+		weight := voteWeight(sv, u)
+		for _, optionID := range optionIDs {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO survey_votes (survey_id, option_id, voter_id, unit_id, weight, comment, proxy_grant_id, on_behalf_of)
+				VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, '')::uuid, NULLIF($8, '')::uuid)
+			`, sv.ID, optionID, voterID, u.UnitID, weight, comment, u.GrantID, u.OnBehalfOf); err != nil {
+				return nil, nil, err
+			}
+			if _, err := tx.Exec(ctx, `
+				UPDATE survey_options
+				SET vote_count = COALESCE(vote_count, 0) + 1, weighted_vote_count = COALESCE(weighted_vote_count, 0) + $2
+				WHERE id = $1
+			`, optionID, weight); err != nil {
+				return nil, nil, err
+			}
+		}
+		voted = append(voted, u)
+	}
+
+	if len(voted) > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE surveys SET total_votes = COALESCE(total_votes, 0) + $2, updated_at = NOW() WHERE id = $1
+		`, sv.ID, len(voted)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return voted, skipped, nil
+}
+
+// ballots anketin tüm oyları (sonuç hesabı için)
+func (s *surveyStore) ballots(ctx context.Context, surveyID string) ([]proxy.Ballot, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT unit_id::text, option_id::text, COALESCE(weight, 1), proxy_grant_id IS NOT NULL
+		FROM survey_votes
+		WHERE survey_id = $1 AND unit_id IS NOT NULL
+		ORDER BY voted_at
+	`, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ballots []proxy.Ballot
+	for rows.Next() {
+		var b proxy.Ballot
+		if err := rows.Scan(&b.UnitID, &b.Choice, &b.Weight, &b.ViaProxy); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, b)
+	}
+	return ballots, rows.Err()
+}
+
+// votesBy kullanıcının ankette kendi dairesi veya vekaleten kullandığı oylar
+func (s *surveyStore) votesBy(ctx context.Context, surveyID, voterID string) ([]SurveyVote, error) {
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT v.id, v.survey_id, v.option_id, v.voter_id, v.unit_id,
+			   COALESCE(u.block || '-', '') || u.door_number, COALESCE(v.weight, 1), COALESCE(v.comment, ''),
+			   COALESCE(v.proxy_grant_id::text, ''), COALESCE(v.on_behalf_of::text, ''), v.voted_at
+		FROM survey_votes v
+		JOIN units u ON u.id = v.unit_id
+		WHERE v.survey_id::text = $1 AND v.voter_id::text = $2
+		ORDER BY v.voted_at
+	`, surveyID, voterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []SurveyVote{}
+	for rows.Next() {
+		var v SurveyVote
+		if err := rows.Scan(&v.ID, &v.SurveyID, &v.OptionID, &v.VoterID, &v.UnitID, &v.UnitNumber, &v.Weight,
+			&v.Comment, &v.ProxyGrantID, &v.OnBehalfOf, &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, rows.Err()
+}
+
+// votedUnits verilen dairelerden ankette oyu kullanılmış olanlar
+func (s *surveyStore) votedUnits(ctx context.Context, surveyID string, units []proxy.Unit) (map[string]bool, error) {
+	ids := make([]string, len(units))
+	for i, u := range units {
+		ids[i] = u.UnitID
+	}
+	rows, err := s.db(ctx).Query(ctx, `
+		SELECT DISTINCT unit_id::text FROM survey_votes WHERE survey_id::text = $1 AND unit_id::text = ANY($2)
+	`, surveyID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	voted := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		voted[id] = true
+	}
+	return voted, rows.Err()
+}
+
+func tenantsMayVote(surveyType string) bool {
+	return surveyType == "POLL" || surveyType == "SURVEY"
+}
+
+// voteWeight ağırlıklı (m² bazlı) anketlerde dairenin alanı, diğerlerinde 1
+func voteWeight(sv *Survey, u proxy.Unit) float64 {
+	if sv.IsWeighted && u.Area > 0 {
+		return u.Area
+	}
+	return 1
+}
+
+// votingOpen anket şu an oy kabul ediyor mu
+func votingOpen(sv *Survey, now time.Time) bool {
+	return sv.Status == "ACTIVE" && !now.Before(sv.StartsAt) && (sv.EndsAt == nil || now.Before(*sv.EndsAt))
+}
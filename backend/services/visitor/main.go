@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/siteeksen/backend/pkg/middleware"
 )
 
 // =====================================================
@@ -87,36 +88,37 @@ func main() {
 		})
 	})
 
-	// Visitor routes
+	// Visitor routes; aktif sitedeki yetkiyle açılır
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.AuthMiddleware())
 	{
 		visitors := v1.Group("/visitors")
 		{
-			visitors.GET("", listVisitors)
-			visitors.GET("/stats", getVisitorStats)
-			visitors.GET("/today", getTodayVisitors)
-			visitors.GET("/expected", getExpectedVisitors)
-			visitors.GET("/inside", getCurrentVisitors)
-			visitors.GET("/:id", getVisitor)
-			visitors.POST("", createVisitor)
-			visitors.PUT("/:id", updateVisitor)
-			visitors.DELETE("/:id", deleteVisitor)
+			visitors.GET("", middleware.RequirePermission("visitor.read"), listVisitors)
+			visitors.GET("/stats", middleware.RequirePermission("visitor.read"), getVisitorStats)
+			visitors.GET("/today", middleware.RequirePermission("visitor.read"), getTodayVisitors)
+			visitors.GET("/expected", middleware.RequirePermission("visitor.read"), getExpectedVisitors)
+			visitors.GET("/inside", middleware.RequirePermission("visitor.read"), getCurrentVisitors)
+			visitors.GET("/:id", middleware.RequirePermission("visitor.read"), getVisitor)
+			visitors.POST("", middleware.RequirePermission("visitor.checkin"), createVisitor)
+			visitors.PUT("/:id", middleware.RequirePermission("visitor.checkin"), updateVisitor)
+			visitors.DELETE("/:id", middleware.RequirePermission("visitor.checkin"), deleteVisitor)
 
 			// Giriş/Çıkış işlemleri
-			visitors.POST("/:id/checkin", checkInVisitor)
-			visitors.POST("/:id/checkout", checkOutVisitor)
+			visitors.POST("/:id/checkin", middleware.RequirePermission("visitor.checkin"), checkInVisitor)
+			visitors.POST("/:id/checkout", middleware.RequirePermission("visitor.checkin"), checkOutVisitor)
 
 			// QR kod işlemleri
-			visitors.GET("/qr/:code", getVisitorByQR)
-			visitors.POST("/:id/regenerate-qr", regenerateQR)
+			visitors.GET("/qr/:code", middleware.RequirePermission("visitor.checkin"), getVisitorByQR)
+			visitors.POST("/:id/regenerate-qr", middleware.RequirePermission("visitor.checkin"), regenerateQR)
 
 			// Bildirim
-			visitors.POST("/:id/notify", notifyResident)
+			visitors.POST("/:id/notify", middleware.RequirePermission("visitor.checkin"), notifyResident)
 		}
 
 		// Unit visitors
-		v1.GET("/units/:unit_id/visitors", getUnitVisitors)
-		v1.GET("/units/:unit_id/visitors/history", getUnitVisitorHistory)
+		v1.GET("/units/:unit_id/visitors", middleware.RequirePermission("visitor.read"), getUnitVisitors)
+		v1.GET("/units/:unit_id/visitors/history", middleware.RequirePermission("visitor.read"), getUnitVisitorHistory)
 	}
 
 	port := os.Getenv("PORT")
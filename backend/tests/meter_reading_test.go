@@ -0,0 +1,147 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/ledger"
+	"github.com/siteeksen/backend/services/finance/metering"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMeterReadingUpload toplu okuma yüklemesinin geçerli okumaları kaydettiğini, şüpheli
+// okumaları incelemeye aldığını ve onaylanan okumanın yazıldığını doğrular. Tarifeli
+// tüketimler faturalanır ve dairenin cari bakiyesine (120) işlenir.
+// TEST_DATABASE_URL tanımlı değilse atlanır.
+func TestMeterReadingUpload(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL tanımlı değil")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	require.NoError(t, err)
+	defer pool.Close()
+	repo := repository.NewFinanceRepository(pool, nil)
+
+	suffix := uuid.NewString()[:8]
+	tenantID, propertyID, unitID := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	serial := func(n int) string { return fmt.Sprintf("T%s-%d", suffix, n) }
+	setup := []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT INTO tenants (id, name, slug) VALUES ($1, 'Sayaç Testi', $2)`, []any{tenantID, "sayac-" + suffix}},
+		{`INSERT INTO properties (id, name, address, city, tenant_id) VALUES ($1, 'Sayaç Sitesi', 'Adres', 'Bursa', $2)`,
+			[]any{propertyID, tenantID}},
+		{`INSERT INTO units (id, property_id, block, floor, door_number, share_ratio) VALUES ($1, $2, 'A', 1, '1', 10)`,
+			[]any{unitID, propertyID}},
+		// 10 TL/birim, %20 KDV
+		{`INSERT INTO consumption_tariffs (property_id, meter_type, effective_from, unit_price, fixed_fee, tax_rate)
+		  VALUES ($1, 'HEAT', '2026-01-01', 10, 0, 20)`, []any{propertyID}},
+	}
+	for i := 1; i <= 4; i++ {
+		setup = append(setup, struct {
+			sql  string
+			args []any
+		}{`INSERT INTO meters (unit_id, meter_type, serial_number) VALUES ($1, 'HEAT', $2)`, []any{unitID, serial(i)}})
+	}
+	// Üç sayacın dört aylık geçmişi: ayda 15 birim
+	for i := 1; i <= 3; i++ {
+		for m := 6; m <= 9; m++ {
+			setup = append(setup, struct {
+				sql  string
+				args []any
+			}{`INSERT INTO meter_readings (meter_id, reading_date, previous_value, current_value)
+			   SELECT id, $2, $3, $4 FROM meters WHERE serial_number = $1`,
+				[]any{serial(i), time.Date(2026, time.Month(m), 1, 0, 0, 0, 0, time.UTC), 1000 + 15*(m-6), 1000 + 15*(m-5)}})
+		}
+	}
+	defer func() {
+		pool.Exec(ctx, `DELETE FROM consumption_invoices WHERE unit_id = $1`, unitID)
+		pool.Exec(ctx, `DELETE FROM properties WHERE id = $1`, propertyID)
+		pool.Exec(ctx, `DELETE FROM tenants WHERE id = $1`, tenantID)
+	}()
+	for _, q := range setup {
+		_, err := pool.Exec(ctx, q.sql, q.args...)
+		require.NoError(t, err, q.sql)
+	}
+
+	value := func(v float64) *float64 { return &v }
+	rows := []metering.Row{
+		{Num: 2, SerialNumber: serial(1), Value: value(1075)}, // 15 birim
+		{Num: 3, SerialNumber: serial(2), Value: value(1050)}, // geri gitmiş
+		{Num: 4, SerialNumber: serial(3), Value: value(1200)}, // sıçrama
+		{Num: 5, SerialNumber: serial(4), Value: value(12.5)}, // ilk okuma
+		{Num: 6, SerialNumber: "YOK-" + suffix, Value: value(1)},
+		{Num: 7, SerialNumber: serial(1)},
+	}
+	date := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	upload, err := repo.RecordMeterReadings(ctx, propertyID, "", date, rows)
+	require.NoError(t, err)
+	require.Len(t, upload.Rows, 6)
+	assert.Equal(t, 2, upload.Recorded)
+	assert.Equal(t, 2, upload.Queued)
+	assert.Equal(t, 1, upload.Skipped)
+	assert.Equal(t, 1, upload.Failed)
+
+	assert.Equal(t, metering.StatusRecorded, upload.Rows[0].Status)
+	assert.Equal(t, "A-1", upload.Rows[0].Unit)
+	require.NotNil(t, upload.Rows[0].Consumption)
+	assert.Equal(t, 15.0, *upload.Rows[0].Consumption)
+	assert.Equal(t, metering.ReasonRollback, upload.Rows[1].Reason)
+	assert.Equal(t, metering.ReasonJump, upload.Rows[2].Reason)
+	assert.Equal(t, 0.0, *upload.Rows[3].Consumption, "ilk okuma başlangıç değeridir")
+	assert.Equal(t, "sayaç bulunamadı", upload.Rows[4].Message)
+
+	// 15 birim × 10 TL + %20 KDV dairenin borcuna işlenir; ilk okuma faturalanmaz
+	book := ledger.New(pool)
+	assert.NotEmpty(t, upload.Rows[0].InvoiceID)
+	require.NotNil(t, upload.Rows[0].Charge)
+	assert.Equal(t, 180.0, *upload.Rows[0].Charge)
+	assert.Empty(t, upload.Rows[3].InvoiceID)
+	balance, err := book.UnitAccountBalanceTx(ctx, pool, unitID, ledger.AccountResidentReceivable)
+	require.NoError(t, err)
+	assert.Equal(t, 180.0, balance)
+
+	// Aynı liste tekrar yüklenirse okumalar tekrar yazılmaz, inceleme çoğalmaz
+	again, err := repo.RecordMeterReadings(ctx, propertyID, "", date, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, again.Unchanged)
+	assert.Equal(t, upload.Rows[2].ReviewID, again.Rows[2].ReviewID)
+
+	reviews, err := repo.ListMeterReadingReviews(ctx, propertyID, "PENDING")
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+
+	_, err = repo.ApproveMeterReadingReview(ctx, propertyID, upload.Rows[1].ReviewID, "", nil, false, "")
+	assert.ErrorIs(t, err, repository.ErrMeterReadingRollback)
+	review, err := repo.ApproveMeterReadingReview(ctx, propertyID, upload.Rows[1].ReviewID, "", nil, true, "Sayaç değişti")
+	require.NoError(t, err)
+	assert.Equal(t, "APPROVED", review.Status)
+	assert.NotEmpty(t, review.MeterReadingID)
+
+	review, err = repo.ApproveMeterReadingReview(ctx, propertyID, upload.Rows[2].ReviewID, "", value(1080), false, "Görevli yanlış yazmış")
+	require.NoError(t, err)
+	var consumption float64
+	require.NoError(t, pool.QueryRow(ctx, `SELECT consumption FROM meter_readings WHERE id = $1`, review.MeterReadingID).Scan(&consumption))
+	assert.Equal(t, 20.0, consumption)
+
+	// Onaylanan okumanın 20 birimlik tüketimi de faturalanır
+	balance, err = book.UnitAccountBalanceTx(ctx, pool, unitID, ledger.AccountResidentReceivable)
+	require.NoError(t, err)
+	assert.Equal(t, 420.0, balance)
+	var invoices int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM consumption_invoices WHERE unit_id = $1`, unitID).Scan(&invoices))
+	assert.Equal(t, 2, invoices)
+
+	_, err = repo.RejectMeterReadingReview(ctx, propertyID, upload.Rows[2].ReviewID, "", "")
+	assert.ErrorIs(t, err, repository.ErrMeterReadingReviewResolved)
+}
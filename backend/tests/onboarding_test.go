@@ -0,0 +1,135 @@
+package integration_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/encryption"
+	"github.com/siteeksen/backend/pkg/tenant"
+	"github.com/siteeksen/backend/services/identity/models"
+	"github.com/siteeksen/backend/services/identity/repository"
+	"github.com/siteeksen/backend/services/identity/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOnboardingBootstrap kayıt kurulumunun gerçek veritabanında tek transaction'da
+// yazıldığını doğrular. TEST_DATABASE_URL tanımlı değilse atlanır.
+func TestOnboardingBootstrap(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL tanımlı değil")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	keyring, err := encryption.NewKeyring(1, map[int]string{1: testKey(t)})
+	require.NoError(t, err)
+	index, err := encryption.NewBlindIndex(testKey(t))
+	require.NoError(t, err)
+	repo := repository.NewOnboardingRepository(pool, &encryption.PIICipher{Keyring: keyring, BlindIndex: index})
+	manager := tenant.NewManager(nil, tenant.DefaultManagerConfig())
+
+	newSite := func(units ...models.SiteUnit) *models.SiteBootstrap {
+		suffix := uuid.NewString()[:8]
+		tn, trialEndsAt, err := manager.NewTenant(tenant.CreateTenantInput{Name: "Kayıt Testi", Slug: "kayit-" + suffix})
+		require.NoError(t, err)
+		return &models.SiteBootstrap{
+			Tenant:            tn,
+			TrialEndsAt:       trialEndsAt,
+			Property:          models.SiteProperty{Name: "Kayıt Sitesi", Address: "Adres", City: "İzmir"},
+			Blocks:            []models.SiteBlock{{Name: "A", FloorCount: 2}},
+			Units:             units,
+			ExpenseCategories: service.DefaultExpenseCategories,
+			RequestCategories: service.DefaultRequestCategories,
+			Admin: &models.User{
+				FirstName: "Kayıt", LastName: "Yönetici", PasswordHash: "x",
+				Phone: fmt.Sprintf("+90533%07d", time.Now().UnixNano()%1e7),
+			},
+			AdminRole: service.SignupAdminRole,
+		}
+	}
+	cleanup := func(site *models.SiteBootstrap) {
+		if site.Admin.ID != "" {
+			pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, site.Admin.ID)
+		}
+		if site.Property.ID != "" {
+			pool.Exec(ctx, `DELETE FROM chart_of_accounts WHERE property_id = $1`, site.Property.ID)
+			pool.Exec(ctx, `DELETE FROM request_categories WHERE property_id = $1`, site.Property.ID)
+			pool.Exec(ctx, `DELETE FROM properties WHERE id = $1`, site.Property.ID)
+		}
+		pool.Exec(ctx, `DELETE FROM tenants WHERE slug = $1`, site.Tenant.Slug)
+	}
+
+	site := newSite(
+		models.SiteUnit{Block: "A", Floor: 0, DoorNumber: "1", ShareRatio: 10, UnitType: models.UnitApartment},
+		models.SiteUnit{Block: "A", Floor: 1, DoorNumber: "2", ShareRatio: 15, UnitType: models.UnitShop, IsCommercial: true},
+	)
+	defer cleanup(site)
+	require.NoError(t, repo.Bootstrap(ctx, site))
+
+	var status, tenantID string
+	var totalUnits, expenses, requests, accounts, roles int
+	var totalShare float64
+	err = pool.QueryRow(ctx, `
+		SELECT t.subscription_status, p.tenant_id::text, p.total_units, p.total_share_ratio,
+		       (SELECT COUNT(*) FROM expense_categories WHERE property_id = p.id),
+		       (SELECT COUNT(*) FROM request_categories WHERE property_id = p.id),
+		       (SELECT COUNT(*) FROM chart_of_accounts WHERE property_id = p.id),
+		       (SELECT COUNT(*) FROM property_role_assignments
+		         WHERE property_id = p.id AND user_id = $2 AND role_code = 'MANAGER' AND revoked_at IS NULL)
+		FROM properties p JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = $1
+	`, site.Property.ID, site.Admin.ID).Scan(&status, &tenantID, &totalUnits, &totalShare,
+		&expenses, &requests, &accounts, &roles)
+	require.NoError(t, err)
+	assert.Equal(t, "trial", status)
+	assert.Equal(t, site.Tenant.ID, tenantID)
+	assert.Equal(t, 2, totalUnits)
+	assert.Equal(t, 25.0, totalShare)
+	assert.Equal(t, len(service.DefaultExpenseCategories), expenses)
+	assert.Equal(t, len(service.DefaultRequestCategories), requests)
+	assert.Positive(t, accounts)
+	assert.Equal(t, 1, roles)
+	assert.NotEmpty(t, site.Units[1].ID)
+
+	// Aynı kapı numarası veritabanında reddedilir; kiracı dahil hiçbir kayıt kalmaz
+	failed := newSite(
+		models.SiteUnit{Block: "A", DoorNumber: "1", ShareRatio: 10, UnitType: models.UnitApartment},
+		models.SiteUnit{Block: "A", DoorNumber: "1", ShareRatio: 10, UnitType: models.UnitApartment},
+	)
+	defer cleanup(failed)
+	require.Error(t, repo.Bootstrap(ctx, failed))
+	var tenants, properties int
+	require.NoError(t, pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM tenants WHERE slug = $1),
+		       (SELECT COUNT(*) FROM properties WHERE tenant_id::text = $2)
+	`, failed.Tenant.Slug, failed.Tenant.ID).Scan(&tenants, &properties))
+	assert.Zero(t, tenants)
+	assert.Zero(t, properties)
+
+	// Yönetici telefonu kayıtlıysa ErrPhoneTaken; kurulum geri alınır
+	taken := newSite(models.SiteUnit{DoorNumber: "1", ShareRatio: 1, UnitType: models.UnitApartment})
+	taken.Admin.Phone = site.Admin.Phone
+	defer cleanup(taken)
+	assert.ErrorIs(t, repo.Bootstrap(ctx, taken), repository.ErrPhoneTaken)
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM tenants WHERE slug = $1`, taken.Tenant.Slug).Scan(&tenants))
+	assert.Zero(t, tenants)
+}
+
+func testKey(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(b)
+}
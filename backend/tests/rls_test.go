@@ -0,0 +1,267 @@
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/siteeksen/backend/pkg/database"
+	"github.com/siteeksen/backend/services/finance/models"
+	"github.com/siteeksen/backend/services/finance/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// RLSTestSuite - Migration'ları uygulanmış gerçek veritabanında kiracı izolasyonu.
+// TEST_DATABASE_URL tanımlı değilse atlanır (docker compose up postgres).
+type RLSTestSuite struct {
+	suite.Suite
+	pool *pgxpool.Pool
+	repo *repository.FinanceRepository
+
+	userID     string
+	tenants    [2]string
+	properties [2]string
+	units      [2]string
+	assessment [2]string
+}
+
+func TestRLSSuite(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL tanımlı değil")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	suite.Run(t, &RLSTestSuite{pool: pool, repo: repository.NewFinanceRepository(pool, nil)})
+}
+
+// SetupSuite iki kiracıda birer site, daire ve tahakkuk oluşturur; sakin iki dairede de
+// kayıtlıdır, böylece yalnızca kiracı kapsamı ayırt edicidir
+func (s *RLSTestSuite) SetupSuite() {
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	s.userID = uuid.NewString()
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO users (id, first_name, last_name, phone, password_hash)
+		VALUES ($1, 'Ayşe', 'Yılmaz', $2, 'x')
+	`, s.userID, fmt.Sprintf("+90555%07d", time.Now().UnixNano()%1e7))
+	s.Require().NoError(err)
+
+	for i, name := range []string{"mavi", "yesil"} {
+		s.tenants[i], s.properties[i], s.units[i], s.assessment[i] =
+			uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+		for _, q := range []struct {
+			sql  string
+			args []any
+		}{
+			{`INSERT INTO tenants (id, name, slug) VALUES ($1, $2, $2)`, []any{s.tenants[i], "rls-" + name + "-" + suffix}},
+			{`INSERT INTO properties (id, name, address, city, tenant_id) VALUES ($1, $2, 'Adres', 'İstanbul', $3)`,
+				[]any{s.properties[i], name, s.tenants[i]}},
+			{`INSERT INTO units (id, property_id, block, floor, door_number, share_ratio) VALUES ($1, $2, 'A', 1, '1', 10)`,
+				[]any{s.units[i], s.properties[i]}},
+			{`INSERT INTO resident_units (resident_id, unit_id, role) VALUES ($1, $2, 'OWNER')`, []any{s.userID, s.units[i]}},
+			{`INSERT INTO monthly_assessments (id, property_id, unit_id, period_year, period_month, base_amount, total_amount, due_date)
+			  VALUES ($1, $2, $3, 2026, 3, $4, $4, '2026-03-15')`, []any{s.assessment[i], s.properties[i], s.units[i], 1000 * (i + 1)}},
+		} {
+			_, err := s.pool.Exec(ctx, q.sql, q.args...)
+			s.Require().NoError(err, q.sql)
+		}
+	}
+}
+
+func (s *RLSTestSuite) TearDownSuite() {
+	ctx := context.Background()
+	s.pool.Exec(ctx, `DELETE FROM payments WHERE user_id = $1`, s.userID)
+	// Denetim logları sahibe de kapalı; her kiracının kendi kapsamında silinir
+	for _, tenantID := range s.tenants {
+		scoped := database.WithScope(ctx, database.Scope{TenantID: tenantID, UserID: s.userID})
+		database.InTenantTx(scoped, s.pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `DELETE FROM audit_logs WHERE user_id = $1`, s.userID)
+			return err
+		})
+	}
+	s.pool.Exec(ctx, `DELETE FROM properties WHERE id = ANY($1::uuid[])`, s.properties[:])
+	s.pool.Exec(ctx, `DELETE FROM tenants WHERE id = ANY($1::uuid[])`, s.tenants[:])
+	s.pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, s.userID)
+}
+
+// scoped ilk kiracının (mavi) kapsamındaki istek context'i
+func (s *RLSTestSuite) scoped() context.Context {
+	return database.WithScope(context.Background(), database.Scope{TenantID: s.tenants[0], UserID: s.userID})
+}
+
+func (s *RLSTestSuite) TestUnscopedQueriesAreRejected() {
+	err := database.InTenantTx(context.Background(), s.pool, func(tx pgx.Tx) error { return nil })
+	s.ErrorIs(err, database.ErrNoTenantScope)
+
+	_, err = s.repo.GetAssessmentDetails(context.Background(), s.userID, "", s.assessment[0])
+	s.ErrorIs(err, database.ErrNoTenantScope)
+}
+
+func (s *RLSTestSuite) TestCrossTenantReadsReturnNothing() {
+	err := database.InTenantTx(s.scoped(), s.pool, func(tx pgx.Tx) error {
+		var properties, units, assessments int
+		err := tx.QueryRow(context.Background(), `
+			SELECT (SELECT COUNT(*) FROM properties WHERE id = ANY($1::uuid[])),
+			       (SELECT COUNT(*) FROM units WHERE id = ANY($2::uuid[])),
+			       (SELECT COUNT(*) FROM monthly_assessments WHERE id = ANY($3::uuid[]))
+		`, s.properties[:], s.units[:], s.assessment[:]).Scan(&properties, &units, &assessments)
+		s.Equal(1, properties)
+		s.Equal(1, units)
+		s.Equal(1, assessments)
+		return err
+	})
+	s.Require().NoError(err)
+
+	// Sakin iki dairede de kayıtlı; diğer kiracının tahakkuku yine de görünmez
+	detail, err := s.repo.GetAssessmentDetails(s.scoped(), s.userID, "", s.assessment[0])
+	s.Require().NoError(err)
+	s.Equal(s.units[0], detail.UnitID)
+
+	_, err = s.repo.GetAssessmentDetails(s.scoped(), s.userID, "", s.assessment[1])
+	s.ErrorIs(err, repository.ErrAssessmentNotFound)
+
+	// Yönetici görünümü de kapsam dışındaki siteyi açmaz
+	_, err = s.repo.GetAssessmentDetails(s.scoped(), uuid.NewString(), s.properties[1], s.assessment[1])
+	s.ErrorIs(err, repository.ErrAssessmentNotFound)
+
+	total, err := s.repo.CalculateTotalAmount(s.scoped(), s.userID, s.assessment[:])
+	s.Require().NoError(err)
+	s.Equal(1000.0, total)
+
+	payable, err := s.repo.GetPayableAssessments(s.scoped(), s.userID, "", s.assessment[:])
+	s.Require().NoError(err)
+	s.Require().Len(payable, 1)
+	s.Equal(s.assessment[0], payable[0].ID)
+}
+
+// database.Conn'dan geçen repository sorguları da kiracı kapsamında çalışır
+func (s *RLSTestSuite) TestScopedRepositoryMethods() {
+	units, err := s.repo.GetUnitsForDistribution(s.scoped(), s.properties[0])
+	s.Require().NoError(err)
+	s.Len(units, 1)
+
+	units, err = s.repo.GetUnitsForDistribution(s.scoped(), s.properties[1])
+	s.Require().NoError(err)
+	s.Empty(units)
+
+	assessed, err := s.repo.GetAssessedUnitIDs(s.scoped(), s.properties[1], 2026, 3)
+	s.Require().NoError(err)
+	s.Empty(assessed)
+
+	// Kiracısı çözülemeyen istek havuza düşmez
+	unresolved := database.WithScope(context.Background(), database.Scope{UserID: s.userID})
+	_, err = s.repo.GetUnitsForDistribution(unresolved, s.properties[0])
+	s.ErrorIs(err, database.ErrNoTenantScope)
+
+	// Arka plan işleri (kapsam yok) sahibin yetkisiyle tüm siteleri görür
+	units, err = s.repo.GetUnitsForDistribution(context.Background(), s.properties[1])
+	s.Require().NoError(err)
+	s.Len(units, 1)
+}
+
+func (s *RLSTestSuite) TestCrossTenantWritesFail() {
+	ctx := s.scoped()
+
+	// Güncelleme diğer kiracının satırını görmez
+	err := database.InTenantTx(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE monthly_assessments SET paid_amount = total_amount WHERE id = $1`, s.assessment[1])
+		s.Equal(int64(0), tag.RowsAffected())
+		return err
+	})
+	s.Require().NoError(err)
+
+	// Diğer kiracının sitesine ekleme politika ihlali
+	err = database.InTenantTx(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO monthly_assessments (property_id, unit_id, period_year, period_month, base_amount, total_amount, due_date)
+			VALUES ($1, $2, 2026, 4, 1, 1, '2026-04-15')
+		`, s.properties[1], s.units[1])
+		return err
+	})
+	assertPolicyViolation(s.T(), err)
+
+	// Kendi satırını diğer kiracıya taşıyamaz
+	err = database.InTenantTx(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE units SET property_id = $2 WHERE id = $1`, s.units[0], s.properties[1])
+		return err
+	})
+	assertPolicyViolation(s.T(), err)
+
+	// Diğer kiracının dairesine ödeme kaydı oluşturulamaz
+	_, err = s.repo.CreatePayment(ctx, s.userID, s.units[1],
+		[]models.PaymentAllocation{{AssessmentID: s.assessment[1], Amount: 2000}}, 2000, "CREDIT_CARD")
+	assertPolicyViolation(s.T(), err)
+
+	// Kendi dairesine ödeme kaydı oluşturulur
+	paymentID, err := s.repo.CreatePayment(ctx, s.userID, s.units[0],
+		[]models.PaymentAllocation{{AssessmentID: s.assessment[0], Amount: 1000}}, 1000, "CREDIT_CARD")
+	s.Require().NoError(err)
+	s.NotEmpty(paymentID)
+
+	var paid float64
+	s.Require().NoError(s.pool.QueryRow(context.Background(),
+		`SELECT COALESCE(paid_amount, 0) FROM monthly_assessments WHERE id = $1`, s.assessment[1]).Scan(&paid))
+	s.Zero(paid)
+}
+
+func (s *RLSTestSuite) TestAuditLogsAreTenantScoped() {
+	ctx := context.Background()
+	for _, tenantID := range s.tenants {
+		scoped := database.WithScope(ctx, database.Scope{TenantID: tenantID, UserID: s.userID})
+		err := database.InTenantTx(scoped, s.pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO audit_logs (tenant_id, user_id, action, entity_type) VALUES ($1, $2, 'LOGIN', 'user')
+			`, tenantID, s.userID)
+			return err
+		})
+		s.Require().NoError(err)
+	}
+
+	err := database.InTenantTx(s.scoped(), s.pool, func(tx pgx.Tx) error {
+		var own, other int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FILTER (WHERE tenant_id = $2), COUNT(*) FILTER (WHERE tenant_id = $3)
+			FROM audit_logs WHERE user_id = $1
+		`, s.userID, s.tenants[0], s.tenants[1]).Scan(&own, &other)
+		s.Equal(1, own)
+		s.Zero(other)
+		return err
+	})
+	s.Require().NoError(err)
+
+	// Diğer kiracı adına log yazılamaz
+	err = database.InTenantTx(s.scoped(), s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO audit_logs (tenant_id, user_id, action, entity_type) VALUES ($1, $2, 'LOGIN', 'user')
+		`, s.tenants[1], s.userID)
+		return err
+	})
+	assertPolicyViolation(s.T(), err)
+
+	// Politika tablo sahibine de uygulanır: kapsamsız sorgu hiçbir log görmez
+	var unscoped int
+	s.Require().NoError(s.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM audit_logs WHERE user_id = $1`, s.userID).Scan(&unscoped))
+	s.Zero(unscoped)
+}
+
+// assertPolicyViolation satır düzeyi güvenlik ihlali (42501 insufficient_privilege)
+func assertPolicyViolation(t *testing.T, err error) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	if assert.True(t, errors.As(err, &pgErr), "beklenen politika ihlali, alınan: %v", err) {
+		assert.Equal(t, "42501", pgErr.Code)
+	}
+}